@@ -3,8 +3,11 @@ package api
 import (
 	"errors"
 	"fmt"
+	"time"
 
+	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/internal/utils"
+	"go.sia.tech/renterd/object"
 )
 
 const (
@@ -18,9 +21,21 @@ var (
 	// with a value that exceeds the maximum of 99 years.
 	ErrMaxDowntimeHoursTooHigh = errors.New("MaxDowntimeHours is too high, exceeds max value of 99 years")
 
+	// ErrMaxAnnouncementAgeHoursTooHigh is returned if the hosts config is
+	// updated with a value that exceeds the maximum of 99 years.
+	ErrMaxAnnouncementAgeHoursTooHigh = errors.New("MaxAnnouncementAgeHours is too high, exceeds max value of 99 years")
+
+	// ErrRecentFailureCooldownHoursTooHigh is returned if the hosts config is
+	// updated with a value that exceeds the maximum of 99 years.
+	ErrRecentFailureCooldownHoursTooHigh = errors.New("RecentFailureCooldownHours is too high, exceeds max value of 99 years")
+
 	// ErrInvalidReleaseVersion is returned if the version is an invalid release
 	// string.
 	ErrInvalidReleaseVersion = errors.New("invalid release version")
+
+	// ErrMigrationNotFound is returned when trying to cancel a slab migration
+	// that isn't in progress.
+	ErrMigrationNotFound = errors.New("migration not found")
 )
 
 type (
@@ -44,9 +59,11 @@ type (
 
 	// HostsConfig contains all hosts settings used in the autopilot.
 	HostsConfig struct {
+		MaxAnnouncementAgeHours    uint64 `json:"maxAnnouncementAgeHours"`
 		MaxConsecutiveScanFailures uint64 `json:"maxConsecutiveScanFailures"`
 		MaxDowntimeHours           uint64 `json:"maxDowntimeHours"`
 		MinProtocolVersion         string `json:"minProtocolVersion"`
+		RecentFailureCooldownHours uint64 `json:"recentFailureCooldownHours"`
 	}
 )
 
@@ -86,19 +103,62 @@ type (
 	// AutopilotStateResponse is the response type for the /autopilot/state
 	// endpoint.
 	AutopilotStateResponse struct {
-		Enabled            bool        `json:"enabled"`
-		Migrating          bool        `json:"migrating"`
-		MigratingLastStart TimeRFC3339 `json:"migratingLastStart"`
-		Pruning            bool        `json:"pruning"`
-		PruningLastStart   TimeRFC3339 `json:"pruningLastStart"`
-		Scanning           bool        `json:"scanning"`
-		ScanningLastStart  TimeRFC3339 `json:"scanningLastStart"`
-		UptimeMS           DurationMS  `json:"uptimeMs"`
+		Enabled             bool        `json:"enabled"`
+		Migrating           bool        `json:"migrating"`
+		MigratingLastStart  TimeRFC3339 `json:"migratingLastStart"`
+		MigratingThroughput uint64      `json:"migratingThroughput"`
+		Pruning             bool        `json:"pruning"`
+		PruningLastStart    TimeRFC3339 `json:"pruningLastStart"`
+		Scanning            bool        `json:"scanning"`
+		ScanningLastStart   TimeRFC3339 `json:"scanningLastStart"`
+		UptimeMS            DurationMS  `json:"uptimeMs"`
 
 		StartTime TimeRFC3339 `json:"startTime"`
 		BuildState
 	}
 
+	// ActiveMigration describes a slab migration that is in progress.
+	ActiveMigration struct {
+		SlabKey        object.EncryptionKey `json:"slabKey"`
+		Health         float64              `json:"health"`
+		StartedAt      TimeRFC3339          `json:"startedAt"`
+		ShardsMigrated int                  `json:"shardsMigrated"`
+		ShardsTotal    int                  `json:"shardsTotal"`
+	}
+
+	// HostVerificationStats contains the results of sampling and verifying
+	// sectors stored on a host.
+	HostVerificationStats struct {
+		HostKey      types.PublicKey `json:"hostKey"`
+		Successes    uint64          `json:"successes"`
+		Failures     uint64          `json:"failures"`
+		SuccessRate  float64         `json:"successRate"`
+		LastVerified TimeRFC3339     `json:"lastVerified"`
+		LastError    string          `json:"lastError,omitempty"`
+	}
+
+	// ScannerStats contains the throughput of the ongoing or most recent host
+	// scan and the hosts that are scanned less often because they repeatedly
+	// failed their scans.
+	ScannerStats struct {
+		Scanning   bool              `json:"scanning"`
+		LastStart  TimeRFC3339       `json:"lastStart"`
+		Threads    int               `json:"threads"`
+		Scanned    uint64            `json:"scanned"`
+		Failed     uint64            `json:"failed"`
+		Skipped    uint64            `json:"skipped"`
+		Throughput float64           `json:"throughput"`
+		Backoff    []HostScanBackoff `json:"backoff"`
+	}
+
+	// HostScanBackoff describes a host that isn't scanned again before
+	// NextScan because it failed its most recent scans.
+	HostScanBackoff struct {
+		HostKey  types.PublicKey `json:"hostKey"`
+		Failures uint64          `json:"failures"`
+		NextScan TimeRFC3339     `json:"nextScan"`
+	}
+
 	ConfigEvaluationRequest struct {
 		AutopilotConfig    AutopilotConfig    `json:"autopilotConfig"`
 		GougingSettings    GougingSettings    `json:"gougingSettings"`
@@ -109,6 +169,26 @@ type (
 		GougingSettings GougingSettings `json:"gougingSettings"`
 	}
 
+	// ContractsDiffResponse is the response type for /contracts/diff
+	ContractsDiffResponse struct {
+		// Unusable contains the hosts of the current contract set that are
+		// no longer usable with the autopilot config, or whose contracts are
+		// no longer usable.
+		Unusable []ContractsDiffHost `json:"unusable"`
+
+		// Uncontracted contains the usable hosts we don't have a contract
+		// with in the current contract set.
+		Uncontracted []types.PublicKey `json:"uncontracted"`
+	}
+
+	// ContractsDiffHost is a host of the current contract set that is no
+	// longer usable, together with its contracts and the reasons why.
+	ContractsDiffHost struct {
+		HostKey   types.PublicKey        `json:"hostKey"`
+		Contracts []types.FileContractID `json:"contracts"`
+		Reasons   []string               `json:"reasons"`
+	}
+
 	// ConfigEvaluationResponse is the response type for /evaluate
 	ConfigEvaluationResponse struct {
 		Hosts    uint64 `json:"hosts"`
@@ -139,9 +219,25 @@ func (cc ContractsConfig) Validate() error {
 	return nil
 }
 
+// MaxAnnouncementAge returns the maximum age of a host's most recent
+// announcement, zero means announcements never go stale.
+func (hc HostsConfig) MaxAnnouncementAge() time.Duration {
+	return time.Duration(hc.MaxAnnouncementAgeHours) * time.Hour
+}
+
+// RecentFailureCooldown returns the duration after a failed interaction
+// during which a host is penalized, zero means failures are not penalized.
+func (hc HostsConfig) RecentFailureCooldown() time.Duration {
+	return time.Duration(hc.RecentFailureCooldownHours) * time.Hour
+}
+
 func (hc HostsConfig) Validate() error {
 	if hc.MaxDowntimeHours > 99*365*24 {
 		return ErrMaxDowntimeHoursTooHigh
+	} else if hc.MaxAnnouncementAgeHours > 99*365*24 {
+		return ErrMaxAnnouncementAgeHoursTooHigh
+	} else if hc.RecentFailureCooldownHours > 99*365*24 {
+		return ErrRecentFailureCooldownHoursTooHigh
 	} else if hc.MinProtocolVersion != "" && !utils.IsVersion(hc.MinProtocolVersion) {
 		return fmt.Errorf("%w: '%s'", ErrInvalidReleaseVersion, hc.MinProtocolVersion)
 	}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -18,6 +19,10 @@ var (
 	// ErrBucketNotFound is returned when an bucket can't be retrieved from the
 	// database.
 	ErrBucketNotFound = errors.New("bucket not found")
+
+	// ErrBucketQuotaExceeded is returned when storing an object would push
+	// the total size of the objects in a bucket over its quota.
+	ErrBucketQuotaExceeded = errors.New("bucket quota exceeded")
 )
 
 type (
@@ -29,11 +34,47 @@ type (
 
 	BucketPolicy struct {
 		PublicReadAccess bool `json:"publicReadAccess"`
+
+		// Redundancy overrides the global redundancy settings for objects
+		// uploaded to the bucket, objects that were uploaded before are
+		// unaffected.
+		Redundancy *RedundancySettings `json:"redundancy,omitempty"`
+
+		// CaseInsensitive makes fetching and deleting objects in the bucket
+		// match their keys case-insensitively, objects keep the key they
+		// were uploaded with. Objects whose keys only differ in case can't
+		// coexist in such a bucket. Keys are compared after lowercasing
+		// them, which also applies to non-ASCII characters.
+		CaseInsensitive bool `json:"caseInsensitive,omitempty"`
+
+		// Quota limits the total size of the objects in the bucket in bytes,
+		// storing an object that exceeds it fails with
+		// ErrBucketQuotaExceeded. Zero means the bucket is unlimited.
+		Quota uint64 `json:"quota,omitempty"`
+	}
+
+	// BucketLifecycleRule expires objects in a bucket after a number of days.
+	BucketLifecycleRule struct {
+		// Prefix limits the rule to objects whose key starts with it, an
+		// empty prefix matches all objects in the bucket.
+		Prefix string `json:"prefix"`
+
+		// ExpirationDays is the number of days after its creation that an
+		// object is deleted.
+		ExpirationDays uint64 `json:"expirationDays"`
 	}
 
 	CreateBucketOptions struct {
 		Policy BucketPolicy
 	}
+
+	DeleteBucketOptions struct {
+		// Force deletes all objects within the bucket before deleting the
+		// bucket itself. This isn't atomic, the objects are deleted in
+		// batches and objects that are added to the bucket in the meantime
+		// cause the deletion to fail with ErrBucketNotEmpty.
+		Force bool
+	}
 )
 
 type (
@@ -45,6 +86,10 @@ type (
 	BucketUpdatePolicyRequest struct {
 		Policy BucketPolicy `json:"policy"`
 	}
+
+	BucketUpdateLifecycleRequest struct {
+		Rules []BucketLifecycleRule `json:"rules"`
+	}
 )
 
 var validBucketExp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
@@ -58,5 +103,28 @@ func (req BucketCreateRequest) Validate() error {
 		!validBucketExp.MatchString(req.Name) {
 		return errors.New("the bucket name doesn't comply with the S3 bucket naming convention (https://docs.aws.amazon.com/AmazonS3/latest/userguide/bucketnamingrules.html)")
 	}
+	return req.Policy.Validate()
+}
+
+func (req BucketUpdatePolicyRequest) Validate() error {
+	return req.Policy.Validate()
+}
+
+// Validate returns an error if the policy's redundancy override is invalid.
+func (bp BucketPolicy) Validate() error {
+	if bp.Redundancy != nil {
+		if err := bp.Redundancy.Validate(); err != nil {
+			return fmt.Errorf("invalid redundancy override: %w", err)
+		}
+	}
+	return nil
+}
+
+func (req BucketUpdateLifecycleRequest) Validate() error {
+	for i, rule := range req.Rules {
+		if rule.ExpirationDays == 0 {
+			return fmt.Errorf("rule %d: expiration days must be greater than zero", i)
+		}
+	}
 	return nil
 }
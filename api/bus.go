@@ -20,6 +20,12 @@ var (
 	ErrExplorerDisabled      = errors.New("explorer is disabled")
 )
 
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusDegraded    = "degraded"
+	HealthStatusUnavailable = "unavailable"
+)
+
 type (
 	// ConsensusState holds the current blockheight and whether we are synced or not.
 	ConsensusState struct {
@@ -80,6 +86,28 @@ type (
 		URL     string `json:"url,omitempty"`
 	}
 
+	// DatabaseHealth describes the health of the bus' database.
+	DatabaseHealth struct {
+		Status            string     `json:"status"`
+		Error             string     `json:"error,omitempty"`
+		Latency           DurationMS `json:"latency"`
+		Migrated          bool       `json:"migrated"`
+		PendingMigrations int        `json:"pendingMigrations"`
+
+		// connection pool
+		ConnectionsAvailable bool `json:"connectionsAvailable"`
+		MaxOpenConnections   int  `json:"maxOpenConnections"`
+		OpenConnections      int  `json:"openConnections"`
+		InUse                int  `json:"inUse"`
+		Idle                 int  `json:"idle"`
+	}
+
+	// HealthResponse is the response type for the /health endpoint.
+	HealthResponse struct {
+		Status   string         `json:"status"`
+		Database DatabaseHealth `json:"database"`
+	}
+
 	// HostScanRequest is the request type for the /host/scan endpoint.
 	HostScanRequest struct {
 		Timeout DurationMS `json:"timeout"`
@@ -23,6 +23,7 @@ const (
 
 const (
 	ContractArchivalReasonHostPruned = "hostpruned"
+	ContractArchivalReasonOffboarded = "offboarded"
 	ContractArchivalReasonRemoved    = "removed"
 	ContractArchivalReasonRenewed    = "renewed"
 )
@@ -43,6 +44,18 @@ type (
 		Size     uint64 `json:"size"`
 	}
 
+	// ContractOffboarding describes the progress of migrating the data off of
+	// a contract that is being offboarded. Slabs is the number of slabs that
+	// had sectors stored on the contract when it was offboarded, Migrated is
+	// the number of those slabs that have been repaired or deleted since. Once
+	// all slabs were migrated the contract is archived.
+	ContractOffboarding struct {
+		ContractID types.FileContractID `json:"contractID"`
+		Slabs      uint64               `json:"slabs"`
+		Migrated   uint64               `json:"migrated"`
+		Archived   bool                 `json:"archived"`
+	}
+
 	// ContractMetadata contains all metadata for a contract.
 	ContractMetadata struct {
 		ID      types.FileContractID `json:"id"`
@@ -70,6 +83,15 @@ type (
 		RenewedTo      types.FileContractID `json:"renewedTo,omitempty"`
 	}
 
+	// ContractCapacity describes how much more data a contract is estimated to
+	// be able to store.
+	ContractCapacity struct {
+		ID             types.FileContractID `json:"id"`
+		HostKey        types.PublicKey      `json:"hostKey"`
+		RemainingFunds types.Currency       `json:"remainingFunds"`
+		RemainingBytes uint64               `json:"remainingBytes"`
+	}
+
 	// ContractPrunableData wraps a contract's size information with its id.
 	ContractPrunableData struct {
 		ID types.FileContractID `json:"id"`
@@ -84,6 +106,21 @@ type (
 		Uploads     types.Currency `json:"uploads"`
 	}
 
+	// ContractSpendingResponse is the response type for the
+	// /contract/:id/spending endpoint. It breaks down the spending of a
+	// contract per category, both for the contract itself and for the contract
+	// including all of the contracts it was renewed from.
+	ContractSpendingResponse struct {
+		ContractID    types.FileContractID `json:"contractID"`
+		ContractPrice types.Currency       `json:"contractPrice"`
+		Spending      ContractSpending     `json:"spending"`
+		Total         types.Currency       `json:"total"`
+
+		LineageContractPrice types.Currency   `json:"lineageContractPrice"`
+		LineageSpending      ContractSpending `json:"lineageSpending"`
+		LineageTotal         types.Currency   `json:"lineageTotal"`
+	}
+
 	ContractSpendingRecord struct {
 		ContractSpending
 		ContractID     types.FileContractID `json:"contractID"`
@@ -149,6 +186,24 @@ type (
 		Error        string `json:"error,omitempty"`
 	}
 
+	// ContractReconcileRequest is the request type for the
+	// /contract/:id/reconcile endpoint.
+	ContractReconcileRequest struct {
+		Timeout DurationMS `json:"timeout"`
+	}
+
+	// ContractReconcileResponse is the response type for the
+	// /contract/:id/reconcile endpoint.
+	ContractReconcileResponse struct {
+		// Checked is the number of sectors the host is expected to store
+		// for the contract.
+		Checked uint64 `json:"checked"`
+
+		// Missing contains the roots of the sectors the host no longer
+		// reports, they were flagged as lost.
+		Missing []types.Hash256 `json:"missing,omitempty"`
+	}
+
 	// ContractAcquireRequest is the request type for the /contract/:id/release
 	// endpoint.
 	ContractReleaseRequest struct {
@@ -167,6 +222,24 @@ type (
 	// ContractsArchiveRequest is the request type for the /contracts/archive endpoint.
 	ContractsArchiveRequest = map[types.FileContractID]string
 
+	// ContractsRelinkRequest is the request type for the /contracts/relink
+	// endpoint. It maps the ids of renewed contracts to their renewals.
+	ContractsRelinkRequest = map[types.FileContractID]types.FileContractID
+
+	// ContractsRelinkResponse is the response type for the /contracts/relink
+	// endpoint.
+	ContractsRelinkResponse struct {
+		Relinked uint64 `json:"relinked"`
+	}
+
+	// ContractsCapacityResponse is the response type for the
+	// /contracts/capacity endpoint.
+	ContractsCapacityResponse struct {
+		Contracts          []ContractCapacity `json:"contracts"`
+		RemainingBytes     uint64             `json:"remainingBytes"`
+		EstimatedFreeBytes uint64             `json:"estimatedFreeBytes"`
+	}
+
 	// ContractsPrunableDataResponse is the response type for the
 	// /contracts/prunable endpoint.
 	ContractsPrunableDataResponse struct {
@@ -178,6 +251,15 @@ type (
 	ContractsOpts struct {
 		FilterMode string `json:"filterMode"`
 	}
+
+	// ContractHostIP is the response type for the /contracts/subnet endpoint,
+	// it contains a contract whose host resolves to an IP within the
+	// requested subnet.
+	ContractHostIP struct {
+		ContractID types.FileContractID `json:"contractID"`
+		HostKey    types.PublicKey      `json:"hostKey"`
+		IP         string               `json:"ip"`
+	}
 )
 
 // Total returns the total cost of the contract spending.
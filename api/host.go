@@ -45,6 +45,8 @@ var (
 	ErrUsabilityHostNotAcceptingContracts = errors.New("host is not accepting contracts")
 	ErrUsabilityHostNotCompletingScan     = errors.New("host is not completing scan")
 	ErrUsabilityHostNotAnnounced          = errors.New("host is not announced")
+	ErrUsabilityHostStaleAnnouncement     = errors.New("announcement stale")
+	ErrUsabilityHostRecentlyFailed        = errors.New("recently failed")
 )
 
 type (
@@ -69,6 +71,12 @@ type (
 		KeyIn           []types.PublicKey `json:"keyIn"`
 		MaxLastScan     TimeRFC3339       `json:"maxLastScan"`
 	}
+
+	// HostsVerificationsRequest is the request type for the
+	// /hosts/verifications endpoint.
+	HostsVerificationsRequest struct {
+		Verifications []HostVerification `json:"verifications"`
+	}
 )
 
 type (
@@ -121,6 +129,11 @@ type (
 		PublicKey         types.PublicKey `json:"publicKey"`
 		SiamuxAddr        string          `json:"siamuxAddr"`
 		V2SiamuxAddresses []string        `json:"v2SiamuxAddresses"`
+
+		// RecentlyFailed indicates the host recently failed an interaction,
+		// such hosts remain usable for downloads but are deprioritised for
+		// uploads.
+		RecentlyFailed bool `json:"recentlyFailed"`
 	}
 
 	HostInteractions struct {
@@ -132,8 +145,9 @@ type (
 		Uptime                  time.Duration `json:"uptime"`
 		Downtime                time.Duration `json:"downtime"`
 
-		SuccessfulInteractions float64 `json:"successfulInteractions"`
-		FailedInteractions     float64 `json:"failedInteractions"`
+		SuccessfulInteractions float64   `json:"successfulInteractions"`
+		FailedInteractions     float64   `json:"failedInteractions"`
+		LastFailedInteraction  time.Time `json:"lastFailedInteraction"`
 	}
 
 	HostScan struct {
@@ -145,6 +159,14 @@ type (
 		Timestamp  time.Time            `json:"timestamp"`
 	}
 
+	// HostVerification is the outcome of verifying a sampled sector stored
+	// on a host, an empty error indicates success.
+	HostVerification struct {
+		HostKey   types.PublicKey `json:"hostKey"`
+		Error     string          `json:"error,omitempty"`
+		Timestamp time.Time       `json:"timestamp"`
+	}
+
 	HostPriceTable struct {
 		rhpv3.HostPriceTable
 		Expiry time.Time `json:"expiry"`
@@ -170,6 +192,26 @@ type (
 		UploadErr   string `json:"uploadErr"`
 	}
 
+	// HostGougingCheck is the outcome of a single gouging check, the value and
+	// threshold are formatted the way the check compares them.
+	HostGougingCheck struct {
+		Category  string `json:"category"`
+		Name      string `json:"name"`
+		Value     string `json:"value"`
+		Threshold string `json:"threshold"`
+		Passed    bool   `json:"passed"`
+	}
+
+	// HostGougingReport is the response type for the /host/:hostkey/gouging
+	// endpoint.
+	HostGougingReport struct {
+		HostKey   types.PublicKey      `json:"hostKey"`
+		V2        bool                 `json:"v2"`
+		Gouging   bool                 `json:"gouging"`
+		Breakdown HostGougingBreakdown `json:"breakdown"`
+		Checks    []HostGougingCheck   `json:"checks"`
+	}
+
 	HostScoreBreakdown struct {
 		Age              float64 `json:"age"`
 		Collateral       float64 `json:"collateral"`
@@ -190,6 +232,8 @@ type (
 		NotAcceptingContracts bool `json:"notAcceptingContracts"`
 		NotAnnounced          bool `json:"notAnnounced"`
 		NotCompletingScan     bool `json:"notCompletingScan"`
+		StaleAnnouncement     bool `json:"staleAnnouncement"`
+		RecentlyFailed        bool `json:"recentlyFailed"`
 	}
 )
 
@@ -206,6 +250,15 @@ func (hc HostChecks) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// Addresses returns all addresses the host announced.
+func (h Host) Addresses() []string {
+	var addrs []string
+	if h.NetAddress != "" {
+		addrs = append(addrs, h.NetAddress)
+	}
+	return append(addrs, h.V2SiamuxAddresses...)
+}
+
 func (h Host) Info() HostInfo {
 	return HostInfo{
 		PublicKey:         h.PublicKey,
@@ -291,7 +344,7 @@ func (sb HostScoreBreakdown) Score() float64 {
 }
 
 func (ub HostUsabilityBreakdown) IsUsable() bool {
-	return !ub.Blocked && !ub.Offline && !ub.LowScore && !ub.RedundantIP && !ub.Gouging && !ub.NotAcceptingContracts && !ub.NotAnnounced && !ub.NotCompletingScan
+	return !ub.Blocked && !ub.Offline && !ub.LowScore && !ub.RedundantIP && !ub.Gouging && !ub.NotAcceptingContracts && !ub.NotAnnounced && !ub.NotCompletingScan && !ub.StaleAnnouncement && !ub.RecentlyFailed
 }
 
 func (ub HostUsabilityBreakdown) String() string {
@@ -324,5 +377,11 @@ func (ub HostUsabilityBreakdown) UnusableReasons() []string {
 	if ub.NotCompletingScan {
 		reasons = append(reasons, ErrUsabilityHostNotCompletingScan.Error())
 	}
+	if ub.StaleAnnouncement {
+		reasons = append(reasons, ErrUsabilityHostStaleAnnouncement.Error())
+	}
+	if ub.RecentlyFailed {
+		reasons = append(reasons, ErrUsabilityHostRecentlyFailed.Error())
+	}
 	return reasons
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 
+	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/object"
 )
 
@@ -36,6 +37,7 @@ type (
 		Key           string               `json:"key"`
 		UploadID      string               `json:"uploadID"`
 		CreatedAt     TimeRFC3339          `json:"createdAt"`
+		PinnedHosts   []types.PublicKey    `json:"pinnedHosts,omitempty"`
 	}
 
 	MultipartListPartItem struct {
@@ -45,6 +47,16 @@ type (
 		Size         int64       `json:"size"`
 	}
 
+	// MultipartPartProgressSlice is a slice that was persisted while its
+	// part was being uploaded. The checksum covers the data the slice was
+	// created from, if a retried upload of the part produces the same data the
+	// slice is reused instead of being uploaded again.
+	MultipartPartProgressSlice struct {
+		Index    int              `json:"index"`
+		Checksum types.Hash256    `json:"checksum"`
+		Slice    object.SlabSlice `json:"slice"`
+	}
+
 	MultipartCompletedPart struct {
 		PartNumber int    `json:"partNumber"`
 		ETag       string `json:"eTag"`
@@ -54,6 +66,7 @@ type (
 		DisableClientSideEncryption bool
 		MimeType                    string
 		Metadata                    ObjectUserMetadata
+		PinnedHosts                 []types.PublicKey
 	}
 
 	CompleteMultipartOptions struct {
@@ -77,6 +90,16 @@ type (
 		Slices     []object.SlabSlice `json:"slices"`
 	}
 
+	MultipartAddPartProgressRequest struct {
+		Bucket     string             `json:"bucket"`
+		Key        string             `json:"key"`
+		UploadID   string             `json:"uploadID"`
+		PartNumber int                `json:"partNumber"`
+		Index      int                `json:"index"`
+		Checksum   types.Hash256      `json:"checksum"`
+		Slices     []object.SlabSlice `json:"slices"`
+	}
+
 	MultipartCompleteResponse struct {
 		ETag string `json:"eTag"`
 	}
@@ -95,6 +118,7 @@ type (
 		MimeType                    string             `json:"mimeType"`
 		Metadata                    ObjectUserMetadata `json:"metadata"`
 		DisableClientSideEncryption bool               `json:"disableClientSideEncryption"`
+		PinnedHosts                 []types.PublicKey  `json:"pinnedHosts,omitempty"`
 	}
 
 	MultipartCreateResponse struct {
@@ -115,6 +139,17 @@ type (
 		Parts      []MultipartListPartItem `json:"parts"`
 	}
 
+	MultipartPartProgressRequest struct {
+		Bucket     string `json:"bucket"`
+		Key        string `json:"key"`
+		UploadID   string `json:"uploadID"`
+		PartNumber int    `json:"partNumber"`
+	}
+
+	MultipartPartProgressResponse struct {
+		Slices []MultipartPartProgressSlice `json:"slices"`
+	}
+
 	MultipartListUploadsRequest struct {
 		Bucket         string `json:"bucket"`
 		Prefix         string `json:"prefix"`
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -9,29 +11,180 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/object"
 )
 
 const (
 	ObjectMetadataPrefix = "X-Sia-Meta-"
 
+	// ObjectEncryptionKeyHeader is the header used to provide the hex encoded
+	// 32-byte customer key an object is encrypted with.
+	ObjectEncryptionKeyHeader = "X-Sia-Encryption-Key"
+
+	// ObjectUploadIDHeader is the header used to return the id of an
+	// asynchronously persisted upload.
+	ObjectUploadIDHeader = "X-Sia-Upload-ID"
+
+	// ObjectUploadPendingHeader is set on uploads that were buffered because
+	// there weren't enough hosts, their data isn't on hosts yet.
+	ObjectUploadPendingHeader = "X-Sia-Upload-Pending"
+
+	// MaxIdempotencyKeyLength is the maximum length of the idempotency key
+	// of an AddObjectRequest.
+	MaxIdempotencyKeyLength = 255
+
+	// MaxContentDispositionLength is the maximum length of an object's
+	// content disposition, it matches the size of the database column.
+	MaxContentDispositionLength = 255
+
+	// MaxObjectsNoSlabsLimit is the maximum number of objects returned by a
+	// single request to the /bus/objects/noslabs endpoint.
+	MaxObjectsNoSlabsLimit = 1000
+
+	// MaxObjectEventsLimit is the maximum number of events returned by a
+	// single request to the /bus/objects/events endpoint.
+	MaxObjectEventsLimit = 1000
+
+	// MaxObjectsMissingChecksumLimit is the maximum number of objects
+	// returned by a single request to the /bus/objects/checksums/missing
+	// endpoint.
+	MaxObjectsMissingChecksumLimit = 1000
+
+	// ObjectEventCreate, ObjectEventUpdate, ObjectEventDelete and
+	// ObjectEventRename are the operations recorded in the object event log.
+	ObjectEventCreate = "create"
+	ObjectEventUpdate = "update"
+	ObjectEventDelete = "delete"
+	ObjectEventRename = "rename"
+
+	// CopyPolicyOverwrite causes a copy to overwrite an existing destination
+	// object.
+	CopyPolicyOverwrite = "overwrite"
+
+	// CopyPolicySkip causes a copy to be skipped if the destination object
+	// already exists.
+	CopyPolicySkip = "skip"
+
+	// CopyPolicyFail causes a copy to fail with ErrObjectExists if the
+	// destination object already exists.
+	CopyPolicyFail = "fail"
+
 	ObjectsRenameModeSingle = "single"
 	ObjectsRenameModeMulti  = "multi"
 
-	ObjectSortByHealth = "health"
-	ObjectSortByName   = "name"
-	ObjectSortBySize   = "size"
+	ObjectSortByHealth  = "health"
+	ObjectSortByModTime = "modtime"
+	ObjectSortByName    = "name"
+	ObjectSortBySize    = "size"
 
 	SortDirAsc  = "asc"
 	SortDirDesc = "desc"
+
+	DownloadModeCost  = "cost"
+	DownloadModeSpeed = "speed"
+
+	// UploadDurabilitySync causes an upload to return after the object was
+	// persisted by the bus.
+	UploadDurabilitySync = "sync"
+
+	// UploadDurabilityAsync causes an upload to return as soon as its data
+	// was uploaded to the hosts, the object is persisted in the background.
+	// Until it is persisted, the object is lost if the worker shuts down or
+	// the bus remains unreachable.
+	UploadDurabilityAsync = "async"
+
+	UploadPersistenceFailed    = "failed"
+	UploadPersistencePending   = "pending"
+	UploadPersistenceSucceeded = "persisted"
 )
 
 var (
+	// ErrInsufficientPinnedHosts is returned when an upload is pinned to a set
+	// of hosts that can't satisfy the redundancy settings.
+	ErrInsufficientPinnedHosts = errors.New("not enough usable pinned hosts to satisfy the redundancy settings")
+
+	// ErrInsufficientDistinctHosts is returned when the worker can't upload to
+	// enough distinct hosts to satisfy the redundancy settings or the
+	// configured minimum number of distinct hosts.
+	ErrInsufficientDistinctHosts = errors.New("not enough distinct hosts to accept the upload")
+
+	// ErrServerBusy is returned when the worker rejects an upload because it
+	// is under memory pressure, the upload can be retried later.
+	ErrServerBusy = errors.New("server is busy, try again later")
+
+	// ErrInvalidCopyPolicy is returned when a copy specifies an unknown
+	// overwrite policy.
+	ErrInvalidCopyPolicy = errors.New("invalid copy policy, must be 'overwrite', 'skip' or 'fail'")
+
+	// ErrInvalidUploadDurability is returned when an upload specifies an
+	// unknown durability mode.
+	ErrInvalidUploadDurability = errors.New("invalid upload durability, must be 'sync' or 'async'")
+
+	// ErrSlabUploadDeadlineExceeded is returned when an upload fails because
+	// one of its slabs wasn't uploaded within the slab deadline.
+	ErrSlabUploadDeadlineExceeded = errors.New("slab upload deadline exceeded")
+
+	// ErrUploadVerificationFailed is returned when an upload that requested
+	// verification downloads a different object than it uploaded.
+	ErrUploadVerificationFailed = errors.New("upload verification failed")
+
+	// ErrUploadVerificationAsync is returned when an upload requests
+	// verification with the async durability mode, the object might not be
+	// persisted when the upload returns.
+	ErrUploadVerificationAsync = errors.New("upload verification requires the sync durability mode")
+
+	// ErrGlobRequiresScan is returned when objects are listed using a glob
+	// pattern without a static prefix, which requires scanning all objects,
+	// and scanning wasn't explicitly allowed.
+	ErrGlobRequiresScan = errors.New("glob pattern without a static prefix requires a full scan, set allowScan to list anyway")
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key is reused for
+	// a request that differs from the one it was first used for.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+	// ErrIdempotencyKeyTooLong is returned when an idempotency key exceeds
+	// the maximum length.
+	ErrIdempotencyKeyTooLong = fmt.Errorf("idempotency key must not be longer than %d characters", MaxIdempotencyKeyLength)
+
+	// ErrInvalidChecksum is returned when a provided object checksum is not
+	// a hex-encoded SHA-256 hash.
+	ErrInvalidChecksum = errors.New("checksum must be a hex-encoded SHA-256 hash")
+
+	// ErrInvalidContentDisposition is returned when a provided content
+	// disposition is not a valid 'inline' or 'attachment' disposition.
+	ErrInvalidContentDisposition = errors.New("content disposition must be a valid 'inline' or 'attachment' disposition")
+
+	// ErrInvalidGlob is returned when objects are listed using a malformed
+	// glob pattern.
+	ErrInvalidGlob = errors.New("invalid glob pattern")
+
 	// ErrObjectExists is returned when an operation fails because an object
 	// already exists.
 	ErrObjectExists = errors.New("object already exists")
 
+	// ErrObjectKeyCaseConflict is returned when an object is added to a
+	// case-insensitive bucket that contains an object whose key only differs
+	// in case, or when a bucket is made case-insensitive while it contains
+	// such objects.
+	ErrObjectKeyCaseConflict = errors.New("object key conflicts case-insensitively with an existing object")
+
+	// ErrObjectModified is returned when a conditional write fails because
+	// the object was modified after the given time.
+	ErrObjectModified = errors.New("object was modified")
+
+	// ErrUnmodifiedSinceWithoutIdempotencyKey is returned when a conditional
+	// write doesn't set an idempotency key, without one a retry can't tell
+	// whether the object was modified by an earlier attempt.
+	ErrUnmodifiedSinceWithoutIdempotencyKey = errors.New("unmodifiedSince requires an idempotency key")
+
+	// ErrObjectKeyIsDirectory is returned when an object is renamed to a key
+	// that other objects use as a directory, e.g. renaming an object to
+	// '/foo' while '/foo/bar' exists.
+	ErrObjectKeyIsDirectory = errors.New("destination key is a directory of existing objects")
+
 	// ErrObjectNotFound is returned when an object can't be retrieved from the
 	// database.
 	ErrObjectNotFound = errors.New("object not found")
@@ -40,6 +193,10 @@ var (
 	// from the database.
 	ErrObjectCorrupted = errors.New("object corrupted")
 
+	// ErrObjectsNotModified is returned by the bus client when a listing of
+	// objects matches the ETag passed through the If-None-Match option.
+	ErrObjectsNotModified = errors.New("objects not modified")
+
 	// ErrInvalidObjectSortParameters is returned when invalid sort parameters
 	// were provided
 	ErrInvalidObjectSortParameters = errors.New("invalid sort parameters")
@@ -48,6 +205,10 @@ var (
 	// database.
 	ErrSlabNotFound = errors.New("slab not found")
 
+	// ErrSlabKeyCollision is returned when a slab is inserted with the key of
+	// an existing slab that has different redundancy parameters.
+	ErrSlabKeyCollision = errors.New("slab key collision")
+
 	// ErrUnknownSector is returned when a slab is being updated with an unknown
 	// sector.
 	ErrUnknownSector = errors.New("unknown sector")
@@ -58,22 +219,47 @@ var (
 )
 
 type (
+	// GlobPart is a part of a parsed glob pattern. It's either a literal
+	// character, a wildcard or a character class.
+	GlobPart struct {
+		// Literal is the character matched by a literal part.
+		Literal rune
+
+		// Wildcard is '*' for a part that matches any sequence of
+		// characters and '?' for a part that matches a single character.
+		Wildcard rune
+
+		// Class contains the members of a character class, without its
+		// brackets and negation, e.g. "a-z" for '[!a-z]'. Negate indicates
+		// the class matches all characters that aren't members.
+		Class  string
+		Negate bool
+	}
+
 	// Object wraps an object.Object with its metadata.
 	Object struct {
 		Metadata ObjectUserMetadata `json:"metadata,omitempty"`
+		Pinned   bool               `json:"pinned,omitempty"`
+
+		// Pending is set while some of the object's data is still in the
+		// slab buffers and wasn't uploaded to hosts yet.
+		Pending bool `json:"pending,omitempty"`
+
 		ObjectMetadata
 		*object.Object
 	}
 
 	// ObjectMetadata contains various metadata about an object.
 	ObjectMetadata struct {
-		Bucket   string      `json:"bucket"`
-		ETag     string      `json:"eTag,omitempty"`
-		Health   float64     `json:"health"`
-		ModTime  TimeRFC3339 `json:"modTime"`
-		Key      string      `json:"key"`
-		Size     int64       `json:"size"`
-		MimeType string      `json:"mimeType,omitempty"`
+		Bucket             string      `json:"bucket"`
+		Checksum           string      `json:"checksum,omitempty"`
+		ContentDisposition string      `json:"contentDisposition,omitempty"`
+		ETag               string      `json:"eTag,omitempty"`
+		Health             float64     `json:"health"`
+		ModTime            TimeRFC3339 `json:"modTime"`
+		Key                string      `json:"key"`
+		Size               int64       `json:"size"`
+		MimeType           string      `json:"mimeType,omitempty"`
 	}
 
 	// ObjectUserMetadata contains user-defined metadata about an object and can
@@ -93,12 +279,13 @@ type (
 
 	// HeadObjectResponse is the response type for the HEAD /worker/object endpoint.
 	HeadObjectResponse struct {
-		ContentType  string
-		Etag         string
-		LastModified TimeRFC3339
-		Range        *ContentRange
-		Size         int64
-		Metadata     ObjectUserMetadata
+		ContentDisposition string
+		ContentType        string
+		Etag               string
+		LastModified       TimeRFC3339
+		Range              *ContentRange
+		Size               int64
+		Metadata           ObjectUserMetadata
 	}
 
 	// ObjectsResponse is the response type for the /bus/objects endpoint.
@@ -106,6 +293,7 @@ type (
 		HasMore    bool             `json:"hasMore"`
 		NextMarker string           `json:"nextMarker"`
 		Objects    []ObjectMetadata `json:"objects"`
+		ETag       string           `json:"eTag,omitempty"`
 	}
 
 	// ObjectsRemoveRequest is the request type for the /bus/objects/remove endpoint.
@@ -114,6 +302,43 @@ type (
 		Prefix string `json:"prefix"`
 	}
 
+	// ObjectsMoveRequest is the request type for the /bus/objects/move endpoint.
+	ObjectsMoveRequest struct {
+		SourceBucket      string `json:"sourceBucket"`
+		DestinationBucket string `json:"destinationBucket"`
+		Key               string `json:"key"`
+	}
+
+	// ObjectManifestEntry is a single line of the NDJSON manifest returned by
+	// the /bus/bucket/:name/manifest endpoint.
+	ObjectManifestEntry struct {
+		Key      string                 `json:"key"`
+		Size     int64                  `json:"size"`
+		ETag     string                 `json:"eTag,omitempty"`
+		Checksum string                 `json:"checksum,omitempty"`
+		Slabs    []object.EncryptionKey `json:"slabs"`
+	}
+
+	// ObjectManifestTrailer is the last line of a complete manifest, a
+	// manifest without it was cut off.
+	ObjectManifestTrailer struct {
+		Done bool `json:"done"`
+	}
+
+	// ObjectsPinRequest is the request type for the /bus/objects/pin endpoint.
+	ObjectsPinRequest struct {
+		Bucket string `json:"bucket"`
+		Key    string `json:"key"`
+		Pinned bool   `json:"pinned"`
+	}
+
+	// ObjectsRenameResponse is the response type for the /bus/objects/rename
+	// endpoint when renaming multiple objects.
+	ObjectsRenameResponse struct {
+		Renamed     uint64 `json:"renamed"`
+		Overwritten uint64 `json:"overwritten"`
+	}
+
 	// ObjectsRenameRequest is the request type for the /bus/objects/rename endpoint.
 	ObjectsRenameRequest struct {
 		Bucket string `json:"bucket"`
@@ -121,6 +346,104 @@ type (
 		From   string `json:"from"`
 		To     string `json:"to"`
 		Mode   string `json:"mode"`
+
+		// AllowDirectoryCollision allows renaming a single object to a key
+		// that other objects use as a directory, the object then coexists
+		// with the objects in that directory. By default such a rename
+		// fails with ErrObjectKeyIsDirectory.
+		AllowDirectoryCollision bool `json:"allowDirectoryCollision,omitempty"`
+	}
+
+	// ObjectsNoSlabsRequest is the request type for the /bus/objects/noslabs
+	// endpoint.
+	ObjectsNoSlabsRequest struct {
+		Bucket          string `json:"bucket"`
+		Marker          string `json:"marker,omitempty"`
+		Limit           int    `json:"limit,omitempty"`
+		MissingDataOnly bool   `json:"missingDataOnly,omitempty"`
+	}
+
+	// ObjectsNoSlabsResponse is the response type for the /bus/objects/noslabs
+	// endpoint.
+	ObjectsNoSlabsResponse struct {
+		HasMore    bool            `json:"hasMore"`
+		NextMarker string          `json:"nextMarker,omitempty"`
+		Objects    []ObjectNoSlabs `json:"objects"`
+	}
+
+	// ObjectsMissingChecksumRequest is the request type for the
+	// /bus/objects/checksums/missing endpoint. Only objects with an ID greater
+	// than the marker are returned.
+	ObjectsMissingChecksumRequest struct {
+		Marker uint64 `json:"marker"`
+		Limit  int    `json:"limit,omitempty"`
+	}
+
+	// ObjectsMissingChecksumResponse is the response type for the
+	// /bus/objects/checksums/missing endpoint.
+	ObjectsMissingChecksumResponse struct {
+		HasMore    bool                    `json:"hasMore"`
+		NextMarker uint64                  `json:"nextMarker"`
+		Objects    []ObjectMissingChecksum `json:"objects"`
+	}
+
+	// ObjectMissingChecksum is an object that was stored without a checksum.
+	// The ID identifies the object across renames, an object that gets
+	// overwritten is assigned a new ID.
+	ObjectMissingChecksum struct {
+		ID     uint64 `json:"id"`
+		Bucket string `json:"bucket"`
+		Key    string `json:"key"`
+		ETag   string `json:"eTag,omitempty"`
+		Size   int64  `json:"size"`
+	}
+
+	// BackfillObjectChecksumRequest is the request type for the
+	// /bus/objects/checksums/backfill endpoint.
+	BackfillObjectChecksumRequest struct {
+		ID       uint64 `json:"id"`
+		Checksum string `json:"checksum"`
+	}
+
+	// ObjectEventsRequest is the request type for the /bus/objects/events
+	// endpoint. Only events with an ID greater than the marker are returned,
+	// events that were recorded in the last few seconds are held back until
+	// all events with a lower ID are guaranteed to be visible.
+	ObjectEventsRequest struct {
+		Marker uint64 `json:"marker"`
+		Limit  int    `json:"limit,omitempty"`
+	}
+
+	// ObjectEventsResponse is the response type for the /bus/objects/events
+	// endpoint.
+	ObjectEventsResponse struct {
+		Events     []ObjectEvent `json:"events"`
+		HasMore    bool          `json:"hasMore"`
+		NextMarker uint64        `json:"nextMarker"`
+	}
+
+	// ObjectEvent is an entry in the object event log. It captures the key,
+	// ETag and size of the object at the time of the mutation, for renames
+	// RenamedTo contains the new key.
+	ObjectEvent struct {
+		ID        uint64      `json:"id"`
+		Timestamp TimeRFC3339 `json:"timestamp"`
+		Operation string      `json:"operation"`
+		Bucket    string      `json:"bucket"`
+		Key       string      `json:"key"`
+		RenamedTo string      `json:"renamedTo,omitempty"`
+		ETag      string      `json:"eTag,omitempty"`
+		Size      int64       `json:"size"`
+	}
+
+	// ObjectNoSlabs is an object that doesn't reference any slabs. That's
+	// expected for empty objects, objects with a size but no slabs are
+	// missing their data.
+	ObjectNoSlabs struct {
+		Key         string      `json:"key"`
+		Size        int64       `json:"size"`
+		ModTime     TimeRFC3339 `json:"modTime"`
+		MissingData bool        `json:"missingData"`
 	}
 
 	ObjectsStatsOpts struct {
@@ -130,6 +453,7 @@ type (
 	// ObjectsStatsResponse is the response type for the /bus/stats/objects endpoint.
 	ObjectsStatsResponse struct {
 		NumObjects                 uint64  `json:"numObjects"`                 // number of objects
+		NumPendingDeletions        uint64  `json:"numPendingDeletions"`        // number of deleted objects that are yet to be cleaned up
 		NumUnfinishedObjects       uint64  `json:"numUnfinishedObjects"`       // number of unfinished objects
 		MinHealth                  float64 `json:"minHealth"`                  // minimum health of all objects
 		TotalObjectsSize           uint64  `json:"totalObjectsSize"`           // size of all objects
@@ -167,24 +491,61 @@ func (o ObjectMetadata) ContentType() string {
 type (
 	// AddObjectOptions is the options type for the bus client.
 	AddObjectOptions struct {
-		ETag     string
-		MimeType string
-		Metadata ObjectUserMetadata
+		Checksum           string
+		ContentDisposition string
+		ETag               string
+		IdempotencyKey     string
+		MimeType           string
+		Metadata           ObjectUserMetadata
+		PinnedHosts        []types.PublicKey
+		UnmodifiedSince    time.Time // requires an idempotency key
 	}
 
 	// AddObjectRequest is the request type for the /bus/object/*key endpoint.
 	AddObjectRequest struct {
-		Bucket   string             `json:"bucket"`
-		Object   object.Object      `json:"object"`
-		ETag     string             `json:"eTag"`
-		MimeType string             `json:"mimeType"`
-		Metadata ObjectUserMetadata `json:"metadata"`
+		Bucket             string             `json:"bucket"`
+		Object             object.Object      `json:"object"`
+		Checksum           string             `json:"checksum,omitempty"`
+		ContentDisposition string             `json:"contentDisposition,omitempty"`
+		ETag               string             `json:"eTag"`
+		MimeType           string             `json:"mimeType"`
+		Metadata           ObjectUserMetadata `json:"metadata"`
+
+		// PinnedHosts restricts the object's slabs to these hosts, the pin is
+		// respected when the slabs are migrated.
+		PinnedHosts []types.PublicKey `json:"pinnedHosts,omitempty"`
+
+		// IdempotencyKey makes retrying the request safe, a request with a
+		// key that was already used for the same request is a no-op.
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
+
+		// UnmodifiedSince makes the write conditional, it fails with
+		// ErrObjectModified if an object with the same key was stored after
+		// the given time. It requires an idempotency key.
+		UnmodifiedSince time.Time `json:"unmodifiedSince,omitempty"`
+	}
+
+	// AddObjectResponse is the response type for the /bus/object/*key
+	// endpoint. If the request was a retry of a request with the same
+	// idempotency key, it contains the ETag recorded for the original request.
+	AddObjectResponse struct {
+		ETag string `json:"eTag"`
 	}
 
 	// CopyObjectOptions is the options type for the bus client.
 	CopyObjectOptions struct {
-		MimeType string
-		Metadata ObjectUserMetadata
+		ContentDisposition string
+		MimeType           string
+		Metadata           ObjectUserMetadata
+		OverwritePolicy    string
+	}
+
+	// CopyObjectResponse is the response type for the /bus/objects/copy
+	// endpoint. If the copy was skipped, it contains the metadata of the
+	// existing destination object.
+	CopyObjectResponse struct {
+		ObjectMetadata
+		Copied bool `json:"copied"`
 	}
 
 	// CopyObjectsRequest is the request type for the /bus/objects/copy endpoint.
@@ -197,6 +558,14 @@ type (
 
 		MimeType string             `json:"mimeType"`
 		Metadata ObjectUserMetadata `json:"metadata"`
+
+		// ContentDisposition overrides the content disposition of the
+		// destination object, if empty the source's disposition is kept.
+		ContentDisposition string `json:"contentDisposition,omitempty"`
+
+		// OverwritePolicy determines what happens if the destination object
+		// already exists, defaults to CopyPolicyOverwrite.
+		OverwritePolicy string `json:"overwritePolicy,omitempty"`
 	}
 
 	HeadObjectOptions struct {
@@ -204,13 +573,18 @@ type (
 	}
 
 	DownloadObjectOptions struct {
-		Range *DownloadRange
+		EncryptionKey *[32]byte // customer key the object was uploaded with
+		Mode          string
+		Range         *DownloadRange
 	}
 
 	GetObjectOptions struct {
 		OnlyMetadata bool
 	}
 
+	// ListObjectOptions is the options type for the bus client. If no bucket
+	// is set, objects are listed across all buckets and the marker is the
+	// object's bucket followed by a slash and its key.
 	ListObjectOptions struct {
 		Bucket            string
 		Delimiter         string
@@ -220,15 +594,53 @@ type (
 		SortDir           string
 		Substring         string
 		SlabEncryptionKey object.EncryptionKey
+
+		// Glob restricts the listing to objects whose key matches the given
+		// glob pattern, see ParseGlob for the supported syntax. Patterns
+		// without a static prefix are only allowed if AllowScan is set,
+		// since they require scanning all objects.
+		Glob      string
+		AllowScan bool
+
+		// MinHealth and MaxHealth restrict the listing to objects whose
+		// worst slab health falls within the given range. If either is set
+		// and no sorting is specified, objects are sorted by health in
+		// ascending order.
+		MinHealth *float64
+		MaxHealth *float64
+
+		// IfNoneMatch causes the listing to fail with ErrObjectsNotModified
+		// if its ETag matches the given one.
+		IfNoneMatch string
 	}
 
 	// UploadObjectOptions is the options type for the worker client.
 	UploadObjectOptions struct {
-		MinShards     int
-		TotalShards   int
-		ContentLength int64
-		MimeType      string
-		Metadata      ObjectUserMetadata
+		MinShards          int
+		TotalShards        int
+		ContentLength      int64
+		MimeType           string
+		Metadata           ObjectUserMetadata
+		HostKeys           []types.PublicKey // restricts the upload to these hosts
+		ContentDisposition string            // served in the 'Content-Disposition' header on download
+		EncryptionKey      *[32]byte         // customer key to encrypt the object with
+		Durability         string            // either UploadDurabilitySync (default) or UploadDurabilityAsync
+		SlabDeadline       time.Duration     // overrides the worker's slab upload deadline
+		Verify             bool              // downloads the object before persisting it and compares it to its ETag
+		BufferIfNoHosts    bool              // buffers the object instead of failing if there aren't enough hosts, requires packing
+	}
+
+	// DeleteObjectOptions is the options type for the bus client.
+	DeleteObjectOptions struct {
+		// Async marks the object as deleted and returns immediately, its
+		// slices are cleaned up in the background.
+		Async bool
+	}
+
+	// EstimateUploadCostOptions is the options type for the worker client.
+	EstimateUploadCostOptions struct {
+		MinShards   int
+		TotalShards int
 	}
 
 	UploadMultipartUploadPartOptions struct {
@@ -239,6 +651,119 @@ type (
 	}
 )
 
+// Hash returns a hash of the request to add an object with the given key. The
+// idempotency key is not part of the hash, which allows for detecting whether
+// a key is reused for a different request.
+func (req AddObjectRequest) Hash(key string) types.Hash256 {
+	req.IdempotencyKey = ""
+	js, _ := json.Marshal(req) // can't fail
+
+	h := types.NewHasher()
+	h.E.WriteString(key)
+	h.E.WriteBytes(js)
+	return h.Sum()
+}
+
+// Validate returns an error if the request contains an invalid idempotency key,
+// checksum, content disposition or condition.
+func (req AddObjectRequest) Validate() error {
+	if len(req.IdempotencyKey) > MaxIdempotencyKeyLength {
+		return ErrIdempotencyKeyTooLong
+	} else if req.IdempotencyKey == "" && !req.UnmodifiedSince.IsZero() {
+		return ErrUnmodifiedSinceWithoutIdempotencyKey
+	} else if err := ValidateContentDisposition(req.ContentDisposition); err != nil {
+		return err
+	} else if req.Checksum == "" {
+		return nil
+	} else if b, err := hex.DecodeString(req.Checksum); err != nil || len(b) != 32 {
+		return ErrInvalidChecksum
+	}
+	return nil
+}
+
+// Validate returns an error if the request doesn't contain a valid checksum.
+func (req BackfillObjectChecksumRequest) Validate() error {
+	if b, err := hex.DecodeString(req.Checksum); err != nil || len(b) != 32 {
+		return ErrInvalidChecksum
+	}
+	return nil
+}
+
+// ParseGlob parses a glob pattern. A '*' matches any sequence of characters,
+// including slashes, and a '?' matches a single character. Character classes
+// like '[abc]' or '[a-z]' match a single character of the class and are
+// negated by a leading '!' or '^'. A ']' right after the opening bracket is a
+// member of the class, wildcards are matched literally by wrapping them in a
+// class, e.g. '[*]'.
+func ParseGlob(pattern string) (parts []GlobPart, _ error) {
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*', '?':
+			parts = append(parts, GlobPart{Wildcard: r})
+		case '[':
+			var part GlobPart
+			start := i + 1
+			if start < len(runes) && (runes[start] == '!' || runes[start] == '^') {
+				part.Negate = true
+				start++
+			}
+			end := start
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated character class at position %d", ErrInvalidGlob, i)
+			}
+			part.Class = string(runes[start:end])
+			parts = append(parts, part)
+			i = end
+		default:
+			parts = append(parts, GlobPart{Literal: r})
+		}
+	}
+	return parts, nil
+}
+
+// GlobPrefix returns the static prefix of a glob pattern, which is the part
+// of the pattern before its first wildcard or character class. All keys that
+// match the pattern start with it.
+func GlobPrefix(pattern string) (string, error) {
+	parts, err := ParseGlob(pattern)
+	if err != nil {
+		return "", err
+	}
+	var prefix []rune
+	for _, part := range parts {
+		if part.Literal == 0 {
+			break
+		}
+		prefix = append(prefix, part.Literal)
+	}
+	return string(prefix), nil
+}
+
+// ValidateContentDisposition returns an error if the given value is not a
+// valid value for the 'Content-Disposition' header of a response, an empty
+// value is valid.
+func ValidateContentDisposition(cd string) error {
+	if cd == "" {
+		return nil
+	} else if len(cd) > MaxContentDispositionLength {
+		return fmt.Errorf("%w: must not be longer than %d bytes", ErrInvalidContentDisposition, MaxContentDispositionLength)
+	}
+	disposition, _, err := mime.ParseMediaType(cd)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidContentDisposition, err)
+	} else if disposition != "inline" && disposition != "attachment" {
+		return fmt.Errorf("%w: unknown disposition type '%s'", ErrInvalidContentDisposition, disposition)
+	}
+	return nil
+}
+
 func (opts UploadObjectOptions) ApplyValues(values url.Values) {
 	if opts.MinShards != 0 {
 		values.Set("minshards", fmt.Sprint(opts.MinShards))
@@ -249,12 +774,42 @@ func (opts UploadObjectOptions) ApplyValues(values url.Values) {
 	if opts.MimeType != "" {
 		values.Set("mimetype", opts.MimeType)
 	}
+	for _, hk := range opts.HostKeys {
+		values.Add("hostkey", hk.String())
+	}
+	if opts.Durability != "" {
+		values.Set("durability", opts.Durability)
+	}
+	if opts.SlabDeadline != 0 {
+		values.Set("slabdeadline", DurationMS(opts.SlabDeadline).String())
+	}
+	if opts.Verify {
+		values.Set("verify", "true")
+	}
+	if opts.BufferIfNoHosts {
+		values.Set("bufferifnohosts", "true")
+	}
 }
 
 func (opts UploadObjectOptions) ApplyHeaders(h http.Header) {
 	for k, v := range opts.Metadata {
 		h.Set(ObjectMetadataPrefix+k, v)
 	}
+	if opts.EncryptionKey != nil {
+		h.Set(ObjectEncryptionKeyHeader, hex.EncodeToString(opts.EncryptionKey[:]))
+	}
+	if opts.ContentDisposition != "" {
+		h.Set("Content-Disposition", opts.ContentDisposition)
+	}
+}
+
+func (opts EstimateUploadCostOptions) Apply(values url.Values) {
+	if opts.MinShards != 0 {
+		values.Set("minshards", fmt.Sprint(opts.MinShards))
+	}
+	if opts.TotalShards != 0 {
+		values.Set("totalshards", fmt.Sprint(opts.TotalShards))
+	}
 }
 
 func (opts UploadMultipartUploadPartOptions) Apply(values url.Values) {
@@ -268,7 +823,17 @@ func (opts UploadMultipartUploadPartOptions) Apply(values url.Values) {
 		values.Set("totalshards", fmt.Sprint(opts.TotalShards))
 	}
 }
+
+func (opts DownloadObjectOptions) Apply(values url.Values) {
+	if opts.Mode != "" {
+		values.Set("mode", opts.Mode)
+	}
+}
+
 func (opts DownloadObjectOptions) ApplyHeaders(h http.Header) {
+	if opts.EncryptionKey != nil {
+		h.Set(ObjectEncryptionKeyHeader, hex.EncodeToString(opts.EncryptionKey[:]))
+	}
 	if opts.Range != nil {
 		if opts.Range.Length == -1 {
 			h.Set("Range", fmt.Sprintf("bytes=%v-", opts.Range.Offset))
@@ -291,6 +856,12 @@ func (opts HeadObjectOptions) ApplyHeaders(h http.Header) {
 	}
 }
 
+func (opts DeleteObjectOptions) Apply(values url.Values) {
+	if opts.Async {
+		values.Set("async", "true")
+	}
+}
+
 func (opts GetObjectOptions) Apply(values url.Values) {
 	if opts.OnlyMetadata {
 		values.Set("onlymetadata", "true")
@@ -322,6 +893,30 @@ func (opts ListObjectOptions) Apply(values url.Values) {
 	if opts.SlabEncryptionKey != (object.EncryptionKey{}) {
 		values.Set("slabencryptionkey", opts.SlabEncryptionKey.String())
 	}
+	if opts.Glob != "" {
+		values.Set("glob", opts.Glob)
+	}
+	if opts.AllowScan {
+		values.Set("allowscan", "true")
+	}
+	if opts.MinHealth != nil {
+		values.Set("minhealth", fmt.Sprint(*opts.MinHealth))
+	}
+	if opts.MaxHealth != nil {
+		values.Set("maxhealth", fmt.Sprint(*opts.MaxHealth))
+	}
+}
+
+// WeakETag returns a weak ETag for the listing that only changes when the
+// contents of the page change.
+func (resp ObjectsResponse) WeakETag() string {
+	resp.ETag = ""
+	js, err := json.Marshal(resp)
+	if err != nil {
+		panic(err) // should never happen
+	}
+	h := types.HashBytes(js)
+	return fmt.Sprintf("W/%q", hex.EncodeToString(h[:]))
 }
 
 func FormatETag(eTag string) string {
@@ -331,3 +926,19 @@ func FormatETag(eTag string) string {
 func ObjectKeyEscape(key string) string {
 	return url.PathEscape(strings.TrimPrefix(key, "/"))
 }
+
+// ParseEncryptionKeyHeader parses the customer key from the
+// ObjectEncryptionKeyHeader, it returns nil if the header isn't set.
+func ParseEncryptionKeyHeader(h http.Header) (*[32]byte, error) {
+	v := h.Get(ObjectEncryptionKeyHeader)
+	if v == "" {
+		return nil, nil
+	}
+	var key [32]byte
+	if n, err := hex.Decode(key[:], []byte(v)); err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	} else if n != len(key) {
+		return nil, fmt.Errorf("invalid encryption key: expected %d bytes, got %d", len(key), n)
+	}
+	return &key, nil
+}
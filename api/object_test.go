@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		prefix  string
+		parts   []GlobPart
+	}{
+		{"/foo", "/foo", []GlobPart{{Literal: '/'}, {Literal: 'f'}, {Literal: 'o'}, {Literal: 'o'}}},
+		{"/f*", "/f", []GlobPart{{Literal: '/'}, {Literal: 'f'}, {Wildcard: '*'}}},
+		{"/?o", "/", []GlobPart{{Literal: '/'}, {Wildcard: '?'}, {Literal: 'o'}}},
+		{"[a-z]", "", []GlobPart{{Class: "a-z"}}},
+		{"[!]a]", "", []GlobPart{{Class: "]a", Negate: true}}},
+		{"[^*]", "", []GlobPart{{Class: "*", Negate: true}}},
+	}
+	for _, test := range tests {
+		if parts, err := ParseGlob(test.pattern); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(parts, test.parts) {
+			t.Fatalf("%q: unexpected parts %+v", test.pattern, parts)
+		} else if prefix, err := GlobPrefix(test.pattern); err != nil {
+			t.Fatal(err)
+		} else if prefix != test.prefix {
+			t.Fatalf("%q: unexpected prefix %q", test.pattern, prefix)
+		}
+	}
+
+	// assert unterminated classes are rejected
+	for _, pattern := range []string{"[", "/foo[a-z", "[]", "[!]"} {
+		if _, err := ParseGlob(pattern); !errors.Is(err, ErrInvalidGlob) {
+			t.Fatalf("%q: unexpected error %v", pattern, err)
+		}
+	}
+}
+
+func TestValidateContentDisposition(t *testing.T) {
+	long := "attachment; filename=" + strings.Repeat("a", MaxContentDispositionLength)
+	tests := []struct {
+		cd    string
+		valid bool
+	}{
+		{"", true},
+		{"inline", true},
+		{`attachment; filename="foo.txt"`, true},
+		{"form-data", false},
+		{"attachment; filename", false},
+		{long[:MaxContentDispositionLength], true},
+		{long, false},
+	}
+	for _, test := range tests {
+		if err := ValidateContentDisposition(test.cd); test.valid && err != nil {
+			t.Fatalf("%q: unexpected error %v", test.cd, err)
+		} else if !test.valid && !errors.Is(err, ErrInvalidContentDisposition) {
+			t.Fatalf("%q: expected ErrInvalidContentDisposition, got %v", test.cd, err)
+		}
+	}
+}
@@ -601,6 +601,10 @@ func (os ObjectsStatsResponse) PrometheusMetric() (metrics []prometheus.Metric)
 			Name:  "renterd_stats_numobjects",
 			Value: float64(os.NumObjects),
 		},
+		{
+			Name:  "renterd_stats_numpendingdeletions",
+			Value: float64(os.NumPendingDeletions),
+		},
 		{
 			Name:  "renterd_stats_numunfinishedobjects",
 			Value: float64(os.NumUnfinishedObjects),
@@ -627,6 +631,50 @@ func (os ObjectsStatsResponse) PrometheusMetric() (metrics []prometheus.Metric)
 		}}
 }
 
+func (ss SlabsStatsResponse) PrometheusMetric() (metrics []prometheus.Metric) {
+	return []prometheus.Metric{
+		{
+			Name:  "renterd_stats_numhealthyslabs",
+			Value: float64(ss.NumHealthy),
+		},
+		{
+			Name:  "renterd_stats_numdegradedslabs",
+			Value: float64(ss.NumDegraded),
+		},
+		{
+			Name:  "renterd_stats_numatriskslabs",
+			Value: float64(ss.NumAtRisk),
+		},
+		{
+			Name:  "renterd_stats_numlostslabs",
+			Value: float64(ss.NumLost),
+		}}
+}
+
+func (ss SlabBuffersStatsResponse) PrometheusMetric() (metrics []prometheus.Metric) {
+	return []prometheus.Metric{
+		{
+			Name:  "renterd_stats_numslabbuffers",
+			Value: float64(ss.NumBuffers),
+		},
+		{
+			Name:  "renterd_stats_numcompleteslabbuffers",
+			Value: float64(ss.NumCompleteBuffers),
+		},
+		{
+			Name:  "renterd_stats_slabbufferssize",
+			Value: float64(ss.TotalSize),
+		},
+		{
+			Name:  "renterd_stats_slabbuffersdiskusage",
+			Value: float64(ss.DiskUsage),
+		},
+		{
+			Name:  "renterd_stats_slabbuffersmaxdiskusage",
+			Value: float64(ss.MaxDiskUsage),
+		}}
+}
+
 func (w WalletResponse) PrometheusMetric() (metrics []prometheus.Metric) {
 	return []prometheus.Metric{
 		{
@@ -762,6 +810,14 @@ func (m UploadStatsResponse) PrometheusMetric() (metrics []prometheus.Metric) {
 		{
 			Name:  "renterd_worker_stats_numuploaders",
 			Value: float64(m.NumUploaders),
+		},
+		{
+			Name:  "renterd_worker_stats_activeuploaders",
+			Value: float64(m.ActiveUploaders),
+		},
+		{
+			Name:  "renterd_worker_stats_idleuploaders",
+			Value: float64(m.IdleUploaders),
 		}}
 }
 
@@ -167,6 +167,21 @@ type (
 		Authentication S3AuthenticationSettings `json:"authentication"`
 	}
 
+	// SettingsResponse is the response type for the /settings endpoint.
+	SettingsResponse struct {
+		Gouging GougingSettings `json:"gouging"`
+		Pinned  PinnedSettings  `json:"pinned"`
+		Upload  UploadSettings  `json:"upload"`
+	}
+
+	// UpdateSettingsRequest is the request type for the PUT /settings
+	// endpoint. Settings that are omitted remain unchanged.
+	UpdateSettingsRequest struct {
+		Gouging *GougingSettings `json:"gouging,omitempty"`
+		Pinned  *PinnedSettings  `json:"pinned,omitempty"`
+		Upload  *UploadSettings  `json:"upload,omitempty"`
+	}
+
 	// S3AuthenticationSettings contains S3 auth settings.
 	S3AuthenticationSettings struct {
 		V4Keypairs map[string]string `json:"v4Keypairs"`
@@ -199,6 +214,11 @@ func (ps PinnedSettings) Validate() error {
 	if ps.Threshold <= 0 || ps.Threshold >= 1 {
 		return fmt.Errorf("price pin settings must have a threshold between 0 and 1")
 	}
+	for _, pin := range []Pin{ps.GougingSettingsPins.MaxDownload, ps.GougingSettingsPins.MaxStorage, ps.GougingSettingsPins.MaxUpload} {
+		if pin.Value < 0 {
+			return fmt.Errorf("price pin settings must not have negative values")
+		}
+	}
 	return nil
 }
 
@@ -1,10 +1,20 @@
 package api
 
 import (
+	"errors"
+
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/object"
 )
 
+// MaxSectorSamples is the maximum number of sectors that can be sampled in a
+// single request.
+const MaxSectorSamples = 1000
+
+// ErrSlabBufferFull is returned when a partial slab can't be buffered because
+// the slab buffers exceed their max disk usage.
+var ErrSlabBufferFull = errors.New("slab buffers exceed their max disk usage")
+
 type (
 	PackedSlab struct {
 		BufferID      uint                 `json:"bufferID"`
@@ -13,16 +23,46 @@ type (
 	}
 
 	SlabBuffer struct {
-		Complete bool   `json:"complete"` // whether the slab buffer is complete and ready to upload
-		Filename string `json:"filename"` // name of the buffer on disk
-		Size     int64  `json:"size"`     // size of the buffer
-		MaxSize  int64  `json:"maxSize"`  // maximum size of the buffer
-		Locked   bool   `json:"locked"`   // whether the slab buffer is locked for uploading
+		Complete    bool   `json:"complete"`              // whether the slab buffer is complete and ready to upload
+		Compression string `json:"compression,omitempty"` // algorithm used to compress the buffer on disk
+		Filename    string `json:"filename"`              // name of the buffer on disk
+		Size        int64  `json:"size"`                  // size of the buffer
+		SizeOnDisk  int64  `json:"sizeOnDisk"`            // size of the buffer on disk
+		MaxSize     int64  `json:"maxSize"`               // maximum size of the buffer
+		MinShards   uint8  `json:"minShards"`             // min shards of the slab the buffer is packed into
+		TotalShards uint8  `json:"totalShards"`           // total shards of the slab the buffer is packed into
+		Locked      bool   `json:"locked"`                // whether the slab buffer is locked for uploading
+	}
+
+	// SlabBuffersStatsResponse is the response type for the
+	// /stats/slabbuffers endpoint.
+	SlabBuffersStatsResponse struct {
+		NumBuffers         uint64 `json:"numBuffers"`         // number of slab buffers
+		NumCompleteBuffers uint64 `json:"numCompleteBuffers"` // number of slab buffers ready to upload
+		TotalSize          int64  `json:"totalSize"`          // size of the buffered data
+		DiskUsage          int64  `json:"diskUsage"`          // size of the buffers on disk
+		MaxDiskUsage       int64  `json:"maxDiskUsage"`       // max size of the buffers on disk, 0 if unlimited
+	}
+
+	// SectorSample is a sector that was randomly picked for verification
+	// together with the host that is expected to store it.
+	SectorSample struct {
+		Root    types.Hash256   `json:"root"`
+		HostKey types.PublicKey `json:"hostKey"`
+	}
+
+	// SharedSlab is a slab that is referenced by multiple objects, its loss
+	// would affect all of them.
+	SharedSlab struct {
+		EncryptionKey object.EncryptionKey `json:"encryptionKey"`
+		NumObjects    uint64               `json:"numObjects"`
 	}
 
 	UnhealthySlab struct {
 		EncryptionKey object.EncryptionKey `json:"encryptionKey"`
 		Health        float64              `json:"health"`
+		Pinned        bool                 `json:"pinned,omitempty"`
+		PinnedHosts   []types.PublicKey    `json:"pinnedHosts,omitempty"`
 	}
 
 	UploadedPackedSlab struct {
@@ -42,6 +82,13 @@ type (
 		Slabs                        []object.SlabSlice `json:"slabs"`
 	}
 
+	// SlabBuffersFlushResponse is the response type for the /slabbuffers/flush
+	// endpoint.
+	SlabBuffersFlushResponse struct {
+		Flushed int               `json:"flushed"`
+		Errors  map[string]string `json:"errors,omitempty"` // errors by slab key
+	}
+
 	// MigrationSlabsRequest is the request type for the /slabs/migration endpoint.
 	MigrationSlabsRequest struct {
 		HealthCutoff float64 `json:"healthCutoff"`
@@ -63,6 +110,16 @@ type (
 		Slabs []UnhealthySlab `json:"slabs"`
 	}
 
+	// SlabsStatsResponse is the response type for the /stats/slabs endpoint.
+	// Uploaded slabs are counted by health, slabs that are still buffered are
+	// not included.
+	SlabsStatsResponse struct {
+		NumHealthy  uint64 `json:"numHealthy"`  // health >= 1, fully redundant
+		NumDegraded uint64 `json:"numDegraded"` // 0 < health < 1, some redundancy lost
+		NumAtRisk   uint64 `json:"numAtRisk"`   // health == 0, no redundancy left
+		NumLost     uint64 `json:"numLost"`     // health < 0, not recoverable
+	}
+
 	// UpdateSlabRequest is the request type for the PUT /slab/:key endpoint.
 	UpdateSlabRequest []UploadedSector
 )
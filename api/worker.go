@@ -13,6 +13,7 @@ import (
 	rhpv3 "go.sia.tech/core/rhp/v3"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/internal/memory"
+	"go.sia.tech/renterd/object"
 )
 
 var (
@@ -55,6 +56,55 @@ type (
 		LockID uint64 `json:"lockID"`
 	}
 
+	// ActiveUpload describes an in-flight object upload and the effective
+	// settings it uses, settings that weren't overridden by the upload carry
+	// the worker's defaults.
+	ActiveUpload struct {
+		ID        UploadID    `json:"id"`
+		Bucket    string      `json:"bucket"`
+		Key       string      `json:"key"`
+		StartedAt TimeRFC3339 `json:"startedAt"`
+
+		Multipart  bool   `json:"multipart"`
+		UploadID   string `json:"uploadID,omitempty"`
+		PartNumber int    `json:"partNumber,omitempty"`
+
+		BlockHeight        uint64             `json:"blockHeight"`
+		CustomerEncryption bool               `json:"customerEncryption"`
+		EncryptionOffset   uint64             `json:"encryptionOffset"`
+		MimeType           string             `json:"mimeType"`
+		Packing            bool               `json:"packing"`
+		Durability         string             `json:"durability,omitempty"`
+		Redundancy         RedundancySettings `json:"redundancy"`
+
+		MaxOverdrive     uint64     `json:"maxOverdrive"`
+		OverdriveTimeout DurationMS `json:"overdriveTimeout"`
+
+		Latency map[string]UploadPhaseLatency `json:"latency"`
+	}
+
+	// UploadPhaseLatency is the time an upload spent in a phase of its
+	// lifecycle, e.g. acquiring memory or uploading sectors.
+	UploadPhaseLatency struct {
+		Count uint64     `json:"count"`
+		Total DurationMS `json:"total"`
+	}
+
+	// UploadLatencyHistogram describes the distribution of the time uploads
+	// spent in a phase of their lifecycle. Buckets are cumulative.
+	UploadLatencyHistogram struct {
+		Count   uint64                `json:"count"`
+		Avg     DurationMS            `json:"avg"`
+		Buckets []UploadLatencyBucket `json:"buckets"`
+	}
+
+	// UploadLatencyBucket holds the number of observations that took at most
+	// the bucket's upper bound.
+	UploadLatencyBucket struct {
+		LE    DurationMS `json:"le"`
+		Count uint64     `json:"count"`
+	}
+
 	MemoryResponse struct {
 		Download memory.Status `json:"download"`
 		Upload   memory.Status `json:"upload"`
@@ -98,10 +148,21 @@ type (
 		HealthyDownloaders   uint64            `json:"healthyDownloaders"`
 		NumDownloaders       uint64            `json:"numDownloaders"`
 		DownloadersStats     []DownloaderStats `json:"downloadersStats"`
+		ReadRepairs          ReadRepairStats   `json:"readRepairs"`
+	}
+
+	// ReadRepairStats contains statistics about the slabs that were repaired
+	// while they were being downloaded.
+	ReadRepairStats struct {
+		Repaired       uint64 `json:"repaired"`
+		RepairedShards uint64 `json:"repairedShards"`
+		Failed         uint64 `json:"failed"`
+		Skipped        uint64 `json:"skipped"`
 	}
 	DownloaderStats struct {
 		AvgSectorDownloadSpeedMBPS float64         `json:"avgSectorDownloadSpeedMbps"`
 		HostKey                    types.PublicKey `json:"hostKey"`
+		Breaker                    string          `json:"breaker"`
 	}
 
 	// UploadStatsResponse is the response type for the /stats/uploads endpoint.
@@ -110,11 +171,42 @@ type (
 		AvgOverdrivePct        float64         `json:"avgOverdrivePct"`
 		HealthyUploaders       uint64          `json:"healthyUploaders"`
 		NumUploaders           uint64          `json:"numUploaders"`
+		ActiveUploaders        uint64          `json:"activeUploaders"`
+		IdleUploaders          uint64          `json:"idleUploaders"`
+		MemoryPressure         float64         `json:"memoryPressure"`
+		RejectedUploads        uint64          `json:"rejectedUploads"`
 		UploadersStats         []UploaderStats `json:"uploadersStats"`
+
+		PhaseLatencies map[string]UploadLatencyHistogram `json:"phaseLatencies"`
+	}
+	// UploaderHistoryResponse is the response type for the
+	// /stats/uploads/:hostkey endpoint. It contains the recent sector upload
+	// stats of a single host.
+	UploaderHistoryResponse struct {
+		HostKey             types.PublicKey      `json:"hostKey"`
+		ContractID          types.FileContractID `json:"contractID"`
+		ConsecutiveFailures uint64               `json:"consecutiveFailures"`
+		Breaker             string               `json:"breaker"`
+
+		SectorUploadEstimateMS UploaderDataPoints `json:"sectorUploadEstimateMs"`
+		SectorUploadSpeedMBPS  UploaderDataPoints `json:"sectorUploadSpeedMbps"`
+	}
+
+	// UploaderDataPoints contains the data points an uploader tracked,
+	// ordered from oldest to newest, and their percentiles.
+	UploaderDataPoints struct {
+		P50    float64   `json:"p50"`
+		P90    float64   `json:"p90"`
+		P99    float64   `json:"p99"`
+		Values []float64 `json:"values"`
 	}
+
 	UploaderStats struct {
 		HostKey                  types.PublicKey `json:"hostKey"`
 		AvgSectorUploadSpeedMBPS float64         `json:"avgSectorUploadSpeedMbps"`
+		SectorUploadTimeout      DurationMS      `json:"sectorUploadTimeout"`
+		SectorUploadTimeouts     uint64          `json:"sectorUploadTimeouts"`
+		Breaker                  string          `json:"breaker"`
 	}
 
 	// WorkerStateResponse is the response type for the /worker/state endpoint.
@@ -126,6 +218,70 @@ type (
 
 	UploadObjectResponse struct {
 		ETag string `json:"etag"`
+
+		// UploadID is only set for asynchronously persisted uploads and can
+		// be used to look up the persistence status of the object.
+		UploadID *UploadID `json:"uploadID,omitempty"`
+
+		// Pending is set if the object was buffered because there weren't
+		// enough hosts, its data is uploaded to hosts once there are.
+		Pending bool `json:"pending,omitempty"`
+	}
+
+	// UploadPersistenceStatus is the response type for the
+	// /uploads/:id/persistence endpoint.
+	UploadPersistenceStatus struct {
+		ID        UploadID    `json:"id"`
+		Bucket    string      `json:"bucket"`
+		Key       string      `json:"key"`
+		Status    string      `json:"status"`
+		Attempts  int         `json:"attempts"`
+		Error     string      `json:"error,omitempty"`
+		UpdatedAt TimeRFC3339 `json:"updatedAt"`
+	}
+
+	// ObjectRecoverabilityResponse is the response type for the
+	// /objects/recoverability/*key endpoint. An object is recoverable if all
+	// of its slabs are.
+	ObjectRecoverabilityResponse struct {
+		Recoverable bool                 `json:"recoverable"`
+		Slabs       []SlabRecoverability `json:"slabs"`
+	}
+
+	// SlabRecoverability reports how many of a slab's shards are stored with
+	// good contracts on usable hosts. The slab is recoverable if that's at
+	// least its minimum number of shards. Buffered slabs are always
+	// recoverable.
+	SlabRecoverability struct {
+		EncryptionKey   object.EncryptionKey `json:"encryptionKey"`
+		MinShards       uint8                `json:"minShards"`
+		TotalShards     uint8                `json:"totalShards"`
+		AvailableShards uint8                `json:"availableShards"`
+		Recoverable     bool                 `json:"recoverable"`
+	}
+
+	// UploadCostEstimateResponse is the response type for the
+	// /upload/estimate endpoint.
+	UploadCostEstimateResponse struct {
+		Size        uint64           `json:"size"`
+		MinShards   int              `json:"minShards"`
+		TotalShards int              `json:"totalShards"`
+		Sectors     uint64           `json:"sectors"`
+		Storage     types.Currency   `json:"storage"`
+		Upload      types.Currency   `json:"upload"`
+		Total       types.Currency   `json:"total"`
+		Hosts       []HostUploadCost `json:"hosts"`
+	}
+
+	// HostUploadCost is the estimated cost of uploading sectors to a single
+	// host, storage is paid for the remaining duration of the host's contract.
+	HostUploadCost struct {
+		HostKey types.PublicKey `json:"hostKey"`
+		Sectors uint64          `json:"sectors"`
+		Period  uint64          `json:"period"`
+		Storage types.Currency  `json:"storage"`
+		Upload  types.Currency  `json:"upload"`
+		Total   types.Currency  `json:"total"`
 	}
 
 	UploadMultipartUploadPartResponse struct {
@@ -16,6 +16,7 @@ import (
 	"go.sia.tech/renterd/autopilot/scanner"
 	"go.sia.tech/renterd/build"
 	"go.sia.tech/renterd/internal/utils"
+	"go.sia.tech/renterd/object"
 	"go.uber.org/zap"
 )
 
@@ -37,14 +38,19 @@ type (
 	}
 
 	Contractor interface {
+		DiffContracts(context.Context, *contractor.MaintenanceState) (api.ContractsDiffResponse, error)
 		PerformContractMaintenance(context.Context, *contractor.MaintenanceState) (bool, error)
 	}
 
 	Migrator interface {
+		ActiveMigrations() []api.ActiveMigration
+		CancelMigration(key object.EncryptionKey) error
 		Migrate(ctx context.Context)
 		SignalMaintenanceFinished()
 		Shutdown(ctx context.Context) error
 		Status() (bool, time.Time)
+		Throughput() uint64
+		VerificationStats(ctx context.Context) ([]api.HostVerificationStats, error)
 	}
 
 	Pruner interface {
@@ -56,6 +62,7 @@ type (
 	Scanner interface {
 		Scan(ctx context.Context, hs scanner.HostScanner, force bool)
 		Shutdown(ctx context.Context) error
+		Stats() api.ScannerStats
 		Status() (bool, time.Time)
 		UpdateHostsConfig(cfg api.HostsConfig)
 	}
@@ -110,8 +117,13 @@ func New(ctx context.Context, cancel context.CancelCauseFunc, b Bus, c Contracto
 func (ap *Autopilot) Handler() http.Handler {
 	return jape.Mux(map[string]jape.Handler{
 		"POST   /config/evaluate": ap.configEvaluateHandlerPOST,
+		"GET    /contracts/diff":  ap.contractsDiffHandlerGET,
+		"GET    /migrations":      ap.migrationsHandlerGET,
+		"DELETE /migrations/:key": ap.migrationsHandlerDELETE,
+		"GET    /scanner":         ap.scannerHandlerGET,
 		"GET    /state":           ap.stateHandlerGET,
 		"POST   /trigger":         ap.triggerHandlerPOST,
+		"GET    /verification":    ap.verificationHandlerGET,
 	})
 }
 
@@ -151,6 +163,23 @@ func (ap *Autopilot) configEvaluateHandlerPOST(jc jape.Context) {
 	jc.Encode(res)
 }
 
+func (ap *Autopilot) contractsDiffHandlerGET(jc jape.Context) {
+	ctx := jc.Request.Context()
+
+	// build the state using the current config
+	state, err := ap.buildState(ctx)
+	if jc.Check("failed to build state", err) != nil {
+		return
+	}
+
+	// diff the current contract set against the config
+	resp, err := ap.contractor.DiffContracts(ctx, state)
+	if jc.Check("failed to diff contracts", err) != nil {
+		return
+	}
+	jc.Encode(resp)
+}
+
 func (ap *Autopilot) Run() {
 	ap.mu.Lock()
 	if ap.isRunning() {
@@ -494,6 +523,35 @@ func (ap *Autopilot) triggerHandlerPOST(jc jape.Context) {
 	})
 }
 
+func (ap *Autopilot) migrationsHandlerGET(jc jape.Context) {
+	jc.Encode(ap.migrator.ActiveMigrations())
+}
+
+func (ap *Autopilot) migrationsHandlerDELETE(jc jape.Context) {
+	var key object.EncryptionKey
+	if jc.DecodeParam("key", &key) != nil {
+		return
+	}
+	err := ap.migrator.CancelMigration(key)
+	if errors.Is(err, api.ErrMigrationNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	}
+	jc.Check("failed to cancel migration", err)
+}
+
+func (ap *Autopilot) verificationHandlerGET(jc jape.Context) {
+	stats, err := ap.migrator.VerificationStats(jc.Request.Context())
+	if jc.Check("failed to fetch verification stats", err) != nil {
+		return
+	}
+	jc.Encode(stats)
+}
+
+func (ap *Autopilot) scannerHandlerGET(jc jape.Context) {
+	jc.Encode(ap.scanner.Stats())
+}
+
 func (ap *Autopilot) stateHandlerGET(jc jape.Context) {
 	pruning, pLastStart := ap.pruner.Status()
 	migrating, mLastStart := ap.migrator.Status()
@@ -506,14 +564,15 @@ func (ap *Autopilot) stateHandlerGET(jc jape.Context) {
 	}
 
 	jc.Encode(api.AutopilotStateResponse{
-		Enabled:            cfg.Enabled,
-		Migrating:          migrating,
-		MigratingLastStart: api.TimeRFC3339(mLastStart),
-		Pruning:            pruning,
-		PruningLastStart:   api.TimeRFC3339(pLastStart),
-		Scanning:           scanning,
-		ScanningLastStart:  api.TimeRFC3339(sLastStart),
-		UptimeMS:           api.DurationMS(ap.Uptime()),
+		Enabled:             cfg.Enabled,
+		Migrating:           migrating,
+		MigratingLastStart:  api.TimeRFC3339(mLastStart),
+		MigratingThroughput: ap.migrator.Throughput(),
+		Pruning:             pruning,
+		PruningLastStart:    api.TimeRFC3339(pLastStart),
+		Scanning:            scanning,
+		ScanningLastStart:   api.TimeRFC3339(sLastStart),
+		UptimeMS:            api.DurationMS(ap.Uptime()),
 
 		StartTime: api.TimeRFC3339(ap.StartTime()),
 		BuildState: api.BuildState{
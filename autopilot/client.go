@@ -2,9 +2,11 @@ package autopilot
 
 import (
 	"context"
+	"fmt"
 
 	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
 )
 
 // A Client provides methods for interacting with an autopilot.
@@ -33,6 +35,42 @@ func (c *Client) Trigger(forceScan bool) (_ bool, err error) {
 	return resp.Triggered, err
 }
 
+// VerificationStats returns the per-host results of the background sector
+// verification.
+func (c *Client) VerificationStats(ctx context.Context) (stats []api.HostVerificationStats, err error) {
+	err = c.c.WithContext(ctx).GET("/verification", &stats)
+	return
+}
+
+// ScannerStats returns the throughput of the ongoing or most recent host scan
+// and the hosts that are currently being backed off from.
+func (c *Client) ScannerStats(ctx context.Context) (stats api.ScannerStats, err error) {
+	err = c.c.WithContext(ctx).GET("/scanner", &stats)
+	return
+}
+
+// ActiveMigrations returns the slab migrations that are currently in
+// progress.
+func (c *Client) ActiveMigrations(ctx context.Context) (migrations []api.ActiveMigration, err error) {
+	err = c.c.WithContext(ctx).GET("/migrations", &migrations)
+	return
+}
+
+// CancelMigration cancels the in-progress migration of the slab with given
+// key.
+func (c *Client) CancelMigration(ctx context.Context, key object.EncryptionKey) error {
+	return c.c.WithContext(ctx).DELETE(fmt.Sprintf("/migrations/%s", key))
+}
+
+// DiffContracts compares the current contract set with the hosts the
+// autopilot config calls for. It returns the hosts of the contract set that
+// are no longer usable, and why, as well as the usable hosts that aren't part
+// of the contract set yet.
+func (c *Client) DiffContracts(ctx context.Context) (resp api.ContractsDiffResponse, err error) {
+	err = c.c.WithContext(ctx).GET("/contracts/diff", &resp)
+	return
+}
+
 // EvaluateConfig evaluates an autopilot config using the given gouging and
 // redundancy settings.
 func (c *Client) EvaluateConfig(ctx context.Context, cfg api.AutopilotConfig, gs api.GougingSettings, rs api.RedundancySettings) (resp api.ConfigEvaluationResponse, err error) {
@@ -14,12 +14,11 @@ import (
 	"github.com/montanaflynn/stats"
 	"go.sia.tech/core/consensus"
 	rhpv2 "go.sia.tech/core/rhp/v2"
-	rhpv3 "go.sia.tech/core/rhp/v3"
-	rhpv4 "go.sia.tech/core/rhp/v4"
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
 	"go.sia.tech/renterd/alerts"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/contracts"
 	"go.sia.tech/renterd/internal/rhp/v4"
 	"go.sia.tech/renterd/internal/utils"
 	"go.uber.org/zap"
@@ -121,6 +120,7 @@ type (
 		revisionLastBroadcast     map[types.FileContractID]time.Time
 		revisionSubmissionBuffer  uint64
 
+		mu                  sync.Mutex
 		firstRefreshFailure map[types.FileContractID]time.Time
 	}
 
@@ -205,9 +205,9 @@ func (c *Contractor) formContract(ctx *mCtx, hs HostScanner, host api.Host, minI
 	endHeight := ctx.EndHeight(cs.BlockHeight)
 	var expectedStorage uint64
 	if host.IsV2() {
-		expectedStorage = renterFundsToExpectedStorageV2(renterFunds, endHeight-cs.BlockHeight, scan.V2Settings.Prices)
+		expectedStorage = contracts.RenterFundsToExpectedStorageV2(renterFunds, endHeight-cs.BlockHeight, scan.V2Settings.Prices)
 	} else {
-		expectedStorage = renterFundsToExpectedStorage(renterFunds, endHeight-cs.BlockHeight, scan.PriceTable)
+		expectedStorage = contracts.RenterFundsToExpectedStorage(renterFunds, endHeight-cs.BlockHeight, scan.PriceTable)
 	}
 
 	// calculate the host collateral
@@ -238,6 +238,9 @@ func (c *Contractor) formContract(ctx *mCtx, hs HostScanner, host api.Host, minI
 }
 
 func (c *Contractor) pruneContractRefreshFailures(contracts []api.ContractMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	contractMap := make(map[types.FileContractID]struct{})
 	for _, contract := range contracts {
 		contractMap[contract.ID] = struct{}{}
@@ -277,10 +280,10 @@ func (c *Contractor) refreshContract(ctx *mCtx, contract contract, host api.Host
 	var contractPrice types.Currency
 	if host.IsV2() {
 		contractPrice = host.V2Settings.Prices.ContractPrice
-		expectedNewStorage = renterFundsToExpectedStorageV2(renterFunds, contract.EndHeight()-cs.BlockHeight, host.V2Settings.Prices)
+		expectedNewStorage = contracts.RenterFundsToExpectedStorageV2(renterFunds, contract.EndHeight()-cs.BlockHeight, host.V2Settings.Prices)
 	} else {
 		contractPrice = host.PriceTable.ContractPrice
-		expectedNewStorage = renterFundsToExpectedStorage(renterFunds, contract.EndHeight()-cs.BlockHeight, pt)
+		expectedNewStorage = contracts.RenterFundsToExpectedStorage(renterFunds, contract.EndHeight()-cs.BlockHeight, pt)
 	}
 
 	// a refresh should always result in a contract that has enough collateral
@@ -343,9 +346,9 @@ func (c *Contractor) renewContract(ctx *mCtx, contract contract, host api.Host,
 	// calculate the expected new storage
 	var expectedNewStorage uint64
 	if host.IsV2() {
-		expectedNewStorage = renterFundsToExpectedStorageV2(renterFunds, contract.EndHeight()-cs.BlockHeight, host.V2Settings.Prices)
+		expectedNewStorage = contracts.RenterFundsToExpectedStorageV2(renterFunds, contract.EndHeight()-cs.BlockHeight, host.V2Settings.Prices)
 	} else {
-		expectedNewStorage = renterFundsToExpectedStorage(renterFunds, contract.EndHeight()-cs.BlockHeight, pt)
+		expectedNewStorage = contracts.RenterFundsToExpectedStorage(renterFunds, contract.EndHeight()-cs.BlockHeight, pt)
 	}
 
 	// unlike a refresh, a renewal doesn't require a minimum amount of
@@ -472,6 +475,9 @@ func (c *Contractor) shouldArchive(contract contract, bh uint64, n consensus.Net
 }
 
 func (c *Contractor) shouldForgiveFailedRefresh(fcid types.FileContractID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	lastFailure, exists := c.firstRefreshFailure[fcid]
 	if !exists {
 		lastFailure = time.Now()
@@ -666,40 +672,6 @@ func renewFundingEstimate(minRenterFunds, initRenterFunds, remainingRenterFunds
 	return renterFunds
 }
 
-// renterFundsToExpectedStorage returns how much storage a renter is expected to
-// be able to afford given the provided 'renterFunds'.
-func renterFundsToExpectedStorage(renterFunds types.Currency, duration uint64, pt rhpv3.HostPriceTable) uint64 {
-	costPerSector := sectorUploadCost(pt, duration)
-	// handle free storage
-	if costPerSector.IsZero() {
-		costPerSector = types.NewCurrency64(1)
-	}
-	// catch overflow
-	expectedStorage := renterFunds.Div(costPerSector).Mul64(rhpv2.SectorSize)
-	if expectedStorage.Cmp(types.NewCurrency64(math.MaxUint64)) > 0 {
-		expectedStorage = types.NewCurrency64(math.MaxUint64)
-	}
-	return expectedStorage.Big().Uint64()
-}
-
-// renterFundsToExpectedStorageV2 returns how much storage a renter is expected to
-// be able to afford given the provided 'renterFunds'.
-func renterFundsToExpectedStorageV2(renterFunds types.Currency, duration uint64, hp rhpv4.HostPrices) uint64 {
-	sectorUploadCost := hp.RPCWriteSectorCost(rhpv4.SectorSize).RenterCost()
-	sectorStorageCost := hp.RPCAppendSectorsCost(1, duration).RenterCost()
-	costPerSector := sectorUploadCost.Add(sectorStorageCost)
-	// handle free storage
-	if costPerSector.IsZero() {
-		costPerSector = types.NewCurrency64(1)
-	}
-	// catch overflow
-	expectedStorage := renterFunds.Div(costPerSector).Mul64(rhpv4.SectorSize)
-	if expectedStorage.Cmp(types.NewCurrency64(math.MaxUint64)) > 0 {
-		expectedStorage = types.NewCurrency64(math.MaxUint64)
-	}
-	return expectedStorage.Big().Uint64()
-}
-
 // performContractChecks checks existing contracts, renewing/refreshing any that
 // need it and marking contracts that should no longer be used as bad. The
 // host filter is updated to contain all hosts that we keep contracts with. If a
@@ -823,10 +795,13 @@ func performContractChecks(ctx *mCtx, alerter alerts.Alerter, s Database, churn
 			continue // no more checks until host is scanned
 		}
 
-		// check usability
-		if !host.Checks.UsabilityBreakdown.IsUsable() {
+		// check usability, a host that recently failed isn't picked for new
+		// contracts but we don't mark existing contracts as bad to avoid churn
+		ub := host.Checks.UsabilityBreakdown
+		ub.RecentlyFailed = false
+		if !ub.IsUsable() {
 			logger.Debug("unusable host")
-			updateUsability(ctx, host, cm, api.ContractUsabilityBad, host.Checks.UsabilityBreakdown.String())
+			updateUsability(ctx, host, cm, api.ContractUsabilityBad, ub.String())
 			continue
 		}
 
@@ -1066,7 +1041,7 @@ func performHostChecks(ctx *mCtx, bus Database, cs ConsensusStore, logger *zap.S
 		// ignore HostBlockHeight
 		h.host.PriceTable.HostBlockHeight = state.BlockHeight
 		h.host.V2Settings.Prices.TipHeight = state.BlockHeight
-		hc := checkHost(ctx.GougingChecker(state), h, minScore, ctx.Period())
+		hc := checkHost(ctx.GougingChecker(state), h, minScore, ctx.Period(), ctx.AutopilotConfig().Hosts.MaxAnnouncementAge(), ctx.AutopilotConfig().Hosts.RecentFailureCooldown())
 		if err := bus.UpdateHostCheck(ctx, h.host.PublicKey, *hc); err != nil {
 			return fmt.Errorf("failed to update host check for host %v: %w", h.host.PublicKey, err)
 		}
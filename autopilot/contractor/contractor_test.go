@@ -1,6 +1,7 @@
 package contractor
 
 import (
+	"fmt"
 	"math"
 	"testing"
 	"time"
@@ -8,6 +9,8 @@ import (
 	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/gouging"
+	"go.sia.tech/renterd/internal/test"
 	"go.uber.org/zap"
 	"lukechampine.com/frand"
 )
@@ -197,3 +200,188 @@ func TestShouldForgiveFailedRenewal(t *testing.T) {
 		t.Fatal("expected no failures")
 	}
 }
+
+func TestHostContractStatus(t *testing.T) {
+	c := &Contractor{
+		firstRefreshFailure: make(map[types.FileContractID]time.Time),
+	}
+	cfg := api.DefaultAutopilotConfig
+	bh := uint64(100)
+
+	// prepare a usable and an expired contract
+	newContract := func(fcid types.FileContractID, endHeight uint64) contract {
+		return contract{
+			Revision: &api.Revision{
+				ContractID:      fcid,
+				MissedHostValue: types.Siacoins(10),
+				RenterFunds:     types.Siacoins(10),
+			},
+			ContractMetadata: api.ContractMetadata{
+				ID:                 fcid,
+				InitialRenterFunds: types.Siacoins(10),
+				WindowStart:        endHeight,
+			},
+		}
+	}
+	usable := newContract(types.FileContractID{1}, bh+cfg.Contracts.RenewWindow+1)
+	expired := newContract(types.FileContractID{2}, bh-1)
+
+	tests := []struct {
+		ub        api.HostUsabilityBreakdown
+		contracts []contract
+		status    string
+		reasons   int
+	}{
+		{api.HostUsabilityBreakdown{}, []contract{usable}, hostStatusUsableContracted, 0},
+		{api.HostUsabilityBreakdown{}, []contract{expired, usable}, hostStatusUsableContracted, 1},
+		{api.HostUsabilityBreakdown{}, []contract{expired}, hostStatusUsableNoContract, 1},
+		{api.HostUsabilityBreakdown{}, nil, hostStatusUsableNoContract, 0},
+		{api.HostUsabilityBreakdown{Offline: true}, []contract{usable}, hostStatusUnusable, 1},
+		{api.HostUsabilityBreakdown{Offline: true, Gouging: true}, []contract{expired}, hostStatusUnusable, 3},
+	}
+	for i, test := range tests {
+		status, reasons := c.hostContractStatus(cfg, api.HostChecks{UsabilityBreakdown: test.ub}, test.contracts, bh)
+		if status != test.status {
+			t.Fatalf("%d: expected status %v, got %v", i, test.status, status)
+		} else if len(reasons) != test.reasons {
+			t.Fatalf("%d: expected %d reasons, got %v", i, test.reasons, reasons)
+		}
+	}
+}
+
+func TestCheckHostStaleAnnouncement(t *testing.T) {
+	gc := gouging.NewChecker(test.GougingSettings, api.ConsensusState{})
+	h := test.NewHost(test.RandomHostKey(), test.NewHostPriceTable(), test.NewHostSettings())
+	h.LastAnnouncement = time.Now().Add(-2 * time.Hour)
+	sh := newScoredHost(h, api.HostScoreBreakdown{})
+
+	// no max age means announcements never go stale
+	if hc := checkHost(gc, sh, 0, 0, 0, 0); hc.UsabilityBreakdown.StaleAnnouncement {
+		t.Fatal("expected announcement not to be stale")
+	}
+
+	// announcement is within the max age
+	if hc := checkHost(gc, sh, 0, 0, 3*time.Hour, 0); hc.UsabilityBreakdown.StaleAnnouncement {
+		t.Fatal("expected announcement not to be stale")
+	}
+
+	// announcement is older than the max age
+	hc := checkHost(gc, sh, 0, 0, time.Hour, 0)
+	if !hc.UsabilityBreakdown.StaleAnnouncement {
+		t.Fatal("expected announcement to be stale")
+	} else if hc.UsabilityBreakdown.IsUsable() {
+		t.Fatal("expected host to be unusable")
+	} else if hc.UsabilityBreakdown.Offline {
+		t.Fatal("expected host to be online")
+	}
+}
+
+func TestCheckHostRecentlyFailed(t *testing.T) {
+	gc := gouging.NewChecker(test.GougingSettings, api.ConsensusState{})
+	h := test.NewHost(test.RandomHostKey(), test.NewHostPriceTable(), test.NewHostSettings())
+	h.Interactions.LastFailedInteraction = time.Now().Add(-2 * time.Hour)
+	sh := newScoredHost(h, api.HostScoreBreakdown{})
+
+	// no cooldown means failures are not penalized
+	if hc := checkHost(gc, sh, 0, 0, 0, 0); hc.UsabilityBreakdown.RecentlyFailed {
+		t.Fatal("expected host not to be flagged")
+	}
+
+	// failure is past the first half of the cooldown
+	if hc := checkHost(gc, sh, 0, 0, 0, 3*time.Hour); hc.UsabilityBreakdown.RecentlyFailed {
+		t.Fatal("expected host not to be flagged")
+	}
+
+	// failure is within the first half of the cooldown
+	hc := checkHost(gc, sh, 0, 0, 0, 5*time.Hour)
+	if !hc.UsabilityBreakdown.RecentlyFailed {
+		t.Fatal("expected host to be flagged")
+	} else if hc.UsabilityBreakdown.IsUsable() {
+		t.Fatal("expected host to be unusable")
+	} else if reasons := hc.UsabilityBreakdown.UnusableReasons(); len(reasons) != 1 || reasons[0] != api.ErrUsabilityHostRecentlyFailed.Error() {
+		t.Fatal("unexpected reasons", reasons)
+	}
+}
+
+func TestDiffContracts(t *testing.T) {
+	c := &Contractor{
+		firstRefreshFailure: make(map[types.FileContractID]time.Time),
+	}
+	cfg := api.DefaultAutopilotConfig
+	bh := uint64(100)
+
+	newContract := func(hk types.PublicKey, endHeight uint64) contract {
+		fcid := types.FileContractID(frand.Entropy256())
+		return contract{
+			Revision: &api.Revision{
+				ContractID:      fcid,
+				MissedHostValue: types.Siacoins(10),
+				RenterFunds:     types.Siacoins(10),
+			},
+			ContractMetadata: api.ContractMetadata{
+				ID:                 fcid,
+				HostKey:            hk,
+				InitialRenterFunds: types.Siacoins(10),
+				WindowStart:        endHeight,
+			},
+		}
+	}
+	usableEndHeight := bh + cfg.Contracts.RenewWindow + 1
+
+	// prepare hosts
+	usable := types.PublicKey{1}
+	offline := types.PublicKey{2}
+	expired := types.PublicKey{3}
+	uncontracted := types.PublicKey{4}
+	unusable := types.PublicKey{5}
+	recentlyFailed := types.PublicKey{6}
+	noRevision := types.PublicKey{7}
+	unknown := types.PublicKey{8}
+	checks := map[types.PublicKey]api.HostChecks{
+		usable:         {},
+		offline:        {UsabilityBreakdown: api.HostUsabilityBreakdown{Offline: true}},
+		expired:        {},
+		uncontracted:   {},
+		unusable:       {UsabilityBreakdown: api.HostUsabilityBreakdown{Gouging: true}},
+		recentlyFailed: {UsabilityBreakdown: api.HostUsabilityBreakdown{RecentlyFailed: true}},
+		noRevision:     {},
+	}
+
+	// prepare contracts
+	withoutRevision := newContract(noRevision, usableEndHeight)
+	withoutRevision.Revision = nil
+	contracts := []contract{
+		newContract(usable, usableEndHeight),
+		newContract(offline, usableEndHeight),
+		newContract(expired, bh-1),
+		newContract(recentlyFailed, usableEndHeight),
+		withoutRevision,
+		newContract(unknown, usableEndHeight),
+	}
+
+	resp := c.diffContracts(cfg, checks, contracts, bh)
+	if len(resp.Unusable) != 3 {
+		t.Fatalf("expected 3 unusable hosts, got %+v", resp.Unusable)
+	}
+	for i, expected := range []struct {
+		hk     types.PublicKey
+		fcid   types.FileContractID
+		reason string
+	}{
+		{offline, contracts[1].ID, api.ErrUsabilityHostOffline.Error()},
+		{expired, contracts[2].ID, fmt.Sprintf("%v: %v", contracts[2].ID, errContractExpired)},
+		{unknown, contracts[5].ID, api.ErrUsabilityHostNotFound.Error()},
+	} {
+		h := resp.Unusable[i]
+		if h.HostKey != expected.hk {
+			t.Fatalf("%d: expected host %v, got %v", i, expected.hk, h.HostKey)
+		} else if len(h.Contracts) != 1 || h.Contracts[0] != expected.fcid {
+			t.Fatalf("%d: unexpected contracts %v", i, h.Contracts)
+		} else if len(h.Reasons) != 1 || h.Reasons[0] != expected.reason {
+			t.Fatalf("%d: unexpected reasons %v", i, h.Reasons)
+		}
+	}
+	if len(resp.Uncontracted) != 1 || resp.Uncontracted[0] != uncontracted {
+		t.Fatalf("expected %v to be uncontracted, got %v", uncontracted, resp.Uncontracted)
+	}
+}
@@ -0,0 +1,126 @@
+package contractor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+)
+
+// DiffContracts compares the current contract set with the hosts the autopilot
+// config in the given state calls for. It runs the same host and contract
+// checks as contract maintenance without updating any state, which allows for
+// previewing the effect of a config change before the next maintenance.
+func (c *Contractor) DiffContracts(ctx context.Context, state *MaintenanceState) (api.ContractsDiffResponse, error) {
+	mctx := newMaintenanceCtx(ctx, state)
+
+	// fetch consensus state
+	cs, err := c.cs.ConsensusState(ctx)
+	if err != nil {
+		return api.ContractsDiffResponse{}, fmt.Errorf("failed to fetch consensus state: %w", err)
+	}
+
+	// score all hosts
+	hosts, err := c.db.Hosts(ctx, api.HostOptions{})
+	if err != nil {
+		return api.ContractsDiffResponse{}, fmt.Errorf("failed to fetch hosts: %w", err)
+	}
+	var scoredHosts []scoredHost
+	for _, host := range hosts {
+		sb, err := mctx.HostScore(host)
+		if err != nil {
+			continue
+		}
+		scoredHosts = append(scoredHosts, newScoredHost(host, sb))
+	}
+
+	// check all hosts against the config
+	minScore := calculateMinScore(scoredHosts, mctx.WantedContracts(), c.logger)
+	checks := make(map[types.PublicKey]api.HostChecks)
+	for _, h := range scoredHosts {
+		// ignore HostBlockHeight
+		h.host.PriceTable.HostBlockHeight = cs.BlockHeight
+		h.host.V2Settings.Prices.TipHeight = cs.BlockHeight
+		checks[h.host.PublicKey] = *checkHost(mctx.GougingChecker(cs), h, minScore, mctx.Period(), state.AP.Hosts.MaxAnnouncementAge(), state.AP.Hosts.RecentFailureCooldown())
+	}
+
+	// fetch the current contract set
+	contracts, err := activeContracts(ctx, c.db, c.cm, c.logger)
+	if err != nil {
+		return api.ContractsDiffResponse{}, fmt.Errorf("failed to fetch contracts: %w", err)
+	}
+	var set []contract
+	for _, contract := range contracts {
+		if contract.IsGood() {
+			set = append(set, contract)
+		}
+	}
+	return c.diffContracts(state.AP, checks, set, cs.BlockHeight), nil
+}
+
+// diffContracts reports the hosts of the given contracts that are no longer
+// usable, or whose contracts are no longer usable, as well as the usable hosts
+// that none of the contracts are formed with. Just like during maintenance, a
+// host that recently failed is not considered unusable for existing contracts
+// and contracts without a revision are assumed to be usable.
+func (c *Contractor) diffContracts(cfg api.AutopilotConfig, checks map[types.PublicKey]api.HostChecks, contracts []contract, bh uint64) (resp api.ContractsDiffResponse) {
+	// group the contracts by host
+	hostContracts := make(map[types.PublicKey][]contract)
+	for _, contract := range contracts {
+		hostContracts[contract.HostKey] = append(hostContracts[contract.HostKey], contract)
+	}
+
+	for hk, hcs := range hostContracts {
+		var checked []contract
+		var unchecked bool
+		for _, contract := range hcs {
+			if contract.Revision == nil {
+				unchecked = true
+			} else {
+				checked = append(checked, contract)
+			}
+		}
+
+		// a host without checks is unusable
+		hc, ok := checks[hk]
+		if !ok {
+			resp.Unusable = append(resp.Unusable, newContractsDiffHost(hk, hcs, []string{api.ErrUsabilityHostNotFound.Error()}))
+			continue
+		}
+		hc.UsabilityBreakdown.RecentlyFailed = false
+
+		status, reasons := c.hostContractStatus(cfg, hc, checked, bh)
+		if status == hostStatusUnusable || (status == hostStatusUsableNoContract && !unchecked) {
+			resp.Unusable = append(resp.Unusable, newContractsDiffHost(hk, hcs, reasons))
+		}
+	}
+
+	for hk, hc := range checks {
+		if _, ok := hostContracts[hk]; !ok && hc.UsabilityBreakdown.IsUsable() {
+			resp.Uncontracted = append(resp.Uncontracted, hk)
+		}
+	}
+
+	// sort the response for consistency
+	sort.Slice(resp.Unusable, func(i, j int) bool {
+		return bytes.Compare(resp.Unusable[i].HostKey[:], resp.Unusable[j].HostKey[:]) < 0
+	})
+	sort.Slice(resp.Uncontracted, func(i, j int) bool {
+		return bytes.Compare(resp.Uncontracted[i][:], resp.Uncontracted[j][:]) < 0
+	})
+	return
+}
+
+func newContractsDiffHost(hk types.PublicKey, contracts []contract, reasons []string) api.ContractsDiffHost {
+	h := api.ContractsDiffHost{
+		HostKey: hk,
+		Reasons: reasons,
+	}
+	for _, contract := range contracts {
+		h.Contracts = append(h.Contracts, contract.ID)
+	}
+	return h
+}
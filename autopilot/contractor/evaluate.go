@@ -13,7 +13,7 @@ var ErrMissingRequiredFields = errors.New("missing required fields in configurat
 func countUsableHosts(cfg api.AutopilotConfig, cs api.ConsensusState, period uint64, rs api.RedundancySettings, gs api.GougingSettings, hosts []api.Host) (usables uint64) {
 	gc := gouging.NewChecker(gs, cs)
 	for _, host := range hosts {
-		hc := checkHost(gc, scoreHost(host, cfg, gs, rs.Redundancy()), minValidScore, period)
+		hc := checkHost(gc, scoreHost(host, cfg, gs, rs.Redundancy()), minValidScore, period, cfg.Hosts.MaxAnnouncementAge(), cfg.Hosts.RecentFailureCooldown())
 		if hc.UsabilityBreakdown.IsUsable() {
 			usables++
 		}
@@ -38,7 +38,7 @@ func EvaluateConfig(cfg api.AutopilotConfig, cs api.ConsensusState, rs api.Redun
 		// ignore block height
 		hosts[i].PriceTable.HostBlockHeight = cs.BlockHeight
 		hosts[i].V2Settings.Prices.TipHeight = cs.BlockHeight
-		hc := checkHost(gc, scoreHost(hosts[i], cfg, gs, rs.Redundancy()), minValidScore, cfg.Contracts.Period)
+		hc := checkHost(gc, scoreHost(hosts[i], cfg, gs, rs.Redundancy()), minValidScore, cfg.Contracts.Period, cfg.Hosts.MaxAnnouncementAge(), cfg.Hosts.RecentFailureCooldown())
 		if hc.UsabilityBreakdown.IsUsable() {
 			resp.Usable++
 			continue
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/contracts"
 	"go.sia.tech/renterd/internal/gouging"
 )
 
@@ -16,6 +18,20 @@ const (
 	ContractConfirmationDeadline = 18
 )
 
+const (
+	// hostStatusUsableContracted indicates that the host is usable and that
+	// we hold at least one usable contract with it.
+	hostStatusUsableContracted = "usable-contracted"
+
+	// hostStatusUsableNoContract indicates that the host is usable but that
+	// we don't hold a usable contract with it.
+	hostStatusUsableNoContract = "usable-no-contract"
+
+	// hostStatusUnusable indicates that the host is unusable, regardless of
+	// the contracts we hold with it.
+	hostStatusUnusable = "unusable"
+)
+
 var (
 	errContractBeyondV2RequireHeight = errors.New("contract is beyond v2 require height")
 	errContractOutOfCollateral       = errors.New("contract is out of collateral")
@@ -35,6 +51,8 @@ type unusableHostsBreakdown struct {
 	notacceptingcontracts uint64
 	notannounced          uint64
 	notcompletingscan     uint64
+	staleannouncement     uint64
+	recentlyfailed        uint64
 }
 
 func (u *unusableHostsBreakdown) track(ub api.HostUsabilityBreakdown) {
@@ -62,6 +80,12 @@ func (u *unusableHostsBreakdown) track(ub api.HostUsabilityBreakdown) {
 	if ub.NotCompletingScan {
 		u.notcompletingscan++
 	}
+	if ub.StaleAnnouncement {
+		u.staleannouncement++
+	}
+	if ub.RecentlyFailed {
+		u.recentlyfailed++
+	}
 }
 
 func (u *unusableHostsBreakdown) keysAndValues() []interface{} {
@@ -74,6 +98,8 @@ func (u *unusableHostsBreakdown) keysAndValues() []interface{} {
 		"notacceptingcontracts", u.notacceptingcontracts,
 		"notcompletingscan", u.notcompletingscan,
 		"notannounced", u.notannounced,
+		"staleannouncement", u.staleannouncement,
+		"recentlyfailed", u.recentlyfailed,
 	}
 	for i := 0; i < len(values); i += 2 {
 		if values[i+1].(uint64) == 0 {
@@ -124,15 +150,38 @@ func (c *Contractor) isUsableContract(cfg api.AutopilotConfig, contract contract
 	return
 }
 
-func (c contract) IsOutOfFunds() bool {
-	// InitialRenterFunds should never be zero but for legacy reasons we check
-	// and return true should it be the case
-	if c.InitialRenterFunds.IsZero() {
-		return true
+// hostContractStatus combines the outcome of the host checks with the
+// usability of the given contracts, which are expected to be the contracts we
+// currently hold with the host. It distinguishes between hosts we could use
+// and hosts we are currently using. The returned reasons contain the host's
+// unusable reasons followed by the reasons of every contract, prefixed with
+// the contract's id.
+func (c *Contractor) hostContractStatus(cfg api.AutopilotConfig, hc api.HostChecks, contracts []contract, bh uint64) (status string, reasons []string) {
+	reasons = hc.UsabilityBreakdown.UnusableReasons()
+
+	var contracted bool
+	for _, contract := range contracts {
+		usable, _, _, contractReasons := c.isUsableContract(cfg, contract, bh)
+		for _, reason := range contractReasons {
+			reasons = append(reasons, fmt.Sprintf("%v: %v", contract.ID, reason))
+		}
+		contracted = contracted || usable
 	}
+
+	if !hc.UsabilityBreakdown.IsUsable() {
+		status = hostStatusUnusable
+	} else if contracted {
+		status = hostStatusUsableContracted
+	} else {
+		status = hostStatusUsableNoContract
+	}
+	return
+}
+
+func (c contract) IsOutOfFunds() bool {
 	// contract is out of funds when the remaining funds are less than 10% of
 	// the initial funds
-	return c.RenterFunds().Cmp(c.InitialRenterFunds.Div64(10)) <= 0
+	return contracts.IsOutOfFunds(c.RenterFunds(), c.InitialRenterFunds, contracts.DefaultOutOfFundsReserve)
 }
 
 func (c contract) IsOutOfCollateral() bool {
@@ -149,8 +198,12 @@ func isUpForRenewal(cfg api.AutopilotConfig, endHeight, blockHeight uint64) (sho
 	return
 }
 
-// checkHost performs a series of checks on the host.
-func checkHost(gc gouging.Checker, sh scoredHost, minScore float64, period uint64) *api.HostChecks {
+// checkHost performs a series of checks on the host. If maxAnnouncementAge is
+// non-zero, hosts that haven't announced themselves within that window are
+// considered unusable. If recentFailureCooldown is non-zero, hosts that failed
+// an interaction within the first half of the cooldown are considered
+// unusable, for the remainder of the cooldown only their score is penalized.
+func checkHost(gc gouging.Checker, sh scoredHost, minScore float64, period uint64, maxAnnouncementAge, recentFailureCooldown time.Duration) *api.HostChecks {
 	h := sh.host
 
 	// prepare host breakdown fields
@@ -205,6 +258,17 @@ func checkHost(gc gouging.Checker, sh scoredHost, minScore float64, period uint6
 		}
 	}
 
+	// stale announcement check, this is deliberately separate from the online
+	// check since a host might be online without having announced in a while
+	if h.IsAnnounced() && maxAnnouncementAge > 0 && time.Since(h.LastAnnouncement) > maxAnnouncementAge {
+		ub.StaleAnnouncement = true
+	}
+
+	// recent failure check
+	if lf := h.Interactions.LastFailedInteraction; recentFailureCooldown > 0 && !lf.IsZero() && time.Since(lf) < recentFailureCooldown/2 {
+		ub.RecentlyFailed = true
+	}
+
 	return &api.HostChecks{
 		UsabilityBreakdown: ub,
 		GougingBreakdown:   gb,
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
-	rhpv3 "go.sia.tech/core/rhp/v3"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/internal/gouging"
@@ -101,7 +100,7 @@ func hostScore(cfg api.AutopilotConfig, gs api.GougingSettings, h api.Host, expe
 	return api.HostScoreBreakdown{
 		Age:              ageScore(h), // not clamped since values are hardcoded
 		Collateral:       clampScore(collateralScore(uploadSectorCost, maxCollateral, collateral, uint64(allocationPerHost), cCfg.Period)),
-		Interactions:     clampScore(interactionScore(h)),
+		Interactions:     clampScore(interactionScore(h) * recentFailureScore(h, cfg.Hosts.RecentFailureCooldown())),
 		Prices:           clampScore(priceAdjustmentScore(egressPrice, ingressPrice, storagePrice, gs)),
 		StorageRemaining: clampScore(storageRemainingScore(remainingStorage, h.StoredData, allocationPerHost)),
 		Uptime:           clampScore(uptimeScore(h)),
@@ -273,6 +272,22 @@ func interactionScore(h api.Host) float64 {
 	return math.Pow(success/(success+fail), 10)
 }
 
+// recentFailureScore penalizes hosts that recently failed an interaction. The
+// penalty halves the score right after the failure and decays linearly until
+// the cooldown has passed.
+func recentFailureScore(h api.Host, cooldown time.Duration) float64 {
+	if cooldown == 0 || h.Interactions.LastFailedInteraction.IsZero() {
+		return 1
+	}
+	since := time.Since(h.Interactions.LastFailedInteraction)
+	if since >= cooldown {
+		return 1
+	} else if since < 0 {
+		since = 0
+	}
+	return 0.5 + 0.5*float64(since)/float64(cooldown)
+}
+
 func uptimeScore(h api.Host) float64 {
 	secondToLastScanSuccess := h.Interactions.SecondToLastScanSuccess
 	lastScanSuccess := h.Interactions.LastScanSuccess
@@ -358,9 +373,3 @@ func bytesToSectors(bytes uint64) uint64 {
 	}
 	return numSectors
 }
-
-func sectorUploadCost(pt rhpv3.HostPriceTable, duration uint64) types.Currency {
-	asc := pt.BaseCost().Add(pt.AppendSectorCost(duration))
-	uploadSectorCostRHPv3, _ := asc.Total()
-	return uploadSectorCostRHPv3
-}
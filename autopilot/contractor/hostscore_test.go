@@ -290,3 +290,35 @@ func TestCollateralScore(t *testing.T) {
 		t.Errorf("expected %v but got %v", 0, s)
 	}
 }
+
+func TestRecentFailureScore(t *testing.T) {
+	h := test.NewHost(test.RandomHostKey(), test.NewHostPriceTable(), test.NewHostSettings())
+
+	// no failure
+	if s := recentFailureScore(h, time.Hour); s != 1 {
+		t.Fatal("unexpected score", s)
+	}
+
+	// no cooldown
+	h.Interactions.LastFailedInteraction = time.Now()
+	if s := recentFailureScore(h, 0); s != 1 {
+		t.Fatal("unexpected score", s)
+	}
+
+	// failure just happened
+	if s := recentFailureScore(h, time.Hour); s < 0.5 || s > 0.51 {
+		t.Fatal("unexpected score", s)
+	}
+
+	// failure halfway through the cooldown
+	h.Interactions.LastFailedInteraction = time.Now().Add(-30 * time.Minute)
+	if s := recentFailureScore(h, time.Hour); s < 0.75 || s > 0.76 {
+		t.Fatal("unexpected score", s)
+	}
+
+	// cooldown expired
+	h.Interactions.LastFailedInteraction = time.Now().Add(-2 * time.Hour)
+	if s := recentFailureScore(h, time.Hour); s != 1 {
+		t.Fatal("unexpected score", s)
+	}
+}
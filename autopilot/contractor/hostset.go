@@ -45,12 +45,7 @@ func (hs *hostSet) resolveHostIP(ctx context.Context, host api.Host) ([]net.IPAd
 		return resolvedAddresses, nil
 	}
 	// resolve host IPs
-	var hostAddrs []string
-	if host.NetAddress != "" {
-		hostAddrs = append(hostAddrs, host.NetAddress)
-	}
-	hostAddrs = append(hostAddrs, host.V2SiamuxAddresses...)
-	resolvedAddresses, err := utils.ResolveHostIPs(ctx, hostAddrs)
+	resolvedAddresses, err := utils.ResolveHostIPs(ctx, host.Addresses())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,127 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
+)
+
+// errMigrationCancelled is the cause of the context of a migration that was
+// cancelled through the API.
+var errMigrationCancelled = errors.New("migration was cancelled")
+
+type (
+	// activeMigrations keeps track of the slab migrations that are in
+	// progress.
+	activeMigrations struct {
+		mu         sync.Mutex
+		migrations map[string]*activeMigration
+	}
+
+	activeMigration struct {
+		key       object.EncryptionKey
+		health    float64
+		startedAt time.Time
+		cancel    context.CancelCauseFunc
+
+		mu       sync.Mutex
+		migrated int
+		total    int
+	}
+)
+
+func newActiveMigrations() *activeMigrations {
+	return &activeMigrations{
+		migrations: make(map[string]*activeMigration),
+	}
+}
+
+// Track registers a migration for the given slab, the returned context is
+// cancelled when the migration is cancelled. The returned function has to be
+// called when the migration is done.
+func (am *activeMigrations) Track(ctx context.Context, slab api.UnhealthySlab) (context.Context, *activeMigration, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	m := &activeMigration{
+		key:       slab.EncryptionKey,
+		health:    slab.Health,
+		startedAt: time.Now(),
+		cancel:    cancel,
+	}
+
+	am.mu.Lock()
+	am.migrations[slab.EncryptionKey.String()] = m
+	am.mu.Unlock()
+
+	return ctx, m, func() {
+		am.mu.Lock()
+		if am.migrations[m.key.String()] == m {
+			delete(am.migrations, m.key.String())
+		}
+		am.mu.Unlock()
+		cancel(context.Canceled)
+	}
+}
+
+// Cancel cancels the migration of the slab with given key.
+func (am *activeMigrations) Cancel(key object.EncryptionKey) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	m, ok := am.migrations[key.String()]
+	if !ok {
+		return api.ErrMigrationNotFound
+	}
+	m.cancel(errMigrationCancelled)
+	return nil
+}
+
+// Active returns the migrations in progress, sorted by start time.
+func (am *activeMigrations) Active() []api.ActiveMigration {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	migrations := make([]api.ActiveMigration, 0, len(am.migrations))
+	for _, m := range am.migrations {
+		m.mu.Lock()
+		migrations = append(migrations, api.ActiveMigration{
+			SlabKey:        m.key,
+			Health:         m.health,
+			StartedAt:      api.TimeRFC3339(m.startedAt),
+			ShardsMigrated: m.migrated,
+			ShardsTotal:    m.total,
+		})
+		m.mu.Unlock()
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].StartedAt.Std().Before(migrations[j].StartedAt.Std())
+	})
+	return migrations
+}
+
+func (m *activeMigration) setTotal(total int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.total = total
+}
+
+func (m *activeMigration) setMigrated(migrated int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.migrated = migrated
+}
+
+// ActiveMigrations returns the slab migrations that are currently in progress.
+func (m *Migrator) ActiveMigrations() []api.ActiveMigration {
+	return m.activeMigrations.Active()
+}
+
+// CancelMigration cancels the in-progress migration of the slab with given
+// key.
+func (m *Migrator) CancelMigration(key object.EncryptionKey) error {
+	return m.activeMigrations.Cancel(key)
+}
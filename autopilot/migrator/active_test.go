@@ -0,0 +1,54 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
+)
+
+func TestActiveMigrations(t *testing.T) {
+	am := newActiveMigrations()
+	if len(am.Active()) != 0 {
+		t.Fatal("expected no migrations")
+	}
+
+	// track two migrations
+	slab1 := api.UnhealthySlab{EncryptionKey: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted), Health: 0.5}
+	slab2 := api.UnhealthySlab{EncryptionKey: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted), Health: 0.25}
+	ctx1, m1, done1 := am.Track(context.Background(), slab1)
+	ctx2, _, done2 := am.Track(context.Background(), slab2)
+
+	// report progress for the first one
+	m1.setTotal(3)
+	m1.setMigrated(2)
+
+	active := am.Active()
+	if len(active) != 2 {
+		t.Fatal("expected 2 migrations", len(active))
+	} else if active[0].SlabKey.String() != slab1.EncryptionKey.String() || active[1].SlabKey.String() != slab2.EncryptionKey.String() {
+		t.Fatal("unexpected order", active)
+	} else if active[0].Health != 0.5 || active[0].ShardsMigrated != 2 || active[0].ShardsTotal != 3 {
+		t.Fatalf("unexpected migration %+v", active[0])
+	}
+
+	// cancel the second migration
+	if err := am.Cancel(slab2.EncryptionKey); err != nil {
+		t.Fatal(err)
+	} else if !errors.Is(context.Cause(ctx2), errMigrationCancelled) {
+		t.Fatal("expected context to be cancelled", context.Cause(ctx2))
+	} else if ctx1.Err() != nil {
+		t.Fatal("expected context to not be cancelled")
+	}
+
+	// finish both migrations
+	done1()
+	done2()
+	if len(am.Active()) != 0 {
+		t.Fatal("expected no migrations")
+	} else if err := am.Cancel(slab1.EncryptionKey); !errors.Is(err, api.ErrMigrationNotFound) {
+		t.Fatal("expected ErrMigrationNotFound", err)
+	}
+}
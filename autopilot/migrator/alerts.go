@@ -13,6 +13,7 @@ import (
 
 var (
 	alertHealthRefreshID     = alerts.RandomAlertID() // constant until restarted
+	alertLostSectorsID       = alerts.RandomAlertID() // constant until restarted
 	alertMigrationID         = alerts.RandomAlertID() // constant until restarted
 	alertOngoingMigrationsID = alerts.RandomAlertID() // constant until restarted
 )
@@ -0,0 +1,104 @@
+package migrator
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// repairThroughputWindow is the window over which the repair throughput
+	// is computed
+	repairThroughputWindow = time.Minute
+)
+
+type (
+	// repairBudget limits the amount of data that is repaired within a given
+	// interval and keeps track of the repair throughput.
+	repairBudget struct {
+		maxBytes uint64 // 0 means unlimited
+		interval time.Duration
+
+		mu          sync.Mutex
+		windowStart time.Time
+		used        uint64
+		repaired    []repairRecord
+	}
+
+	repairRecord struct {
+		timestamp time.Time
+		bytes     uint64
+	}
+)
+
+func newRepairBudget(maxBytes uint64, interval time.Duration) *repairBudget {
+	return &repairBudget{
+		maxBytes: maxBytes,
+		interval: interval,
+	}
+}
+
+// Acquire blocks until n bytes can be repaired without exceeding the budget of
+// the current interval. A request that exceeds the budget on its own is
+// granted as soon as the budget of an interval is untouched, to avoid
+// blocking forever.
+func (b *repairBudget) Acquire(ctx context.Context, n uint64) error {
+	if b.maxBytes == 0 || b.interval == 0 {
+		return nil
+	}
+
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		if now.Sub(b.windowStart) >= b.interval {
+			b.windowStart = now
+			b.used = 0
+		}
+		if b.used == 0 || b.used+n <= b.maxBytes {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		wait := b.windowStart.Add(b.interval).Sub(now)
+		b.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return context.Cause(ctx)
+		case <-t.C:
+		}
+	}
+}
+
+// Throughput returns the number of bytes repaired per second over the last
+// minute.
+func (b *repairBudget) Throughput() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pruneRecords(time.Now())
+
+	var total uint64
+	for _, r := range b.repaired {
+		total += r.bytes
+	}
+	return total / uint64(repairThroughputWindow.Seconds())
+}
+
+// Track records that n bytes were repaired.
+func (b *repairBudget) Track(n uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.pruneRecords(now)
+	b.repaired = append(b.repaired, repairRecord{timestamp: now, bytes: n})
+}
+
+func (b *repairBudget) pruneRecords(now time.Time) {
+	var i int
+	for i < len(b.repaired) && now.Sub(b.repaired[i].timestamp) > repairThroughputWindow {
+		i++
+	}
+	b.repaired = b.repaired[i:]
+}
@@ -0,0 +1,56 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRepairBudget(t *testing.T) {
+	// unlimited budget never blocks
+	b := newRepairBudget(0, time.Minute)
+	if err := b.Acquire(context.Background(), 1<<40); err != nil {
+		t.Fatal(err)
+	}
+
+	// acquire the whole budget of an interval
+	b = newRepairBudget(100, 100*time.Millisecond)
+	if err := b.Acquire(context.Background(), 60); err != nil {
+		t.Fatal(err)
+	} else if err := b.Acquire(context.Background(), 40); err != nil {
+		t.Fatal(err)
+	}
+
+	// exceeding the budget blocks until the context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected deadline exceeded", err)
+	}
+
+	// or until the next interval starts
+	start := time.Now()
+	if err := b.Acquire(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	} else if time.Since(start) < 50*time.Millisecond {
+		t.Fatal("expected to block until the next interval")
+	}
+
+	// a request that exceeds the budget on its own is granted in an empty
+	// interval
+	time.Sleep(100 * time.Millisecond)
+	if err := b.Acquire(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert throughput is tracked
+	if b.Throughput() != 0 {
+		t.Fatal("expected no throughput")
+	}
+	b.Track(120)
+	b.Track(60)
+	if tp := b.Throughput(); tp != 3 {
+		t.Fatal("unexpected throughput", tp)
+	}
+}
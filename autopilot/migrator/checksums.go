@@ -0,0 +1,200 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/gouging"
+	"go.sia.tech/renterd/object"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// checksumBackfillBatchSize is the number of objects without a checksum
+	// we fetch from the bus at once
+	checksumBackfillBatchSize = 100
+
+	// checksumBackfillFailureTTL is the amount of time an object is skipped
+	// after its checksum failed to be backfilled
+	checksumBackfillFailureTTL = 24 * time.Hour
+)
+
+type (
+	// rateLimitedWriter limits the rate at which data is written to the
+	// underlying writer, which in turn limits the rate at which a download
+	// writing to it progresses.
+	rateLimitedWriter struct {
+		ctx context.Context
+		l   *rate.Limiter
+		w   io.Writer
+	}
+
+	// backfillFailures keeps track of objects whose checksum failed to be
+	// backfilled, those are skipped until their failure expires to avoid
+	// downloading objects that are likely to fail again on every pass.
+	backfillFailures struct {
+		ttl        time.Duration
+		retryAfter map[uint64]time.Time
+	}
+)
+
+func newBackfillFailures(ttl time.Duration) *backfillFailures {
+	return &backfillFailures{
+		ttl:        ttl,
+		retryAfter: make(map[uint64]time.Time),
+	}
+}
+
+// prune removes all failures that expired.
+func (f *backfillFailures) prune(now time.Time) {
+	for id, retryAfter := range f.retryAfter {
+		if !now.Before(retryAfter) {
+			delete(f.retryAfter, id)
+		}
+	}
+}
+
+// record marks the object with the given id as failed.
+func (f *backfillFailures) record(id uint64, now time.Time) {
+	f.retryAfter[id] = now.Add(f.ttl)
+}
+
+// skip returns true if the object with the given id failed recently.
+func (f *backfillFailures) skip(id uint64, now time.Time) bool {
+	retryAfter, ok := f.retryAfter[id]
+	return ok && now.Before(retryAfter)
+}
+
+func newChecksumBackfillLimiter(bytesPerSecond uint64) *rate.Limiter {
+	if bytesPerSecond == 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), rhpv2.SectorSize)
+}
+
+func (w *rateLimitedWriter) Write(p []byte) (n int, err error) {
+	for n < len(p) {
+		chunk := len(p) - n
+		if w.l.Limit() != rate.Inf && chunk > w.l.Burst() {
+			chunk = w.l.Burst()
+		}
+		if err := w.l.WaitN(w.ctx, chunk); err != nil {
+			return n, err
+		}
+		written, err := w.w.Write(p[n : n+chunk])
+		n += written
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (m *Migrator) threadedBackfillChecksums(interval time.Duration, bytesPerSecond uint64) {
+	defer m.wg.Done()
+
+	failures := newBackfillFailures(checksumBackfillFailureTTL)
+	limiter := newChecksumBackfillLimiter(bytesPerSecond)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-m.shutdownCtx.Done():
+			return
+		case <-t.C:
+		}
+
+		if err := m.backfillChecksums(m.shutdownCtx, limiter, failures); err != nil && !errors.Is(err, context.Canceled) {
+			m.logger.Errorw("failed to backfill object checksums", "error", err)
+		}
+	}
+}
+
+// backfillChecksums computes and stores the checksum of every object that was
+// stored without one. Objects are marked as backfilled by storing their
+// checksum, so a backfill that gets interrupted continues where it left off.
+// Objects that fail are skipped until their failure expires.
+func (m *Migrator) backfillChecksums(ctx context.Context, limiter *rate.Limiter, failures *backfillFailures) error {
+	gp, err := m.bus.GougingParams(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't fetch gouging parameters from bus: %w", err)
+	}
+	ctx = gouging.WithChecker(ctx, m.bus, gp)
+
+	failures.prune(time.Now())
+
+	var marker uint64
+	var backfilled, failed, skipped int
+	for {
+		resp, err := m.bus.ObjectsMissingChecksum(ctx, marker, checksumBackfillBatchSize)
+		if err != nil {
+			return fmt.Errorf("couldn't fetch objects missing a checksum: %w", err)
+		} else if len(resp.Objects) == 0 {
+			break
+		}
+
+		hosts, err := m.bus.UsableHosts(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't fetch hosts from bus: %w", err)
+		}
+
+		for _, o := range resp.Objects {
+			if failures.skip(o.ID, time.Now()) {
+				skipped++
+				continue
+			}
+
+			err := m.backfillChecksum(ctx, o, hosts, limiter)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			} else if err != nil {
+				failures.record(o.ID, time.Now())
+				failed++
+				m.logger.Debugw("failed to backfill object checksum", "bucket", o.Bucket, "key", o.Key, "error", err)
+				continue
+			}
+			backfilled++
+		}
+
+		m.logger.Debugw("backfilling object checksums", "backfilled", backfilled, "failed", failed, "skipped", skipped)
+		if !resp.HasMore {
+			break
+		}
+		marker = resp.NextMarker
+	}
+
+	if backfilled > 0 || failed > 0 {
+		m.logger.Infow("finished backfilling object checksums", "backfilled", backfilled, "failed", failed)
+	}
+	return nil
+}
+
+func (m *Migrator) backfillChecksum(ctx context.Context, o api.ObjectMissingChecksum, hosts []api.HostInfo, limiter *rate.Limiter) error {
+	h := sha256.New()
+	if o.Size > 0 {
+		res, err := m.bus.Object(ctx, o.Bucket, o.Key, api.GetObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("couldn't fetch object: %w", err)
+		} else if res.Object == nil {
+			return errors.New("object has no data")
+		} else if res.ETag != o.ETag || res.Size != o.Size {
+			return errors.New("object was modified")
+		} else if res.Object.Key.Type() == object.EncryptionKeyTypeCustomer {
+			return errors.New("object is encrypted with a customer key")
+		}
+
+		w := &rateLimitedWriter{ctx: ctx, l: limiter, w: h}
+		if err := m.downloadManager.DownloadObject(ctx, w, *res.Object, 0, uint64(res.Size), hosts); err != nil {
+			return fmt.Errorf("couldn't download object: %w", err)
+		}
+	}
+	return m.bus.BackfillObjectChecksum(ctx, o.ID, hex.EncodeToString(h.Sum(nil)))
+}
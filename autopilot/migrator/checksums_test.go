@@ -0,0 +1,76 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+	"lukechampine.com/frand"
+)
+
+func TestRateLimitedWriter(t *testing.T) {
+	// prepare a writer that allows 100 bytes per second with a burst of 10
+	var buf bytes.Buffer
+	w := &rateLimitedWriter{
+		ctx: context.Background(),
+		l:   rate.NewLimiter(100, 10),
+		w:   &buf,
+	}
+
+	// assert writes larger than the burst are split up and throttled
+	data := frand.Bytes(30)
+	start := time.Now()
+	if n, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	} else if n != len(data) {
+		t.Fatalf("unexpected number of bytes written, %v != %v", n, len(data))
+	} else if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("unexpected data")
+	} else if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("expected write to be throttled, took %v", elapsed)
+	}
+
+	// assert an unlimited writer isn't throttled
+	w.l = newChecksumBackfillLimiter(0)
+	if _, err := w.Write(frand.Bytes(1 << 20)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the write is interrupted if the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.ctx = ctx
+	w.l = rate.NewLimiter(1, 1)
+	if _, err := w.Write(data); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestBackfillFailures(t *testing.T) {
+	f := newBackfillFailures(time.Hour)
+
+	// assert unknown objects aren't skipped
+	now := time.Now()
+	if f.skip(1, now) {
+		t.Fatal("unexpected skip")
+	}
+
+	// assert failed objects are skipped until their failure expires
+	f.record(1, now)
+	if !f.skip(1, now) || !f.skip(1, now.Add(time.Hour-time.Second)) {
+		t.Fatal("expected object to be skipped")
+	} else if f.skip(1, now.Add(time.Hour)) {
+		t.Fatal("expected object to be retried")
+	}
+
+	// assert only expired failures are pruned
+	f.record(2, now.Add(time.Minute))
+	f.prune(now.Add(time.Hour))
+	if _, ok := f.retryAfter[1]; ok {
+		t.Fatal("expected failure to be pruned")
+	} else if !f.skip(2, now.Add(time.Hour)) {
+		t.Fatal("expected object to be skipped")
+	}
+}
@@ -2,6 +2,7 @@ package migrator
 
 import (
 	"context"
+	"errors"
 	"math"
 	"net"
 	"sort"
@@ -13,6 +14,7 @@ import (
 	"go.sia.tech/renterd/alerts"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/internal/accounts"
+	"go.sia.tech/renterd/internal/breaker"
 	"go.sia.tech/renterd/internal/contracts"
 	"go.sia.tech/renterd/internal/download"
 	"go.sia.tech/renterd/internal/hosts"
@@ -20,6 +22,7 @@ import (
 	"go.sia.tech/renterd/internal/rhp"
 	rhp4 "go.sia.tech/renterd/internal/rhp/v4"
 	"go.sia.tech/renterd/internal/upload"
+	"go.sia.tech/renterd/internal/upload/uploader"
 	"go.sia.tech/renterd/internal/utils"
 	"go.sia.tech/renterd/object"
 	"go.uber.org/zap"
@@ -39,10 +42,12 @@ type (
 	Bus interface {
 		Accounts(context.Context, string) ([]api.Account, error)
 		AddMultipartPart(ctx context.Context, bucket, key, ETag, uploadID string, partNumber int, slices []object.SlabSlice) (err error)
+		AddMultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber, index int, checksum types.Hash256, slices []object.SlabSlice) (err error)
 		AddObject(ctx context.Context, bucket, key string, o object.Object, opts api.AddObjectOptions) error
 		AddPartialSlab(ctx context.Context, data []byte, minShards, totalShards uint8) (slabs []object.SlabSlice, slabBufferMaxSizeSoftReached bool, err error)
 		AddUploadingSectors(ctx context.Context, uID api.UploadID, root []types.Hash256) error
 		AcquireContract(ctx context.Context, fcid types.FileContractID, priority int, d time.Duration) (lockID uint64, err error)
+		BackfillObjectChecksum(ctx context.Context, id uint64, checksum string) error
 		ConsensusState(ctx context.Context) (api.ConsensusState, error)
 		Contracts(ctx context.Context, opts api.ContractsOpts) ([]api.ContractMetadata, error)
 		DeleteHostSector(ctx context.Context, hk types.PublicKey, root types.Hash256) error
@@ -51,12 +56,19 @@ type (
 		FundAccount(ctx context.Context, account rhpv3.Account, fcid types.FileContractID, amount types.Currency) (types.Currency, error)
 		GougingParams(ctx context.Context) (api.GougingParams, error)
 		Host(ctx context.Context, hostKey types.PublicKey) (api.Host, error)
+		HostVerificationStats(ctx context.Context) ([]api.HostVerificationStats, error)
 		KeepaliveContract(ctx context.Context, fcid types.FileContractID, lockID uint64, d time.Duration) (err error)
 		MarkPackedSlabsUploaded(ctx context.Context, slabs []api.UploadedPackedSlab) error
+		MultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber int) ([]api.MultipartPartProgressSlice, error)
+		Object(ctx context.Context, bucket, key string, opts api.GetObjectOptions) (api.Object, error)
 		Objects(ctx context.Context, prefix string, opts api.ListObjectOptions) (resp api.ObjectsResponse, err error)
+		ObjectsMissingChecksum(ctx context.Context, marker uint64, limit int) (api.ObjectsMissingChecksumResponse, error)
+		ReconcileContract(ctx context.Context, contractID types.FileContractID, timeout time.Duration) (api.ContractReconcileResponse, error)
 		RecordContractSpending(ctx context.Context, records []api.ContractSpendingRecord) error
+		RecordHostVerifications(ctx context.Context, verifications []api.HostVerification) error
 		ReleaseContract(ctx context.Context, fcid types.FileContractID, lockID uint64) (err error)
 		RenewedContract(ctx context.Context, renewedFrom types.FileContractID) (api.ContractMetadata, error)
+		SampleSectors(ctx context.Context, n int) ([]api.SectorSample, error)
 		Slab(ctx context.Context, key object.EncryptionKey) (object.Slab, error)
 		TrackUpload(ctx context.Context, uID api.UploadID) error
 		UpdateAccounts(context.Context, []api.Account) error
@@ -73,6 +85,34 @@ type (
 )
 
 type (
+	// Config contains the settings of a Migrator, zero values disable the
+	// respective background task.
+	Config struct {
+		HealthCutoff float64
+		NumThreads   uint64
+
+		DownloadMaxOverdrive     uint64
+		DownloadOverdriveTimeout time.Duration
+		UploadMaxOverdrive       uint64
+		UploadOverdriveTimeout   time.Duration
+
+		// RepairBudget caps the number of bytes that are repaired within
+		// every RepairBudgetInterval.
+		RepairBudget         uint64
+		RepairBudgetInterval time.Duration
+
+		AccountsRefillInterval time.Duration
+
+		VerificationInterval   time.Duration
+		VerificationSampleSize uint64
+
+		ReconciliationInterval   time.Duration
+		ReconciliationSampleSize uint64
+
+		ChecksumBackfillInterval time.Duration
+		ChecksumBackfillRate     uint64
+	}
+
 	Migrator struct {
 		alerts alerts.Alerter
 		bus    Bus
@@ -81,10 +121,12 @@ type (
 		healthCutoff float64
 		numThreads   uint64
 
-		accounts        *accounts.Manager
-		downloadManager *download.Manager
-		uploadManager   *upload.Manager
-		hostManager     hosts.Manager
+		accounts         *accounts.Manager
+		activeMigrations *activeMigrations
+		budget           *repairBudget
+		downloadManager  *download.Manager
+		uploadManager    *upload.Manager
+		hostManager      hosts.Manager
 
 		rhp4Client *rhp4.Client
 
@@ -104,15 +146,18 @@ type (
 	}
 )
 
-func New(ctx context.Context, masterKey [32]byte, alerts alerts.Alerter, ss SlabStore, b Bus, healthCutoff float64, numThreads, downloadMaxOverdrive, uploadMaxOverdrive uint64, downloadOverdriveTimeout, uploadOverdriveTimeout, accountsRefillInterval time.Duration, logger *zap.Logger) (*Migrator, error) {
+func New(ctx context.Context, masterKey [32]byte, alerts alerts.Alerter, ss SlabStore, b Bus, cfg Config, logger *zap.Logger) (*Migrator, error) {
 	logger = logger.Named("migrator")
 	m := &Migrator{
 		alerts: alerts,
 		bus:    b,
 		ss:     ss,
 
-		healthCutoff: healthCutoff,
-		numThreads:   numThreads,
+		activeMigrations: newActiveMigrations(),
+		budget:           newRepairBudget(cfg.RepairBudget, cfg.RepairBudgetInterval),
+
+		healthCutoff: cfg.HealthCutoff,
+		numThreads:   cfg.NumThreads,
 
 		signalConsensusNotSynced:  make(chan struct{}, 1),
 		signalMaintenanceFinished: make(chan struct{}, 1),
@@ -130,7 +175,7 @@ func New(ctx context.Context, masterKey [32]byte, alerts alerts.Alerter, ss Slab
 	uk := mk.DeriveUploadKey()
 
 	// create account manager
-	am, err := accounts.NewManager(ak, "migrator", alerts, m, m, b, b, b, b, accountsRefillInterval, logger)
+	am, err := accounts.NewManager(ak, "migrator", alerts, m, m, b, b, b, b, cfg.AccountsRefillInterval, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -144,8 +189,34 @@ func New(ctx context.Context, masterKey [32]byte, alerts alerts.Alerter, ss Slab
 
 	// create upload & download manager
 	mm := memory.NewManager(math.MaxInt64, logger)
-	m.downloadManager = download.NewManager(ctx, &uk, m.hostManager, mm, b, downloadMaxOverdrive, downloadOverdriveTimeout, logger)
-	m.uploadManager = upload.NewManager(ctx, &uk, m.hostManager, mm, b, b, b, uploadMaxOverdrive, uploadOverdriveTimeout, logger)
+	hb := breaker.New(breaker.DefaultThreshold, breaker.DefaultCooldown)
+	m.downloadManager = download.NewManager(ctx, &uk, hb, m.hostManager, mm, b, object.DefaultErasureBackend, 0, cfg.DownloadMaxOverdrive, cfg.DownloadOverdriveTimeout, logger)
+	m.uploadManager = upload.NewManager(ctx, &uk, hb, m.hostManager, mm, b, b, b, object.DefaultErasureBackend, upload.ManagerConfig{
+		MaxOverdrive:              cfg.UploadMaxOverdrive,
+		CandidateFailureThreshold: uploader.DefaultCandidateFailureThreshold,
+		OverdriveTimeout:          cfg.UploadOverdriveTimeout,
+		StatsRecomputeInterval:    uploader.DefaultStatsRecomputeInterval,
+		SectorUploadTimeoutMin:    uploader.DefaultSectorUploadTimeoutMin,
+		SectorUploadTimeoutMax:    uploader.DefaultSectorUploadTimeoutMax,
+	}, logger)
+
+	// start verifying sampled sectors in the background
+	if cfg.VerificationInterval > 0 && cfg.VerificationSampleSize > 0 {
+		m.wg.Add(1)
+		go m.threadedVerifySectors(cfg.VerificationInterval, cfg.VerificationSampleSize)
+	}
+
+	// start reconciling sampled contracts in the background
+	if cfg.ReconciliationInterval > 0 && cfg.ReconciliationSampleSize > 0 {
+		m.wg.Add(1)
+		go m.threadedReconcileContracts(cfg.ReconciliationInterval, cfg.ReconciliationSampleSize)
+	}
+
+	// start backfilling the checksums of objects that don't have one
+	if cfg.ChecksumBackfillInterval > 0 {
+		m.wg.Add(1)
+		go m.threadedBackfillChecksums(cfg.ChecksumBackfillInterval, cfg.ChecksumBackfillRate)
+	}
 
 	return m, nil
 }
@@ -194,6 +265,12 @@ func (m *Migrator) Status() (bool, time.Time) {
 	return m.migrating, m.migratingLastStart
 }
 
+// Throughput returns the number of bytes repaired per second over the last
+// minute.
+func (m *Migrator) Throughput() uint64 {
+	return m.budget.Throughput()
+}
+
 func (m *Migrator) slabMigrationEstimate(remaining int) time.Duration {
 	// recompute p90
 	m.statsSlabMigrationSpeedMS.Recompute()
@@ -228,7 +305,9 @@ func (m *Migrator) performMigrations(ctx context.Context) {
 			// process jobs
 			for j := range jobs {
 				start := time.Now()
-				err := m.migrateSlab(ctx, j.EncryptionKey)
+				jobCtx, am, done := m.activeMigrations.Track(ctx, j)
+				err := m.migrateSlab(jobCtx, j.EncryptionKey, j.PinnedHosts, am)
+				done()
 				m.statsSlabMigrationSpeedMS.Track(float64(time.Since(start).Milliseconds()))
 				if utils.IsErr(err, api.ErrConsensusNotSynced) {
 					// interrupt migrations if consensus is not synced
@@ -237,7 +316,7 @@ func (m *Migrator) performMigrations(ctx context.Context) {
 					default:
 					}
 					return
-				} else if err != nil {
+				} else if err != nil && !errors.Is(err, errMigrationCancelled) {
 					m.logger.Errorw("migration failed",
 						zap.Float64("health", j.Health),
 						zap.Stringer("slab", j.EncryptionKey))
@@ -288,10 +367,13 @@ func (m *Migrator) performMigrations(ctx context.Context) {
 			toMigrate = append(toMigrate, *slab)
 		}
 
-		// sort the newly added slabs by health
+		// sort the newly added slabs by health, pinned slabs go first
 		newSlabs := toMigrate[len(toMigrate)-len(migrateNewMap):]
 		sort.Slice(newSlabs, func(i, j int) bool {
-			return newSlabs[i].Health < newSlabs[j].Health
+			if newSlabs[i].Health != newSlabs[j].Health {
+				return newSlabs[i].Health < newSlabs[j].Health
+			}
+			return newSlabs[i].Pinned && !newSlabs[j].Pinned
 		})
 	}
 
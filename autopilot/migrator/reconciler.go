@@ -0,0 +1,88 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.sia.tech/renterd/alerts"
+	"go.sia.tech/renterd/api"
+	"lukechampine.com/frand"
+)
+
+const (
+	// contractReconciliationTimeout is the maximum amount of time we wait for
+	// a single contract to be reconciled
+	contractReconciliationTimeout = 5 * time.Minute
+)
+
+func (m *Migrator) threadedReconcileContracts(interval time.Duration, sampleSize uint64) {
+	defer m.wg.Done()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-m.shutdownCtx.Done():
+			return
+		case <-t.C:
+		}
+
+		if err := m.reconcileContracts(m.shutdownCtx, sampleSize); err != nil && !errors.Is(err, context.Canceled) {
+			m.logger.Errorw("failed to reconcile contracts", "error", err)
+		}
+	}
+}
+
+// reconcileContracts compares the sector roots of a random sample of contracts
+// against the roots their hosts report, sectors a host lost are flagged by the
+// bus so the affected slabs get repaired.
+func (m *Migrator) reconcileContracts(ctx context.Context, sampleSize uint64) error {
+	contracts, err := m.bus.Contracts(ctx, api.ContractsOpts{FilterMode: api.ContractFilterModeGood})
+	if err != nil {
+		return err
+	}
+
+	// sample contracts
+	frand.Shuffle(len(contracts), func(i, j int) { contracts[i], contracts[j] = contracts[j], contracts[i] })
+	if uint64(len(contracts)) > sampleSize {
+		contracts = contracts[:sampleSize]
+	}
+
+	var missing int
+	for _, c := range contracts {
+		res, err := m.bus.ReconcileContract(ctx, c.ID, contractReconciliationTimeout)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			m.logger.Debugw("failed to reconcile contract", "fcid", c.ID, "hk", c.HostKey, "error", err)
+			continue
+		}
+
+		if len(res.Missing) > 0 {
+			missing += len(res.Missing)
+			m.alerts.RegisterAlert(ctx, newLostSectorsAlert(c, res))
+		} else {
+			m.alerts.DismissAlerts(ctx, alerts.IDForContract(alertLostSectorsID, c.ID))
+		}
+	}
+	m.logger.Infow("reconciled sampled contracts", "sampled", len(contracts), "missing", missing)
+	return nil
+}
+
+func newLostSectorsAlert(c api.ContractMetadata, res api.ContractReconcileResponse) alerts.Alert {
+	return alerts.Alert{
+		ID:       alerts.IDForContract(alertLostSectorsID, c.ID),
+		Severity: alerts.SeverityWarning,
+		Message:  "Host lost sectors",
+		Data: map[string]interface{}{
+			"contractID": c.ID,
+			"hostKey":    c.HostKey,
+			"checked":    res.Checked,
+			"missing":    len(res.Missing),
+			"hint":       "The host no longer stores sectors it was paid to store, the affected slabs will be repaired.",
+		},
+		Timestamp: time.Now(),
+	}
+}
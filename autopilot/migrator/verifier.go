@@ -0,0 +1,116 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"lukechampine.com/frand"
+)
+
+const (
+	// sectorVerificationTimeout is the maximum amount of time we wait for a
+	// host to return a sampled sector segment
+	sectorVerificationTimeout = time.Minute
+)
+
+// VerificationStats returns the results of the background sector verification
+// per host.
+func (m *Migrator) VerificationStats(ctx context.Context) ([]api.HostVerificationStats, error) {
+	return m.bus.HostVerificationStats(ctx)
+}
+
+func (m *Migrator) threadedVerifySectors(interval time.Duration, sampleSize uint64) {
+	defer m.wg.Done()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-m.shutdownCtx.Done():
+			return
+		case <-t.C:
+		}
+
+		if err := m.verifySectors(m.shutdownCtx, sampleSize); err != nil && !errors.Is(err, context.Canceled) {
+			m.logger.Errorw("failed to verify sectors", "error", err)
+		}
+	}
+}
+
+func (m *Migrator) verifySectors(ctx context.Context, sampleSize uint64) error {
+	samples, err := m.bus.SampleSectors(ctx, int(min(sampleSize, api.MaxSectorSamples)))
+	if err != nil {
+		return err
+	} else if len(samples) == 0 {
+		return nil
+	}
+
+	// we can only verify sectors on hosts we can download from
+	hosts, err := m.bus.UsableHosts(ctx)
+	if err != nil {
+		return err
+	}
+	usable := make(map[types.PublicKey]api.HostInfo)
+	for _, h := range hosts {
+		usable[h.PublicKey] = h
+	}
+
+	// record the outcomes even if verifying got interrupted halfway
+	var verifications []api.HostVerification
+	defer func() {
+		if len(verifications) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := m.bus.RecordHostVerifications(ctx, verifications); err != nil {
+			m.logger.Errorw("failed to record host verifications", "error", err)
+		}
+	}()
+
+	var verified, failed int
+	for _, sample := range samples {
+		hi, ok := usable[sample.HostKey]
+		if !ok {
+			continue
+		}
+
+		// verification downloads count towards the repair budget
+		if err := m.budget.Acquire(ctx, rhpv2.LeafSize); err != nil {
+			return err
+		}
+
+		// download a random segment of the sector, the host has to provide a
+		// valid proof for it to be accepted
+		offset := uint64(frand.Intn(rhpv2.LeavesPerSector)) * rhpv2.LeafSize
+		err := m.verifySector(ctx, hi, sample.Root, offset)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m.budget.Track(rhpv2.LeafSize)
+
+		v := api.HostVerification{HostKey: sample.HostKey, Timestamp: time.Now()}
+		if err != nil {
+			failed++
+			v.Error = err.Error()
+			m.logger.Debugw("sector verification failed", "hk", sample.HostKey, "root", sample.Root, "error", err)
+		} else {
+			verified++
+		}
+		verifications = append(verifications, v)
+	}
+	m.logger.Infow("verified sampled sectors", "sampled", len(samples), "verified", verified, "failed", failed)
+	return nil
+}
+
+func (m *Migrator) verifySector(ctx context.Context, hi api.HostInfo, root types.Hash256, offset uint64) error {
+	ctx, cancel := context.WithTimeout(ctx, sectorVerificationTimeout)
+	defer cancel()
+	return m.hostManager.Downloader(hi).DownloadSector(ctx, io.Discard, root, offset, rhpv2.LeafSize)
+}
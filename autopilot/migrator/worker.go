@@ -2,6 +2,7 @@ package migrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
@@ -16,7 +17,7 @@ import (
 	"go.uber.org/zap"
 )
 
-func (m *Migrator) migrateSlab(ctx context.Context, key object.EncryptionKey) error {
+func (m *Migrator) migrateSlab(ctx context.Context, key object.EncryptionKey, pinnedHosts []types.PublicKey, am *activeMigration) error {
 	// fetch slab
 	slab, err := m.ss.Slab(ctx, key)
 	if err != nil {
@@ -54,8 +55,17 @@ func (m *Migrator) migrateSlab(ctx context.Context, key object.EncryptionKey) er
 		return fmt.Errorf("couldn't fetch contracts from bus: %v", err)
 	}
 
+	// slabs of pinned objects are only migrated to the pinned hosts
+	pinned := make(map[types.PublicKey]struct{})
+	for _, hk := range pinnedHosts {
+		pinned[hk] = struct{}{}
+	}
+
 	var ulHosts []upload.HostInfo
 	for _, c := range contracts {
+		if _, ok := pinned[c.HostKey]; len(pinned) > 0 && !ok {
+			continue
+		}
 		if h, ok := hmap[c.HostKey]; ok {
 			ulHosts = append(ulHosts, upload.HostInfo{
 				HostInfo:            h,
@@ -67,8 +77,11 @@ func (m *Migrator) migrateSlab(ctx context.Context, key object.EncryptionKey) er
 	}
 
 	// migrate the slab and handle alerts
-	err = m.migrate(ctx, slab, dlHosts, ulHosts, up.CurrentHeight)
-	if err != nil && !utils.IsErr(err, api.ErrSlabNotFound) {
+	err = m.migrate(ctx, slab, dlHosts, ulHosts, up.CurrentHeight, am)
+	if err != nil && errors.Is(context.Cause(ctx), errMigrationCancelled) {
+		m.logger.Infow("slab migration cancelled", zap.Stringer("slab", slab.EncryptionKey))
+		return errMigrationCancelled
+	} else if err != nil && !utils.IsErr(err, api.ErrSlabNotFound) {
 		var objects []api.ObjectMetadata
 		if res, err := m.bus.Objects(ctx, "", api.ListObjectOptions{SlabEncryptionKey: slab.EncryptionKey}); err != nil {
 			m.logger.Errorf("failed to list objects for slab key; %v", err)
@@ -90,7 +103,7 @@ func (m *Migrator) migrateSlab(ctx context.Context, key object.EncryptionKey) er
 	return nil
 }
 
-func (m *Migrator) migrate(ctx context.Context, s object.Slab, dlHosts []api.HostInfo, ulHosts []upload.HostInfo, bh uint64) error {
+func (m *Migrator) migrate(ctx context.Context, s object.Slab, dlHosts []api.HostInfo, ulHosts []upload.HostInfo, bh uint64, am *activeMigration) error {
 	// map usable hosts
 	usableHosts := make(map[types.PublicKey]struct{})
 	for _, h := range dlHosts {
@@ -134,6 +147,7 @@ SHARDS:
 	if len(shardIndices) == 0 {
 		return nil
 	}
+	am.setTotal(len(shardIndices))
 
 	// calculate the number of missing shards and take into account hosts for
 	// which we have a contract (so hosts from which we can download)
@@ -155,30 +169,30 @@ SHARDS:
 		return fmt.Errorf("not enough hosts to download unhealthy shard, %d<%d", len(s.Shards)-missingShards, int(s.MinShards))
 	}
 
+	// acquire repair budget for the migration, we account for the same amount
+	// of data as we acquire memory for
+	repairSize := uint64(len(shardIndices)) * rhpv2.SectorSize
+	if err := m.budget.Acquire(ctx, repairSize); err != nil {
+		return fmt.Errorf("failed to acquire repair budget for migration: %w", err)
+	}
+
 	// acquire memory for the migration
-	mem := m.uploadManager.AcquireMemory(ctx, uint64(len(shardIndices))*rhpv2.SectorSize)
+	mem := m.uploadManager.AcquireMemory(ctx, repairSize)
 	if mem == nil {
 		return fmt.Errorf("failed to acquire memory for migration")
 	}
 	defer mem.Release()
 
-	// download the slab
-	shards, err := m.downloadManager.DownloadSlab(ctx, s, dlHosts)
+	// download the healthy shards of the slab
+	shards, err := m.downloadManager.DownloadShards(ctx, s, dlHosts)
 	if err != nil {
 		m.logger.Debugw("slab migration failed",
 			zap.Error(err),
 			zap.Stringer("slab", s.EncryptionKey),
-			zap.Int("numShardsMigrated", len(shards)),
+			zap.Int("numShardsMigrated", len(shardIndices)),
 		)
 		return fmt.Errorf("failed to download slab for migration: %w", err)
 	}
-	s.Encrypt(shards)
-
-	// filter it down to the shards we need to migrate
-	for i, si := range shardIndices {
-		shards[i] = shards[si]
-	}
-	shards = shards[:len(shardIndices)]
 
 	// filter upload contracts to the ones we haven't used yet
 	var allowed []upload.HostInfo
@@ -189,21 +203,26 @@ SHARDS:
 		}
 	}
 
-	// migrate the shards
-	err = m.uploadManager.UploadShards(ctx, s, shardIndices, shards, allowed, bh, mem)
+	// migrate the shards, only the ones we didn't download are regenerated
+	ctx = upload.WithShardProgress(ctx, am.setMigrated)
+	regenerated, err := m.uploadManager.RepairShards(ctx, s, shardIndices, shards, allowed, bh, mem)
 	if err != nil {
 		m.logger.Debugw("slab migration failed",
 			zap.Error(err),
 			zap.Stringer("slab", s.EncryptionKey),
-			zap.Int("numShardsMigrated", len(shards)),
+			zap.Int("numShardsMigrated", len(shardIndices)),
 		)
 		return fmt.Errorf("failed to upload slab for migration: %w", err)
 	}
 
+	// track repaired data
+	m.budget.Track(repairSize)
+
 	// debug log migration result
 	m.logger.Debugw("slab migration succeeded",
 		zap.Stringer("slab", s.EncryptionKey),
-		zap.Int("numShardsMigrated", len(shards)),
+		zap.Int("numShardsMigrated", len(shardIndices)),
+		zap.Ints("shardsRegenerated", regenerated),
 	)
 
 	return nil
@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"time"
+
+	"go.sia.tech/core/types"
+)
+
+// inUseMaxBackoffScans is the maximum number of scan intervals a host that
+// stores data for the renter is backed off for, such hosts have to be rescanned
+// quickly to avoid excluding them from uploads for longer than necessary.
+const inUseMaxBackoffScans = 4
+
+type hostBackoff struct {
+	failures uint64
+	nextScan time.Time
+}
+
+// backoffDelay returns the time to wait before scanning a host again after it
+// failed the given number of consecutive scans. The first failure doesn't
+// delay the host's next scan beyond the regular scan interval, every further
+// failure doubles it until the max backoff is reached.
+func backoffDelay(interval, maxBackoff time.Duration, failures uint64) time.Duration {
+	delay := interval
+	for i := uint64(1); i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// pruneBackoff removes the backoff of hosts that should have been scanned
+// again a long time ago, which is the case for hosts that were removed from
+// the store. The caller must hold the lock.
+func (s *Scanner) pruneBackoff() {
+	for hk, b := range s.backoff {
+		if time.Since(b.nextScan) > s.scanMaxBackoff {
+			delete(s.backoff, hk)
+		}
+	}
+}
+
+func (s *Scanner) recordScanFailure(hk types.PublicKey, inUse bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statsFailed++
+
+	if s.scanMaxBackoff <= 0 {
+		return // backoff disabled
+	}
+
+	b, ok := s.backoff[hk]
+	if !ok {
+		b = &hostBackoff{}
+		s.backoff[hk] = b
+	}
+	maxBackoff := s.scanMaxBackoff
+	if inUse && maxBackoff > inUseMaxBackoffScans*s.scanInterval {
+		maxBackoff = inUseMaxBackoffScans * s.scanInterval
+	}
+	b.failures++
+	b.nextScan = time.Now().Add(backoffDelay(s.scanInterval, maxBackoff, b.failures))
+}
+
+func (s *Scanner) recordScanSuccess(hk types.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statsScanned++
+	delete(s.backoff, hk)
+}
+
+func (s *Scanner) shouldSkip(hk types.PublicKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.backoff[hk]; ok && time.Now().Before(b.nextScan) {
+		s.statsSkipped++
+		return true
+	}
+	return false
+}
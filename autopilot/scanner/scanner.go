@@ -3,6 +3,7 @@ package scanner
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -37,9 +38,10 @@ type (
 	Scanner struct {
 		hs HostStore
 
-		scanBatchSize int
-		scanThreads   int
-		scanInterval  time.Duration
+		scanBatchSize  int
+		scanThreads    int
+		scanInterval   time.Duration
+		scanMaxBackoff time.Duration
 
 		statsHostPingMS *utils.DataPoints
 
@@ -52,16 +54,23 @@ type (
 
 		scanning          bool
 		scanningLastStart time.Time
+		scanningLastEnd   time.Time
 		scanningCtxCancel context.CancelCauseFunc
+
+		backoff      map[types.PublicKey]*hostBackoff
+		statsScanned uint64
+		statsFailed  uint64
+		statsSkipped uint64
 	}
 
 	scanJob struct {
 		hostKey types.PublicKey
 		hostIP  string
+		inUse   bool
 	}
 )
 
-func New(hs HostStore, scanBatchSize, scanThreads uint64, scanMinInterval time.Duration, logger *zap.Logger) (*Scanner, error) {
+func New(hs HostStore, scanBatchSize, scanThreads uint64, scanMinInterval, scanMaxBackoff time.Duration, logger *zap.Logger) (*Scanner, error) {
 	logger = logger.Named("scanner")
 	if scanBatchSize == 0 {
 		return nil, errors.New("scanner batch size has to be greater than zero")
@@ -72,12 +81,15 @@ func New(hs HostStore, scanBatchSize, scanThreads uint64, scanMinInterval time.D
 	return &Scanner{
 		hs: hs,
 
-		scanBatchSize: int(scanBatchSize),
-		scanThreads:   int(scanThreads),
-		scanInterval:  scanMinInterval,
+		scanBatchSize:  int(scanBatchSize),
+		scanThreads:    int(scanThreads),
+		scanInterval:   scanMinInterval,
+		scanMaxBackoff: scanMaxBackoff,
 
 		statsHostPingMS: utils.NewDataPoints(0),
 		logger:          logger.Sugar(),
+
+		backoff: make(map[types.PublicKey]*hostBackoff),
 	}, nil
 }
 
@@ -102,6 +114,8 @@ func (s *Scanner) Scan(ctx context.Context, hs HostScanner, force bool) {
 	s.scanningCtxCancel = cancel
 	s.scanningLastStart = time.Now()
 	s.scanning = true
+	s.statsScanned, s.statsFailed, s.statsSkipped = 0, 0, 0
+	s.pruneBackoff()
 	s.mu.Unlock()
 
 	cutoff := time.Now()
@@ -121,12 +135,13 @@ func (s *Scanner) Scan(ctx context.Context, hs HostScanner, force bool) {
 		defer func() {
 			s.mu.Lock()
 			s.scanning = false
+			s.scanningLastEnd = time.Now()
 			s.mu.Unlock()
 
 			s.wg.Done()
 			cancel(nil)
 		}()
-		scanned := s.scanHosts(ctx, hs, cutoff)
+		scanned, skipped := s.scanHosts(ctx, hs, cutoff, force)
 		removed := s.removeOfflineHosts(ctx)
 		s.logger.Infow("scan finished",
 			"force", force,
@@ -134,7 +149,8 @@ func (s *Scanner) Scan(ctx context.Context, hs HostScanner, force bool) {
 			"pingMSAvg", s.statsHostPingMS.Average(),
 			"pingMSP90", s.statsHostPingMS.P90(),
 			"removed", removed,
-			"scanned", scanned)
+			"scanned", scanned,
+			"skipped", skipped)
 	}()
 }
 
@@ -174,7 +190,44 @@ func (s *Scanner) UpdateHostsConfig(cfg api.HostsConfig) {
 	s.hostsCfg = &cfg
 }
 
-func (s *Scanner) scanHosts(ctx context.Context, hs HostScanner, cutoff time.Time) (scanned uint64) {
+// Stats returns the throughput of the ongoing or most recent scan as well as
+// the hosts that are currently being backed off from.
+func (s *Scanner) Stats() api.ScannerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := api.ScannerStats{
+		Scanning:  s.scanning,
+		LastStart: api.TimeRFC3339(s.scanningLastStart),
+		Threads:   s.scanThreads,
+		Scanned:   s.statsScanned,
+		Failed:    s.statsFailed,
+		Skipped:   s.statsSkipped,
+		Backoff:   make([]api.HostScanBackoff, 0, len(s.backoff)),
+	}
+
+	end := s.scanningLastEnd
+	if s.scanning {
+		end = time.Now()
+	}
+	if elapsed := end.Sub(s.scanningLastStart); !s.scanningLastStart.IsZero() && elapsed > 0 {
+		stats.Throughput = float64(s.statsScanned+s.statsFailed) / elapsed.Seconds()
+	}
+
+	for hk, b := range s.backoff {
+		stats.Backoff = append(stats.Backoff, api.HostScanBackoff{
+			HostKey:  hk,
+			Failures: b.failures,
+			NextScan: api.TimeRFC3339(b.nextScan),
+		})
+	}
+	sort.Slice(stats.Backoff, func(i, j int) bool {
+		return stats.Backoff[i].NextScan.Std().Before(stats.Backoff[j].NextScan.Std())
+	})
+	return stats
+}
+
+func (s *Scanner) scanHosts(ctx context.Context, hs HostScanner, cutoff time.Time, force bool) (scanned, skipped uint64) {
 	// define worker
 	worker := func(jobs <-chan scanJob) {
 		for h := range jobs {
@@ -186,8 +239,10 @@ func (s *Scanner) scanHosts(ctx context.Context, hs HostScanner, cutoff time.Tim
 				return // abort
 			} else if err := scan.Error(); err != nil {
 				s.logger.Debugw("host scan failed", zap.Error(err), "hk", h.hostKey, "ip", h.hostIP)
+				s.recordScanFailure(h.hostKey, h.inUse)
 			} else {
 				s.statsHostPingMS.Track(float64(time.Duration(scan.Ping).Milliseconds()))
+				s.recordScanSuccess(h.hostKey)
 				atomic.AddUint64(&scanned, 1)
 			}
 		}
@@ -221,10 +276,11 @@ func (s *Scanner) scanHosts(ctx context.Context, hs HostScanner, cutoff time.Tim
 			}()
 		}
 
-		// fetch batch
+		// fetch batch, hosts we skipped are not scanned so they keep matching
+		// the cutoff and need to be skipped over
 		hosts, err := s.hs.Hosts(ctx, api.HostOptions{
 			MaxLastScan: api.TimeRFC3339(cutoff),
-			Offset:      0,
+			Offset:      int(skipped),
 			Limit:       s.scanBatchSize,
 		})
 		if err != nil {
@@ -236,10 +292,15 @@ func (s *Scanner) scanHosts(ctx context.Context, hs HostScanner, cutoff time.Tim
 
 		// send batch to workers
 		for _, h := range hosts {
+			if !force && s.shouldSkip(h.PublicKey) {
+				skipped++
+				continue
+			}
 			select {
 			case jobs <- scanJob{
 				hostKey: h.PublicKey,
 				hostIP:  h.NetAddress,
+				inUse:   h.StoredData > 0,
 			}:
 			case <-ctx.Done():
 				continue
@@ -79,10 +79,12 @@ func (hs *mockHostStore) state() ([]string, []string) {
 
 type mockHostScanner struct {
 	blockChan chan struct{}
+	failing   map[types.PublicKey]bool
 	hs        *mockHostStore
 
 	mu        sync.Mutex
 	scanCount int
+	scans     map[types.PublicKey]int
 }
 
 func (w *mockHostScanner) ScanHost(ctx context.Context, hostKey types.PublicKey, _ time.Duration) (api.HostScanResponse, error) {
@@ -98,16 +100,28 @@ func (w *mockHostScanner) ScanHost(ctx context.Context, hostKey types.PublicKey,
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	w.scanCount++
+	if w.scans != nil {
+		w.scans[hostKey]++
+	}
 
+	if w.failing[hostKey] {
+		return api.HostScanResponse{ScanError: "host is offline"}, nil
+	}
 	return api.HostScanResponse{}, nil
 }
 
+func (w *mockHostScanner) numScans(hk types.PublicKey) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.scans[hk]
+}
+
 func TestScanner(t *testing.T) {
 	// create mock store
 	hs := &mockHostStore{hosts: test.NewHosts(100)}
 
 	// create test scanner
-	s, err := New(hs, testBatchSize, testNumThreads, time.Minute, zap.NewNop())
+	s, err := New(hs, testBatchSize, testNumThreads, time.Minute, 0, zap.NewNop())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -217,3 +231,136 @@ func TestScanner(t *testing.T) {
 		t.Fatal("unexpected")
 	}
 }
+
+func TestScannerBackoff(t *testing.T) {
+	// create mock store
+	hosts := test.NewHosts(10)
+	hs := &mockHostStore{hosts: hosts}
+
+	// create test scanner
+	interval := 100 * time.Millisecond
+	s, err := New(hs, 3, testNumThreads, interval, time.Hour, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown(context.Background())
+
+	// the first two hosts fail their scans
+	failing, online := hosts[:2], hosts[2:]
+	b := &mockHostScanner{
+		failing: map[types.PublicKey]bool{
+			failing[0].PublicKey: true,
+			failing[1].PublicKey: true,
+		},
+		hs:    hs,
+		scans: make(map[types.PublicKey]int),
+	}
+
+	// helper to perform a scan and wait for it to finish
+	scan := func(force bool) {
+		t.Helper()
+		if !force {
+			time.Sleep(interval)
+		}
+		s.Scan(context.Background(), b, force)
+		for {
+			if scanning, _ := s.Status(); !scanning {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	assertScans := func(hosts []api.Host, n int) {
+		t.Helper()
+		for _, h := range hosts {
+			if scans := b.numScans(h.PublicKey); scans != n {
+				t.Fatalf("expected host to be scanned %d times, got %d", n, scans)
+			}
+		}
+	}
+
+	// after the first failure, hosts are scanned at the regular interval
+	scan(false)
+	scan(false)
+	assertScans(failing, 2)
+	assertScans(online, 2)
+
+	// assert the failing hosts are backed off from
+	stats := s.Stats()
+	if len(stats.Backoff) != 2 {
+		t.Fatalf("expected 2 hosts in backoff, got %d", len(stats.Backoff))
+	}
+	for _, hb := range stats.Backoff {
+		if !b.failing[hb.HostKey] {
+			t.Fatal("unexpected host in backoff", hb.HostKey)
+		} else if hb.Failures != 2 {
+			t.Fatal("unexpected number of failures", hb.Failures)
+		} else if until := time.Until(hb.NextScan.Std()); until <= 0 || until > 2*interval {
+			t.Fatal("unexpected next scan", until)
+		}
+	}
+
+	// the next regular scan should skip the failing hosts
+	scan(false)
+	assertScans(failing, 2)
+	assertScans(online, 3)
+	if stats := s.Stats(); stats.Skipped != 2 || stats.Scanned != uint64(len(online)) || stats.Failed != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	} else if stats.Throughput <= 0 {
+		t.Fatal("expected throughput to be set")
+	}
+
+	// forced scans ignore the backoff
+	scan(true)
+	assertScans(failing, 3)
+	assertScans(online, 4)
+
+	// once a host comes back online, its backoff is reset
+	b.mu.Lock()
+	delete(b.failing, failing[0].PublicKey)
+	b.mu.Unlock()
+	scan(true)
+	if stats := s.Stats(); len(stats.Backoff) != 1 || stats.Backoff[0].HostKey != failing[1].PublicKey {
+		t.Fatalf("unexpected backoff %+v", stats.Backoff)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		failures uint64
+		want     time.Duration
+	}{
+		{1, time.Hour},
+		{2, 2 * time.Hour},
+		{3, 4 * time.Hour},
+		{4, 8 * time.Hour},
+		{5, 10 * time.Hour},
+		{100, 10 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := backoffDelay(time.Hour, 10*time.Hour, test.failures); got != test.want {
+			t.Fatalf("failures %d: expected %v, got %v", test.failures, test.want, got)
+		}
+	}
+}
+
+func TestBackoffInUse(t *testing.T) {
+	s, err := New(&mockHostStore{}, 1, 1, time.Hour, 48*time.Hour, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fail the scans of an unused host and a host that stores data
+	unused, inUse := types.PublicKey{1}, types.PublicKey{2}
+	for i := 0; i < 10; i++ {
+		s.recordScanFailure(unused, false)
+		s.recordScanFailure(inUse, true)
+	}
+
+	// assert the backoff of the host in use is capped
+	if until := time.Until(s.backoff[unused].nextScan); until <= 24*time.Hour {
+		t.Fatal("unexpected backoff for unused host", until)
+	} else if until := time.Until(s.backoff[inUse].nextScan); until > inUseMaxBackoffScans*time.Hour {
+		t.Fatal("unexpected backoff for host in use", until)
+	}
+}
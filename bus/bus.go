@@ -46,10 +46,15 @@ const (
 	defaultPinUpdateInterval          = 5 * time.Minute
 	defaultPinRateWindow              = 6 * time.Hour
 
-	lockingPriorityPruning   = 20
-	lockingPriorityFunding   = 40
-	lockingPriorityRenew     = 80
-	lockingPriorityBroadcast = 100
+	lockingPriorityPruning        = 20
+	lockingPriorityReconciliation = 20
+	lockingPriorityFunding        = 40
+	lockingPriorityRenew          = 80
+	lockingPriorityBroadcast      = 100
+
+	// objectManifestBatchSize is the number of objects fetched from the
+	// store at once when streaming a bucket's object manifest
+	objectManifestBatchSize = 1000
 
 	stdTxnSize = 1200 // bytes
 )
@@ -166,6 +171,7 @@ type (
 		AutopilotStore
 		BackupStore
 		ChainStore
+		HealthStore
 		HostStore
 		MetadataStore
 		MetricsStore
@@ -192,6 +198,11 @@ type (
 		Backup(ctx context.Context, dbID, dst string) error
 	}
 
+	// A HealthStore reports the health of the underlying database.
+	HealthStore interface {
+		DatabaseHealth(ctx context.Context) api.DatabaseHealth
+	}
+
 	// A ChainStore stores information about the chain.
 	ChainStore interface {
 		ChainIndex(ctx context.Context) (types.ChainIndex, error)
@@ -204,8 +215,10 @@ type (
 		Host(ctx context.Context, hostKey types.PublicKey) (api.Host, error)
 		HostAllowlist(ctx context.Context) ([]types.PublicKey, error)
 		HostBlocklist(ctx context.Context) ([]string, error)
+		HostVerificationStats(ctx context.Context) ([]api.HostVerificationStats, error)
 		Hosts(ctx context.Context, opts api.HostOptions) ([]api.Host, error)
 		RecordHostScans(ctx context.Context, scans []api.HostScan) error
+		RecordHostVerifications(ctx context.Context, verifications []api.HostVerification) error
 		RemoveOfflineHosts(ctx context.Context, maxConsecutiveScanFailures uint64, maxDowntime time.Duration) (uint64, error)
 		ResetLostSectors(ctx context.Context, hk types.PublicKey) error
 		UpdateHostAllowlistEntries(ctx context.Context, add, remove []types.PublicKey, clear bool) error
@@ -224,6 +237,7 @@ type (
 		Contract(ctx context.Context, id types.FileContractID) (api.ContractMetadata, error)
 		Contracts(ctx context.Context, opts api.ContractsOpts) ([]api.ContractMetadata, error)
 		RecordContractSpending(ctx context.Context, records []api.ContractSpendingRecord) error
+		RelinkContractSectors(ctx context.Context, renewals map[types.FileContractID]types.FileContractID) (uint64, error)
 		PutContract(ctx context.Context, c api.ContractMetadata) error
 		RenewedContract(ctx context.Context, renewedFrom types.FileContractID) (api.ContractMetadata, error)
 		UpdateContractUsability(ctx context.Context, id types.FileContractID, usability string) error
@@ -231,43 +245,63 @@ type (
 		ContractRoots(ctx context.Context, id types.FileContractID) ([]types.Hash256, error)
 		ContractSizes(ctx context.Context) (map[types.FileContractID]api.ContractSize, error)
 		ContractSize(ctx context.Context, id types.FileContractID) (api.ContractSize, error)
+		ContractOffboarding(ctx context.Context, id types.FileContractID) (api.ContractOffboarding, error)
+		OffboardContract(ctx context.Context, id types.FileContractID) (api.ContractOffboarding, error)
 		PrunableContractRoots(ctx context.Context, id types.FileContractID, roots []types.Hash256) ([]uint64, error)
 
 		DeleteHostSector(ctx context.Context, hk types.PublicKey, root types.Hash256) (int, error)
+		SampleSectors(ctx context.Context, n int) ([]api.SectorSample, error)
 
 		Bucket(_ context.Context, bucketName string) (api.Bucket, error)
+		BucketLifecycleRules(ctx context.Context, bucketName string) ([]api.BucketLifecycleRule, error)
 		Buckets(_ context.Context) ([]api.Bucket, error)
 		CreateBucket(_ context.Context, bucketName string, policy api.BucketPolicy) error
-		DeleteBucket(_ context.Context, bucketName string) error
+		DeleteBucket(_ context.Context, bucketName string, force bool) error
+		UpdateBucketLifecycleRules(ctx context.Context, bucketName string, rules []api.BucketLifecycleRule) error
 		UpdateBucketPolicy(ctx context.Context, bucketName string, policy api.BucketPolicy) error
 
-		CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey, mimeType string, metadata api.ObjectUserMetadata) (api.ObjectMetadata, error)
+		CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey, mimeType, contentDisposition string, metadata api.ObjectUserMetadata, policy string) (api.ObjectMetadata, bool, error)
 		Object(ctx context.Context, bucketName, key string) (api.Object, error)
-		Objects(ctx context.Context, bucketName, prefix, substring, delim, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey) (api.ObjectsResponse, error)
+		ObjectManifest(ctx context.Context, bucketName, marker string, limit int) ([]api.ObjectManifestEntry, error)
+		MoveObject(ctx context.Context, srcBucket, dstBucket, key string) error
+		Objects(ctx context.Context, bucketName, prefix, substring, glob, delim, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey, minHealth, maxHealth *float64) (api.ObjectsResponse, error)
 		ObjectMetadata(ctx context.Context, bucketName, key string) (api.Object, error)
+		ObjectsNoSlabs(ctx context.Context, bucket, marker string, limit int, missingDataOnly bool) (api.ObjectsNoSlabsResponse, error)
+		ObjectsMissingChecksum(ctx context.Context, marker uint64, limit int) (api.ObjectsMissingChecksumResponse, error)
+		BackfillObjectChecksum(ctx context.Context, id uint64, checksum string) error
+		ObjectEvents(ctx context.Context, marker uint64, limit int) (api.ObjectEventsResponse, error)
 		ObjectsStats(ctx context.Context, opts api.ObjectsStatsOpts) (api.ObjectsStatsResponse, error)
+		PinObject(ctx context.Context, bucketName, key string, pinned bool) error
 		RemoveObject(ctx context.Context, bucketName, key string) error
-		RemoveObjects(ctx context.Context, bucketName, prefix string) error
-		RenameObject(ctx context.Context, bucketName, from, to string, force bool) error
-		RenameObjects(ctx context.Context, bucketName, from, to string, force bool) error
-		UpdateObject(ctx context.Context, bucketName, key, ETag, mimeType string, metadata api.ObjectUserMetadata, o object.Object) error
+		RemoveObjectAsync(ctx context.Context, bucketName, key string) error
+		RemoveObjects(ctx context.Context, bucketName, prefix string) (int64, error)
+		RenameObject(ctx context.Context, bucketName, from, to string, force, allowDirCollision bool) error
+		RenameObjects(ctx context.Context, bucketName, from, to string, force bool) (api.ObjectsRenameResponse, error)
+		UpdateObject(ctx context.Context, bucketName, key, ETag, checksum, mimeType, contentDisposition string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey, o object.Object) error
+		UpdateObjectIdempotent(ctx context.Context, idempotencyKey string, requestHash types.Hash256, unmodifiedSince time.Time, bucketName, key, ETag, checksum, mimeType, contentDisposition string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey, o object.Object) (string, error)
 
 		AbortMultipartUpload(ctx context.Context, bucketName, key string, uploadID string) (err error)
 		AddMultipartPart(ctx context.Context, bucketName, key, eTag, uploadID string, partNumber int, slices []object.SlabSlice) (err error)
+		AddMultipartPartProgress(ctx context.Context, bucketName, key, uploadID string, partNumber, index int, checksum types.Hash256, slices []object.SlabSlice) (err error)
 		CompleteMultipartUpload(ctx context.Context, bucketName, key, uploadID string, parts []api.MultipartCompletedPart, opts api.CompleteMultipartOptions) (_ api.MultipartCompleteResponse, err error)
-		CreateMultipartUpload(ctx context.Context, bucketName, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata) (api.MultipartCreateResponse, error)
+		CreateMultipartUpload(ctx context.Context, bucketName, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey) (api.MultipartCreateResponse, error)
+		MultipartPartProgress(ctx context.Context, bucketName, key, uploadID string, partNumber int) ([]api.MultipartPartProgressSlice, error)
 		MultipartUpload(ctx context.Context, uploadID string) (resp api.MultipartUpload, _ error)
 		MultipartUploads(ctx context.Context, bucketName, prefix, keyMarker, uploadIDMarker string, maxUploads int) (resp api.MultipartListUploadsResponse, _ error)
 		MultipartUploadParts(ctx context.Context, bucketName, object string, uploadID string, marker int, limit int64) (resp api.MultipartListPartsResponse, _ error)
 
 		MarkPackedSlabsUploaded(ctx context.Context, slabs []api.UploadedPackedSlab) error
+		MarkSlabBuffersComplete(ctx context.Context) error
 		PackedSlabsForUpload(ctx context.Context, lockingDuration time.Duration, minShards, totalShards uint8, limit int) ([]api.PackedSlab, error)
 		SlabBuffers(ctx context.Context) ([]api.SlabBuffer, error)
+		SlabBuffersStats(ctx context.Context) (api.SlabBuffersStatsResponse, error)
 
 		AddPartialSlab(ctx context.Context, data []byte, minShards, totalShards uint8) (slabs []object.SlabSlice, bufferSize int64, err error)
 		FetchPartialSlab(ctx context.Context, key object.EncryptionKey, offset, length uint32) ([]byte, error)
+		SharedSlabs(ctx context.Context, minObjects uint64, limit int) ([]api.SharedSlab, error)
 		Slab(ctx context.Context, key object.EncryptionKey) (object.Slab, error)
 		SlabsForMigration(ctx context.Context, healthCutoff float64, limit int) ([]api.UnhealthySlab, error)
+		SlabsStats(ctx context.Context) (api.SlabsStatsResponse, error)
 		RefreshHealth(ctx context.Context) error
 		UpdateSlab(ctx context.Context, key object.EncryptionKey, sectors []api.UploadedSector) error
 	}
@@ -299,6 +333,8 @@ type (
 
 		S3Settings(ctx context.Context) (api.S3Settings, error)
 		UpdateS3Settings(ctx context.Context, s3as api.S3Settings) error
+
+		UpdateSettings(ctx context.Context, gs *api.GougingSettings, ps *api.PinnedSettings, us *api.UploadSettings) error
 	}
 
 	WalletMetricsRecorder interface {
@@ -398,11 +434,14 @@ func (b *Bus) Handler() http.Handler {
 		"GET    /autopilot": b.autopilotHandlerGET,
 		"PUT    /autopilot": b.autopilotHandlerPUT,
 
-		"GET    /buckets":             b.bucketsHandlerGET,
-		"POST   /buckets":             b.bucketsHandlerPOST,
-		"PUT    /bucket/:name/policy": b.bucketsHandlerPolicyPUT,
-		"DELETE /bucket/:name":        b.bucketHandlerDELETE,
-		"GET    /bucket/:name":        b.bucketHandlerGET,
+		"GET    /buckets":                b.bucketsHandlerGET,
+		"POST   /buckets":                b.bucketsHandlerPOST,
+		"PUT    /bucket/:name/policy":    b.bucketsHandlerPolicyPUT,
+		"DELETE /bucket/:name":           b.bucketHandlerDELETE,
+		"GET    /bucket/:name":           b.bucketHandlerGET,
+		"GET    /bucket/:name/lifecycle": b.bucketLifecycleHandlerGET,
+		"PUT    /bucket/:name/lifecycle": b.bucketLifecycleHandlerPUT,
+		"GET    /bucket/:name/manifest":  b.bucketManifestHandlerGET,
 
 		"POST   /consensus/acceptblock":        b.consensusAcceptBlock,
 		"GET    /consensus/network":            b.consensusNetworkHandler,
@@ -413,10 +452,13 @@ func (b *Bus) Handler() http.Handler {
 		"GET    /contracts":             b.contractsHandlerGET,
 		"DELETE /contracts/all":         b.contractsAllHandlerDELETE,
 		"POST   /contracts/archive":     b.contractsArchiveHandlerPOST,
+		"GET    /contracts/capacity":    b.contractsCapacityHandlerGET,
 		"POST   /contracts/form":        b.contractsFormHandler,
 		"GET    /contracts/prunable":    b.contractsPrunableDataHandlerGET,
+		"POST   /contracts/relink":      b.contractsRelinkHandlerPOST,
 		"GET    /contracts/renewed/:id": b.contractsRenewedIDHandlerGET,
 		"POST   /contracts/spending":    b.contractsSpendingHandlerPOST,
+		"GET    /contracts/subnet":      b.contractsSubnetHandlerGET,
 
 		"GET    /contract/:id":           b.contractIDHandlerGET,
 		"DELETE /contract/:id":           b.contractIDHandlerDELETE,
@@ -424,24 +466,33 @@ func (b *Bus) Handler() http.Handler {
 		"GET    /contract/:id/ancestors": b.contractIDAncestorsHandler,
 		"POST   /contract/:id/broadcast": b.contractIDBroadcastHandler,
 		"POST   /contract/:id/keepalive": b.contractKeepaliveHandlerPOST,
+		"GET    /contract/:id/offboard":  b.contractOffboardHandlerGET,
+		"POST   /contract/:id/offboard":  b.contractOffboardHandlerPOST,
 		"GET    /contract/:id/revision":  b.contractLatestRevisionHandlerGET,
 		"POST   /contract/:id/prune":     b.contractPruneHandlerPOST,
+		"POST   /contract/:id/reconcile": b.contractReconcileHandlerPOST,
 		"POST   /contract/:id/renew":     b.contractIDRenewHandlerPOST,
 		"POST   /contract/:id/release":   b.contractReleaseHandlerPOST,
 		"GET    /contract/:id/roots":     b.contractIDRootsHandlerGET,
 		"GET    /contract/:id/size":      b.contractSizeHandlerGET,
+		"GET    /contract/:id/spending":  b.contractSpendingHandlerGET,
 		"PUT    /contract/:id/usability": b.contractUsabilityHandlerPUT,
 
-		"GET    /hosts":           b.hostsHandlerGET,
-		"POST   /hosts":           b.hostsHandlerPOST,
-		"GET    /hosts/allowlist": b.hostsAllowlistHandlerGET,
-		"PUT    /hosts/allowlist": b.hostsAllowlistHandlerPUT,
-		"GET    /hosts/blocklist": b.hostsBlocklistHandlerGET,
-		"PUT    /hosts/blocklist": b.hostsBlocklistHandlerPUT,
-		"POST   /hosts/remove":    b.hostsRemoveHandlerPOST,
+		"GET    /health": b.healthHandlerGET,
+
+		"GET    /hosts":               b.hostsHandlerGET,
+		"POST   /hosts":               b.hostsHandlerPOST,
+		"GET    /hosts/allowlist":     b.hostsAllowlistHandlerGET,
+		"PUT    /hosts/allowlist":     b.hostsAllowlistHandlerPUT,
+		"GET    /hosts/blocklist":     b.hostsBlocklistHandlerGET,
+		"PUT    /hosts/blocklist":     b.hostsBlocklistHandlerPUT,
+		"POST   /hosts/remove":        b.hostsRemoveHandlerPOST,
+		"GET    /hosts/verifications": b.hostsVerificationsHandlerGET,
+		"POST   /hosts/verifications": b.hostsVerificationsHandlerPOST,
 
 		"GET    /host/:hostkey":                  b.hostsPubkeyHandlerGET,
 		"PUT    /host/:hostkey/check":            b.hostsCheckHandlerPUT,
+		"GET    /host/:hostkey/gouging":          b.hostsGougingHandlerGET,
 		"POST   /host/:hostkey/resetlostsectors": b.hostsResetLostSectorsPOST,
 		"POST   /host/:hostkey/scan":             b.hostsScanHandlerPOST,
 
@@ -449,18 +500,26 @@ func (b *Bus) Handler() http.Handler {
 		"GET    /metric/:key": b.metricsHandlerGET,
 		"DELETE /metric/:key": b.metricsHandlerDELETE,
 
-		"POST   /multipart/create":      b.multipartHandlerCreatePOST,
-		"POST   /multipart/abort":       b.multipartHandlerAbortPOST,
-		"POST   /multipart/complete":    b.multipartHandlerCompletePOST,
-		"PUT    /multipart/part":        b.multipartHandlerUploadPartPUT,
-		"GET    /multipart/upload/:id":  b.multipartHandlerUploadGET,
-		"POST   /multipart/listuploads": b.multipartHandlerListUploadsPOST,
-		"POST   /multipart/listparts":   b.multipartHandlerListPartsPOST,
-
-		"GET    /objects/*prefix": b.objectsHandlerGET,
-		"POST   /objects/copy":    b.objectsCopyHandlerPOST,
-		"POST   /objects/remove":  b.objectsRemoveHandlerPOST,
-		"POST   /objects/rename":  b.objectsRenameHandlerPOST,
+		"POST   /multipart/create":       b.multipartHandlerCreatePOST,
+		"POST   /multipart/abort":        b.multipartHandlerAbortPOST,
+		"POST   /multipart/complete":     b.multipartHandlerCompletePOST,
+		"PUT    /multipart/part":         b.multipartHandlerUploadPartPUT,
+		"POST   /multipart/partprogress": b.multipartHandlerPartProgressPOST,
+		"PUT    /multipart/partprogress": b.multipartHandlerPartProgressPUT,
+		"GET    /multipart/upload/:id":   b.multipartHandlerUploadGET,
+		"POST   /multipart/listuploads":  b.multipartHandlerListUploadsPOST,
+		"POST   /multipart/listparts":    b.multipartHandlerListPartsPOST,
+
+		"GET    /objects/*prefix":            b.objectsHandlerGET,
+		"POST   /objects/checksums/backfill": b.objectsChecksumsBackfillHandlerPOST,
+		"POST   /objects/checksums/missing":  b.objectsChecksumsMissingHandlerPOST,
+		"POST   /objects/copy":               b.objectsCopyHandlerPOST,
+		"POST   /objects/move":               b.objectsMoveHandlerPOST,
+		"POST   /objects/events":             b.objectsEventsHandlerPOST,
+		"POST   /objects/noslabs":            b.objectsNoSlabsHandlerPOST,
+		"POST   /objects/pin":                b.objectsPinHandlerPOST,
+		"POST   /objects/remove":             b.objectsRemoveHandlerPOST,
+		"POST   /objects/rename":             b.objectsRenameHandlerPOST,
 
 		"GET    /object/*key": b.objectHandlerGET,
 		"PUT    /object/*key": b.objectHandlerPUT,
@@ -469,8 +528,11 @@ func (b *Bus) Handler() http.Handler {
 		"GET    /params/gouging": b.paramsHandlerGougingGET,
 		"GET    /params/upload":  b.paramsHandlerUploadGET,
 
+		"GET    /sectors/sample":         b.sectorsSampleHandlerGET,
 		"DELETE /sectors/:hostkey/:root": b.sectorsHostRootHandlerDELETE,
 
+		"GET    /settings":         b.settingsHandlerGET,
+		"PUT    /settings":         b.settingsHandlerPUT,
 		"GET    /settings/gouging": b.settingsGougingHandlerGET,
 		"PUT    /settings/gouging": b.settingsGougingHandlerPUT,
 		"GET    /settings/pinned":  b.settingsPinnedHandlerGET,
@@ -480,20 +542,24 @@ func (b *Bus) Handler() http.Handler {
 		"GET    /settings/upload":  b.settingsUploadHandlerGET,
 		"PUT    /settings/upload":  b.settingsUploadHandlerPUT,
 
-		"GET    /slabbuffers":      b.slabbuffersHandlerGET,
-		"POST   /slabbuffer/done":  b.packedSlabsHandlerDonePOST,
-		"POST   /slabbuffer/fetch": b.packedSlabsHandlerFetchPOST,
+		"GET    /slabbuffers":          b.slabbuffersHandlerGET,
+		"POST   /slabbuffers/complete": b.slabbuffersCompleteHandlerPOST,
+		"POST   /slabbuffer/done":      b.packedSlabsHandlerDonePOST,
+		"POST   /slabbuffer/fetch":     b.packedSlabsHandlerFetchPOST,
 
 		"POST   /slabs/migration":     b.slabsMigrationHandlerPOST,
 		"GET    /slabs/partial/:key":  b.slabsPartialHandlerGET,
 		"POST   /slabs/partial":       b.slabsPartialHandlerPOST,
 		"POST   /slabs/refreshhealth": b.slabsRefreshHealthHandlerPOST,
+		"GET    /slabs/shared":        b.slabsSharedHandlerGET,
 		"GET    /slab/:key":           b.slabHandlerGET,
 		"PUT    /slab/:key":           b.slabHandlerPUT,
 
 		"GET    /state": b.stateHandlerGET,
 
-		"GET    /stats/objects": b.objectsStatshandlerGET,
+		"GET    /stats/objects":     b.objectsStatshandlerGET,
+		"GET    /stats/slabs":       b.slabsStatsHandlerGET,
+		"GET    /stats/slabbuffers": b.slabBuffersStatsHandlerGET,
 
 		"GET    /syncer/address": b.syncerAddrHandler,
 		"POST   /syncer/connect": b.syncerConnectHandler,
@@ -748,6 +814,36 @@ func (b *Bus) isPassedV2AllowHeight() bool {
 	return cs.Index.Height >= cs.Network.HardforkV2.AllowHeight
 }
 
+// latestRevision fetches the latest revision of the given contract from its
+// host.
+func (b *Bus) latestRevision(ctx context.Context, fcid types.FileContractID, host api.Host) (api.Revision, error) {
+	if host.IsV2() {
+		revision, err := b.rhp4Client.LatestRevision(ctx, host.PublicKey, host.V2SiamuxAddr(), fcid)
+		if err != nil {
+			return api.Revision{}, err
+		}
+		return api.Revision{
+			ContractID:      fcid,
+			MissedHostValue: revision.MissedHostValue,
+			RenterFunds:     revision.RenterOutput.Value,
+			RevisionNumber:  revision.RevisionNumber,
+			Size:            revision.Filesize,
+		}, nil
+	}
+
+	revision, err := b.rhp3Client.Revision(ctx, fcid, host.PublicKey, host.Settings.SiamuxAddr())
+	if err != nil {
+		return api.Revision{}, err
+	}
+	return api.Revision{
+		ContractID:      fcid,
+		MissedHostValue: revision.MissedHostPayout(),
+		RenterFunds:     revision.ValidRenterPayout(),
+		RevisionNumber:  revision.RevisionNumber,
+		Size:            revision.Filesize,
+	}, nil
+}
+
 func (b *Bus) prepareRenew(cs consensus.State, revision types.FileContractRevision, hostAddress, renterAddress types.Address, renterFunds, minNewCollateral types.Currency, endHeight, expectedStorage uint64) rhp3.PrepareRenewFn {
 	return func(pt rhpv3.HostPriceTable) ([]types.Hash256, []types.Transaction, types.Currency, rhp3.DiscardTxnFn, error) {
 		// create the final revision from the provided revision
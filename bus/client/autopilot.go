@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
 )
 
@@ -26,7 +27,7 @@ func WithHostsConfig(cfg api.HostsConfig) UpdateAutopilotOption {
 
 // Autopilot returns the autopilot configuration.
 func (c *Client) AutopilotConfig(ctx context.Context) (ap api.AutopilotConfig, err error) {
-	err = c.c.WithContext(ctx).GET("/autopilot", &ap)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/autopilot", &ap) })
 	return
 }
 
@@ -36,5 +37,5 @@ func (c *Client) UpdateAutopilotConfig(ctx context.Context, opts ...UpdateAutopi
 	for _, opt := range opts {
 		opt(&req)
 	}
-	return c.c.WithContext(ctx).PUT("/autopilot", req)
+	return c.do(ctx, func(jc jape.Client) error { return jc.PUT("/autopilot", req) })
 }
@@ -2,39 +2,125 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
 )
 
 // Bucket returns information about a specific bucket.
 func (c *Client) Bucket(ctx context.Context, bucketName string) (resp api.Bucket, err error) {
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/bucket/%s", bucketName), &resp)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/bucket/%s", bucketName), &resp) })
 	return
 }
 
+// ObjectManifest streams the manifest of all objects in a bucket, sorted by
+// key, and calls fn for every entry. The manifest starts after the given
+// marker, which allows resuming an interrupted export by passing the key of
+// the last entry that was processed. An error is returned if the manifest was
+// cut off before its trailer. Since the manifest is streamed, the client's
+// default request timeout doesn't apply, only the deadline of the context.
+func (c *Client) ObjectManifest(ctx context.Context, bucketName, marker string, fn func(api.ObjectManifestEntry) error) error {
+	c.c.Custom("GET", fmt.Sprintf("/bucket/%s/manifest", bucketName), nil, &[]api.ObjectManifestEntry{})
+	values := url.Values{}
+	values.Set("marker", marker)
+
+	u, err := url.Parse(fmt.Sprintf("%s/bucket/%s/manifest", c.c.BaseURL, bucketName))
+	if err != nil {
+		panic(err)
+	}
+	u.RawQuery = values.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), http.NoBody)
+	if err != nil {
+		panic(err)
+	}
+	req.SetBasicAuth("", c.c.WithContext(ctx).Password)
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer io.Copy(io.Discard, resp.Body)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err, _ := io.ReadAll(resp.Body)
+		return errors.New(string(err))
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var line struct {
+			api.ObjectManifestEntry
+			api.ObjectManifestTrailer
+		}
+		if err := dec.Decode(&line); errors.Is(err, io.EOF) {
+			return fmt.Errorf("manifest ended without trailer: %w", io.ErrUnexpectedEOF)
+		} else if err != nil {
+			return fmt.Errorf("failed to decode manifest entry: %w", err)
+		} else if line.Done {
+			return nil
+		} else if err := fn(line.ObjectManifestEntry); err != nil {
+			return err
+		}
+	}
+}
+
 // CreateBucket creates a new bucket.
 func (c *Client) CreateBucket(ctx context.Context, bucketName string, opts api.CreateBucketOptions) error {
-	return c.c.WithContext(ctx).POST("/buckets", api.BucketCreateRequest{
-		Name:   bucketName,
-		Policy: opts.Policy,
-	}, nil)
+	return c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/buckets", api.BucketCreateRequest{
+			Name:   bucketName,
+			Policy: opts.Policy,
+		}, nil)
+	})
+}
+
+// DeleteBucket deletes an existing bucket. Fails if the bucket isn't empty
+// unless the force option is set, in which case all objects in the bucket are
+// deleted as well. A forced deletion isn't atomic, objects that are added while
+// it runs make it fail after the existing objects were deleted.
+func (c *Client) DeleteBucket(ctx context.Context, bucketName string, opts api.DeleteBucketOptions) error {
+	values := url.Values{}
+	if opts.Force {
+		values.Set("force", "true")
+	}
+	return c.do(ctx, func(jc jape.Client) error {
+		return jc.DELETE(fmt.Sprintf("/bucket/%s?%s", bucketName, values.Encode()))
+	})
 }
 
-// DeleteBucket deletes an existing bucket. Fails if the bucket isn't empty.
-func (c *Client) DeleteBucket(ctx context.Context, bucketName string) error {
-	return c.c.WithContext(ctx).DELETE(fmt.Sprintf("/bucket/%s", bucketName))
+// BucketLifecycleRules returns the lifecycle rules of the given bucket.
+func (c *Client) BucketLifecycleRules(ctx context.Context, bucketName string) (rules []api.BucketLifecycleRule, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/bucket/%s/lifecycle", bucketName), &rules) })
+	return
 }
 
 // ListBuckets lists all available buckets.
 func (c *Client) ListBuckets(ctx context.Context) (buckets []api.Bucket, err error) {
-	err = c.c.WithContext(ctx).GET("/buckets", &buckets)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/buckets", &buckets) })
 	return
 }
 
+// UpdateBucketLifecycleRules replaces the lifecycle rules of an existing
+// bucket. Objects that expire according to the rules are deleted in the
+// background.
+func (c *Client) UpdateBucketLifecycleRules(ctx context.Context, bucketName string, rules []api.BucketLifecycleRule) error {
+	return c.do(ctx, func(jc jape.Client) error {
+		return jc.PUT(fmt.Sprintf("/bucket/%s/lifecycle", bucketName), api.BucketUpdateLifecycleRequest{
+			Rules: rules,
+		})
+	})
+}
+
 // UpdateBucketPolicy updates the policy of an existing bucket.
 func (c *Client) UpdateBucketPolicy(ctx context.Context, bucketName string, policy api.BucketPolicy) error {
-	return c.c.WithContext(ctx).PUT(fmt.Sprintf("/bucket/%s/policy", bucketName), api.BucketUpdatePolicyRequest{
-		Policy: policy,
+	return c.do(ctx, func(jc jape.Client) error {
+		return jc.PUT(fmt.Sprintf("/bucket/%s/policy", bucketName), api.BucketUpdatePolicyRequest{
+			Policy: policy,
+		})
 	})
 }
@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sync/atomic"
 	"time"
 
 	"go.sia.tech/core/types"
@@ -10,32 +12,91 @@ import (
 	"go.sia.tech/renterd/api"
 )
 
+type requestTimeoutKey struct{}
+
 // A Client provides methods for interacting with a bus.
 type Client struct {
 	c jape.Client
+
+	// streamClient performs requests with streamed response bodies, which
+	// jape doesn't support
+	streamClient *http.Client
+
+	defaultTimeout atomic.Int64 // time.Duration
 }
 
 // New returns a new bus client.
 func New(addr, password string) *Client {
-	return &Client{jape.Client{
-		BaseURL:  addr,
-		Password: password,
-	}}
+	return &Client{
+		c: jape.Client{
+			BaseURL:  addr,
+			Password: password,
+		},
+		streamClient: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		},
+	}
+}
+
+// WithRequestTimeout returns a context that overrides the client's default
+// request timeout for all requests made with it. A timeout of 0 disables the
+// default timeout. The timeout is ignored if the context has a deadline.
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// SetDefaultRequestTimeout sets the timeout that is applied to every request
+// made with a context without a deadline, except for requests that stream
+// their response. A timeout of 0 disables it, which is the default.
+func (c *Client) SetDefaultRequestTimeout(timeout time.Duration) {
+	c.defaultTimeout.Store(int64(timeout))
+}
+
+// requestContext returns the context to perform a request with, if the given
+// context has no deadline the client's default request timeout is applied.
+func (c *Client) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := time.Duration(c.defaultTimeout.Load())
+	if override, ok := ctx.Value(requestTimeoutKey{}).(time.Duration); ok {
+		timeout = override
+	}
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// do calls fn with a jape client that performs its requests using the request
+// context derived from ctx.
+func (c *Client) do(ctx context.Context, fn func(jc jape.Client) error) error {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	return fn(*c.c.WithContext(ctx))
 }
 
 func (c *Client) Backup(ctx context.Context, database, dstPath string) (err error) {
-	err = c.c.WithContext(ctx).POST("/system/sqlite3/backup", api.BackupRequest{
-		Database: database,
-		Path:     dstPath,
-	}, nil)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/system/sqlite3/backup", api.BackupRequest{
+			Database: database,
+			Path:     dstPath,
+		}, nil)
+	})
 	return
 }
 
 // ScanHost scans a host, returning its current settings and prices.
 func (c *Client) ScanHost(ctx context.Context, hostKey types.PublicKey, timeout time.Duration) (resp api.HostScanResponse, err error) {
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/host/%s/scan", hostKey), api.HostScanRequest{
-		Timeout: api.DurationMS(timeout),
-	}, &resp)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/host/%s/scan", hostKey), api.HostScanRequest{
+			Timeout: api.DurationMS(timeout),
+		}, &resp)
+	})
+	return
+}
+
+// Health returns the health of the bus. An error is returned if the bus is
+// unavailable.
+func (c *Client) Health(ctx context.Context) (resp api.HealthResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/health", &resp) })
 	return
 }
 
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestContext(t *testing.T) {
+	c := New("http://localhost", "")
+
+	// without a default timeout the context is left untouched
+	ctx, cancel := c.requestContext(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("unexpected deadline")
+	}
+
+	// with a default timeout a deadline is applied
+	c.SetDefaultRequestTimeout(time.Minute)
+	ctx, cancel = c.requestContext(context.Background())
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Fatal("unexpected deadline", deadline, ok)
+	}
+
+	// an existing deadline takes precedence
+	parent, parentCancel := context.WithTimeout(context.Background(), time.Hour)
+	defer parentCancel()
+	expected, _ := parent.Deadline()
+	ctx, cancel = c.requestContext(parent)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(expected) {
+		t.Fatal("unexpected deadline", deadline, expected)
+	}
+
+	// the timeout can be overridden per request
+	ctx, cancel = c.requestContext(WithRequestTimeout(context.Background(), time.Second))
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Second {
+		t.Fatal("unexpected deadline", deadline, ok)
+	}
+
+	// and disabled
+	ctx, cancel = c.requestContext(WithRequestTimeout(context.Background(), 0))
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("unexpected deadline")
+	}
+
+	// the default timeout can be updated while requests are performed
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.SetDefaultRequestTimeout(time.Duration(i) * time.Second)
+			_, cancel := c.requestContext(context.Background())
+			cancel()
+		}()
+	}
+	wg.Wait()
+}
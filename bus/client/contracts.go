@@ -7,20 +7,23 @@ import (
 	"time"
 
 	"go.sia.tech/core/types"
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
 )
 
 // AddContract adds the provided contract to the metadata store, if the contract
 // already exists it will be replaced.
 func (c *Client) AddContract(ctx context.Context, contract api.ContractMetadata) error {
-	return c.c.WithContext(ctx).PUT("/contracts", contract)
+	return c.do(ctx, func(jc jape.Client) error { return jc.PUT("/contracts", contract) })
 }
 
 // AncestorContracts returns any ancestors of a given contract.
 func (c *Client) AncestorContracts(ctx context.Context, contractID types.FileContractID, minStartHeight uint64) (contracts []api.ContractMetadata, err error) {
 	values := url.Values{}
 	values.Set("minstartheight", fmt.Sprint(minStartHeight))
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/contract/%s/ancestors?"+values.Encode(), contractID), &contracts)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.GET(fmt.Sprintf("/contract/%s/ancestors?"+values.Encode(), contractID), &contracts)
+	})
 	return
 }
 
@@ -28,42 +31,78 @@ func (c *Client) AncestorContracts(ctx context.Context, contractID types.FileCon
 // released manually before that time.
 func (c *Client) AcquireContract(ctx context.Context, contractID types.FileContractID, priority int, d time.Duration) (lockID uint64, err error) {
 	var resp api.ContractAcquireResponse
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/contract/%s/acquire", contractID), api.ContractAcquireRequest{
-		Duration: api.DurationMS(d),
-		Priority: priority,
-	}, &resp)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/contract/%s/acquire", contractID), api.ContractAcquireRequest{
+			Duration: api.DurationMS(d),
+			Priority: priority,
+		}, &resp)
+	})
 	lockID = resp.LockID
 	return
 }
 
 // ArchiveContracts archives the contracts with the given IDs and archival reason.
 func (c *Client) ArchiveContracts(ctx context.Context, toArchive map[types.FileContractID]string) (err error) {
-	err = c.c.WithContext(ctx).POST("/contracts/archive", toArchive, nil)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.POST("/contracts/archive", toArchive, nil) })
+	return
+}
+
+// RelinkContractSectors links the sectors of renewed contracts to their
+// renewals and returns the number of links that were updated.
+func (c *Client) RelinkContractSectors(ctx context.Context, renewals map[types.FileContractID]types.FileContractID) (relinked uint64, err error) {
+	var resp api.ContractsRelinkResponse
+	err = c.do(ctx, func(jc jape.Client) error { return jc.POST("/contracts/relink", renewals, &resp) })
+	relinked = resp.Relinked
 	return
 }
 
 // BroadcastContract broadcasts the latest revision for a contract.
 func (c *Client) BroadcastContract(ctx context.Context, contractID types.FileContractID) (txnID types.TransactionID, err error) {
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/contract/%s/broadcast", contractID), nil, &txnID)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/contract/%s/broadcast", contractID), nil, &txnID)
+	})
 	return
 }
 
 // Contract returns the contract with the given ID.
 func (c *Client) Contract(ctx context.Context, id types.FileContractID) (contract api.ContractMetadata, err error) {
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/contract/%s", id), &contract)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/contract/%s", id), &contract) })
 	return
 }
 
 // ContractRoots returns the sector roots, as well as the ones that are still
 // uploading, for the contract with given id.
 func (c *Client) ContractRoots(ctx context.Context, contractID types.FileContractID) (roots []types.Hash256, err error) {
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/contract/%s/roots", contractID), &roots)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/contract/%s/roots", contractID), &roots) })
+	return
+}
+
+// ContractOffboarding returns the migration progress of a contract that was
+// offboarded.
+func (c *Client) ContractOffboarding(ctx context.Context, contractID types.FileContractID) (res api.ContractOffboarding, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/contract/%s/offboard", contractID), &res) })
+	return
+}
+
+// OffboardContract marks a contract as bad and causes the slabs stored on it
+// to be migrated to other hosts, the contract is archived once they were.
+func (c *Client) OffboardContract(ctx context.Context, contractID types.FileContractID) (res api.ContractOffboarding, err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/contract/%s/offboard", contractID), nil, &res)
+	})
 	return
 }
 
 // ContractSize returns the contract's size.
 func (c *Client) ContractSize(ctx context.Context, contractID types.FileContractID) (size api.ContractSize, err error) {
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/contract/%s/size", contractID), &size)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/contract/%s/size", contractID), &size) })
+	return
+}
+
+// ContractSpending returns a breakdown of the spending of the contract with
+// given id, both for the contract itself and including its ancestors.
+func (c *Client) ContractSpending(ctx context.Context, contractID types.FileContractID) (resp api.ContractSpendingResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/contract/%s/spending", contractID), &resp) })
 	return
 }
 
@@ -74,13 +113,22 @@ func (c *Client) Contracts(ctx context.Context, opts api.ContractsOpts) (contrac
 	if opts.FilterMode != "" {
 		values.Set("filtermode", opts.FilterMode)
 	}
-	err = c.c.WithContext(ctx).GET("/contracts?"+values.Encode(), &contracts)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/contracts?"+values.Encode(), &contracts) })
+	return
+}
+
+// ContractsInSubnet returns the active contracts whose hosts resolve to an IP
+// within the given subnet, e.g. "1.2.3.0/24".
+func (c *Client) ContractsInSubnet(ctx context.Context, cidr string) (contracts []api.ContractHostIP, err error) {
+	values := url.Values{}
+	values.Set("cidr", cidr)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/contracts/subnet?"+values.Encode(), &contracts) })
 	return
 }
 
 // DeleteContract deletes the contract with the given ID.
 func (c *Client) DeleteContract(ctx context.Context, id types.FileContractID) (err error) {
-	err = c.c.WithContext(ctx).DELETE(fmt.Sprintf("/contract/%s", id))
+	err = c.do(ctx, func(jc jape.Client) error { return jc.DELETE(fmt.Sprintf("/contract/%s", id)) })
 	return
 }
 
@@ -97,49 +145,75 @@ func (c *Client) DeleteContracts(ctx context.Context, ids []types.FileContractID
 
 // DeleteAllContracts deletes all contracts from the bus.
 func (c *Client) DeleteAllContracts(ctx context.Context) (err error) {
-	err = c.c.WithContext(ctx).DELETE("/contracts/all")
+	err = c.do(ctx, func(jc jape.Client) error { return jc.DELETE("/contracts/all") })
 	return
 }
 
 // FormContract forms a contract with a host and adds it to the bus.
 func (c *Client) FormContract(ctx context.Context, renterAddress types.Address, renterFunds types.Currency, hostKey types.PublicKey, hostCollateral types.Currency, endHeight uint64) (contract api.ContractMetadata, err error) {
-	err = c.c.WithContext(ctx).POST("/contracts/form", api.ContractFormRequest{
-		EndHeight:      endHeight,
-		HostCollateral: hostCollateral,
-		HostKey:        hostKey,
-		RenterFunds:    renterFunds,
-		RenterAddress:  renterAddress,
-	}, &contract)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/contracts/form", api.ContractFormRequest{
+			EndHeight:      endHeight,
+			HostCollateral: hostCollateral,
+			HostKey:        hostKey,
+			RenterFunds:    renterFunds,
+			RenterAddress:  renterAddress,
+		}, &contract)
+	})
 	return
 }
 
 // KeepaliveContract extends the duration on an already acquired lock on a
 // contract.
 func (c *Client) KeepaliveContract(ctx context.Context, contractID types.FileContractID, lockID uint64, d time.Duration) (err error) {
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/contract/%s/keepalive", contractID), api.ContractKeepaliveRequest{
-		Duration: api.DurationMS(d),
-		LockID:   lockID,
-	}, nil)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/contract/%s/keepalive", contractID), api.ContractKeepaliveRequest{
+			Duration: api.DurationMS(d),
+			LockID:   lockID,
+		}, nil)
+	})
 	return
 }
 
 // ContractRevision fetches the latest revision of a contract directly from the
 // host.
 func (c *Client) ContractRevision(ctx context.Context, contractID types.FileContractID) (resp api.Revision, err error) {
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/contract/%s/revision", contractID), &resp)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/contract/%s/revision", contractID), &resp) })
 	return
 }
 
 // PrunableData returns an overview of all contract sizes, the total size and
 // the amount of data that can be pruned.
 func (c *Client) PrunableData(ctx context.Context) (prunableData api.ContractsPrunableDataResponse, err error) {
-	err = c.c.WithContext(ctx).GET("/contracts/prunable", &prunableData)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/contracts/prunable", &prunableData) })
+	return
+}
+
+// ContractsCapacity returns an estimate of how much more data can be stored
+// on the good contracts before running out of funds. The given percentage of
+// every contract's initial funds is kept in reserve.
+func (c *Client) ContractsCapacity(ctx context.Context, reservePct uint64) (resp api.ContractsCapacityResponse, err error) {
+	values := url.Values{}
+	values.Set("reserve", fmt.Sprint(reservePct))
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/contracts/capacity?"+values.Encode(), &resp) })
 	return
 }
 
 // PruneContract prunes the given contract.
 func (c *Client) PruneContract(ctx context.Context, contractID types.FileContractID, timeout time.Duration) (res api.ContractPruneResponse, err error) {
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/contract/%s/prune", contractID), api.ContractPruneRequest{Timeout: api.DurationMS(timeout)}, &res)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/contract/%s/prune", contractID), api.ContractPruneRequest{Timeout: api.DurationMS(timeout)}, &res)
+	})
+	return
+}
+
+// ReconcileContract compares the sector roots the host reports for the given
+// contract against the ones stored in the bus, sectors the host lost are
+// flagged so they get repaired.
+func (c *Client) ReconcileContract(ctx context.Context, contractID types.FileContractID, timeout time.Duration) (res api.ContractReconcileResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/contract/%s/reconcile", contractID), api.ContractReconcileRequest{Timeout: api.DurationMS(timeout)}, &res)
+	})
 	return
 }
 
@@ -151,32 +225,40 @@ func (c *Client) RenewContract(ctx context.Context, contractID types.FileContrac
 		MinNewCollateral:   minNewCollateral,
 		RenterFunds:        renterFunds,
 	}
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/contract/%s/renew", contractID), req, &renewal)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/contract/%s/renew", contractID), req, &renewal)
+	})
 	return
 }
 
 // RenewedContract returns the renewed contract for the given ID.
 func (c *Client) RenewedContract(ctx context.Context, renewedFrom types.FileContractID) (contract api.ContractMetadata, err error) {
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/contracts/renewed/%s", renewedFrom), &contract)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.GET(fmt.Sprintf("/contracts/renewed/%s", renewedFrom), &contract)
+	})
 	return
 }
 
 // RecordContractSpending records contract spending metrics for contracts.
 func (c *Client) RecordContractSpending(ctx context.Context, records []api.ContractSpendingRecord) (err error) {
-	err = c.c.WithContext(ctx).POST("/contracts/spending", records, nil)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.POST("/contracts/spending", records, nil) })
 	return
 }
 
 // ReleaseContract releases a contract that was previously acquired using AcquireContract.
 func (c *Client) ReleaseContract(ctx context.Context, contractID types.FileContractID, lockID uint64) (err error) {
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/contract/%s/release", contractID), api.ContractReleaseRequest{
-		LockID: lockID,
-	}, nil)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST(fmt.Sprintf("/contract/%s/release", contractID), api.ContractReleaseRequest{
+			LockID: lockID,
+		}, nil)
+	})
 	return
 }
 
 // UpdateContractUsability updates the usability of the given contract.
 func (c *Client) UpdateContractUsability(ctx context.Context, contractID types.FileContractID, usability string) (err error) {
-	err = c.c.WithContext(ctx).PUT(fmt.Sprintf("/contract/%s/usability", contractID), usability)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.PUT(fmt.Sprintf("/contract/%s/usability", contractID), usability)
+	})
 	return
 }
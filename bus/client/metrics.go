@@ -73,6 +73,9 @@ func (c *Client) RecordContractPruneMetric(ctx context.Context, metrics ...api.C
 }
 
 func (c *Client) PruneMetrics(ctx context.Context, metric string, cutoff time.Time) error {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
 	values := url.Values{}
 	values.Set("cutoff", api.TimeRFC3339(cutoff).String())
 	c.c.Custom("DELETE", fmt.Sprintf("/metric/%s?"+values.Encode(), metric), nil, nil)
@@ -92,6 +95,9 @@ func (c *Client) PruneMetrics(ctx context.Context, metric string, cutoff time.Ti
 }
 
 func (c *Client) recordMetric(ctx context.Context, key string, d interface{}) error {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
 	c.c.Custom("PUT", fmt.Sprintf("/metric/%s", key), (interface{})(nil), nil)
 
 	js, err := json.Marshal(d)
@@ -113,6 +119,9 @@ func (c *Client) recordMetric(ctx context.Context, key string, d interface{}) er
 }
 
 func (c *Client) metric(ctx context.Context, key string, values url.Values, res interface{}) error {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
 	c.c.Custom("GET", fmt.Sprintf("/metric/%s", key), nil, (*interface{})(nil))
 
 	u, err := url.Parse(fmt.Sprintf("%s/metric/%s", c.c.BaseURL, key))
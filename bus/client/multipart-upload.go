@@ -4,83 +4,131 @@ import (
 	"context"
 	"fmt"
 
+	"go.sia.tech/core/types"
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/object"
 )
 
 // AbortMultipartUpload aborts a multipart upload.
 func (c *Client) AbortMultipartUpload(ctx context.Context, bucket, key string, uploadID string) (err error) {
-	err = c.c.WithContext(ctx).POST("/multipart/abort", api.MultipartAbortRequest{
-		Bucket:   bucket,
-		Key:      key,
-		UploadID: uploadID,
-	}, nil)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/multipart/abort", api.MultipartAbortRequest{
+			Bucket:   bucket,
+			Key:      key,
+			UploadID: uploadID,
+		}, nil)
+	})
 	return
 }
 
 // AddMultipartPart adds a part to a multipart upload.
 func (c *Client) AddMultipartPart(ctx context.Context, bucket, key, eTag, uploadID string, partNumber int, slices []object.SlabSlice) (err error) {
-	err = c.c.WithContext(ctx).PUT("/multipart/part", api.MultipartAddPartRequest{
-		Bucket:     bucket,
-		ETag:       eTag,
-		Key:        key,
-		UploadID:   uploadID,
-		PartNumber: partNumber,
-		Slices:     slices,
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.PUT("/multipart/part", api.MultipartAddPartRequest{
+			Bucket:     bucket,
+			ETag:       eTag,
+			Key:        key,
+			UploadID:   uploadID,
+			PartNumber: partNumber,
+			Slices:     slices,
+		})
+	})
+	return
+}
+
+// AddMultipartPartProgress persists slices of a part that is still being
+// uploaded, starting at the given index. The checksum is the checksum of the
+// data the slices were created from.
+func (c *Client) AddMultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber, index int, checksum types.Hash256, slices []object.SlabSlice) (err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.PUT("/multipart/partprogress", api.MultipartAddPartProgressRequest{
+			Bucket:     bucket,
+			Key:        key,
+			UploadID:   uploadID,
+			PartNumber: partNumber,
+			Index:      index,
+			Checksum:   checksum,
+			Slices:     slices,
+		})
 	})
 	return
 }
 
 // CompleteMultipartUpload completes a multipart upload.
 func (c *Client) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []api.MultipartCompletedPart, opts api.CompleteMultipartOptions) (resp api.MultipartCompleteResponse, err error) {
-	err = c.c.WithContext(ctx).POST("/multipart/complete", api.MultipartCompleteRequest{
-		Bucket:   bucket,
-		Key:      key,
-		Metadata: opts.Metadata,
-		UploadID: uploadID,
-		Parts:    parts,
-	}, &resp)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/multipart/complete", api.MultipartCompleteRequest{
+			Bucket:   bucket,
+			Key:      key,
+			Metadata: opts.Metadata,
+			UploadID: uploadID,
+			Parts:    parts,
+		}, &resp)
+	})
 	return
 }
 
 // CreateMultipartUpload creates a new multipart upload.
 func (c *Client) CreateMultipartUpload(ctx context.Context, bucket, key string, opts api.CreateMultipartOptions) (resp api.MultipartCreateResponse, err error) {
-	err = c.c.WithContext(ctx).POST("/multipart/create", api.MultipartCreateRequest{
-		Bucket:                      bucket,
-		DisableClientSideEncryption: opts.DisableClientSideEncryption,
-		Key:                         key,
-		MimeType:                    opts.MimeType,
-		Metadata:                    opts.Metadata,
-	}, &resp)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/multipart/create", api.MultipartCreateRequest{
+			Bucket:                      bucket,
+			DisableClientSideEncryption: opts.DisableClientSideEncryption,
+			Key:                         key,
+			MimeType:                    opts.MimeType,
+			Metadata:                    opts.Metadata,
+			PinnedHosts:                 opts.PinnedHosts,
+		}, &resp)
+	})
 	return
 }
 
+// MultipartPartProgress returns the slices that were persisted for a part that
+// is still being uploaded.
+func (c *Client) MultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber int) (slices []api.MultipartPartProgressSlice, err error) {
+	var resp api.MultipartPartProgressResponse
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/multipart/partprogress", api.MultipartPartProgressRequest{
+			Bucket:     bucket,
+			Key:        key,
+			UploadID:   uploadID,
+			PartNumber: partNumber,
+		}, &resp)
+	})
+	return resp.Slices, err
+}
+
 // MultipartUpload returns information about a specific multipart upload.
 func (c *Client) MultipartUpload(ctx context.Context, uploadID string) (resp api.MultipartUpload, err error) {
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/multipart/upload/%s", uploadID), &resp)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/multipart/upload/%s", uploadID), &resp) })
 	return
 }
 
 // MultipartUploads returns information about all multipart uploads.
 func (c *Client) MultipartUploads(ctx context.Context, bucket, prefix, keyMarker, uploadIDMarker string, maxUploads int) (resp api.MultipartListUploadsResponse, err error) {
-	err = c.c.WithContext(ctx).POST("/multipart/listuploads", api.MultipartListUploadsRequest{
-		Bucket:         bucket,
-		Prefix:         prefix,
-		KeyMarker:      keyMarker,
-		UploadIDMarker: uploadIDMarker,
-		Limit:          maxUploads,
-	}, &resp)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/multipart/listuploads", api.MultipartListUploadsRequest{
+			Bucket:         bucket,
+			Prefix:         prefix,
+			KeyMarker:      keyMarker,
+			UploadIDMarker: uploadIDMarker,
+			Limit:          maxUploads,
+		}, &resp)
+	})
 	return
 }
 
 // MultipartUploadParts returns information about all parts of a multipart upload.
 func (c *Client) MultipartUploadParts(ctx context.Context, bucket, key string, uploadID string, partNumberMarker int, limit int64) (resp api.MultipartListPartsResponse, err error) {
-	err = c.c.WithContext(ctx).POST("/multipart/listparts", api.MultipartListPartsRequest{
-		Bucket:           bucket,
-		Key:              key,
-		UploadID:         uploadID,
-		PartNumberMarker: partNumberMarker,
-		Limit:            limit,
-	}, &resp)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/multipart/listparts", api.MultipartListPartsRequest{
+			Bucket:           bucket,
+			Key:              key,
+			UploadID:         uploadID,
+			PartNumberMarker: partNumberMarker,
+			Limit:            limit,
+		}, &resp)
+	})
 	return
 }
@@ -3,55 +3,74 @@ package client
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/utils"
 	"go.sia.tech/renterd/object"
 )
 
 // AddObject stores the provided object under the given path.
 func (c *Client) AddObject(ctx context.Context, bucket, path string, o object.Object, opts api.AddObjectOptions) (err error) {
 	path = api.ObjectKeyEscape(path)
-	err = c.c.WithContext(ctx).PUT(fmt.Sprintf("/object/%s", path), api.AddObjectRequest{
-		Bucket:   bucket,
-		Object:   o,
-		ETag:     opts.ETag,
-		MimeType: opts.MimeType,
-		Metadata: opts.Metadata,
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.PUT(fmt.Sprintf("/object/%s", path), api.AddObjectRequest{
+			Bucket:             bucket,
+			Object:             o,
+			Checksum:           opts.Checksum,
+			ContentDisposition: opts.ContentDisposition,
+			ETag:               opts.ETag,
+			MimeType:           opts.MimeType,
+			Metadata:           opts.Metadata,
+			PinnedHosts:        opts.PinnedHosts,
+			IdempotencyKey:     opts.IdempotencyKey,
+			UnmodifiedSince:    opts.UnmodifiedSince,
+		})
 	})
 	return
 }
 
 // CopyObject copies the object from the source bucket and path to the
-// destination bucket and path.
-func (c *Client) CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey string, opts api.CopyObjectOptions) (om api.ObjectMetadata, err error) {
-	err = c.c.WithContext(ctx).POST("/objects/copy", api.CopyObjectsRequest{
-		SourceBucket:      srcBucket,
-		DestinationBucket: dstBucket,
-		SourceKey:         srcKey,
-		DestinationKey:    dstKey,
-		MimeType:          opts.MimeType,
-		Metadata:          opts.Metadata,
-	}, &om)
+// destination bucket and path. The response indicates whether the object was
+// copied, which isn't the case if the copy was skipped due to the overwrite
+// policy.
+func (c *Client) CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey string, opts api.CopyObjectOptions) (resp api.CopyObjectResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/copy", api.CopyObjectsRequest{
+			SourceBucket:       srcBucket,
+			DestinationBucket:  dstBucket,
+			SourceKey:          srcKey,
+			DestinationKey:     dstKey,
+			MimeType:           opts.MimeType,
+			Metadata:           opts.Metadata,
+			ContentDisposition: opts.ContentDisposition,
+			OverwritePolicy:    opts.OverwritePolicy,
+		}, &resp)
+	})
 	return
 }
 
 // DeleteObject deletes the object with given key.
-func (c *Client) DeleteObject(ctx context.Context, bucket, key string) (err error) {
+func (c *Client) DeleteObject(ctx context.Context, bucket, key string, opts api.DeleteObjectOptions) (err error) {
 	values := url.Values{}
 	values.Set("bucket", bucket)
+	opts.Apply(values)
 
 	key = api.ObjectKeyEscape(key)
-	err = c.c.WithContext(ctx).DELETE(fmt.Sprintf("/object/%s?"+values.Encode(), key))
+	err = c.do(ctx, func(jc jape.Client) error { return jc.DELETE(fmt.Sprintf("/object/%s?"+values.Encode(), key)) })
 	return
 }
 
 // RemoveObjects removes objects with given prefix.
 func (c *Client) RemoveObjects(ctx context.Context, bucket, prefix string) (err error) {
-	err = c.c.WithContext(ctx).POST("/objects/remove", api.ObjectsRemoveRequest{
-		Bucket: bucket,
-		Prefix: prefix,
-	}, nil)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/remove", api.ObjectsRemoveRequest{
+			Bucket: bucket,
+			Prefix: prefix,
+		}, nil)
+	})
 	return
 }
 
@@ -64,19 +83,119 @@ func (c *Client) Object(ctx context.Context, bucket, key string, opts api.GetObj
 	key = api.ObjectKeyEscape(key)
 	key += "?" + values.Encode()
 
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/object/%s", key), &res)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/object/%s", key), &res) })
 	return
 }
 
 // Objects lists objects in the given bucket.
 func (c *Client) Objects(ctx context.Context, prefix string, opts api.ListObjectOptions) (resp api.ObjectsResponse, err error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
 	values := url.Values{}
 	opts.Apply(values)
 
 	prefix = api.ObjectKeyEscape(prefix)
-	prefix += "?" + values.Encode()
+	if opts.IfNoneMatch == "" {
+		prefix += "?" + values.Encode()
+		err = c.c.WithContext(ctx).GET(fmt.Sprintf("/objects/%s", prefix), &resp)
+		return
+	}
 
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/objects/%s", prefix), &resp)
+	u, err := url.Parse(fmt.Sprintf("%s/objects/%s", c.c.BaseURL, prefix))
+	if err != nil {
+		panic(err)
+	}
+	u.RawQuery = values.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), http.NoBody)
+	if err != nil {
+		panic(err)
+	}
+	req.SetBasicAuth("", c.c.Password)
+	req.Header.Set("If-None-Match", opts.IfNoneMatch)
+
+	_, status, err := utils.DoRequest(req, &resp)
+	if status == http.StatusNotModified {
+		return api.ObjectsResponse{}, api.ErrObjectsNotModified
+	}
+	return
+}
+
+// MoveObject moves an object from one bucket to another.
+func (c *Client) MoveObject(ctx context.Context, srcBucket, dstBucket, key string) (err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/move", api.ObjectsMoveRequest{
+			SourceBucket:      srcBucket,
+			DestinationBucket: dstBucket,
+			Key:               key,
+		}, nil)
+	})
+	return
+}
+
+// PinObject sets the pinned flag of an object. The slabs of pinned objects are
+// repaired before those of unpinned objects with the same health.
+func (c *Client) PinObject(ctx context.Context, bucket, key string, pinned bool) (err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/pin", api.ObjectsPinRequest{
+			Bucket: bucket,
+			Key:    key,
+			Pinned: pinned,
+		}, nil)
+	})
+	return
+}
+
+// ObjectEvents returns a batch of events from the object event log that were
+// recorded after the event with the given marker ID. Passing a marker of 0
+// returns the log from the start.
+func (c *Client) ObjectEvents(ctx context.Context, marker uint64, limit int) (resp api.ObjectEventsResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/events", api.ObjectEventsRequest{
+			Marker: marker,
+			Limit:  limit,
+		}, &resp)
+	})
+	return
+}
+
+// ObjectsMissingChecksum returns a batch of objects without a checksum that
+// have an ID greater than the given marker. Passing a marker of 0 starts from
+// the first object.
+func (c *Client) ObjectsMissingChecksum(ctx context.Context, marker uint64, limit int) (resp api.ObjectsMissingChecksumResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/checksums/missing", api.ObjectsMissingChecksumRequest{
+			Marker: marker,
+			Limit:  limit,
+		}, &resp)
+	})
+	return
+}
+
+// BackfillObjectChecksum sets the checksum of the object with the given ID if
+// it doesn't have one yet.
+func (c *Client) BackfillObjectChecksum(ctx context.Context, id uint64, checksum string) (err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/checksums/backfill", api.BackfillObjectChecksumRequest{
+			ID:       id,
+			Checksum: checksum,
+		}, nil)
+	})
+	return
+}
+
+// ObjectsNoSlabs returns a batch of objects in the given bucket that don't
+// reference any slabs, starting after the given marker. If missingDataOnly is
+// set, empty objects are omitted.
+func (c *Client) ObjectsNoSlabs(ctx context.Context, bucket, marker string, limit int, missingDataOnly bool) (resp api.ObjectsNoSlabsResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/noslabs", api.ObjectsNoSlabsRequest{
+			Bucket:          bucket,
+			Marker:          marker,
+			Limit:           limit,
+			MissingDataOnly: missingDataOnly,
+		}, &resp)
+	})
 	return
 }
 
@@ -86,27 +205,39 @@ func (c *Client) ObjectsStats(ctx context.Context, opts api.ObjectsStatsOpts) (o
 	if opts.Bucket != "" {
 		values.Set("bucket", opts.Bucket)
 	}
-	err = c.c.WithContext(ctx).GET("/stats/objects?"+values.Encode(), &osr)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/stats/objects?"+values.Encode(), &osr) })
 	return
 }
 
 // RenameObject renames a single object.
 func (c *Client) RenameObject(ctx context.Context, bucket, from, to string, force bool) (err error) {
-	return c.renameObjects(ctx, bucket, from, to, api.ObjectsRenameModeSingle, force)
+	return c.renameObjects(ctx, bucket, from, to, api.ObjectsRenameModeSingle, force, false, nil)
 }
 
-// RenameObjects renames all objects with the prefix 'from' to the prefix 'to'.
-func (c *Client) RenameObjects(ctx context.Context, bucket, from, to string, force bool) (err error) {
-	return c.renameObjects(ctx, bucket, from, to, api.ObjectsRenameModeMulti, force)
+// RenameObjectAllowDirectory renames a single object like RenameObject but
+// doesn't fail if 'to' is a directory of existing objects.
+func (c *Client) RenameObjectAllowDirectory(ctx context.Context, bucket, from, to string, force bool) (err error) {
+	return c.renameObjects(ctx, bucket, from, to, api.ObjectsRenameModeSingle, force, true, nil)
 }
 
-func (c *Client) renameObjects(ctx context.Context, bucket, from, to, mode string, force bool) (err error) {
-	err = c.c.WithContext(ctx).POST("/objects/rename", api.ObjectsRenameRequest{
-		Bucket: bucket,
-		Force:  force,
-		From:   from,
-		To:     to,
-		Mode:   mode,
-	}, nil)
+// RenameObjects renames all objects with the prefix 'from' to the prefix 'to'
+// and returns how many objects were renamed and overwritten.
+func (c *Client) RenameObjects(ctx context.Context, bucket, from, to string, force bool) (res api.ObjectsRenameResponse, err error) {
+	err = c.renameObjects(ctx, bucket, from, to, api.ObjectsRenameModeMulti, force, false, &res)
+	return
+}
+
+func (c *Client) renameObjects(ctx context.Context, bucket, from, to, mode string, force, allowDirCollision bool, resp any) (err error) {
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/objects/rename", api.ObjectsRenameRequest{
+			Bucket: bucket,
+			Force:  force,
+			From:   from,
+			To:     to,
+			Mode:   mode,
+
+			AllowDirectoryCollision: allowDirCollision,
+		}, resp)
+	})
 	return
 }
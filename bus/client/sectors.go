@@ -3,11 +3,23 @@ package client
 import (
 	"context"
 	"fmt"
+	"net/url"
 
 	"go.sia.tech/core/types"
+	"go.sia.tech/jape"
+	"go.sia.tech/renterd/api"
 )
 
 // DeleteHostSector deletes the given sector on host with given host key.
 func (c *Client) DeleteHostSector(ctx context.Context, hostKey types.PublicKey, sectorRoot types.Hash256) error {
-	return c.c.WithContext(ctx).DELETE(fmt.Sprintf("/sectors/%s/%s", hostKey, sectorRoot))
+	return c.do(ctx, func(jc jape.Client) error { return jc.DELETE(fmt.Sprintf("/sectors/%s/%s", hostKey, sectorRoot)) })
+}
+
+// SampleSectors returns up to n randomly picked sectors together with the host
+// that is expected to store them.
+func (c *Client) SampleSectors(ctx context.Context, n int) (samples []api.SectorSample, err error) {
+	values := url.Values{}
+	values.Set("limit", fmt.Sprint(n))
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/sectors/sample?"+values.Encode(), &samples) })
+	return
 }
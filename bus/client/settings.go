@@ -1,51 +1,90 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
 
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/utils"
 )
 
+// Settings returns the gouging, pinned and upload settings.
+func (c *Client) Settings(ctx context.Context) (resp api.SettingsResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/settings", &resp) })
+	return
+}
+
+// UpdateSettings updates the given settings in a single transaction and
+// returns the resulting settings. Settings that are omitted remain unchanged.
+func (c *Client) UpdateSettings(ctx context.Context, req api.UpdateSettingsRequest) (resp api.SettingsResponse, err error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	c.c.Custom("PUT", "/settings", api.UpdateSettingsRequest{}, (*api.SettingsResponse)(nil))
+
+	js, err := json.Marshal(req)
+	if err != nil {
+		return api.SettingsResponse{}, err
+	}
+	u, err := url.Parse(fmt.Sprintf("%s/settings", c.c.BaseURL))
+	if err != nil {
+		panic(err)
+	}
+	r, err := http.NewRequestWithContext(ctx, "PUT", u.String(), bytes.NewReader(js))
+	if err != nil {
+		panic(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.SetBasicAuth("", c.c.WithContext(ctx).Password)
+	_, _, err = utils.DoRequest(r, &resp)
+	return
+}
+
 // GougingSettings returns the gouging settings.
 func (c *Client) GougingSettings(ctx context.Context) (gs api.GougingSettings, err error) {
-	err = c.c.WithContext(ctx).GET("/settings/gouging", &gs)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/settings/gouging", &gs) })
 	return
 }
 
 // UpdateGougingSettings updates the given setting.
 func (c *Client) UpdateGougingSettings(ctx context.Context, gs api.GougingSettings) error {
-	return c.c.WithContext(ctx).PUT("/settings/gouging", gs)
+	return c.do(ctx, func(jc jape.Client) error { return jc.PUT("/settings/gouging", gs) })
 }
 
 // PinnedSettings returns the pinned settings.
 func (c *Client) PinnedSettings(ctx context.Context) (ps api.PinnedSettings, err error) {
-	err = c.c.WithContext(ctx).GET("/settings/pinned", &ps)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/settings/pinned", &ps) })
 	return
 }
 
 // UpdatePinnedSettings updates the given setting.
 func (c *Client) UpdatePinnedSettings(ctx context.Context, ps api.PinnedSettings) error {
-	return c.c.WithContext(ctx).PUT("/settings/pinned", ps)
+	return c.do(ctx, func(jc jape.Client) error { return jc.PUT("/settings/pinned", ps) })
 }
 
 // S3Settings returns the S3 settings.
 func (c *Client) S3Settings(ctx context.Context) (as api.S3Settings, err error) {
-	err = c.c.WithContext(ctx).GET("/settings/s3", &as)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/settings/s3", &as) })
 	return
 }
 
 // UpdateS3Settings updates the given setting.
 func (c *Client) UpdateS3Settings(ctx context.Context, as api.S3Settings) error {
-	return c.c.WithContext(ctx).PUT("/settings/s3", as)
+	return c.do(ctx, func(jc jape.Client) error { return jc.PUT("/settings/s3", as) })
 }
 
 // UploadSettings returns the upload settings.
 func (c *Client) UploadSettings(ctx context.Context) (css api.UploadSettings, err error) {
-	err = c.c.WithContext(ctx).GET("/settings/upload", &css)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/settings/upload", &css) })
 	return
 }
 
 // UpdateUploadSettings update the given setting.
 func (c *Client) UpdateUploadSettings(ctx context.Context, us api.UploadSettings) error {
-	return c.c.WithContext(ctx).PUT("/settings/upload", us)
+	return c.do(ctx, func(jc jape.Client) error { return jc.PUT("/settings/upload", us) })
 }
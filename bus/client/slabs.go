@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"time"
 
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/internal/utils"
 	"go.sia.tech/renterd/object"
@@ -17,6 +18,9 @@ import (
 
 // AddPartialSlab adds a partial slab to the bus.
 func (c *Client) AddPartialSlab(ctx context.Context, data []byte, minShards, totalShards uint8) (slabs []object.SlabSlice, slabBufferMaxSizeSoftReached bool, err error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
 	c.c.Custom("POST", "/slabs/partial", nil, &api.AddPartialSlabResponse{})
 	values := url.Values{}
 	values.Set("minshards", fmt.Sprint(minShards))
@@ -42,6 +46,9 @@ func (c *Client) AddPartialSlab(ctx context.Context, data []byte, minShards, tot
 
 // FetchPartialSlab fetches a partial slab from the bus.
 func (c *Client) FetchPartialSlab(ctx context.Context, key object.EncryptionKey, offset, length uint32) ([]byte, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
 	c.c.Custom("GET", fmt.Sprintf("/slabs/partial/%s", key), nil, &[]byte{})
 	values := url.Values{}
 	values.Set("offset", fmt.Sprint(offset))
@@ -70,39 +77,61 @@ func (c *Client) FetchPartialSlab(ctx context.Context, key object.EncryptionKey,
 	return io.ReadAll(resp.Body)
 }
 
+// CompleteSlabBuffers marks all slab buffers as complete, regardless of how
+// full they are, and returns the slab buffers.
+func (c *Client) CompleteSlabBuffers(ctx context.Context) (buffers []api.SlabBuffer, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.POST("/slabbuffers/complete", nil, &buffers) })
+	return
+}
+
 // MarkPackedSlabsUploaded marks the given slabs as uploaded.
 func (c *Client) MarkPackedSlabsUploaded(ctx context.Context, slabs []api.UploadedPackedSlab) (err error) {
-	err = c.c.WithContext(ctx).POST("/slabbuffer/done", api.PackedSlabsRequestPOST{
-		Slabs: slabs,
-	}, nil)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/slabbuffer/done", api.PackedSlabsRequestPOST{
+			Slabs: slabs,
+		}, nil)
+	})
 	return
 }
 
 // PackedSlabsForUpload returns packed slabs that are ready to upload.
 func (c *Client) PackedSlabsForUpload(ctx context.Context, lockingDuration time.Duration, minShards, totalShards uint8, limit int) (slabs []api.PackedSlab, err error) {
-	err = c.c.WithContext(ctx).POST("/slabbuffer/fetch", api.PackedSlabsRequestGET{
-		LockingDuration: api.DurationMS(lockingDuration),
-		MinShards:       minShards,
-		TotalShards:     totalShards,
-		Limit:           limit,
-	}, &slabs)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/slabbuffer/fetch", api.PackedSlabsRequestGET{
+			LockingDuration: api.DurationMS(lockingDuration),
+			MinShards:       minShards,
+			TotalShards:     totalShards,
+			Limit:           limit,
+		}, &slabs)
+	})
 	return
 }
 
 // RefreshHealth recomputes the cached health of all slabs.
 func (c *Client) RefreshHealth(ctx context.Context) error {
-	return c.c.WithContext(ctx).POST("/slabs/refreshhealth", nil, nil)
+	return c.do(ctx, func(jc jape.Client) error { return jc.POST("/slabs/refreshhealth", nil, nil) })
 }
 
 // Slab returns the slab with the given key from the bus.
 func (c *Client) Slab(ctx context.Context, key object.EncryptionKey) (slab object.Slab, err error) {
-	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/slab/%s", key), &slab)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET(fmt.Sprintf("/slab/%s", key), &slab) })
 	return
 }
 
 // SlabBuffers returns information about the number of objects and their size.
-func (c *Client) SlabBuffers() (buffers []api.SlabBuffer, err error) {
-	err = c.c.GET("/slabbuffers", &buffers)
+func (c *Client) SlabBuffers(ctx context.Context) (buffers []api.SlabBuffer, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/slabbuffers", &buffers) })
+	return
+}
+
+// SharedSlabs returns up to 'limit' slabs that are referenced by more than
+// 'minObjects' objects, sorted by the number of objects referencing them. The
+// limit must be positive.
+func (c *Client) SharedSlabs(ctx context.Context, minObjects uint64, limit int) (slabs []api.SharedSlab, err error) {
+	values := url.Values{}
+	values.Set("minobjects", fmt.Sprint(minObjects))
+	values.Set("limit", fmt.Sprint(limit))
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/slabs/shared?"+values.Encode(), &slabs) })
 	return
 }
 
@@ -111,16 +140,30 @@ func (c *Client) SlabBuffers() (buffers []api.SlabBuffer, err error) {
 // given 'set'.
 func (c *Client) SlabsForMigration(ctx context.Context, healthCutoff float64, limit int) (slabs []api.UnhealthySlab, err error) {
 	var usr api.SlabsForMigrationResponse
-	err = c.c.WithContext(ctx).POST("/slabs/migration", api.MigrationSlabsRequest{HealthCutoff: healthCutoff, Limit: limit}, &usr)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/slabs/migration", api.MigrationSlabsRequest{HealthCutoff: healthCutoff, Limit: limit}, &usr)
+	})
 	if err != nil {
 		return
 	}
 	return usr.Slabs, nil
 }
 
+// SlabBuffersStats returns stats about the slab buffers and their disk usage.
+func (c *Client) SlabBuffersStats(ctx context.Context) (resp api.SlabBuffersStatsResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/stats/slabbuffers", &resp) })
+	return
+}
+
+// SlabsStats returns the number of uploaded slabs per health range.
+func (c *Client) SlabsStats(ctx context.Context) (resp api.SlabsStatsResponse, err error) {
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/stats/slabs", &resp) })
+	return
+}
+
 // UpdateSlab updates a slab with given key, adding the given contract sector
 // links to the database.
 func (c *Client) UpdateSlab(ctx context.Context, key object.EncryptionKey, sectors []api.UploadedSector) (err error) {
-	err = c.c.WithContext(ctx).PUT(fmt.Sprintf("/slab/%s", key), sectors)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.PUT(fmt.Sprintf("/slab/%s", key), sectors) })
 	return
 }
@@ -5,23 +5,24 @@ import (
 	"fmt"
 
 	"go.sia.tech/core/types"
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
 )
 
 // AddUploadingSectors adds the given sectors to the upload with given id.
 func (c *Client) AddUploadingSectors(ctx context.Context, uID api.UploadID, roots []types.Hash256) (err error) {
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/upload/%s/sector", uID), &roots, nil)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.POST(fmt.Sprintf("/upload/%s/sector", uID), &roots, nil) })
 	return
 }
 
 // FinishUpload marks the given upload as finished.
 func (c *Client) FinishUpload(ctx context.Context, uID api.UploadID) (err error) {
-	err = c.c.WithContext(ctx).DELETE(fmt.Sprintf("/upload/%s", uID))
+	err = c.do(ctx, func(jc jape.Client) error { return jc.DELETE(fmt.Sprintf("/upload/%s", uID)) })
 	return
 }
 
 // TrackUpload tracks the upload with given id in the bus.
 func (c *Client) TrackUpload(ctx context.Context, uID api.UploadID) (err error) {
-	err = c.c.WithContext(ctx).POST(fmt.Sprintf("/upload/%s", uID), nil, nil)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.POST(fmt.Sprintf("/upload/%s", uID), nil, nil) })
 	return
 }
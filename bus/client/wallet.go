@@ -6,30 +6,33 @@ import (
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
 )
 
 // SendSiacoins is a helper method that sends siacoins to the given address.
 func (c *Client) SendSiacoins(ctx context.Context, addr types.Address, amt types.Currency, useUnconfirmedTxns bool) (txnID types.TransactionID, err error) {
-	err = c.c.WithContext(ctx).POST("/wallet/send", api.WalletSendRequest{
-		Address:          addr,
-		Amount:           amt,
-		SubtractMinerFee: false,
-		UseUnconfirmed:   useUnconfirmedTxns,
-	}, &txnID)
+	err = c.do(ctx, func(jc jape.Client) error {
+		return jc.POST("/wallet/send", api.WalletSendRequest{
+			Address:          addr,
+			Amount:           amt,
+			SubtractMinerFee: false,
+			UseUnconfirmed:   useUnconfirmedTxns,
+		}, &txnID)
+	})
 	return
 }
 
 // Wallet calls the /wallet endpoint on the bus.
 func (c *Client) Wallet(ctx context.Context) (resp api.WalletResponse, err error) {
-	err = c.c.WithContext(ctx).GET("/wallet", &resp)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/wallet", &resp) })
 	return
 }
 
 // WalletPending returns the txpool transactions that are relevant to the
 // wallet.
 func (c *Client) WalletPending(ctx context.Context) (resp []wallet.Event, err error) {
-	err = c.c.WithContext(ctx).GET("/wallet/pending", &resp)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/wallet/pending", &resp) })
 	return
 }
 
@@ -42,7 +45,7 @@ func (c *Client) WalletRedistribute(ctx context.Context, outputs int, amount typ
 		Outputs: outputs,
 	}
 
-	err = c.c.WithContext(ctx).POST("/wallet/redistribute", req, &ids)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.POST("/wallet/redistribute", req, &ids) })
 	return
 }
 
@@ -52,6 +55,6 @@ func (c *Client) WalletEvents(ctx context.Context, opts ...api.WalletTransaction
 	for _, opt := range opts {
 		opt(values)
 	}
-	err = c.c.WithContext(ctx).GET("/wallet/events?"+values.Encode(), &resp)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/wallet/events?"+values.Encode(), &resp) })
 	return
 }
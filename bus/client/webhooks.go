@@ -3,13 +3,14 @@ package client
 import (
 	"context"
 
+	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/webhooks"
 )
 
 // BroadcastAction broadcasts an action that triggers a webhook.
 func (c *Client) BroadcastAction(ctx context.Context, action webhooks.Event) error {
-	err := c.c.WithContext(ctx).POST("/webhooks/action", action, nil)
+	err := c.do(ctx, func(jc jape.Client) error { return jc.POST("/webhooks/action", action, nil) })
 	return err
 }
 
@@ -20,12 +21,12 @@ func (c *Client) UnregisterWebhook(ctx context.Context, webhook webhooks.Webhook
 
 // RegisterWebhook registers the given webhook.
 func (c *Client) RegisterWebhook(ctx context.Context, webhook webhooks.Webhook) error {
-	err := c.c.WithContext(ctx).POST("/webhooks", webhook, nil)
+	err := c.do(ctx, func(jc jape.Client) error { return jc.POST("/webhooks", webhook, nil) })
 	return err
 }
 
 // Webhooks returns all webhooks currently registered.
 func (c *Client) Webhooks(ctx context.Context) (resp api.WebhookResponse, err error) {
-	err = c.c.WithContext(ctx).GET("/webhooks", &resp)
+	err = c.do(ctx, func(jc jape.Client) error { return jc.GET("/webhooks", &resp) })
 	return
 }
@@ -13,6 +13,7 @@ import (
 	ibus "go.sia.tech/renterd/internal/bus"
 	"go.sia.tech/renterd/internal/gouging"
 	rhp2 "go.sia.tech/renterd/internal/rhp/v2"
+	"go.uber.org/zap"
 )
 
 func (b *Bus) pruneContractV1(ctx context.Context, rk types.PrivateKey, cm api.ContractMetadata, hostIP string, gc gouging.Checker, pendingUploads map[types.Hash256]struct{}) (api.ContractPruneResponse, error) {
@@ -44,21 +45,7 @@ func (b *Bus) pruneContractV1(ctx context.Context, rk types.PrivateKey, cm api.C
 	}
 
 	// record spending
-	if !spending.Total().IsZero() {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-		defer cancel()
-		b.store.RecordContractSpending(ctx, []api.ContractSpendingRecord{
-			{
-				ContractSpending: spending,
-				ContractID:       cm.ID,
-				RevisionNumber:   rev.RevisionNumber,
-				Size:             rev.Filesize,
-
-				MissedHostPayout:  rev.MissedHostPayout(),
-				ValidRenterPayout: rev.ValidRenterPayout(),
-			},
-		})
-	}
+	b.recordSpendingV1(cm.ID, rev, spending)
 
 	return api.ContractPruneResponse{
 		ContractSize: rev.Filesize,
@@ -92,34 +79,9 @@ func (b *Bus) pruneContractV2(ctx context.Context, rk types.PrivateKey, cm api.C
 	}
 
 	// fetch all contract roots
-	numsectors := rev.Filesize / rhpv4.SectorSize
-	sectorRoots := make([]types.Hash256, 0, numsectors)
-	var rootsUsage rhpv4.Usage
-	for offset := uint64(0); offset < numsectors; {
-		// calculate the batch size
-		length := uint64(rhpv4.MaxSectorBatchSize)
-		if offset+length > numsectors {
-			length = numsectors - offset
-		}
-
-		// fetch the batch
-		res, err := b.rhp4Client.SectorRoots(ctx, cm.HostKey, hostIP, b.cm.TipState(), prices, signer, cRHP4.ContractRevision{
-			ID:       cm.ID,
-			Revision: rev,
-		}, offset, length)
-		if err != nil {
-			return api.ContractPruneResponse{}, err
-		}
-
-		// update revision since it was revised
-		rev = res.Revision
-
-		// collect roots
-		sectorRoots = append(sectorRoots, res.Roots...)
-		offset += uint64(len(res.Roots))
-
-		// update the cost
-		rootsUsage = rootsUsage.Add(res.Usage)
+	sectorRoots, rev, rootsUsage, err := b.fetchContractRootsV2(ctx, cm, hostIP, prices, signer, rev)
+	if err != nil {
+		return api.ContractPruneResponse{}, err
 	}
 
 	// fetch indices to prune
@@ -157,24 +119,10 @@ func (b *Bus) pruneContractV2(ctx context.Context, rk types.PrivateKey, cm api.C
 	rev = res.Revision // update rev
 
 	// record spending
-	if !rootsUsage.Add(deleteUsage).RenterCost().IsZero() {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-		defer cancel()
-		b.store.RecordContractSpending(ctx, []api.ContractSpendingRecord{
-			{
-				ContractSpending: api.ContractSpending{
-					Deletions:   deleteUsage.RenterCost(),
-					SectorRoots: rootsUsage.RenterCost(),
-				},
-				ContractID:     cm.ID,
-				RevisionNumber: rev.RevisionNumber,
-				Size:           rev.Filesize,
-
-				MissedHostPayout:  rev.MissedHostOutput().Value,
-				ValidRenterPayout: rev.RenterOutput.Value,
-			},
-		})
-	}
+	b.recordSpendingV2(cm.ID, rev, api.ContractSpending{
+		Deletions:   deleteUsage.RenterCost(),
+		SectorRoots: rootsUsage.RenterCost(),
+	})
 
 	return api.ContractPruneResponse{
 		ContractSize: rev.Filesize,
@@ -182,3 +130,81 @@ func (b *Bus) pruneContractV2(ctx context.Context, rk types.PrivateKey, cm api.C
 		Remaining:    (totalToPrune - uint64(len(toPrune))) * rhpv4.SectorSize,
 	}, nil
 }
+
+// recordSpendingV1 records the spending of an RPC on the given contract
+// together with the revision that resulted from it.
+func (b *Bus) recordSpendingV1(fcid types.FileContractID, rev *types.FileContractRevision, spending api.ContractSpending) {
+	if rev == nil || spending.Total().IsZero() {
+		return
+	}
+	b.recordSpending(api.ContractSpendingRecord{
+		ContractSpending: spending,
+		ContractID:       fcid,
+		RevisionNumber:   rev.RevisionNumber,
+		Size:             rev.Filesize,
+
+		MissedHostPayout:  rev.MissedHostPayout(),
+		ValidRenterPayout: rev.ValidRenterPayout(),
+	})
+}
+
+// recordSpendingV2 records the spending of an RPC on the given contract
+// together with the revision that resulted from it.
+func (b *Bus) recordSpendingV2(fcid types.FileContractID, rev types.V2FileContract, spending api.ContractSpending) {
+	if spending.Total().IsZero() {
+		return
+	}
+	b.recordSpending(api.ContractSpendingRecord{
+		ContractSpending: spending,
+		ContractID:       fcid,
+		RevisionNumber:   rev.RevisionNumber,
+		Size:             rev.Filesize,
+
+		MissedHostPayout:  rev.MissedHostOutput().Value,
+		ValidRenterPayout: rev.RenterOutput.Value,
+	})
+}
+
+func (b *Bus) recordSpending(record api.ContractSpendingRecord) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := b.store.RecordContractSpending(ctx, []api.ContractSpendingRecord{record}); err != nil {
+		b.logger.Errorw("failed to record contract spending", "fcid", record.ContractID, zap.Error(err))
+	}
+}
+
+// fetchContractRootsV2 fetches all sector roots of the given contract from the
+// host in batches, it returns the roots as well as the latest revision and the
+// usage of fetching them.
+func (b *Bus) fetchContractRootsV2(ctx context.Context, cm api.ContractMetadata, hostIP string, prices rhpv4.HostPrices, signer cRHP4.ContractSigner, rev types.V2FileContract) ([]types.Hash256, types.V2FileContract, rhpv4.Usage, error) {
+	numsectors := rev.Filesize / rhpv4.SectorSize
+	sectorRoots := make([]types.Hash256, 0, numsectors)
+	var rootsUsage rhpv4.Usage
+	for offset := uint64(0); offset < numsectors; {
+		// calculate the batch size
+		length := uint64(rhpv4.MaxSectorBatchSize)
+		if offset+length > numsectors {
+			length = numsectors - offset
+		}
+
+		// fetch the batch
+		res, err := b.rhp4Client.SectorRoots(ctx, cm.HostKey, hostIP, b.cm.TipState(), prices, signer, cRHP4.ContractRevision{
+			ID:       cm.ID,
+			Revision: rev,
+		}, offset, length)
+		if err != nil {
+			return nil, types.V2FileContract{}, rhpv4.Usage{}, err
+		}
+
+		// update revision since it was revised
+		rev = res.Revision
+
+		// collect roots
+		sectorRoots = append(sectorRoots, res.Roots...)
+		offset += uint64(len(res.Roots))
+
+		// update the cost
+		rootsUsage = rootsUsage.Add(res.Usage)
+	}
+	return sectorRoots, rev, rootsUsage, nil
+}
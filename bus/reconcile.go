@@ -0,0 +1,97 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	ibus "go.sia.tech/renterd/internal/bus"
+	"go.sia.tech/renterd/internal/gouging"
+)
+
+// reconcileContract compares the sector roots the host reports for the given
+// contract against the roots stored in the database. Sectors the host no
+// longer has are removed from the host's contracts, which lowers the health of
+// the affected slabs and causes them to be repaired.
+func (b *Bus) reconcileContract(ctx context.Context, rk types.PrivateKey, cm api.ContractMetadata, host api.Host, gc gouging.Checker) (api.ContractReconcileResponse, error) {
+	// fetch the roots from the host, this requires the contract lock to be
+	// held to make sure no sectors are appended in the meantime
+	var hostRoots []types.Hash256
+	var err error
+	if b.isPassedV2AllowHeight() {
+		hostRoots, err = b.contractRootsV2(ctx, rk, cm, host.V2SiamuxAddr(), gc)
+	} else {
+		hostRoots, err = b.contractRootsV1(ctx, rk, cm, host.NetAddress, gc)
+	}
+	if err != nil {
+		return api.ContractReconcileResponse{}, fmt.Errorf("failed to fetch roots from host: %w", err)
+	}
+
+	// fetch the roots we expect the host to have
+	roots, err := b.store.ContractRoots(ctx, cm.ID)
+	if err != nil {
+		return api.ContractReconcileResponse{}, fmt.Errorf("failed to fetch contract roots: %w", err)
+	}
+
+	// flag the sectors the host has lost
+	has := make(map[types.Hash256]struct{}, len(hostRoots))
+	for _, root := range hostRoots {
+		has[root] = struct{}{}
+	}
+	res := api.ContractReconcileResponse{Checked: uint64(len(roots))}
+	for _, root := range roots {
+		if _, ok := has[root]; ok {
+			continue
+		}
+		if _, err := b.store.DeleteHostSector(ctx, cm.HostKey, root); err != nil {
+			return api.ContractReconcileResponse{}, fmt.Errorf("failed to flag lost sector %v: %w", root, err)
+		}
+		res.Missing = append(res.Missing, root)
+	}
+	if len(res.Missing) > 0 {
+		b.logger.Warnw("host lost sectors", "hk", cm.HostKey, "fcid", cm.ID, "missing", len(res.Missing), "checked", len(roots))
+	}
+	return res, nil
+}
+
+func (b *Bus) contractRootsV1(ctx context.Context, rk types.PrivateKey, cm api.ContractMetadata, hostIP string, gc gouging.Checker) ([]types.Hash256, error) {
+	roots, rev, cost, err := b.rhp2Client.ContractRoots(ctx, rk, gc, hostIP, cm.HostKey, cm.ID, cm.RevisionNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// record spending
+	b.recordSpendingV1(cm.ID, rev, api.ContractSpending{SectorRoots: cost})
+	return roots, nil
+}
+
+func (b *Bus) contractRootsV2(ctx context.Context, rk types.PrivateKey, cm api.ContractMetadata, hostIP string, gc gouging.Checker) ([]types.Hash256, error) {
+	signer := ibus.NewFormContractSigner(b.w, rk)
+
+	// get latest revision
+	rev, err := b.rhp4Client.LatestRevision(ctx, cm.HostKey, hostIP, cm.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch revision: %w", err)
+	} else if rev.RevisionNumber < cm.RevisionNumber {
+		return nil, fmt.Errorf("latest known revision %d is less than contract revision %d", rev.RevisionNumber, cm.RevisionNumber)
+	}
+
+	// get prices and make sure they are sane
+	settings, err := b.rhp4Client.Settings(ctx, cm.HostKey, hostIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+	} else if gb := gc.CheckV2(settings); gb.Gouging() {
+		return nil, fmt.Errorf("host is gouging: %v", gb.String())
+	}
+
+	// fetch all contract roots
+	roots, rev, usage, err := b.fetchContractRootsV2(ctx, cm, hostIP, settings.Prices, signer, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	// record spending
+	b.recordSpendingV2(cm.ID, rev, api.ContractSpending{SectorRoots: usage.RenterCost()})
+	return roots, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"net/http"
 	"runtime"
 	"sort"
@@ -25,6 +26,7 @@ import (
 	"go.sia.tech/renterd/internal/utils"
 	"go.sia.tech/renterd/stores/sql"
 
+	"go.sia.tech/renterd/internal/contracts"
 	"go.sia.tech/renterd/internal/gouging"
 
 	"go.sia.tech/core/gateway"
@@ -294,6 +296,9 @@ func (b *Bus) bucketsHandlerPolicyPUT(jc jape.Context) {
 	var req api.BucketUpdatePolicyRequest
 	if jc.Decode(&req) != nil {
 		return
+	} else if err := req.Validate(); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
 	}
 	bucket := jc.PathParam("name")
 	if bucket == "" {
@@ -305,6 +310,9 @@ func (b *Bus) bucketsHandlerPolicyPUT(jc jape.Context) {
 	if errors.Is(err, api.ErrBucketNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
+	} else if errors.Is(err, api.ErrObjectKeyCaseConflict) {
+		jc.Error(err, http.StatusConflict)
+		return
 	}
 	jc.Check("failed to create bucket", err)
 }
@@ -317,8 +325,12 @@ func (b *Bus) bucketHandlerDELETE(jc jape.Context) {
 		jc.Error(errors.New("no name provided"), http.StatusBadRequest)
 		return
 	}
+	var force bool
+	if jc.DecodeForm("force", &force) != nil {
+		return
+	}
 
-	err := b.store.DeleteBucket(jc.Request.Context(), name)
+	err := b.store.DeleteBucket(jc.Request.Context(), name, force)
 	if errors.Is(err, api.ErrBucketNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
@@ -330,6 +342,59 @@ func (b *Bus) bucketHandlerDELETE(jc jape.Context) {
 	jc.Check("failed to delete bucket", err)
 }
 
+func (b *Bus) bucketManifestHandlerGET(jc jape.Context) {
+	jc.Custom(nil, []api.ObjectManifestEntry{})
+
+	var name string
+	if jc.DecodeParam("name", &name) != nil {
+		return
+	}
+	var marker string
+	if jc.DecodeForm("marker", &marker) != nil {
+		return
+	}
+
+	// fetch the first batch before writing anything to be able to return a
+	// proper error
+	ctx := jc.Request.Context()
+	entries, err := b.store.ObjectManifest(ctx, name, marker, objectManifestBatchSize)
+	if errors.Is(err, api.ErrBucketNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch object manifest", err) != nil {
+		return
+	}
+
+	// stream the manifest as NDJSON, a client that gets cut off can resume
+	// using the key of the last entry it received as the marker, a complete
+	// manifest ends with a sentinel entry so truncated responses can be
+	// detected
+	jc.ResponseWriter.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(jc.ResponseWriter)
+	flusher, _ := jc.ResponseWriter.(http.Flusher)
+	for {
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return // client disconnected
+			}
+		}
+		if len(entries) < objectManifestBatchSize {
+			break
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		marker = entries[len(entries)-1].Key
+		entries, err = b.store.ObjectManifest(ctx, name, marker, objectManifestBatchSize)
+		if err != nil {
+			b.logger.Errorw("failed to fetch object manifest", "bucket", name, "marker", marker, zap.Error(err))
+			return
+		}
+	}
+	_ = enc.Encode(api.ObjectManifestTrailer{Done: true})
+}
+
 func (b *Bus) bucketHandlerGET(jc jape.Context) {
 	var name string
 	if jc.DecodeParam("name", &name) != nil {
@@ -348,6 +413,47 @@ func (b *Bus) bucketHandlerGET(jc jape.Context) {
 	jc.Encode(bucket)
 }
 
+func (b *Bus) bucketLifecycleHandlerGET(jc jape.Context) {
+	var name string
+	if jc.DecodeParam("name", &name) != nil {
+		return
+	} else if name == "" {
+		jc.Error(errors.New("parameter 'name' is required"), http.StatusBadRequest)
+		return
+	}
+	rules, err := b.store.BucketLifecycleRules(jc.Request.Context(), name)
+	if errors.Is(err, api.ErrBucketNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch lifecycle rules", err) != nil {
+		return
+	}
+	jc.Encode(rules)
+}
+
+func (b *Bus) bucketLifecycleHandlerPUT(jc jape.Context) {
+	var name string
+	if jc.DecodeParam("name", &name) != nil {
+		return
+	} else if name == "" {
+		jc.Error(errors.New("parameter 'name' is required"), http.StatusBadRequest)
+		return
+	}
+	var req api.BucketUpdateLifecycleRequest
+	if jc.Decode(&req) != nil {
+		return
+	} else if err := req.Validate(); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	err := b.store.UpdateBucketLifecycleRules(jc.Request.Context(), name, req.Rules)
+	if errors.Is(err, api.ErrBucketNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	}
+	jc.Check("failed to update lifecycle rules", err)
+}
+
 func (b *Bus) walletHandler(jc jape.Context) {
 	address := b.w.Address()
 	balance, err := b.w.Balance()
@@ -658,6 +764,54 @@ func (b *Bus) hostsRemoveHandlerPOST(jc jape.Context) {
 	jc.Encode(removed)
 }
 
+func (b *Bus) hostsVerificationsHandlerGET(jc jape.Context) {
+	stats, err := b.store.HostVerificationStats(jc.Request.Context())
+	if jc.Check("couldn't fetch host verification stats", err) != nil {
+		return
+	}
+	jc.Encode(stats)
+}
+
+func (b *Bus) hostsVerificationsHandlerPOST(jc jape.Context) {
+	var req api.HostsVerificationsRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+	jc.Check("couldn't record host verifications", b.store.RecordHostVerifications(jc.Request.Context(), req.Verifications))
+}
+
+func (b *Bus) hostsGougingHandlerGET(jc jape.Context) {
+	var hostKey types.PublicKey
+	if jc.DecodeParam("hostkey", &hostKey) != nil {
+		return
+	}
+	host, err := b.store.Host(jc.Request.Context(), hostKey)
+	if errors.Is(err, api.ErrHostNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("couldn't load host", err) != nil {
+		return
+	}
+
+	gp, err := b.gougingParams(jc.Request.Context())
+	if jc.Check("could not get gouging parameters", err) != nil {
+		return
+	}
+	gc := gouging.NewChecker(gp.GougingSettings, gp.ConsensusState)
+
+	// replay the gouging checks with the current settings
+	report := api.HostGougingReport{HostKey: hostKey, V2: host.IsV2()}
+	if report.V2 {
+		report.Breakdown = gc.CheckV2(host.V2Settings)
+		report.Checks = gc.DiagnoseV2(host.V2Settings)
+	} else {
+		report.Breakdown = gc.CheckV1(&host.Settings, &host.PriceTable.HostPriceTable)
+		report.Checks = gc.DiagnoseV1(&host.Settings, &host.PriceTable.HostPriceTable)
+	}
+	report.Gouging = report.Breakdown.Gouging()
+	jc.Encode(report)
+}
+
 func (b *Bus) hostsPubkeyHandlerGET(jc jape.Context) {
 	var hostKey types.PublicKey
 	if jc.DecodeParam("hostkey", &hostKey) != nil {
@@ -827,6 +981,75 @@ func (b *Bus) contractsHandlerGET(jc jape.Context) {
 	}
 }
 
+func (b *Bus) contractsSubnetHandlerGET(jc jape.Context) {
+	var cidr string
+	if jc.DecodeForm("cidr", &cidr) != nil {
+		return
+	}
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		jc.Error(fmt.Errorf("invalid cidr '%v': %w", cidr, err), http.StatusBadRequest)
+		return
+	}
+
+	// fetch active contracts
+	ctx := jc.Request.Context()
+	contracts, err := b.store.Contracts(ctx, api.ContractsOpts{FilterMode: api.ContractFilterModeActive})
+	if jc.Check("couldn't load contracts", err) != nil {
+		return
+	} else if len(contracts) == 0 {
+		jc.Encode([]api.ContractHostIP{})
+		return
+	}
+
+	// fetch their hosts
+	var hks []types.PublicKey
+	seen := make(map[types.PublicKey]struct{})
+	for _, c := range contracts {
+		if _, ok := seen[c.HostKey]; !ok {
+			seen[c.HostKey] = struct{}{}
+			hks = append(hks, c.HostKey)
+		}
+	}
+	hosts, err := b.store.Hosts(ctx, api.HostOptions{
+		FilterMode:    api.HostFilterModeAll,
+		UsabilityMode: api.UsabilityFilterModeAll,
+		KeyIn:         hks,
+		Limit:         -1,
+	})
+	if jc.Check("couldn't fetch hosts", err) != nil {
+		return
+	}
+
+	// resolve the hosts' addresses, hosts that can't be resolved are skipped
+	hostIPs := make(map[types.PublicKey][]net.IPAddr)
+	for _, h := range hosts {
+		ips, err := utils.ResolveHostIPs(ctx, h.Addresses())
+		if err != nil {
+			b.logger.Debugw("failed to resolve host addresses", "hostKey", h.PublicKey, zap.Error(err))
+			continue
+		}
+		for _, ip := range ips {
+			if subnet.Contains(ip.IP) {
+				hostIPs[h.PublicKey] = append(hostIPs[h.PublicKey], ip)
+			}
+		}
+	}
+
+	// collect the contracts
+	matches := make([]api.ContractHostIP, 0)
+	for _, c := range contracts {
+		for _, ip := range hostIPs[c.HostKey] {
+			matches = append(matches, api.ContractHostIP{
+				ContractID: c.ID,
+				HostKey:    c.HostKey,
+				IP:         ip.String(),
+			})
+		}
+	}
+	jc.Encode(matches)
+}
+
 func (b *Bus) contractsRenewedIDHandlerGET(jc jape.Context) {
 	var id types.FileContractID
 	if jc.DecodeParam("id", &id) != nil {
@@ -848,6 +1071,60 @@ func (b *Bus) contractsArchiveHandlerPOST(jc jape.Context) {
 	jc.Check("failed to archive contracts", b.store.ArchiveContracts(jc.Request.Context(), toArchive))
 }
 
+func (b *Bus) contractOffboardHandlerGET(jc jape.Context) {
+	var id types.FileContractID
+	if jc.DecodeParam("id", &id) != nil {
+		return
+	}
+
+	res, err := b.store.ContractOffboarding(jc.Request.Context(), id)
+	if errors.Is(err, api.ErrContractNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch contract offboarding", err) != nil {
+		return
+	}
+	jc.Encode(res)
+}
+
+func (b *Bus) contractOffboardHandlerPOST(jc jape.Context) {
+	var id types.FileContractID
+	if jc.DecodeParam("id", &id) != nil {
+		return
+	}
+
+	res, err := b.store.OffboardContract(jc.Request.Context(), id)
+	if errors.Is(err, api.ErrContractNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to offboard contract", err) != nil {
+		return
+	}
+	jc.Encode(res)
+}
+
+func (b *Bus) contractsRelinkHandlerPOST(jc jape.Context) {
+	var renewals api.ContractsRelinkRequest
+	if jc.Decode(&renewals) != nil {
+		return
+	}
+	for from, to := range renewals {
+		if from == to {
+			jc.Error(fmt.Errorf("contract %v can't be relinked to itself", from), http.StatusBadRequest)
+			return
+		}
+	}
+
+	relinked, err := b.store.RelinkContractSectors(jc.Request.Context(), renewals)
+	if errors.Is(err, api.ErrContractNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to relink contract sectors", err) != nil {
+		return
+	}
+	jc.Encode(api.ContractsRelinkResponse{Relinked: relinked})
+}
+
 func (b *Bus) contractAcquireHandlerPOST(jc jape.Context) {
 	var id types.FileContractID
 	if jc.DecodeParam("id", &id) != nil {
@@ -900,31 +1177,11 @@ func (b *Bus) contractLatestRevisionHandlerGET(jc jape.Context) {
 		return
 	}
 
-	if host.IsV2() {
-		revision, err := b.rhp4Client.LatestRevision(jc.Request.Context(), contract.HostKey, host.V2SiamuxAddr(), fcid)
-		if jc.Check("failed to fetch revision", err) != nil {
-			return
-		}
-		jc.Encode(api.Revision{
-			ContractID:      fcid,
-			MissedHostValue: revision.MissedHostValue,
-			RenterFunds:     revision.RenterOutput.Value,
-			RevisionNumber:  revision.RevisionNumber,
-			Size:            revision.Filesize,
-		})
-	} else {
-		revision, err := b.rhp3Client.Revision(jc.Request.Context(), fcid, contract.HostKey, host.Settings.SiamuxAddr())
-		if jc.Check("failed to fetch revision", err) != nil {
-			return
-		}
-		jc.Encode(api.Revision{
-			ContractID:      fcid,
-			MissedHostValue: revision.MissedHostPayout(),
-			RenterFunds:     revision.ValidRenterPayout(),
-			RevisionNumber:  revision.RevisionNumber,
-			Size:            revision.Filesize,
-		})
+	revision, err := b.latestRevision(jc.Request.Context(), contract.ID, host)
+	if jc.Check("failed to fetch revision", err) != nil {
+		return
 	}
+	jc.Encode(revision)
 }
 
 func (b *Bus) contractPruneHandlerPOST(jc jape.Context) {
@@ -1000,7 +1257,174 @@ func (b *Bus) contractPruneHandlerPOST(jc jape.Context) {
 	if jc.Check("failed to prune contract", err) != nil {
 		return
 	}
-	jc.Encode(res)
+	jc.Encode(res)
+}
+
+func (b *Bus) contractReconcileHandlerPOST(jc jape.Context) {
+	ctx := jc.Request.Context()
+
+	// decode fcid
+	var fcid types.FileContractID
+	if jc.DecodeParam("id", &fcid) != nil {
+		return
+	}
+
+	// decode timeout
+	var req api.ContractReconcileRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+
+	// create gouging checker
+	gp, err := b.gougingParams(ctx)
+	if jc.Check("couldn't fetch gouging parameters", err) != nil {
+		return
+	}
+	gc := gouging.NewChecker(gp.GougingSettings, gp.ConsensusState)
+
+	// apply timeout
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout))
+		defer cancel()
+	}
+
+	// acquire contract lock and defer the release, this prevents sectors
+	// from being appended while we compare the roots
+	lockID, err := b.contractLocker.Acquire(ctx, lockingPriorityReconciliation, fcid, time.Duration(math.MaxInt64))
+	if jc.Check("couldn't acquire contract lock", err) != nil {
+		return
+	}
+	defer func() {
+		if err := b.contractLocker.Release(fcid, lockID); err != nil {
+			b.logger.Errorw("failed to release contract lock", zap.Error(err))
+		}
+	}()
+
+	// fetch the contract from the bus
+	c, err := b.store.Contract(ctx, fcid)
+	if errors.Is(err, api.ErrContractNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("couldn't fetch contract", err) != nil {
+		return
+	}
+
+	// fetch the corresponding host
+	host, err := b.store.Host(ctx, c.HostKey)
+	if jc.Check("failed to fetch host for reconciliation", err) != nil {
+		return
+	}
+
+	// reconcile the contract
+	rk := b.masterKey.DeriveContractKey(c.HostKey)
+	res, err := b.reconcileContract(ctx, rk, c, host, gc)
+	if jc.Check("failed to reconcile contract", err) != nil {
+		return
+	}
+	jc.Encode(res)
+}
+
+func (b *Bus) contractsCapacityHandlerGET(jc jape.Context) {
+	reserve := uint64(contracts.DefaultOutOfFundsReserve)
+	if jc.DecodeForm("reserve", &reserve) != nil {
+		return
+	} else if reserve > 100 {
+		jc.Error(errors.New("reserve must be a percentage between 0 and 100"), http.StatusBadRequest)
+		return
+	}
+
+	ctx := jc.Request.Context()
+	good, err := b.store.Contracts(ctx, api.ContractsOpts{FilterMode: api.ContractFilterModeGood})
+	if jc.Check("failed to fetch contracts", err) != nil {
+		return
+	}
+	us, err := b.uploadSettings(ctx)
+	if jc.Check("failed to fetch upload settings", err) != nil {
+		return
+	}
+	bh := b.cm.Tip().Height
+
+	// fetch the hosts of all contracts at once
+	var hks []types.PublicKey
+	seen := make(map[types.PublicKey]struct{})
+	for _, c := range good {
+		if _, ok := seen[c.HostKey]; !ok {
+			seen[c.HostKey] = struct{}{}
+			hks = append(hks, c.HostKey)
+		}
+	}
+	hosts, err := b.store.Hosts(ctx, api.HostOptions{
+		FilterMode:    api.HostFilterModeAll,
+		UsabilityMode: api.UsabilityFilterModeAll,
+		KeyIn:         hks,
+		Limit:         -1,
+	})
+	if jc.Check("couldn't fetch hosts", err) != nil {
+		return
+	}
+	hostMap := make(map[types.PublicKey]api.Host, len(hosts))
+	for _, h := range hosts {
+		hostMap[h.PublicKey] = h
+	}
+
+	// estimate the capacity of every contract
+	var resp api.ContractsCapacityResponse
+	for _, c := range good {
+		host, ok := hostMap[c.HostKey]
+		if !ok {
+			jc.Error(fmt.Errorf("%w: %v", api.ErrHostNotFound, c.HostKey), http.StatusInternalServerError)
+			return
+		}
+
+		// use the renter funds of the latest revision, if the host can't be
+		// reached we fall back to the funds that weren't spent yet
+		var remaining types.Currency
+		if rev, err := b.latestRevision(ctx, c.ID, host); err == nil {
+			remaining = rev.RenterFunds
+		} else {
+			b.logger.Debugw("failed to fetch revision, estimating remaining funds", "fcid", c.ID, zap.Error(err))
+			if spent := c.Spending.Total(); spent.Cmp(c.InitialRenterFunds) < 0 {
+				remaining = c.InitialRenterFunds.Sub(spent)
+			}
+		}
+
+		// the part of the funds that is reserved before a contract is
+		// considered out of funds is not usable
+		usable := contracts.UsableFunds(remaining, c.InitialRenterFunds, reserve)
+
+		var remainingBytes uint64
+		if c.EndHeight() > bh && !usable.IsZero() {
+			if c.V2 {
+				remainingBytes = contracts.RenterFundsToExpectedStorageV2(usable, c.EndHeight()-bh, host.V2Settings.Prices)
+			} else {
+				remainingBytes = contracts.RenterFundsToExpectedStorage(usable, c.EndHeight()-bh, host.PriceTable.HostPriceTable)
+			}
+		}
+
+		resp.Contracts = append(resp.Contracts, api.ContractCapacity{
+			ID:             c.ID,
+			HostKey:        c.HostKey,
+			RemainingFunds: remaining,
+			RemainingBytes: remainingBytes,
+		})
+		if resp.RemainingBytes+remainingBytes < resp.RemainingBytes {
+			resp.RemainingBytes = math.MaxUint64
+		} else {
+			resp.RemainingBytes += remainingBytes
+		}
+	}
+
+	// account for redundancy
+	if rs := us.Redundancy; rs.MinShards > 0 && rs.TotalShards > 0 {
+		resp.EstimatedFreeBytes = resp.RemainingBytes / uint64(rs.TotalShards) * uint64(rs.MinShards)
+	}
+
+	// sort contracts by their remaining capacity
+	sort.Slice(resp.Contracts, func(i, j int) bool {
+		return resp.Contracts[i].RemainingBytes > resp.Contracts[j].RemainingBytes
+	})
+	jc.Encode(resp)
 }
 
 func (b *Bus) contractsPrunableDataHandlerGET(jc jape.Context) {
@@ -1054,6 +1478,42 @@ func (b *Bus) contractSizeHandlerGET(jc jape.Context) {
 	jc.Encode(size)
 }
 
+func (b *Bus) contractSpendingHandlerGET(jc jape.Context) {
+	var id types.FileContractID
+	if jc.DecodeParam("id", &id) != nil {
+		return
+	}
+
+	c, err := b.store.Contract(jc.Request.Context(), id)
+	if errors.Is(err, api.ErrContractNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch contract", err) != nil {
+		return
+	}
+
+	ancestors, err := b.store.AncestorContracts(jc.Request.Context(), id, 0)
+	if jc.Check("failed to fetch ancestor contracts", err) != nil {
+		return
+	}
+
+	resp := api.ContractSpendingResponse{
+		ContractID:    c.ID,
+		ContractPrice: c.ContractPrice,
+		Spending:      c.Spending,
+		Total:         c.ContractPrice.Add(c.Spending.Total()),
+
+		LineageContractPrice: c.ContractPrice,
+		LineageSpending:      c.Spending,
+	}
+	for _, ancestor := range ancestors {
+		resp.LineageContractPrice = resp.LineageContractPrice.Add(ancestor.ContractPrice)
+		resp.LineageSpending = resp.LineageSpending.Add(ancestor.Spending)
+	}
+	resp.LineageTotal = resp.LineageContractPrice.Add(resp.LineageSpending.Total())
+	jc.Encode(resp)
+}
+
 func (b *Bus) contractUsabilityHandlerPUT(jc jape.Context) {
 	var id types.FileContractID
 	if jc.DecodeParam("id", &id) != nil {
@@ -1256,7 +1716,7 @@ func (b *Bus) objectHandlerGET(jc jape.Context) {
 }
 
 func (b *Bus) objectsHandlerGET(jc jape.Context) {
-	var bucket, marker, delim, sortBy, sortDir, substring string
+	var bucket, marker, delim, sortBy, sortDir, substring, glob string
 	if jc.DecodeForm("bucket", &bucket) != nil {
 		return
 	}
@@ -1280,21 +1740,78 @@ func (b *Bus) objectsHandlerGET(jc jape.Context) {
 	if jc.DecodeForm("substring", &substring) != nil {
 		return
 	}
+	var allowScan bool
+	if jc.DecodeForm("glob", &glob) != nil {
+		return
+	} else if jc.DecodeForm("allowscan", &allowScan) != nil {
+		return
+	}
 	var slabEncryptionKey object.EncryptionKey
 	if jc.DecodeForm("slabencryptionkey", &slabEncryptionKey) != nil {
 		return
 	}
+	var minHealth, maxHealth *float64
+	if jc.Request.FormValue("minhealth") != "" {
+		minHealth = new(float64)
+		if jc.DecodeForm("minhealth", minHealth) != nil {
+			return
+		}
+	}
+	if jc.Request.FormValue("maxhealth") != "" {
+		maxHealth = new(float64)
+		if jc.DecodeForm("maxhealth", maxHealth) != nil {
+			return
+		}
+	}
+	if minHealth != nil && maxHealth != nil && *minHealth > *maxHealth {
+		jc.Error(fmt.Errorf("minHealth %v is greater than maxHealth %v", *minHealth, *maxHealth), http.StatusBadRequest)
+		return
+	}
+
+	// every key starts with a slash, so a glob is only matched against a
+	// subset of the objects if either it or the listing has a longer prefix
+	prefix := jc.PathParam("prefix")
+	if glob != "" {
+		globPrefix, err := api.GlobPrefix(glob)
+		if err != nil {
+			jc.Error(err, http.StatusBadRequest)
+			return
+		} else if !allowScan && strings.TrimPrefix(globPrefix, "/") == "" && strings.TrimPrefix(prefix, "/") == "" {
+			jc.Error(api.ErrGlobRequiresScan, http.StatusBadRequest)
+			return
+		}
+	}
 
-	resp, err := b.store.Objects(jc.Request.Context(), bucket, jc.PathParam("prefix"), substring, delim, sortBy, sortDir, marker, limit, slabEncryptionKey)
+	resp, err := b.store.Objects(jc.Request.Context(), bucket, prefix, substring, glob, delim, sortBy, sortDir, marker, limit, slabEncryptionKey, minHealth, maxHealth)
 	if errors.Is(err, api.ErrUnsupportedDelimiter) {
 		jc.Error(err, http.StatusBadRequest)
 		return
 	} else if jc.Check("failed to query objects", err) != nil {
 		return
 	}
+
+	// return early if the client already has the listing
+	resp.ETag = resp.WeakETag()
+	jc.ResponseWriter.Header().Set("ETag", resp.ETag)
+	if etagMatches(jc.Request.Header.Get("If-None-Match"), resp.ETag) {
+		jc.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
 	api.WriteResponse(jc, resp)
 }
 
+// etagMatches returns true if the value of an If-None-Match header matches the
+// given ETag using weak comparison.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
 func (b *Bus) objectHandlerPUT(jc jape.Context) {
 	var aor api.AddObjectRequest
 	if jc.Decode(&aor) != nil {
@@ -1302,8 +1819,29 @@ func (b *Bus) objectHandlerPUT(jc jape.Context) {
 	} else if aor.Bucket == "" {
 		jc.Error(api.ErrBucketMissing, http.StatusBadRequest)
 		return
+	} else if err := aor.Validate(); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	key := jc.PathParam("key")
+	var err error
+	eTag := aor.ETag
+	if aor.IdempotencyKey == "" {
+		err = b.store.UpdateObject(jc.Request.Context(), aor.Bucket, key, aor.ETag, aor.Checksum, aor.MimeType, aor.ContentDisposition, aor.Metadata, aor.PinnedHosts, aor.Object)
+	} else {
+		eTag, err = b.store.UpdateObjectIdempotent(jc.Request.Context(), aor.IdempotencyKey, aor.Hash(key), aor.UnmodifiedSince, aor.Bucket, key, aor.ETag, aor.Checksum, aor.MimeType, aor.ContentDisposition, aor.Metadata, aor.PinnedHosts, aor.Object)
+	}
+	if errors.Is(err, api.ErrIdempotencyKeyReused) || errors.Is(err, api.ErrObjectKeyCaseConflict) || errors.Is(err, api.ErrBucketQuotaExceeded) {
+		jc.Error(err, http.StatusConflict)
+		return
+	} else if errors.Is(err, api.ErrObjectModified) {
+		jc.Error(err, http.StatusPreconditionFailed)
+		return
+	} else if jc.Check("couldn't store object", err) != nil {
+		return
 	}
-	jc.Check("couldn't store object", b.store.UpdateObject(jc.Request.Context(), aor.Bucket, jc.PathParam("key"), aor.ETag, aor.MimeType, aor.Metadata, aor.Object))
+	jc.Encode(api.AddObjectResponse{ETag: eTag})
 }
 
 func (b *Bus) objectsCopyHandlerPOST(jc jape.Context) {
@@ -1311,14 +1849,71 @@ func (b *Bus) objectsCopyHandlerPOST(jc jape.Context) {
 	if jc.Decode(&orr) != nil {
 		return
 	}
-	om, err := b.store.CopyObject(jc.Request.Context(), orr.SourceBucket, orr.DestinationBucket, orr.SourceKey, orr.DestinationKey, orr.MimeType, orr.Metadata)
-	if jc.Check("couldn't copy object", err) != nil {
+	switch orr.OverwritePolicy {
+	case "":
+		orr.OverwritePolicy = api.CopyPolicyOverwrite
+	case api.CopyPolicyOverwrite, api.CopyPolicySkip, api.CopyPolicyFail:
+	default:
+		jc.Error(fmt.Errorf("%w: %q", api.ErrInvalidCopyPolicy, orr.OverwritePolicy), http.StatusBadRequest)
+		return
+	}
+	if err := api.ValidateContentDisposition(orr.ContentDisposition); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	om, copied, err := b.store.CopyObject(jc.Request.Context(), orr.SourceBucket, orr.DestinationBucket, orr.SourceKey, orr.DestinationKey, orr.MimeType, orr.ContentDisposition, orr.Metadata, orr.OverwritePolicy)
+	if errors.Is(err, api.ErrObjectExists) || errors.Is(err, api.ErrBucketQuotaExceeded) {
+		jc.Error(err, http.StatusConflict)
+		return
+	} else if jc.Check("couldn't copy object", err) != nil {
 		return
 	}
 
 	jc.ResponseWriter.Header().Set("Last-Modified", om.ModTime.Std().Format(http.TimeFormat))
 	jc.ResponseWriter.Header().Set("ETag", api.FormatETag(om.ETag))
-	jc.Encode(om)
+	jc.Encode(api.CopyObjectResponse{
+		ObjectMetadata: om,
+		Copied:         copied,
+	})
+}
+
+func (b *Bus) objectsMoveHandlerPOST(jc jape.Context) {
+	var omr api.ObjectsMoveRequest
+	if jc.Decode(&omr) != nil {
+		return
+	} else if omr.SourceBucket == "" || omr.DestinationBucket == "" {
+		jc.Error(api.ErrBucketMissing, http.StatusBadRequest)
+		return
+	} else if omr.Key == "" || strings.HasSuffix(omr.Key, "/") {
+		jc.Error(errors.New("key must be a valid object key"), http.StatusBadRequest)
+		return
+	}
+	err := b.store.MoveObject(jc.Request.Context(), omr.SourceBucket, omr.DestinationBucket, omr.Key)
+	if errors.Is(err, api.ErrBucketQuotaExceeded) {
+		jc.Error(err, http.StatusConflict)
+		return
+	}
+	jc.Check("couldn't move object", err)
+}
+
+func (b *Bus) objectsPinHandlerPOST(jc jape.Context) {
+	var opr api.ObjectsPinRequest
+	if jc.Decode(&opr) != nil {
+		return
+	} else if opr.Bucket == "" {
+		jc.Error(api.ErrBucketMissing, http.StatusBadRequest)
+		return
+	} else if opr.Key == "" || strings.HasSuffix(opr.Key, "/") {
+		jc.Error(errors.New("key must be a valid object key"), http.StatusBadRequest)
+		return
+	}
+	err := b.store.PinObject(jc.Request.Context(), opr.Bucket, opr.Key, opr.Pinned)
+	if errors.Is(err, api.ErrObjectNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	}
+	jc.Check("couldn't pin object", err)
 }
 
 func (b *Bus) objectsRemoveHandlerPOST(jc jape.Context) {
@@ -1335,7 +1930,73 @@ func (b *Bus) objectsRemoveHandlerPOST(jc jape.Context) {
 		return
 	}
 
-	jc.Check("failed to remove objects", b.store.RemoveObjects(jc.Request.Context(), orr.Bucket, orr.Prefix))
+	deleted, err := b.store.RemoveObjects(jc.Request.Context(), orr.Bucket, orr.Prefix)
+	if jc.Check("failed to remove objects", err) != nil {
+		return
+	}
+	b.logger.Debugw("removed objects", "bucket", orr.Bucket, "prefix", orr.Prefix, "deleted", deleted)
+}
+
+func (b *Bus) objectsEventsHandlerPOST(jc jape.Context) {
+	var req api.ObjectEventsRequest
+	if jc.Decode(&req) != nil {
+		return
+	} else if req.Limit <= 0 || req.Limit > api.MaxObjectEventsLimit {
+		req.Limit = api.MaxObjectEventsLimit
+	}
+
+	resp, err := b.store.ObjectEvents(jc.Request.Context(), req.Marker, req.Limit)
+	if jc.Check("failed to fetch object events", err) != nil {
+		return
+	}
+	jc.Encode(resp)
+}
+
+func (b *Bus) objectsChecksumsMissingHandlerPOST(jc jape.Context) {
+	var req api.ObjectsMissingChecksumRequest
+	if jc.Decode(&req) != nil {
+		return
+	} else if req.Limit <= 0 || req.Limit > api.MaxObjectsMissingChecksumLimit {
+		req.Limit = api.MaxObjectsMissingChecksumLimit
+	}
+
+	resp, err := b.store.ObjectsMissingChecksum(jc.Request.Context(), req.Marker, req.Limit)
+	if jc.Check("failed to fetch objects missing a checksum", err) != nil {
+		return
+	}
+	jc.Encode(resp)
+}
+
+func (b *Bus) objectsChecksumsBackfillHandlerPOST(jc jape.Context) {
+	var req api.BackfillObjectChecksumRequest
+	if jc.Decode(&req) != nil {
+		return
+	} else if err := req.Validate(); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+	jc.Check("failed to backfill object checksum", b.store.BackfillObjectChecksum(jc.Request.Context(), req.ID, req.Checksum))
+}
+
+func (b *Bus) objectsNoSlabsHandlerPOST(jc jape.Context) {
+	var req api.ObjectsNoSlabsRequest
+	if jc.Decode(&req) != nil {
+		return
+	} else if req.Bucket == "" {
+		jc.Error(api.ErrBucketMissing, http.StatusBadRequest)
+		return
+	} else if req.Limit <= 0 || req.Limit > api.MaxObjectsNoSlabsLimit {
+		req.Limit = api.MaxObjectsNoSlabsLimit
+	}
+
+	resp, err := b.store.ObjectsNoSlabs(jc.Request.Context(), req.Bucket, req.Marker, req.Limit, req.MissingDataOnly)
+	if errors.Is(err, api.ErrBucketNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch objects without slabs", err) != nil {
+		return
+	}
+	jc.Encode(resp)
 }
 
 func (b *Bus) objectsRenameHandlerPOST(jc jape.Context) {
@@ -1352,7 +2013,12 @@ func (b *Bus) objectsRenameHandlerPOST(jc jape.Context) {
 			jc.Error(fmt.Errorf("can't rename dirs with mode %v", orr.Mode), http.StatusBadRequest)
 			return
 		}
-		jc.Check("couldn't rename object", b.store.RenameObject(jc.Request.Context(), orr.Bucket, orr.From, orr.To, orr.Force))
+		err := b.store.RenameObject(jc.Request.Context(), orr.Bucket, orr.From, orr.To, orr.Force, orr.AllowDirectoryCollision)
+		if errors.Is(err, api.ErrObjectKeyIsDirectory) {
+			jc.Error(err, http.StatusConflict)
+			return
+		}
+		jc.Check("couldn't rename object", err)
 		return
 	} else if orr.Mode == api.ObjectsRenameModeMulti {
 		// Multi object rename.
@@ -1360,7 +2026,11 @@ func (b *Bus) objectsRenameHandlerPOST(jc jape.Context) {
 			jc.Error(fmt.Errorf("can't rename file with mode %v", orr.Mode), http.StatusBadRequest)
 			return
 		}
-		jc.Check("couldn't rename objects", b.store.RenameObjects(jc.Request.Context(), orr.Bucket, orr.From, orr.To, orr.Force))
+		res, err := b.store.RenameObjects(jc.Request.Context(), orr.Bucket, orr.From, orr.To, orr.Force)
+		if jc.Check("couldn't rename objects", err) != nil {
+			return
+		}
+		jc.Encode(res)
 		return
 	} else {
 		// Invalid mode.
@@ -1371,13 +2041,22 @@ func (b *Bus) objectsRenameHandlerPOST(jc jape.Context) {
 
 func (b *Bus) objectHandlerDELETE(jc jape.Context) {
 	var bucket string
+	var async bool
 	if jc.DecodeForm("bucket", &bucket) != nil {
 		return
 	} else if bucket == "" {
 		jc.Error(api.ErrBucketMissing, http.StatusBadRequest)
 		return
+	} else if jc.DecodeForm("async", &async) != nil {
+		return
+	}
+
+	var err error
+	if async {
+		err = b.store.RemoveObjectAsync(jc.Request.Context(), bucket, jc.PathParam("key"))
+	} else {
+		err = b.store.RemoveObject(jc.Request.Context(), bucket, jc.PathParam("key"))
 	}
-	err := b.store.RemoveObject(jc.Request.Context(), bucket, jc.PathParam("key"))
 	if errors.Is(err, api.ErrObjectNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
@@ -1393,6 +2072,18 @@ func (b *Bus) slabbuffersHandlerGET(jc jape.Context) {
 	api.WriteResponse(jc, api.SlabBuffersResp(buffers))
 }
 
+func (b *Bus) slabbuffersCompleteHandlerPOST(jc jape.Context) {
+	ctx := jc.Request.Context()
+	if jc.Check("couldn't mark slab buffers as complete", b.store.MarkSlabBuffersComplete(ctx)) != nil {
+		return
+	}
+	buffers, err := b.store.SlabBuffers(ctx)
+	if jc.Check("couldn't get slab buffers info", err) != nil {
+		return
+	}
+	jc.Encode(buffers)
+}
+
 func (b *Bus) objectsStatshandlerGET(jc jape.Context) {
 	opts := api.ObjectsStatsOpts{}
 	if jc.DecodeForm("bucket", &opts.Bucket) != nil {
@@ -1405,6 +2096,22 @@ func (b *Bus) objectsStatshandlerGET(jc jape.Context) {
 	api.WriteResponse(jc, info)
 }
 
+func (b *Bus) slabsStatsHandlerGET(jc jape.Context) {
+	info, err := b.store.SlabsStats(jc.Request.Context())
+	if jc.Check("couldn't get slabs stats", err) != nil {
+		return
+	}
+	api.WriteResponse(jc, info)
+}
+
+func (b *Bus) slabBuffersStatsHandlerGET(jc jape.Context) {
+	stats, err := b.store.SlabBuffersStats(jc.Request.Context())
+	if jc.Check("couldn't get slab buffer stats", err) != nil {
+		return
+	}
+	api.WriteResponse(jc, stats)
+}
+
 func (b *Bus) packedSlabsHandlerFetchPOST(jc jape.Context) {
 	var psrg api.PackedSlabsRequestGET
 	if jc.Decode(&psrg) != nil {
@@ -1433,6 +2140,59 @@ func (b *Bus) packedSlabsHandlerDonePOST(jc jape.Context) {
 	jc.Check("failed to mark packed slab(s) as uploaded", b.store.MarkPackedSlabsUploaded(jc.Request.Context(), psrp.Slabs))
 }
 
+func (b *Bus) settingsHandlerGET(jc jape.Context) {
+	settings, err := b.settings(jc.Request.Context())
+	if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+	jc.Encode(settings)
+}
+
+func (b *Bus) settingsHandlerPUT(jc jape.Context) {
+	var req api.UpdateSettingsRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+
+	// validate the settings
+	if req.Gouging != nil {
+		if err := req.Gouging.Validate(); err != nil {
+			jc.Error(fmt.Errorf("couldn't update gouging settings, error: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Pinned != nil {
+		if err := req.Pinned.Validate(); err != nil {
+			jc.Error(fmt.Errorf("couldn't update pinned settings, error: %v", err), http.StatusBadRequest)
+			return
+		} else if req.Pinned.Enabled() && !b.explorer.Enabled() {
+			jc.Error(fmt.Errorf("can't enable price pinning, %w", api.ErrExplorerDisabled), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Upload != nil {
+		if err := req.Upload.Validate(); err != nil {
+			jc.Error(fmt.Errorf("couldn't update upload settings, error: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// update the settings
+	if jc.Check("failed to update settings", b.store.UpdateSettings(jc.Request.Context(), req.Gouging, req.Pinned, req.Upload)) != nil {
+		return
+	} else if req.Gouging != nil || req.Pinned != nil {
+		b.pinMgr.TriggerUpdate()
+	}
+
+	// return the effective settings
+	settings, err := b.settings(jc.Request.Context())
+	if jc.Check("failed to fetch settings", err) != nil {
+		return
+	}
+	jc.Encode(settings)
+}
+
 func (b *Bus) settingsGougingHandlerGET(jc jape.Context) {
 	gs, err := b.gougingSettings(jc.Request.Context())
 	if err != nil {
@@ -1544,6 +2304,24 @@ func (b *Bus) sectorsHostRootHandlerDELETE(jc jape.Context) {
 	}
 }
 
+func (b *Bus) sectorsSampleHandlerGET(jc jape.Context) {
+	var limit int
+	if jc.DecodeForm("limit", &limit) != nil {
+		return
+	} else if limit <= 0 {
+		jc.Error(api.ErrInvalidLimit, http.StatusBadRequest)
+		return
+	} else if limit > api.MaxSectorSamples {
+		jc.Error(fmt.Errorf("limit can't exceed %d", api.MaxSectorSamples), http.StatusBadRequest)
+		return
+	}
+	samples, err := b.store.SampleSectors(jc.Request.Context(), limit)
+	if jc.Check("failed to sample sectors", err) != nil {
+		return
+	}
+	jc.Encode(samples)
+}
+
 func (b *Bus) slabHandlerGET(jc jape.Context) {
 	var key object.EncryptionKey
 	if jc.DecodeParam("key", &key) != nil {
@@ -1616,6 +2394,26 @@ func (b *Bus) slabsMigrationHandlerPOST(jc jape.Context) {
 	jc.Encode(api.SlabsForMigrationResponse{Slabs: slabs})
 }
 
+func (b *Bus) slabsSharedHandlerGET(jc jape.Context) {
+	minObjects := uint64(1)
+	if jc.DecodeForm("minobjects", &minObjects) != nil {
+		return
+	}
+	var limit int
+	if jc.DecodeForm("limit", &limit) != nil {
+		return
+	} else if limit <= 0 {
+		jc.Error(api.ErrInvalidLimit, http.StatusBadRequest)
+		return
+	}
+
+	slabs, err := b.store.SharedSlabs(jc.Request.Context(), minObjects, limit)
+	if jc.Check("couldn't fetch shared slabs", err) != nil {
+		return
+	}
+	jc.Encode(slabs)
+}
+
 func (b *Bus) slabsPartialHandlerGET(jc jape.Context) {
 	jc.Custom(nil, []byte{})
 
@@ -1673,7 +2471,10 @@ func (b *Bus) slabsPartialHandlerPOST(jc jape.Context) {
 		return
 	}
 	slabs, bufferSize, err := b.store.AddPartialSlab(jc.Request.Context(), data, uint8(minShards), uint8(totalShards))
-	if jc.Check("failed to add partial slab", err) != nil {
+	if errors.Is(err, api.ErrSlabBufferFull) {
+		jc.Error(err, http.StatusServiceUnavailable)
+		return
+	} else if jc.Check("failed to add partial slab", err) != nil {
 		return
 	}
 	us, err := b.uploadSettings(jc.Request.Context())
@@ -1681,9 +2482,14 @@ func (b *Bus) slabsPartialHandlerPOST(jc jape.Context) {
 		jc.Error(fmt.Errorf("could not get upload packing settings: %w", err), http.StatusInternalServerError)
 		return
 	}
+	stats, err := b.store.SlabBuffersStats(jc.Request.Context())
+	if jc.Check("failed to fetch slab buffer stats", err) != nil {
+		return
+	}
+	diskUsageReached := stats.MaxDiskUsage > 0 && stats.DiskUsage >= stats.MaxDiskUsage
 	jc.Encode(api.AddPartialSlabResponse{
 		Slabs:                        slabs,
-		SlabBufferMaxSizeSoftReached: bufferSize >= us.Packing.SlabBufferMaxSizeSoft,
+		SlabBufferMaxSizeSoftReached: bufferSize >= us.Packing.SlabBufferMaxSizeSoft || diskUsageReached,
 	})
 }
 
@@ -1942,6 +2748,23 @@ func (b *Bus) consensusPayoutContractTaxHandlerGET(jc jape.Context) {
 	jc.Encode(cs.FileContractTax(types.FileContract{Payout: payout}))
 }
 
+func (b *Bus) healthHandlerGET(jc jape.Context) {
+	resp := api.HealthResponse{
+		Database: b.store.DatabaseHealth(jc.Request.Context()),
+	}
+	resp.Status = resp.Database.Status
+
+	// respond with a 503 if the bus is unavailable so load balancers don't
+	// have to parse the response
+	if resp.Status == api.HealthStatusUnavailable {
+		jc.ResponseWriter.Header().Set("Content-Type", "application/json")
+		jc.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(jc.ResponseWriter).Encode(resp)
+		return
+	}
+	jc.Encode(resp)
+}
+
 func (b *Bus) stateHandlerGET(jc jape.Context) {
 	api.WriteResponse(jc, api.BusStateResponse{
 		StartTime: api.TimeRFC3339(b.startTime),
@@ -2158,7 +2981,7 @@ func (b *Bus) multipartHandlerCreatePOST(jc jape.Context) {
 		key = object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted)
 	}
 
-	resp, err := b.store.CreateMultipartUpload(jc.Request.Context(), req.Bucket, req.Key, key, req.MimeType, req.Metadata)
+	resp, err := b.store.CreateMultipartUpload(jc.Request.Context(), req.Bucket, req.Key, key, req.MimeType, req.Metadata, req.PinnedHosts)
 	if jc.Check("failed to create multipart upload", err) != nil {
 		return
 	}
@@ -2184,7 +3007,10 @@ func (b *Bus) multipartHandlerCompletePOST(jc jape.Context) {
 	resp, err := b.store.CompleteMultipartUpload(jc.Request.Context(), req.Bucket, req.Key, req.UploadID, req.Parts, api.CompleteMultipartOptions{
 		Metadata: req.Metadata,
 	})
-	if jc.Check("failed to complete multipart upload", err) != nil {
+	if errors.Is(err, api.ErrBucketQuotaExceeded) {
+		jc.Error(err, http.StatusConflict)
+		return
+	} else if jc.Check("failed to complete multipart upload", err) != nil {
 		return
 	}
 	jc.Encode(resp)
@@ -2214,6 +3040,42 @@ func (b *Bus) multipartHandlerUploadPartPUT(jc jape.Context) {
 	}
 }
 
+func (b *Bus) multipartHandlerPartProgressPOST(jc jape.Context) {
+	var req api.MultipartPartProgressRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+	slices, err := b.store.MultipartPartProgress(jc.Request.Context(), req.Bucket, req.Key, req.UploadID, req.PartNumber)
+	if jc.Check("failed to fetch part progress", err) != nil {
+		return
+	}
+	jc.Encode(api.MultipartPartProgressResponse{Slices: slices})
+}
+
+func (b *Bus) multipartHandlerPartProgressPUT(jc jape.Context) {
+	var req api.MultipartAddPartProgressRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+	if req.Bucket == "" {
+		jc.Error(api.ErrBucketMissing, http.StatusBadRequest)
+		return
+	} else if req.PartNumber <= 0 || req.PartNumber > gofakes3.MaxUploadPartNumber {
+		jc.Error(fmt.Errorf("part_number must be between 1 and %d", gofakes3.MaxUploadPartNumber), http.StatusBadRequest)
+		return
+	} else if req.UploadID == "" {
+		jc.Error(errors.New("upload_id must be non-empty"), http.StatusBadRequest)
+		return
+	} else if req.Index < 0 {
+		jc.Error(errors.New("index must not be negative"), http.StatusBadRequest)
+		return
+	}
+	err := b.store.AddMultipartPartProgress(jc.Request.Context(), req.Bucket, req.Key, req.UploadID, req.PartNumber, req.Index, req.Checksum, req.Slices)
+	if jc.Check("failed to add part progress", err) != nil {
+		return
+	}
+}
+
 func (b *Bus) multipartHandlerUploadGET(jc jape.Context) {
 	resp, err := b.store.MultipartUpload(jc.Request.Context(), jc.PathParam("id"))
 	if jc.Check("failed to get multipart upload", err) != nil {
@@ -2240,7 +3102,10 @@ func (b *Bus) multipartHandlerListPartsPOST(jc jape.Context) {
 		return
 	}
 	resp, err := b.store.MultipartUploadParts(jc.Request.Context(), req.Bucket, req.Key, req.UploadID, req.PartNumberMarker, int64(req.Limit))
-	if jc.Check("failed to list multipart upload parts", err) != nil {
+	if errors.Is(err, api.ErrMultipartUploadNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to list multipart upload parts", err) != nil {
 		return
 	}
 	jc.Encode(resp)
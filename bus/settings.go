@@ -8,6 +8,17 @@ import (
 	"go.sia.tech/renterd/stores/sql"
 )
 
+func (b Bus) settings(ctx context.Context) (resp api.SettingsResponse, err error) {
+	if resp.Gouging, err = b.gougingSettings(ctx); err != nil {
+		return api.SettingsResponse{}, err
+	} else if resp.Pinned, err = b.pinnedSettings(ctx); err != nil {
+		return api.SettingsResponse{}, err
+	} else if resp.Upload, err = b.uploadSettings(ctx); err != nil {
+		return api.SettingsResponse{}, err
+	}
+	return
+}
+
 func (b Bus) gougingSettings(ctx context.Context) (api.GougingSettings, error) {
 	gs, err := b.store.GougingSettings(ctx)
 	if errors.Is(err, sql.ErrSettingNotFound) {
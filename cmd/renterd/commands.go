@@ -11,7 +11,9 @@ import (
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
 	"go.sia.tech/renterd/build"
+	"go.sia.tech/renterd/stores/sql/mysql"
 	"go.sia.tech/renterd/stores/sql/sqlite"
+	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,6 +22,41 @@ func cmdBackup() {
 	checkFatalError("failed to backup sqlite database", err)
 }
 
+func cmdMigrateToMySQL() {
+	if cfg.Database.MySQL.URI == "" {
+		checkFatalError("failed to migrate sqlite database", errors.New("no MySQL database configured"))
+	}
+
+	// make sure the source database exists, opening it would create it
+	srcPath := flag.Arg(2)
+	if _, err := os.Stat(srcPath); err != nil {
+		checkFatalError("failed to open sqlite database", err)
+	}
+	src, err := sqlite.Open(srcPath)
+	checkFatalError("failed to open sqlite database", err)
+	defer src.Close()
+
+	logger, err := zap.NewDevelopment()
+	checkFatalError("failed to create logger", err)
+
+	// open and migrate the destination database
+	conn, err := mysql.Open(
+		cfg.Database.MySQL.User,
+		cfg.Database.MySQL.Password,
+		cfg.Database.MySQL.URI,
+		cfg.Database.MySQL.Database,
+	)
+	checkFatalError("failed to open MySQL database", err)
+	dst, err := mysql.NewMainDatabase(conn, logger, cfg.Log.Database.SlowThreshold, cfg.Log.Database.SlowThreshold, filepath.Join(cfg.Directory, "partial_slabs"))
+	checkFatalError("failed to create MySQL database", err)
+	defer dst.Close()
+	checkFatalError("failed to migrate MySQL database", dst.Migrate(context.Background()))
+
+	// copy the data
+	checkFatalError("failed to copy sqlite database", dst.CopyFrom(context.Background(), src, 500))
+	fmt.Println("Successfully migrated sqlite database to MySQL")
+}
+
 func cmdBuildConfig(fp string) {
 	fmt.Println("renterd Configuration Wizard")
 	fmt.Println("This wizard will help you configure renterd for the first time.")
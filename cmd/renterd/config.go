@@ -17,6 +17,7 @@ import (
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
 	"go.sia.tech/renterd/config"
+	"go.sia.tech/renterd/object"
 	"golang.org/x/term"
 )
 
@@ -83,8 +84,10 @@ var cfg = config.Config{
 		AnnouncementMaxAgeHours:       24 * 7 * 52, // 1 year
 		Bootstrap:                     true,
 		GatewayAddr:                   ":9981",
+		ObjectEventRetention:          7 * 24 * time.Hour,
 		UsedUTXOExpiry:                24 * time.Hour,
 		SlabBufferCompletionThreshold: 1 << 12,
+		SlabPruningParallelism:        1,
 	},
 	Worker: config.Worker{
 		Enabled: true,
@@ -93,14 +96,28 @@ var cfg = config.Config{
 		AccountsRefillInterval: defaultAccountRefillInterval,
 		BusFlushInterval:       5 * time.Second,
 		CacheExpiry:            5 * time.Minute,
+		ErasureBackend:         object.ErasureBackendSIMD,
 
 		DownloadMaxOverdrive:     5,
 		DownloadOverdriveTimeout: 3 * time.Second,
 
+		DownloadReadRepairBudget:         1 << 30, // 1 GiB
+		DownloadReadRepairBudgetInterval: time.Hour,
+
+		HostBreakerThreshold: 5,
+		HostBreakerCooldown:  time.Minute,
+
 		DownloadMaxMemory:      1 << 30, // 1 GiB
 		UploadMaxMemory:        1 << 30, // 1 GiB
 		UploadMaxOverdrive:     5,
 		UploadOverdriveTimeout: 3 * time.Second,
+
+		UploadStatsRecomputeInterval:    3 * time.Second,
+		UploadCandidateFailureThreshold: 2,
+		UploadSectorTimeoutMin:          10 * time.Second,
+		UploadSectorTimeoutMax:          time.Minute,
+		UploadPersistMaxAttempts:        5,
+		UploadETagBufferSize:            1 << 23, // 8 MiB
 	},
 	Autopilot: config.Autopilot{
 		Enabled: true,
@@ -110,10 +127,16 @@ var cfg = config.Config{
 		MigratorAccountsRefillInterval:   defaultAccountRefillInterval,
 		MigratorHealthCutoff:             0.75,
 		MigratorNumThreads:               1,
+		MigratorRepairBudgetInterval:     time.Minute,
 		MigratorDownloadMaxOverdrive:     5,
 		MigratorDownloadOverdriveTimeout: 3 * time.Second,
 		MigratorUploadMaxOverdrive:       5,
 		MigratorUploadOverdriveTimeout:   3 * time.Second,
+		MigratorVerificationInterval:     time.Hour,
+		MigratorVerificationSampleSize:   10,
+		MigratorReconciliationInterval:   24 * time.Hour,
+		MigratorReconciliationSampleSize: 5,
+		MigratorChecksumBackfillRate:     1 << 20, // 1 MiB/s
 
 		RevisionBroadcastInterval: 7 * 24 * time.Hour,
 		RevisionSubmissionBuffer:  150, // 144 + 6 blocks leeway
@@ -121,6 +144,7 @@ var cfg = config.Config{
 		ScannerBatchSize:  100,
 		ScannerInterval:   4 * time.Hour,
 		ScannerNumThreads: 10,
+		ScannerMaxBackoff: 48 * time.Hour,
 	},
 	S3: config.S3{
 		Address:     "localhost:8080",
@@ -227,20 +251,48 @@ func parseCLIFlags() {
 	flag.BoolVar(&cfg.Bus.AllowPrivateIPs, "bus.allowPrivateIPs", cfg.Bus.AllowPrivateIPs, "Allows hosts with private IPs")
 	flag.Uint64Var(&cfg.Bus.AnnouncementMaxAgeHours, "bus.announcementMaxAgeHours", cfg.Bus.AnnouncementMaxAgeHours, "Max age for announcements")
 	flag.BoolVar(&cfg.Bus.Bootstrap, "bus.bootstrap", cfg.Bus.Bootstrap, "Bootstraps gateway and consensus modules")
+	flag.DurationVar(&cfg.Bus.ClientRequestTimeout, "bus.clientRequestTimeout", cfg.Bus.ClientRequestTimeout, "Default timeout for requests the worker and autopilot make to the bus, 0 disables it")
 	flag.StringVar(&cfg.Bus.GatewayAddr, "bus.gatewayAddr", cfg.Bus.GatewayAddr, "Address for Sia peer connections (overrides with RENTERD_BUS_GATEWAY_ADDR)")
+	flag.BoolVar(&cfg.Bus.ObjectEventLog, "bus.objectEventLog", cfg.Bus.ObjectEventLog, "Records every object mutation in the object event log")
+	flag.DurationVar(&cfg.Bus.ObjectEventRetention, "bus.objectEventRetention", cfg.Bus.ObjectEventRetention, "Amount of time events are kept in the object event log, 0 keeps them forever")
 	flag.DurationVar(&cfg.Bus.UsedUTXOExpiry, "bus.usedUTXOExpiry", cfg.Bus.UsedUTXOExpiry, "Expiry for used UTXOs in transactions")
 	flag.Int64Var(&cfg.Bus.SlabBufferCompletionThreshold, "bus.slabBufferCompletionThreshold", cfg.Bus.SlabBufferCompletionThreshold, "Threshold for slab buffer upload (overrides with RENTERD_BUS_SLAB_BUFFER_COMPLETION_THRESHOLD)")
+	flag.StringVar(&cfg.Bus.SlabBufferCompression, "bus.slabBufferCompression", cfg.Bus.SlabBufferCompression, "Compression used for slab buffers on disk, either empty or 'zstd' (overrides with RENTERD_BUS_SLAB_BUFFER_COMPRESSION)")
+	flag.Int64Var(&cfg.Bus.SlabBufferMaxDiskUsage, "bus.slabBufferMaxDiskUsage", cfg.Bus.SlabBufferMaxDiskUsage, "Max number of bytes slab buffers may take up on disk before partial slabs are rejected, 0 means unlimited (overrides with RENTERD_BUS_SLAB_BUFFER_MAX_DISK_USAGE)")
+	flag.IntVar(&cfg.Bus.SlabPruningParallelism, "bus.slabPruningParallelism", cfg.Bus.SlabPruningParallelism, "Number of concurrent workers used to prune unreferenced slabs (overrides with RENTERD_BUS_SLAB_PRUNING_PARALLELISM)")
 
 	// worker
 	flag.DurationVar(&cfg.Worker.AccountsRefillInterval, "worker.accountRefillInterval", cfg.Worker.AccountsRefillInterval, "Interval for refilling workers' account balances")
 	flag.DurationVar(&cfg.Worker.BusFlushInterval, "worker.busFlushInterval", cfg.Worker.BusFlushInterval, "Interval for flushing data to bus")
 	flag.Uint64Var(&cfg.Worker.DownloadMaxMemory, "worker.downloadMaxMemory", cfg.Worker.DownloadMaxMemory, "Max amount of RAM the worker allocates for slabs when downloading (overrides with RENTERD_WORKER_DOWNLOAD_MAX_MEMORY)")
 	flag.Uint64Var(&cfg.Worker.DownloadMaxOverdrive, "worker.downloadMaxOverdrive", cfg.Worker.DownloadMaxOverdrive, "Max overdrive workers for downloads")
+	flag.Uint64Var(&cfg.Worker.DownloadMaxHostsPerSlab, "worker.downloadMaxHostsPerSlab", cfg.Worker.DownloadMaxHostsPerSlab, "Max hosts tried when downloading a slab, 0 tries all hosts")
 	flag.StringVar(&cfg.Worker.ID, "worker.id", cfg.Worker.ID, "Unique ID for worker (overrides with RENTERD_WORKER_ID)")
 	flag.DurationVar(&cfg.Worker.DownloadOverdriveTimeout, "worker.downloadOverdriveTimeout", cfg.Worker.DownloadOverdriveTimeout, "Timeout for overdriving slab downloads")
+	flag.BoolVar(&cfg.Worker.DownloadReadRepair, "worker.downloadReadRepair", cfg.Worker.DownloadReadRepair, "Enables repairing the unhealthy shards of slabs that are fully downloaded")
+	flag.Uint64Var(&cfg.Worker.DownloadReadRepairBudget, "worker.downloadReadRepairBudget", cfg.Worker.DownloadReadRepairBudget, "Max number of bytes repaired by read-repair per budget interval, 0 means unlimited")
+	flag.DurationVar(&cfg.Worker.DownloadReadRepairBudgetInterval, "worker.downloadReadRepairBudgetInterval", cfg.Worker.DownloadReadRepairBudgetInterval, "Interval over which the read-repair budget is enforced")
+	flag.Uint64Var(&cfg.Worker.HostBreakerThreshold, "worker.hostBreakerThreshold", cfg.Worker.HostBreakerThreshold, "Number of consecutive failed sector uploads or downloads after which a host is avoided by both")
+	flag.DurationVar(&cfg.Worker.HostBreakerCooldown, "worker.hostBreakerCooldown", cfg.Worker.HostBreakerCooldown, "Time a host whose circuit breaker opened is avoided before a probe request decides whether it's used again")
 	flag.Uint64Var(&cfg.Worker.UploadMaxMemory, "worker.uploadMaxMemory", cfg.Worker.UploadMaxMemory, "Max amount of RAM the worker allocates for slabs when uploading (overrides with RENTERD_WORKER_UPLOAD_MAX_MEMORY)")
 	flag.Uint64Var(&cfg.Worker.UploadMaxOverdrive, "worker.uploadMaxOverdrive", cfg.Worker.UploadMaxOverdrive, "Max overdrive workers for uploads")
+	flag.Uint64Var(&cfg.Worker.UploadMaxOverdrivePerSlab, "worker.uploadMaxOverdrivePerSlab", cfg.Worker.UploadMaxOverdrivePerSlab, "Max overdrive uploads in flight per slab regardless of the number of remaining sectors, 0 means no cap")
+	flag.Uint64Var(&cfg.Worker.UploadMinFreeMemory, "worker.uploadMinFreeMemory", cfg.Worker.UploadMinFreeMemory, "Min amount of free upload memory required to accept new uploads, uploads are rejected as busy below it, 0 disables the check")
+	flag.Float64Var(&cfg.Worker.UploadContractDurationWeight, "worker.uploadContractDurationWeight", cfg.Worker.UploadContractDurationWeight, "Weight of a contract's remaining duration when picking hosts for uploads, higher values favour contracts that expire later over faster hosts, 0 disables it")
+	flag.Float64Var(&cfg.Worker.UploadCandidateTolerance, "worker.uploadCandidateTolerance", cfg.Worker.UploadCandidateTolerance, "Relative difference in score within which upload hosts are considered equally fast and picked in random order, e.g. 0.1 for 10%, 0 always picks the fastest host first")
+	flag.Uint64Var(&cfg.Worker.UploadCandidateFailureThreshold, "worker.uploadCandidateFailureThreshold", cfg.Worker.UploadCandidateFailureThreshold, "Number of consecutive failed sector uploads after which a host is only tried after all healthy hosts, 0 disables it")
+	flag.Uint64Var(&cfg.Worker.UploadMaxActiveUploaders, "worker.uploadMaxActiveUploaders", cfg.Worker.UploadMaxActiveUploaders, "Max number of contracts uploads are performed on concurrently, the most promising ones are used and the others are kept idle until an active one fails, must be at least the number of total shards, 0 uses all contracts")
+	flag.Uint64Var(&cfg.Worker.UploadMinDistinctHosts, "worker.uploadMinDistinctHosts", cfg.Worker.UploadMinDistinctHosts, "Min number of distinct hosts required to accept uploads, 0 only requires as many hosts as the upload has shards")
 	flag.DurationVar(&cfg.Worker.UploadOverdriveTimeout, "worker.uploadOverdriveTimeout", cfg.Worker.UploadOverdriveTimeout, "Timeout for overdriving slab uploads")
+	flag.DurationVar(&cfg.Worker.UploadNoCandidateWait, "worker.uploadNoCandidateWait", cfg.Worker.UploadNoCandidateWait, "Max time a slab upload waits for a host to become available when it runs out of hosts, 0 fails the upload immediately")
+	flag.DurationVar(&cfg.Worker.UploadStatsRecomputeInterval, "worker.uploadStatsRecomputeInterval", cfg.Worker.UploadStatsRecomputeInterval, "Min interval for recomputing upload estimates of hosts, lower values give fresher estimates at the cost of CPU")
+	flag.DurationVar(&cfg.Worker.UploadSectorTimeoutMin, "worker.uploadSectorTimeoutMin", cfg.Worker.UploadSectorTimeoutMin, "Lower bound of the per-host sector upload timeout, which is derived from the host's upload speed")
+	flag.DurationVar(&cfg.Worker.UploadSectorTimeoutMax, "worker.uploadSectorTimeoutMax", cfg.Worker.UploadSectorTimeoutMax, "Upper bound of the per-host sector upload timeout, which is derived from the host's upload speed")
+	flag.DurationVar(&cfg.Worker.UploadSlabDeadline, "worker.uploadSlabDeadline", cfg.Worker.UploadSlabDeadline, "Max time a slab upload may take before the upload fails, 0 disables the deadline")
+	flag.IntVar(&cfg.Worker.UploadPersistMaxAttempts, "worker.uploadPersistMaxAttempts", cfg.Worker.UploadPersistMaxAttempts, "Max number of attempts at persisting an uploaded object or part once its data is on the hosts")
+	flag.Uint64Var(&cfg.Worker.UploadETagBufferSize, "worker.uploadETagBufferSize", cfg.Worker.UploadETagBufferSize, "Max number of bytes per upload buffered to compute its ETag concurrently with encrypting the data, 0 computes it serially")
+	flag.DurationVar(&cfg.Worker.UploadShutdownGracePeriod, "worker.uploadShutdownGracePeriod", cfg.Worker.UploadShutdownGracePeriod, "Max time in-flight uploads are given to finish on shutdown before they are aborted")
+	flag.StringVar(&cfg.Worker.ErasureBackend, "worker.erasureBackend", cfg.Worker.ErasureBackend, "Erasure backend used to encode and recover slabs, either 'simd' or 'generic'")
 	flag.BoolVar(&cfg.Worker.Enabled, "worker.enabled", cfg.Worker.Enabled, "Enables/disables worker (overrides with RENTERD_WORKER_ENABLED)")
 	flag.BoolVar(&cfg.Worker.AllowUnauthenticatedDownloads, "worker.unauthenticatedDownloads", cfg.Worker.AllowUnauthenticatedDownloads, "Allows unauthenticated downloads (overrides with RENTERD_WORKER_UNAUTHENTICATED_DOWNLOADS)")
 
@@ -250,16 +302,25 @@ func parseCLIFlags() {
 	flag.Uint64Var(&cfg.Autopilot.ScannerBatchSize, "autopilot.scannerBatchSize", cfg.Autopilot.ScannerBatchSize, "Batch size for host scanning")
 	flag.DurationVar(&cfg.Autopilot.ScannerInterval, "autopilot.scannerInterval", cfg.Autopilot.ScannerInterval, "Interval for scanning hosts")
 	flag.Uint64Var(&cfg.Autopilot.ScannerNumThreads, "autopilot.scannerNumThreads", cfg.Autopilot.ScannerNumThreads, "Number of threads for scanning hosts")
+	flag.DurationVar(&cfg.Autopilot.ScannerMaxBackoff, "autopilot.scannerMaxBackoff", cfg.Autopilot.ScannerMaxBackoff, "Max time between scans of hosts that repeatedly fail their scans (0 to disable backoff)")
 	flag.BoolVar(&cfg.Autopilot.Enabled, "autopilot.enabled", cfg.Autopilot.Enabled, "Enables/disables autopilot (overrides with RENTERD_AUTOPILOT_ENABLED)")
 	flag.DurationVar(&cfg.ShutdownTimeout, "node.shutdownTimeout", cfg.ShutdownTimeout, "Timeout for node shutdown")
 
 	flag.DurationVar(&cfg.Autopilot.MigratorAccountsRefillInterval, "autopilot.migratorAccountRefillInterval", cfg.Autopilot.MigratorAccountsRefillInterval, "Interval for refilling migrator' account balances")
 	flag.Float64Var(&cfg.Autopilot.MigratorHealthCutoff, "autopilot.migratorHealthCutoff", cfg.Autopilot.MigratorHealthCutoff, "Threshold for migrating slabs based on health")
 	flag.Uint64Var(&cfg.Autopilot.MigratorNumThreads, "autopilot.migratorNumThreads", cfg.Autopilot.MigratorNumThreads, "Parallel slab migrations per worker (overrides with RENTERD_MIGRATOR_PARALLEL_SLABS_PER_WORKER)")
+	flag.Uint64Var(&cfg.Autopilot.MigratorRepairBudget, "autopilot.migratorRepairBudget", cfg.Autopilot.MigratorRepairBudget, "Max number of bytes repaired per repair budget interval, 0 means unlimited")
+	flag.DurationVar(&cfg.Autopilot.MigratorRepairBudgetInterval, "autopilot.migratorRepairBudgetInterval", cfg.Autopilot.MigratorRepairBudgetInterval, "Interval over which the repair budget is enforced")
 	flag.Uint64Var(&cfg.Autopilot.MigratorDownloadMaxOverdrive, "autopilot.migratorDownloadMaxOverdrive", cfg.Autopilot.MigratorDownloadMaxOverdrive, "Max overdrive workers for migration downloads")
 	flag.DurationVar(&cfg.Autopilot.MigratorDownloadOverdriveTimeout, "autopilot.migratorDownloadOverdriveTimeout", cfg.Autopilot.MigratorDownloadOverdriveTimeout, "Timeout for overdriving migration downloads")
 	flag.Uint64Var(&cfg.Autopilot.MigratorUploadMaxOverdrive, "autopilot.migratorUploadMaxOverdrive", cfg.Autopilot.MigratorUploadMaxOverdrive, "Max overdrive workers for migration uploads")
 	flag.DurationVar(&cfg.Autopilot.MigratorUploadOverdriveTimeout, "autopilot.migratorUploadOverdriveTimeout", cfg.Autopilot.MigratorUploadOverdriveTimeout, "Timeout for overdriving migration uploads")
+	flag.DurationVar(&cfg.Autopilot.MigratorVerificationInterval, "autopilot.migratorVerificationInterval", cfg.Autopilot.MigratorVerificationInterval, "Interval at which a random sample of stored sectors is verified, 0 disables verification")
+	flag.Uint64Var(&cfg.Autopilot.MigratorVerificationSampleSize, "autopilot.migratorVerificationSampleSize", cfg.Autopilot.MigratorVerificationSampleSize, "Number of sectors sampled for verification per interval, 0 disables verification")
+	flag.DurationVar(&cfg.Autopilot.MigratorReconciliationInterval, "autopilot.migratorReconciliationInterval", cfg.Autopilot.MigratorReconciliationInterval, "Interval at which the roots of a random sample of contracts are compared against the roots their hosts report, 0 disables reconciliation")
+	flag.Uint64Var(&cfg.Autopilot.MigratorReconciliationSampleSize, "autopilot.migratorReconciliationSampleSize", cfg.Autopilot.MigratorReconciliationSampleSize, "Number of contracts sampled for reconciliation per interval, 0 disables reconciliation")
+	flag.DurationVar(&cfg.Autopilot.MigratorChecksumBackfillInterval, "autopilot.migratorChecksumBackfillInterval", cfg.Autopilot.MigratorChecksumBackfillInterval, "Interval at which checksums are computed for objects that were stored without one, 0 disables the backfill")
+	flag.Uint64Var(&cfg.Autopilot.MigratorChecksumBackfillRate, "autopilot.migratorChecksumBackfillRate", cfg.Autopilot.MigratorChecksumBackfillRate, "Max number of bytes per second downloaded to backfill object checksums, 0 means unlimited")
 
 	// s3
 	flag.StringVar(&cfg.S3.Address, "s3.address", cfg.S3.Address, "Address for serving S3 API (overrides with RENTERD_S3_ADDRESS)")
@@ -298,6 +359,9 @@ func parseEnvironmentVariables() {
 	parseEnvVar("RENTERD_BUS_API_PASSWORD", &cfg.Bus.RemotePassword)
 	parseEnvVar("RENTERD_BUS_GATEWAY_ADDR", &cfg.Bus.GatewayAddr)
 	parseEnvVar("RENTERD_BUS_SLAB_BUFFER_COMPLETION_THRESHOLD", &cfg.Bus.SlabBufferCompletionThreshold)
+	parseEnvVar("RENTERD_BUS_SLAB_BUFFER_COMPRESSION", &cfg.Bus.SlabBufferCompression)
+	parseEnvVar("RENTERD_BUS_SLAB_BUFFER_MAX_DISK_USAGE", &cfg.Bus.SlabBufferMaxDiskUsage)
+	parseEnvVar("RENTERD_BUS_SLAB_PRUNING_PARALLELISM", &cfg.Bus.SlabPruningParallelism)
 
 	parseEnvVar("RENTERD_DB_URI", &cfg.Database.MySQL.URI)
 	parseEnvVar("RENTERD_DB_USER", &cfg.Database.MySQL.User)
@@ -36,13 +36,16 @@ Usage:
 `
 	// usageFooter is the footer for the CLI usage text.
 	usageFooter = `
-There are 4 commands:
+There are 5 commands:
   - version: prints the network as well as build information
   - config: builds a YAML config file through a series of prompts
   - seed: generates a new seed and prints the recovery phrase
   - sqlite backup <src> <dest>: backs up the sqlite database at a
     specified source path to the specified destination path
     (safe to use while renterd is running)
+  - sqlite migrate <src>: copies the main sqlite database at the specified
+    source path into the configured MySQL database, an interrupted migration
+    is resumed when running the command again (renterd must not be running)
 
 See the documentation (https://docs.sia.tech/) for more information and examples
 on how to configure and use renterd.
@@ -90,6 +93,10 @@ func main() {
 		flag.Arg(2) != "" && flag.Arg(3) != "" {
 		cmdBackup()
 		return
+	} else if flag.Arg(0) == "sqlite" && flag.Arg(1) == "migrate" &&
+		flag.Arg(2) != "" {
+		cmdMigrateToMySQL()
+		return
 	} else if flag.Arg(0) != "" {
 		flag.Usage()
 		return
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	dsql "database/sql"
 	"errors"
 	"fmt"
 	"net"
@@ -177,6 +178,7 @@ func newNode(cfg config.Config, configPath string, network *consensus.Network, g
 		logger.Info("connecting to remote bus at " + busAddr)
 	}
 	bc := bus.NewClient(busAddr, busPassword)
+	bc.SetDefaultRequestTimeout(cfg.Bus.ClientRequestTimeout)
 
 	// initialise workers
 	var s3Srv *http.Server
@@ -262,13 +264,35 @@ func newAutopilot(masterKey [32]byte, cfg config.Autopilot, bus *bus.Client, l *
 	l = l.Named("autopilot")
 
 	ctx, cancel := context.WithCancelCause(context.Background())
-	m, err := migrator.New(ctx, masterKey, a, bus, bus, cfg.MigratorHealthCutoff, cfg.MigratorNumThreads, cfg.MigratorDownloadMaxOverdrive, cfg.MigratorUploadMaxOverdrive, cfg.MigratorDownloadOverdriveTimeout, cfg.MigratorUploadOverdriveTimeout, cfg.MigratorAccountsRefillInterval, l)
+	m, err := migrator.New(ctx, masterKey, a, bus, bus, migrator.Config{
+		HealthCutoff: cfg.MigratorHealthCutoff,
+		NumThreads:   cfg.MigratorNumThreads,
+
+		DownloadMaxOverdrive:     cfg.MigratorDownloadMaxOverdrive,
+		DownloadOverdriveTimeout: cfg.MigratorDownloadOverdriveTimeout,
+		UploadMaxOverdrive:       cfg.MigratorUploadMaxOverdrive,
+		UploadOverdriveTimeout:   cfg.MigratorUploadOverdriveTimeout,
+
+		RepairBudget:         cfg.MigratorRepairBudget,
+		RepairBudgetInterval: cfg.MigratorRepairBudgetInterval,
+
+		AccountsRefillInterval: cfg.MigratorAccountsRefillInterval,
+
+		VerificationInterval:   cfg.MigratorVerificationInterval,
+		VerificationSampleSize: cfg.MigratorVerificationSampleSize,
+
+		ReconciliationInterval:   cfg.MigratorReconciliationInterval,
+		ReconciliationSampleSize: cfg.MigratorReconciliationSampleSize,
+
+		ChecksumBackfillInterval: cfg.MigratorChecksumBackfillInterval,
+		ChecksumBackfillRate:     cfg.MigratorChecksumBackfillRate,
+	}, l)
 	if err != nil {
 		cancel(nil)
 		return nil, err
 	}
 
-	s, err := scanner.New(bus, cfg.ScannerBatchSize, cfg.ScannerNumThreads, cfg.ScannerInterval, l)
+	s, err := scanner.New(bus, cfg.ScannerBatchSize, cfg.ScannerNumThreads, cfg.ScannerInterval, cfg.ScannerMaxBackoff, l)
 	if err != nil {
 		cancel(nil)
 		return nil, err
@@ -491,6 +515,26 @@ func (n *node) Shutdown() error {
 	return errors.Join(errs...)
 }
 
+// openSQLite opens the SQLite database at the given path, it warns if the
+// configured page size doesn't match the one of an existing database.
+func openSQLite(path string, cfg config.SQLite, logger *zap.Logger) (*dsql.DB, error) {
+	db, err := sqlite.OpenCustom(path, cfg.PageSize, cfg.CacheSize)
+	if err != nil {
+		return nil, err
+	} else if cfg.PageSize == 0 {
+		return db, nil
+	}
+
+	pageSize, err := sqlite.PageSize(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to fetch page size: %w", err)
+	} else if pageSize != cfg.PageSize {
+		logger.Sugar().Warnf("configured SQLite page size %d is ignored for existing database '%s' with page size %d, it only applies to new databases", cfg.PageSize, path, pageSize)
+	}
+	return db, nil
+}
+
 // TODO: needs a better spot
 func buildStoreConfig(am alerts.Alerter, cfg config.Config, pk types.PrivateKey, logger *zap.Logger) (stores.Config, error) {
 	partialSlabDir := filepath.Join(cfg.Directory, "partial_slabs")
@@ -539,7 +583,7 @@ func buildStoreConfig(am alerts.Alerter, cfg config.Config, pk types.PrivateKey,
 		}
 
 		// create SQLite connections
-		db, err := sqlite.Open(filepath.Join(dbDir, "db.sqlite"))
+		db, err := openSQLite(filepath.Join(dbDir, "db.sqlite"), cfg.Database.SQLite, logger)
 		if err != nil {
 			return stores.Config{}, fmt.Errorf("failed to open SQLite main database: %w", err)
 		}
@@ -548,7 +592,7 @@ func buildStoreConfig(am alerts.Alerter, cfg config.Config, pk types.PrivateKey,
 			return stores.Config{}, fmt.Errorf("failed to create SQLite main database: %w", err)
 		}
 
-		dbm, err := sqlite.Open(filepath.Join(dbDir, "metrics.sqlite"))
+		dbm, err := openSQLite(filepath.Join(dbDir, "metrics.sqlite"), cfg.Database.SQLite, logger)
 		if err != nil {
 			return stores.Config{}, fmt.Errorf("failed to open SQLite metrics database: %w", err)
 		}
@@ -565,6 +609,11 @@ func buildStoreConfig(am alerts.Alerter, cfg config.Config, pk types.PrivateKey,
 		PartialSlabDir:                partialSlabDir,
 		Migrate:                       true,
 		SlabBufferCompletionThreshold: cfg.Bus.SlabBufferCompletionThreshold,
+		SlabBufferCompression:         cfg.Bus.SlabBufferCompression,
+		SlabBufferMaxDiskUsage:        cfg.Bus.SlabBufferMaxDiskUsage,
+		SlabPruningParallelism:        cfg.Bus.SlabPruningParallelism,
+		ObjectEventLog:                cfg.Bus.ObjectEventLog,
+		ObjectEventRetention:          cfg.Bus.ObjectEventRetention,
 		Logger:                        logger,
 		WalletAddress:                 types.StandardUnlockHash(pk.PublicKey()),
 		LongQueryDuration:             cfg.Log.Database.SlowThreshold,
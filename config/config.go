@@ -53,7 +53,8 @@ type (
 
 	Database struct {
 		// optional fields depending on backend
-		MySQL MySQL `yaml:"mysql,omitempty"`
+		MySQL  MySQL  `yaml:"mysql,omitempty"`
+		SQLite SQLite `yaml:"sqlite,omitempty"`
 	}
 
 	// Bus contains the configuration for a bus.
@@ -61,11 +62,17 @@ type (
 		AllowPrivateIPs               bool          `yaml:"allowPrivateIPs,omitempty"`
 		AnnouncementMaxAgeHours       uint64        `yaml:"announcementMaxAgeHours,omitempty"`
 		Bootstrap                     bool          `yaml:"bootstrap,omitempty"`
+		ClientRequestTimeout          time.Duration `yaml:"clientRequestTimeout,omitempty"`
 		GatewayAddr                   string        `yaml:"gatewayAddr,omitempty"`
+		ObjectEventLog                bool          `yaml:"objectEventLog,omitempty"`
+		ObjectEventRetention          time.Duration `yaml:"objectEventRetention,omitempty"`
 		RemoteAddr                    string        `yaml:"remoteAddr,omitempty"`
 		RemotePassword                string        `yaml:"remotePassword,omitempty"`
 		UsedUTXOExpiry                time.Duration `yaml:"usedUtxoExpiry,omitempty"`
 		SlabBufferCompletionThreshold int64         `yaml:"slabBufferCompleionThreshold,omitempty"`
+		SlabBufferCompression         string        `yaml:"slabBufferCompression,omitempty"`
+		SlabBufferMaxDiskUsage        int64         `yaml:"slabBufferMaxDiskUsage,omitempty"`
+		SlabPruningParallelism        int           `yaml:"slabPruningParallelism,omitempty"`
 	}
 
 	// LogFile configures the file output of the logger.
@@ -96,6 +103,8 @@ type (
 	SQLite struct {
 		Database        string `yaml:"database,omitempty"`
 		MetricsDatabase string `yaml:"metricsDatabase,omitempty"`
+		PageSize        int64  `yaml:"pageSize,omitempty"`
+		CacheSize       int64  `yaml:"cacheSize,omitempty"`
 	}
 
 	// MySQL contains the configuration for a MySQL database.
@@ -117,18 +126,42 @@ type (
 
 	// Worker contains the configuration for a worker.
 	Worker struct {
-		Enabled                       bool          `yaml:"enabled,omitempty"`
-		ID                            string        `yaml:"id,omitempty"`
-		AccountsRefillInterval        time.Duration `yaml:"accountsRefillInterval,omitempty"`
-		BusFlushInterval              time.Duration `yaml:"busFlushInterval,omitempty"`
-		DownloadOverdriveTimeout      time.Duration `yaml:"downloadOverdriveTimeout,omitempty"`
-		UploadOverdriveTimeout        time.Duration `yaml:"uploadOverdriveTimeout,omitempty"`
-		DownloadMaxOverdrive          uint64        `yaml:"downloadMaxOverdrive,omitempty"`
-		DownloadMaxMemory             uint64        `yaml:"downloadMaxMemory,omitempty"`
-		UploadMaxMemory               uint64        `yaml:"uploadMaxMemory,omitempty"`
-		UploadMaxOverdrive            uint64        `yaml:"uploadMaxOverdrive,omitempty"`
-		AllowUnauthenticatedDownloads bool          `yaml:"allowUnauthenticatedDownloads,omitempty"`
-		CacheExpiry                   time.Duration `yaml:"cacheExpiry,omitempty"`
+		Enabled                          bool          `yaml:"enabled,omitempty"`
+		ID                               string        `yaml:"id,omitempty"`
+		AccountsRefillInterval           time.Duration `yaml:"accountsRefillInterval,omitempty"`
+		BusFlushInterval                 time.Duration `yaml:"busFlushInterval,omitempty"`
+		DownloadOverdriveTimeout         time.Duration `yaml:"downloadOverdriveTimeout,omitempty"`
+		UploadOverdriveTimeout           time.Duration `yaml:"uploadOverdriveTimeout,omitempty"`
+		DownloadMaxOverdrive             uint64        `yaml:"downloadMaxOverdrive,omitempty"`
+		DownloadMaxHostsPerSlab          uint64        `yaml:"downloadMaxHostsPerSlab,omitempty"`
+		DownloadMaxMemory                uint64        `yaml:"downloadMaxMemory,omitempty"`
+		DownloadReadRepair               bool          `yaml:"downloadReadRepair,omitempty"`
+		DownloadReadRepairBudget         uint64        `yaml:"downloadReadRepairBudget,omitempty"`
+		DownloadReadRepairBudgetInterval time.Duration `yaml:"downloadReadRepairBudgetInterval,omitempty"`
+		HostBreakerThreshold             uint64        `yaml:"hostBreakerThreshold,omitempty"`
+		HostBreakerCooldown              time.Duration `yaml:"hostBreakerCooldown,omitempty"`
+		UploadMaxMemory                  uint64        `yaml:"uploadMaxMemory,omitempty"`
+		UploadMaxOverdrive               uint64        `yaml:"uploadMaxOverdrive,omitempty"`
+		UploadMaxOverdrivePerSlab        uint64        `yaml:"uploadMaxOverdrivePerSlab,omitempty"`
+		UploadMinFreeMemory              uint64        `yaml:"uploadMinFreeMemory,omitempty"`
+		UploadContractDurationWeight     float64       `yaml:"uploadContractDurationWeight,omitempty"`
+		UploadCandidateTolerance         float64       `yaml:"uploadCandidateTolerance,omitempty"`
+		UploadCandidateFailureThreshold  uint64        `yaml:"uploadCandidateFailureThreshold,omitempty"`
+		UploadMaxActiveUploaders         uint64        `yaml:"uploadMaxActiveUploaders,omitempty"`
+		UploadMinDistinctHosts           uint64        `yaml:"uploadMinDistinctHosts,omitempty"`
+		UploadNoCandidateWait            time.Duration `yaml:"uploadNoCandidateWait,omitempty"`
+		UploadStatsRecomputeInterval     time.Duration `yaml:"uploadStatsRecomputeInterval,omitempty"`
+		UploadSectorTimeoutMin           time.Duration `yaml:"uploadSectorTimeoutMin,omitempty"`
+		UploadSectorTimeoutMax           time.Duration `yaml:"uploadSectorTimeoutMax,omitempty"`
+		UploadSlabDeadline               time.Duration `yaml:"uploadSlabDeadline,omitempty"`
+		UploadShutdownGracePeriod        time.Duration `yaml:"uploadShutdownGracePeriod,omitempty"`
+		UploadPersistMaxAttempts         int           `yaml:"uploadPersistMaxAttempts,omitempty"`
+		UploadETagBufferSize             uint64        `yaml:"uploadETagBufferSize,omitempty"`
+		AllowUnauthenticatedDownloads    bool          `yaml:"allowUnauthenticatedDownloads,omitempty"`
+		CacheExpiry                      time.Duration `yaml:"cacheExpiry,omitempty"`
+		CacheExpiryDownloadPrices        time.Duration `yaml:"cacheExpiryDownloadPrices,omitempty"`
+		CacheExpiryUsableHosts           time.Duration `yaml:"cacheExpiryUsableHosts,omitempty"`
+		ErasureBackend                   string        `yaml:"erasureBackend,omitempty"`
 	}
 
 	// Autopilot contains the configuration for an autopilot.
@@ -137,17 +170,26 @@ type (
 		AllowRedundantHostIPs            bool          `yaml:"allowRedundantHostIPs,omitempty"`
 		Heartbeat                        time.Duration `yaml:"heartbeat,omitempty"`
 		MigratorAccountsRefillInterval   time.Duration `yaml:"migratorAccountsRefillInterval,omitempty"`
+		MigratorChecksumBackfillInterval time.Duration `yaml:"migratorChecksumBackfillInterval,omitempty"`
+		MigratorChecksumBackfillRate     uint64        `yaml:"migratorChecksumBackfillRate,omitempty"`
 		MigratorDownloadMaxOverdrive     uint64        `yaml:"migratorDownloadMaxOverdrive,omitempty"`
 		MigratorDownloadOverdriveTimeout time.Duration `yaml:"migratorDownloadOverdriveTimeout,omitempty"`
 		MigratorHealthCutoff             float64       `yaml:"migratorHealthCutoff,omitempty"`
 		MigratorNumThreads               uint64        `yaml:"migratorNumThreads,omitempty"`
+		MigratorRepairBudget             uint64        `yaml:"migratorRepairBudget,omitempty"`
+		MigratorRepairBudgetInterval     time.Duration `yaml:"migratorRepairBudgetInterval,omitempty"`
 		MigratorUploadMaxOverdrive       uint64        `yaml:"migratorUploadMaxOverdrive,omitempty"`
 		MigratorUploadOverdriveTimeout   time.Duration `yaml:"migratorUploadOverdriveTimeout,omitempty"`
+		MigratorVerificationInterval     time.Duration `yaml:"migratorVerificationInterval,omitempty"`
+		MigratorVerificationSampleSize   uint64        `yaml:"migratorVerificationSampleSize,omitempty"`
+		MigratorReconciliationInterval   time.Duration `yaml:"migratorReconciliationInterval,omitempty"`
+		MigratorReconciliationSampleSize uint64        `yaml:"migratorReconciliationSampleSize,omitempty"`
 		RevisionBroadcastInterval        time.Duration `yaml:"revisionBroadcastInterval,omitempty"`
 		RevisionSubmissionBuffer         uint64        `yaml:"revisionSubmissionBuffer,omitempty"`
 		ScannerInterval                  time.Duration `yaml:"scannerInterval,omitempty"`
 		ScannerBatchSize                 uint64        `yaml:"scannerBatchSize,omitempty"`
 		ScannerNumThreads                uint64        `yaml:"scannerNumThreads,omitempty"`
+		ScannerMaxBackoff                time.Duration `yaml:"scannerMaxBackoff,omitempty"`
 	}
 )
 
@@ -0,0 +1,196 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.sia.tech/core/types"
+)
+
+const (
+	// DefaultThreshold is the default number of consecutive failures after
+	// which a host's breaker opens, DefaultCooldown is the default time after
+	// which the host is given another chance.
+	DefaultThreshold = 5
+	DefaultCooldown  = time.Minute
+)
+
+const (
+	// StateClosed indicates the host is considered healthy and requests are
+	// sent to it.
+	StateClosed State = "closed"
+
+	// StateOpen indicates the host failed too many requests in a row and is
+	// avoided until the cooldown has passed.
+	StateOpen State = "open"
+
+	// StateHalfOpen indicates the cooldown has passed and a single probe
+	// request is allowed to decide whether the breaker closes again.
+	StateHalfOpen State = "halfOpen"
+)
+
+// ErrOpen is returned for requests to a host whose breaker is open.
+var ErrOpen = errors.New("host circuit breaker is open")
+
+type (
+	// State is the state of a host's circuit breaker.
+	State string
+
+	// Breakers keeps track of a circuit breaker per host. It's shared between
+	// subsystems, so a host that trips the breaker in one of them is avoided by
+	// all of them. A nil Breakers allows all requests.
+	Breakers struct {
+		threshold uint64
+		cooldown  time.Duration
+
+		mu    sync.Mutex
+		hosts map[types.PublicKey]*breaker
+	}
+
+	breaker struct {
+		failures uint64
+		openedAt time.Time
+		probedAt time.Time
+	}
+)
+
+// New returns a set of host circuit breakers that open after 'threshold'
+// consecutive failures and allow a probe request after 'cooldown'.
+func New(threshold uint64, cooldown time.Duration) *Breakers {
+	return &Breakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[types.PublicKey]*breaker),
+	}
+}
+
+// Allow returns whether a request to the given host is allowed. If the breaker
+// is half-open the caller is granted the probe request, until the probe
+// completes or the cooldown passes again other requests are refused.
+func (b *Breakers) Allow(hk types.PublicKey) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.hosts[hk]
+	if !ok {
+		return true
+	}
+	switch b.state(br) {
+	case StateClosed:
+		return true
+	case StateHalfOpen:
+		if time.Since(br.probedAt) < b.cooldown {
+			return false // probe in flight
+		}
+		br.probedAt = time.Now()
+		return true
+	default:
+		return false
+	}
+}
+
+// Available returns whether the host's breaker would allow a request, unlike
+// Allow it doesn't claim the probe request of a half-open breaker.
+func (b *Breakers) Available(hk types.PublicKey) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.hosts[hk]
+	if !ok {
+		return true
+	}
+	switch b.state(br) {
+	case StateClosed:
+		return true
+	case StateHalfOpen:
+		return time.Since(br.probedAt) >= b.cooldown
+	default:
+		return false
+	}
+}
+
+// RecordFailure records a failed request to the given host, a failing probe
+// request opens the breaker again.
+func (b *Breakers) RecordFailure(hk types.PublicKey) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.hosts[hk]
+	if !ok {
+		br = &breaker{}
+		b.hosts[hk] = br
+	}
+
+	br.failures++
+	if br.failures >= b.threshold {
+		br.openedAt = time.Now()
+		br.probedAt = time.Time{}
+	}
+}
+
+// RecordSuccess records a successful request to the given host, closing its
+// breaker.
+func (b *Breakers) RecordSuccess(hk types.PublicKey) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.hosts, hk)
+}
+
+// State returns the state of the given host's breaker.
+func (b *Breakers) State(hk types.PublicKey) State {
+	if b == nil {
+		return StateClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.hosts[hk]
+	if !ok {
+		return StateClosed
+	}
+	return b.state(br)
+}
+
+// States returns the state of every breaker that isn't closed.
+func (b *Breakers) States() map[types.PublicKey]State {
+	states := make(map[types.PublicKey]State)
+	if b == nil {
+		return states
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for hk, br := range b.hosts {
+		if state := b.state(br); state != StateClosed {
+			states[hk] = state
+		}
+	}
+	return states
+}
+
+func (b *Breakers) state(br *breaker) State {
+	if br.failures < b.threshold {
+		return StateClosed
+	} else if time.Since(br.openedAt) < b.cooldown {
+		return StateOpen
+	}
+	return StateHalfOpen
+}
@@ -0,0 +1,67 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+)
+
+func TestBreakers(t *testing.T) {
+	cooldown := 50 * time.Millisecond
+	b := New(2, cooldown)
+	hk := types.PublicKey{1}
+
+	assertState := func(state State, allowed bool) {
+		t.Helper()
+		if s := b.State(hk); s != state {
+			t.Fatalf("expected state %v, got %v", state, s)
+		} else if b.Available(hk) != allowed {
+			t.Fatalf("expected available to be %v", allowed)
+		}
+	}
+
+	// breaker opens after the threshold is reached
+	assertState(StateClosed, true)
+	b.RecordFailure(hk)
+	assertState(StateClosed, true)
+	b.RecordFailure(hk)
+	assertState(StateOpen, false)
+	if b.Allow(hk) {
+		t.Fatal("expected request to be refused")
+	} else if states := b.States(); len(states) != 1 || states[hk] != StateOpen {
+		t.Fatal("unexpected states", states)
+	}
+
+	// after the cooldown a single probe is allowed
+	time.Sleep(cooldown)
+	assertState(StateHalfOpen, true)
+	if !b.Allow(hk) {
+		t.Fatal("expected probe to be allowed")
+	} else if b.Allow(hk) {
+		t.Fatal("expected only one probe to be allowed")
+	}
+	assertState(StateHalfOpen, false)
+
+	// a failing probe opens the breaker again
+	b.RecordFailure(hk)
+	assertState(StateOpen, false)
+
+	// a successful probe closes it
+	time.Sleep(cooldown)
+	if !b.Allow(hk) {
+		t.Fatal("expected probe to be allowed")
+	}
+	b.RecordSuccess(hk)
+	assertState(StateClosed, true)
+	if states := b.States(); len(states) != 0 {
+		t.Fatal("unexpected states", states)
+	}
+
+	// a nil breaker allows everything
+	var nb *Breakers
+	nb.RecordFailure(hk)
+	if !nb.Allow(hk) || !nb.Available(hk) || nb.State(hk) != StateClosed {
+		t.Fatal("expected nil breaker to allow requests")
+	}
+}
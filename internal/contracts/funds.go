@@ -0,0 +1,32 @@
+package contracts
+
+import "go.sia.tech/core/types"
+
+// DefaultOutOfFundsReserve is the default percentage of a contract's initial
+// funds that is kept in reserve, a contract whose remaining funds drop to or
+// below it is considered out of funds.
+const DefaultOutOfFundsReserve = 10
+
+// IsOutOfFunds returns true if the remaining 'renterFunds' of a contract are
+// less than or equal to 'reservePct' percent of its initial funds.
+func IsOutOfFunds(renterFunds, initialRenterFunds types.Currency, reservePct uint64) bool {
+	// InitialRenterFunds should never be zero but for legacy reasons we check
+	// and return true should it be the case
+	if initialRenterFunds.IsZero() {
+		return true
+	}
+	return renterFunds.Cmp(outOfFundsReserve(initialRenterFunds, reservePct)) <= 0
+}
+
+// UsableFunds returns the part of the remaining 'renterFunds' of a contract
+// that can be spent before the contract is considered out of funds.
+func UsableFunds(renterFunds, initialRenterFunds types.Currency, reservePct uint64) types.Currency {
+	if IsOutOfFunds(renterFunds, initialRenterFunds, reservePct) {
+		return types.ZeroCurrency
+	}
+	return renterFunds.Sub(outOfFundsReserve(initialRenterFunds, reservePct))
+}
+
+func outOfFundsReserve(initialRenterFunds types.Currency, reservePct uint64) types.Currency {
+	return initialRenterFunds.Mul64(reservePct).Div64(100)
+}
@@ -0,0 +1,38 @@
+package contracts
+
+import (
+	"math"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	rhpv3 "go.sia.tech/core/rhp/v3"
+	rhpv4 "go.sia.tech/core/rhp/v4"
+	"go.sia.tech/core/types"
+)
+
+// RenterFundsToExpectedStorage returns how much storage a renter is expected to
+// be able to afford given the provided 'renterFunds'.
+func RenterFundsToExpectedStorage(renterFunds types.Currency, duration uint64, pt rhpv3.HostPriceTable) uint64 {
+	costPerSector, _ := pt.BaseCost().Add(pt.AppendSectorCost(duration)).Total()
+	return fundsToStorage(renterFunds, costPerSector, rhpv2.SectorSize)
+}
+
+// RenterFundsToExpectedStorageV2 returns how much storage a renter is expected
+// to be able to afford given the provided 'renterFunds'.
+func RenterFundsToExpectedStorageV2(renterFunds types.Currency, duration uint64, hp rhpv4.HostPrices) uint64 {
+	sectorUploadCost := hp.RPCWriteSectorCost(rhpv4.SectorSize).RenterCost()
+	sectorStorageCost := hp.RPCAppendSectorsCost(1, duration).RenterCost()
+	return fundsToStorage(renterFunds, sectorUploadCost.Add(sectorStorageCost), rhpv4.SectorSize)
+}
+
+func fundsToStorage(renterFunds, costPerSector types.Currency, sectorSize uint64) uint64 {
+	// handle free storage
+	if costPerSector.IsZero() {
+		costPerSector = types.NewCurrency64(1)
+	}
+	// catch overflow
+	expectedStorage := renterFunds.Div(costPerSector).Mul64(sectorSize)
+	if expectedStorage.Cmp(types.NewCurrency64(math.MaxUint64)) > 0 {
+		expectedStorage = types.NewCurrency64(math.MaxUint64)
+	}
+	return expectedStorage.Big().Uint64()
+}
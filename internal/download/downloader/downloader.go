@@ -10,6 +10,7 @@ import (
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/internal/breaker"
 	"go.sia.tech/renterd/internal/host"
 	rhp3 "go.sia.tech/renterd/internal/rhp/v3"
 	"go.sia.tech/renterd/internal/utils"
@@ -55,6 +56,7 @@ type (
 )
 type (
 	Downloader struct {
+		hb   *breaker.Breakers
 		host host.Downloader
 
 		statsDownloadSpeedBytesPerMS    *utils.DataPoints // keep track of this separately for stats (no decay is applied)
@@ -73,8 +75,9 @@ type (
 	}
 )
 
-func New(ctx context.Context, h host.Downloader) *Downloader {
+func New(ctx context.Context, hb *breaker.Breakers, h host.Downloader) *Downloader {
 	return &Downloader{
+		hb:   hb,
 		host: h,
 
 		statsSectorDownloadEstimateInMS: utils.NewDataPoints(10 * time.Minute),
@@ -98,6 +101,12 @@ func (d *Downloader) AvgDownloadSpeedBytesPerMS() float64 {
 }
 
 func (d *Downloader) Enqueue(download *SectorDownloadReq) {
+	// check whether the host's circuit breaker allows the request
+	if !d.hb.Allow(d.PublicKey()) {
+		go download.fail(breaker.ErrOpen) // don't block the caller
+		return
+	}
+
 	d.mu.Lock()
 	// check for stopped
 	if d.stopped {
@@ -343,6 +352,7 @@ func (d *Downloader) trackFailure(err error) {
 
 	if err == nil {
 		d.consecutiveFailures = 0
+		d.hb.RecordSuccess(d.PublicKey())
 		return
 	}
 
@@ -354,6 +364,9 @@ func (d *Downloader) trackFailure(err error) {
 	}
 
 	d.consecutiveFailures++
+	if !utils.IsErr(err, context.Canceled) {
+		d.hb.RecordFailure(d.PublicKey()) // overdrive cancellations don't trip the breaker
+	}
 	d.statsSectorDownloadEstimateInMS.Track(float64(time.Hour.Milliseconds()))
 }
 
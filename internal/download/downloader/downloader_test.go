@@ -24,7 +24,7 @@ func TestDownloaderStopped(t *testing.T) {
 
 	t.Run("stop before enqueue", func(t *testing.T) {
 		hm := mocks.NewHostManager()
-		dl := New(context.Background(), hm.Downloader(api.HostInfo{}))
+		dl := New(context.Background(), nil, hm.Downloader(api.HostInfo{}))
 		req := SectorDownloadReq{
 			Ctx:   context.Background(),
 			Resps: NewSectorResponses(),
@@ -38,7 +38,7 @@ func TestDownloaderStopped(t *testing.T) {
 
 	t.Run("stop after enqueue", func(t *testing.T) {
 		hm := mocks.NewHostManager()
-		dl := New(context.Background(), hm.Downloader(api.HostInfo{}))
+		dl := New(context.Background(), nil, hm.Downloader(api.HostInfo{}))
 		req := SectorDownloadReq{
 			Ctx:   context.Background(),
 			Resps: NewSectorResponses(),
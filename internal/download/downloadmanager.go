@@ -14,6 +14,7 @@ import (
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/breaker"
 	"go.sia.tech/renterd/internal/download/downloader"
 	"go.sia.tech/renterd/internal/hosts"
 	"go.sia.tech/renterd/internal/memory"
@@ -34,21 +35,38 @@ const (
 )
 
 var (
-	ErrDownloadCancelled      = errors.New("download was cancelled")
-	ErrDownloadNotEnoughHosts = errors.New("not enough hosts available to download the slab")
-	ErrShuttingDown           = errors.New("download manager is shutting down")
+	ErrDownloadCancelled           = errors.New("download was cancelled")
+	ErrDownloadNotEnoughHosts      = errors.New("not enough hosts available to download the slab")
+	ErrInsufficientShardsRecovered = errors.New("insufficient shards recovered")
+	ErrMaxHostsPerSlabReached      = errors.New("max hosts per slab reached")
+	ErrShuttingDown                = errors.New("download manager is shutting down")
 
 	errHostNoLongerUsable = errors.New("host no longer usable")
 )
 
 type (
+	// A ReadRepairer repairs the unhealthy shards of a slab using the shards
+	// that were downloaded to recover it.
+	ReadRepairer interface {
+		// ReadRepair is called with the decrypted shards of a recovered slab
+		// and the indices of its unhealthy shards. Shards that weren't
+		// downloaded have a len of zero. If the repairer returns true it
+		// takes ownership of the shards and the memory they were downloaded
+		// with, which it releases once the repair is done. It must not
+		// block the download.
+		ReadRepair(s object.Slab, shards [][]byte, unhealthy []int, mem memory.Memory) bool
+	}
+
 	Manager struct {
+		hb        *breaker.Breakers
 		hm        hosts.Manager
 		mm        memory.MemoryManager
 		os        ObjectStore
+		eb        object.ErasureBackend
 		uploadKey *utils.UploadKey
 		logger    *zap.SugaredLogger
 
+		maxHostsPerSlab  uint64
 		maxOverdrive     uint64
 		overdriveTimeout time.Duration
 
@@ -64,14 +82,18 @@ type (
 	slabDownload struct {
 		mgr *Manager
 
-		minShards int
-		offset    uint64
-		length    uint64
+		minShards    int
+		offset       uint64
+		length       uint64
+		maxHosts     uint64
+		maxOverdrive uint64
+		prices       map[types.PublicKey]types.Currency
 
 		created time.Time
 
 		mu             sync.Mutex
 		lastOverdrive  time.Time
+		numAttempted   uint64
 		numCompleted   int
 		numInflight    uint64
 		numLaunched    uint64
@@ -79,6 +101,11 @@ type (
 
 		sectors []*sectorInfo
 		errs    utils.HostErrorSet
+
+		// budgetErr is set when the slab download ran out of hosts it's
+		// allowed to try, it's not tied to any host so it's tracked
+		// separately from errs
+		budgetErr error
 	}
 
 	slabDownloadResponse struct {
@@ -115,15 +142,21 @@ func (s *sectorInfo) selectHost(h types.PublicKey) {
 	}
 }
 
-func NewManager(ctx context.Context, uploadKey *utils.UploadKey, hm hosts.Manager, mm memory.MemoryManager, os ObjectStore, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.Logger) *Manager {
+// NewManager returns a new download manager. The number of hosts that are tried
+// when downloading a single slab is bounded by maxHostsPerSlab, if it's 0 all
+// hosts that store a sector of the slab are tried.
+func NewManager(ctx context.Context, uploadKey *utils.UploadKey, hb *breaker.Breakers, hm hosts.Manager, mm memory.MemoryManager, os ObjectStore, eb object.ErasureBackend, maxHostsPerSlab, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.Logger) *Manager {
 	logger = logger.Named("downloadmanager")
 	return &Manager{
+		hb:        hb,
 		hm:        hm,
 		mm:        mm,
 		os:        os,
+		eb:        eb,
 		uploadKey: uploadKey,
 		logger:    logger.Sugar(),
 
+		maxHostsPerSlab:  maxHostsPerSlab,
 		maxOverdrive:     maxOverdrive,
 		overdriveTimeout: overdriveTimeout,
 
@@ -136,7 +169,13 @@ func NewManager(ctx context.Context, uploadKey *utils.UploadKey, hm hosts.Manage
 	}
 }
 
-func (mgr *Manager) DownloadObject(ctx context.Context, w io.Writer, o object.Object, offset, length uint64, hosts []api.HostInfo) (err error) {
+func (mgr *Manager) DownloadObject(ctx context.Context, w io.Writer, o object.Object, offset, length uint64, hosts []api.HostInfo, opts ...Option) (err error) {
+	// apply the options
+	var params parameters
+	for _, opt := range opts {
+		opt(&params)
+	}
+
 	// calculate what slabs we need
 	var ss []slabSlice
 	for _, s := range o.Slabs {
@@ -178,8 +217,9 @@ func (mgr *Manager) DownloadObject(ctx context.Context, w io.Writer, o object.Ob
 
 	// create the cipher writer
 	cw, err := o.Key.Decrypt(w, object.EncryptionOptions{
-		Offset: offset,
-		Key:    mgr.uploadKey,
+		Offset:      offset,
+		Key:         mgr.uploadKey,
+		CustomerKey: params.customerKey,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create cipher writer: %w", err)
@@ -252,7 +292,7 @@ func (mgr *Manager) DownloadObject(ctx context.Context, w io.Writer, o object.Ob
 			wg.Add(1)
 			go func(index int) {
 				defer wg.Done()
-				shards, err := mgr.downloadSlab(ctx, next.SlabSlice)
+				shards, err := mgr.downloadSlab(ctx, next.SlabSlice, params)
 				select {
 				case responseChan <- &slabDownloadResponse{
 					mem:    mem,
@@ -268,8 +308,16 @@ func (mgr *Manager) DownloadObject(ctx context.Context, w io.Writer, o object.Ob
 	}()
 
 	// collect the response, responses might come in out of order so we keep
-	// them in a map and return what we can when we can
+	// them in a map and return what we can when we can, their memory is
+	// released once they were handled
 	responses := make(map[int]*slabDownloadResponse)
+	defer func() {
+		for _, resp := range responses {
+			if resp.mem != nil {
+				resp.mem.Release()
+			}
+		}
+	}()
 	var respIndex int
 outer:
 	for {
@@ -284,47 +332,29 @@ outer:
 
 		// handle response
 		err := func() error {
-			if resp.mem != nil {
-				defer resp.mem.Release()
-			}
-
 			if resp.err != nil {
+				if resp.mem != nil {
+					resp.mem.Release()
+				}
 				mgr.logger.Errorw("slab download failed",
 					zap.Int("index", resp.index),
-					zap.Error(err),
+					zap.Error(resp.err),
 				)
 				return resp.err
 			}
 
 			responses[resp.index] = resp
 			for {
-				if next, exists := responses[respIndex]; exists {
-					s := slabs[respIndex]
-					if s.PartialSlab {
-						// Partial slab.
-						_, err = bw.Write(s.Data)
-						if err != nil {
-							mgr.logger.Errorf("failed to send partial slab", respIndex, err)
-							return err
-						}
-					} else {
-						// Regular slab.
-						slabs[respIndex].Decrypt(next.shards)
-						err := slabs[respIndex].Recover(bw, next.shards)
-						if err != nil {
-							mgr.logger.Errorf("failed to recover slab %v: %v", respIndex, err)
-							return err
-						}
-					}
-
-					next = nil
-					delete(responses, respIndex)
-					respIndex++
-
-					continue
-				} else {
+				next, exists := responses[respIndex]
+				if !exists {
 					break
 				}
+				delete(responses, respIndex)
+				if err := mgr.writeSlab(bw, slabs[respIndex], next, available, params.readRepairer); err != nil {
+					mgr.logger.Errorf("failed to write slab %v: %v", respIndex, err)
+					return err
+				}
+				respIndex++
 			}
 			return nil
 		}()
@@ -341,7 +371,57 @@ outer:
 	return nil
 }
 
+// writeSlab recovers the slab from the downloaded shards and writes it to the
+// writer. The response's memory is released afterwards, unless the shards were
+// handed off to the read-repairer which then releases it.
+func (mgr *Manager) writeSlab(w io.Writer, s slabSlice, resp *slabDownloadResponse, available map[types.PublicKey]struct{}, rr ReadRepairer) (err error) {
+	handedOff := false
+	defer func() {
+		if resp.mem != nil && !handedOff {
+			resp.mem.Release()
+		}
+	}()
+
+	// partial slabs are written as is
+	if s.PartialSlab {
+		_, err = w.Write(s.Data)
+		return err
+	}
+
+	// recover regular slabs
+	s.Decrypt(resp.shards)
+	if err := s.Recover(mgr.eb, w, resp.shards); err != nil {
+		return err
+	}
+
+	// repair the slab if necessary
+	if rr != nil {
+		if unhealthy := readRepairShards(s.SlabSlice, available); len(unhealthy) > 0 {
+			handedOff = rr.ReadRepair(s.Slab, resp.shards, unhealthy, resp.mem)
+		}
+	}
+	return nil
+}
+
 func (mgr *Manager) DownloadSlab(ctx context.Context, slab object.Slab, hosts []api.HostInfo) ([][]byte, error) {
+	shards, err := mgr.DownloadShards(ctx, slab, hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	// recover the missing shards
+	err = slab.Reconstruct(mgr.eb, shards)
+	if err != nil {
+		return nil, err
+	}
+
+	return shards, err
+}
+
+// DownloadShards downloads the minimum number of shards required to recover
+// the given slab and returns them decrypted. Shards that weren't downloaded
+// have a len of zero.
+func (mgr *Manager) DownloadShards(ctx context.Context, slab object.Slab, hosts []api.HostInfo) ([][]byte, error) {
 	// refresh the downloaders
 	mgr.refreshDownloaders(hosts)
 
@@ -364,8 +444,8 @@ func (mgr *Manager) DownloadSlab(ctx context.Context, slab object.Slab, hosts []
 		return nil, fmt.Errorf("not enough hosts available to download the slab: %v/%v", availableShards, slab.MinShards)
 	}
 
-	// NOTE: we don't acquire memory here since DownloadSlab is only used for
-	// migrations which already have memory acquired
+	// NOTE: we don't acquire memory here since DownloadShards is only used
+	// for migrations which already have memory acquired
 
 	// download the slab
 	slice := object.SlabSlice{
@@ -373,19 +453,14 @@ func (mgr *Manager) DownloadSlab(ctx context.Context, slab object.Slab, hosts []
 		Offset: 0,
 		Length: uint32(slab.MinShards) * rhpv2.SectorSize,
 	}
-	shards, err := mgr.downloadSlab(ctx, slice)
+	shards, err := mgr.downloadSlab(ctx, slice, parameters{})
 	if err != nil {
 		return nil, err
 	}
 
-	// decrypt and recover
+	// decrypt the shards
 	slice.Decrypt(shards)
-	err = slice.Reconstruct(shards)
-	if err != nil {
-		return nil, err
-	}
-
-	return shards, err
+	return shards, nil
 }
 
 func (mgr *Manager) MemoryStatus() memory.Status {
@@ -475,12 +550,12 @@ func (mgr *Manager) refreshDownloaders(hosts []api.HostInfo) {
 
 	// update downloaders
 	for hk, hi := range want {
-		mgr.downloaders[hk] = downloader.New(mgr.shutdownCtx, mgr.hm.Downloader(hi))
+		mgr.downloaders[hk] = downloader.New(mgr.shutdownCtx, mgr.hb, mgr.hm.Downloader(hi))
 		go mgr.downloaders[hk].Start()
 	}
 }
 
-func (mgr *Manager) newSlabDownload(slice object.SlabSlice) *slabDownload {
+func (mgr *Manager) newSlabDownload(slice object.SlabSlice, params parameters) *slabDownload {
 	// calculate the offset and length
 	offset, length := slice.SectorRegion()
 
@@ -498,13 +573,28 @@ func (mgr *Manager) newSlabDownload(slice object.SlabSlice) *slabDownload {
 		})
 	}
 
+	// disable overdrive when optimizing for cost
+	maxOverdrive := mgr.maxOverdrive
+	if params.prices != nil {
+		maxOverdrive = 0
+	}
+
+	// we always allow trying at least 'MinShards' hosts
+	maxHosts := mgr.maxHostsPerSlab
+	if maxHosts > 0 && maxHosts < uint64(slice.MinShards) {
+		maxHosts = uint64(slice.MinShards)
+	}
+
 	// create slab download
 	return &slabDownload{
 		mgr: mgr,
 
-		minShards: int(slice.MinShards),
-		offset:    offset,
-		length:    length,
+		minShards:    int(slice.MinShards),
+		offset:       offset,
+		length:       length,
+		maxHosts:     maxHosts,
+		maxOverdrive: maxOverdrive,
+		prices:       params.prices,
 
 		created: time.Now(),
 
@@ -513,9 +603,9 @@ func (mgr *Manager) newSlabDownload(slice object.SlabSlice) *slabDownload {
 	}
 }
 
-func (mgr *Manager) downloadSlab(ctx context.Context, slice object.SlabSlice) ([][]byte, error) {
+func (mgr *Manager) downloadSlab(ctx context.Context, slice object.SlabSlice, params parameters) ([][]byte, error) {
 	// prepare new download
-	slab := mgr.newSlabDownload(slice)
+	slab := mgr.newSlabDownload(slice, params)
 
 	// execute download
 	return slab.download(ctx)
@@ -557,7 +647,7 @@ func (s *slabDownload) overdrive(ctx context.Context, resps *downloader.SectorRe
 
 		// overdrive is maxed out
 		remaining := s.minShards - s.numCompleted
-		if s.numInflight >= s.mgr.maxOverdrive+uint64(remaining) {
+		if s.numInflight >= s.maxOverdrive+uint64(remaining) {
 			return false
 		}
 
@@ -590,6 +680,12 @@ func (s *slabDownload) nextRequest(ctx context.Context, resps *downloader.Sector
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// check whether we've tried as many hosts as we're allowed to
+	if s.maxHosts > 0 && s.numAttempted >= s.maxHosts {
+		s.budgetErr = fmt.Errorf("%w: tried %d hosts", ErrMaxHostsPerSlabReached, s.numAttempted)
+		return nil
+	}
+
 	// update pending sectors
 	var pending []*sectorInfo
 	for _, sector := range s.sectors {
@@ -600,34 +696,46 @@ func (s *slabDownload) nextRequest(ctx context.Context, resps *downloader.Sector
 
 	// sort pending sectors
 	sort.Slice(pending, func(i, j int) bool {
-		// get fastest download for each sector
-		iFastest := s.mgr.fastest(pending[i].hks)
-		jFastest := s.mgr.fastest(pending[j].hks)
+		// get best downloader for each sector
+		iBest := s.best(pending[i].hks)
+		jBest := s.best(pending[j].hks)
 
 		// check edge case where a sector doesn't have a downloader
-		if iFastest != nil && jFastest == nil {
+		if iBest != nil && jBest == nil {
 			return true // prefer i
-		} else if iFastest == nil && jFastest != nil {
+		} else if iBest == nil && jBest != nil {
 			return false // prefer j
-		} else if iFastest == nil && jFastest == nil {
+		} else if iBest == nil && jBest == nil {
 			return false // doesn't matter
 		}
 		// both have a downloader, sort by number of selections next
 		if pending[i].selected != pending[j].selected {
 			return pending[i].selected < pending[j].selected
 		}
-		// both have been selected the same number of times, pick the faster one
-		return iFastest.Estimate() < jFastest.Estimate()
+		// both have been selected the same number of times, pick the cheaper
+		// one if we optimize for cost
+		if s.prices != nil {
+			iPrice, iKnown := s.prices[iBest.PublicKey()]
+			jPrice, jKnown := s.prices[jBest.PublicKey()]
+			if iKnown != jKnown {
+				return iKnown
+			} else if !iPrice.Equals(jPrice) {
+				return iPrice.Cmp(jPrice) < 0
+			}
+		}
+		// pick the faster one
+		return iBest.Estimate() < jBest.Estimate()
 	})
 
 	for _, next := range pending {
-		fastest := s.mgr.fastest(next.hks)
+		fastest := s.best(next.hks)
 		if fastest == nil {
 			// no host available for this sector, clean 'hks'
 			next.hks = nil
 			continue
 		}
 		next.selectHost(fastest.PublicKey())
+		s.numAttempted++
 		return &downloader.SectorDownloadReq{
 			Ctx: ctx,
 
@@ -744,7 +852,11 @@ func (s *slabDownload) finish() ([][]byte, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.numCompleted < s.minShards {
-		return nil, fmt.Errorf("failed to download slab: completed=%d inflight=%d launched=%d downloaders=%d errors=%d %v", s.numCompleted, s.numInflight, s.numLaunched, s.mgr.numDownloaders(), len(s.errs), s.errs)
+		err := fmt.Errorf("%w: recovered %d/%d shards", ErrInsufficientShardsRecovered, s.numCompleted, s.minShards)
+		if s.budgetErr != nil {
+			err = fmt.Errorf("%w, %w", err, s.budgetErr)
+		}
+		return nil, fmt.Errorf("failed to download slab: %w, inflight=%d launched=%d downloaders=%d errors=%d %v", err, s.numInflight, s.numLaunched, s.mgr.numDownloaders(), len(s.errs), s.errs)
 	}
 
 	data := make([][]byte, len(s.sectors))
@@ -800,12 +912,42 @@ func (s *slabDownload) receive(resp downloader.SectorDownloadResp) (finished boo
 	return s.numCompleted >= s.minShards
 }
 
+// best returns the downloader to use for a sector stored on the given hosts,
+// depending on whether the download is optimized for speed or cost.
+func (s *slabDownload) best(hosts []types.PublicKey) *downloader.Downloader {
+	if s.prices != nil {
+		return s.mgr.cheapest(hosts, s.prices)
+	}
+	return s.mgr.fastest(hosts)
+}
+
+func (mgr *Manager) cheapest(hosts []types.PublicKey, prices map[types.PublicKey]types.Currency) (cheapest *downloader.Downloader) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	var lowest types.Currency
+	var lowestKnown bool
+	for _, h := range hosts {
+		d, ok := mgr.downloaders[h]
+		if !ok || !mgr.hb.Available(h) {
+			continue
+		}
+		price, known := prices[h]
+		if cheapest == nil ||
+			(known && !lowestKnown) ||
+			(known && lowestKnown && price.Cmp(lowest) < 0) ||
+			(known == lowestKnown && price.Equals(lowest) && d.Estimate() < cheapest.Estimate()) {
+			cheapest, lowest, lowestKnown = d, price, known
+		}
+	}
+	return
+}
+
 func (mgr *Manager) fastest(hosts []types.PublicKey) (fastest *downloader.Downloader) {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
 	lowest := math.MaxFloat64
 	for _, h := range hosts {
-		if d, ok := mgr.downloaders[h]; !ok {
+		if d, ok := mgr.downloaders[h]; !ok || !mgr.hb.Available(h) {
 			continue
 		} else if estimate := d.Estimate(); estimate < lowest {
 			lowest = estimate
@@ -857,6 +999,21 @@ func slabsForDownload(slabs []slabSlice, offset, length uint64) []slabSlice {
 	return slabs
 }
 
+// readRepairShards returns the indices of the shards of the slab that aren't
+// available on any of the given hosts. Only slabs whose sectors were downloaded
+// in full can be repaired, for all other slabs it returns nil.
+func readRepairShards(ss object.SlabSlice, hosts map[types.PublicKey]struct{}) (unhealthy []int) {
+	if offset, length := ss.SectorRegion(); offset != 0 || length != rhpv2.SectorSize {
+		return nil
+	}
+	for i, shard := range ss.Shards {
+		if !isSectorAvailable(shard, hosts) {
+			unhealthy = append(unhealthy, i)
+		}
+	}
+	return
+}
+
 func isSectorAvailable(s object.Sector, hosts map[types.PublicKey]struct{}) bool {
 	// if any of the other hosts that store the sector are
 	// available, the sector is also considered available
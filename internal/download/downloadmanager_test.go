@@ -0,0 +1,129 @@
+package download
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/memory"
+	"go.sia.tech/renterd/internal/test/mocks"
+	"go.sia.tech/renterd/object"
+	"go.uber.org/zap"
+)
+
+func TestCheapest(t *testing.T) {
+	mgr := NewManager(context.Background(), nil, nil, mocks.NewHostManager(), nil, nil, object.DefaultErasureBackend, 0, 0, 0, zap.NewNop())
+
+	// add downloaders for 4 hosts
+	hks := []types.PublicKey{{1}, {2}, {3}, {4}}
+	var hosts []api.HostInfo
+	for _, hk := range hks {
+		hosts = append(hosts, api.HostInfo{PublicKey: hk})
+	}
+	mgr.refreshDownloaders(hosts)
+	defer mgr.Stop()
+
+	// assert the cheapest host is picked
+	prices := map[types.PublicKey]types.Currency{
+		{1}: types.NewCurrency64(3),
+		{2}: types.NewCurrency64(1),
+		{3}: types.NewCurrency64(2),
+	}
+	if d := mgr.cheapest(hks, prices); d == nil || d.PublicKey() != (types.PublicKey{2}) {
+		t.Fatal("unexpected downloader", d)
+	}
+
+	// assert hosts without a known price are only picked as a last resort
+	if d := mgr.cheapest([]types.PublicKey{{4}, {1}}, prices); d == nil || d.PublicKey() != (types.PublicKey{1}) {
+		t.Fatal("unexpected downloader", d)
+	} else if d := mgr.cheapest([]types.PublicKey{{4}}, prices); d == nil || d.PublicKey() != (types.PublicKey{4}) {
+		t.Fatal("unexpected downloader", d)
+	}
+
+	// assert hosts without a downloader are ignored
+	if d := mgr.cheapest([]types.PublicKey{{5}}, prices); d != nil {
+		t.Fatal("unexpected downloader", d)
+	}
+}
+
+func TestReadRepairShards(t *testing.T) {
+	// create a 2-of-3 slab with one shard on an unavailable host
+	newSector := func(hks ...types.PublicKey) object.Sector {
+		s := object.Sector{Contracts: make(map[types.PublicKey][]types.FileContractID)}
+		for _, hk := range hks {
+			s.Contracts[hk] = []types.FileContractID{{}}
+		}
+		return s
+	}
+	slab := object.Slab{
+		MinShards: 2,
+		Shards: []object.Sector{
+			newSector(types.PublicKey{1}),
+			newSector(types.PublicKey{2}, types.PublicKey{4}),
+			newSector(types.PublicKey{3}),
+		},
+	}
+	available := map[types.PublicKey]struct{}{
+		{1}: {},
+		{4}: {},
+	}
+
+	// assert the unavailable shard is returned
+	ss := object.SlabSlice{Slab: slab, Length: uint32(slab.Length())}
+	if unhealthy := readRepairShards(ss, available); len(unhealthy) != 1 || unhealthy[0] != 2 {
+		t.Fatal("unexpected unhealthy shards", unhealthy)
+	}
+
+	// assert slabs that weren't downloaded in full are ignored
+	ss.Offset = 64
+	if unhealthy := readRepairShards(ss, available); unhealthy != nil {
+		t.Fatal("unexpected unhealthy shards", unhealthy)
+	}
+}
+
+type testMemory struct{ released bool }
+
+func (m *testMemory) Release()             { m.released = true }
+func (m *testMemory) ReleaseSome(_ uint64) {}
+
+type testReadRepairer struct{ accept bool }
+
+func (rr testReadRepairer) ReadRepair(_ object.Slab, _ [][]byte, _ []int, _ memory.Memory) bool {
+	return rr.accept
+}
+
+func TestWriteSlabReadRepairMemory(t *testing.T) {
+	mgr := NewManager(context.Background(), nil, nil, mocks.NewHostManager(), nil, nil, object.DefaultErasureBackend, 0, 0, 0, zap.NewNop())
+
+	// create a 1-of-2 slab with one shard on an unavailable host
+	slab := object.Slab{
+		EncryptionKey: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
+		MinShards:     1,
+		Shards: []object.Sector{
+			{Contracts: map[types.PublicKey][]types.FileContractID{{1}: {{}}}},
+			{Contracts: map[types.PublicKey][]types.FileContractID{{2}: {{}}}},
+		},
+	}
+	s := slabSlice{SlabSlice: object.SlabSlice{Slab: slab, Length: uint32(slab.Length())}}
+	available := map[types.PublicKey]struct{}{{1}: {}}
+
+	// assert the memory is released if the repairer doesn't take the shards
+	mem := &testMemory{}
+	resp := &slabDownloadResponse{mem: mem, shards: make([][]byte, 2)}
+	if err := mgr.writeSlab(io.Discard, s, resp, available, testReadRepairer{accept: false}); err != nil {
+		t.Fatal(err)
+	} else if !mem.released {
+		t.Fatal("expected memory to be released")
+	}
+
+	// assert the memory is held if it does
+	mem = &testMemory{}
+	resp = &slabDownloadResponse{mem: mem, shards: make([][]byte, 2)}
+	if err := mgr.writeSlab(io.Discard, s, resp, available, testReadRepairer{accept: true}); err != nil {
+		t.Fatal(err)
+	} else if mem.released {
+		t.Fatal("expected memory to be held by the repairer")
+	}
+}
@@ -0,0 +1,48 @@
+package download
+
+import (
+	"go.sia.tech/core/types"
+)
+
+type parameters struct {
+	// customerKey is the key the object was encrypted with if it was
+	// uploaded with a customer provided key
+	customerKey *[32]byte
+
+	// prices contains the download bandwidth price of every host, if set the
+	// download prefers cheap hosts over fast ones
+	prices map[types.PublicKey]types.Currency
+
+	// readRepairer is handed the shards of recovered slabs that have
+	// unhealthy shards, if set
+	readRepairer ReadRepairer
+}
+
+type Option func(*parameters)
+
+// WithCustomerKey sets the customer provided key that is required to decrypt
+// objects that were uploaded with a customer provided key.
+func WithCustomerKey(key [32]byte) Option {
+	return func(p *parameters) {
+		p.customerKey = &key
+	}
+}
+
+// WithCheapestHosts optimizes the download for cost rather than speed by
+// preferring hosts with the lowest download bandwidth price and disabling
+// overdrive. Hosts without a known price are considered to be the most
+// expensive ones.
+func WithCheapestHosts(prices map[types.PublicKey]types.Currency) Option {
+	return func(p *parameters) {
+		p.prices = prices
+	}
+}
+
+// WithReadRepair enables read-repair for the download. Slabs that are fully
+// downloaded and have shards that aren't stored on any of the download's hosts
+// are handed to the given repairer after they were recovered.
+func WithReadRepair(rr ReadRepairer) Option {
+	return func(p *parameters) {
+		p.readRepairer = rr
+	}
+}
@@ -0,0 +1,148 @@
+package gouging
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	rhpv3 "go.sia.tech/core/rhp/v3"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/rhp/v4"
+)
+
+const (
+	categoryDownload = "download"
+	categoryGouging  = "gouging"
+	categoryPrune    = "prune"
+	categoryUpload   = "upload"
+)
+
+// check is the outcome of a single gouging check, both the gouging breakdown
+// and the diagnosis of a host are derived from it.
+type check struct {
+	api.HostGougingCheck
+	err error
+}
+
+// DiagnoseV1 performs the same checks as CheckV1 but returns the computed
+// value and threshold of every individual check instead of only the errors.
+func (gc checker) DiagnoseV1(hs *rhpv2.HostSettings, pt *rhpv3.HostPriceTable) []api.HostGougingCheck {
+	if hs == nil && pt == nil {
+		panic("gouging checker needs to be provided with at least host settings or a price table") // developer error
+	}
+	return diagnosis(
+		hostSettingsChecks(gc.settings, hs),
+		pruneChecksRHPv2(gc.settings, hs),
+		priceTableChecks(gc.settings, gc.consensusState, pt),
+		downloadChecksRHPv3(gc.settings, pt),
+		uploadChecksRHPv3(gc.settings, pt),
+	)
+}
+
+// DiagnoseV2 performs the same checks as CheckV2 but returns the computed
+// value and threshold of every individual check instead of only the errors.
+func (gc checker) DiagnoseV2(hs rhp.HostSettings) []api.HostGougingCheck {
+	return diagnosis(checksRHPv4(gc.settings, gc.consensusState, hs))
+}
+
+func diagnosis(checks ...[]check) (diagnosis []api.HostGougingCheck) {
+	for _, cs := range checks {
+		for _, c := range cs {
+			diagnosis = append(diagnosis, c.HostGougingCheck)
+		}
+	}
+	return
+}
+
+func categoryErrs(checks []check, category string) (errs []error) {
+	for _, c := range checks {
+		if c.Category == category && c.err != nil {
+			errs = append(errs, c.err)
+		}
+	}
+	return
+}
+
+func firstErr(checks []check) error {
+	for _, c := range checks {
+		if c.err != nil {
+			return c.err
+		}
+	}
+	return nil
+}
+
+func newCheck(category, name string, value any, threshold string, err error) check {
+	return check{
+		HostGougingCheck: api.HostGougingCheck{
+			Category:  category,
+			Name:      name,
+			Value:     fmt.Sprint(value),
+			Threshold: threshold,
+			Passed:    err == nil,
+		},
+		err: err,
+	}
+}
+
+// wrap prefixes the check's error with the given error if the check failed.
+func (c check) wrap(err error) check {
+	if c.err != nil {
+		c.err = fmt.Errorf("%w: %v", err, c.err)
+	}
+	return c
+}
+
+// durationCheck fails if value is less than min, the error is formatted using
+// value and min.
+func durationCheck(category, name string, value, min time.Duration, format string) check {
+	var err error
+	if value < min {
+		err = fmt.Errorf(format, value, min)
+	}
+	return newCheck(category, name, value, fmt.Sprintf(">= %v", min), err)
+}
+
+// maxCheck fails if value exceeds max, the error is formatted using value and
+// max.
+func maxCheck(category, name string, value, max types.Currency, format string) check {
+	var err error
+	if value.Cmp(max) > 0 {
+		err = fmt.Errorf(format, value, max)
+	}
+	return newCheck(category, name, value, fmt.Sprintf("<= %v", max), err)
+}
+
+// minCheck fails if value is less than min, the error is formatted using value
+// and min.
+func minCheck(category, name string, value, min types.Currency, format string) check {
+	var err error
+	if value.Cmp(min) < 0 {
+		err = fmt.Errorf(format, value, min)
+	}
+	return newCheck(category, name, value, fmt.Sprintf(">= %v", min), err)
+}
+
+func nonZeroCheck(category, name string, value types.Currency, msg string) check {
+	var err error
+	if value.IsZero() {
+		err = errors.New(msg)
+	}
+	return newCheck(category, name, value, "> 0", err)
+}
+
+// optionalMaxCheck is like maxCheck but always passes if no maximum is set.
+func optionalMaxCheck(category, name string, value, max types.Currency, format string) check {
+	if max.IsZero() {
+		return newCheck(category, name, value, "unlimited", nil)
+	}
+	return maxCheck(category, name, value, max, format)
+}
+
+// overflowCheck is a failed check for a price that overflowed while being
+// computed.
+func overflowCheck(category, name string, max types.Currency, price string) check {
+	return newCheck(category, name, "overflow", fmt.Sprintf("<= %v", max), fmt.Errorf("overflow detected when computing %s", price))
+}
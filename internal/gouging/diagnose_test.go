@@ -0,0 +1,89 @@
+package gouging
+
+import (
+	"testing"
+	"time"
+
+	rhpv4 "go.sia.tech/core/rhp/v4"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/rhp/v4"
+	"go.sia.tech/renterd/internal/test"
+)
+
+func TestDiagnoseV2(t *testing.T) {
+	gc := NewChecker(api.GougingSettings{
+		MaxContractPrice:      types.Siacoins(1),
+		MaxDownloadPrice:      types.Siacoins(1),
+		MaxUploadPrice:        types.Siacoins(1),
+		MaxStoragePrice:       types.Siacoins(1),
+		HostBlockHeightLeeway: 1,
+		MinPriceTableValidity: api.DurationMS(time.Minute),
+	}, api.ConsensusState{
+		BlockHeight:   10,
+		Synced:        true,
+		LastBlockTime: api.TimeRFC3339(time.Now()),
+	})
+	settings := rhp.HostSettings{
+		HostSettings: rhpv4.HostSettings{
+			MaxCollateral: types.Siacoins(1),
+			Prices: rhpv4.HostPrices{
+				ContractPrice: types.Siacoins(1),
+				StoragePrice:  types.Siacoins(1),
+				IngressPrice:  types.Siacoins(1),
+				EgressPrice:   types.Siacoins(2),
+				TipHeight:     10,
+			},
+		},
+		Validity: time.Minute,
+	}
+
+	// assert only the egress price check fails and it carries the numbers
+	var failed []api.HostGougingCheck
+	for _, check := range gc.DiagnoseV2(settings) {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	if len(failed) != 1 {
+		t.Fatal("expected one failed check", failed)
+	} else if c := failed[0]; c.Category != categoryDownload || c.Name != "egressPrice" || c.Value != types.Siacoins(2).String() || c.Threshold != "<= "+types.Siacoins(1).String() {
+		t.Fatal("unexpected check", c)
+	}
+
+	// assert the checks agree with the breakdown
+	if gb := gc.CheckV2(settings); gb.DownloadErr == "" || gb.UploadErr != "" || gb.GougingErr != "" || gb.PruneErr != "" {
+		t.Fatal("unexpected breakdown", gb)
+	}
+}
+
+func TestDiagnoseV1(t *testing.T) {
+	hs := test.NewHostSettings()
+	pt := test.NewHostPriceTable()
+	gc := NewChecker(test.GougingSettings, api.ConsensusState{})
+
+	// assert the checks pass for a host that isn't gouging
+	if gb := gc.CheckV1(&hs, &pt); gb.Gouging() {
+		t.Fatal("unexpected gouging", gb)
+	}
+	for _, check := range gc.DiagnoseV1(&hs, &pt) {
+		if !check.Passed {
+			t.Fatal("unexpected failed check", check)
+		}
+	}
+
+	// raise the contract price and assert the price table check fails
+	pt.ContractPrice = test.GougingSettings.MaxContractPrice.Add(types.NewCurrency64(1))
+	if gb := gc.CheckV1(&hs, &pt); !gb.Gouging() {
+		t.Fatal("expected gouging")
+	}
+	var failed []string
+	for _, check := range gc.DiagnoseV1(&hs, &pt) {
+		if !check.Passed {
+			failed = append(failed, check.Name)
+		}
+	}
+	if len(failed) != 1 || failed[0] != "contractPrice" {
+		t.Fatal("unexpected failed checks", failed)
+	}
+}
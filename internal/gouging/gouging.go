@@ -47,6 +47,8 @@ type (
 		CheckSettings(rhpv2.HostSettings) api.HostGougingBreakdown
 		CheckUnusedDefaults(rhpv3.HostPriceTable) error
 		BlocksUntilBlockHeightGouging(hostHeight uint64) int64
+		DiagnoseV1(*rhpv2.HostSettings, *rhpv3.HostPriceTable) []api.HostGougingCheck
+		DiagnoseV2(rhp.HostSettings) []api.HostGougingCheck
 	}
 
 	checker struct {
@@ -96,70 +98,24 @@ func (gc checker) CheckV1(hs *rhpv2.HostSettings, pt *rhpv3.HostPriceTable) api.
 	}
 
 	return api.HostGougingBreakdown{
-		DownloadErr: errsToStr(checkDownloadGougingRHPv3(gc.settings, pt)),
+		DownloadErr: errsToStr(firstErr(downloadChecksRHPv3(gc.settings, pt))),
 		GougingErr: errsToStr(
-			checkPriceGougingPT(gc.settings, gc.consensusState, pt),
-			checkPriceGougingHS(gc.settings, hs),
+			firstErr(priceTableChecks(gc.settings, gc.consensusState, pt)),
+			firstErr(hostSettingsChecks(gc.settings, hs)),
 		),
-		PruneErr:  errsToStr(checkPruneGougingRHPv2(gc.settings, hs)),
-		UploadErr: errsToStr(checkUploadGougingRHPv3(gc.settings, pt)),
+		PruneErr:  errsToStr(firstErr(pruneChecksRHPv2(gc.settings, hs))),
+		UploadErr: errsToStr(firstErr(uploadChecksRHPv3(gc.settings, pt))),
 	}
 }
 
-func (gc checker) CheckV2(hs rhp.HostSettings) (gb api.HostGougingBreakdown) {
-	prices := hs.Prices
-	gs := gc.settings
-
-	// upload gouging
-	var uploadErrs []error
-	if prices.StoragePrice.Cmp(gs.MaxStoragePrice) > 0 {
-		uploadErrs = append(uploadErrs, fmt.Errorf("%v: storage price exceeds max storage price: %v > %v", ErrPriceTableGouging, prices.StoragePrice, gs.MaxStoragePrice))
-	}
-	if prices.IngressPrice.Cmp(gs.MaxUploadPrice) > 0 {
-		uploadErrs = append(uploadErrs, fmt.Errorf("%v: ingress price exceeds max upload price: %v > %v", ErrPriceTableGouging, prices.IngressPrice, gs.MaxUploadPrice))
-	}
-	gb.UploadErr = errsToStr(uploadErrs...)
-	if gougingErr := errsToStr(uploadErrs...); gougingErr != "" {
-		gb.UploadErr = fmt.Sprintf("%v: %s", ErrPriceTableGouging, gougingErr)
-	}
-
-	// download gouging
-	if prices.EgressPrice.Cmp(gs.MaxDownloadPrice) > 0 {
-		gb.DownloadErr = fmt.Sprintf("%v: egress price exceeds max download price: %v > %v", ErrPriceTableGouging, prices.EgressPrice, gs.MaxDownloadPrice)
-	}
-
-	// prune gouging
-	maxFreeSectorCost := types.Siacoins(1).Div64((1 << 40) / rhpv4.SectorSize) // 1 SC / TiB
-	if prices.FreeSectorPrice.Cmp(maxFreeSectorCost) > 0 {
-		gb.PruneErr = fmt.Sprintf("%v: cost to free a sector exceeds max free sector cost: %v  > %v", ErrPriceTableGouging, prices.FreeSectorPrice, maxFreeSectorCost)
-	}
-
-	// general gouging
-	var errs []error
-	if prices.ContractPrice.Cmp(gs.MaxContractPrice) > 0 {
-		errs = append(errs, fmt.Errorf("contract price exceeds max contract price: %v > %v", prices.ContractPrice, gs.MaxContractPrice))
-	}
-	if hs.MaxCollateral.IsZero() {
-		errs = append(errs, errors.New("max collateral is zero"))
-	}
-	if hs.Validity < time.Duration(gs.MinPriceTableValidity) {
-		errs = append(errs, fmt.Errorf("price table validity is less than %v: %v", gs.MinPriceTableValidity, hs.Validity))
-	}
-	if err := checkBlockHeight(gc.consensusState, hs.Prices.TipHeight, uint64(gs.HostBlockHeightLeeway)); err != nil {
-		errs = append(errs, err)
-	}
-	if hs.Validity < time.Duration(gs.MinPriceTableValidity) {
-		errs = append(errs, fmt.Errorf("price table validity is less than %v: %v", gs.MinPriceTableValidity, hs.Validity))
-	}
-	if hs.Validity < time.Duration(gs.MinPriceTableValidity) {
-		errs = append(errs, fmt.Errorf("price table validity is less than %v: %v", gs.MinPriceTableValidity, hs.Validity))
-	}
-	if gougingErr := errsToStr(errs...); gougingErr != "" {
-		gb.GougingErr = fmt.Sprintf("%v: %s", ErrPriceTableGouging, gougingErr)
+func (gc checker) CheckV2(hs rhp.HostSettings) api.HostGougingBreakdown {
+	checks := checksRHPv4(gc.settings, gc.consensusState, hs)
+	return api.HostGougingBreakdown{
+		DownloadErr: errsToStr(categoryErrs(checks, categoryDownload)...),
+		GougingErr:  errsToStr(categoryErrs(checks, categoryGouging)...),
+		PruneErr:    errsToStr(categoryErrs(checks, categoryPrune)...),
+		UploadErr:   errsToStr(categoryErrs(checks, categoryUpload)...),
 	}
-	gb.GougingErr = errsToStr(errs...)
-
-	return
 }
 
 func (gc checker) CheckSettings(hs rhpv2.HostSettings) api.HostGougingBreakdown {
@@ -170,84 +126,44 @@ func (gc checker) CheckUnusedDefaults(pt rhpv3.HostPriceTable) error {
 	return checkUnusedDefaults(pt)
 }
 
-func checkPriceGougingHS(gs api.GougingSettings, hs *rhpv2.HostSettings) error {
+func hostSettingsChecks(gs api.GougingSettings, hs *rhpv2.HostSettings) []check {
 	// check if we have settings
 	if hs == nil {
 		return nil
 	}
-	// check base rpc price
-	if !gs.MaxRPCPrice.IsZero() && hs.BaseRPCPrice.Cmp(gs.MaxRPCPrice) > 0 {
-		return fmt.Errorf("rpc price exceeds max: %v > %v", hs.BaseRPCPrice, gs.MaxRPCPrice)
-	}
-	maxBaseRPCPrice := hs.DownloadBandwidthPrice.Mul64(maxBaseRPCPriceVsBandwidth)
-	if hs.BaseRPCPrice.Cmp(maxBaseRPCPrice) > 0 {
-		return fmt.Errorf("rpc price too high, %v > %v", hs.BaseRPCPrice, maxBaseRPCPrice)
-	}
 
-	// check sector access price
-	if hs.DownloadBandwidthPrice.IsZero() {
-		hs.DownloadBandwidthPrice = types.NewCurrency64(1)
-	}
-	maxSectorAccessPrice := hs.DownloadBandwidthPrice.Mul64(maxSectorAccessPriceVsBandwidth)
-	if hs.SectorAccessPrice.Cmp(maxSectorAccessPrice) > 0 {
-		return fmt.Errorf("sector access price too high, %v > %v", hs.SectorAccessPrice, maxSectorAccessPrice)
-	}
-
-	// check max storage price
-	if !gs.MaxStoragePrice.IsZero() && hs.StoragePrice.Cmp(gs.MaxStoragePrice) > 0 {
-		return fmt.Errorf("storage price exceeds max: %v > %v", hs.StoragePrice, gs.MaxStoragePrice)
+	// check sector access price, avoid a zero threshold if download bandwidth
+	// is free
+	dlBandwidthPrice := hs.DownloadBandwidthPrice
+	if dlBandwidthPrice.IsZero() {
+		dlBandwidthPrice = types.NewCurrency64(1)
 	}
 
-	// check contract price
-	if !gs.MaxContractPrice.IsZero() && hs.ContractPrice.Cmp(gs.MaxContractPrice) > 0 {
-		return fmt.Errorf("contract price exceeds max: %v > %v", hs.ContractPrice, gs.MaxContractPrice)
+	return []check{
+		optionalMaxCheck(categoryGouging, "rpcPrice", hs.BaseRPCPrice, gs.MaxRPCPrice, "rpc price exceeds max: %v > %v"),
+		maxCheck(categoryGouging, "rpcPriceVsBandwidth", hs.BaseRPCPrice, hs.DownloadBandwidthPrice.Mul64(maxBaseRPCPriceVsBandwidth), "rpc price too high, %v > %v"),
+		maxCheck(categoryGouging, "sectorAccessPrice", hs.SectorAccessPrice, dlBandwidthPrice.Mul64(maxSectorAccessPriceVsBandwidth), "sector access price too high, %v > %v"),
+		optionalMaxCheck(categoryGouging, "storagePrice", hs.StoragePrice, gs.MaxStoragePrice, "storage price exceeds max: %v > %v"),
+		optionalMaxCheck(categoryGouging, "contractPrice", hs.ContractPrice, gs.MaxContractPrice, "contract price exceeds max: %v > %v"),
+		minCheck(categoryGouging, "maxEphemeralAccountBalance", hs.MaxEphemeralAccountBalance, gs.MinMaxEphemeralAccountBalance, "'MaxEphemeralAccountBalance' is less than the allowed minimum value, %v < %v"),
+		durationCheck(categoryGouging, "ephemeralAccountExpiry", hs.EphemeralAccountExpiry, time.Duration(gs.MinAccountExpiry), "'EphemeralAccountExpiry' is less than the allowed minimum value, %v < %v"),
 	}
-
-	// check max EA balance
-	if hs.MaxEphemeralAccountBalance.Cmp(gs.MinMaxEphemeralAccountBalance) < 0 {
-		return fmt.Errorf("'MaxEphemeralAccountBalance' is less than the allowed minimum value, %v < %v", hs.MaxEphemeralAccountBalance, gs.MinMaxEphemeralAccountBalance)
-	}
-
-	// check EA expiry
-	if hs.EphemeralAccountExpiry < time.Duration(gs.MinAccountExpiry) {
-		return fmt.Errorf("'EphemeralAccountExpiry' is less than the allowed minimum value, %v < %v", hs.EphemeralAccountExpiry, time.Duration(gs.MinAccountExpiry))
-	}
-
-	return nil
 }
 
 // TODO: if we ever stop assuming that certain prices in the pricetable are
 // always set to 1H we should account for those fields in
 // `hostPeriodCostForScore` as well.
-func checkPriceGougingPT(gs api.GougingSettings, cs api.ConsensusState, pt *rhpv3.HostPriceTable) error {
+func priceTableChecks(gs api.GougingSettings, cs api.ConsensusState, pt *rhpv3.HostPriceTable) []check {
 	// check if we have a price table
 	if pt == nil {
 		return nil
 	}
 
 	// check unused defaults
-	if err := checkUnusedDefaults(*pt); err != nil {
-		return err
-	}
-
-	// check base rpc price
-	if !gs.MaxRPCPrice.IsZero() && gs.MaxRPCPrice.Cmp(pt.InitBaseCost) < 0 {
-		return fmt.Errorf("init base cost exceeds max: %v > %v", pt.InitBaseCost, gs.MaxRPCPrice)
-	}
-
-	// check contract price
-	if !gs.MaxContractPrice.IsZero() && pt.ContractPrice.Cmp(gs.MaxContractPrice) > 0 {
-		return fmt.Errorf("contract price exceeds max: %v > %v", pt.ContractPrice, gs.MaxContractPrice)
-	}
-
-	// check max storage
-	if !gs.MaxStoragePrice.IsZero() && pt.WriteStoreCost.Cmp(gs.MaxStoragePrice) > 0 {
-		return fmt.Errorf("storage price exceeds max: %v > %v", pt.WriteStoreCost, gs.MaxStoragePrice)
-	}
-
-	// check max collateral
-	if pt.MaxCollateral.IsZero() {
-		return errors.New("MaxCollateral of host is 0")
+	var unusedDefaults string
+	err := checkUnusedDefaults(*pt)
+	if err != nil {
+		unusedDefaults = err.Error()
 	}
 
 	// check LatestRevisionCost - expect sane value
@@ -259,55 +175,24 @@ func checkPriceGougingPT(gs api.GougingSettings, cs api.ConsensusState, pt *rhpv
 	if overflow {
 		maxRevisionCost = types.MaxCurrency
 	}
-	if pt.LatestRevisionCost.Cmp(maxRevisionCost) > 0 {
-		return fmt.Errorf("LatestRevisionCost of %v exceeds maximum cost of %v", pt.LatestRevisionCost, maxRevisionCost)
-	}
-
-	// check block height
-	if err := checkBlockHeight(cs, pt.HostBlockHeight, uint64(gs.HostBlockHeightLeeway)); err != nil {
-		return err
-	}
-
-	// check TxnFeeMaxRecommended - expect it to be lower or equal than the max contract price
-	if !gs.MaxContractPrice.IsZero() && pt.TxnFeeMaxRecommended.Mul64(4096).Cmp(gs.MaxContractPrice) > 0 {
-		return fmt.Errorf("TxnFeeMaxRecommended %v exceeds %v", pt.TxnFeeMaxRecommended, gs.MaxContractPrice.Div64(4096))
-	}
-
-	// check TxnFeeMinRecommended - expect it to be lower or equal than the max
-	if pt.TxnFeeMinRecommended.Cmp(pt.TxnFeeMaxRecommended) > 0 {
-		return fmt.Errorf("TxnFeeMinRecommended is greater than TxnFeeMaxRecommended, %v > %v", pt.TxnFeeMinRecommended, pt.TxnFeeMaxRecommended)
-	}
 
-	// check Validity
-	if pt.Validity < time.Duration(gs.MinPriceTableValidity) {
-		return fmt.Errorf("'Validity' is less than the allowed minimum value, %v < %v", pt.Validity, time.Duration(gs.MinPriceTableValidity))
+	return []check{
+		newCheck(categoryGouging, "unusedDefaults", unusedDefaults, "defaults", err),
+		optionalMaxCheck(categoryGouging, "initBaseCost", pt.InitBaseCost, gs.MaxRPCPrice, "init base cost exceeds max: %v > %v"),
+		optionalMaxCheck(categoryGouging, "contractPrice", pt.ContractPrice, gs.MaxContractPrice, "contract price exceeds max: %v > %v"),
+		optionalMaxCheck(categoryGouging, "writeStoreCost", pt.WriteStoreCost, gs.MaxStoragePrice, "storage price exceeds max: %v > %v"),
+		nonZeroCheck(categoryGouging, "maxCollateral", pt.MaxCollateral, "MaxCollateral of host is 0"),
+		maxCheck(categoryGouging, "latestRevisionCost", pt.LatestRevisionCost, maxRevisionCost, "LatestRevisionCost of %v exceeds maximum cost of %v"),
+		blockHeightCheck(cs, pt.HostBlockHeight, uint64(gs.HostBlockHeightLeeway)),
+		// check TxnFeeMaxRecommended - expect it to be lower or equal than the max contract price
+		optionalMaxCheck(categoryGouging, "txnFeeMaxRecommended", pt.TxnFeeMaxRecommended, gs.MaxContractPrice.Div64(4096), "TxnFeeMaxRecommended %v exceeds %v"),
+		// check TxnFeeMinRecommended - expect it to be lower or equal than the max
+		maxCheck(categoryGouging, "txnFeeMinRecommended", pt.TxnFeeMinRecommended, pt.TxnFeeMaxRecommended, "TxnFeeMinRecommended is greater than TxnFeeMaxRecommended, %v > %v"),
+		durationCheck(categoryGouging, "validity", pt.Validity, time.Duration(gs.MinPriceTableValidity), "'Validity' is less than the allowed minimum value, %v < %v"),
 	}
-
-	return nil
 }
 
-func checkBlockHeight(cs api.ConsensusState, hostBH, leeway uint64) error {
-	// check block height - if too much time has passed since the last block
-	// there is a chance we are not up-to-date anymore. So we only check whether
-	// the host's height is at least equal to ours.
-	if !cs.Synced || time.Since(cs.LastBlockTime.Std()) > time.Hour {
-		if hostBH < cs.BlockHeight {
-			return fmt.Errorf("consensus not synced and host block height is lower, %v < %v", hostBH, cs.BlockHeight)
-		}
-	} else {
-		var minHeight uint64
-		if cs.BlockHeight >= leeway {
-			minHeight = cs.BlockHeight - leeway
-		}
-		maxHeight := cs.BlockHeight + leeway
-		if !(minHeight <= hostBH && hostBH <= maxHeight) {
-			return fmt.Errorf("consensus is synced and host block height is not within range, %v-%v %v", minHeight, maxHeight, hostBH)
-		}
-	}
-	return nil
-}
-
-func checkPruneGougingRHPv2(gs api.GougingSettings, hs *rhpv2.HostSettings) error {
+func pruneChecksRHPv2(gs api.GougingSettings, hs *rhpv2.HostSettings) []check {
 	if hs == nil {
 		return nil
 	}
@@ -322,39 +207,78 @@ func checkPruneGougingRHPv2(gs api.GougingSettings, hs *rhpv2.HostSettings) erro
 		hs.UploadBandwidthPrice,
 	)
 	if overflow {
-		return fmt.Errorf("%w: overflow detected when computing sector download price", ErrHostSettingsGouging)
+		return []check{overflowCheck(categoryPrune, "prunePricePerByte", gs.MaxDownloadPrice, "sector download price").wrap(ErrHostSettingsGouging)}
 	}
 	dppb := sectorDownloadPrice.Div64(rhpv2.SectorSize)
-	if !gs.MaxDownloadPrice.IsZero() && dppb.Cmp(gs.MaxDownloadPrice) > 0 {
-		return fmt.Errorf("%w: cost per byte exceeds max dl price: %v > %v", ErrHostSettingsGouging, dppb, gs.MaxDownloadPrice)
-	}
-	return nil
+	return []check{optionalMaxCheck(categoryPrune, "prunePricePerByte", dppb, gs.MaxDownloadPrice, "cost per byte exceeds max dl price: %v > %v").wrap(ErrHostSettingsGouging)}
 }
 
-func checkDownloadGougingRHPv3(gs api.GougingSettings, pt *rhpv3.HostPriceTable) error {
+func downloadChecksRHPv3(gs api.GougingSettings, pt *rhpv3.HostPriceTable) []check {
 	if pt == nil {
 		return nil
 	}
 	dppb, overflow := DownloadPricePerByte(*pt)
 	if overflow {
-		return fmt.Errorf("%w: overflow detected when computing sector download price", ErrPriceTableGouging)
-	} else if !gs.MaxDownloadPrice.IsZero() && dppb.Cmp(gs.MaxDownloadPrice) > 0 {
-		return fmt.Errorf("%w: cost per byte exceeds max dl price: %v > %v", ErrPriceTableGouging, dppb, gs.MaxDownloadPrice)
+		return []check{overflowCheck(categoryDownload, "downloadPricePerByte", gs.MaxDownloadPrice, "sector download price").wrap(ErrPriceTableGouging)}
 	}
-	return nil
+	return []check{optionalMaxCheck(categoryDownload, "downloadPricePerByte", dppb, gs.MaxDownloadPrice, "cost per byte exceeds max dl price: %v > %v").wrap(ErrPriceTableGouging)}
 }
 
-func checkUploadGougingRHPv3(gs api.GougingSettings, pt *rhpv3.HostPriceTable) error {
+func uploadChecksRHPv3(gs api.GougingSettings, pt *rhpv3.HostPriceTable) []check {
 	if pt == nil {
 		return nil
 	}
 	uploadPrice, overflow := UploadPricePerByte(*pt)
 	if overflow {
-		return fmt.Errorf("%w: overflow detected when computing sector price", ErrPriceTableGouging)
-	} else if !gs.MaxUploadPrice.IsZero() && uploadPrice.Cmp(gs.MaxUploadPrice) > 0 {
-		return fmt.Errorf("%w: cost per byte exceeds max ul price: %v > %v", ErrPriceTableGouging, uploadPrice, gs.MaxUploadPrice)
+		return []check{overflowCheck(categoryUpload, "uploadPricePerByte", gs.MaxUploadPrice, "sector price").wrap(ErrPriceTableGouging)}
 	}
-	return nil
+	return []check{optionalMaxCheck(categoryUpload, "uploadPricePerByte", uploadPrice, gs.MaxUploadPrice, "cost per byte exceeds max ul price: %v > %v").wrap(ErrPriceTableGouging)}
+}
+
+func checksRHPv4(gs api.GougingSettings, cs api.ConsensusState, hs rhp.HostSettings) []check {
+	prices := hs.Prices
+	maxFreeSectorCost := types.Siacoins(1).Div64((1 << 40) / rhpv4.SectorSize) // 1 SC / TiB
+	return []check{
+		// upload gouging
+		maxCheck(categoryUpload, "storagePrice", prices.StoragePrice, gs.MaxStoragePrice, "storage price exceeds max storage price: %v > %v").wrap(ErrPriceTableGouging),
+		maxCheck(categoryUpload, "ingressPrice", prices.IngressPrice, gs.MaxUploadPrice, "ingress price exceeds max upload price: %v > %v").wrap(ErrPriceTableGouging),
+
+		// download gouging
+		maxCheck(categoryDownload, "egressPrice", prices.EgressPrice, gs.MaxDownloadPrice, "egress price exceeds max download price: %v > %v").wrap(ErrPriceTableGouging),
+
+		// prune gouging
+		maxCheck(categoryPrune, "freeSectorPrice", prices.FreeSectorPrice, maxFreeSectorCost, "cost to free a sector exceeds max free sector cost: %v > %v").wrap(ErrPriceTableGouging),
+
+		// general gouging
+		maxCheck(categoryGouging, "contractPrice", prices.ContractPrice, gs.MaxContractPrice, "contract price exceeds max contract price: %v > %v"),
+		nonZeroCheck(categoryGouging, "maxCollateral", hs.MaxCollateral, "max collateral is zero"),
+		durationCheck(categoryGouging, "validity", hs.Validity, time.Duration(gs.MinPriceTableValidity), "price table validity is less than %[2]v: %[1]v"),
+		blockHeightCheck(cs, prices.TipHeight, uint64(gs.HostBlockHeightLeeway)),
+	}
+}
+
+func blockHeightCheck(cs api.ConsensusState, hostBH, leeway uint64) check {
+	// check block height - if too much time has passed since the last block
+	// there is a chance we are not up-to-date anymore. So we only check whether
+	// the host's height is at least equal to ours.
+	if !cs.Synced || time.Since(cs.LastBlockTime.Std()) > time.Hour {
+		var err error
+		if hostBH < cs.BlockHeight {
+			err = fmt.Errorf("consensus not synced and host block height is lower, %v < %v", hostBH, cs.BlockHeight)
+		}
+		return newCheck(categoryGouging, "blockHeight", hostBH, fmt.Sprintf(">= %d", cs.BlockHeight), err)
+	}
+
+	var minHeight uint64
+	if cs.BlockHeight >= leeway {
+		minHeight = cs.BlockHeight - leeway
+	}
+	maxHeight := cs.BlockHeight + leeway
+	var err error
+	if !(minHeight <= hostBH && hostBH <= maxHeight) {
+		err = fmt.Errorf("consensus is synced and host block height is not within range, %v-%v %v", minHeight, maxHeight, hostBH)
+	}
+	return newCheck(categoryGouging, "blockHeight", hostBH, fmt.Sprintf("%d-%d", minHeight, maxHeight), err)
 }
 
 func checkUnusedDefaults(pt rhpv3.HostPriceTable) error {
@@ -3,6 +3,8 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"go.uber.org/zap"
@@ -146,3 +148,32 @@ func (lt *loggedTxn) QueryRow(ctx context.Context, query string, args ...any) *L
 	}
 	return &LoggedRow{row, lt.log.Named("row"), lt.longQueryDuration}
 }
+
+// Savepoint executes fn within a named savepoint. If fn returns an error, all
+// changes made since the savepoint was created are rolled back while the
+// surrounding transaction remains usable. If the backend doesn't support
+// savepoints, fn is executed as is and an error is wrapped with
+// ErrSavepointsUnsupported.
+func (lt *loggedTxn) Savepoint(ctx context.Context, name string, fn func() error) error {
+	if _, err := lt.Exec(ctx, "SAVEPOINT "+name); err != nil {
+		lt.log.Debug("savepoints unsupported, falling back to full rollback", zap.String("savepoint", name), zap.Error(err))
+		if err := fn(); err != nil {
+			return fmt.Errorf("%w: %w", ErrSavepointsUnsupported, err)
+		}
+		return nil
+	}
+
+	if err := fn(); err != nil {
+		if _, rbErr := lt.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to roll back to savepoint '%s': %w", name, rbErr))
+		} else if _, relErr := lt.Exec(ctx, "RELEASE SAVEPOINT "+name); relErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to release savepoint '%s': %w", name, relErr))
+		}
+		return err
+	}
+
+	if _, err := lt.Exec(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to release savepoint '%s': %w", name, err)
+	}
+	return nil
+}
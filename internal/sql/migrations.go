@@ -549,6 +549,108 @@ var (
 					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00035_fix_ns_ms", log)
 				},
 			},
+			{
+				ID: "00036_object_checksum",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00036_object_checksum", log)
+				},
+			},
+			{
+				ID: "00037_buffered_slab_compression",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00037_buffered_slab_compression", log)
+				},
+			},
+			{
+				ID: "00038_host_announcement_age",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00038_host_announcement_age", log)
+				},
+			},
+			{
+				ID: "00039_host_recent_failure",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00039_host_recent_failure", log)
+				},
+			},
+			{
+				ID: "00040_object_pinned",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00040_object_pinned", log)
+				},
+			},
+			{
+				ID: "00041_bucket_lifecycle_rules",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00041_bucket_lifecycle_rules", log)
+				},
+			},
+			{
+				ID: "00042_object_idempotency_keys",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00042_object_idempotency_keys", log)
+				},
+			},
+			{
+				ID: "00043_object_content_disposition",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00043_object_content_disposition", log)
+				},
+			},
+			{
+				ID: "00044_object_events",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00044_object_events", log)
+				},
+			},
+			{
+				ID: "00045_contract_offboardings",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00045_contract_offboardings", log)
+				},
+			},
+			{
+				ID: "00046_case_insensitive_buckets",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00046_case_insensitive_buckets", log)
+				},
+			},
+			{
+				ID: "00047_object_checksum_backfill",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00047_object_checksum_backfill", log)
+				},
+			},
+			{
+				ID: "00048_multipart_part_progress",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00048_multipart_part_progress", log)
+				},
+			},
+			{
+				ID: "00049_object_pinned_hosts",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00049_object_pinned_hosts", log)
+				},
+			},
+			{
+				ID: "00050_host_sector_verifications",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00050_host_sector_verifications", log)
+				},
+			},
+			{
+				ID: "00051_idx_objects_no_slabs",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00051_idx_objects_no_slabs", log)
+				},
+			},
+			{
+				ID: "00052_bucket_size",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00052_bucket_size", log)
+				},
+			},
 		}
 	}
 	MetricsMigrations = func(ctx context.Context, migrationsFs embed.FS, log *zap.SugaredLogger) []Migration {
@@ -634,6 +736,36 @@ func PerformMigrations(ctx context.Context, m Migrator, fs embed.FS, identifier
 	return nil
 }
 
+// PendingMigrations returns the number of the given migrations that weren't
+// applied to the database yet.
+func PendingMigrations(ctx context.Context, db *DB, migrations []Migration) (int, error) {
+	rows, err := db.Query(ctx, "SELECT id FROM migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]struct{})
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("failed to scan migration id: %w", err)
+		}
+		applied[id] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to fetch applied migrations: %w", err)
+	}
+
+	var pending int
+	for _, migration := range migrations {
+		if _, ok := applied[migration.ID]; !ok {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
 func execSQLFile(ctx context.Context, tx Tx, fs embed.FS, folder, filename string) error {
 	path := fmt.Sprintf("migrations/%s/%s.sql", folder, filename)
 
@@ -9,6 +9,7 @@ import (
 	"math"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -28,6 +29,11 @@ const (
 var (
 	ErrRunV072               = errors.New("can't upgrade to >=v1.0.0 from your current version - please upgrade to v0.7.2 first (https://github.com/SiaFoundation/renterd/releases/tag/v0.7.2)")
 	ErrMySQLNoSuperPrivilege = errors.New("You do not have the SUPER privilege and binary logging is enabled")
+
+	// ErrSavepointsUnsupported is returned by Savepoint alongside the error
+	// returned by fn if the backend doesn't support savepoints. In that case
+	// the transaction can't be continued and has to be rolled back.
+	ErrSavepointsUnsupported = errors.New("savepoints are not supported")
 )
 
 type (
@@ -38,6 +44,9 @@ type (
 		log               *zap.Logger
 		longQueryDuration time.Duration
 		longTxDuration    time.Duration
+
+		retriesMu sync.Mutex
+		retries   map[string]uint64
 	}
 
 	// A txn is an interface for executing queries within a transaction.
@@ -59,6 +68,12 @@ type (
 		// Scan will return ErrNoRows. Otherwise, the *Row's Scan scans the
 		// first selected row and discards the rest.
 		QueryRow(ctx context.Context, query string, args ...any) *LoggedRow
+		// Savepoint executes fn within a named savepoint. If fn returns an
+		// error, all changes made since the savepoint was created are rolled
+		// back while the surrounding transaction remains usable. If the backend
+		// doesn't support savepoints, fn is executed as is and an error is
+		// wrapped with ErrSavepointsUnsupported.
+		Savepoint(ctx context.Context, name string, fn func() error) error
 	}
 )
 
@@ -72,9 +87,31 @@ func NewDB(db *sql.DB, log *zap.Logger, dbLockedMsgs []string, longQueryDuration
 		log:               log,
 		longQueryDuration: longQueryDuration,
 		longTxDuration:    longTxDuration,
+		retries:           make(map[string]uint64),
 	}, nil
 }
 
+type operationKey struct{}
+
+// WithOperation annotates the context with the name of the operation that is
+// performed by a transaction. Retries due to contention are tracked per
+// operation.
+func WithOperation(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, operationKey{}, op)
+}
+
+// TransactionRetries returns the number of times transactions had to be
+// retried due to the database being locked, grouped by operation.
+func (s *DB) TransactionRetries() map[string]uint64 {
+	s.retriesMu.Lock()
+	defer s.retriesMu.Unlock()
+	retries := make(map[string]uint64, len(s.retries))
+	for op, n := range s.retries {
+		retries[op] = n
+	}
+	return retries
+}
+
 func (s *DB) DB() *sql.DB {
 	return s.db
 }
@@ -169,6 +206,7 @@ LOOP:
 		if !locked {
 			return err
 		}
+		s.trackRetry(ctx)
 		// exponential backoff
 		sleep := time.Duration(math.Pow(factor, float64(attempt))) * time.Millisecond
 		if sleep > maxBackoff {
@@ -195,6 +233,17 @@ func (s *DB) Close() error {
 	return s.db.Close()
 }
 
+// Ping runs a trivial query against the database and returns the round-trip
+// latency.
+func (s *DB) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	var one int
+	if err := s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
 // transaction is a helper function to execute a function within a transaction.
 // If fn returns an error, the transaction is rolled back. Otherwise, the
 // transaction is committed.
@@ -229,6 +278,18 @@ func (s *DB) transaction(ctx context.Context, fn func(tx Tx) error) error {
 	return nil
 }
 
+// trackRetry increments the retry counter of the operation the context was
+// annotated with.
+func (s *DB) trackRetry(ctx context.Context) {
+	op, ok := ctx.Value(operationKey{}).(string)
+	if !ok || op == "" {
+		return
+	}
+	s.retriesMu.Lock()
+	s.retries[op]++
+	s.retriesMu.Unlock()
+}
+
 // jitterSleep sleeps for a random duration between t and t*1.5.
 func jitterAfter(t time.Duration) <-chan time.Time {
 	return time.After(t + time.Duration(rand.Int63n(int64(t/2))))
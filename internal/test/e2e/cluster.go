@@ -546,13 +546,35 @@ func newTestAutopilot(masterKey utils.MasterKey, cfg config.Autopilot, bus *bus.
 	l = l.Named("autopilot")
 
 	ctx, cancel := context.WithCancelCause(context.Background())
-	m, err := migrator.New(ctx, masterKey, a, bus, bus, cfg.MigratorHealthCutoff, cfg.MigratorNumThreads, cfg.MigratorDownloadMaxOverdrive, cfg.MigratorUploadMaxOverdrive, cfg.MigratorDownloadOverdriveTimeout, cfg.MigratorUploadOverdriveTimeout, cfg.MigratorAccountsRefillInterval, l)
+	m, err := migrator.New(ctx, masterKey, a, bus, bus, migrator.Config{
+		HealthCutoff: cfg.MigratorHealthCutoff,
+		NumThreads:   cfg.MigratorNumThreads,
+
+		DownloadMaxOverdrive:     cfg.MigratorDownloadMaxOverdrive,
+		DownloadOverdriveTimeout: cfg.MigratorDownloadOverdriveTimeout,
+		UploadMaxOverdrive:       cfg.MigratorUploadMaxOverdrive,
+		UploadOverdriveTimeout:   cfg.MigratorUploadOverdriveTimeout,
+
+		RepairBudget:         cfg.MigratorRepairBudget,
+		RepairBudgetInterval: cfg.MigratorRepairBudgetInterval,
+
+		AccountsRefillInterval: cfg.MigratorAccountsRefillInterval,
+
+		VerificationInterval:   cfg.MigratorVerificationInterval,
+		VerificationSampleSize: cfg.MigratorVerificationSampleSize,
+
+		ReconciliationInterval:   cfg.MigratorReconciliationInterval,
+		ReconciliationSampleSize: cfg.MigratorReconciliationSampleSize,
+
+		ChecksumBackfillInterval: cfg.MigratorChecksumBackfillInterval,
+		ChecksumBackfillRate:     cfg.MigratorChecksumBackfillRate,
+	}, l)
 	if err != nil {
 		cancel(nil)
 		return nil, err
 	}
 
-	s, err := scanner.New(bus, cfg.ScannerBatchSize, cfg.ScannerNumThreads, cfg.ScannerInterval, l)
+	s, err := scanner.New(bus, cfg.ScannerBatchSize, cfg.ScannerNumThreads, cfg.ScannerInterval, cfg.ScannerMaxBackoff, l)
 	if err != nil {
 		cancel(nil)
 		return nil, err
@@ -1055,16 +1077,22 @@ func testDBCfg() dbConfig {
 
 func testWorkerCfg() config.Worker {
 	return config.Worker{
-		AccountsRefillInterval:   10 * time.Millisecond,
-		CacheExpiry:              100 * time.Millisecond,
-		ID:                       "worker",
-		BusFlushInterval:         testBusFlushInterval,
-		DownloadOverdriveTimeout: 500 * time.Millisecond,
-		UploadOverdriveTimeout:   500 * time.Millisecond,
-		DownloadMaxMemory:        1 << 28, // 256 MiB
-		UploadMaxMemory:          1 << 28, // 256 MiB
-		DownloadMaxOverdrive:     5,       // TODO: added b/c I think this was overlooked but not sure
-		UploadMaxOverdrive:       5,
+		AccountsRefillInterval:          10 * time.Millisecond,
+		CacheExpiry:                     100 * time.Millisecond,
+		ID:                              "worker",
+		BusFlushInterval:                testBusFlushInterval,
+		DownloadOverdriveTimeout:        500 * time.Millisecond,
+		UploadOverdriveTimeout:          500 * time.Millisecond,
+		UploadStatsRecomputeInterval:    3 * time.Second,
+		UploadCandidateFailureThreshold: 2,
+		UploadSectorTimeoutMin:          10 * time.Second,
+		UploadSectorTimeoutMax:          time.Minute,
+		UploadPersistMaxAttempts:        5,
+		UploadETagBufferSize:            1 << 20,
+		DownloadMaxMemory:               1 << 28, // 256 MiB
+		UploadMaxMemory:                 1 << 28, // 256 MiB
+		DownloadMaxOverdrive:            5,       // TODO: added b/c I think this was overlooked but not sure
+		UploadMaxOverdrive:              5,
 	}
 }
 
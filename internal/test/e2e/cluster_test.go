@@ -71,6 +71,12 @@ func TestObjectsWithNoDelimiter(t *testing.T) {
 				t.Fatal("etag should be set for files and empty for dirs")
 			}
 			entries[i].ETag = ""
+
+			// assert checksum
+			if isDir != (entries[i].Checksum == "") {
+				t.Fatal("checksum should be set for files and empty for dirs")
+			}
+			entries[i].Checksum = ""
 		}
 	}
 
@@ -214,6 +220,17 @@ func TestNewTestCluster(t *testing.T) {
 		t.Fatal("contract should be good")
 	}
 
+	// assert the contract has capacity left
+	capacity, err := cluster.Bus.ContractsCapacity(context.Background(), 10)
+	tt.OK(err)
+	if len(capacity.Contracts) != 1 || capacity.Contracts[0].ID != contract.ID {
+		t.Fatal("unexpected contracts", capacity.Contracts)
+	} else if capacity.RemainingBytes == 0 || capacity.RemainingBytes != capacity.Contracts[0].RemainingBytes {
+		t.Fatal("unexpected remaining bytes", capacity.RemainingBytes)
+	} else if capacity.EstimatedFreeBytes == 0 || capacity.EstimatedFreeBytes > capacity.RemainingBytes {
+		t.Fatal("unexpected estimated free bytes", capacity.EstimatedFreeBytes)
+	}
+
 	// Mine blocks until contracts start renewing.
 	cluster.MineToRenewWindow()
 
@@ -364,6 +381,17 @@ func TestNewTestCluster(t *testing.T) {
 		t.Fatal("autopilot should be enabled")
 	}
 
+	// Fetch the scanner stats
+	stats, err := cluster.Autopilot.ScannerStats(context.Background())
+	tt.OK(err)
+	if time.Time(stats.LastStart).IsZero() {
+		t.Fatal("scanner should have completed a scan")
+	} else if stats.Threads == 0 {
+		t.Fatal("scanner threads should be set")
+	} else if len(stats.Backoff) != 0 {
+		t.Fatal("no hosts should be backed off from", stats.Backoff)
+	}
+
 	// Fetch host
 	if _, err := cluster.Bus.Host(context.Background(), cluster.hosts[0].PublicKey()); err != nil {
 		t.Fatal("unexpected error", err)
@@ -404,6 +432,167 @@ func TestObjectsBucket(t *testing.T) {
 	tt.OK(w.DeleteObject(context.Background(), bucket, t.Name()))
 }
 
+// TestBucketRedundancyOverride asserts the redundancy settings of a bucket's
+// policy override the global ones when uploading to the bucket.
+func TestBucketRedundancyOverride(t *testing.T) {
+	// create a test cluster
+	cluster := newTestCluster(t, testClusterOptions{
+		hosts: test.RedundancySettings.TotalShards,
+	})
+	defer cluster.Shutdown()
+
+	b := cluster.Bus
+	w := cluster.Worker
+	tt := cluster.tt
+
+	// assert invalid overrides are rejected
+	invalid := api.BucketPolicy{Redundancy: &api.RedundancySettings{MinShards: 2, TotalShards: 1}}
+	if err := b.CreateBucket(context.Background(), "invalid", api.CreateBucketOptions{Policy: invalid}); !utils.IsErr(err, api.ErrInvalidRedundancySettings) {
+		t.Fatal("expected ErrInvalidRedundancySettings, got", err)
+	} else if err := b.UpdateBucketPolicy(context.Background(), testBucket, invalid); !utils.IsErr(err, api.ErrInvalidRedundancySettings) {
+		t.Fatal("expected ErrInvalidRedundancySettings, got", err)
+	}
+
+	// create a bucket with less redundancy
+	bucket := "low-redundancy"
+	rs := api.RedundancySettings{MinShards: 1, TotalShards: 2}
+	tt.OK(b.CreateBucket(context.Background(), bucket, api.CreateBucketOptions{Policy: api.BucketPolicy{Redundancy: &rs}}))
+
+	// assert the override is surfaced in the bucket's metadata
+	resp, err := b.Bucket(context.Background(), bucket)
+	tt.OK(err)
+	if resp.Policy.Redundancy == nil || *resp.Policy.Redundancy != rs {
+		t.Fatal("unexpected redundancy override", resp.Policy.Redundancy)
+	}
+
+	// helper to assert the redundancy of an uploaded object
+	assertRedundancy := func(bucket, key string, minShards, totalShards int) {
+		t.Helper()
+		o, err := b.Object(context.Background(), bucket, key, api.GetObjectOptions{})
+		tt.OK(err)
+		if len(o.Object.Slabs) != 1 {
+			t.Fatal("expected 1 slab", len(o.Object.Slabs))
+		} else if s := o.Object.Slabs[0]; int(s.MinShards) != minShards || len(s.Shards) != totalShards {
+			t.Fatalf("expected %d-of-%d, got %d-of-%d", minShards, totalShards, s.MinShards, len(s.Shards))
+		}
+	}
+
+	// upload an object to both buckets and assert only the one in the bucket
+	// with the override uses its redundancy
+	data := frand.Bytes(100)
+	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader(data), bucket, "foo", api.UploadObjectOptions{}))
+	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader(data), testBucket, "foo", api.UploadObjectOptions{}))
+	assertRedundancy(bucket, "foo", rs.MinShards, rs.TotalShards)
+	assertRedundancy(testBucket, "foo", test.RedundancySettings.MinShards, test.RedundancySettings.TotalShards)
+
+	// assert upload options take precedence over the bucket's override
+	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader(data), bucket, "bar", api.UploadObjectOptions{MinShards: 1, TotalShards: 3}))
+	assertRedundancy(bucket, "bar", 1, 3)
+
+	// remove the override and assert the object is still downloadable
+	tt.OK(b.UpdateBucketPolicy(context.Background(), bucket, api.BucketPolicy{}))
+	var buf bytes.Buffer
+	tt.OK(w.DownloadObject(context.Background(), &buf, bucket, "foo", api.DownloadObjectOptions{}))
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("data mismatch")
+	}
+}
+
+func TestObjectManifest(t *testing.T) {
+	// create a test cluster
+	cluster := newTestCluster(t, testClusterOptions{
+		hosts: test.RedundancySettings.TotalShards,
+	})
+	defer cluster.Shutdown()
+
+	b := cluster.Bus
+	w := cluster.Worker
+	tt := cluster.tt
+
+	// upload a few objects
+	keys := []string{"/a", "/b", "/c"}
+	for _, key := range keys {
+		tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader([]byte(key)), testBucket, key, api.UploadObjectOptions{}))
+	}
+
+	// helper to fetch the manifest
+	manifest := func(marker string) (entries []api.ObjectManifestEntry) {
+		t.Helper()
+		tt.OK(b.ObjectManifest(context.Background(), testBucket, marker, func(entry api.ObjectManifestEntry) error {
+			entries = append(entries, entry)
+			return nil
+		}))
+		return
+	}
+
+	// assert the manifest contains all objects
+	entries := manifest("")
+	if len(entries) != len(keys) {
+		t.Fatalf("unexpected number of entries, %v != %v", len(entries), len(keys))
+	}
+	for i, entry := range entries {
+		if entry.Key != keys[i] || entry.Size != int64(len(keys[i])) || len(entry.Slabs) != 1 {
+			t.Fatalf("unexpected entry %+v", entry)
+		}
+	}
+
+	// assert the manifest can be resumed
+	if entries := manifest(keys[0]); len(entries) != 2 || entries[0].Key != keys[1] {
+		t.Fatalf("unexpected entries %+v", entries)
+	} else if entries := manifest(keys[2]); len(entries) != 0 {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	// assert unknown buckets are reported
+	if err := b.ObjectManifest(context.Background(), "unknown", "", func(api.ObjectManifestEntry) error { return nil }); !utils.IsErr(err, api.ErrBucketNotFound) {
+		t.Fatal("expected bucket not found error", err)
+	}
+}
+
+func TestObjectsETag(t *testing.T) {
+	// create a test cluster
+	cluster := newTestCluster(t, testClusterOptions{
+		hosts: test.RedundancySettings.TotalShards,
+	})
+	defer cluster.Shutdown()
+
+	b := cluster.Bus
+	w := cluster.Worker
+	tt := cluster.tt
+
+	// upload an object
+	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader([]byte{1}), testBucket, "foo", api.UploadObjectOptions{}))
+
+	// list the objects and assert the ETag is stable
+	opts := api.ListObjectOptions{Bucket: testBucket}
+	resp, err := b.Objects(context.Background(), "", opts)
+	tt.OK(err)
+	if resp.ETag == "" {
+		t.Fatal("expected ETag to be set")
+	} else if resp2, err := b.Objects(context.Background(), "", opts); err != nil {
+		t.Fatal(err)
+	} else if resp2.ETag != resp.ETag {
+		t.Fatal("expected ETag to be stable", resp.ETag, resp2.ETag)
+	}
+
+	// assert the listing isn't returned if it wasn't modified
+	opts.IfNoneMatch = resp.ETag
+	_, err = b.Objects(context.Background(), "", opts)
+	if !errors.Is(err, api.ErrObjectsNotModified) {
+		t.Fatal("expected ErrObjectsNotModified", err)
+	}
+
+	// upload another object and assert the listing is returned
+	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader([]byte{2}), testBucket, "bar", api.UploadObjectOptions{}))
+	resp2, err := b.Objects(context.Background(), "", opts)
+	tt.OK(err)
+	if len(resp2.Objects) != 2 {
+		t.Fatal("expected 2 objects", len(resp2.Objects))
+	} else if resp2.ETag == resp.ETag {
+		t.Fatal("expected ETag to change")
+	}
+}
+
 // TestObjectsWithDelimiterSlash is an integration test that verifies
 // objects are uploaded, download and deleted from and to the paths we
 // would expect. It is similar to the TestObjectEntries unit test, but uses
@@ -438,6 +627,12 @@ func TestObjectsWithDelimiterSlash(t *testing.T) {
 				t.Fatal("etag should be set for files and empty for dirs")
 			}
 			entries[i].ETag = ""
+
+			// assert checksum
+			if isDir != (entries[i].Checksum == "") {
+				t.Fatal("checksum should be set for files and empty for dirs")
+			}
+			entries[i].Checksum = ""
 		}
 	}
 
@@ -614,7 +809,12 @@ func TestObjectsRename(t *testing.T) {
 	}
 
 	// rename
-	if err := b.RenameObjects(context.Background(), testBucket, "/foo/", "/", false); err != nil {
+	if res, err := b.RenameObjects(context.Background(), testBucket, "/foo/", "/", false); err != nil {
+		t.Fatal(err)
+	} else if res.Renamed == 0 || res.Overwritten != 0 {
+		t.Fatalf("unexpected response %+v", res)
+	}
+	if err := b.RenameObject(context.Background(), testBucket, "/bat", "/baz", true); err == nil || !strings.Contains(err.Error(), api.ErrObjectKeyIsDirectory.Error()) {
 		t.Fatal(err)
 	}
 	if err := b.RenameObject(context.Background(), testBucket, "/baz/quuz", "/quuz", false); err != nil {
@@ -640,6 +840,81 @@ func TestObjectsRename(t *testing.T) {
 	}
 }
 
+// TestCopyObjectSharesSlabs verifies that copying an object across buckets
+// shares the slabs of the source, the copy remains downloadable after the
+// source was deleted and its slabs were pruned.
+func TestCopyObjectSharesSlabs(t *testing.T) {
+	// create a test cluster
+	cluster := newTestCluster(t, testClusterOptions{
+		hosts: test.RedundancySettings.TotalShards,
+	})
+	defer cluster.Shutdown()
+
+	b := cluster.Bus
+	w := cluster.Worker
+	tt := cluster.tt
+
+	// upload an object
+	data := frand.Bytes(rhpv2.SectorSize)
+	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader(data), testBucket, "/src", api.UploadObjectOptions{}))
+
+	// copy it to another bucket
+	tt.OK(b.CreateBucket(context.Background(), "dst", api.CreateBucketOptions{}))
+	tt.OKAll(b.CopyObject(context.Background(), testBucket, "dst", "/src", "/copy", api.CopyObjectOptions{}))
+
+	// assert both objects reference the same slabs
+	src, err := b.Object(context.Background(), testBucket, "/src", api.GetObjectOptions{})
+	tt.OK(err)
+	dst, err := b.Object(context.Background(), "dst", "/copy", api.GetObjectOptions{})
+	tt.OK(err)
+	if len(src.Object.Slabs) != len(dst.Object.Slabs) {
+		t.Fatal("unexpected number of slabs", len(src.Object.Slabs), len(dst.Object.Slabs))
+	}
+	for i := range src.Object.Slabs {
+		if src.Object.Slabs[i].EncryptionKey.String() != dst.Object.Slabs[i].EncryptionKey.String() {
+			t.Fatal("expected copy to share the slabs of the source")
+		}
+	}
+
+	// upload an unrelated object, once its slabs are pruned we know pruning
+	// ran after the source was deleted
+	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader(frand.Bytes(rhpv2.SectorSize)), testBucket, "/other", api.UploadObjectOptions{}))
+	other, err := b.Object(context.Background(), testBucket, "/other", api.GetObjectOptions{})
+	tt.OK(err)
+
+	// delete the source and wait until the slabs of the other object are pruned
+	tt.OK(b.DeleteObject(context.Background(), testBucket, "/src", api.DeleteObjectOptions{}))
+	tt.OK(b.DeleteObject(context.Background(), testBucket, "/other", api.DeleteObjectOptions{}))
+	assertPruned := func(slabs []object.SlabSlice) {
+		t.Helper()
+		tt.Retry(100, 100*time.Millisecond, func() error {
+			for _, slab := range slabs {
+				if _, err := b.Slab(context.Background(), slab.EncryptionKey); !utils.IsErr(err, api.ErrSlabNotFound) {
+					return fmt.Errorf("slab %v wasn't pruned, err: %v", slab.EncryptionKey, err)
+				}
+			}
+			return nil
+		})
+	}
+	assertPruned(other.Object.Slabs)
+
+	// assert the shared slabs weren't pruned
+	for _, slab := range dst.Object.Slabs {
+		tt.OKAll(b.Slab(context.Background(), slab.EncryptionKey))
+	}
+
+	// assert the copy can still be downloaded
+	var buf bytes.Buffer
+	tt.OK(w.DownloadObject(context.Background(), &buf, "dst", "/copy", api.DownloadObjectOptions{}))
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("data mismatch")
+	}
+
+	// delete the copy and assert the shared slabs are pruned
+	tt.OK(b.DeleteObject(context.Background(), "dst", "/copy", api.DeleteObjectOptions{}))
+	assertPruned(dst.Object.Slabs)
+}
+
 // TestUploadDownloadEmpty is an integration test that verifies empty objects
 // can be uploaded and download correctly.
 func TestUploadDownloadEmpty(t *testing.T) {
@@ -663,6 +938,18 @@ func TestUploadDownloadEmpty(t *testing.T) {
 	if len(buffer.Bytes()) != 0 {
 		t.Fatal("unexpected")
 	}
+
+	// assert it's listed as an object without slabs that isn't missing data
+	resp, err := cluster.Bus.ObjectsNoSlabs(context.Background(), testBucket, "", 0, false)
+	tt.OK(err)
+	if len(resp.Objects) != 1 || resp.Objects[0].Key != "/empty" || resp.Objects[0].MissingData {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	resp, err = cluster.Bus.ObjectsNoSlabs(context.Background(), testBucket, "", 0, true)
+	tt.OK(err)
+	if len(resp.Objects) != 0 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
 }
 
 // TestUploadDownloadBasic is an integration test that verifies objects can be
@@ -1372,7 +1659,7 @@ func TestUploadDownloadSameHost(t *testing.T) {
 		}
 
 		// delete the object
-		tt.OK(b.DeleteObject(context.Background(), testBucket, fmt.Sprintf("foo_%d", i)))
+		tt.OK(b.DeleteObject(context.Background(), testBucket, fmt.Sprintf("foo_%d", i), api.DeleteObjectOptions{}))
 	}
 
 	// wait until the slabs and sectors were pruned before constructing the
@@ -1652,7 +1939,7 @@ func TestUploadPacking(t *testing.T) {
 	uploadDownload("file4", data4)
 	download("file4", data4, 0, int64(len(data4)))
 	tt.Retry(100, 100*time.Millisecond, func() error {
-		buffers, err := b.SlabBuffers()
+		buffers, err := b.SlabBuffers(context.Background())
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1886,7 +2173,7 @@ func TestSlabBufferStats(t *testing.T) {
 	})
 
 	// check the slab buffers
-	buffers, err := b.SlabBuffers()
+	buffers, err := b.SlabBuffers(context.Background())
 	tt.OK(err)
 	if len(buffers) != 1 {
 		t.Fatal("expected 1 slab buffer, got", len(buffers))
@@ -1939,7 +2226,7 @@ func TestSlabBufferStats(t *testing.T) {
 
 	// check the slab buffers, again a retry loop to avoid NDFs
 	tt.Retry(100, 100*time.Millisecond, func() error {
-		buffers, err = b.SlabBuffers()
+		buffers, err = b.SlabBuffers(context.Background())
 		tt.OK(err)
 		if len(buffers) != 0 {
 			return fmt.Errorf("expected 0 slab buffers, got %d", len(buffers))
@@ -1,6 +1,7 @@
 package e2e
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"testing"
@@ -87,3 +88,118 @@ func TestFormContract(t *testing.T) {
 		return nil
 	})
 }
+
+func TestContractsInSubnet(t *testing.T) {
+	// create cluster
+	cluster := newTestCluster(t, testClusterOptions{
+		hosts: 2,
+	})
+	defer cluster.Shutdown()
+
+	// convenience variables
+	b := cluster.Bus
+	tt := cluster.tt
+
+	// fetch contracts
+	contracts, err := b.Contracts(context.Background(), api.ContractsOpts{})
+	tt.OK(err)
+	if len(contracts) != 2 {
+		t.Fatal("expected 2 contracts", len(contracts))
+	}
+
+	// all hosts run on localhost
+	matches, err := b.ContractsInSubnet(context.Background(), "127.0.0.0/8")
+	tt.OK(err)
+	if len(matches) != len(contracts) {
+		t.Fatalf("expected %v matches, got %v", len(contracts), len(matches))
+	}
+	for _, m := range matches {
+		if m.IP != "127.0.0.1" {
+			t.Fatal("unexpected ip", m.IP)
+		}
+	}
+
+	// no hosts run in another subnet
+	matches, err = b.ContractsInSubnet(context.Background(), "10.0.0.0/8")
+	tt.OK(err)
+	if len(matches) != 0 {
+		t.Fatal("expected no matches", len(matches))
+	}
+
+	// invalid subnets are rejected
+	_, err = b.ContractsInSubnet(context.Background(), "foo")
+	tt.AssertContains(err, "invalid cidr")
+}
+
+func TestContractReconciliation(t *testing.T) {
+	// create a cluster
+	cluster := newTestCluster(t, testClusterOptions{
+		hosts: test.RedundancySettings.TotalShards,
+	})
+	defer cluster.Shutdown()
+
+	// convenience variables
+	w := cluster.Worker
+	b := cluster.Bus
+	tt := cluster.tt
+
+	// upload an object
+	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader([]byte(t.Name())), testBucket, t.Name(), api.UploadObjectOptions{}))
+	obj, err := b.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	tt.OK(err)
+	slab := obj.Object.Slabs[0].Slab
+
+	// map hosts to contracts
+	contracts, err := b.Contracts(context.Background(), api.ContractsOpts{})
+	tt.OK(err)
+	hostContract := make(map[types.PublicKey]types.FileContractID)
+	for _, c := range contracts {
+		hostContract[c.HostKey] = c.ID
+	}
+
+	// reconcile all contracts and assert nothing is missing
+	for _, c := range contracts {
+		res, err := b.ReconcileContract(context.Background(), c.ID, 0)
+		tt.OK(err)
+		if len(res.Missing) != 0 {
+			t.Fatal("expected no missing sectors", res.Missing)
+		}
+	}
+
+	// pretend the first sector is also stored on the host of the second
+	// sector, which the host doesn't have
+	root := slab.Shards[0].Root
+	var otherHK types.PublicKey
+	for hk := range slab.Shards[1].Contracts {
+		otherHK = hk
+	}
+	otherFCID := hostContract[otherHK]
+	tt.OK(b.UpdateSlab(context.Background(), slab.EncryptionKey, []api.UploadedSector{{ContractID: otherFCID, Root: root}}))
+
+	// reconcile the contract and assert the sector was flagged
+	res, err := b.ReconcileContract(context.Background(), otherFCID, 0)
+	tt.OK(err)
+	if len(res.Missing) != 1 || res.Missing[0] != root {
+		t.Fatal("expected the sector to be missing", res.Missing)
+	} else if res.Checked != 2 {
+		t.Fatal("expected 2 sectors to be checked", res.Checked)
+	}
+
+	// assert the sector was removed from the contract
+	roots, err := b.ContractRoots(context.Background(), otherFCID)
+	tt.OK(err)
+	for _, r := range roots {
+		if r == root {
+			t.Fatal("expected the sector to be removed from the contract")
+		}
+	}
+
+	// assert the sector is still linked to the host that stores it
+	for hk := range slab.Shards[0].Contracts {
+		roots, err := b.ContractRoots(context.Background(), hostContract[hk])
+		tt.OK(err)
+		if len(roots) != 1 || roots[0] != root {
+			t.Fatal("expected the sector to still be stored on the original host", roots)
+		}
+	}
+}
@@ -108,7 +108,7 @@ func TestContractPruning(t *testing.T) {
 
 	// create prunable data by adding and immediately removing an object
 	tt.OKAll(w.UploadObject(context.Background(), bytes.NewReader([]byte(t.Name())), testBucket, t.Name(), api.UploadObjectOptions{}))
-	tt.OK(b.DeleteObject(context.Background(), testBucket, t.Name()))
+	tt.OK(b.DeleteObject(context.Background(), testBucket, t.Name(), api.DeleteObjectOptions{}))
 
 	// assert there's data to prune and there's nothing pruning it
 	assertPrunableData(true)
@@ -207,7 +207,7 @@ func TestSectorPruning(t *testing.T) {
 	// delete every other object
 	for i := 0; i < numObjects; i += 2 {
 		filename := fmt.Sprintf("obj_%d", i)
-		tt.OK(b.DeleteObject(context.Background(), testBucket, filename))
+		tt.OK(b.DeleteObject(context.Background(), testBucket, filename, api.DeleteObjectOptions{}))
 	}
 
 	// assert amount of prunable data
@@ -253,7 +253,7 @@ func TestSectorPruning(t *testing.T) {
 	// delete other object
 	for i := 1; i < numObjects; i += 2 {
 		filename := fmt.Sprintf("obj_%d", i)
-		tt.OK(b.DeleteObject(context.Background(), testBucket, filename))
+		tt.OK(b.DeleteObject(context.Background(), testBucket, filename, api.DeleteObjectOptions{}))
 	}
 
 	// assert amount of prunable data
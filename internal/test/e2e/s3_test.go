@@ -36,7 +36,7 @@ func TestS3Basic(t *testing.T) {
 
 	// delete default bucket before testing.
 	tt := cluster.tt
-	if err := cluster.Bus.DeleteBucket(context.Background(), testBucket); err != nil {
+	if err := cluster.Bus.DeleteBucket(context.Background(), testBucket, api.DeleteBucketOptions{}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -543,7 +543,7 @@ func TestS3MultipartUploads(t *testing.T) {
 
 	// delete default bucket for the remainder of the test. This makes sure we
 	// can delete the bucket even though it contains a multipart upload.
-	tt.OK(cluster.Bus.DeleteBucket(context.Background(), testBucket))
+	tt.OK(cluster.Bus.DeleteBucket(context.Background(), testBucket, api.DeleteBucketOptions{}))
 
 	// Add 3 parts out of order to make sure the object is reconstructed
 	// correctly.
@@ -573,6 +573,10 @@ func TestS3MultipartUploads(t *testing.T) {
 		t.Fatal("unexpected part:", part3)
 	}
 
+	// List parts of an unknown upload
+	_, err = cluster.S3.ListObjectParts("multipart", "foo", hex.EncodeToString(frand.Bytes(32)))
+	tt.AssertContains(err, "NoSuchUpload")
+
 	// Complete upload
 	ui, err := cluster.S3.CompleteMultipartUpload("multipart", "foo", uploadID, []completePart{
 		{
@@ -660,7 +664,7 @@ func TestS3MultipartPruneSlabs(t *testing.T) {
 	tt := cluster.tt
 
 	// delete default bucket before testing.
-	tt.OK(cluster.Bus.DeleteBucket(context.Background(), testBucket))
+	tt.OK(cluster.Bus.DeleteBucket(context.Background(), testBucket, api.DeleteBucketOptions{}))
 
 	// Create bucket.
 	tt.OK(cluster.S3.CreateBucket(bucket))
@@ -683,7 +687,7 @@ func TestS3MultipartPruneSlabs(t *testing.T) {
 
 	// Block until the buffer is uploaded.
 	tt.Retry(100, 100*time.Millisecond, func() error {
-		buffers, err := cluster.Bus.SlabBuffers()
+		buffers, err := cluster.Bus.SlabBuffers(context.Background())
 		tt.OK(err)
 		if len(buffers) != 1 {
 			return fmt.Errorf("expected 1 slab buffer, got %d", len(buffers))
@@ -80,6 +80,38 @@ func TestSettings(t *testing.T) {
 		t.Fatalf("expected upload packing to be disabled by default, got %v", us.Packing.Enabled)
 	}
 
+	// assert updating multiple settings at once
+	gs.MaxRPCPrice = types.Siacoins(456)
+	us.Packing.Enabled = false
+	settings, err := b.UpdateSettings(context.Background(), api.UpdateSettingsRequest{
+		Gouging: &gs,
+		Upload:  &us,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if settings.Gouging.MaxRPCPrice.Cmp(types.Siacoins(456)) != 0 {
+		t.Fatal("expected updated max RPC price to be 456 SC")
+	} else if settings.Upload.Packing.Enabled {
+		t.Fatal("expected upload packing to be disabled")
+	} else if settings.Pinned.Currency != "yen" {
+		t.Fatal("expected pinned settings to remain unchanged")
+	}
+
+	// assert invalid settings are rejected and nothing is updated
+	invalid := us
+	invalid.Redundancy.MinShards = 0
+	gs.MaxRPCPrice = types.Siacoins(789)
+	if _, err := b.UpdateSettings(context.Background(), api.UpdateSettingsRequest{
+		Gouging: &gs,
+		Upload:  &invalid,
+	}); err == nil {
+		t.Fatal("expected error")
+	} else if settings, err := b.Settings(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if settings.Gouging.MaxRPCPrice.Cmp(types.Siacoins(456)) != 0 {
+		t.Fatal("expected max RPC price to remain 456 SC")
+	}
+
 	// assert S3 settings
 	ds3 := api.S3Settings{
 		Authentication: api.S3AuthenticationSettings{
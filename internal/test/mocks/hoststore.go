@@ -35,6 +35,18 @@ func (hs *HostStore) Host(ctx context.Context, hostKey types.PublicKey) (api.Hos
 	return h.hi, nil
 }
 
+func (hs *HostStore) Hosts(ctx context.Context, opts api.HostOptions) (hosts []api.Host, _ error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	for _, hk := range opts.KeyIn {
+		if h, ok := hs.hosts[hk]; ok {
+			hosts = append(hosts, h.hi)
+		}
+	}
+	return
+}
+
 func (hs *HostStore) RecordHostScans(ctx context.Context, scans []api.HostScan) error {
 	return nil
 }
@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"errors"
+	"math"
 
 	rhpv3 "go.sia.tech/core/rhp/v3"
 	rhpv4 "go.sia.tech/core/rhp/v4"
@@ -153,7 +154,10 @@ func (mm *MemoryManager) Limit(amt uint64) (memory.MemoryManager, error) {
 	return mm, nil
 }
 
-func (mm *MemoryManager) Status() memory.Status { return memory.Status{} }
+// Status reports unlimited memory since the mock never runs out of it.
+func (mm *MemoryManager) Status() memory.Status {
+	return memory.Status{Available: math.MaxUint64, Total: math.MaxUint64}
+}
 
 func (mm *MemoryManager) AcquireMemory(ctx context.Context, amt uint64) memory.Memory {
 	<-mm.memBlockChan
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,11 +20,16 @@ type (
 	ObjectStore struct {
 		cs *ContractStore // TODO: remove
 
-		mu                    sync.Mutex
-		objects               map[string]map[string]object.Object
-		partials              map[string]*packedSlabMock
-		slabBufferMaxSizeSoft int
-		bufferIDCntr          uint // allows marking packed slabs as uploaded
+		mu                       sync.Mutex
+		objects                  map[string]map[string]object.Object
+		partials                 map[string]*packedSlabMock
+		parts                    map[string][]object.SlabSlice
+		partProgress             map[string]map[int]api.MultipartPartProgressSlice
+		slabBufferMaxSizeSoft    int
+		slabBufferFull           bool
+		addObjectFailures        int  // number of AddObject calls that fail
+		addMultipartPartFailures int  // number of AddMultipartPart calls that fail
+		bufferIDCntr             uint // allows marking packed slabs as uploaded
 	}
 
 	packedSlabMock struct {
@@ -40,6 +46,8 @@ func NewObjectStore(bucket string, cs *ContractStore) *ObjectStore {
 		cs:                    cs,
 		objects:               make(map[string]map[string]object.Object),
 		partials:              make(map[string]*packedSlabMock),
+		parts:                 make(map[string][]object.SlabSlice),
+		partProgress:          make(map[string]map[int]api.MultipartPartProgressSlice),
 		slabBufferMaxSizeSoft: math.MaxInt64,
 	}
 	os.objects[bucket] = make(map[string]object.Object)
@@ -47,9 +55,57 @@ func NewObjectStore(bucket string, cs *ContractStore) *ObjectStore {
 }
 
 func (os *ObjectStore) AddMultipartPart(ctx context.Context, bucket, path, eTag, uploadID string, partNumber int, slices []object.SlabSlice) (err error) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	// check if the call should fail
+	if os.addMultipartPartFailures > 0 {
+		os.addMultipartPartFailures--
+		return errors.New("failed to add multipart part")
+	}
+
+	key := partKey(uploadID, partNumber)
+	os.parts[key] = slices
+	delete(os.partProgress, key)
+	return nil
+}
+
+func (os *ObjectStore) AddMultipartPartProgress(ctx context.Context, bucket, path, uploadID string, partNumber, index int, checksum types.Hash256, slices []object.SlabSlice) error {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	key := partKey(uploadID, partNumber)
+	if _, ok := os.partProgress[key]; !ok {
+		os.partProgress[key] = make(map[int]api.MultipartPartProgressSlice)
+	}
+	for i, slice := range slices {
+		os.partProgress[key][index+i] = api.MultipartPartProgressSlice{
+			Index:    index + i,
+			Checksum: checksum,
+			Slice:    slice,
+		}
+	}
 	return nil
 }
 
+func (os *ObjectStore) MultipartPart(uploadID string, partNumber int) ([]object.SlabSlice, bool) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+	slices, ok := os.parts[partKey(uploadID, partNumber)]
+	return slices, ok
+}
+
+func (os *ObjectStore) MultipartPartProgress(ctx context.Context, bucket, path, uploadID string, partNumber int) (slices []api.MultipartPartProgressSlice, _ error) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	for _, ps := range os.partProgress[partKey(uploadID, partNumber)] {
+		slices = append(slices, ps)
+	}
+	sort.Slice(slices, func(i, j int) bool { return slices[i].Index < slices[j].Index })
+	return slices, nil
+}
+
 func (os *ObjectStore) AddUploadingSectors(ctx context.Context, uID api.UploadID, root []types.Hash256) error {
 	return nil
 }
@@ -83,7 +139,7 @@ func (os *ObjectStore) DeleteHostSector(ctx context.Context, hk types.PublicKey,
 	return nil
 }
 
-func (os *ObjectStore) DeleteObject(ctx context.Context, bucket, key string) error {
+func (os *ObjectStore) DeleteObject(ctx context.Context, bucket, key string, opts api.DeleteObjectOptions) error {
 	return nil
 }
 
@@ -91,6 +147,12 @@ func (os *ObjectStore) AddObject(ctx context.Context, bucket, path string, o obj
 	os.mu.Lock()
 	defer os.mu.Unlock()
 
+	// check if the call should fail
+	if os.addObjectFailures > 0 {
+		os.addObjectFailures--
+		return errors.New("failed to add object")
+	}
+
 	// check if the bucket exists
 	if _, exists := os.objects[bucket]; !exists {
 		return api.ErrBucketNotFound
@@ -104,6 +166,11 @@ func (os *ObjectStore) AddPartialSlab(ctx context.Context, data []byte, minShard
 	os.mu.Lock()
 	defer os.mu.Unlock()
 
+	// check if the buffers are full
+	if os.slabBufferFull {
+		return nil, false, api.ErrSlabBufferFull
+	}
+
 	// check if given data is too big
 	slabSize := int(minShards) * int(rhpv2.SectorSize)
 	if len(data) > slabSize {
@@ -230,6 +297,37 @@ func (os *ObjectStore) UpdateSlab(ctx context.Context, key object.EncryptionKey,
 	return err
 }
 
+func (os *ObjectStore) CompleteSlabBuffers(ctx context.Context) (sbs []api.SlabBuffer, _ error) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+	return os.slabBuffers()
+}
+
+func (os *ObjectStore) SlabBuffers(ctx context.Context) (sbs []api.SlabBuffer, _ error) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+	return os.slabBuffers()
+}
+
+func (os *ObjectStore) slabBuffers() (sbs []api.SlabBuffer, _ error) {
+	for _, ps := range os.partials {
+		var minShards, totalShards uint8
+		if _, err := fmt.Sscanf(ps.parameterKey, "%d-%d", &minShards, &totalShards); err != nil {
+			return nil, err
+		}
+		sbs = append(sbs, api.SlabBuffer{
+			Complete:    true,
+			Filename:    fmt.Sprint(ps.bufferID),
+			Size:        int64(len(ps.data)),
+			MaxSize:     int64(minShards) * int64(rhpv2.SectorSize),
+			MinShards:   minShards,
+			TotalShards: totalShards,
+			Locked:      time.Now().Before(ps.lockedUntil),
+		})
+	}
+	return
+}
+
 func (os *ObjectStore) PackedSlabsForUpload(ctx context.Context, lockingDuration time.Duration, minShards, totalShards uint8, limit int) (pss []api.PackedSlab, _ error) {
 	os.mu.Lock()
 	defer os.mu.Unlock()
@@ -322,6 +420,24 @@ func (os *ObjectStore) SetSlabBufferMaxSizeSoft(n int) {
 	os.slabBufferMaxSizeSoft = n
 }
 
+func (os *ObjectStore) SetSlabBufferFull(full bool) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+	os.slabBufferFull = full
+}
+
+func (os *ObjectStore) SetAddObjectFailures(n int) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+	os.addObjectFailures = n
+}
+
+func (os *ObjectStore) SetAddMultipartPartFailures(n int) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+	os.addMultipartPartFailures = n
+}
+
 func (os *ObjectStore) forEachObject(fn func(bucket, key string, o object.Object)) {
 	for bucket, objects := range os.objects {
 		for path, object := range objects {
@@ -344,3 +460,7 @@ func (os *ObjectStore) hostForContract(ctx context.Context, fcid types.FileContr
 	}
 	return c.HostKey, nil
 }
+
+func partKey(uploadID string, partNumber int) string {
+	return fmt.Sprintf("%s-%d", uploadID, partNumber)
+}
@@ -20,8 +20,8 @@ func (*s3Mock) ListBuckets(context.Context) (buckets []api.Bucket, err error) {
 	return nil, nil
 }
 
-func (*s3Mock) CopyObject(context.Context, string, string, string, string, api.CopyObjectOptions) (om api.ObjectMetadata, err error) {
-	return api.ObjectMetadata{}, nil
+func (*s3Mock) CopyObject(context.Context, string, string, string, string, api.CopyObjectOptions) (resp api.CopyObjectResponse, err error) {
+	return api.CopyObjectResponse{}, nil
 }
 
 func (*s3Mock) AbortMultipartUpload(context.Context, string, string, string) (err error) {
@@ -0,0 +1,171 @@
+package upload
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"sync"
+)
+
+// etagChunkSize is the max size of the chunks the data of an upload is split
+// into before being handed off to the goroutine computing the ETag.
+const etagChunkSize = 1 << 20 // 1 MiB
+
+var errETagHasherClosed = errors.New("etag hasher was closed")
+
+// etagHasher computes the ETag and the checksum of an upload. If created with a
+// buffer, the data is hashed on a separate goroutine so that computing the ETag
+// isn't serial with reading and encrypting the upload. The data is copied into
+// the buffer in order and hashed in order, so the ETag is the same either way.
+// The buffer is allocated as it's needed, so small uploads don't pay for the
+// full buffer size.
+type etagHasher struct {
+	h        hash.Hash
+	checksum hash.Hash
+
+	chunks    chan []byte
+	free      chan []byte
+	chunkSize uint64
+	numChunks uint64 // max number of chunks
+	allocated uint64 // number of chunks allocated so far
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	finish    chan struct{}
+	done      chan struct{}
+}
+
+func newETagHasher(bufferSize uint64) *etagHasher {
+	// NOTE: we use md5 since it's s3 compatible and clients expect it to be md5
+	eh := &etagHasher{h: md5.New(), checksum: sha256.New()}
+	if bufferSize == 0 {
+		return eh
+	}
+
+	chunkSize := uint64(etagChunkSize)
+	if bufferSize < chunkSize {
+		chunkSize = bufferSize
+	}
+	numChunks := bufferSize / chunkSize
+
+	eh.chunks = make(chan []byte, numChunks)
+	eh.free = make(chan []byte, numChunks)
+	eh.chunkSize = chunkSize
+	eh.numChunks = numChunks
+	eh.closed = make(chan struct{})
+	eh.finish = make(chan struct{})
+	eh.done = make(chan struct{})
+	go eh.threadedHash()
+	return eh
+}
+
+// Close stops a concurrent hasher, any ongoing or future writes fail. It's a
+// no-op if the ETag was already computed.
+func (eh *etagHasher) Close() {
+	if eh.closed == nil {
+		return
+	}
+	eh.closeOnce.Do(func() { close(eh.closed) })
+	<-eh.done
+}
+
+// Checksum returns the hex encoded SHA-256 hash of all data written to the
+// hasher. It must not be called concurrently with Write.
+func (eh *etagHasher) Checksum() string {
+	eh.wait()
+	return hex.EncodeToString(eh.checksum.Sum(nil))
+}
+
+// ETag returns the hex encoded hash of all data written to the hasher. It must
+// not be called concurrently with Write.
+func (eh *etagHasher) ETag() string {
+	eh.wait()
+	return hex.EncodeToString(eh.h.Sum(nil))
+}
+
+// wait waits for a concurrent hasher to hash all data written to it.
+func (eh *etagHasher) wait() {
+	if eh.closed != nil {
+		eh.closeOnce.Do(func() { close(eh.finish) })
+		<-eh.done
+	}
+}
+
+// Write implements io.Writer. It must not be called concurrently.
+func (eh *etagHasher) Write(p []byte) (int, error) {
+	if eh.closed == nil {
+		eh.checksum.Write(p)
+		return eh.h.Write(p)
+	}
+
+	var n int
+	for n < len(p) {
+		buf, err := eh.nextChunk(min(uint64(len(p)-n), eh.chunkSize))
+		if err != nil {
+			return n, err
+		}
+		buf = buf[:copy(buf[:cap(buf)], p[n:])]
+
+		select {
+		case eh.chunks <- buf:
+		case <-eh.closed:
+			return n, errETagHasherClosed
+		}
+		n += len(buf)
+	}
+	return n, nil
+}
+
+// nextChunk returns a buffer of at least the given size to copy data into. It
+// reuses a free buffer, allocates a new one as long as the hasher is below its
+// buffer size or waits for one to be freed.
+func (eh *etagHasher) nextChunk(size uint64) (buf []byte, _ error) {
+	select {
+	case buf = <-eh.free:
+	default:
+		if eh.allocated < eh.numChunks {
+			eh.allocated++
+			return make([]byte, size), nil
+		}
+		select {
+		case buf = <-eh.free:
+		case <-eh.closed:
+			return nil, errETagHasherClosed
+		}
+	}
+	if uint64(cap(buf)) < size {
+		buf = make([]byte, size) // grow buffers allocated for small writes
+	}
+	return buf, nil
+}
+
+func (eh *etagHasher) threadedHash() {
+	defer close(eh.done)
+
+	hashChunk := func(buf []byte) {
+		eh.h.Write(buf)
+		eh.checksum.Write(buf)
+		eh.free <- buf[:cap(buf)]
+	}
+
+	for {
+		select {
+		case buf := <-eh.chunks:
+			hashChunk(buf)
+		case <-eh.closed:
+			return
+		case <-eh.finish:
+			// all writes returned, hash the remaining chunks
+			for {
+				select {
+				case buf := <-eh.chunks:
+					hashChunk(buf)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/renterd/object"
+	"lukechampine.com/frand"
+)
+
+func TestETagHasher(t *testing.T) {
+	data := frand.Bytes(3*etagChunkSize + 123)
+	sum := md5.Sum(data)
+	expected := hex.EncodeToString(sum[:])
+	checksum := sha256.Sum256(data)
+	expectedChecksum := hex.EncodeToString(checksum[:])
+
+	for _, bufferSize := range []uint64{0, 1, 1000, etagChunkSize, 2 * etagChunkSize, 10 * etagChunkSize} {
+		t.Run(fmt.Sprint(bufferSize), func(t *testing.T) {
+			eh := newETagHasher(bufferSize)
+			defer eh.Close()
+
+			// write the data in chunks of random size, reusing the same
+			// buffer to assert the hasher doesn't hold on to it
+			buf := make([]byte, 2*etagChunkSize)
+			r := bytes.NewReader(data)
+			for {
+				n, err := r.Read(buf[:frand.Intn(len(buf))+1])
+				if errors.Is(err, io.EOF) {
+					break
+				} else if _, err := eh.Write(buf[:n]); err != nil {
+					t.Fatal(err)
+				}
+				frand.Read(buf)
+			}
+
+			if etag := eh.ETag(); etag != expected {
+				t.Fatalf("unexpected etag %v != %v", etag, expected)
+			} else if checksum := eh.Checksum(); checksum != expectedChecksum {
+				t.Fatalf("unexpected checksum %v != %v", checksum, expectedChecksum)
+			}
+		})
+	}
+
+	// assert the buffer is allocated lazily and only as large as needed
+	eh := newETagHasher(10 * etagChunkSize)
+	small := md5.Sum(data[:123])
+	if _, err := eh.Write(data[:123]); err != nil {
+		t.Fatal(err)
+	} else if eh.allocated != 1 {
+		t.Fatalf("expected 1 allocated chunk, got %v", eh.allocated)
+	} else if etag := eh.ETag(); etag != hex.EncodeToString(small[:]) {
+		t.Fatal("unexpected etag", etag)
+	}
+	select {
+	case buf := <-eh.free:
+		if cap(buf) != 123 {
+			t.Fatalf("expected a 123 byte chunk, got %v", cap(buf))
+		}
+	default:
+		t.Fatal("expected the chunk to be freed")
+	}
+
+	// assert closing the hasher unblocks writes
+	eh = newETagHasher(etagChunkSize)
+	eh.Close()
+	if _, err := eh.Write(data); !errors.Is(err, errETagHasherClosed) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+// BenchmarkETagHasher benchmarks reading and encrypting an upload's data
+// while computing its ETag. Computing the ETag concurrently only pays off if
+// there's a spare core to hash on.
+//
+// Buffer | Speed       | CPU
+// 0 B    | 272.48 MB/s | Intel Xeon (1 core)
+// 8 MiB  | 261.87 MB/s | Intel Xeon (1 core)
+func BenchmarkETagHasher(b *testing.B) {
+	slab := make([]byte, 10*rhpv2.SectorSize)
+	data := frand.Bytes(len(slab))
+	o := object.NewObject(object.GenerateEncryptionKey(object.EncryptionKeyTypeBasic))
+
+	for _, bufferSize := range []uint64{0, 1 << 23} {
+		b.Run(fmt.Sprint(bufferSize), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				eh := newETagHasher(bufferSize)
+				cr, err := o.Encrypt(io.TeeReader(bytes.NewReader(data), eh), object.EncryptionOptions{})
+				if err != nil {
+					b.Fatal(err)
+				} else if _, err := io.ReadFull(cr, slab); err != nil {
+					b.Fatal(err)
+				}
+				_ = eh.ETag()
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package upload
+
+import (
+	"sync"
+	"time"
+)
+
+// The phases of an upload's lifecycle for which the time spent is tracked.
+const (
+	PhaseAcquireMemory = "acquireMemory"
+	PhaseEncode        = "encode"
+	PhaseContractLock  = "contractLock"
+	PhaseSectorUpload  = "sectorUpload"
+	PhasePersist       = "persist"
+)
+
+// latencyBuckets are the upper bounds of the buckets of the latency histograms
+// that are tracked for every phase.
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+type (
+	// PhaseLatency is the time an upload spent in a single phase, phases like
+	// the sector upload are entered more than once per upload.
+	PhaseLatency struct {
+		Count uint64
+		Total time.Duration
+	}
+
+	// LatencyHistogram describes the distribution of the time spent in a
+	// phase across all uploads. The buckets are cumulative, observations that
+	// exceed the last bucket are only reflected in the count.
+	LatencyHistogram struct {
+		Count   uint64
+		Total   time.Duration
+		Buckets []LatencyBucket
+	}
+
+	// LatencyBucket contains the number of observations that took at most
+	// the bucket's upper bound.
+	LatencyBucket struct {
+		UpperBound time.Duration
+		Count      uint64
+	}
+
+	phaseLatencies struct {
+		mu     sync.Mutex
+		phases map[string]PhaseLatency
+	}
+
+	latencyHistograms struct {
+		mu     sync.Mutex
+		phases map[string]*LatencyHistogram
+	}
+)
+
+func newPhaseLatencies() *phaseLatencies {
+	return &phaseLatencies{phases: make(map[string]PhaseLatency)}
+}
+
+func (pl *phaseLatencies) track(phase string, d time.Duration) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	l := pl.phases[phase]
+	l.Count++
+	l.Total += d
+	pl.phases[phase] = l
+}
+
+func (pl *phaseLatencies) snapshot() map[string]PhaseLatency {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	phases := make(map[string]PhaseLatency, len(pl.phases))
+	for phase, l := range pl.phases {
+		phases[phase] = l
+	}
+	return phases
+}
+
+func newLatencyHistograms() *latencyHistograms {
+	return &latencyHistograms{phases: make(map[string]*LatencyHistogram)}
+}
+
+func (lh *latencyHistograms) track(phase string, d time.Duration) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+
+	h, ok := lh.phases[phase]
+	if !ok {
+		h = &LatencyHistogram{Buckets: make([]LatencyBucket, len(latencyBuckets))}
+		for i, bound := range latencyBuckets {
+			h.Buckets[i].UpperBound = bound
+		}
+		lh.phases[phase] = h
+	}
+
+	h.Count++
+	h.Total += d
+	for i := range h.Buckets {
+		if d <= h.Buckets[i].UpperBound {
+			h.Buckets[i].Count++
+		}
+	}
+}
+
+func (lh *latencyHistograms) snapshot() map[string]LatencyHistogram {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	phases := make(map[string]LatencyHistogram, len(lh.phases))
+	for phase, h := range lh.phases {
+		phases[phase] = LatencyHistogram{
+			Count:   h.Count,
+			Total:   h.Total,
+			Buckets: append([]LatencyBucket(nil), h.Buckets...),
+		}
+	}
+	return phases
+}
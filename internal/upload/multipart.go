@@ -0,0 +1,67 @@
+package upload
+
+import (
+	"context"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
+)
+
+// partProgress contains the slices that were persisted by a previous attempt
+// at uploading a part of a multipart upload, keyed by their index within the
+// part.
+type partProgress map[int]api.MultipartPartProgressSlice
+
+func newPartProgress(slices []api.MultipartPartProgressSlice, rs api.RedundancySettings) partProgress {
+	pp := make(partProgress, len(slices))
+	for _, ps := range slices {
+		// slabs with different erasure coding parameters are uploaded again
+		if int(ps.Slice.MinShards) != rs.MinShards {
+			continue
+		}
+		pp[ps.Index] = ps
+	}
+	return pp
+}
+
+// reusableSlices returns the slices starting at the given index that were
+// created from data with the given checksum and length. If there are none,
+// the data has to be uploaded.
+func (pp partProgress) reusableSlices(index int, checksum types.Hash256, length int) []object.SlabSlice {
+	var slices []object.SlabSlice
+	var n int
+	for i := index; ; i++ {
+		ps, ok := pp[i]
+		if !ok || ps.Checksum != checksum {
+			break
+		}
+		slices = append(slices, ps.Slice)
+		n += int(ps.Slice.Length)
+	}
+	if n != length {
+		return nil
+	}
+	return slices
+}
+
+// fetchPartProgress fetches the progress of previous attempts at uploading
+// the part. Failing to do so only means that the part's data is uploaded
+// again, so errors are logged rather than returned.
+func (mgr *Manager) fetchPartProgress(ctx context.Context, u *upload, up Parameters) partProgress {
+	slices, err := mgr.os.MultipartPartProgress(ctx, up.Bucket, up.Key, up.UploadID, up.PartNumber)
+	if err != nil {
+		u.logger.Warnw("failed to fetch part progress", "partNumber", up.PartNumber, "error", err)
+		return nil
+	}
+	return newPartProgress(slices, up.RS)
+}
+
+// persistPartProgress persists slices of the part that is being uploaded, so
+// a retried upload of the part can reuse them. Like fetching the progress,
+// it's best-effort.
+func (mgr *Manager) persistPartProgress(ctx context.Context, u *upload, up Parameters, index int, checksum types.Hash256, slices []object.SlabSlice) {
+	if err := mgr.os.AddMultipartPartProgress(ctx, up.Bucket, up.Key, up.UploadID, up.PartNumber, index, checksum, slices); err != nil {
+		u.logger.Warnw("failed to persist part progress", "partNumber", up.PartNumber, "index", index, "error", err)
+	}
+}
@@ -0,0 +1,245 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/utils"
+	"go.sia.tech/renterd/object"
+	"go.uber.org/zap"
+)
+
+const (
+	// persistMaxAttempts is the number of times we try to persist an
+	// asynchronously uploaded object before giving up
+	persistMaxAttempts = 5
+
+	// persistRetryInterval is the initial interval between attempts to
+	// persist an asynchronously uploaded object, it doubles after every
+	// failed attempt
+	persistRetryInterval = time.Second
+
+	// persistTimeout is the timeout of a single attempt to persist an
+	// asynchronously uploaded object
+	persistTimeout = time.Minute
+
+	// persistWorkers is the number of goroutines that persist asynchronously
+	// uploaded objects
+	persistWorkers = 16
+
+	// persistQueueSize is the number of asynchronously uploaded objects that
+	// can wait to be persisted, once the queue is full uploads block until
+	// there's room
+	persistQueueSize = 1024
+
+	// persistStatusRetention is how long we keep the persistence status of an
+	// upload around after it was persisted or failed to be persisted
+	persistStatusRetention = time.Hour
+
+	// persistStatusPruneInterval is the interval at which statuses that
+	// exceeded their retention are pruned
+	persistStatusPruneInterval = 10 * time.Minute
+)
+
+type persistJob struct {
+	id     api.UploadID
+	bucket string
+	key    string
+	o      object.Object
+	opts   api.AddObjectOptions
+	logger *zap.SugaredLogger
+}
+
+// PersistenceStatus returns the persistence status of the asynchronously
+// persisted upload with given id.
+func (mgr *Manager) PersistenceStatus(id api.UploadID) (api.UploadPersistenceStatus, bool) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	status, ok := mgr.persistence[id]
+	if !ok {
+		return api.UploadPersistenceStatus{}, false
+	}
+	return *status, true
+}
+
+// persistObjectAsync queues the object to be persisted in the background. The
+// write is conditional on the object not being modified after the upload
+// started, that way a delayed attempt can't overwrite a newer object.
+func (mgr *Manager) persistObjectAsync(id api.UploadID, startedAt time.Time, logger *zap.SugaredLogger, bucket, key string, o object.Object, opts api.AddObjectOptions) {
+	mgr.mu.Lock()
+	mgr.persistence[id] = &api.UploadPersistenceStatus{
+		ID:        id,
+		Bucket:    bucket,
+		Key:       key,
+		Status:    api.UploadPersistencePending,
+		UpdatedAt: api.TimeRFC3339(time.Now()),
+	}
+	mgr.mu.Unlock()
+
+	// the upload id makes retrying to add the object idempotent
+	opts.IdempotencyKey = id.String()
+	opts.UnmodifiedSince = startedAt
+
+	// keep track of the object until it's persisted so draining the manager
+	// can wait for it
+	mgr.persisting.Add(1)
+	select {
+	case mgr.persistQueue <- persistJob{id: id, bucket: bucket, key: key, o: o, opts: opts, logger: logger}:
+	case <-mgr.shutdownCtx.Done():
+		mgr.updatePersistenceStatus(id, 0, ErrShuttingDown, true)
+		mgr.finishUpload(id, logger)
+		mgr.persisting.Done()
+	}
+}
+
+// threadedPersistObjects persists queued objects until the manager is shut
+// down.
+func (mgr *Manager) threadedPersistObjects() {
+	for {
+		select {
+		case <-mgr.shutdownCtx.Done():
+			return
+		case job := <-mgr.persistQueue:
+			mgr.persistObject(job)
+		}
+	}
+}
+
+// threadedPrunePersistenceStatuses periodically removes the statuses of
+// uploads that were persisted or failed to be persisted a while ago.
+func (mgr *Manager) threadedPrunePersistenceStatuses() {
+	t := time.NewTicker(persistStatusPruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-mgr.shutdownCtx.Done():
+			return
+		case <-t.C:
+		}
+
+		mgr.mu.Lock()
+		for id, status := range mgr.persistence {
+			if status.Status != api.UploadPersistencePending && time.Since(time.Time(status.UpdatedAt)) > persistStatusRetention {
+				delete(mgr.persistence, id)
+			}
+		}
+		mgr.mu.Unlock()
+	}
+}
+
+func (mgr *Manager) persistObject(job persistJob) {
+	defer mgr.persisting.Done()
+
+	// finish the upload once we're done, until then the sectors are protected
+	// from being pruned
+	defer mgr.finishUpload(job.id, job.logger)
+
+	interval := persistRetryInterval
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(mgr.shutdownCtx, persistTimeout)
+		err := mgr.os.AddObject(ctx, job.bucket, job.key, job.o, job.opts)
+		cancel()
+
+		// neither will the bucket reappear nor the newer object go away by
+		// retrying
+		final := err == nil ||
+			attempt == persistMaxAttempts ||
+			utils.IsErr(err, api.ErrBucketNotFound) ||
+			utils.IsErr(err, api.ErrObjectModified)
+		mgr.updatePersistenceStatus(job.id, attempt, err, final)
+		if err == nil {
+			return
+		} else if final {
+			job.logger.Errorw("failed to persist object", "attempts", attempt, "error", err)
+			return
+		}
+
+		select {
+		case <-mgr.shutdownCtx.Done():
+			mgr.updatePersistenceStatus(job.id, attempt, ErrShuttingDown, true)
+			job.logger.Errorw("failed to persist object before shutting down", "attempts", attempt)
+			return
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+}
+
+func (mgr *Manager) finishUpload(id api.UploadID, logger *zap.SugaredLogger) {
+	ctx, cancel := context.WithTimeout(mgr.shutdownCtx, time.Minute)
+	defer cancel()
+	if err := mgr.os.FinishUpload(ctx, id); err != nil && !errors.Is(err, context.Canceled) {
+		logger.Errorw("failed to mark upload as finished", "error", err)
+	}
+}
+
+// persistWithRetry calls persist until it succeeds or maxAttempts is reached,
+// backing off between attempts. The data was already uploaded to the hosts at
+// this point so retrying to persist the metadata is cheap. If all attempts fail
+// the last error is returned wrapped in ErrPersistFailed.
+func (mgr *Manager) persistWithRetry(ctx context.Context, maxAttempts int, persist func(ctx context.Context, attempt int) error) error {
+	interval := persistRetryInterval
+	for attempt := 1; ; attempt++ {
+		err := persist(ctx, attempt)
+		if err == nil {
+			return nil
+		}
+
+		// neither the bucket nor the multipart upload will reappear by
+		// retrying, neither will a reused idempotency key become valid
+		final := attempt >= maxAttempts ||
+			utils.IsErr(err, api.ErrBucketNotFound) ||
+			utils.IsErr(err, api.ErrMultipartUploadNotFound) ||
+			utils.IsErr(err, api.ErrIdempotencyKeyReused)
+		if final {
+			return fmt.Errorf("%w after %d attempt(s): %w", ErrPersistFailed, attempt, err)
+		}
+		mgr.logger.Debugw("failed to persist upload, retrying", "attempt", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w after %d attempt(s): %w", ErrPersistFailed, attempt, errors.Join(err, context.Cause(ctx)))
+		case <-mgr.shutdownCtx.Done():
+			return fmt.Errorf("%w after %d attempt(s): %w", ErrPersistFailed, attempt, errors.Join(err, ErrShuttingDown))
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+}
+
+// retryOptions returns the options to add an object with on the given attempt.
+// Retries use the upload id as idempotency key, which makes them safe if an
+// earlier retry was persisted but its response got lost. The first attempt
+// doesn't use a key so the bus doesn't have to record one for every upload.
+func retryOptions(opts api.AddObjectOptions, id api.UploadID, attempt int) api.AddObjectOptions {
+	if attempt > 1 {
+		opts.IdempotencyKey = id.String()
+	}
+	return opts
+}
+
+func (mgr *Manager) updatePersistenceStatus(id api.UploadID, attempts int, err error, final bool) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	status, ok := mgr.persistence[id]
+	if !ok {
+		return
+	}
+	status.Attempts = attempts
+	status.UpdatedAt = api.TimeRFC3339(time.Now())
+	if err == nil {
+		status.Status = api.UploadPersistenceSucceeded
+		status.Error = ""
+		return
+	}
+	status.Error = err.Error()
+	if final {
+		status.Status = api.UploadPersistenceFailed
+	}
+}
@@ -0,0 +1,21 @@
+package upload
+
+import "context"
+
+const (
+	keyShardProgress contextKey = "ShardProgress"
+)
+
+type contextKey string
+
+// WithShardProgress returns a context that causes fn to be called with the
+// number of shards uploaded so far every time a shard of a slab is uploaded.
+func WithShardProgress(ctx context.Context, fn func(uploaded int)) context.Context {
+	return context.WithValue(ctx, keyShardProgress, fn)
+}
+
+func reportShardProgress(ctx context.Context, uploaded uint64) {
+	if fn, ok := ctx.Value(keyShardProgress).(func(int)); ok {
+		fn(int(uploaded))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/breaker"
 	"go.sia.tech/renterd/internal/hosts"
 	"go.sia.tech/renterd/internal/locking"
 	rhp3 "go.sia.tech/renterd/internal/rhp/v3"
@@ -18,19 +19,59 @@ import (
 )
 
 const (
-	lockingPriorityUpload     = 10
-	revisionFetchTimeout      = 30 * time.Second
-	sectorUploadTimeout       = 60 * time.Second
-	statsRecomputeMinInterval = 3 * time.Second
+	// DefaultStatsRecomputeInterval is the default minimum interval between
+	// recomputing an uploader's stats. Recomputing more often keeps the upload
+	// estimates fresher, which helps picking the fastest hosts, at the cost of
+	// more CPU time spent on computing percentiles on busy nodes.
+	DefaultStatsRecomputeInterval = 3 * time.Second
+
+	// DefaultSectorUploadTimeoutMin and DefaultSectorUploadTimeoutMax are the
+	// default bounds of the per-host sector upload timeout.
+	DefaultSectorUploadTimeoutMin = 10 * time.Second
+	DefaultSectorUploadTimeoutMax = 60 * time.Second
+
+	// DefaultCandidateFailureThreshold is the default number of consecutive
+	// failures after which an uploader is tried after all healthy ones, a
+	// single transient failure doesn't deprioritize a host.
+	DefaultCandidateFailureThreshold = 2
+
+	lockingPriorityUpload = 10
+	revisionFetchTimeout  = 30 * time.Second
+
+	// sectorUploadTimeoutMultiplier is the factor applied to the expected
+	// sector upload time of a host to get its timeout, it leaves the host
+	// enough room to upload a sector at a fraction of its average speed
+	sectorUploadTimeoutMultiplier = 5
 )
 
 var (
 	errAcquireContractFailed = errors.New("failed to acquire contract lock")
 	ErrStopped               = errors.New("uploader was stopped")
+
+	// ErrIdle is returned for requests to an uploader that isn't running,
+	// e.g. because it was demoted from the set of active uploaders.
+	ErrIdle = errors.New("uploader is idle")
+
+	// ErrPermanentUploadFailure wraps sector upload errors that indicate the
+	// upload can never succeed on the uploader's current contract, e.g.
+	// because the contract ran out of funds. Uploaders that encounter such an
+	// error are stopped until their contract is renewed.
+	ErrPermanentUploadFailure = errors.New("permanent upload failure")
 )
 
+// permanentErrors contains the errors that indicate a sector upload can never
+// succeed on a contract, all other errors are considered transient.
+var permanentErrors = []error{
+	errors.New("insufficient funds to pay host"),           // v1 contract out of funds
+	errors.New("insufficient funds to move missed payout"), // v1 contract out of funds
+	errors.New("insufficient collateral"),                  // v1 host out of collateral
+	errors.New("insufficient renter funds"),                // v2 contract out of funds
+	errors.New("insufficient host collateral"),             // v2 host out of collateral
+}
+
 var (
 	ErrSectorUploadFinished = errors.New("sector upload already finished")
+	ErrSectorUploadTimeout  = errors.New("sector upload timed out")
 )
 
 type (
@@ -54,6 +95,12 @@ type (
 		HK   types.PublicKey
 		Req  *SectorUploadReq
 		Err  error
+
+		// LockDuration is the time it took to acquire the contract lock,
+		// UploadDuration the time it took to upload the sector to the host,
+		// it's zero if the upload failed.
+		LockDuration   time.Duration
+		UploadDuration time.Duration
 	}
 )
 
@@ -72,6 +119,7 @@ type (
 	Uploader struct {
 		cs     ContractStore
 		cl     locking.ContractLocker
+		hb     *breaker.Breakers
 		hm     hosts.Manager
 		logger *zap.SugaredLogger
 
@@ -85,20 +133,49 @@ type (
 		host    api.HostInfo
 		queue   []*SectorUploadReq
 		stopped bool
+		running bool
+		busy    bool          // whether a popped request is being executed
+		halt    chan struct{} // closed when the uploader is made idle
 
 		// stats related field
-		consecutiveFailures uint64
-		lastRecompute       time.Time
+		consecutiveFailures       uint64
+		lastRecompute             time.Time
+		statsRecomputeInterval    time.Duration
+		statsSectorUploadTimeouts uint64
+
+		sectorUploadTimeoutMin time.Duration
+		sectorUploadTimeoutMax time.Duration
 
 		statsSectorUploadEstimateInMS    *utils.DataPoints
 		statsSectorUploadSpeedBytesPerMS *utils.DataPoints
 	}
 )
 
-func New(ctx context.Context, cl locking.ContractLocker, cs ContractStore, hm hosts.Manager, hi api.HostInfo, fcid types.FileContractID, endHeight uint64, l *zap.SugaredLogger) *Uploader {
+type (
+	// History contains the recent sector upload stats of an uploader.
+	History struct {
+		ContractID          types.FileContractID
+		ConsecutiveFailures uint64
+
+		SectorUploadEstimateMS      DataPoints
+		SectorUploadSpeedBytesPerMS DataPoints
+	}
+
+	// DataPoints contains the tracked data points, ordered from oldest to
+	// newest, and their percentiles.
+	DataPoints struct {
+		P50    float64
+		P90    float64
+		P99    float64
+		Values []float64
+	}
+)
+
+func New(ctx context.Context, cl locking.ContractLocker, cs ContractStore, hb *breaker.Breakers, hm hosts.Manager, hi api.HostInfo, fcid types.FileContractID, endHeight uint64, statsRecomputeInterval, sectorUploadTimeoutMin, sectorUploadTimeoutMax time.Duration, l *zap.SugaredLogger) *Uploader {
 	return &Uploader{
 		cl:     cl,
 		cs:     cs,
+		hb:     hb,
 		hm:     hm,
 		logger: l,
 
@@ -107,15 +184,19 @@ func New(ctx context.Context, cl locking.ContractLocker, cs ContractStore, hm ho
 		shutdownCtx:     ctx,
 		signalNewUpload: make(chan struct{}, 1),
 
+		sectorUploadTimeoutMin: sectorUploadTimeoutMin,
+		sectorUploadTimeoutMax: sectorUploadTimeoutMax,
+
 		// stats
 		statsSectorUploadEstimateInMS:    utils.NewDataPoints(10 * time.Minute),
 		statsSectorUploadSpeedBytesPerMS: utils.NewDataPoints(0),
 
 		// covered by mutex
-		expiry: endHeight,
-		fcid:   fcid,
-		host:   hi,
-		queue:  make([]*SectorUploadReq, 0),
+		expiry:                 endHeight,
+		fcid:                   fcid,
+		host:                   hi,
+		queue:                  make([]*SectorUploadReq, 0),
+		statsRecomputeInterval: statsRecomputeInterval,
 	}
 }
 
@@ -123,33 +204,88 @@ func (u *Uploader) AvgUploadSpeedBytesPerMS() float64 {
 	return u.statsSectorUploadSpeedBytesPerMS.Average()
 }
 
+// SectorUploadTimeout returns the timeout for uploading a sector to the
+// uploader's host. It is derived from the host's average upload speed and
+// clamped to the uploader's configured bounds, hosts without a recorded speed
+// get the upper bound.
+func (u *Uploader) SectorUploadTimeout() time.Duration {
+	speedBytesPerMS := u.statsSectorUploadSpeedBytesPerMS.Average()
+	if speedBytesPerMS <= 0 {
+		return u.sectorUploadTimeoutMax
+	}
+
+	expected := time.Duration(float64(rhpv2.SectorSize)/speedBytesPerMS) * time.Millisecond
+	timeout := expected * sectorUploadTimeoutMultiplier
+	if timeout < u.sectorUploadTimeoutMin {
+		return u.sectorUploadTimeoutMin
+	} else if timeout > u.sectorUploadTimeoutMax {
+		return u.sectorUploadTimeoutMax
+	}
+	return timeout
+}
+
+// SectorUploadTimeouts returns the number of sector uploads to the uploader's
+// host that timed out.
+func (u *Uploader) SectorUploadTimeouts() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.statsSectorUploadTimeouts
+}
+
 func (u *Uploader) ContractID() types.FileContractID {
 	u.mu.Lock()
 	defer u.mu.Unlock()
 	return u.fcid
 }
 
+// EndHeight returns the end height of the uploader's contract.
+func (u *Uploader) EndHeight() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.expiry
+}
+
 func (u *Uploader) Expired(bh uint64) bool {
 	u.mu.Lock()
 	defer u.mu.Unlock()
 	return bh >= u.expiry
 }
 
+// ConsecutiveFailures returns the number of sector uploads that failed in a row.
+func (u *Uploader) ConsecutiveFailures() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.consecutiveFailures
+}
+
 func (u *Uploader) Healthy() bool {
 	u.mu.Lock()
 	defer u.mu.Unlock()
 	return u.consecutiveFailures == 0
 }
 
+// RecentlyFailed returns whether the uploader's host recently failed an
+// interaction.
+func (u *Uploader) RecentlyFailed() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.host.RecentlyFailed
+}
+
 func (u *Uploader) PublicKey() types.PublicKey {
 	return u.hk
 }
 
+// Refresh updates the uploader's host and contract, an uploader that was
+// stopped due to a permanent failure is usable again if its contract changed.
 func (u *Uploader) Refresh(hi *api.HostInfo, fcid types.FileContractID, endHeight uint64) {
 	u.mu.Lock()
 	defer u.mu.Unlock()
 
 	// update state
+	if fcid != u.fcid {
+		u.stopped = false
+	}
 	u.expiry = endHeight
 	u.fcid = fcid
 	if hi != nil {
@@ -157,12 +293,66 @@ func (u *Uploader) Refresh(hi *api.HostInfo, fcid types.FileContractID, endHeigh
 	}
 }
 
+// Stopped returns whether the uploader was stopped.
+func (u *Uploader) Stopped() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.stopped
+}
+
+// Start starts processing enqueued requests in the background until the
+// uploader is shut down or made idle. Starting a running uploader is a no-op.
 func (u *Uploader) Start() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.running {
+		return
+	}
+	u.running = true
+	u.halt = make(chan struct{})
+	go u.run(u.halt)
+}
+
+// Idle stops processing requests once the request that is currently executed
+// finished, queued requests and requests that are enqueued until the uploader
+// is started again fail with ErrIdle.
+func (u *Uploader) Idle() {
+	u.mu.Lock()
+	if !u.running {
+		u.mu.Unlock()
+		return
+	}
+	u.running = false
+	close(u.halt)
+	queue := u.queue
+	u.queue = nil
+	u.mu.Unlock()
+
+	go func() {
+		for _, req := range queue {
+			if !req.done() {
+				req.finish(ErrIdle)
+			}
+		}
+	}() // don't block the caller
+}
+
+// Busy returns whether the uploader has requests that are queued or being
+// executed.
+func (u *Uploader) Busy() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.busy || len(u.queue) > 0
+}
+
+func (u *Uploader) run(halt chan struct{}) {
 outer:
 	for {
 		// wait for work
 		select {
 		case <-u.signalNewUpload:
+		case <-halt:
+			return
 		case <-u.shutdownCtx.Done():
 			return
 		}
@@ -170,37 +360,50 @@ outer:
 		for {
 			// check if we are stopped
 			select {
+			case <-halt:
+				return
 			case <-u.shutdownCtx.Done():
 				return
 			default:
 			}
 
 			// pop the next upload req
-			req := u.pop()
+			req := u.next()
 			if req == nil {
 				continue outer
 			}
 
 			// skip if upload is done
 			if req.done() {
+				u.setBusy(false)
 				continue
 			}
 
 			// execute it
 			start := time.Now()
-			duration, err := u.execute(req)
+			lockDuration, duration, err := u.execute(req)
 			elapsed := time.Since(start)
 			if errors.Is(err, rhp3.ErrMaxRevisionReached) {
 				if u.tryRefresh(req.Ctx) {
 					u.Enqueue(req)
+					u.setBusy(false)
 					continue outer
 				}
+				// the contract can't be revised anymore and wasn't renewed
+				err = fmt.Errorf("%w: %w", ErrPermanentUploadFailure, err)
+			} else if isPermanentError(err) {
+				err = fmt.Errorf("%w: %w", ErrPermanentUploadFailure, err)
 			}
 
 			// track stats
 			success, failure, uploadEstimateMS, uploadSpeedBytesPerMS := handleSectorUpload(err, duration, elapsed, req.Overdrive)
 			u.trackSectorUploadStats(uploadEstimateMS, uploadSpeedBytesPerMS)
 			u.trackConsecutiveFailures(success, failure)
+			if success {
+				u.hb.RecordSuccess(u.hk)
+			} else if failure && !utils.IsErr(err, errAcquireContractFailed) {
+				u.hb.RecordFailure(u.hk) // lock contention isn't the host's fault
+			}
 
 			// debug log
 			if uploadEstimateMS > 0 && !success {
@@ -209,20 +412,47 @@ outer:
 				u.logger.Debugw("sector upload failure was ignored", "uploadError", err, "uploadDuration", duration, "totalDuration", elapsed, "overdrive", req.Overdrive, "hk", u.hk)
 			}
 
+			// stop the uploader on permanent failures, this fails all
+			// queued requests and excludes the uploader from future
+			// uploads until its contract is renewed
+			fcid := u.ContractID()
+			if errors.Is(err, ErrPermanentUploadFailure) {
+				u.logger.Infow("stopping uploader due to permanent failure", "uploadError", err, "hk", u.hk, "fcid", fcid)
+				u.Stop(err)
+			}
+
 			// send the response
 			select {
 			case <-req.Ctx.Done():
 			case req.ResponseChan <- SectorUploadResp{
-				FCID: u.fcid,
+				FCID: fcid,
 				HK:   u.hk,
 				Err:  err,
 				Req:  req,
+
+				LockDuration:   lockDuration,
+				UploadDuration: duration,
 			}:
 			}
+			u.setBusy(false)
 		}
 	}
 }
 
+// isPermanentError returns true if the given sector upload error indicates the
+// upload can never succeed on the uploader's current contract.
+func isPermanentError(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, permanentErr := range permanentErrors {
+		if utils.IsErr(err, permanentErr) {
+			return true
+		}
+	}
+	return false
+}
+
 func handleSectorUpload(uploadErr error, uploadDuration, totalDuration time.Duration, overdrive bool) (success bool, failure bool, uploadEstimateMS float64, uploadSpeedBytesPerMS float64) {
 	// no-op cases
 	if utils.IsErr(uploadErr, rhp3.ErrMaxRevisionReached) {
@@ -282,12 +512,22 @@ func (u *Uploader) Stop(err error) {
 }
 
 func (u *Uploader) Enqueue(req *SectorUploadReq) {
+	// check whether the host's circuit breaker allows the request
+	if !u.hb.Allow(u.hk) {
+		go req.finish(breaker.ErrOpen) // don't block the caller
+		return
+	}
+
 	u.mu.Lock()
 	// check for stopped
 	if u.stopped {
 		u.mu.Unlock()
 		go req.finish(ErrStopped) // don't block the caller
 		return
+	} else if !u.running {
+		u.mu.Unlock()
+		go req.finish(ErrIdle) // don't block the caller
+		return
 	}
 
 	// enqueue the request
@@ -315,7 +555,7 @@ func (u *Uploader) Estimate() float64 {
 
 // execute executes the sector upload request, if the upload was successful it
 // returns the time it took to upload the sector to the host
-func (u *Uploader) execute(req *SectorUploadReq) (_ time.Duration, err error) {
+func (u *Uploader) execute(req *SectorUploadReq) (lockDuration, _ time.Duration, err error) {
 	// grab fields
 	u.mu.Lock()
 	host := u.host
@@ -334,9 +574,11 @@ func (u *Uploader) execute(req *SectorUploadReq) (_ time.Duration, err error) {
 	}()
 
 	// acquire contract lock
+	start := time.Now()
 	lock, err := locking.NewContractLock(req.Ctx, fcid, lockingPriorityUpload, u.cl, u.logger)
+	lockDuration = time.Since(start)
 	if err != nil {
-		return 0, fmt.Errorf("%w; %w", errAcquireContractFailed, err)
+		return lockDuration, 0, fmt.Errorf("%w; %w", errAcquireContractFailed, err)
 	}
 	defer func() {
 		ctx, cancel := context.WithTimeout(u.shutdownCtx, 10*time.Second)
@@ -344,18 +586,23 @@ func (u *Uploader) execute(req *SectorUploadReq) (_ time.Duration, err error) {
 		cancel()
 	}()
 
-	// apply sane timeout
-	ctx, cancel := context.WithTimeout(req.Ctx, sectorUploadTimeout)
+	// apply a timeout that matches the host's speed
+	timeout := u.SectorUploadTimeout()
+	ctx, cancel := context.WithTimeout(req.Ctx, timeout)
 	defer cancel()
 
 	// upload the sector
-	start := time.Now()
+	start = time.Now()
 	err = u.hm.Uploader(host, fcid).UploadSector(ctx, req.Root, req.Data)
+	if err != nil && req.Ctx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		u.trackSectorUploadTimeout()
+		err = fmt.Errorf("%w after %v; %w", ErrSectorUploadTimeout, timeout, err)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("failed to upload sector to contract %v; %w", fcid, err)
+		return lockDuration, 0, fmt.Errorf("failed to upload sector to contract %v; %w", fcid, err)
 	}
 
-	return time.Since(start), nil
+	return lockDuration, time.Since(start), nil
 }
 
 func (u *Uploader) pop() *SectorUploadReq {
@@ -371,6 +618,28 @@ func (u *Uploader) pop() *SectorUploadReq {
 	return nil
 }
 
+// next pops the next request off the queue and marks the uploader as busy
+// until setBusy(false) is called.
+func (u *Uploader) next() *SectorUploadReq {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.queue) > 0 {
+		j := u.queue[0]
+		u.queue[0] = nil
+		u.queue = u.queue[1:]
+		u.busy = true
+		return j
+	}
+	return nil
+}
+
+func (u *Uploader) setBusy(busy bool) {
+	u.mu.Lock()
+	u.busy = busy
+	u.mu.Unlock()
+}
+
 func (u *Uploader) signalWork() {
 	select {
 	case u.signalNewUpload <- struct{}{}:
@@ -389,6 +658,12 @@ func (u *Uploader) trackConsecutiveFailures(success, failure bool) {
 	}
 }
 
+func (u *Uploader) trackSectorUploadTimeout() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.statsSectorUploadTimeouts++
+}
+
 func (u *Uploader) trackSectorUploadStats(uploadEstimateMS, uploadSpeedBytesPerMS float64) {
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -401,10 +676,32 @@ func (u *Uploader) trackSectorUploadStats(uploadEstimateMS, uploadSpeedBytesPerM
 	}
 }
 
+// History returns the uploader's recent sector upload stats.
+func (u *Uploader) History() History {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return History{
+		ContractID:                  u.fcid,
+		ConsecutiveFailures:         u.consecutiveFailures,
+		SectorUploadEstimateMS:      newDataPoints(u.statsSectorUploadEstimateInMS),
+		SectorUploadSpeedBytesPerMS: newDataPoints(u.statsSectorUploadSpeedBytesPerMS),
+	}
+}
+
+func newDataPoints(dp *utils.DataPoints) DataPoints {
+	ps := dp.Percentiles(50, 90, 99)
+	return DataPoints{
+		P50:    ps[0],
+		P90:    ps[1],
+		P99:    ps[2],
+		Values: dp.Values(),
+	}
+}
+
 func (u *Uploader) TryRecomputeStats() {
 	u.mu.Lock()
 	defer u.mu.Unlock()
-	if time.Since(u.lastRecompute) < statsRecomputeMinInterval {
+	if time.Since(u.lastRecompute) < u.statsRecomputeInterval {
 		return
 	}
 
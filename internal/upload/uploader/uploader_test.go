@@ -24,7 +24,7 @@ func TestUploaderStopped(t *testing.T) {
 	c := mocks.NewContract(types.PublicKey{1}, types.FileContractID{1})
 	md := c.Metadata()
 
-	ul := New(context.Background(), cl, cs, hm, api.HostInfo{}, md.ID, md.WindowEnd, zap.NewNop().Sugar())
+	ul := New(context.Background(), cl, cs, nil, hm, api.HostInfo{}, md.ID, md.WindowEnd, DefaultStatsRecomputeInterval, DefaultSectorUploadTimeoutMin, DefaultSectorUploadTimeoutMax, zap.NewNop().Sugar())
 	ul.Stop(errors.New("test"))
 
 	req := SectorUploadReq{
@@ -120,7 +120,7 @@ func TestRefreshUploader(t *testing.T) {
 	c := cs.AddContract(hi.PublicKey).Metadata()
 
 	// create uploader
-	ul := New(context.Background(), cl, cs, hm, hi, c.ID, c.WindowEnd, zap.NewNop().Sugar())
+	ul := New(context.Background(), cl, cs, nil, hm, hi, c.ID, c.WindowEnd, DefaultStatsRecomputeInterval, DefaultSectorUploadTimeoutMin, DefaultSectorUploadTimeoutMax, zap.NewNop().Sugar())
 
 	// assert state
 	if ul.expiry != c.WindowEnd {
@@ -158,3 +158,133 @@ func TestRefreshUploader(t *testing.T) {
 		t.Fatal("host info was not updated", ul.host, update)
 	}
 }
+
+func TestTryRecomputeStats(t *testing.T) {
+	ul := New(context.Background(), nil, nil, nil, nil, api.HostInfo{}, types.FileContractID{}, 0, time.Hour, DefaultSectorUploadTimeoutMin, DefaultSectorUploadTimeoutMax, zap.NewNop().Sugar())
+
+	// first recompute should always happen
+	ul.TryRecomputeStats()
+	last := ul.lastRecompute
+	if last.IsZero() {
+		t.Fatal("expected stats to be recomputed")
+	}
+
+	// second one shouldn't since the interval hasn't passed
+	ul.TryRecomputeStats()
+	if ul.lastRecompute != last {
+		t.Fatal("expected stats not to be recomputed")
+	}
+
+	// lower the interval and assert stats are recomputed
+	ul.statsRecomputeInterval = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	ul.TryRecomputeStats()
+	if !ul.lastRecompute.After(last) {
+		t.Fatal("expected stats to be recomputed")
+	}
+}
+
+func TestSectorUploadTimeout(t *testing.T) {
+	minTimeout, maxTimeout := 10*time.Second, time.Minute
+
+	// bytes per ms for a sector upload that takes given duration
+	speed := func(d time.Duration) float64 {
+		return float64(rhpv2.SectorSize) / float64(d.Milliseconds())
+	}
+
+	cases := []struct {
+		speedBytesPerMS float64
+		timeout         time.Duration
+	}{
+		{0, maxTimeout},                       // no data
+		{speed(time.Millisecond), minTimeout}, // fast host
+		{speed(4 * time.Second), 20 * time.Second},
+		{speed(100 * time.Second), maxTimeout}, // slow host
+	}
+	for i, c := range cases {
+		ul := New(context.Background(), nil, nil, nil, nil, api.HostInfo{}, types.FileContractID{}, 0, time.Hour, minTimeout, maxTimeout, zap.NewNop().Sugar())
+		if c.speedBytesPerMS > 0 {
+			ul.trackSectorUploadStats(0, c.speedBytesPerMS)
+		}
+		if timeout := ul.SectorUploadTimeout(); timeout != c.timeout {
+			t.Fatalf("case %d: expected timeout %v, got %v", i, c.timeout, timeout)
+		}
+	}
+}
+
+func TestPermanentErrors(t *testing.T) {
+	cases := []struct {
+		err       error
+		permanent bool
+	}{
+		{nil, false},
+		{errors.New("some host error"), false},
+		{context.Canceled, false},
+		{rhp3.ErrMaxRevisionReached, false},
+		{fmt.Errorf("failed to upload sector: %w", errors.New("insufficient funds to pay host")), true},
+		{errors.New("insufficient renter funds: 1 SC < 2 SC"), true},
+		{errors.New("insufficient host collateral: 1 SC < 2 SC"), true},
+	}
+	for i, c := range cases {
+		if isPermanentError(c.err) != c.permanent {
+			t.Fatalf("case %d: unexpected classification for '%v'", i, c.err)
+		}
+	}
+}
+
+func TestRefreshStoppedUploader(t *testing.T) {
+	ul := New(context.Background(), nil, nil, nil, nil, api.HostInfo{}, types.FileContractID{1}, 0, DefaultStatsRecomputeInterval, DefaultSectorUploadTimeoutMin, DefaultSectorUploadTimeoutMax, zap.NewNop().Sugar())
+	ul.Stop(ErrPermanentUploadFailure)
+	if !ul.Stopped() {
+		t.Fatal("expected uploader to be stopped")
+	}
+
+	// refreshing with the same contract shouldn't restart the uploader
+	ul.Refresh(nil, types.FileContractID{1}, 0)
+	if !ul.Stopped() {
+		t.Fatal("expected uploader to be stopped")
+	}
+
+	// refreshing with a renewed contract should
+	ul.Refresh(nil, types.FileContractID{2}, 0)
+	if ul.Stopped() {
+		t.Fatal("expected uploader to not be stopped")
+	}
+}
+
+func TestUploaderHistory(t *testing.T) {
+	fcid := types.FileContractID{1}
+	ul := New(context.Background(), nil, nil, nil, nil, api.HostInfo{}, fcid, 0, time.Hour, DefaultSectorUploadTimeoutMin, DefaultSectorUploadTimeoutMax, zap.NewNop().Sugar())
+
+	// track more data points than are kept while reading the history
+	const n = 1005
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= n; i++ {
+			ul.trackSectorUploadStats(float64(i), float64(i))
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		ul.History()
+	}
+	<-done
+	ul.trackConsecutiveFailures(false, true)
+
+	// assert the oldest data points were overwritten and the rest is ordered
+	h := ul.History()
+	if h.ContractID != fcid {
+		t.Fatalf("unexpected contract id %v", h.ContractID)
+	} else if h.ConsecutiveFailures != 1 {
+		t.Fatalf("unexpected consecutive failures %v", h.ConsecutiveFailures)
+	}
+	for _, dp := range []DataPoints{h.SectorUploadEstimateMS, h.SectorUploadSpeedBytesPerMS} {
+		if len(dp.Values) != 1000 {
+			t.Fatalf("unexpected number of data points %v", len(dp.Values))
+		} else if dp.Values[0] != 6 || dp.Values[len(dp.Values)-1] != n {
+			t.Fatalf("unexpected data points %v ... %v", dp.Values[0], dp.Values[len(dp.Values)-1])
+		} else if dp.P50 >= dp.P90 || dp.P90 >= dp.P99 || dp.P99 > n {
+			t.Fatalf("unexpected percentiles %v %v %v", dp.P50, dp.P90, dp.P99)
+		}
+	}
+}
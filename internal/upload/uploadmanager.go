@@ -2,8 +2,6 @@ package upload
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -16,20 +14,30 @@ import (
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/breaker"
 	"go.sia.tech/renterd/internal/hosts"
 	"go.sia.tech/renterd/internal/memory"
 	"go.sia.tech/renterd/internal/upload/uploader"
 	"go.sia.tech/renterd/internal/utils"
 	"go.sia.tech/renterd/object"
 	"go.uber.org/zap"
+	"lukechampine.com/frand"
 )
 
+// noCandidateRetryInterval is the interval at which a slab upload that ran out
+// of candidates retries launching its pending sector uploads, if it's allowed
+// to wait for a candidate.
+const noCandidateRetryInterval = time.Second
+
 var (
 	ErrContractExpired      = errors.New("contract expired")
 	ErrNoCandidateUploader  = errors.New("no candidate uploader found")
+	ErrPersistFailed        = errors.New("data was uploaded to the hosts but persisting the metadata failed")
 	ErrShuttingDown         = errors.New("upload manager is shutting down")
+	ErrSlabExceedsMemory    = errors.New("slab exceeds the upload memory")
 	ErrUploadCancelled      = errors.New("upload was cancelled")
 	ErrUploadNotEnoughHosts = errors.New("not enough hosts to support requested upload redundancy")
+	ErrUploaderNotFound     = errors.New("no uploader found for host")
 )
 
 type (
@@ -41,11 +49,13 @@ type (
 
 	ObjectStore interface {
 		AddMultipartPart(ctx context.Context, bucket, key, ETag, uploadID string, partNumber int, slices []object.SlabSlice) (err error)
+		AddMultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber, index int, checksum types.Hash256, slices []object.SlabSlice) (err error)
 		AddObject(ctx context.Context, bucket, key string, o object.Object, opts api.AddObjectOptions) error
 		AddPartialSlab(ctx context.Context, data []byte, minShards, totalShards uint8) (slabs []object.SlabSlice, slabBufferMaxSizeSoftReached bool, err error)
 		AddUploadingSectors(ctx context.Context, uID api.UploadID, root []types.Hash256) error
 		FinishUpload(ctx context.Context, uID api.UploadID) error
 		MarkPackedSlabsUploaded(ctx context.Context, slabs []api.UploadedPackedSlab) error
+		MultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber int) ([]api.MultipartPartProgressSlice, error)
 		Objects(ctx context.Context, prefix string, opts api.ListObjectOptions) (resp api.ObjectsResponse, err error)
 		TrackUpload(ctx context.Context, uID api.UploadID) error
 		UpdateSlab(ctx context.Context, key object.EncryptionKey, sectors []api.UploadedSector) error
@@ -62,25 +72,95 @@ type (
 		ContractRenewedFrom types.FileContractID
 	}
 
+	// ManagerConfig contains the settings of a Manager, zero values disable
+	// the respective feature unless stated otherwise.
+	ManagerConfig struct {
+		// MaxOverdrive is the number of sectors that are uploaded on top of a
+		// slab's total shards to speed up uploads, MaxOverdrivePerSlab caps
+		// it for a single slab.
+		MaxOverdrive        uint64
+		MaxOverdrivePerSlab uint64
+
+		// MinFreeMemory is the amount of upload memory that has to be free
+		// for new uploads to be accepted.
+		MinFreeMemory uint64
+
+		// ContractDurationWeight penalises candidates with contracts that
+		// expire soon, CandidateTolerance is the relative score difference
+		// within which candidates are shuffled.
+		ContractDurationWeight float64
+		CandidateTolerance     float64
+
+		// CandidateFailureThreshold is the number of consecutive failures
+		// after which an uploader is sorted after the healthy ones.
+		CandidateFailureThreshold uint64
+
+		// MaxActiveUploaders caps the number of uploaders uploads are
+		// performed on concurrently.
+		MaxActiveUploaders uint64
+
+		OverdriveTimeout       time.Duration
+		NoCandidateWait        time.Duration
+		StatsRecomputeInterval time.Duration
+		SectorUploadTimeoutMin time.Duration
+		SectorUploadTimeoutMax time.Duration
+	}
+
 	Manager struct {
+		hb        *breaker.Breakers
 		hm        hosts.Manager
 		mm        memory.MemoryManager
 		os        ObjectStore
 		cl        ContractLocker
 		cs        uploader.ContractStore
+		eb        object.ErasureBackend
 		uploadKey *utils.UploadKey
 		logger    *zap.SugaredLogger
 
-		maxOverdrive     uint64
-		overdriveTimeout time.Duration
+		maxOverdrive           uint64
+		maxOverdrivePerSlab    uint64
+		minFreeMemory          uint64
+		contractDurationWeight float64
+		candidateTolerance     float64
+		candidateFailures      uint64
+		maxActiveUploaders     uint64
+		overdriveTimeout       time.Duration
+		noCandidateWait        time.Duration
+		statsRecomputeInterval time.Duration
+		sectorUploadTimeoutMin time.Duration
+		sectorUploadTimeoutMax time.Duration
 
 		statsOverdrivePct              *utils.DataPoints
 		statsSlabUploadSpeedBytesPerMS *utils.DataPoints
+		statsPhaseLatencies            *latencyHistograms
 
 		shutdownCtx context.Context
+		inflight    sync.WaitGroup
+		persisting  sync.WaitGroup
 
-		mu        sync.Mutex
-		uploaders []*uploader.Uploader
+		mu                   sync.Mutex
+		draining             bool
+		statsRejectedUploads uint64
+		uploaders            []*uploader.Uploader
+		activeUploaders      map[*uploader.Uploader]struct{}
+		activeUploads        map[api.UploadID]*upload
+		persistence          map[api.UploadID]*api.UploadPersistenceStatus
+		persistQueue         chan persistJob
+	}
+
+	// ActiveUpload describes an in-flight object upload and the effective
+	// parameters it was started with.
+	ActiveUpload struct {
+		ID        api.UploadID
+		StartedAt time.Time
+		Params    Parameters
+
+		MaxOverdrive     uint64
+		OverdriveTimeout time.Duration
+
+		// Latency breaks down the time the upload has spent so far by
+		// phase.
+		Latency map[string]PhaseLatency
 	}
 
 	Stats struct {
@@ -88,16 +168,37 @@ type (
 		AvgOverdrivePct        float64
 		HealthyUploaders       uint64
 		NumUploaders           uint64
+		ActiveUploaders        uint64
+		IdleUploaders          uint64
+		MemoryPressure         float64
+		RejectedUploads        uint64
 		UploadSpeedsMBPS       map[types.PublicKey]float64
+		SectorUploadTimeouts   map[types.PublicKey]UploaderTimeoutStats
+		PhaseLatencies         map[string]LatencyHistogram
+	}
+
+	// UploaderTimeoutStats contains the current sector upload timeout of a host
+	// and the number of sector uploads to it that timed out.
+	UploaderTimeoutStats struct {
+		Timeout  time.Duration
+		TimedOut uint64
 	}
 )
 
 type (
 	upload struct {
 		id          api.UploadID
+		params      Parameters
+		startedAt   time.Time
+		bh          uint64
 		allowed     map[types.PublicKey]struct{}
+		eb          object.ErasureBackend
 		os          ObjectStore
 		shutdownCtx context.Context
+		logger      *zap.SugaredLogger
+
+		latencies  *phaseLatencies
+		histograms *latencyHistograms
 	}
 
 	uploadedSector struct {
@@ -109,8 +210,9 @@ type (
 	slabUpload struct {
 		uploadID api.UploadID
 
-		maxOverdrive  uint64
-		lastOverdrive time.Time
+		maxOverdrive        uint64
+		maxOverdrivePerSlab uint64
+		lastOverdrive       time.Time
 
 		sectors    []*sectorUpload
 		candidates []*candidate // sorted by upload estimate
@@ -129,6 +231,7 @@ type (
 	candidate struct {
 		uploader *uploader.Uploader
 		req      *uploader.SectorUploadReq
+		failed   bool
 	}
 
 	slabUploadResponse struct {
@@ -150,27 +253,76 @@ type (
 	}
 )
 
-func NewManager(ctx context.Context, uploadKey *utils.UploadKey, hm hosts.Manager, mm memory.MemoryManager, os ObjectStore, cl ContractLocker, cs uploader.ContractStore, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.Logger) *Manager {
+func NewManager(ctx context.Context, uploadKey *utils.UploadKey, hb *breaker.Breakers, hm hosts.Manager, mm memory.MemoryManager, os ObjectStore, cl ContractLocker, cs uploader.ContractStore, eb object.ErasureBackend, cfg ManagerConfig, logger *zap.Logger) *Manager {
 	logger = logger.Named("uploadmanager")
-	return &Manager{
+	mgr := &Manager{
+		hb:        hb,
 		hm:        hm,
 		mm:        mm,
 		os:        os,
 		cl:        cl,
 		cs:        cs,
+		eb:        eb,
 		uploadKey: uploadKey,
 		logger:    logger.Sugar(),
 
-		maxOverdrive:     maxOverdrive,
-		overdriveTimeout: overdriveTimeout,
+		maxOverdrive:           cfg.MaxOverdrive,
+		maxOverdrivePerSlab:    cfg.MaxOverdrivePerSlab,
+		minFreeMemory:          cfg.MinFreeMemory,
+		contractDurationWeight: cfg.ContractDurationWeight,
+		candidateTolerance:     cfg.CandidateTolerance,
+		candidateFailures:      cfg.CandidateFailureThreshold,
+		maxActiveUploaders:     cfg.MaxActiveUploaders,
+		overdriveTimeout:       cfg.OverdriveTimeout,
+		noCandidateWait:        cfg.NoCandidateWait,
+		statsRecomputeInterval: cfg.StatsRecomputeInterval,
+		sectorUploadTimeoutMin: cfg.SectorUploadTimeoutMin,
+		sectorUploadTimeoutMax: cfg.SectorUploadTimeoutMax,
 
 		statsOverdrivePct:              utils.NewDataPoints(0),
 		statsSlabUploadSpeedBytesPerMS: utils.NewDataPoints(0),
+		statsPhaseLatencies:            newLatencyHistograms(),
 
 		shutdownCtx: ctx,
 
-		uploaders: make([]*uploader.Uploader, 0),
+		uploaders:       make([]*uploader.Uploader, 0),
+		activeUploaders: make(map[*uploader.Uploader]struct{}),
+		activeUploads:   make(map[api.UploadID]*upload),
+		persistence:     make(map[api.UploadID]*api.UploadPersistenceStatus),
+		persistQueue:    make(chan persistJob, persistQueueSize),
 	}
+
+	// start the goroutines that persist asynchronously uploaded objects
+	for i := 0; i < persistWorkers; i++ {
+		go mgr.threadedPersistObjects()
+	}
+	go mgr.threadedPrunePersistenceStatuses()
+	return mgr
+}
+
+// ActiveUploads returns the object uploads that are currently in progress,
+// sorted by the time they were started.
+func (mgr *Manager) ActiveUploads() []ActiveUpload {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	uploads := make([]ActiveUpload, 0, len(mgr.activeUploads))
+	for _, u := range mgr.activeUploads {
+		uploads = append(uploads, ActiveUpload{
+			ID:        u.id,
+			StartedAt: u.startedAt,
+			Params:    u.params,
+
+			MaxOverdrive:     mgr.maxOverdrive,
+			OverdriveTimeout: mgr.overdriveTimeout,
+
+			Latency: u.latencies.snapshot(),
+		})
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].StartedAt.Before(uploads[j].StartedAt)
+	})
+	return uploads
 }
 
 func (mgr *Manager) AcquireMemory(ctx context.Context, amt uint64) memory.Memory {
@@ -185,13 +337,27 @@ func (mgr *Manager) Stats() Stats {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
 
-	var numHealthy uint64
+	var numHealthy, numIdle uint64
 	speeds := make(map[types.PublicKey]float64)
+	timeouts := make(map[types.PublicKey]UploaderTimeoutStats)
 	for _, u := range mgr.uploaders {
 		speeds[u.PublicKey()] = u.AvgUploadSpeedBytesPerMS() * 0.008
+		timeouts[u.PublicKey()] = UploaderTimeoutStats{
+			Timeout:  u.SectorUploadTimeout(),
+			TimedOut: u.SectorUploadTimeouts(),
+		}
 		if u.Healthy() {
 			numHealthy++
 		}
+		if _, active := mgr.activeUploaders[u]; !active && !u.Stopped() {
+			numIdle++
+		}
+	}
+
+	// compute the share of upload memory that is in use
+	var memoryPressure float64
+	if status := mgr.mm.Status(); status.Total > 0 {
+		memoryPressure = 1 - float64(status.Available)/float64(status.Total)
 	}
 
 	// prepare stats
@@ -200,7 +366,90 @@ func (mgr *Manager) Stats() Stats {
 		AvgOverdrivePct:        mgr.statsOverdrivePct.Average(),
 		HealthyUploaders:       numHealthy,
 		NumUploaders:           uint64(len(speeds)),
+		ActiveUploaders:        uint64(len(mgr.activeUploaders)),
+		IdleUploaders:          numIdle,
+		MemoryPressure:         memoryPressure,
+		RejectedUploads:        mgr.statsRejectedUploads,
 		UploadSpeedsMBPS:       speeds,
+		SectorUploadTimeouts:   timeouts,
+		PhaseLatencies:         mgr.statsPhaseLatencies.snapshot(),
+	}
+}
+
+// HostStats returns the recent sector upload stats of the uploader for the
+// given host. If the host has more than one uploader, the one that isn't
+// stopped is preferred.
+func (mgr *Manager) HostStats(hk types.PublicKey) (uploader.History, error) {
+	mgr.mu.Lock()
+	var found *uploader.Uploader
+	for _, u := range mgr.uploaders {
+		if u.PublicKey() != hk {
+			continue
+		} else if found == nil || (found.Stopped() && !u.Stopped()) {
+			found = u
+		}
+	}
+	mgr.mu.Unlock()
+
+	if found == nil {
+		return uploader.History{}, fmt.Errorf("%w: %v", ErrUploaderNotFound, hk)
+	}
+	return found.History(), nil
+}
+
+// checkFreeMemory returns api.ErrServerBusy if less than the configured
+// minimum of upload memory is available.
+func (mgr *Manager) checkFreeMemory() error {
+	if mgr.minFreeMemory == 0 {
+		return nil
+	}
+	if status := mgr.mm.Status(); status.Available < mgr.minFreeMemory {
+		mgr.mu.Lock()
+		mgr.statsRejectedUploads++
+		mgr.mu.Unlock()
+		return fmt.Errorf("%w: %d bytes of upload memory available, at least %d required", api.ErrServerBusy, status.Available, mgr.minFreeMemory)
+	}
+	return nil
+}
+
+// CheckSlabMemory returns ErrSlabExceedsMemory if a single slab with the given
+// redundancy requires more memory than the manager has in total. Acquiring the
+// memory for such a slab would block forever.
+func (mgr *Manager) CheckSlabMemory(rs api.RedundancySettings) error {
+	if total := mgr.mm.Status().Total; rs.SlabSize() > total {
+		return fmt.Errorf("%w: uploading a slab with %d shards requires %d bytes of memory but only %d bytes are configured, increase the worker's upload memory", ErrSlabExceedsMemory, rs.TotalShards, rs.SlabSize(), total)
+	}
+	return nil
+}
+
+// Drain stops the manager from accepting new uploads and waits for in-flight
+// uploads to finish, including the background persistence of asynchronously
+// persisted objects. It returns once all uploads are done, the grace period
+// elapsed or the context is cancelled. Uploads that are still in progress at
+// that point fail with ErrShuttingDown once the shutdown context is cancelled.
+func (mgr *Manager) Drain(ctx context.Context, gracePeriod time.Duration) {
+	mgr.mu.Lock()
+	mgr.draining = true
+	mgr.mu.Unlock()
+	if gracePeriod <= 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		mgr.inflight.Wait()
+		mgr.persisting.Wait() // in-flight uploads queue objects to persist
+		close(done)
+	}()
+
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		mgr.logger.Warn("shutdown grace period elapsed before all uploads finished")
+	case <-ctx.Done():
 	}
 }
 
@@ -212,7 +461,29 @@ func (mgr *Manager) Stop() {
 	}
 }
 
-func (mgr *Manager) Upload(ctx context.Context, r io.Reader, hosts []HostInfo, up Parameters) (bufferSizeLimitReached bool, eTag string, err error) {
+func (mgr *Manager) Upload(ctx context.Context, r io.Reader, hosts []HostInfo, up Parameters) (bufferSizeLimitReached bool, eTag string, uID api.UploadID, err error) {
+	// reject the upload if we're draining, otherwise keep track of it so a
+	// shutdown can wait for it to finish
+	mgr.mu.Lock()
+	if mgr.draining {
+		mgr.mu.Unlock()
+		return false, "", api.UploadID{}, ErrShuttingDown
+	}
+	mgr.inflight.Add(1)
+	mgr.mu.Unlock()
+	defer mgr.inflight.Done()
+
+	// reject the upload if we're low on memory instead of blocking until
+	// memory becomes available
+	if err := mgr.checkFreeMemory(); err != nil {
+		return false, "", api.UploadID{}, err
+	}
+
+	// reject the upload if a slab can never fit in memory
+	if err := mgr.CheckSlabMemory(up.RS); err != nil {
+		return false, "", api.UploadID{}, err
+	}
+
 	// cancel all in-flight requests when the upload is done
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -220,40 +491,66 @@ func (mgr *Manager) Upload(ctx context.Context, r io.Reader, hosts []HostInfo, u
 	// create the object
 	o := object.NewObject(up.EC)
 
-	// create the md5 hasher for the etag
-	// NOTE: we use md5 since it's s3 compatible and clients expect it to be md5
-	hasher := md5.New()
+	// create the hasher for the etag
+	hasher := newETagHasher(up.ETagBufferSize)
+	defer hasher.Close()
 	r = io.TeeReader(r, hasher)
 
 	// create the cipher reader
 	cr, err := o.Encrypt(r, object.EncryptionOptions{
-		Offset: up.EncryptionOffset,
-		Key:    mgr.uploadKey,
+		Offset:      up.EncryptionOffset,
+		Key:         mgr.uploadKey,
+		CustomerKey: up.CustomerKey,
 	})
 	if err != nil {
-		return false, "", err
+		return false, "", api.UploadID{}, err
 	}
 
 	// create the upload
-	upload, err := mgr.newUpload(up.RS.TotalShards, hosts, up.BH)
+	upload, err := mgr.newUpload(up.RS.TotalShards, hosts, up.BH, mgr.logger.With("bucket", up.Bucket, "key", up.Key))
 	if err != nil {
-		return false, "", err
+		return false, "", api.UploadID{}, err
 	}
 
+	// record the parameters used by the upload so they can be inspected
+	// while the upload is in progress
+	upload.params = up
+	mgr.mu.Lock()
+	mgr.activeUploads[upload.id] = upload
+	mgr.mu.Unlock()
+	defer func() {
+		mgr.mu.Lock()
+		delete(mgr.activeUploads, upload.id)
+		mgr.mu.Unlock()
+	}()
+
 	// track the upload in the bus
 	if err := mgr.os.TrackUpload(ctx, upload.id); err != nil {
-		return false, "", fmt.Errorf("failed to track upload '%v', err: %w", upload.id, err)
+		return false, "", api.UploadID{}, fmt.Errorf("failed to track upload '%v', err: %w", upload.id, err)
 	}
 
-	// defer a function that finishes the upload
+	// defer a function that finishes the upload, asynchronously persisted
+	// uploads are finished once the object was persisted since the bus
+	// prevents uploading sectors from being pruned until then
+	async := up.Durability == api.UploadDurabilityAsync && !up.Multipart
 	defer func() {
+		if async && err == nil {
+			return
+		}
 		ctx, cancel := context.WithTimeout(mgr.shutdownCtx, time.Minute)
 		if err := mgr.os.FinishUpload(ctx, upload.id); err != nil && !errors.Is(err, context.Canceled) {
-			mgr.logger.Errorf("failed to mark upload %v as finished: %v", upload.id, err)
+			upload.logger.Errorw("failed to mark upload as finished", "error", err)
 		}
 		cancel()
 	}()
 
+	// fetch the progress of previous attempts at uploading the part, slabs
+	// that were uploaded for the same data are reused
+	var progress partProgress
+	if up.Multipart {
+		progress = mgr.fetchPartProgress(ctx, upload, up)
+	}
+
 	// create the response channel
 	respChan := make(chan slabUploadResponse)
 
@@ -277,7 +574,9 @@ func (mgr *Manager) Upload(ctx context.Context, r io.Reader, hosts []HostInfo, u
 			default:
 			}
 			// acquire memory
+			start := time.Now()
 			mem := mgr.mm.AcquireMemory(ctx, slabSize)
+			upload.trackLatency(PhaseAcquireMemory, time.Since(start))
 			if mem == nil {
 				return // interrupted
 			}
@@ -312,9 +611,29 @@ func (mgr *Manager) Upload(ctx context.Context, r io.Reader, hosts []HostInfo, u
 				// uploading.
 				partialSlab = data[:length]
 			} else {
+				// parts of multipart uploads reuse slabs that a previous
+				// attempt at uploading the part uploaded for the same data
+				var checksum types.Hash256
+				if up.Multipart {
+					checksum = types.HashBytes(data[:length])
+				}
+				if reused := progress.reusableSlices(slabIndex, checksum, length); len(reused) == 1 {
+					mem.Release()
+					upload.logger.Debugw("reusing slab uploaded by a previous attempt", "partNumber", up.PartNumber, "slabIndex", slabIndex)
+					go func(slab object.SlabSlice, slabIndex int) {
+						select {
+						case respChan <- slabUploadResponse{slab: slab, index: slabIndex}:
+						case <-ctx.Done():
+						}
+					}(reused[0], slabIndex)
+					slabIndex++
+					continue
+				}
+
 				// regular upload
 				go func(rs api.RedundancySettings, data []byte, length, slabIndex int) {
-					uploadSpeed, overdrivePct := upload.uploadSlab(ctx, rs, data, length, slabIndex, respChan, mgr.candidates(upload.allowed), mem, mgr.maxOverdrive, mgr.overdriveTimeout)
+					slabRespChan := make(chan slabUploadResponse, 1)
+					uploadSpeed, overdrivePct := upload.uploadSlab(ctx, rs, data, length, slabIndex, slabRespChan, mgr.candidates(upload.allowed, upload.bh), mem, mgr.maxOverdrive, mgr.maxOverdrivePerSlab, mgr.overdriveTimeout, mgr.noCandidateWait, up.SlabDeadline)
 
 					// track stats
 					mgr.statsSlabUploadSpeedBytesPerMS.Track(float64(uploadSpeed))
@@ -322,6 +641,24 @@ func (mgr *Manager) Upload(ctx context.Context, r io.Reader, hosts []HostInfo, u
 
 					// release memory
 					mem.Release()
+
+					var res slabUploadResponse
+					select {
+					case res = <-slabRespChan:
+					default:
+						return // interrupted
+					}
+
+					// persist the slab before handing it off, that way all
+					// slabs are persisted by the time the part is added
+					if up.Multipart && res.err == nil {
+						mgr.persistPartProgress(ctx, upload, up, slabIndex, checksum, []object.SlabSlice{res.slab})
+					}
+
+					select {
+					case respChan <- res:
+					case <-ctx.Done():
+					}
 				}(up.RS, data, length, slabIndex)
 			}
 
@@ -335,13 +672,13 @@ func (mgr *Manager) Upload(ctx context.Context, r io.Reader, hosts []HostInfo, u
 	for len(responses) < numSlabs {
 		select {
 		case <-mgr.shutdownCtx.Done():
-			return false, "", ErrShuttingDown
+			return false, "", api.UploadID{}, ErrShuttingDown
 		case <-ctx.Done():
-			return false, "", ErrUploadCancelled
+			return false, "", api.UploadID{}, ErrUploadCancelled
 		case numSlabs = <-numSlabsChan:
 		case res := <-respChan:
 			if res.err != nil {
-				return false, "", res.err
+				return false, "", api.UploadID{}, res.err
 			}
 			responses = append(responses, res)
 		}
@@ -357,46 +694,194 @@ func (mgr *Manager) Upload(ctx context.Context, r io.Reader, hosts []HostInfo, u
 		o.Slabs = append(o.Slabs, resp.slab)
 	}
 
-	// compute etag
-	eTag = hex.EncodeToString(hasher.Sum(nil))
+	// compute etag and checksum
+	eTag = hasher.ETag()
+	checksum := hasher.Checksum()
 
-	// add partial slabs
+	// add partial slabs, a partial slab that was buffered by a previous
+	// attempt at uploading the part is reused rather than buffered again
 	if len(partialSlab) > 0 {
 		var pss []object.SlabSlice
-		pss, bufferSizeLimitReached, err = mgr.os.AddPartialSlab(ctx, partialSlab, uint8(up.RS.MinShards), uint8(up.RS.TotalShards))
-		if err != nil {
-			return false, "", err
+		var psChecksum types.Hash256
+		if up.Multipart {
+			psChecksum = types.HashBytes(partialSlab)
+			pss = progress.reusableSlices(len(o.Slabs), psChecksum, len(partialSlab))
+		}
+		if len(pss) == 0 {
+			pss, bufferSizeLimitReached, err = mgr.os.AddPartialSlab(ctx, partialSlab, uint8(up.RS.MinShards), uint8(up.RS.TotalShards))
+			if utils.IsErr(err, api.ErrSlabBufferFull) {
+				// the bus refuses to buffer more data until the buffers are
+				// uploaded, upload the partial slab right away instead
+				var ss object.SlabSlice
+				ss, err = mgr.uploadPartialSlab(ctx, upload, up, partialSlab, len(o.Slabs))
+				if err != nil {
+					return false, "", api.UploadID{}, err
+				}
+				pss, bufferSizeLimitReached = []object.SlabSlice{ss}, true
+			} else if err != nil {
+				return false, "", api.UploadID{}, err
+			}
+			if up.Multipart {
+				mgr.persistPartProgress(ctx, upload, up, len(o.Slabs), psChecksum, pss)
+			}
 		}
 		o.Slabs = append(o.Slabs, pss...)
 	}
 
+	// verify the object before persisting it
+	if up.Verify != nil && !up.Multipart {
+		if err := up.Verify(ctx, o, eTag); err != nil {
+			return bufferSizeLimitReached, "", api.UploadID{}, err
+		}
+	}
+
 	if up.Multipart {
 		// persist the part
-		err = mgr.os.AddMultipartPart(ctx, up.Bucket, up.Key, eTag, up.UploadID, up.PartNumber, o.Slabs)
+		start := time.Now()
+		err = mgr.persistWithRetry(ctx, up.PersistMaxAttempts, func(ctx context.Context, _ int) error {
+			return mgr.os.AddMultipartPart(ctx, up.Bucket, up.Key, eTag, up.UploadID, up.PartNumber, o.Slabs)
+		})
+		upload.trackLatency(PhasePersist, time.Since(start))
 		if err != nil {
-			return bufferSizeLimitReached, "", fmt.Errorf("couldn't add multi part: %w", err)
+			return bufferSizeLimitReached, "", api.UploadID{}, fmt.Errorf("couldn't add multi part: %w", err)
 		}
+	} else if async {
+		// persist the object in the background
+		mgr.persistObjectAsync(upload.id, upload.startedAt, upload.logger, up.Bucket, up.Key, o, api.AddObjectOptions{Checksum: checksum, MimeType: up.MimeType, ContentDisposition: up.ContentDisposition, ETag: eTag, Metadata: up.Metadata, PinnedHosts: up.PinnedHosts})
 	} else {
 		// persist the object
-		err = mgr.os.AddObject(ctx, up.Bucket, up.Key, o, api.AddObjectOptions{MimeType: up.MimeType, ETag: eTag, Metadata: up.Metadata})
+		opts := api.AddObjectOptions{Checksum: checksum, MimeType: up.MimeType, ContentDisposition: up.ContentDisposition, ETag: eTag, Metadata: up.Metadata, PinnedHosts: up.PinnedHosts}
+		start := time.Now()
+		err = mgr.persistWithRetry(ctx, up.PersistMaxAttempts, func(ctx context.Context, attempt int) error {
+			return mgr.os.AddObject(ctx, up.Bucket, up.Key, o, retryOptions(opts, upload.id, attempt))
+		})
+		upload.trackLatency(PhasePersist, time.Since(start))
 		if err != nil {
-			return bufferSizeLimitReached, "", fmt.Errorf("couldn't add object: %w", err)
+			return bufferSizeLimitReached, "", api.UploadID{}, fmt.Errorf("couldn't add object: %w", err)
 		}
 	}
 
+	uID = upload.id
 	return
 }
 
+// BufferObject adds the object's data to the partial slab buffers instead of
+// uploading it to hosts and persists the object. The buffered slabs are
+// uploaded together with other packed slabs once enough hosts are available.
+// Since the buffers can't be drained without hosts, buffering stops with
+// api.ErrSlabBufferFull once they exceed their soft limit.
+func (mgr *Manager) BufferObject(ctx context.Context, r io.Reader, up Parameters) (eTag string, uID api.UploadID, err error) {
+	// reject the upload if we're draining
+	mgr.mu.Lock()
+	if mgr.draining {
+		mgr.mu.Unlock()
+		return "", api.UploadID{}, ErrShuttingDown
+	}
+	mgr.inflight.Add(1)
+	mgr.mu.Unlock()
+	defer mgr.inflight.Done()
+	startedAt := time.Now()
+
+	// create the object
+	o := object.NewObject(up.EC)
+
+	// create the hasher for the etag
+	hasher := newETagHasher(up.ETagBufferSize)
+	defer hasher.Close()
+	r = io.TeeReader(r, hasher)
+
+	// create the cipher reader
+	cr, err := o.Encrypt(r, object.EncryptionOptions{
+		Offset:      up.EncryptionOffset,
+		Key:         mgr.uploadKey,
+		CustomerKey: up.CustomerKey,
+	})
+	if err != nil {
+		return "", api.UploadID{}, err
+	}
+
+	// buffer the data one slab at a time
+	var limitReached bool
+	slabSize := up.RS.SlabSizeNoRedundancy()
+	for {
+		mem := mgr.mm.AcquireMemory(ctx, slabSize)
+		if mem == nil {
+			return "", api.UploadID{}, ErrUploadCancelled
+		}
+
+		data := make([]byte, slabSize)
+		n, err := io.ReadFull(io.LimitReader(cr, int64(slabSize)), data)
+		if n > 0 && limitReached {
+			mem.Release()
+			return "", api.UploadID{}, fmt.Errorf("failed to buffer object: %w", api.ErrSlabBufferFull)
+		} else if n > 0 {
+			var pss []object.SlabSlice
+			var bufErr error
+			pss, limitReached, bufErr = mgr.os.AddPartialSlab(ctx, data[:n], uint8(up.RS.MinShards), uint8(up.RS.TotalShards))
+			if bufErr != nil {
+				mem.Release()
+				return "", api.UploadID{}, fmt.Errorf("failed to buffer object: %w", bufErr)
+			}
+			o.Slabs = append(o.Slabs, pss...)
+		}
+		mem.Release()
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return "", api.UploadID{}, err
+		}
+	}
+
+	// persist the object, the upload id makes retrying idempotent
+	eTag = hasher.ETag()
+	uID = api.NewUploadID()
+	opts := api.AddObjectOptions{Checksum: hasher.Checksum(), MimeType: up.MimeType, ContentDisposition: up.ContentDisposition, ETag: eTag, Metadata: up.Metadata}
+	if up.Durability == api.UploadDurabilityAsync {
+		mgr.persistObjectAsync(uID, startedAt, mgr.logger.With("bucket", up.Bucket, "key", up.Key), up.Bucket, up.Key, o, opts)
+		return eTag, uID, nil
+	}
+	err = mgr.persistWithRetry(ctx, up.PersistMaxAttempts, func(ctx context.Context, attempt int) error {
+		return mgr.os.AddObject(ctx, up.Bucket, up.Key, o, retryOptions(opts, uID, attempt))
+	})
+	if err != nil {
+		return "", api.UploadID{}, fmt.Errorf("couldn't add object: %w", err)
+	}
+	return eTag, uID, nil
+}
+
+// uploadPartialSlab uploads the given partial slab as a regular slab, it's
+// used when the partial slab can't be buffered.
+func (mgr *Manager) uploadPartialSlab(ctx context.Context, upload *upload, up Parameters, partialSlab []byte, index int) (object.SlabSlice, error) {
+	mem := mgr.mm.AcquireMemory(ctx, up.RS.SlabSize())
+	if mem == nil {
+		return object.SlabSlice{}, ErrUploadCancelled
+	}
+	defer mem.Release()
+
+	data := make([]byte, up.RS.SlabSizeNoRedundancy())
+	copy(data, partialSlab)
+
+	respChan := make(chan slabUploadResponse, 1)
+	upload.uploadSlab(ctx, up.RS, data, len(partialSlab), index, respChan, mgr.candidates(upload.allowed, upload.bh), mem, mgr.maxOverdrive, mgr.maxOverdrivePerSlab, mgr.overdriveTimeout, mgr.noCandidateWait, up.SlabDeadline)
+	select {
+	case res := <-respChan:
+		return res.slab, res.err
+	default:
+		return object.SlabSlice{}, ErrUploadCancelled // context was cancelled
+	}
+}
+
 func (mgr *Manager) UploadPackedSlab(ctx context.Context, rs api.RedundancySettings, ps api.PackedSlab, mem memory.Memory, hosts []HostInfo, bh uint64) (err error) {
 	// cancel all in-flight requests when the upload is done
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// build the shards
-	shards := encryptPartialSlab(ps.Data, ps.EncryptionKey, uint8(rs.MinShards), uint8(rs.TotalShards))
+	shards := encryptPartialSlab(mgr.eb, ps.Data, ps.EncryptionKey, uint8(rs.MinShards), uint8(rs.TotalShards))
 
 	// create the upload
-	upload, err := mgr.newUpload(len(shards), hosts, bh)
+	upload, err := mgr.newUpload(len(shards), hosts, bh, mgr.logger.With("bufferID", ps.BufferID))
 	if err != nil {
 		return err
 	}
@@ -410,13 +895,13 @@ func (mgr *Manager) UploadPackedSlab(ctx context.Context, rs api.RedundancySetti
 	defer func() {
 		ctx, cancel := context.WithTimeout(mgr.shutdownCtx, time.Minute)
 		if err := mgr.os.FinishUpload(ctx, upload.id); err != nil {
-			mgr.logger.Errorf("failed to mark upload %v as finished: %v", upload.id, err)
+			upload.logger.Errorw("failed to mark upload as finished", "error", err)
 		}
 		cancel()
 	}()
 
 	// upload the shards
-	uploaded, uploadSpeed, overdrivePct, err := upload.uploadShards(ctx, shards, mgr.candidates(upload.allowed), mem, mgr.maxOverdrive, mgr.overdriveTimeout)
+	uploaded, uploadSpeed, overdrivePct, err := upload.uploadShards(ctx, upload.logger, shards, mgr.candidates(upload.allowed, upload.bh), mem, mgr.maxOverdrive, mgr.maxOverdrivePerSlab, mgr.overdriveTimeout, mgr.noCandidateWait)
 	if err != nil {
 		return err
 	}
@@ -444,13 +929,50 @@ func (mgr *Manager) UploadPackedSlab(ctx context.Context, rs api.RedundancySetti
 	return nil
 }
 
+// RepairShards moves the shards at the given indices of a slab to the given
+// hosts. The shards are expected to be decrypted and shards that weren't
+// downloaded are expected to have a len of zero, only those are regenerated
+// from the healthy shards before being uploaded. It returns the indices of
+// the shards that were regenerated.
+func (mgr *Manager) RepairShards(ctx context.Context, s object.Slab, shardIndices []int, shards [][]byte, hosts []HostInfo, bh uint64, mem memory.Memory) (regenerated []int, err error) {
+	// regenerate the missing shards
+	for _, si := range shardIndices {
+		if len(shards[si]) == 0 {
+			regenerated = append(regenerated, si)
+		}
+	}
+	if len(regenerated) > 0 {
+		if err := s.ReconstructSome(mgr.eb, shards, regenerated); err != nil {
+			return nil, fmt.Errorf("failed to regenerate shards: %w", err)
+		}
+	}
+
+	// encrypt the shards we need to upload, encryption depends on the shard's
+	// index so we encrypt them before filtering
+	filtered := make([][]byte, len(shards))
+	for _, si := range shardIndices {
+		filtered[si] = shards[si]
+	}
+	s.Encrypt(filtered)
+	for i, si := range shardIndices {
+		filtered[i] = filtered[si]
+	}
+	filtered = filtered[:len(shardIndices)]
+
+	// upload the shards
+	if err := mgr.UploadShards(ctx, s, shardIndices, filtered, hosts, bh, mem); err != nil {
+		return nil, err
+	}
+	return regenerated, nil
+}
+
 func (mgr *Manager) UploadShards(ctx context.Context, s object.Slab, shardIndices []int, shards [][]byte, hosts []HostInfo, bh uint64, mem memory.Memory) (err error) {
 	// cancel all in-flight requests when the upload is done
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// create the upload
-	upload, err := mgr.newUpload(len(shards), hosts, bh)
+	upload, err := mgr.newUpload(len(shards), hosts, bh, mgr.logger.With("slabKey", s.EncryptionKey))
 	if err != nil {
 		return err
 	}
@@ -464,13 +986,13 @@ func (mgr *Manager) UploadShards(ctx context.Context, s object.Slab, shardIndice
 	defer func() {
 		ctx, cancel := context.WithTimeout(mgr.shutdownCtx, time.Minute)
 		if err := mgr.os.FinishUpload(ctx, upload.id); err != nil {
-			mgr.logger.Errorf("failed to mark upload %v as finished: %v", upload.id, err)
+			upload.logger.Errorw("failed to mark upload as finished", "error", err)
 		}
 		cancel()
 	}()
 
 	// upload the shards
-	uploaded, uploadSpeed, overdrivePct, err := upload.uploadShards(ctx, shards, mgr.candidates(upload.allowed), mem, mgr.maxOverdrive, mgr.overdriveTimeout)
+	uploaded, uploadSpeed, overdrivePct, err := upload.uploadShards(ctx, upload.logger, shards, mgr.candidates(upload.allowed, upload.bh), mem, mgr.maxOverdrive, mgr.maxOverdrivePerSlab, mgr.overdriveTimeout, mgr.noCandidateWait)
 
 	// build sectors
 	var sectors []api.UploadedSector
@@ -498,24 +1020,126 @@ func (mgr *Manager) UploadShards(ctx context.Context, s object.Slab, shardIndice
 	return nil
 }
 
-func (mgr *Manager) candidates(allowed map[types.PublicKey]struct{}) (candidates []*uploader.Uploader) {
+// candidates returns the uploaders of the allowed hosts, sorted by their score.
+// The score of an uploader is its upload estimate, which is penalised for
+// contracts that expire sooner than the longest running contract if the
+// manager was configured with a contract duration weight. With a weight of 1,
+// an uploader whose contract is about to expire has a score twice as high as
+// that of an equally fast uploader with the longest remaining duration.
+//
+// If the manager was configured with a candidate tolerance, candidates whose
+// scores are within that tolerance of each other are shuffled. That way the
+// load is spread over similarly fast hosts rather than always hitting the
+// fastest one first, while slower hosts are still tried last.
+//
+// Candidates that failed at least the configured number of sector uploads in a
+// row are sorted after all healthy candidates, regardless of their score. A
+// score only reflects failures once the uploader's stats are recomputed, so a
+// failing host with a short queue would otherwise still be tried first.
+func (mgr *Manager) candidates(allowed map[types.PublicKey]struct{}, bh uint64) (candidates []*uploader.Uploader) {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
 
+	// promote idle uploaders to replace active ones that failed
+	mgr.selectActiveUploaders(bh)
+
+	var maxRemaining uint64
 	for _, u := range mgr.uploaders {
+		if _, active := mgr.activeUploaders[u]; !active {
+			continue // idle
+		} else if u.Stopped() {
+			continue // permanently failed
+		} else if !mgr.hb.Available(u.PublicKey()) {
+			continue // circuit breaker is open
+		}
 		if _, allowed := allowed[u.PublicKey()]; allowed {
 			candidates = append(candidates, u)
 		}
 	}
 
-	// sort candidates by upload estimate
+	// the active uploaders are selected without taking the allowed hosts into
+	// account, if too few of them are allowed the candidates are topped up
+	// with the most promising idle uploaders of the allowed hosts
+	if mgr.maxActiveUploaders > 0 && uint64(len(candidates)) < mgr.maxActiveUploaders {
+		var idle []*uploader.Uploader
+		for _, u := range mgr.uploaders {
+			if _, active := mgr.activeUploaders[u]; active {
+				continue
+			} else if _, allowed := allowed[u.PublicKey()]; !allowed {
+				continue
+			} else if u.Stopped() || !mgr.hb.Available(u.PublicKey()) {
+				continue
+			}
+			idle = append(idle, u)
+		}
+		mgr.sortUploaders(idle, bh)
+		for len(idle) > 0 && uint64(len(candidates)) < mgr.maxActiveUploaders {
+			idle[0].Start()
+			mgr.activeUploaders[idle[0]] = struct{}{}
+			candidates = append(candidates, idle[0])
+			idle = idle[1:]
+		}
+	}
+	for _, u := range candidates {
+		if remaining := remainingDuration(u, bh); remaining > maxRemaining {
+			maxRemaining = remaining
+		}
+	}
+
+	// score the candidates
+	scores := make(map[*uploader.Uploader]float64, len(candidates))
+	for _, u := range candidates {
+		score := u.Estimate()
+		if mgr.contractDurationWeight > 0 && maxRemaining > 0 {
+			shortfall := 1 - float64(remainingDuration(u, bh))/float64(maxRemaining)
+			score *= 1 + mgr.contractDurationWeight*shortfall
+		}
+		scores[u] = score
+	}
+
+	// determine which candidates are failing, hosts that recently failed an
+	// interaction are treated as failing so they are only used if necessary
+	failing := make(map[*uploader.Uploader]bool, len(candidates))
+	for _, u := range candidates {
+		failing[u] = u.RecentlyFailed() || (mgr.candidateFailures > 0 && u.ConsecutiveFailures() >= mgr.candidateFailures)
+	}
+
+	// sort candidates by health and score
 	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].Estimate() < candidates[j].Estimate()
+		if fi, fj := failing[candidates[i]], failing[candidates[j]]; fi != fj {
+			return fj
+		}
+		return scores[candidates[i]] < scores[candidates[j]]
 	})
+
+	// shuffle candidates with similar scores and the same health
+	if mgr.candidateTolerance > 0 {
+		for start := 0; start < len(candidates); {
+			end := start + 1
+			maxScore := scores[candidates[start]] * (1 + mgr.candidateTolerance)
+			for end < len(candidates) && scores[candidates[end]] <= maxScore && failing[candidates[end]] == failing[candidates[start]] {
+				end++
+			}
+			band := candidates[start:end]
+			frand.Shuffle(len(band), func(i, j int) { band[i], band[j] = band[j], band[i] })
+			start = end
+		}
+	}
 	return
 }
 
-func (mgr *Manager) newUpload(totalShards int, hosts []HostInfo, bh uint64) (*upload, error) {
+// remainingDuration returns the number of blocks until the uploader's contract
+// ends.
+func remainingDuration(u *uploader.Uploader, bh uint64) uint64 {
+	if endHeight := u.EndHeight(); endHeight > bh {
+		return endHeight - bh
+	}
+	return 0
+}
+
+// newUpload creates a new upload, the given logger is decorated with the
+// upload's id so every log line of the upload can be correlated.
+func (mgr *Manager) newUpload(totalShards int, hosts []HostInfo, bh uint64, logger *zap.SugaredLogger) (*upload, error) {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
 
@@ -525,6 +1149,8 @@ func (mgr *Manager) newUpload(totalShards int, hosts []HostInfo, bh uint64) (*up
 	// check if we have enough contracts
 	if len(hosts) < totalShards {
 		return nil, fmt.Errorf("%v < %v: %w", len(hosts), totalShards, ErrUploadNotEnoughHosts)
+	} else if mgr.maxActiveUploaders > 0 && mgr.maxActiveUploaders < uint64(totalShards) {
+		return nil, fmt.Errorf("%w: the number of active uploaders is capped at %v which is less than the %v shards of the upload", ErrUploadNotEnoughHosts, mgr.maxActiveUploaders, totalShards)
 	}
 
 	// create allowed map
@@ -534,18 +1160,28 @@ func (mgr *Manager) newUpload(totalShards int, hosts []HostInfo, bh uint64) (*up
 	}
 
 	// create upload
+	id := api.NewUploadID()
 	return &upload{
-		id:          api.NewUploadID(),
+		id:          id,
+		startedAt:   time.Now(),
+		bh:          bh,
 		allowed:     allowed,
+		eb:          mgr.eb,
 		os:          mgr.os,
 		shutdownCtx: mgr.shutdownCtx,
+		logger:      logger.Named(id.String()).With("uploadID", id),
+
+		latencies:  newPhaseLatencies(),
+		histograms: mgr.statsPhaseLatencies,
 	}, nil
 }
 
 func (mgr *Manager) refreshUploaders(hosts []HostInfo, bh uint64) {
 	// build table to lookup lookup
 	lookup := make(map[types.FileContractID]HostInfo)
+	current := make(map[types.FileContractID]HostInfo)
 	for _, h := range hosts {
+		current[h.ContractID] = h
 		if h.ContractRenewedFrom != (types.FileContractID{}) {
 			lookup[h.ContractRenewedFrom] = h
 		}
@@ -558,6 +1194,8 @@ func (mgr *Manager) refreshUploaders(hosts []HostInfo, bh uint64) {
 		// refresh uploaders that got renewed
 		if renewal, renewed := lookup[uploader.ContractID()]; renewed {
 			uploader.Refresh(&renewal.HostInfo, renewal.ContractID, renewal.ContractEndHeight)
+		} else if h, ok := current[uploader.ContractID()]; ok {
+			uploader.Refresh(&h.HostInfo, h.ContractID, h.ContractEndHeight)
 		}
 
 		// stop uploaders that expired
@@ -577,17 +1215,96 @@ func (mgr *Manager) refreshUploaders(hosts []HostInfo, bh uint64) {
 	// add missing uploaders
 	for _, h := range hosts {
 		if _, exists := existing[h.ContractID]; !exists && bh < h.ContractEndHeight {
-			uploader := uploader.New(mgr.shutdownCtx, mgr.cl, mgr.cs, mgr.hm, h.HostInfo, h.ContractID, h.ContractEndHeight, mgr.logger)
+			uploader := uploader.New(mgr.shutdownCtx, mgr.cl, mgr.cs, mgr.hb, mgr.hm, h.HostInfo, h.ContractID, h.ContractEndHeight, mgr.statsRecomputeInterval, mgr.sectorUploadTimeoutMin, mgr.sectorUploadTimeoutMax, mgr.logger)
 			refreshed = append(refreshed, uploader)
-			go uploader.Start()
 		}
 	}
 
 	mgr.uploaders = refreshed
-	return
+	mgr.selectActiveUploaders(bh)
+}
+
+// selectActiveUploaders updates the set of uploaders that uploads are performed
+// on. If the number of active uploaders is capped, the most promising uploaders
+// are kept active and the others are made idle until an active uploader fails.
+// Uploaders in excess of the cap, which were activated to top up the candidates
+// of an upload, are only made idle once they finished their work.
+func (mgr *Manager) selectActiveUploaders(bh uint64) {
+	usable := func(u *uploader.Uploader) bool {
+		return u.Healthy() && mgr.hb.Available(u.PublicKey())
+	}
+
+	// keep usable uploaders active, the others compete with the idle ones
+	var active, idle []*uploader.Uploader
+	for _, u := range mgr.uploaders {
+		if u.Stopped() {
+			continue
+		} else if _, ok := mgr.activeUploaders[u]; ok && usable(u) {
+			active = append(active, u)
+		} else {
+			idle = append(idle, u)
+		}
+	}
+
+	// fill up the active uploaders with the most promising idle ones
+	mgr.sortUploaders(idle, bh)
+	for len(idle) > 0 && (mgr.maxActiveUploaders == 0 || uint64(len(active)) < mgr.maxActiveUploaders) {
+		active = append(active, idle[0])
+		idle = idle[1:]
+	}
+
+	// drop the least promising uploaders in excess of the cap
+	if mgr.maxActiveUploaders > 0 && uint64(len(active)) > mgr.maxActiveUploaders {
+		mgr.sortUploaders(active, bh)
+		keep := append([]*uploader.Uploader(nil), active[:mgr.maxActiveUploaders]...)
+		for _, u := range active[mgr.maxActiveUploaders:] {
+			if u.Busy() {
+				keep = append(keep, u)
+			}
+		}
+		active = keep
+	}
+
+	// start the active uploaders and make the others idle, including the
+	// ones that were removed
+	selected := make(map[*uploader.Uploader]struct{}, len(active))
+	for _, u := range active {
+		selected[u] = struct{}{}
+		u.Start()
+	}
+	for u := range mgr.activeUploaders {
+		if _, ok := selected[u]; !ok {
+			u.Idle()
+		}
+	}
+	mgr.activeUploaders = selected
+}
+
+// sortUploaders sorts the given uploaders by how promising they are, usable
+// uploaders come first, followed by the ones with the longest remaining
+// contract duration and the lowest estimate.
+func (mgr *Manager) sortUploaders(uploaders []*uploader.Uploader, bh uint64) {
+	usable := func(u *uploader.Uploader) bool {
+		return u.Healthy() && mgr.hb.Available(u.PublicKey())
+	}
+	sort.SliceStable(uploaders, func(i, j int) bool {
+		if ui, uj := usable(uploaders[i]), usable(uploaders[j]); ui != uj {
+			return ui
+		} else if ri, rj := remainingDuration(uploaders[i], bh), remainingDuration(uploaders[j], bh); ri != rj {
+			return ri > rj
+		}
+		return uploaders[i].Estimate() < uploaders[j].Estimate()
+	})
+}
+
+// trackLatency tracks the time spent in the given phase, both for the upload
+// itself and in the manager's aggregate histograms.
+func (u *upload) trackLatency(phase string, d time.Duration) {
+	u.latencies.track(phase, d)
+	u.histograms.track(phase, d)
 }
 
-func (u *upload) newSlabUpload(ctx context.Context, shards [][]byte, uploaders []*uploader.Uploader, mem memory.Memory, maxOverdrive uint64) (*slabUpload, chan uploader.SectorUploadResp) {
+func (u *upload) newSlabUpload(ctx context.Context, shards [][]byte, uploaders []*uploader.Uploader, mem memory.Memory, maxOverdrive, maxOverdrivePerSlab uint64) (*slabUpload, chan uploader.SectorUploadResp) {
 	// prepare response channel
 	responseChan := make(chan uploader.SectorUploadResp)
 
@@ -629,8 +1346,9 @@ func (u *upload) newSlabUpload(ctx context.Context, shards [][]byte, uploaders [
 	return &slabUpload{
 		uploadID: u.id,
 
-		maxOverdrive: maxOverdrive,
-		mem:          mem,
+		maxOverdrive:        maxOverdrive,
+		maxOverdrivePerSlab: maxOverdrivePerSlab,
+		mem:                 mem,
 
 		sectors:    sectors,
 		candidates: candidates,
@@ -640,7 +1358,7 @@ func (u *upload) newSlabUpload(ctx context.Context, shards [][]byte, uploaders [
 	}, responseChan
 }
 
-func (u *upload) uploadSlab(ctx context.Context, rs api.RedundancySettings, data []byte, length, index int, respChan chan slabUploadResponse, candidates []*uploader.Uploader, mem memory.Memory, maxOverdrive uint64, overdriveTimeout time.Duration) (int64, float64) {
+func (u *upload) uploadSlab(ctx context.Context, rs api.RedundancySettings, data []byte, length, index int, respChan chan slabUploadResponse, candidates []*uploader.Uploader, mem memory.Memory, maxOverdrive, maxOverdrivePerSlab uint64, overdriveTimeout, noCandidateWait, deadline time.Duration) (int64, float64) {
 	// create the response
 	resp := slabUploadResponse{
 		slab: object.SlabSlice{
@@ -652,12 +1370,26 @@ func (u *upload) uploadSlab(ctx context.Context, rs api.RedundancySettings, data
 	}
 
 	// create the shards
+	start := time.Now()
 	shards := make([][]byte, rs.TotalShards)
-	resp.slab.Slab.Encode(data, shards)
+	resp.slab.Slab.Encode(u.eb, data, shards)
 	resp.slab.Slab.Encrypt(shards)
+	u.trackLatency(PhaseEncode, time.Since(start))
+
+	// apply the deadline, the response is sent using the parent context
+	uploadCtx := ctx
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		uploadCtx, cancel = context.WithTimeoutCause(ctx, deadline, api.ErrSlabUploadDeadlineExceeded)
+		defer cancel()
+	}
 
 	// upload the shards
-	uploaded, uploadSpeed, overdrivePct, err := u.uploadShards(ctx, shards, candidates, mem, maxOverdrive, overdriveTimeout)
+	logger := u.logger.With("slabIndex", index)
+	uploaded, uploadSpeed, overdrivePct, err := u.uploadShards(uploadCtx, logger, shards, candidates, mem, maxOverdrive, maxOverdrivePerSlab, overdriveTimeout, noCandidateWait)
+	if err != nil {
+		err = fmt.Errorf("slab %d: %w", index, err)
+	}
 
 	// build the sectors
 	var sectors []object.Sector
@@ -680,14 +1412,17 @@ func (u *upload) uploadSlab(ctx context.Context, rs api.RedundancySettings, data
 
 // uploadShards uploads the shards to the provided candidates. It returns an
 // error if it fails to upload all shards but len(sectors) will be > 0 if some
-// shards were uploaded successfully.
-func (u *upload) uploadShards(ctx context.Context, shards [][]byte, candidates []*uploader.Uploader, mem memory.Memory, maxOverdrive uint64, overdriveTimeout time.Duration) (sectors []uploadedSector, uploadSpeed int64, overdrivePct float64, err error) {
+// shards were uploaded successfully. If no candidate is available to upload a
+// shard to, the upload fails unless 'noCandidateWait' is set. In that case the
+// shard is retried periodically, giving candidates that failed another chance,
+// until a candidate accepts it or the wait is over.
+func (u *upload) uploadShards(ctx context.Context, logger *zap.SugaredLogger, shards [][]byte, candidates []*uploader.Uploader, mem memory.Memory, maxOverdrive, maxOverdrivePerSlab uint64, overdriveTimeout, noCandidateWait time.Duration) (sectors []uploadedSector, uploadSpeed int64, overdrivePct float64, err error) {
 	// ensure inflight uploads get cancelled
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// prepare the upload
-	slab, respChan := u.newSlabUpload(ctx, shards, candidates, mem, maxOverdrive)
+	slab, respChan := u.newSlabUpload(ctx, shards, candidates, mem, maxOverdrive, maxOverdrivePerSlab)
 
 	// prepare requests
 	requests := make([]*uploader.SectorUploadReq, len(shards))
@@ -703,22 +1438,41 @@ func (u *upload) uploadShards(ctx context.Context, shards [][]byte, candidates [
 		return nil, 0, 0, fmt.Errorf("failed to add sector to uploading sectors: %w", err)
 	}
 
+	// create a request buffer
+	var buffer []*uploader.SectorUploadReq
+
 	// launch all requests
 	for _, upload := range requests {
-		if err := slab.launch(upload); err != nil {
+		if err := slab.launch(upload); errors.Is(err, ErrNoCandidateUploader) && noCandidateWait > 0 {
+			buffer = append(buffer, upload)
+		} else if err != nil {
 			return nil, 0, 0, err
 		}
 	}
 
+	// prepare a helper to schedule retrying the buffered requests if we are
+	// allowed to wait for a candidate
+	var retryChan <-chan time.Time
+	var waitDeadline time.Time
+	scheduleRetry := func() {
+		if noCandidateWait == 0 || retryChan != nil || len(buffer) == 0 {
+			return
+		}
+		if waitDeadline.IsZero() {
+			waitDeadline = time.Now().Add(noCandidateWait)
+		}
+		if time.Now().Before(waitDeadline) {
+			retryChan = time.After(noCandidateRetryInterval)
+		}
+	}
+	scheduleRetry()
+
 	// create an overdrive timer
 	if overdriveTimeout == 0 {
 		overdriveTimeout = time.Duration(math.MaxInt64)
 	}
 	timer := time.NewTimer(overdriveTimeout)
 
-	// create a request buffer
-	var buffer []*uploader.SectorUploadReq
-
 	// start the timer after the upload has started
 	// newSlabUpload is quite slow due to computing the sector roots
 	start := time.Now()
@@ -727,15 +1481,28 @@ func (u *upload) uploadShards(ctx context.Context, shards [][]byte, candidates [
 	var used bool
 	var done bool
 loop:
-	for slab.numInflight > 0 && !done {
+	for (slab.numInflight > 0 || retryChan != nil) && !done {
 		select {
 		case <-u.shutdownCtx.Done():
 			return nil, 0, 0, ErrShuttingDown
 		case <-ctx.Done():
 			return nil, 0, 0, context.Cause(ctx)
 		case resp := <-respChan:
+			if resp.Err != nil {
+				logger.Debugw("failed to upload sector", "sectorIndex", resp.Req.Idx, "hk", resp.HK, "overdrive", resp.Req.Overdrive, "error", resp.Err)
+			}
+
+			// track the time spent on the host
+			u.trackLatency(PhaseContractLock, resp.LockDuration)
+			if resp.UploadDuration > 0 {
+				u.trackLatency(PhaseSectorUpload, resp.UploadDuration)
+			}
+
 			// receive the response
 			used, done = slab.receive(resp)
+			if used {
+				reportShardProgress(ctx, slab.numUploaded)
+			}
 			if done {
 				break loop
 			}
@@ -765,7 +1532,16 @@ loop:
 			if slab.canOverdrive(overdriveTimeout) {
 				_ = slab.launch(slab.nextRequest(respChan)) // ignore result
 			}
+		case <-retryChan:
+			// give failed candidates another chance and relaunch buffered
+			// upload requests
+			retryChan = nil
+			slab.releaseFailed()
+			for len(buffer) > 0 && slab.launch(buffer[0]) == nil {
+				buffer = buffer[1:]
+			}
 		}
+		scheduleRetry()
 
 		// reset the overdrive timer
 		if overdriveTimeout != math.MaxInt64 {
@@ -804,6 +1580,7 @@ loop:
 	if slab.numUploaded < slab.numSectors {
 		remaining := slab.numSectors - slab.numUploaded
 		err = fmt.Errorf("failed to upload slab: launched=%d uploaded=%d remaining=%d inflight=%d pending=%d uploaders=%d errors=%d %w", slab.numLaunched, slab.numUploaded, remaining, slab.numInflight, len(buffer), len(slab.candidates), len(slab.errs), slab.errs)
+		logger.Warnw("failed to upload slab", "uploaded", slab.numUploaded, "remaining", remaining, "error", err)
 		return
 	}
 
@@ -827,9 +1604,26 @@ func (s *slabUpload) canOverdrive(overdriveTimeout time.Duration) bool {
 		return false
 	}
 
+	// the slab has reached its cap of overdrive uploads, no matter how many
+	// sectors are still remaining
+	if s.maxOverdrivePerSlab > 0 && s.numOverdriving >= s.maxOverdrivePerSlab {
+		return false
+	}
+
 	return true
 }
 
+// releaseFailed makes candidates that failed to upload a sector available
+// again, unless their uploader was stopped.
+func (s *slabUpload) releaseFailed() {
+	for _, c := range s.candidates {
+		if c.failed && !c.uploader.Stopped() {
+			c.req = nil
+			c.failed = false
+		}
+	}
+}
+
 func (s *slabUpload) launch(req *uploader.SectorUploadReq) error {
 	// nothing to do
 	if req == nil {
@@ -906,7 +1700,7 @@ func (s *slabUpload) receive(resp uploader.SectorUploadResp) (bool, bool) {
 	if sector.isUploaded() {
 		// release the candidate
 		for _, candidate := range s.candidates {
-			if candidate.req == req {
+			if candidate.req == req && !candidate.failed {
 				candidate.req = nil
 				break
 			}
@@ -919,6 +1713,12 @@ func (s *slabUpload) receive(resp uploader.SectorUploadResp) (bool, bool) {
 	// result of the sector ctx being closed
 	if resp.Err != nil {
 		s.errs[resp.HK] = resp.Err
+		for _, candidate := range s.candidates {
+			if candidate.req == req && !candidate.failed {
+				candidate.failed = true
+				break
+			}
+		}
 		return false, false
 	}
 
@@ -958,14 +1758,14 @@ func (us uploadedSector) toObjectSector() object.Sector {
 	}
 }
 
-func encryptPartialSlab(data []byte, key object.EncryptionKey, minShards, totalShards uint8) [][]byte {
+func encryptPartialSlab(eb object.ErasureBackend, data []byte, key object.EncryptionKey, minShards, totalShards uint8) [][]byte {
 	slab := object.Slab{
 		EncryptionKey: key,
 		MinShards:     minShards,
 		Shards:        make([]object.Sector, totalShards),
 	}
 	encodedShards := make([][]byte, totalShards)
-	slab.Encode(data, encodedShards)
+	slab.Encode(eb, data, encodedShards)
 	slab.Encrypt(encodedShards)
 	return encodedShards
 }
@@ -1,12 +1,21 @@
 package upload
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"reflect"
 	"testing"
 
+	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/internal/host"
+	"go.sia.tech/renterd/internal/memory"
+	"go.sia.tech/renterd/internal/test/mocks"
+	"go.sia.tech/renterd/internal/upload/uploader"
+	"go.sia.tech/renterd/internal/utils"
+	"go.sia.tech/renterd/object"
 	"go.uber.org/zap"
 )
 
@@ -22,7 +31,7 @@ func (hm *hostManager) Host(hk types.PublicKey, fcid types.FileContractID, siamu
 
 func TestRefreshUploaders(t *testing.T) {
 	hm := &hostManager{}
-	ul := NewManager(context.Background(), nil, hm, nil, nil, nil, nil, 0, 0, zap.NewNop())
+	ul := NewManager(context.Background(), nil, nil, hm, nil, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{}, zap.NewNop())
 
 	// prepare host info
 	hi := HostInfo{
@@ -65,3 +74,418 @@ func TestRefreshUploaders(t *testing.T) {
 		t.Fatalf("unexpected number of uploaders, %v != 0", len(ul.uploaders))
 	}
 }
+
+func TestMaxActiveUploaders(t *testing.T) {
+	// prepare three hosts, the one whose contract expires first is the least
+	// promising
+	hosts := []HostInfo{
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{1}},
+			ContractEndHeight: 200,
+			ContractID:        types.FileContractID{1},
+		},
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{2}},
+			ContractEndHeight: 110,
+			ContractID:        types.FileContractID{2},
+		},
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{3}},
+			ContractEndHeight: 190,
+			ContractID:        types.FileContractID{3},
+		},
+	}
+	allowed := make(map[types.PublicKey]struct{})
+	for _, h := range hosts {
+		allowed[h.PublicKey] = struct{}{}
+	}
+
+	assertUploaders := func(ul *Manager, active, idle uint64) {
+		t.Helper()
+		if stats := ul.Stats(); stats.ActiveUploaders != active {
+			t.Fatalf("unexpected number of active uploaders, %v != %v", stats.ActiveUploaders, active)
+		} else if stats.IdleUploaders != idle {
+			t.Fatalf("unexpected number of idle uploaders, %v != %v", stats.IdleUploaders, idle)
+		}
+	}
+
+	// assert all uploaders are active without a cap
+	mm := memory.NewManager(100, zap.NewNop())
+	ul := NewManager(context.Background(), nil, nil, &hostManager{}, mm, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{}, zap.NewNop())
+	ul.refreshUploaders(hosts, 100)
+	assertUploaders(ul, 3, 0)
+
+	// assert the most promising uploaders are active with a cap
+	ul = NewManager(context.Background(), nil, nil, &hostManager{}, mm, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{MaxActiveUploaders: 2}, zap.NewNop())
+	ul.refreshUploaders(hosts, 100)
+	assertUploaders(ul, 2, 1)
+	candidates := ul.candidates(allowed, 100)
+	if len(candidates) != 2 {
+		t.Fatalf("unexpected number of candidates, %v != 2", len(candidates))
+	}
+	for _, c := range candidates {
+		if c.PublicKey() == hosts[1].PublicKey {
+			t.Fatal("expected least promising uploader to be idle")
+		}
+	}
+
+	// assert idle uploaders of allowed hosts are activated if too few of the
+	// active uploaders are allowed
+	restricted := map[types.PublicKey]struct{}{hosts[1].PublicKey: {}, hosts[2].PublicKey: {}}
+	if candidates := ul.candidates(restricted, 100); len(candidates) != 2 {
+		t.Fatalf("unexpected number of candidates, %v != 2", len(candidates))
+	}
+	assertUploaders(ul, 3, 0)
+
+	// assert the uploader that was activated in excess of the cap is made idle
+	// again once it has no more work
+	candidates = ul.candidates(allowed, 100)
+	if len(candidates) != 2 {
+		t.Fatalf("unexpected number of candidates, %v != 2", len(candidates))
+	}
+	assertUploaders(ul, 2, 1)
+	for _, u := range ul.uploaders {
+		if u.PublicKey() != hosts[1].PublicKey {
+			continue
+		}
+		respChan := make(chan uploader.SectorUploadResp, 1)
+		u.Enqueue(uploader.NewUploadRequest(context.Background(), &[rhpv2.SectorSize]byte{}, 0, respChan, types.Hash256{}, false))
+		if resp := <-respChan; !errors.Is(resp.Err, uploader.ErrIdle) {
+			t.Fatalf("expected ErrIdle, got %v", resp.Err)
+		}
+	}
+
+	// assert uploads with more shards than active uploaders are rejected
+	if _, err := ul.newUpload(3, hosts, 100, zap.NewNop().Sugar()); !errors.Is(err, ErrUploadNotEnoughHosts) {
+		t.Fatalf("expected ErrUploadNotEnoughHosts, got %v", err)
+	}
+
+	// stop an active uploader and assert the idle one gets promoted
+	candidates[0].Stop(errors.New("failed"))
+	candidates = ul.candidates(allowed, 100)
+	if len(candidates) != 2 {
+		t.Fatalf("unexpected number of candidates, %v != 2", len(candidates))
+	}
+	assertUploaders(ul, 2, 0)
+}
+
+func TestHostStats(t *testing.T) {
+	ul := NewManager(context.Background(), nil, nil, &hostManager{}, nil, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{}, zap.NewNop())
+	ul.refreshUploaders([]HostInfo{{
+		HostInfo:          api.HostInfo{PublicKey: types.PublicKey{1}},
+		ContractEndHeight: 10,
+		ContractID:        types.FileContractID{1},
+	}}, 0)
+
+	// assert the history of a known host is returned
+	if h, err := ul.HostStats(types.PublicKey{1}); err != nil {
+		t.Fatal(err)
+	} else if h.ContractID != (types.FileContractID{1}) {
+		t.Fatalf("unexpected contract id %v", h.ContractID)
+	}
+
+	// assert an unknown host returns an error
+	if _, err := ul.HostStats(types.PublicKey{2}); !errors.Is(err, ErrUploaderNotFound) {
+		t.Fatalf("expected ErrUploaderNotFound, got %v", err)
+	}
+}
+
+func TestUploadMinFreeMemory(t *testing.T) {
+	mm := memory.NewManager(100, zap.NewNop())
+	ul := NewManager(context.Background(), nil, nil, &hostManager{}, mm, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{MinFreeMemory: 50}, zap.NewNop())
+
+	// acquire memory to drop below the minimum
+	mem := mm.AcquireMemory(context.Background(), 60)
+	defer mem.Release()
+
+	// assert the upload is rejected
+	_, _, _, err := ul.Upload(context.Background(), bytes.NewReader(nil), nil, Parameters{})
+	if !errors.Is(err, api.ErrServerBusy) {
+		t.Fatalf("expected ErrServerBusy, got %v", err)
+	}
+
+	// assert the stats reflect the memory pressure and rejected upload
+	stats := ul.Stats()
+	if stats.MemoryPressure != 0.6 {
+		t.Fatalf("unexpected memory pressure, %v != 0.6", stats.MemoryPressure)
+	} else if stats.RejectedUploads != 1 {
+		t.Fatalf("unexpected rejected uploads, %v != 1", stats.RejectedUploads)
+	}
+}
+
+func TestUploadSlabExceedsMemory(t *testing.T) {
+	mm := memory.NewManager(3*rhpv2.SectorSize, zap.NewNop())
+	ul := NewManager(context.Background(), nil, nil, &hostManager{}, mm, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{}, zap.NewNop())
+
+	// assert slabs that fit in memory are accepted
+	if err := ul.CheckSlabMemory(api.RedundancySettings{MinShards: 1, TotalShards: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the upload is rejected if a slab doesn't fit in memory
+	_, _, _, err := ul.Upload(context.Background(), bytes.NewReader(nil), nil, Parameters{RS: api.RedundancySettings{MinShards: 1, TotalShards: 4}})
+	if !errors.Is(err, ErrSlabExceedsMemory) {
+		t.Fatalf("expected ErrSlabExceedsMemory, got %v", err)
+	}
+}
+
+func TestCandidatesContractDuration(t *testing.T) {
+	// prepare two hosts with equal estimates but different contract durations
+	hosts := []HostInfo{
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{1}},
+			ContractEndHeight: 110,
+			ContractID:        types.FileContractID{1},
+		},
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{2}},
+			ContractEndHeight: 200,
+			ContractID:        types.FileContractID{2},
+		},
+	}
+	allowed := map[types.PublicKey]struct{}{
+		hosts[0].PublicKey: {},
+		hosts[1].PublicKey: {},
+	}
+
+	// assert the weight favours the contract that expires later
+	ul := NewManager(context.Background(), nil, nil, &hostManager{}, nil, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{ContractDurationWeight: 1}, zap.NewNop())
+	ul.refreshUploaders(hosts, 100)
+	candidates := ul.candidates(allowed, 100)
+	if len(candidates) != 2 {
+		t.Fatalf("unexpected number of candidates, %v != 2", len(candidates))
+	} else if candidates[0].PublicKey() != hosts[1].PublicKey {
+		t.Fatalf("expected host with longest remaining duration first, got %v", candidates[0].PublicKey())
+	}
+
+}
+
+func TestCandidatesTolerance(t *testing.T) {
+	// prepare three hosts with equal estimates, the contract duration weight
+	// turns their remaining durations into scores of 1, 1.05 and 1.9
+	hosts := []HostInfo{
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{1}},
+			ContractEndHeight: 200,
+			ContractID:        types.FileContractID{1},
+		},
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{2}},
+			ContractEndHeight: 195,
+			ContractID:        types.FileContractID{2},
+		},
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{3}},
+			ContractEndHeight: 110,
+			ContractID:        types.FileContractID{3},
+		},
+	}
+	allowed := make(map[types.PublicKey]struct{})
+	for _, h := range hosts {
+		allowed[h.PublicKey] = struct{}{}
+	}
+
+	order := func(candidates []*uploader.Uploader) (hks []types.PublicKey) {
+		for _, c := range candidates {
+			hks = append(hks, c.PublicKey())
+		}
+		return
+	}
+
+	// assert the order is deterministic without a tolerance
+	ul := NewManager(context.Background(), nil, nil, &hostManager{}, nil, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{ContractDurationWeight: 1}, zap.NewNop())
+	ul.refreshUploaders(hosts, 100)
+	for i := 0; i < 10; i++ {
+		if hks := order(ul.candidates(allowed, 100)); !reflect.DeepEqual(hks, []types.PublicKey{{1}, {2}, {3}}) {
+			t.Fatal("unexpected order", hks)
+		}
+	}
+
+	// assert the first two hosts are shuffled with a tolerance of 10% but the
+	// third host always comes last
+	ul = NewManager(context.Background(), nil, nil, &hostManager{}, nil, nil, nil, nil, object.DefaultErasureBackend, ManagerConfig{ContractDurationWeight: 1, CandidateTolerance: 0.1}, zap.NewNop())
+	ul.refreshUploaders(hosts, 100)
+	firsts := make(map[types.PublicKey]struct{})
+	for i := 0; i < 100; i++ {
+		hks := order(ul.candidates(allowed, 100))
+		if len(hks) != 3 {
+			t.Fatalf("unexpected number of candidates, %v != 3", len(hks))
+		} else if hks[2] != (types.PublicKey{3}) {
+			t.Fatal("expected slowest host last", hks)
+		}
+		firsts[hks[0]] = struct{}{}
+	}
+	if len(firsts) != 2 {
+		t.Fatal("expected both similarly fast hosts to be picked first", firsts)
+	}
+}
+
+// failingHostManager returns uploaders that fail every sector upload.
+type failingHostManager struct {
+	hostManager
+}
+
+type failingUploader struct {
+	hk types.PublicKey
+}
+
+func (hm *failingHostManager) Uploader(hi api.HostInfo, fcid types.FileContractID) host.Uploader {
+	return failingUploader{hk: hi.PublicKey}
+}
+
+func (u failingUploader) PublicKey() types.PublicKey { return u.hk }
+
+func (u failingUploader) UploadSector(context.Context, types.Hash256, *[rhpv2.SectorSize]byte) error {
+	return errors.New("upload failed")
+}
+
+func TestCandidatesFailures(t *testing.T) {
+	// prepare four hosts, the contract duration weight turns their remaining
+	// durations into scores that order them 1, 2, 4, 3
+	hosts := []HostInfo{
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{1}},
+			ContractEndHeight: 200,
+			ContractID:        types.FileContractID{1},
+		},
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{2}},
+			ContractEndHeight: 190,
+			ContractID:        types.FileContractID{2},
+		},
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{3}},
+			ContractEndHeight: 150,
+			ContractID:        types.FileContractID{3},
+		},
+		{
+			HostInfo:          api.HostInfo{PublicKey: types.PublicKey{4}},
+			ContractEndHeight: 180,
+			ContractID:        types.FileContractID{4},
+		},
+	}
+	allowed := make(map[types.PublicKey]struct{})
+	for _, h := range hosts {
+		allowed[h.PublicKey] = struct{}{}
+	}
+
+	order := func(candidates []*uploader.Uploader) (hks []types.PublicKey) {
+		for _, c := range candidates {
+			hks = append(hks, c.PublicKey())
+		}
+		return
+	}
+
+	// fail uploads to have hosts 1, 2 and 4 fail 2, 1 and 3 times in a row
+	newManager := func(threshold uint64) *Manager {
+		t.Helper()
+		ul := NewManager(context.Background(), nil, nil, &failingHostManager{}, nil, nil, mocks.NewContractLocker(), nil, object.DefaultErasureBackend, ManagerConfig{ContractDurationWeight: 1, CandidateFailureThreshold: threshold}, zap.NewNop())
+		ul.refreshUploaders(hosts, 100)
+
+		failures := map[types.PublicKey]int{{1}: 2, {2}: 1, {4}: 3}
+		for _, u := range ul.uploaders {
+			respChan := make(chan uploader.SectorUploadResp)
+			for i := 0; i < failures[u.PublicKey()]; i++ {
+				u.Enqueue(uploader.NewUploadRequest(context.Background(), new([rhpv2.SectorSize]byte), 0, respChan, types.Hash256{}, false))
+				if resp := <-respChan; resp.Err == nil {
+					t.Fatal("expected upload to fail")
+				}
+			}
+			if n := u.ConsecutiveFailures(); n != uint64(failures[u.PublicKey()]) {
+				t.Fatalf("unexpected number of consecutive failures, %v != %v", n, failures[u.PublicKey()])
+			}
+		}
+		return ul
+	}
+
+	// assert failing hosts are sorted after the healthy ones, a single
+	// failure doesn't deprioritize host 2
+	ul := newManager(2)
+	if hks := order(ul.candidates(allowed, 100)); !reflect.DeepEqual(hks, []types.PublicKey{{2}, {3}, {1}, {4}}) {
+		t.Fatal("unexpected order", hks)
+	}
+
+	// assert a threshold of 0 sorts purely by score
+	ul = newManager(0)
+	if hks := order(ul.candidates(allowed, 100)); !reflect.DeepEqual(hks, []types.PublicKey{{1}, {2}, {4}, {3}}) {
+		t.Fatal("unexpected order", hks)
+	}
+}
+
+func TestOverdrivePerSlabCap(t *testing.T) {
+	// prepare a helper to create a slab upload with 5 sectors that never
+	// finish and plenty of candidates to overdrive them on
+	const numSectors = 5
+	newSlab := func(maxOverdrivePerSlab uint64) (*slabUpload, chan uploader.SectorUploadResp) {
+		respChan := make(chan uploader.SectorUploadResp)
+		slab := &slabUpload{
+			maxOverdrive:        10,
+			maxOverdrivePerSlab: maxOverdrivePerSlab,
+			numSectors:          numSectors,
+			errs:                make(utils.HostErrorSet),
+		}
+		for i := 0; i < numSectors; i++ {
+			slab.sectors = append(slab.sectors, &sectorUpload{index: i, ctx: context.Background()})
+		}
+		for i := 0; i < 30; i++ {
+			ul := uploader.New(context.Background(), nil, nil, nil, nil, api.HostInfo{PublicKey: types.PublicKey{byte(i)}}, types.FileContractID{byte(i)}, 100, 0, 0, 0, zap.NewNop().Sugar())
+			slab.candidates = append(slab.candidates, &candidate{uploader: ul})
+		}
+		for _, s := range slab.sectors {
+			if err := slab.launch(uploader.NewUploadRequest(s.ctx, s.data, s.index, respChan, s.root, false)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return slab, respChan
+	}
+
+	// prepare a helper that overdrives until it's no longer allowed to
+	overdrive := func(slab *slabUpload, respChan chan uploader.SectorUploadResp, maxOverdriving uint64) {
+		t.Helper()
+		for slab.canOverdrive(0) {
+			if err := slab.launch(slab.nextRequest(respChan)); err != nil {
+				t.Fatal(err)
+			} else if maxOverdriving > 0 && slab.numOverdriving > maxOverdriving {
+				t.Fatalf("overdrive count exceeds the cap, %v > %v", slab.numOverdriving, maxOverdriving)
+			}
+		}
+	}
+
+	// without a cap every sector is overdriven up to the max overdrive
+	slab, respChan := newSlab(0)
+	overdrive(slab, respChan, 0)
+	if slab.numOverdriving != 10 {
+		t.Fatalf("unexpected number of overdrives, %v != 10", slab.numOverdriving)
+	}
+
+	// with a cap the slab is limited no matter how many sectors remain
+	slab, respChan = newSlab(2)
+	overdrive(slab, respChan, 2)
+	if slab.numOverdriving != 2 {
+		t.Fatalf("unexpected number of overdrives, %v != 2", slab.numOverdriving)
+	}
+
+	// fail the overdrives permanently and assert the cap still holds when
+	// they are relaunched
+	for i := 0; i < 10; i++ {
+		var req *uploader.SectorUploadReq
+		var hk types.PublicKey
+		for _, c := range slab.candidates {
+			if c.req != nil && c.req.Overdrive && !c.failed {
+				req, hk = c.req, c.uploader.PublicKey()
+				break
+			}
+		}
+		if req == nil {
+			t.Fatal("no overdrive in flight")
+		}
+		slab.receive(uploader.SectorUploadResp{HK: hk, Req: req, Err: errors.New("permanent failure")})
+		if slab.numOverdriving != 1 {
+			t.Fatalf("unexpected number of overdrives, %v != 1", slab.numOverdriving)
+		}
+		overdrive(slab, respChan, 2)
+	}
+	if slab.numOverdriving != 2 {
+		t.Fatalf("unexpected number of overdrives, %v != 2", slab.numOverdriving)
+	}
+}
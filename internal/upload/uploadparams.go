@@ -1,6 +1,10 @@
 package upload
 
 import (
+	"context"
+	"time"
+
+	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/object"
 )
@@ -15,15 +19,34 @@ type Parameters struct {
 
 	EC               object.EncryptionKey
 	EncryptionOffset uint64
+	CustomerKey      *[32]byte
 
 	RS       api.RedundancySettings
 	BH       uint64
 	Packing  bool
 	MimeType string
 
+	ContentDisposition string
+
 	Metadata api.ObjectUserMetadata
+
+	PinnedHosts []types.PublicKey
+
+	Durability string
+
+	SlabDeadline time.Duration
+
+	PersistMaxAttempts int
+
+	ETagBufferSize uint64
+
+	Verify VerifyFunc
 }
 
+// VerifyFunc verifies an uploaded object before it's persisted, eTag is the
+// ETag that was computed while uploading it.
+type VerifyFunc func(ctx context.Context, o object.Object, eTag string) error
+
 func DefaultParameters(bucket, key string, rs api.RedundancySettings) Parameters {
 	return Parameters{
 		Bucket: bucket,
@@ -50,6 +73,16 @@ func WithCustomKey(ec object.EncryptionKey) Option {
 	}
 }
 
+// WithCustomerKey encrypts the object with a key that requires the given
+// customer key to encrypt and decrypt the object, the customer key itself is
+// never persisted.
+func WithCustomerKey(key [32]byte) Option {
+	return func(up *Parameters) {
+		up.EC = object.GenerateCustomerEncryptionKey(key)
+		up.CustomerKey = &key
+	}
+}
+
 func WithCustomEncryptionOffset(offset uint64) Option {
 	return func(up *Parameters) {
 		up.EncryptionOffset = offset
@@ -62,6 +95,12 @@ func WithMimeType(mimeType string) Option {
 	}
 }
 
+func WithContentDisposition(cd string) Option {
+	return func(up *Parameters) {
+		up.ContentDisposition = cd
+	}
+}
+
 func WithPacking(packing bool) Option {
 	return func(up *Parameters) {
 		up.Packing = packing
@@ -81,8 +120,60 @@ func WithUploadID(uploadID string) Option {
 	}
 }
 
+// WithDurability sets the durability mode of the upload, uploads with
+// api.UploadDurabilityAsync return before the object is persisted.
+func WithDurability(durability string) Option {
+	return func(up *Parameters) {
+		up.Durability = durability
+	}
+}
+
+// WithSlabDeadline sets the max amount of time a slab upload may take, if a
+// slab isn't uploaded within the deadline the upload fails with
+// api.ErrSlabUploadDeadlineExceeded.
+func WithSlabDeadline(deadline time.Duration) Option {
+	return func(up *Parameters) {
+		up.SlabDeadline = deadline
+	}
+}
+
+// WithPersistMaxAttempts sets the max number of attempts at persisting the
+// object or part once its data was uploaded to the hosts, a value of 0 or 1
+// disables retries.
+func WithPersistMaxAttempts(attempts int) Option {
+	return func(up *Parameters) {
+		up.PersistMaxAttempts = attempts
+	}
+}
+
+// WithETagBufferSize sets the max number of bytes that are buffered to compute
+// the upload's ETag concurrently with reading and encrypting the data, a value
+// of 0 computes the ETag serially.
+func WithETagBufferSize(size uint64) Option {
+	return func(up *Parameters) {
+		up.ETagBufferSize = size
+	}
+}
+
 func WithObjectUserMetadata(metadata api.ObjectUserMetadata) Option {
 	return func(up *Parameters) {
 		up.Metadata = metadata
 	}
 }
+
+// WithPinnedHosts persists the hosts the upload is restricted to with the
+// object, its slabs are then only migrated to those hosts.
+func WithPinnedHosts(hks []types.PublicKey) Option {
+	return func(up *Parameters) {
+		up.PinnedHosts = hks
+	}
+}
+
+// WithVerification makes the upload manager verify the object using the given
+// function before persisting it, objects that fail verification aren't
+// persisted. Multipart parts aren't verified.
+func WithVerification(fn VerifyFunc) Option {
+	return func(up *Parameters) {
+		up.Verify = fn
+	}
+}
@@ -54,6 +54,37 @@ func (a *DataPoints) P90() float64 {
 	return a.p90
 }
 
+// Percentiles computes the given percentiles of the current data points, unlike
+// P90 it doesn't rely on the last recompute.
+func (a *DataPoints) Percentiles(percentiles ...float64) []float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	res := make([]float64, len(percentiles))
+	for i, p := range percentiles {
+		if v, err := a.Percentile(p); err == nil {
+			res[i] = v
+		}
+	}
+	return res
+}
+
+// Values returns a copy of the current data points, ordered from oldest to
+// newest.
+func (a *DataPoints) Values() []float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	values := make([]float64, 0, len(a.Float64Data))
+	if a.cnt > a.size {
+		values = append(values, a.Float64Data[a.cnt%a.size:]...)
+		values = append(values, a.Float64Data[:a.cnt%a.size]...)
+	} else {
+		values = append(values, a.Float64Data...)
+	}
+	return values
+}
+
 func (a *DataPoints) Recompute() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
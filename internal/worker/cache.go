@@ -2,23 +2,38 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"go.sia.tech/core/types"
 	"go.uber.org/zap"
 
 	"go.sia.tech/renterd/api"
 )
 
+// The keys of the cached entries, the expiry of an entry can be overridden
+// using its key. Download prices are cached per host and share the expiry of
+// their prefix.
 const (
-	cacheKeyUsableHosts = "usablehosts"
+	CacheKeyDownloadPricePrefix = "downloadprice_"
+	CacheKeyUsableHosts         = "usablehosts"
 )
 
 type memoryCache struct {
-	cacheEntryExpiry time.Duration
-	items            map[string]*cacheEntry
-	mu               sync.RWMutex
+	defaultExpiry time.Duration
+	expiries      []expiryOverride
+	items         map[string]*cacheEntry
+	mu            sync.RWMutex
+}
+
+type expiryOverride struct {
+	prefix string
+	expiry time.Duration
 }
 
 type cacheEntry struct {
@@ -26,10 +41,26 @@ type cacheEntry struct {
 	expiry time.Time
 }
 
-func newMemoryCache(expiry time.Duration) *memoryCache {
+func newMemoryCache(defaultExpiry time.Duration, expiries map[string]time.Duration) *memoryCache {
+	// sort the overrides by descending prefix length so the longest matching
+	// prefix wins
+	var overrides []expiryOverride
+	for prefix, expiry := range expiries {
+		if expiry > 0 {
+			overrides = append(overrides, expiryOverride{prefix, expiry})
+		}
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		if len(overrides[i].prefix) != len(overrides[j].prefix) {
+			return len(overrides[i].prefix) > len(overrides[j].prefix)
+		}
+		return overrides[i].prefix < overrides[j].prefix
+	})
+
 	return &memoryCache{
-		cacheEntryExpiry: expiry,
-		items:            make(map[string]*cacheEntry),
+		defaultExpiry: defaultExpiry,
+		expiries:      overrides,
+		items:         make(map[string]*cacheEntry),
 	}
 }
 
@@ -44,7 +75,7 @@ func (c *memoryCache) Get(key string) (value interface{}, found bool, expired bo
 	}
 
 	t := reflect.TypeOf(entry.value)
-	if t.Kind() == reflect.Slice {
+	if t != nil && t.Kind() == reflect.Slice {
 		v := reflect.ValueOf(entry.value)
 		copied := reflect.MakeSlice(t, v.Len(), v.Cap())
 		reflect.Copy(copied, v)
@@ -54,21 +85,41 @@ func (c *memoryCache) Get(key string) (value interface{}, found bool, expired bo
 	return entry.value, true, false
 }
 
+func (c *memoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
+
 func (c *memoryCache) Set(key string, value interface{}) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.items[key] = &cacheEntry{
 		value:  value,
-		expiry: time.Now().Add(c.cacheEntryExpiry),
+		expiry: time.Now().Add(c.expiry(key)),
+	}
+}
+
+// expiry returns the expiry of the entry with the given key, overrides are
+// matched on the key's prefix to cover entries that are cached per host. If
+// multiple prefixes match, the longest one is used.
+func (c *memoryCache) expiry(key string) time.Duration {
+	for _, o := range c.expiries {
+		if strings.HasPrefix(key, o.prefix) {
+			return o.expiry
+		}
 	}
+	return c.defaultExpiry
 }
 
 type (
 	Bus interface {
+		Hosts(ctx context.Context, opts api.HostOptions) ([]api.Host, error)
 		UsableHosts(ctx context.Context) ([]api.HostInfo, error)
 	}
 
 	WorkerCache interface {
+		DownloadPrices(ctx context.Context, hks []types.PublicKey) (map[types.PublicKey]types.Currency, error)
 		UsableHosts(ctx context.Context) ([]api.HostInfo, error)
 	}
 )
@@ -79,24 +130,84 @@ type cache struct {
 	logger *zap.SugaredLogger
 }
 
-func NewCache(b Bus, expiry time.Duration, logger *zap.Logger) WorkerCache {
+// NewCache returns a cache for data the worker fetches from the bus. Entries
+// expire after 'expiry' unless 'expiries' overrides the expiry for their key.
+func NewCache(b Bus, expiry time.Duration, expiries map[string]time.Duration, logger *zap.Logger) WorkerCache {
 	logger = logger.Named("workercache")
 	return &cache{
 		b: b,
 
-		cache:  newMemoryCache(expiry),
+		cache:  newMemoryCache(expiry, expiries),
 		logger: logger.Sugar(),
 	}
 }
 
+// DownloadPrices returns the download bandwidth price per byte for the given
+// hosts. Prices that aren't cached are fetched from the bus in a single request,
+// hosts for which the price can't be fetched are omitted.
+func (c *cache) DownloadPrices(ctx context.Context, hks []types.PublicKey) (map[types.PublicKey]types.Currency, error) {
+	prices := make(map[types.PublicKey]types.Currency, len(hks))
+	var missing []types.PublicKey
+	for _, hk := range hks {
+		key := CacheKeyDownloadPricePrefix + hk.String()
+		value, found, expired := c.cache.Get(key)
+		if found && !expired {
+			if price, ok := value.(types.Currency); ok {
+				prices[hk] = price
+				continue
+			}
+			c.invalidate(key, value)
+		}
+		missing = append(missing, hk)
+	}
+	if len(missing) == 0 {
+		return prices, nil
+	}
+
+	hosts, err := c.b.Hosts(ctx, api.HostOptions{
+		FilterMode: api.HostFilterModeAll,
+		KeyIn:      missing,
+		Limit:      -1,
+	})
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	} else if err != nil {
+		c.logger.Debugw("failed to fetch hosts for download prices", "hosts", len(missing), zap.Error(err))
+		return prices, nil
+	}
+
+	for _, h := range hosts {
+		var price types.Currency
+		if h.IsV2() {
+			price = h.V2Settings.Prices.EgressPrice
+		} else {
+			price = h.PriceTable.DownloadBandwidthCost
+		}
+		c.cache.Set(CacheKeyDownloadPricePrefix+h.PublicKey.String(), price)
+		prices[h.PublicKey] = price
+	}
+	return prices, nil
+}
+
 func (c *cache) UsableHosts(ctx context.Context) (hosts []api.HostInfo, err error) {
-	value, found, expired := c.cache.Get(cacheKeyUsableHosts)
-	if !found || expired {
-		hosts, err = c.b.UsableHosts(ctx)
-		if err == nil {
-			c.cache.Set(cacheKeyUsableHosts, hosts)
+	value, found, expired := c.cache.Get(CacheKeyUsableHosts)
+	if found && !expired {
+		if hosts, ok := value.([]api.HostInfo); ok {
+			return hosts, nil
 		}
-		return
+		c.invalidate(CacheKeyUsableHosts, value)
 	}
-	return value.([]api.HostInfo), nil
+
+	hosts, err = c.b.UsableHosts(ctx)
+	if err == nil {
+		c.cache.Set(CacheKeyUsableHosts, hosts)
+	}
+	return
+}
+
+// invalidate removes an entry that holds a value of an unexpected type from the
+// cache, causing it to be refetched from the bus instead of panicking.
+func (c *cache) invalidate(key string, value interface{}) {
+	c.logger.Warnw("invalidating cache entry of unexpected type", "key", key, "type", fmt.Sprintf("%T", value))
+	c.cache.Delete(key)
 }
@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
+
+type mockBus struct {
+	hosts []api.HostInfo
+	price types.Currency
+
+	hostCalls        int
+	usableHostsCalls int
+}
+
+func (b *mockBus) Hosts(_ context.Context, opts api.HostOptions) (hosts []api.Host, _ error) {
+	b.hostCalls++
+	for _, hk := range opts.KeyIn {
+		h := api.Host{PublicKey: hk}
+		h.PriceTable.DownloadBandwidthCost = b.price
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+func (b *mockBus) UsableHosts(_ context.Context) ([]api.HostInfo, error) {
+	b.usableHostsCalls++
+	return b.hosts, nil
+}
+
+func TestCacheWrongType(t *testing.T) {
+	b := &mockBus{
+		hosts: []api.HostInfo{{PublicKey: types.PublicKey{1}}},
+		price: types.NewCurrency64(1),
+	}
+	c := NewCache(b, time.Minute, nil, zap.NewNop()).(*cache)
+
+	// inject wrong-typed entries
+	hk := types.PublicKey{1}
+	c.cache.Set(CacheKeyUsableHosts, []api.ContractMetadata{{}})
+	c.cache.Set(CacheKeyDownloadPricePrefix+hk.String(), "not a currency")
+
+	// assert the usable hosts are refetched and cached again
+	hosts, err := c.UsableHosts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(hosts) != 1 || hosts[0].PublicKey != hk {
+		t.Fatal("unexpected hosts", hosts)
+	} else if b.usableHostsCalls != 1 {
+		t.Fatal("expected hosts to be fetched from the bus", b.usableHostsCalls)
+	} else if _, err := c.UsableHosts(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if b.usableHostsCalls != 1 {
+		t.Fatal("expected hosts to be cached", b.usableHostsCalls)
+	}
+
+	// assert the download price is refetched and cached again
+	prices, err := c.DownloadPrices(context.Background(), []types.PublicKey{hk})
+	if err != nil {
+		t.Fatal(err)
+	} else if !prices[hk].Equals(b.price) {
+		t.Fatal("unexpected price", prices[hk])
+	} else if b.hostCalls != 1 {
+		t.Fatal("expected host to be fetched from the bus", b.hostCalls)
+	} else if _, err := c.DownloadPrices(context.Background(), []types.PublicKey{hk}); err != nil {
+		t.Fatal(err)
+	} else if b.hostCalls != 1 {
+		t.Fatal("expected price to be cached", b.hostCalls)
+	}
+
+	// assert a nil entry doesn't cause a panic either
+	c.cache.Set(CacheKeyUsableHosts, nil)
+	if _, err := c.UsableHosts(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if b.usableHostsCalls != 2 {
+		t.Fatal("expected hosts to be refetched", b.usableHostsCalls)
+	}
+}
+
+func TestCacheExpiries(t *testing.T) {
+	b := &mockBus{
+		hosts: []api.HostInfo{{PublicKey: types.PublicKey{1}}},
+		price: types.NewCurrency64(1),
+	}
+	expiry := 50 * time.Millisecond
+	c := NewCache(b, time.Minute, map[string]time.Duration{
+		CacheKeyDownloadPricePrefix: expiry,
+	}, zap.NewNop())
+
+	// populate the cache
+	hk := types.PublicKey{1}
+	if _, err := c.UsableHosts(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.DownloadPrices(context.Background(), []types.PublicKey{hk}); err != nil {
+		t.Fatal(err)
+	} else if b.usableHostsCalls != 1 || b.hostCalls != 1 {
+		t.Fatal("unexpected calls", b.usableHostsCalls, b.hostCalls)
+	}
+
+	// assert only the entry with the overridden expiry is refetched once it
+	// expired
+	time.Sleep(expiry)
+	if _, err := c.UsableHosts(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.DownloadPrices(context.Background(), []types.PublicKey{hk}); err != nil {
+		t.Fatal(err)
+	} else if b.usableHostsCalls != 1 {
+		t.Fatal("expected hosts to be cached", b.usableHostsCalls)
+	} else if b.hostCalls != 2 {
+		t.Fatal("expected price to be refetched", b.hostCalls)
+	}
+}
+
+func TestCacheDownloadPricesBatched(t *testing.T) {
+	b := &mockBus{price: types.NewCurrency64(1)}
+	c := NewCache(b, time.Minute, nil, zap.NewNop())
+
+	// assert prices that aren't cached are fetched in a single request
+	hks := []types.PublicKey{{1}, {2}}
+	if prices, err := c.DownloadPrices(context.Background(), hks); err != nil {
+		t.Fatal(err)
+	} else if len(prices) != 2 {
+		t.Fatal("unexpected prices", prices)
+	} else if b.hostCalls != 1 {
+		t.Fatal("expected prices to be fetched in one request", b.hostCalls)
+	}
+
+	// assert only the missing price is fetched
+	hks = append(hks, types.PublicKey{3})
+	if prices, err := c.DownloadPrices(context.Background(), hks); err != nil {
+		t.Fatal(err)
+	} else if len(prices) != 3 {
+		t.Fatal("unexpected prices", prices)
+	} else if b.hostCalls != 2 {
+		t.Fatal("unexpected calls", b.hostCalls)
+	}
+
+	// assert no request is made if all prices are cached
+	if _, err := c.DownloadPrices(context.Background(), hks); err != nil {
+		t.Fatal(err)
+	} else if b.hostCalls != 2 {
+		t.Fatal("expected prices to be cached", b.hostCalls)
+	}
+}
+
+func TestCacheExpiryLongestPrefix(t *testing.T) {
+	c := newMemoryCache(time.Minute, map[string]time.Duration{
+		"a":   time.Second,
+		"ab":  2 * time.Second,
+		"abc": 3 * time.Second,
+		"b":   0,
+	})
+
+	// assert the longest matching prefix wins and disabled overrides fall
+	// back to the default expiry
+	for key, expiry := range map[string]time.Duration{
+		"a_":   time.Second,
+		"ab_":  2 * time.Second,
+		"abc_": 3 * time.Second,
+		"b_":   time.Minute,
+		"c_":   time.Minute,
+	} {
+		if got := c.expiry(key); got != expiry {
+			t.Fatalf("unexpected expiry for key '%s', %v != %v", key, got, expiry)
+		}
+	}
+}
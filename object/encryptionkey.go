@@ -12,6 +12,7 @@ import (
 	"math"
 
 	"go.sia.tech/renterd/internal/utils"
+	"golang.org/x/crypto/blake2b"
 	"golang.org/x/crypto/chacha20"
 	"lukechampine.com/frand"
 )
@@ -32,15 +33,34 @@ var NoOpKey = EncryptionKey{
 var (
 	ErrKeyType     = errors.New("invalid key type")
 	ErrKeyRequired = errors.New("key required")
+
+	// ErrMissingEncryptionKey is returned when a customer key is required to
+	// encrypt or decrypt an object but it wasn't provided or doesn't match
+	// the key the object was uploaded with.
+	ErrMissingEncryptionKey = errors.New("missing or invalid customer encryption key")
+
+	// ErrInvalidEncryptionOffset is returned when encrypting at an offset
+	// that isn't a multiple of EncryptionBlockSize.
+	ErrInvalidEncryptionOffset = errors.New("invalid encryption offset")
 )
 
+// EncryptionBlockSize is the block size of the cipher used to encrypt objects,
+// the offset at which encryption starts has to be a multiple of it.
+const EncryptionBlockSize = 64
+
 type EncryptionKeyType int
 
 const (
 	EncryptionKeyTypeBasic = EncryptionKeyType(iota + 1)
 	EncryptionKeyTypeSalted
+	EncryptionKeyTypeCustomer
 )
 
+// customerKeyFingerprintSize is the number of bytes of a customer key's entropy
+// that hold the fingerprint of the customer key, the remaining bytes are a
+// random salt.
+const customerKeyFingerprintSize = 16
+
 // A EncryptionKey can encrypt and decrypt messages.
 type EncryptionKey struct {
 	entropy *[32]byte
@@ -57,6 +77,21 @@ func GenerateEncryptionKey(t EncryptionKeyType) EncryptionKey {
 	return key
 }
 
+// GenerateCustomerEncryptionKey returns a random encryption key which can only
+// encrypt and decrypt data in combination with the given customer key. The key
+// only contains a fingerprint of the customer key, which means it is safe to
+// persist.
+func GenerateCustomerEncryptionKey(customerKey [32]byte) EncryptionKey {
+	key := EncryptionKey{
+		entropy: new([32]byte),
+		keyType: EncryptionKeyTypeCustomer,
+	}
+	fingerprint := customerKeyFingerprint(customerKey)
+	copy(key.entropy[:customerKeyFingerprintSize], fingerprint[:])
+	frand.Read(key.entropy[customerKeyFingerprintSize:])
+	return key
+}
+
 func (k EncryptionKey) IsNoopKey() bool {
 	return bytes.Equal(k.entropy[:], NoOpKey.entropy[:])
 }
@@ -71,6 +106,8 @@ func (k EncryptionKey) String() string {
 		prefix = "key"
 	case EncryptionKeyTypeSalted:
 		prefix = "skey"
+	case EncryptionKeyTypeCustomer:
+		prefix = "ckey"
 	default:
 		return ""
 	}
@@ -81,6 +118,22 @@ func (k EncryptionKey) Type() EncryptionKeyType {
 	return k.keyType
 }
 
+// VerifyCustomerKey checks whether the given customer key is the one the key
+// was generated with. Keys that don't require a customer key are always
+// considered valid.
+func (k EncryptionKey) VerifyCustomerKey(customerKey *[32]byte) error {
+	if k.keyType != EncryptionKeyTypeCustomer {
+		return nil
+	} else if customerKey == nil {
+		return ErrMissingEncryptionKey
+	}
+	fingerprint := customerKeyFingerprint(*customerKey)
+	if !bytes.Equal(fingerprint[:], k.entropy[:customerKeyFingerprintSize]) {
+		return ErrMissingEncryptionKey
+	}
+	return nil
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler.
 func (k EncryptionKey) MarshalBinary() ([]byte, error) {
 	var b [33]byte
@@ -89,6 +142,8 @@ func (k EncryptionKey) MarshalBinary() ([]byte, error) {
 		b[0] = 1
 	case EncryptionKeyTypeSalted:
 		b[0] = 2
+	case EncryptionKeyTypeCustomer:
+		b[0] = 3
 	default:
 		return nil, ErrKeyType
 	}
@@ -106,6 +161,8 @@ func (k *EncryptionKey) UnmarshalBinary(b []byte) error {
 		k.keyType = EncryptionKeyTypeBasic
 	case 2:
 		k.keyType = EncryptionKeyTypeSalted
+	case 3:
+		k.keyType = EncryptionKeyTypeCustomer
 	default:
 		return ErrKeyType
 	}
@@ -130,6 +187,8 @@ func (k *EncryptionKey) UnmarshalText(b []byte) error {
 		k.keyType = EncryptionKeyTypeBasic
 	case "skey":
 		k.keyType = EncryptionKeyTypeSalted
+	case "ckey":
+		k.keyType = EncryptionKeyTypeCustomer
 	default:
 		return fmt.Errorf("invalid prefix for key: '%s'", splits[0])
 	}
@@ -145,8 +204,9 @@ func (k *EncryptionKey) UnmarshalText(b []byte) error {
 }
 
 type EncryptionOptions struct {
-	Offset uint64
-	Key    *utils.UploadKey
+	Offset      uint64
+	Key         *utils.UploadKey
+	CustomerKey *[32]byte
 }
 
 func (k *EncryptionKey) Encrypt(r io.Reader, opts EncryptionOptions) (cipher.StreamReader, error) {
@@ -158,6 +218,11 @@ func (k *EncryptionKey) Encrypt(r io.Reader, opts EncryptionOptions) (cipher.Str
 			return cipher.StreamReader{}, ErrKeyRequired
 		}
 		return (*encryptionKeySalted)(k).Encrypt(r, opts.Offset, opts.Key)
+	case EncryptionKeyTypeCustomer:
+		if err := k.VerifyCustomerKey(opts.CustomerKey); err != nil {
+			return cipher.StreamReader{}, err
+		}
+		return (*encryptionKeyCustomer)(k).Encrypt(r, opts.Offset, opts.CustomerKey)
 	default:
 		return cipher.StreamReader{}, fmt.Errorf("%w: %v", ErrKeyType, k.keyType)
 	}
@@ -172,6 +237,11 @@ func (k *EncryptionKey) Decrypt(w io.Writer, opts EncryptionOptions) (cipher.Str
 			return cipher.StreamWriter{}, ErrKeyRequired
 		}
 		return (*encryptionKeySalted)(k).Decrypt(w, opts.Offset, opts.Key), nil
+	case EncryptionKeyTypeCustomer:
+		if err := k.VerifyCustomerKey(opts.CustomerKey); err != nil {
+			return cipher.StreamWriter{}, err
+		}
+		return (*encryptionKeyCustomer)(k).Decrypt(w, opts.Offset, opts.CustomerKey), nil
 	default:
 		return cipher.StreamWriter{}, fmt.Errorf("%w: %v", ErrKeyType, k.keyType)
 	}
@@ -197,6 +267,30 @@ func (k *encryptionKeySalted) Decrypt(w io.Writer, offset uint64, key *utils.Upl
 	return decrypt(&derivedKey, w, offset)
 }
 
+type encryptionKeyCustomer EncryptionKey
+
+func (k *encryptionKeyCustomer) Encrypt(r io.Reader, offset uint64, customerKey *[32]byte) (cipher.StreamReader, error) {
+	derivedKey := k.deriveKey(customerKey)
+	return encrypt(&derivedKey, r, offset)
+}
+func (k *encryptionKeyCustomer) Decrypt(w io.Writer, offset uint64, customerKey *[32]byte) cipher.StreamWriter {
+	derivedKey := k.deriveKey(customerKey)
+	return decrypt(&derivedKey, w, offset)
+}
+
+// deriveKey derives the key used to encrypt the data from the customer key and
+// the key's salt, the salt makes sure the same customer key results in a
+// different key for every object.
+func (k *encryptionKeyCustomer) deriveKey(customerKey *[32]byte) [32]byte {
+	return blake2b.Sum256(append(customerKey[:], k.entropy[customerKeyFingerprintSize:]...))
+}
+
+func customerKeyFingerprint(customerKey [32]byte) (fingerprint [customerKeyFingerprintSize]byte) {
+	sum := blake2b.Sum256(append([]byte("customerkeyfingerprint"), customerKey[:]...))
+	copy(fingerprint[:], sum[:])
+	return
+}
+
 type rekeyStream struct {
 	key []byte
 	c   *chacha20.Cipher
@@ -231,11 +325,20 @@ func (noOpStream) XORKeyStream(dst, src []byte) {
 	copy(dst, src)
 }
 
+// ValidateEncryptionOffset returns ErrInvalidEncryptionOffset if encryption
+// can't start at the given offset.
+func ValidateEncryptionOffset(offset uint64) error {
+	if offset%EncryptionBlockSize != 0 {
+		return fmt.Errorf("%w: offset must be a multiple of %d, got %v", ErrInvalidEncryptionOffset, EncryptionBlockSize, offset)
+	}
+	return nil
+}
+
 // Encrypt returns a cipher.StreamReader that encrypts r with k starting at the
 // given offset.
 func encrypt(key *[32]byte, r io.Reader, offset uint64) (cipher.StreamReader, error) {
-	if offset%64 != 0 {
-		return cipher.StreamReader{}, fmt.Errorf("offset must be a multiple of 64, got %v", offset)
+	if err := ValidateEncryptionOffset(offset); err != nil {
+		return cipher.StreamReader{}, err
 	}
 	if bytes.Equal(key[:], NoOpKey.entropy[:]) {
 		return cipher.StreamReader{S: &noOpStream{}, R: r}, nil
@@ -1,8 +1,14 @@
 package object
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"testing"
+
+	"lukechampine.com/frand"
 )
 
 func TestEncryptionKeyJSON(t *testing.T) {
@@ -13,3 +19,94 @@ func TestEncryptionKeyJSON(t *testing.T) {
 		t.Fatal("unexpected JSON:", string(b))
 	}
 }
+
+func TestCustomerEncryptionKey(t *testing.T) {
+	var customerKey, wrongKey [32]byte
+	frand.Read(customerKey[:])
+	frand.Read(wrongKey[:])
+
+	// generate a key and assert it survives a round trip
+	key := GenerateCustomerEncryptionKey(customerKey)
+	b, err := key.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key2 EncryptionKey
+	if err := key2.UnmarshalText([]byte(key.String())); err != nil {
+		t.Fatal(err)
+	} else if key2.String() != key.String() {
+		t.Fatal("key mismatch after text round trip")
+	} else if err := key2.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	} else if key2.String() != key.String() {
+		t.Fatal("key mismatch after binary round trip")
+	}
+
+	// the key shouldn't contain the customer key
+	if bytes.Contains(b, customerKey[:customerKeyFingerprintSize]) {
+		t.Fatal("key contains customer key")
+	}
+
+	// encrypt some data
+	data := frand.Bytes(128)
+	if _, err := key.Encrypt(bytes.NewReader(data), EncryptionOptions{}); !errors.Is(err, ErrMissingEncryptionKey) {
+		t.Fatal("expected ErrMissingEncryptionKey, got", err)
+	}
+	cr, err := key.Encrypt(bytes.NewReader(data), EncryptionOptions{CustomerKey: &customerKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := io.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	} else if bytes.Equal(encrypted, data) {
+		t.Fatal("data wasn't encrypted")
+	}
+
+	// decrypting requires the same customer key
+	var buf bytes.Buffer
+	if _, err := key.Decrypt(&buf, EncryptionOptions{}); !errors.Is(err, ErrMissingEncryptionKey) {
+		t.Fatal("expected ErrMissingEncryptionKey, got", err)
+	} else if _, err := key.Decrypt(&buf, EncryptionOptions{CustomerKey: &wrongKey}); !errors.Is(err, ErrMissingEncryptionKey) {
+		t.Fatal("expected ErrMissingEncryptionKey, got", err)
+	}
+	cw, err := key.Decrypt(&buf, EncryptionOptions{CustomerKey: &customerKey})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := cw.Write(encrypted); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("data mismatch")
+	}
+
+	// the same customer key should result in different keys
+	if GenerateCustomerEncryptionKey(customerKey).String() == key.String() {
+		t.Fatal("expected keys to differ")
+	}
+}
+
+func TestValidateEncryptionOffset(t *testing.T) {
+	slabSize := uint64(10 << 22) // 10 sectors
+	for _, tc := range []struct {
+		offset uint64
+		valid  bool
+	}{
+		{0, true},
+		{1, false},
+		{63, false},
+		{64, true},
+		{65, false},
+		{slabSize - 1, false},
+		{slabSize, true},
+		{slabSize + 64, true},
+		{10 * slabSize, true},
+		{math.MaxUint64, false},
+		{math.MaxUint64 - 63, true},
+	} {
+		if err := ValidateEncryptionOffset(tc.offset); tc.valid && err != nil {
+			t.Fatalf("offset %d: unexpected error %v", tc.offset, err)
+		} else if !tc.valid && !errors.Is(err, ErrInvalidEncryptionOffset) {
+			t.Fatalf("offset %d: expected ErrInvalidEncryptionOffset, got %v", tc.offset, err)
+		}
+	}
+}
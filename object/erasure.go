@@ -0,0 +1,82 @@
+package object
+
+import (
+	"fmt"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+const (
+	// ErasureBackendSIMD is the default Reed-Solomon backend, it uses the SIMD
+	// instructions that are available on the CPU.
+	ErasureBackendSIMD = "simd"
+
+	// ErasureBackendGeneric is a Reed-Solomon backend that doesn't use any
+	// SIMD instructions.
+	ErasureBackendGeneric = "generic"
+)
+
+// DefaultErasureBackend is the erasure backend that is used unless configured
+// otherwise.
+var DefaultErasureBackend = newReedSolomonBackend(ErasureBackendSIMD)
+
+type (
+	// An ErasureCoder erasure-codes the shards of a slab.
+	ErasureCoder interface {
+		Encode(shards [][]byte) error
+		Reconstruct(shards [][]byte) error
+		ReconstructData(shards [][]byte) error
+		ReconstructSome(shards [][]byte, required []bool) error
+	}
+
+	// An ErasureBackend creates erasure coders. All backends must produce
+	// identical shards for the same parameters, otherwise slabs uploaded using
+	// one backend can't be recovered using another.
+	ErasureBackend interface {
+		Name() string
+		New(dataShards, parityShards int) (ErasureCoder, error)
+	}
+)
+
+type reedSolomonBackend struct {
+	name string
+	opts []reedsolomon.Option
+}
+
+// NewErasureBackend returns the erasure backend with the given name, an empty
+// name returns the default backend.
+func NewErasureBackend(name string) (ErasureBackend, error) {
+	switch name {
+	case "":
+		return DefaultErasureBackend, nil
+	case ErasureBackendSIMD, ErasureBackendGeneric:
+		return newReedSolomonBackend(name), nil
+	default:
+		return nil, fmt.Errorf("unknown erasure backend %q", name)
+	}
+}
+
+func newReedSolomonBackend(name string) reedSolomonBackend {
+	var opts []reedsolomon.Option
+	if name == ErasureBackendGeneric {
+		opts = append(opts,
+			reedsolomon.WithSSE2(false),
+			reedsolomon.WithSSSE3(false),
+			reedsolomon.WithAVX2(false),
+			reedsolomon.WithAVX512(false),
+			reedsolomon.WithGFNI(false),
+			reedsolomon.WithAVXGFNI(false),
+		)
+	}
+	return reedSolomonBackend{name: name, opts: opts}
+}
+
+// Name implements ErasureBackend.
+func (b reedSolomonBackend) Name() string {
+	return b.name
+}
+
+// New implements ErasureBackend.
+func (b reedSolomonBackend) New(dataShards, parityShards int) (ErasureCoder, error) {
+	return reedsolomon.New(dataShards, parityShards, b.opts...)
+}
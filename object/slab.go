@@ -2,6 +2,7 @@ package object
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"sync"
 
@@ -87,9 +88,10 @@ func (s Slab) Encrypt(shards [][]byte) {
 	wg.Wait()
 }
 
-// Encode encodes slab data into sector-sized shards. The supplied shards should
-// have a capacity of at least rhpv2.SectorSize, or they will be reallocated.
-func (s Slab) Encode(buf []byte, shards [][]byte) {
+// Encode encodes slab data into sector-sized shards using the given erasure
+// backend. The supplied shards should have a capacity of at least
+// rhpv2.SectorSize, or they will be reallocated.
+func (s Slab) Encode(eb ErasureBackend, buf []byte, shards [][]byte) {
 	for i := range shards {
 		if cap(shards[i]) < rhpv2.SectorSize {
 			shards[i] = make([]byte, 0, rhpv2.SectorSize)
@@ -97,16 +99,18 @@ func (s Slab) Encode(buf []byte, shards [][]byte) {
 		shards[i] = shards[i][:rhpv2.SectorSize]
 	}
 	stripedSplit(buf, shards[:s.MinShards])
-	rsc, _ := reedsolomon.New(int(s.MinShards), len(shards)-int(s.MinShards))
-	if err := rsc.Encode(shards); err != nil {
+	rsc, err := eb.New(int(s.MinShards), len(shards)-int(s.MinShards))
+	if err != nil {
+		panic(err)
+	} else if err := rsc.Encode(shards); err != nil {
 		panic(err)
 	}
 }
 
-// Reconstruct reconstructs the missing shards of a slab. Missing shards must
-// have a len of zero. All shards should have a capacity of at least
-// rhpv2.SectorSize, or they will be reallocated.
-func (s Slab) Reconstruct(shards [][]byte) error {
+// Reconstruct reconstructs the missing shards of a slab using the given erasure
+// backend. Missing shards must have a len of zero. All shards should have a
+// capacity of at least rhpv2.SectorSize, or they will be reallocated.
+func (s Slab) Reconstruct(eb ErasureBackend, shards [][]byte) error {
 	for i := range shards {
 		if len(shards[i]) != rhpv2.SectorSize && len(shards[i]) != 0 {
 			panic("shards must have a len of either 0 or rhpv2.SectorSize")
@@ -119,11 +123,39 @@ func (s Slab) Reconstruct(shards [][]byte) error {
 		}
 	}
 
-	rsc, _ := reedsolomon.New(int(s.MinShards), len(shards)-int(s.MinShards))
-	if err := rsc.Reconstruct(shards); err != nil {
+	rsc, err := eb.New(int(s.MinShards), len(shards)-int(s.MinShards))
+	if err != nil {
 		return err
 	}
-	return nil
+	return rsc.Reconstruct(shards)
+}
+
+// ReconstructSome reconstructs only the shards at the given indices using the
+// given erasure backend, leaving all other missing shards untouched. Missing
+// shards must have a len of zero. The shards to reconstruct should have a
+// capacity of at least rhpv2.SectorSize, or they will be reallocated.
+func (s Slab) ReconstructSome(eb ErasureBackend, shards [][]byte, indices []int) error {
+	required := make([]bool, len(shards))
+	for _, i := range indices {
+		if i < 0 || i >= len(shards) {
+			return fmt.Errorf("shard index %d out of bounds", i)
+		}
+		required[i] = true
+	}
+	for i := range shards {
+		if len(shards[i]) != rhpv2.SectorSize && len(shards[i]) != 0 {
+			panic("shards must have a len of either 0 or rhpv2.SectorSize")
+		}
+		if required[i] && cap(shards[i]) < rhpv2.SectorSize {
+			shards[i] = make([]byte, 0, rhpv2.SectorSize)
+		}
+	}
+
+	rsc, err := eb.New(int(s.MinShards), len(shards)-int(s.MinShards))
+	if err != nil {
+		return err
+	}
+	return rsc.ReconstructSome(shards, required)
 }
 
 // A SlabSlice is a contiguous region within a Slab. Note that the offset and
@@ -166,8 +198,9 @@ func (ss SlabSlice) Decrypt(shards [][]byte) {
 	wg.Wait()
 }
 
-// Recover recovers a slice of slab data from the supplied shards.
-func (ss SlabSlice) Recover(w io.Writer, shards [][]byte) error {
+// Recover recovers a slice of slab data from the supplied shards using the
+// given erasure backend.
+func (ss SlabSlice) Recover(eb ErasureBackend, w io.Writer, shards [][]byte) error {
 	empty := true
 	for _, s := range shards {
 		empty = empty && len(s) == 0
@@ -175,8 +208,10 @@ func (ss SlabSlice) Recover(w io.Writer, shards [][]byte) error {
 	if empty || len(shards) == 0 {
 		return nil
 	}
-	rsc, _ := reedsolomon.New(int(ss.MinShards), len(shards)-int(ss.MinShards))
-	if err := rsc.ReconstructData(shards); err != nil {
+	rsc, err := eb.New(int(ss.MinShards), len(shards)-int(ss.MinShards))
+	if err != nil {
+		return err
+	} else if err := rsc.ReconstructData(shards); err != nil {
 		return err
 	}
 	skip := ss.Offset % (rhpv2.LeafSize * uint32(ss.MinShards))
@@ -12,7 +12,7 @@ import (
 func checkRecover(s Slab, shards [][]byte, data []byte) bool {
 	ss := SlabSlice{s, 0, uint32(len(data))}
 	var buf bytes.Buffer
-	if err := ss.Recover(&buf, shards); err != nil {
+	if err := ss.Recover(DefaultErasureBackend, &buf, shards); err != nil {
 		return false
 	}
 	return bytes.Equal(buf.Bytes(), data)
@@ -23,7 +23,7 @@ func TestReedSolomon(t *testing.T) {
 	s := Slab{MinShards: 3, Shards: make([]Sector, 10)}
 	data := frand.Bytes(rhpv2.SectorSize * 3)
 	shards := make([][]byte, 10)
-	s.Encode(data, shards)
+	s.Encode(DefaultErasureBackend, data, shards)
 
 	// delete 7 random shards
 	partialShards := make([][]byte, len(shards))
@@ -34,7 +34,7 @@ func TestReedSolomon(t *testing.T) {
 		partialShards[i] = nil
 	}
 	// reconstruct
-	if err := s.Reconstruct(partialShards); err != nil {
+	if err := s.Reconstruct(DefaultErasureBackend, partialShards); err != nil {
 		t.Fatal(err)
 	}
 	for i := range shards {
@@ -70,6 +70,80 @@ func TestReedSolomon(t *testing.T) {
 	}
 }
 
+func TestReconstructSome(t *testing.T) {
+	// 3-of-10 code
+	s := Slab{MinShards: 3, Shards: make([]Sector, 10)}
+	data := frand.Bytes(rhpv2.SectorSize * 3)
+	shards := make([][]byte, 10)
+	s.Encode(DefaultErasureBackend, data, shards)
+
+	// keep 3 data shards and the first parity shard
+	partialShards := make([][]byte, len(shards))
+	for _, i := range []int{0, 1, 2, 3} {
+		partialShards[i] = append([]byte(nil), shards[i]...)
+	}
+
+	// reconstruct two of the missing parity shards
+	if err := s.ReconstructSome(DefaultErasureBackend, partialShards, []int{5, 8}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		switch i {
+		case 0, 1, 2, 3, 5, 8:
+			if !bytes.Equal(shards[i], partialShards[i]) {
+				t.Fatalf("shard %d wasn't reconstructed", i)
+			}
+		default:
+			if len(partialShards[i]) != 0 {
+				t.Fatalf("shard %d shouldn't have been reconstructed", i)
+			}
+		}
+	}
+
+	// assert out of bounds indices are rejected
+	if err := s.ReconstructSome(DefaultErasureBackend, partialShards, []int{10}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestErasureBackends(t *testing.T) {
+	generic, err := NewErasureBackend(ErasureBackendGeneric)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewErasureBackend("foo"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// encode the same data using both backends
+	s := Slab{MinShards: 3, Shards: make([]Sector, 10)}
+	data := frand.Bytes(rhpv2.SectorSize * 3)
+	shards := make([][]byte, 10)
+	s.Encode(DefaultErasureBackend, data, shards)
+	genericShards := make([][]byte, 10)
+	s.Encode(generic, data, genericShards)
+
+	// assert the shards are identical
+	for i := range shards {
+		if !bytes.Equal(shards[i], genericShards[i]) {
+			t.Fatalf("shard %d differs between backends", i)
+		}
+	}
+
+	// assert the generic backend can reconstruct the shards
+	partialShards := make([][]byte, len(shards))
+	for _, i := range frand.Perm(len(shards))[:3] {
+		partialShards[i] = append([]byte(nil), shards[i]...)
+	}
+	if err := s.Reconstruct(generic, partialShards); err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		if !bytes.Equal(shards[i], partialShards[i]) {
+			t.Fatalf("failed to reconstruct shard %d", i)
+		}
+	}
+}
+
 func BenchmarkReedSolomon(b *testing.B) {
 	makeSlab := func(m, n uint8) (Slab, []byte, [][]byte) {
 		return Slab{EncryptionKey: GenerateEncryptionKey(EncryptionKeyTypeSalted), MinShards: m, Shards: make([]Sector, n)},
@@ -77,20 +151,20 @@ func BenchmarkReedSolomon(b *testing.B) {
 			make([][]byte, n)
 	}
 
-	benchEncode := func(m, n uint8) func(*testing.B) {
+	benchEncode := func(eb ErasureBackend, m, n uint8) func(*testing.B) {
 		s, data, shards := makeSlab(m, n)
 		return func(b *testing.B) {
 			b.ReportAllocs()
 			b.SetBytes(int64(len(data)))
 			for i := 0; i < b.N; i++ {
-				s.Encode(data, shards)
+				s.Encode(eb, data, shards)
 			}
 		}
 	}
 
-	benchRecover := func(m, n, r uint8) func(*testing.B) {
+	benchRecover := func(eb ErasureBackend, m, n, r uint8) func(*testing.B) {
 		s, data, shards := makeSlab(m, n)
-		s.Encode(data, shards)
+		s.Encode(eb, data, shards)
 		ss := SlabSlice{s, 0, uint32(len(data))}
 		return func(b *testing.B) {
 			b.ReportAllocs()
@@ -99,16 +173,16 @@ func BenchmarkReedSolomon(b *testing.B) {
 				for j := range shards[:r] {
 					shards[j] = shards[j][:0]
 				}
-				if err := ss.Recover(io.Discard, shards); err != nil {
+				if err := ss.Recover(eb, io.Discard, shards); err != nil {
 					b.Fatal(err)
 				}
 			}
 		}
 	}
 
-	benchReconstruct := func(m, n, r uint8) func(*testing.B) {
+	benchReconstruct := func(eb ErasureBackend, m, n, r uint8) func(*testing.B) {
 		s, data, shards := makeSlab(m, n)
-		s.Encode(data, shards)
+		s.Encode(eb, data, shards)
 		return func(b *testing.B) {
 			b.ReportAllocs()
 			b.SetBytes(int64(len(shards[0])) * int64(r))
@@ -116,22 +190,29 @@ func BenchmarkReedSolomon(b *testing.B) {
 				for j := range shards[:r] {
 					shards[j] = shards[j][:0]
 				}
-				if err := s.Reconstruct(shards); err != nil {
+				if err := s.Reconstruct(eb, shards); err != nil {
 					b.Fatal(err)
 				}
 			}
 		}
 	}
 
-	b.Run("encode-10-of-40", benchEncode(10, 40))
-	b.Run("encode-20-of-40", benchEncode(20, 40))
-	b.Run("encode-30-of-40", benchEncode(30, 40))
-	b.Run("encode-10-of-10", benchEncode(10, 10))
+	for _, name := range []string{ErasureBackendSIMD, ErasureBackendGeneric} {
+		eb, err := NewErasureBackend(name)
+		if err != nil {
+			b.Fatal(err)
+		}
 
-	b.Run("recover-1-of-10-of-40", benchRecover(10, 40, 1))
-	b.Run("recover-10-of-10-of-40", benchRecover(10, 40, 10))
-	b.Run("recover-0-of-10-of-10", benchRecover(10, 10, 0))
+		b.Run(eb.Name()+"/encode-10-of-40", benchEncode(eb, 10, 40))
+		b.Run(eb.Name()+"/encode-20-of-40", benchEncode(eb, 20, 40))
+		b.Run(eb.Name()+"/encode-30-of-40", benchEncode(eb, 30, 40))
+		b.Run(eb.Name()+"/encode-10-of-10", benchEncode(eb, 10, 10))
 
-	b.Run("reconstruct-1-of-10-of-40", benchReconstruct(10, 40, 1))
-	b.Run("reconstruct-10-of-10-of-40", benchReconstruct(10, 40, 10))
+		b.Run(eb.Name()+"/recover-1-of-10-of-40", benchRecover(eb, 10, 40, 1))
+		b.Run(eb.Name()+"/recover-10-of-10-of-40", benchRecover(eb, 10, 40, 10))
+		b.Run(eb.Name()+"/recover-0-of-10-of-10", benchRecover(eb, 10, 10, 0))
+
+		b.Run(eb.Name()+"/reconstruct-1-of-10-of-40", benchReconstruct(eb, 10, 40, 1))
+		b.Run(eb.Name()+"/reconstruct-10-of-10-of-40", benchReconstruct(eb, 10, 40, 10))
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -150,7 +151,7 @@ func BenchmarkPruneSlabs(b *testing.B) {
 	err = db.Transaction(context.Background(), func(tx sql.DatabaseTx) error {
 		if err := tx.CreateBucket(context.Background(), testBucket, api.BucketPolicy{}); err != nil {
 			b.Fatal(err)
-		} else if err := tx.InsertObject(context.Background(), testBucket, "foo", obj, "", "", api.ObjectUserMetadata{}); err != nil {
+		} else if err := tx.InsertObject(context.Background(), testBucket, "foo", obj, "", "", "", "", api.ObjectUserMetadata{}, nil); err != nil {
 			b.Fatal(err)
 		}
 		return nil
@@ -181,7 +182,7 @@ func BenchmarkPruneSlabs(b *testing.B) {
 	b.SetBytes(int64(batchSize * slabSize))
 	for i := 0; i < b.N; i++ {
 		if err := db.Transaction(context.Background(), func(tx sql.DatabaseTx) error {
-			pruned, err := tx.PruneSlabs(context.Background(), int64(batchSize))
+			pruned, err := tx.PruneSlabs(context.Background(), 0, math.MaxInt64, int64(batchSize))
 			if err != nil {
 				return err
 			} else if pruned != int64(batchSize) {
@@ -220,7 +221,7 @@ func BenchmarkObjects(b *testing.B) {
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
 			if err := db.Transaction(context.Background(), func(tx sql.DatabaseTx) error {
-				_, err := tx.Objects(context.Background(), bucket, dirs[i%len(dirs)], "", "/", "", "", "", -1, object.EncryptionKey{})
+				_, err := tx.Objects(context.Background(), bucket, dirs[i%len(dirs)], "", "", "/", "", "", "", -1, object.EncryptionKey{}, nil, nil)
 				return err
 			}); err != nil {
 				b.Fatal(err)
@@ -233,7 +234,7 @@ func BenchmarkObjects(b *testing.B) {
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
 			if err := db.Transaction(context.Background(), func(tx sql.DatabaseTx) error {
-				err := tx.RenameObjects(context.Background(), bucket, dirs[frand.Intn(i+1)%len(dirs)], dirs[frand.Intn(i+1)%len(dirs)], true)
+				_, err := tx.RenameObjects(context.Background(), bucket, dirs[frand.Intn(i+1)%len(dirs)], dirs[frand.Intn(i+1)%len(dirs)], true)
 				if err != nil && !errors.Is(err, api.ErrObjectNotFound) {
 					return err
 				}
@@ -245,6 +246,42 @@ func BenchmarkObjects(b *testing.B) {
 	})
 }
 
+// BenchmarkObjectsCacheSize benchmarks listing objects with various SQLite
+// page cache sizes, the cache size is in KiB.
+func BenchmarkObjectsCacheSize(b *testing.B) {
+	for _, cacheSize := range []int64{2 << 10, 64 << 10, 256 << 10} {
+		b.Run(fmt.Sprintf("%dKiB", cacheSize), func(b *testing.B) {
+			conn, err := sqlite.OpenCustom(filepath.Join(b.TempDir(), "db.sqlite"), 0, -cacheSize)
+			if err != nil {
+				b.Fatal(err)
+			}
+			db, err := sqlite.NewMainDatabase(conn, zap.NewNop(), 100*time.Millisecond, 100*time.Millisecond, "")
+			if err != nil {
+				b.Fatal(err)
+			} else if err := db.Migrate(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+
+			bucket := "bucket"
+			dirs, err := insertObjects(db.DB(), bucket, 1e4)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := db.Transaction(context.Background(), func(tx sql.DatabaseTx) error {
+					_, err := tx.Objects(context.Background(), bucket, dirs[i%len(dirs)], "", "", "", "", "", "", 100, object.EncryptionKey{}, nil, nil)
+					return err
+				}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 // BenchmarkPrunableContractRoots benchmarks diffing the roots of a contract
 // with a given set of roots to determine which roots are prunable.
 //
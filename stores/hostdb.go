@@ -104,12 +104,26 @@ func (s *SQLStore) HostBlocklist(ctx context.Context) (blocklist []string, err e
 	return
 }
 
+func (s *SQLStore) HostVerificationStats(ctx context.Context) (stats []api.HostVerificationStats, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		stats, err = tx.HostVerificationStats(ctx)
+		return err
+	})
+	return
+}
+
 func (s *SQLStore) RecordHostScans(ctx context.Context, scans []api.HostScan) error {
 	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
 		return tx.RecordHostScans(ctx, scans)
 	})
 }
 
+func (s *SQLStore) RecordHostVerifications(ctx context.Context, verifications []api.HostVerification) error {
+	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		return tx.RecordHostVerifications(ctx, verifications)
+	})
+}
+
 func (s *SQLStore) UsableHosts(ctx context.Context) (hosts []sql.HostInfo, err error) {
 	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
 		hosts, err = tx.UsableHosts(ctx)
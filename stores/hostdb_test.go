@@ -636,8 +636,11 @@ func TestRecordScan(t *testing.T) {
 	}
 	if host.Interactions.LastScan.UnixMilli() != thirdScanTime.UnixMilli() {
 		t.Fatal("wrong time")
+	} else if host.Interactions.LastFailedInteraction.UnixMilli() != thirdScanTime.UnixMilli() {
+		t.Fatal("wrong last failed interaction", host.Interactions.LastFailedInteraction)
 	}
 	host.Interactions.LastScan = time.Time{}
+	host.Interactions.LastFailedInteraction = time.Time{}
 	downtime += thirdScanTime.Sub(secondScanTime)
 	if host.Interactions != (api.HostInteractions{
 		TotalScans:              3,
@@ -653,6 +656,63 @@ func TestRecordScan(t *testing.T) {
 	}
 }
 
+func TestHostVerificationStats(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+	ctx := context.Background()
+
+	// assert there are no stats initially
+	if stats, err := ss.HostVerificationStats(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(stats) != 0 {
+		t.Fatal("expected no stats", len(stats))
+	}
+
+	// record verifications for two hosts and one unknown host
+	hks, err := ss.addTestHosts(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hk1, hk2 := hks[0], hks[1]
+	if hk2.String() < hk1.String() {
+		hk1, hk2 = hk2, hk1
+	}
+	now := time.Now().Round(time.Millisecond)
+	if err := ss.RecordHostVerifications(ctx, []api.HostVerification{
+		{HostKey: hk1, Timestamp: now},
+		{HostKey: hk1, Timestamp: now},
+		{HostKey: hk1, Error: "invalid proof", Timestamp: now},
+		{HostKey: hk2, Error: "timeout", Timestamp: now},
+		{HostKey: types.PublicKey{9}, Timestamp: now},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a later success accumulates but keeps the last error
+	if err := ss.RecordHostVerifications(ctx, []api.HostVerification{
+		{HostKey: hk1, Timestamp: now.Add(time.Second)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := ss.HostVerificationStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(stats) != 2 {
+		t.Fatal("expected 2 hosts", len(stats))
+	} else if stats[0].HostKey != hk1 || stats[1].HostKey != hk2 {
+		t.Fatal("unexpected order", stats)
+	}
+	if s := stats[0]; s.Successes != 3 || s.Failures != 1 || s.SuccessRate != 0.75 || s.LastError != "invalid proof" {
+		t.Fatalf("unexpected stats for hk1: %+v", s)
+	} else if !time.Time(s.LastVerified).Equal(now.Add(time.Second)) {
+		t.Fatal("unexpected last verified", s.LastVerified)
+	}
+	if s := stats[1]; s.Successes != 0 || s.Failures != 1 || s.SuccessRate != 0 || s.LastError != "timeout" {
+		t.Fatalf("unexpected stats for hk2: %+v", s)
+	}
+}
+
 func TestRemoveHosts(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
 	defer ss.Close()
@@ -1269,6 +1329,8 @@ func newTestHostCheck() api.HostChecks {
 			NotAcceptingContracts: false,
 			NotAnnounced:          false,
 			NotCompletingScan:     false,
+			StaleAnnouncement:     false,
+			RecentlyFailed:        false,
 		},
 	}
 }
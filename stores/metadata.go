@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"sync"
 	"time"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/alerts"
 	"go.sia.tech/renterd/api"
+	isql "go.sia.tech/renterd/internal/sql"
 	"go.sia.tech/renterd/object"
 	sql "go.sia.tech/renterd/stores/sql"
 	"go.uber.org/zap"
@@ -37,13 +39,74 @@ const (
 	// we prune host sectors.
 	hostSectorPruningBatchSize = 10000
 
+	// contractSectorsRelinkBatchSize is the number of contract sectors per
+	// batch when we relink the sectors of a renewed contract to its renewal.
+	contractSectorsRelinkBatchSize = 10000
+
+	// tombstonePruningBatchSize is the number of rows per batch when we prune
+	// objects that were deleted asynchronously. Slices are deleted before
+	// their objects so that deleting a large object doesn't result in a
+	// single, very large transaction.
+	tombstonePruningBatchSize = 1000
+
+	// idempotencyKeyTTL is the amount of time an idempotency key of an added
+	// object is remembered, after which it can be reused.
+	idempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyKeyPruneBatchSize is the number of keys per batch when we
+	// prune idempotency keys that expired.
+	idempotencyKeyPruneBatchSize = 10000
+
+	// idempotencyKeyPruneInterval is the interval at which we prune
+	// idempotency keys that expired.
+	idempotencyKeyPruneInterval = time.Hour
+
+	// objectExpiryBatchSize is the number of objects per batch when we mark
+	// objects that expired according to their bucket's lifecycle rules as
+	// deleted.
+	objectExpiryBatchSize = 1000
+
+	// objectExpiryInterval is the interval at which we check for objects that
+	// expired according to their bucket's lifecycle rules.
+	objectExpiryInterval = time.Hour
+
+	// objectEventPruneBatchSize is the number of events per batch when we
+	// prune events that are older than the retention of the object event log.
+	objectEventPruneBatchSize = 10000
+
+	// objectEventPruneInterval is the interval at which we prune events that
+	// are older than the retention of the object event log.
+	objectEventPruneInterval = time.Hour
+
+	// objectEventVisibilityDelay is the amount of time an event has to be in
+	// the object event log before it's returned to consumers. Event IDs are
+	// assigned on insert but only become visible once their transaction
+	// commits, so an event can become visible after one with a higher ID.
+	// Consumers page through the log by ID and would skip such an event, the
+	// delay gives transactions time to commit before their events are served.
+	objectEventVisibilityDelay = 10 * time.Second
+
 	refreshHealthMinHealthValidity = 12 * time.Hour
 	refreshHealthMaxHealthValidity = 72 * time.Hour
 )
 
+// The following operations are tracked when it comes to transaction retries
+// due to contention.
+const (
+	opDeleteObjects   = "DeleteObjects"
+	opExpireObjects   = "ExpireObjects"
+	opInsertObject    = "InsertObject"
+	opPruneSlabs      = "PruneSlabs"
+	opPruneTombstones = "PruneTombstones"
+	opRenameObjects   = "RenameObjects"
+)
+
 var (
+	expireObjectsAlertID    = frand.Entropy256()
 	pruneHostSectorsAlertID = frand.Entropy256()
 	pruneSlabsAlertID       = frand.Entropy256()
+	pruneTombstonesAlertID  = frand.Entropy256()
+	slabKeyCollisionAlertID = frand.Entropy256()
 )
 
 var objectDeleteBatchSizes = []int64{10, 50, 100, 200, 500, 1000, 5000, 10000, 50000, 100000}
@@ -70,16 +133,65 @@ func (s *SQLStore) CreateBucket(ctx context.Context, bucket string, policy api.B
 	})
 }
 
+// BucketLifecycleRules returns the lifecycle rules of the given bucket.
+func (s *SQLStore) BucketLifecycleRules(ctx context.Context, bucket string) (rules []api.BucketLifecycleRule, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+		rules, err = tx.BucketLifecycleRules(ctx, bucket)
+		return
+	})
+	return
+}
+
+// UpdateBucketLifecycleRules replaces the lifecycle rules of the given bucket.
+// Objects that expire according to the rules are deleted in the background.
+func (s *SQLStore) UpdateBucketLifecycleRules(ctx context.Context, bucket string, rules []api.BucketLifecycleRule) error {
+	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		return tx.UpdateBucketLifecycleRules(ctx, bucket, rules)
+	})
+}
+
 func (s *SQLStore) UpdateBucketPolicy(ctx context.Context, bucket string, policy api.BucketPolicy) error {
 	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
 		return tx.UpdateBucketPolicy(ctx, bucket, policy)
 	})
 }
 
-func (s *SQLStore) DeleteBucket(ctx context.Context, bucket string) error {
-	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+// DeleteBucket deletes the bucket with the given name. If force is set, all
+// objects in the bucket are removed first, otherwise deleting a bucket that
+// isn't empty fails with api.ErrBucketNotEmpty. Forcing the deletion isn't
+// atomic, writes to the bucket aren't blocked while its objects are removed so
+// an object that is added in the meantime makes the deletion fail with
+// api.ErrBucketNotEmpty.
+func (s *SQLStore) DeleteBucket(ctx context.Context, bucket string, force bool) error {
+	// the objects are removed in batches, if that fails halfway the bucket
+	// is left partially emptied but still exists since the bucket itself is
+	// only deleted once it's empty
+	if force {
+		if _, err := s.removeObjects(ctx, bucket, "", 0); err != nil {
+			return err
+		}
+	}
+	err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
 		return tx.DeleteBucket(ctx, bucket)
 	})
+	if err != nil {
+		return err
+	}
+
+	// deleting the bucket also deletes objects that were pending deletion
+	s.triggerSlabPruning()
+	return nil
+}
+
+// ObjectsNoSlabs returns a batch of objects in the given bucket that don't
+// reference any slabs. Empty objects are expected to have no slabs, objects
+// with a size are missing their data.
+func (s *SQLStore) ObjectsNoSlabs(ctx context.Context, bucket, marker string, limit int, missingDataOnly bool) (resp api.ObjectsNoSlabsResponse, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		resp, err = tx.ObjectsNoSlabs(ctx, bucket, marker, limit, missingDataOnly)
+		return err
+	})
+	return
 }
 
 // ObjectsStats returns some info related to the objects stored in the store. To
@@ -93,10 +205,31 @@ func (s *SQLStore) ObjectsStats(ctx context.Context, opts api.ObjectsStatsOpts)
 	return resp, err
 }
 
+// SlabsStats returns the number of uploaded slabs per health range.
+func (s *SQLStore) SlabsStats(ctx context.Context) (resp api.SlabsStatsResponse, _ error) {
+	err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+		resp, err = tx.SlabsStats(ctx)
+		return
+	})
+	return resp, err
+}
+
 func (s *SQLStore) SlabBuffers(ctx context.Context) ([]api.SlabBuffer, error) {
 	return s.slabBufferMgr.SlabBuffers(), nil
 }
 
+// SlabBuffersStats returns stats about the slab buffers and their disk usage.
+func (s *SQLStore) SlabBuffersStats(ctx context.Context) (api.SlabBuffersStatsResponse, error) {
+	return s.slabBufferMgr.Stats(), nil
+}
+
+// MarkSlabBuffersComplete marks all slab buffers as complete, regardless of how
+// full they are, which makes them available for upload.
+func (s *SQLStore) MarkSlabBuffersComplete(ctx context.Context) error {
+	s.slabBufferMgr.MarkAllComplete()
+	return nil
+}
+
 func (s *SQLStore) AddRenewal(ctx context.Context, c api.ContractMetadata) error {
 	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
 		// fetch renewed contract
@@ -160,6 +293,64 @@ func (s *SQLStore) ArchiveContracts(ctx context.Context, toArchive map[types.Fil
 	return nil
 }
 
+// OffboardContract marks the contract with the given id as bad. The slabs
+// that had sectors stored on the contract are remembered and their health is
+// invalidated, causing them to be migrated to other hosts. The contract is
+// archived by RefreshHealth once all of them were migrated. The returned
+// progress can be refreshed using ContractOffboarding.
+func (s *SQLStore) OffboardContract(ctx context.Context, id types.FileContractID) (res api.ContractOffboarding, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		if _, err := tx.OffboardContract(ctx, id); err != nil {
+			return err
+		}
+		res, err = tx.ContractOffboarding(ctx, id)
+		return err
+	})
+	if err == nil && res.Archived {
+		s.triggerHostSectorPruning()
+	}
+	return
+}
+
+// ContractOffboarding returns the migration progress of a contract that was
+// offboarded using OffboardContract.
+func (s *SQLStore) ContractOffboarding(ctx context.Context, id types.FileContractID) (res api.ContractOffboarding, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+		res, err = tx.ContractOffboarding(ctx, id)
+		return
+	})
+	return
+}
+
+// RelinkContractSectors links the sectors of renewed contracts to their
+// renewals. The renewals map the id of a renewed contract to the id of the
+// contract it was renewed to. The links are moved in batches, each in its own
+// transaction, and the total number of moved links is returned.
+func (s *SQLStore) RelinkContractSectors(ctx context.Context, renewals map[types.FileContractID]types.FileContractID) (relinked uint64, _ error) {
+	for from, to := range renewals {
+		for {
+			var n int64
+			if err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+				n, err = tx.RelinkContractSectors(ctx, from, to, contractSectorsRelinkBatchSize)
+				return
+			}); err != nil {
+				return relinked, fmt.Errorf("failed to relink sectors of contract %v to %v: %w", from, to, err)
+			}
+			relinked += uint64(n)
+			if n < contractSectorsRelinkBatchSize {
+				break
+			}
+		}
+
+		// the health of the affected slabs depends on the contract the
+		// sectors are linked to
+		if err := s.invalidateSlabHealthByFCID(ctx, []types.FileContractID{to}); err != nil {
+			return relinked, err
+		}
+	}
+	return relinked, nil
+}
+
 func (s *SQLStore) ArchiveAllContracts(ctx context.Context, reason string) error {
 	contracts, err := s.Contracts(ctx, api.ContractsOpts{})
 	if err != nil {
@@ -245,6 +436,10 @@ func (s *SQLStore) RenewedContract(ctx context.Context, renewedFrom types.FileCo
 
 func (s *SQLStore) Object(ctx context.Context, bucket, key string) (obj api.Object, err error) {
 	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		key, err := tx.ResolveObjectKey(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
 		obj, err = tx.Object(ctx, bucket, key)
 		return err
 	})
@@ -328,25 +523,122 @@ func (s *SQLStore) RecordContractSpending(ctx context.Context, records []api.Con
 	return nil
 }
 
-func (s *SQLStore) RenameObject(ctx context.Context, bucket, keyOld, keyNew string, force bool) error {
+func (s *SQLStore) MoveObject(ctx context.Context, srcBucket, dstBucket, key string) error {
 	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
-		err := tx.RenameObject(ctx, bucket, keyOld, keyNew, force)
+		if srcBucket == dstBucket {
+			return tx.MoveObject(ctx, srcBucket, dstBucket, key)
+		} else if err := s.recordObjectEvent(ctx, tx, api.ObjectEventDelete, srcBucket, key, ""); err != nil {
+			return err
+		} else if err := tx.MoveObject(ctx, srcBucket, dstBucket, key); err != nil {
+			return err
+		} else if err := checkBucketQuota(ctx, tx, dstBucket); err != nil {
+			return err
+		}
+		return s.recordObjectEvent(ctx, tx, api.ObjectEventCreate, dstBucket, key, "")
+	})
+}
+
+// ObjectEvents returns up to 'limit' events from the object event log that were
+// recorded after the event with the given marker ID.
+func (s *SQLStore) ObjectEvents(ctx context.Context, marker uint64, limit int) (resp api.ObjectEventsResponse, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		// fetch one more to see if there are more entries, events that were
+		// recorded too recently are held back until their transactions are
+		// guaranteed to have committed
+		events, err := tx.ObjectEvents(ctx, marker, time.Now().Add(-s.objectEventDelay), limit+1)
 		if err != nil {
 			return err
+		} else if len(events) > limit {
+			resp.HasMore = true
+			events = events[:limit]
 		}
-		s.triggerSlabPruning()
+		resp.Events = events
 		return nil
 	})
+	resp.NextMarker = marker
+	if len(resp.Events) > 0 {
+		resp.NextMarker = resp.Events[len(resp.Events)-1].ID
+	}
+	return
+}
+
+// ObjectsMissingChecksum returns up to 'limit' objects without a checksum that
+// have an ID greater than the given marker.
+func (s *SQLStore) ObjectsMissingChecksum(ctx context.Context, marker uint64, limit int) (resp api.ObjectsMissingChecksumResponse, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		// fetch one more to see if there are more entries
+		objects, err := tx.ObjectsMissingChecksum(ctx, marker, limit+1)
+		if err != nil {
+			return err
+		} else if len(objects) > limit {
+			resp.HasMore = true
+			objects = objects[:limit]
+		}
+		resp.Objects = objects
+		return nil
+	})
+	resp.NextMarker = marker
+	if len(resp.Objects) > 0 {
+		resp.NextMarker = resp.Objects[len(resp.Objects)-1].ID
+	}
+	return
+}
+
+// BackfillObjectChecksum sets the checksum of the object with the given ID,
+// objects that already have a checksum are left untouched which makes it safe
+// to call more than once.
+func (s *SQLStore) BackfillObjectChecksum(ctx context.Context, id uint64, checksum string) error {
+	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		return tx.BackfillObjectChecksum(ctx, id, checksum)
+	})
+}
+
+func (s *SQLStore) ObjectManifest(ctx context.Context, bucket, marker string, limit int) (entries []api.ObjectManifestEntry, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		entries, err = tx.ObjectManifest(ctx, bucket, marker, limit)
+		return err
+	})
+	return
+}
+
+func (s *SQLStore) PinObject(ctx context.Context, bucket, key string, pinned bool) error {
+	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		return tx.PinObject(ctx, bucket, key, pinned)
+	})
 }
 
-func (s *SQLStore) RenameObjects(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) error {
+func (s *SQLStore) RenameObject(ctx context.Context, bucket, keyOld, keyNew string, force, allowDirCollision bool) error {
 	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
-		if err := tx.RenameObjects(ctx, bucket, prefixOld, prefixNew, force); err != nil {
+		if force && keyOld != keyNew {
+			if err := s.recordObjectEvent(ctx, tx, api.ObjectEventDelete, bucket, keyNew, ""); err != nil {
+				return err
+			}
+		}
+		if err := s.recordObjectEvent(ctx, tx, api.ObjectEventRename, bucket, keyOld, keyNew); err != nil {
+			return err
+		}
+		err := tx.RenameObject(ctx, bucket, keyOld, keyNew, force, allowDirCollision)
+		if err != nil {
+			return err
+		}
+		s.triggerSlabPruning()
+		return nil
+	})
+}
+
+func (s *SQLStore) RenameObjects(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) (res api.ObjectsRenameResponse, err error) {
+	err = s.db.Transaction(isql.WithOperation(ctx, opRenameObjects), func(tx sql.DatabaseTx) error {
+		if err := s.recordRenameObjectsEvents(ctx, tx, bucket, prefixOld, prefixNew, force); err != nil {
+			return err
+		}
+		res, err = tx.RenameObjects(ctx, bucket, prefixOld, prefixNew, force)
+		if err != nil {
 			return err
 		}
 		s.triggerSlabPruning()
 		return nil
 	})
+	return
 }
 
 func (s *SQLStore) FetchPartialSlab(ctx context.Context, ec object.EncryptionKey, offset, length uint32) ([]byte, error) {
@@ -357,16 +649,55 @@ func (s *SQLStore) AddPartialSlab(ctx context.Context, data []byte, minShards, t
 	return s.slabBufferMgr.AddPartialSlab(ctx, data, minShards, totalShards)
 }
 
-func (s *SQLStore) CopyObject(ctx context.Context, srcBucket, dstBucket, srcPath, dstPath, mimeType string, metadata api.ObjectUserMetadata) (om api.ObjectMetadata, err error) {
+// CopyObject copies an object, the given policy determines what happens if the
+// destination object already exists. It returns whether the object was copied,
+// if the copy was skipped the metadata of the existing object is returned.
+func (s *SQLStore) CopyObject(ctx context.Context, srcBucket, dstBucket, srcPath, dstPath, mimeType, contentDisposition string, metadata api.ObjectUserMetadata, policy string) (om api.ObjectMetadata, copied bool, err error) {
 	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		copied = false
+
+		// check whether the destination exists
+		if policy == api.CopyPolicySkip || policy == api.CopyPolicyFail {
+			dst, err := tx.ObjectMetadata(ctx, dstBucket, dstPath)
+			if err == nil && policy == api.CopyPolicyFail {
+				return fmt.Errorf("%w: %v", api.ErrObjectExists, dstPath)
+			} else if err == nil {
+				om = dst.ObjectMetadata
+				return nil
+			} else if !errors.Is(err, api.ErrObjectNotFound) {
+				return fmt.Errorf("CopyObject: failed to fetch destination object: %w", err)
+			}
+		}
+
+		op := api.ObjectEventUpdate
+		var pinned bool
 		if srcBucket != dstBucket || srcPath != dstPath {
-			_, err = tx.DeleteObject(ctx, dstBucket, dstPath)
+			pinned, err = tx.ObjectPinned(ctx, dstBucket, dstPath)
+			if err != nil {
+				return fmt.Errorf("CopyObject: failed to fetch pinned flag: %w", err)
+			}
+			deleted, err := tx.DeleteObject(ctx, dstBucket, dstPath)
 			if err != nil {
 				return fmt.Errorf("CopyObject: failed to delete object: %w", err)
+			} else if !deleted {
+				op = api.ObjectEventCreate
 			}
 		}
-		om, err = tx.CopyObject(ctx, srcBucket, dstBucket, srcPath, dstPath, mimeType, metadata)
-		return err
+		om, err = tx.CopyObject(ctx, srcBucket, dstBucket, srcPath, dstPath, mimeType, contentDisposition, metadata)
+		if err != nil {
+			return err
+		} else if err := checkBucketQuota(ctx, tx, dstBucket); err != nil {
+			return err
+		}
+
+		// the copy stays pinned if the object it replaced was
+		if pinned {
+			if err := tx.PinObject(ctx, dstBucket, dstPath, true); err != nil {
+				return fmt.Errorf("CopyObject: failed to pin object: %w", err)
+			}
+		}
+		copied = true
+		return s.recordObjectEvent(ctx, tx, op, dstBucket, dstPath, "")
 	})
 	return
 }
@@ -379,56 +710,109 @@ func (s *SQLStore) DeleteHostSector(ctx context.Context, hk types.PublicKey, roo
 	return
 }
 
-func (s *SQLStore) UpdateObject(ctx context.Context, bucket, key, eTag, mimeType string, metadata api.ObjectUserMetadata, o object.Object) error {
-	// Sanity check input.
-	for _, s := range o.Slabs {
-		for i, shard := range s.Shards {
-			// Verify that all hosts have a contract.
-			if len(shard.Contracts) == 0 {
-				return fmt.Errorf("missing hosts for slab %d", i)
-			}
-		}
+// TransactionRetries returns the number of times object related transactions
+// had to be retried due to contention, grouped by operation. High retry rates
+// for a specific operation indicate a hotspot in the database.
+func (s *SQLStore) TransactionRetries() map[string]uint64 {
+	return s.db.TransactionRetries()
+}
+
+func (s *SQLStore) UpdateObject(ctx context.Context, bucket, key, eTag, checksum, mimeType, contentDisposition string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey, o object.Object) error {
+	if err := validateObject(o); err != nil {
+		return err
 	}
 
 	// UpdateObject is ACID.
 	var prune bool
-	err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
-		// Try to delete. We want to get rid of the object and its slices if it
-		// exists.
-		//
-		// NOTE: the object's created_at is currently used as its ModTime, if we
-		// ever stop recreating the object but update it instead we need to take
-		// this into account
-		//
-		// NOTE: the metadata is not deleted because this delete will cascade,
-		// if we stop recreating the object we have to make sure to delete the
-		// object's metadata before trying to recreate it
-		var err error
-		prune, err = tx.DeleteObject(ctx, bucket, key)
+	err := s.db.Transaction(isql.WithOperation(ctx, opInsertObject), func(tx sql.DatabaseTx) (err error) {
+		prune, err = updateObject(ctx, tx, bucket, key, eTag, checksum, mimeType, contentDisposition, metadata, pinnedHosts, o)
 		if err != nil {
-			return fmt.Errorf("UpdateObject: failed to delete object: %w", err)
+			return err
+		} else if err := checkBucketQuota(ctx, tx, bucket); err != nil {
+			return err
 		}
+		return s.recordObjectEvent(ctx, tx, objectEventOp(prune), bucket, key, "")
+	})
+	if err != nil {
+		s.alertOnSlabKeyCollision(err)
+		return err
+	} else if prune {
+		// trigger pruning if we deleted an object
+		s.triggerSlabPruning()
+	}
+	return nil
+}
 
-		// Insert a new object.
-		err = tx.InsertObject(ctx, bucket, key, o, mimeType, eTag, metadata)
+// UpdateObjectIdempotent updates the object like UpdateObject but records the
+// idempotency key together with the hash of the request and returns the ETag
+// of the stored object. Retrying a request with the same key is a no-op that
+// returns the ETag recorded with the key, while reusing the key for a
+// different request fails with api.ErrIdempotencyKeyReused. Keys are forgotten
+// after idempotencyKeyTTL. If unmodifiedSince is set, the update fails with
+// api.ErrObjectModified if the object was stored after that time, the check
+// happens after the key was checked so a retry of a request that succeeded
+// doesn't fail.
+func (s *SQLStore) UpdateObjectIdempotent(ctx context.Context, idempotencyKey string, requestHash types.Hash256, unmodifiedSince time.Time, bucket, key, eTag, checksum, mimeType, contentDisposition string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey, o object.Object) (string, error) {
+	if err := validateObject(o); err != nil {
+		return "", err
+	}
+
+	var prune bool
+	storedETag := eTag
+	err := s.db.Transaction(isql.WithOperation(ctx, opInsertObject), func(tx sql.DatabaseTx) error {
+		// check whether the key was used before
+		usedHash, usedETag, used, err := tx.IdempotencyKey(ctx, idempotencyKey, time.Now().Add(-idempotencyKeyTTL))
 		if err != nil {
-			return fmt.Errorf("failed to insert object: %w", err)
+			return err
+		} else if used && usedHash != requestHash {
+			return api.ErrIdempotencyKeyReused
+		} else if used {
+			storedETag = usedETag
+			return nil // retry of a request that succeeded
 		}
-		return nil
+
+		// check whether the object was modified in the meantime
+		if !unmodifiedSince.IsZero() {
+			existing, err := tx.ObjectMetadata(ctx, bucket, key)
+			if err != nil && !errors.Is(err, api.ErrObjectNotFound) {
+				return err
+			} else if err == nil && existing.ModTime.Std().After(unmodifiedSince) {
+				return api.ErrObjectModified
+			}
+		}
+
+		prune, err = updateObject(ctx, tx, bucket, key, eTag, checksum, mimeType, contentDisposition, metadata, pinnedHosts, o)
+		if err != nil {
+			return err
+		} else if err := checkBucketQuota(ctx, tx, bucket); err != nil {
+			return err
+		} else if err := s.recordObjectEvent(ctx, tx, objectEventOp(prune), bucket, key, ""); err != nil {
+			return err
+		}
+		return tx.InsertIdempotencyKey(ctx, idempotencyKey, requestHash, eTag)
 	})
 	if err != nil {
-		return err
+		s.alertOnSlabKeyCollision(err)
+		return "", err
 	} else if prune {
 		// trigger pruning if we deleted an object
 		s.triggerSlabPruning()
 	}
-	return nil
+	return storedETag, nil
 }
 
 func (s *SQLStore) RemoveObject(ctx context.Context, bucket, key string) error {
 	var prune bool
+	var size int64
 	err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
-		prune, err = tx.DeleteObject(ctx, bucket, key)
+		key, err := tx.ResolveObjectKey(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		if err := s.recordObjectEvent(ctx, tx, api.ObjectEventDelete, bucket, key, ""); err != nil {
+			return err
+		}
+		prune, size, err = tx.DeleteObjectWithSize(ctx, bucket, key)
 		return
 	})
 	if err != nil {
@@ -436,42 +820,96 @@ func (s *SQLStore) RemoveObject(ctx context.Context, bucket, key string) error {
 	} else if !prune {
 		return fmt.Errorf("%w: key: %s", api.ErrObjectNotFound, key)
 	}
+	s.logger.Debugw("removed object", "bucket", bucket, "key", key, "size", size)
 	s.triggerSlabPruning()
 	return nil
 }
 
-func (s *SQLStore) RemoveObjects(ctx context.Context, bucket, prefix string) error {
-	var prune bool
+// RemoveObjectAsync marks the object as deleted and returns immediately. The
+// object's slices are deleted in the background, after which the slabs that
+// are no longer referenced get pruned.
+func (s *SQLStore) RemoveObjectAsync(ctx context.Context, bucket, key string) error {
+	var deleted bool
+	err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+		key, err := tx.ResolveObjectKey(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		if err := s.recordObjectEvent(ctx, tx, api.ObjectEventDelete, bucket, key, ""); err != nil {
+			return err
+		}
+		deleted, err = tx.TombstoneObject(ctx, bucket, key)
+		return
+	})
+	if err != nil {
+		return fmt.Errorf("RemoveObjectAsync: failed to mark object as deleted: %w", err)
+	} else if !deleted {
+		return fmt.Errorf("%w: key: %s", api.ErrObjectNotFound, key)
+	}
+	s.triggerTombstonePruning()
+	return nil
+}
+
+// RemoveObjects deletes all objects with the given prefix and returns the
+// number of deleted objects.
+func (s *SQLStore) RemoveObjects(ctx context.Context, bucket, prefix string) (int64, error) {
+	deleted, err := s.removeObjects(ctx, bucket, prefix, 0)
+	if err != nil {
+		return deleted, err
+	} else if deleted == 0 {
+		return 0, fmt.Errorf("%w: prefix: %s", api.ErrObjectNotFound, prefix)
+	}
+	return deleted, nil
+}
+
+// removeObjects deletes the objects with the given prefix in batches and
+// returns the number of deleted objects. If 'maxDeleted' is non-zero, it stops
+// once that many objects were deleted.
+func (s *SQLStore) removeObjects(ctx context.Context, bucket, prefix string, maxDeleted int64) (total int64, err error) {
+	defer func() {
+		if total > 0 {
+			s.triggerSlabPruning()
+		}
+	}()
+
 	batchSizeIdx := 0
-	for {
+	for maxDeleted == 0 || total < maxDeleted {
+		limit := objectDeleteBatchSizes[batchSizeIdx]
+		if maxDeleted > 0 && maxDeleted-total < limit {
+			limit = maxDeleted - total
+		}
+
 		start := time.Now()
-		var done bool
-		var duration time.Duration
-		if err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
-			deleted, err := tx.DeleteObjects(ctx, bucket, prefix, objectDeleteBatchSizes[batchSizeIdx])
-			if err != nil {
-				return err
+		var deleted int64
+		if err := s.db.Transaction(isql.WithOperation(ctx, opDeleteObjects), func(tx sql.DatabaseTx) (err error) {
+			if s.objectEventLog {
+				deleted, err = s.deleteObjectsWithEvents(ctx, tx, bucket, prefix, limit)
+			} else {
+				deleted, err = tx.DeleteObjects(ctx, bucket, prefix, limit)
 			}
-			prune = prune || deleted
-			done = !deleted
-			return nil
+			return
 		}); err != nil {
-			return fmt.Errorf("failed to delete objects: %w", err)
-		} else if done {
+			return total, fmt.Errorf("failed to delete objects: %w", err)
+		}
+		total += deleted
+		if deleted < limit {
 			break // nothing more to delete
 		}
-		duration = time.Since(start)
 
 		// increase the batch size if deletion was faster than the threshold
-		if duration < batchDurationThreshold && batchSizeIdx < len(objectDeleteBatchSizes)-1 {
+		if time.Since(start) < batchDurationThreshold && batchSizeIdx < len(objectDeleteBatchSizes)-1 {
 			batchSizeIdx++
 		}
 	}
-	if !prune {
-		return fmt.Errorf("%w: prefix: %s", api.ErrObjectNotFound, prefix)
-	}
-	s.triggerSlabPruning()
-	return nil
+	return total, nil
+}
+
+func (s *SQLStore) SampleSectors(ctx context.Context, n int) (samples []api.SectorSample, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		samples, err = tx.SampleSectors(ctx, n)
+		return err
+	})
+	return
 }
 
 func (s *SQLStore) Slab(ctx context.Context, key object.EncryptionKey) (slab object.Slab, err error) {
@@ -501,7 +939,7 @@ func (s *SQLStore) RefreshHealth(ctx context.Context) error {
 		}
 		// check if done
 		if rowsAffected < refreshHealthBatchSize {
-			return nil // done
+			break
 		}
 		select {
 		case <-ctx.Done():
@@ -509,6 +947,30 @@ func (s *SQLStore) RefreshHealth(ctx context.Context) error {
 		case <-time.After(time.Second):
 		}
 	}
+
+	// archive offboarded contracts whose slabs were all migrated
+	var archived int64
+	err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+		archived, err = tx.FinishContractOffboardings(ctx)
+		return
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish contract offboardings: %w", err)
+	} else if archived > 0 {
+		s.triggerHostSectorPruning()
+	}
+	return nil
+}
+
+// SharedSlabs returns up to 'limit' slabs that are referenced by more than
+// 'minObjects' objects. Losing a shared slab affects every object that
+// references it, which makes repairing it more urgent.
+func (s *SQLStore) SharedSlabs(ctx context.Context, minObjects uint64, limit int) (slabs []api.SharedSlab, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		slabs, err = tx.SharedSlabs(ctx, minObjects, limit)
+		return err
+	})
+	return
 }
 
 // SlabsForMigration returns up to 'limit' slabs that do not reach full
@@ -528,6 +990,10 @@ func (s *SQLStore) SlabsForMigration(ctx context.Context, healthCutoff float64,
 // ObjectMetadata returns an object's metadata
 func (s *SQLStore) ObjectMetadata(ctx context.Context, bucket, key string) (obj api.Object, err error) {
 	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		key, err := tx.ResolveObjectKey(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
 		obj, err = tx.ObjectMetadata(ctx, bucket, key)
 		return err
 	})
@@ -583,6 +1049,166 @@ func (s *SQLStore) MarkPackedSlabsUploaded(ctx context.Context, slabs []api.Uplo
 	return nil
 }
 
+// updateObject replaces the object with the given key and returns whether an
+// existing object was deleted in the process.
+func updateObject(ctx context.Context, tx sql.DatabaseTx, bucket, key, eTag, checksum, mimeType, contentDisposition string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey, o object.Object) (bool, error) {
+	// Try to delete. We want to get rid of the object and its slices if it
+	// exists.
+	//
+	// NOTE: the object's created_at is currently used as its ModTime, if we
+	// ever stop recreating the object but update it instead we need to take
+	// this into account
+	//
+	// NOTE: the metadata is not deleted because this delete will cascade,
+	// if we stop recreating the object we have to make sure to delete the
+	// object's metadata before trying to recreate it
+	pinned, err := tx.ObjectPinned(ctx, bucket, key)
+	if err != nil {
+		return false, fmt.Errorf("UpdateObject: failed to fetch pinned flag: %w", err)
+	}
+	prune, err := tx.DeleteObject(ctx, bucket, key)
+	if err != nil {
+		return false, fmt.Errorf("UpdateObject: failed to delete object: %w", err)
+	}
+
+	// Insert a new object.
+	err = tx.InsertObject(ctx, bucket, key, o, mimeType, eTag, checksum, contentDisposition, metadata, pinnedHosts)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert object: %w", err)
+	}
+
+	// The new object stays pinned if the object it replaced was.
+	if pinned {
+		if err := tx.PinObject(ctx, bucket, key, true); err != nil {
+			return false, fmt.Errorf("UpdateObject: failed to pin object: %w", err)
+		}
+	}
+	return prune, nil
+}
+
+// objectEventOp returns the operation of the event recorded when an object is
+// stored, depending on whether an existing object was replaced.
+func objectEventOp(replaced bool) string {
+	if replaced {
+		return api.ObjectEventUpdate
+	}
+	return api.ObjectEventCreate
+}
+
+func newObjectEvent(op string, om api.ObjectMetadata, renamedTo string) api.ObjectEvent {
+	return api.ObjectEvent{
+		Timestamp: api.TimeRFC3339(time.Now()),
+		Operation: op,
+		Bucket:    om.Bucket,
+		Key:       om.Key,
+		RenamedTo: renamedTo,
+		ETag:      om.ETag,
+		Size:      om.Size,
+	}
+}
+
+// checkBucketQuota returns api.ErrBucketQuotaExceeded if the objects in the
+// given bucket exceed its quota. It's called after storing an object so the
+// transaction is rolled back if the object doesn't fit. The bucket's size is
+// updated along with its objects so checking the quota doesn't require summing
+// up the sizes of the bucket's objects.
+func checkBucketQuota(ctx context.Context, tx sql.DatabaseTx, bucket string) error {
+	b, err := tx.Bucket(ctx, bucket)
+	if err != nil {
+		return err
+	} else if b.Policy.Quota == 0 {
+		return nil
+	}
+	size, err := tx.BucketSize(ctx, bucket)
+	if err != nil {
+		return err
+	} else if size > b.Policy.Quota {
+		return fmt.Errorf("%w: %d > %d bytes", api.ErrBucketQuotaExceeded, size, b.Policy.Quota)
+	}
+	return nil
+}
+
+// recordObjectEvent records an event for the object with the given key if the
+// object event log is enabled. The event captures the current state of the
+// object, so deletions and renames have to be recorded before they are applied
+// and creations or updates afterwards.
+func (s *SQLStore) recordObjectEvent(ctx context.Context, tx sql.DatabaseTx, op, bucket, key, renamedTo string) error {
+	if !s.objectEventLog {
+		return nil
+	}
+	obj, err := tx.ObjectMetadata(ctx, bucket, key)
+	if errors.Is(err, api.ErrObjectNotFound) {
+		return nil // nothing to record
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch object for event log: %w", err)
+	}
+	return tx.RecordObjectEvents(ctx, []api.ObjectEvent{newObjectEvent(op, obj.ObjectMetadata, renamedTo)})
+}
+
+// recordRenameObjectsEvents records the events for renaming all objects with
+// the given prefix if the object event log is enabled. When forced, objects
+// that are overwritten by the rename are recorded as deleted.
+func (s *SQLStore) recordRenameObjectsEvents(ctx context.Context, tx sql.DatabaseTx, bucket, prefixOld, prefixNew string, force bool) error {
+	if !s.objectEventLog {
+		return nil
+	}
+	return tx.RecordRenameObjectsEvents(ctx, bucket, prefixOld, prefixNew, force)
+}
+
+// deleteObjectsWithEvents deletes a batch of objects with the given prefix
+// like DeleteObjects but records an event for every deleted object.
+func (s *SQLStore) deleteObjectsWithEvents(ctx context.Context, tx sql.DatabaseTx, bucket, prefix string, limit int64) (int64, error) {
+	res, err := tx.Objects(ctx, bucket, prefix, "", "", "", api.ObjectSortByName, api.SortDirAsc, "", int(limit), object.EncryptionKey{}, nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch objects for event log: %w", err)
+	}
+
+	events := make([]api.ObjectEvent, 0, len(res.Objects))
+	for _, om := range res.Objects {
+		if _, err := tx.DeleteObject(ctx, bucket, om.Key); err != nil {
+			return 0, fmt.Errorf("failed to delete object: %w", err)
+		}
+		events = append(events, newObjectEvent(api.ObjectEventDelete, om, ""))
+	}
+	if err := tx.RecordObjectEvents(ctx, events); err != nil {
+		return 0, err
+	}
+	return int64(len(events)), nil
+}
+
+// validateObject sanity checks an object before it is stored.
+func validateObject(o object.Object) error {
+	for _, s := range o.Slabs {
+		for i, shard := range s.Shards {
+			// Verify that all hosts have a contract.
+			if len(shard.Contracts) == 0 {
+				return fmt.Errorf("missing hosts for slab %d", i)
+			}
+		}
+	}
+	return nil
+}
+
+// alertOnSlabKeyCollision registers an alert if the given error indicates that
+// a slab was inserted with the key of a different slab, this should never
+// happen and points to a bug.
+func (s *SQLStore) alertOnSlabKeyCollision(err error) {
+	if !errors.Is(err, api.ErrSlabKeyCollision) {
+		return
+	}
+	s.logger.Errorw("refused to merge slabs with colliding keys", zap.Error(err))
+	s.alerts.RegisterAlert(s.shutdownCtx, alerts.Alert{
+		ID:        slabKeyCollisionAlertID,
+		Severity:  alerts.SeverityCritical,
+		Message:   "Slab key collision detected",
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"error": err.Error(),
+			"hint":  "A slab was inserted with the key of an existing slab that has different redundancy parameters. The insertion was rejected to avoid corrupting the existing slab, please report this issue.",
+		},
+	})
+}
+
 func (s *SQLStore) pruneHostSectorLoop() {
 	for {
 		select {
@@ -641,45 +1267,294 @@ func (s *SQLStore) pruneSlabsLoop() {
 		}
 
 		// prune slabs
-		pruneSuccess := true
+		pruned, err := s.pruneSlabs(s.shutdownCtx)
+		if err != nil {
+			s.logger.Errorw("slab pruning failed", zap.Error(err))
+			s.alerts.RegisterAlert(s.shutdownCtx, alerts.Alert{
+				ID:        pruneSlabsAlertID,
+				Severity:  alerts.SeverityWarning,
+				Message:   "Failed to prune slabs",
+				Timestamp: time.Now(),
+				Data: map[string]interface{}{
+					"error": err.Error(),
+					"hint":  "This might happen when your database is under a lot of load due to deleting objects rapidly. This alert will disappear the next time slabs are pruned successfully.",
+				},
+			})
+			continue
+		}
+		s.alerts.DismissAlerts(s.shutdownCtx, pruneSlabsAlertID)
+		s.logger.Debugw("pruned slabs", "pruned", pruned)
+
+		// mark the last prune time where both slabs and dirs were pruned
+		s.mu.Lock()
+		s.lastPrunedSlabsAt = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+func (s *SQLStore) expireObjectsLoop() {
+	t := time.NewTicker(objectExpiryInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-s.shutdownCtx.Done():
+			return
+		}
+
+		expired, err := s.expireObjects(s.shutdownCtx)
+		if err != nil {
+			s.logger.Errorw("object expiry failed", zap.Error(err))
+			s.alerts.RegisterAlert(s.shutdownCtx, alerts.Alert{
+				ID:        expireObjectsAlertID,
+				Severity:  alerts.SeverityWarning,
+				Message:   "Failed to delete expired objects",
+				Timestamp: time.Now(),
+				Data: map[string]interface{}{
+					"error": err.Error(),
+					"hint":  "This might happen when your database is under a lot of load. This alert will disappear the next time expired objects are deleted successfully.",
+				},
+			})
+		} else {
+			s.alerts.DismissAlerts(s.shutdownCtx, expireObjectsAlertID)
+		}
+		if expired > 0 {
+			s.logger.Debugw("expired objects", "expired", expired)
+		}
+	}
+}
+
+func (s *SQLStore) pruneObjectEventsLoop() {
+	t := time.NewTicker(objectEventPruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-s.shutdownCtx.Done():
+			return
+		}
+
+		pruned, err := s.pruneObjectEvents(s.shutdownCtx, time.Now().Add(-s.objectEventRetention))
+		if err != nil {
+			s.logger.Errorw("failed to prune object events", zap.Error(err))
+		} else if pruned > 0 {
+			s.logger.Debugw("pruned object events", "pruned", pruned)
+		}
+	}
+}
+
+func (s *SQLStore) pruneIdempotencyKeysLoop() {
+	t := time.NewTicker(idempotencyKeyPruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-s.shutdownCtx.Done():
+			return
+		}
+
+		pruned, err := s.pruneIdempotencyKeys(s.shutdownCtx, time.Now().Add(-idempotencyKeyTTL))
+		if err != nil {
+			s.logger.Errorw("failed to prune idempotency keys", zap.Error(err))
+		} else if pruned > 0 {
+			s.logger.Debugw("pruned idempotency keys", "pruned", pruned)
+		}
+	}
+}
+
+// pruneIdempotencyKeys deletes all idempotency keys that were used before the
+// given time, in batches of idempotencyKeyPruneBatchSize.
+func (s *SQLStore) pruneIdempotencyKeys(ctx context.Context, before time.Time) (pruned int64, _ error) {
+	for {
+		var n int64
+		if err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+			n, err = tx.PruneIdempotencyKeys(ctx, before, idempotencyKeyPruneBatchSize)
+			return
+		}); err != nil {
+			return pruned, err
+		}
+		pruned += n
+		if n < idempotencyKeyPruneBatchSize {
+			return pruned, nil
+		}
+	}
+}
+
+// pruneObjectEvents deletes all events from the object event log that were
+// recorded before the given time, in batches of objectEventPruneBatchSize.
+func (s *SQLStore) pruneObjectEvents(ctx context.Context, before time.Time) (pruned int64, _ error) {
+	for {
+		var n int64
+		if err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+			n, err = tx.PruneObjectEvents(ctx, before, objectEventPruneBatchSize)
+			return
+		}); err != nil {
+			return pruned, err
+		}
+		pruned += n
+		if n < objectEventPruneBatchSize {
+			return pruned, nil
+		}
+	}
+}
+
+// expireObjects marks all objects that expired according to the lifecycle
+// rules of their bucket as deleted, in batches of objectExpiryBatchSize. The
+// marked objects are then deleted by the tombstone pruning loop.
+func (s *SQLStore) expireObjects(ctx context.Context) (expired int64, err error) {
+	defer func() {
+		if expired > 0 {
+			s.triggerTombstonePruning()
+		}
+	}()
+
+	now := time.Now()
+	for {
+		var n int64
+		err = s.db.Transaction(isql.WithOperation(ctx, opExpireObjects), func(tx sql.DatabaseTx) error {
+			objs, err := tx.TombstoneExpiredObjects(ctx, now, objectExpiryBatchSize)
+			if err != nil {
+				return err
+			} else if s.objectEventLog {
+				events := make([]api.ObjectEvent, 0, len(objs))
+				for _, om := range objs {
+					events = append(events, newObjectEvent(api.ObjectEventDelete, om, ""))
+				}
+				if err := tx.RecordObjectEvents(ctx, events); err != nil {
+					return err
+				}
+			}
+			n = int64(len(objs))
+			return nil
+		})
+		if err != nil {
+			return
+		}
+		expired += n
+		if n < objectExpiryBatchSize {
+			return // done
+		}
+	}
+}
+
+func (s *SQLStore) pruneTombstonesLoop() {
+	for {
+		select {
+		case <-s.tombstonePruneSigChan:
+		case <-s.shutdownCtx.Done():
+			return
+		}
+
+		// prune tombstones in batches
+		var pruned int64
 		for {
 			var deleted int64
-			err := s.db.Transaction(s.shutdownCtx, func(dt sql.DatabaseTx) error {
-				var err error
-				deleted, err = dt.PruneSlabs(s.shutdownCtx, slabPruningBatchSize)
-				return err
+			err := s.db.Transaction(isql.WithOperation(s.shutdownCtx, opPruneTombstones), func(tx sql.DatabaseTx) (err error) {
+				deleted, err = tx.PruneObjectTombstones(s.shutdownCtx, tombstonePruningBatchSize)
+				return
 			})
+			pruned += deleted
 			if err != nil {
-				s.logger.Errorw("slab pruning failed", zap.Error(err))
+				s.logger.Errorw("tombstone pruning failed", zap.Error(err))
 				s.alerts.RegisterAlert(s.shutdownCtx, alerts.Alert{
-					ID:        pruneSlabsAlertID,
+					ID:        pruneTombstonesAlertID,
 					Severity:  alerts.SeverityWarning,
-					Message:   "Failed to prune slabs",
+					Message:   "Failed to prune deleted objects",
 					Timestamp: time.Now(),
 					Data: map[string]interface{}{
 						"error": err.Error(),
-						"hint":  "This might happen when your database is under a lot of load due to deleting objects rapidly. This alert will disappear the next time slabs are pruned successfully.",
+						"hint":  "This might happen when your database is under a lot of load due to deleting objects rapidly. This alert will disappear the next time deleted objects are pruned successfully.",
 					},
 				})
-				pruneSuccess = false
-			} else {
-				s.alerts.DismissAlerts(s.shutdownCtx, pruneSlabsAlertID)
+				break
 			}
+			s.alerts.DismissAlerts(s.shutdownCtx, pruneTombstonesAlertID)
 
-			if deleted < slabPruningBatchSize {
+			if deleted < tombstonePruningBatchSize {
 				break // done
 			}
 		}
 
-		// mark the last prune time where both slabs and dirs were pruned
-		if pruneSuccess {
-			s.mu.Lock()
-			s.lastPrunedSlabsAt = time.Now()
-			s.mu.Unlock()
+		// prune the slabs that are no longer referenced
+		if pruned > 0 {
+			s.logger.Debugw("pruned tombstones", "pruned", pruned)
+			s.triggerSlabPruning()
 		}
 	}
 }
 
+// pruneSlabs prunes all unreferenced slabs and returns the number of slabs
+// that were pruned. The slab id range is split into as many contiguous ranges
+// as the configured parallelism and each range is pruned concurrently in its
+// own transactions. Since the ranges don't overlap, the workers never try to
+// delete the same rows.
+func (s *SQLStore) pruneSlabs(ctx context.Context) (int64, error) {
+	var minID, maxID int64
+	if err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+		minID, maxID, err = tx.SlabIDRange(ctx)
+		return
+	}); err != nil {
+		return 0, fmt.Errorf("failed to fetch slab id range: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var pruned int64
+	var errs []error
+	for _, r := range partitionIDRange(minID, maxID, s.slabPruningParallelism) {
+		wg.Add(1)
+		go func(from, to int64) {
+			defer wg.Done()
+			for {
+				var deleted int64
+				err := s.db.Transaction(isql.WithOperation(ctx, opPruneSlabs), func(tx sql.DatabaseTx) (err error) {
+					deleted, err = tx.PruneSlabs(ctx, from, to, slabPruningBatchSize)
+					return
+				})
+
+				mu.Lock()
+				pruned += deleted
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to prune slabs in range [%d, %d): %w", from, to, err))
+				}
+				mu.Unlock()
+
+				if err != nil || deleted < slabPruningBatchSize {
+					return // done
+				}
+			}
+		}(r[0], r[1])
+	}
+	wg.Wait()
+	return pruned, errors.Join(errs...)
+}
+
+// partitionIDRange splits the range [minID, maxID] into at most n contiguous
+// ranges of the form [from, to).
+func partitionIDRange(minID, maxID int64, n int) (ranges [][2]int64) {
+	if n < 1 {
+		n = 1
+	}
+	size := (maxID - minID + 1) / int64(n)
+	if size < 1 {
+		size = 1
+	}
+	for from := minID; from <= maxID; from += size {
+		to := from + size
+		if len(ranges) == n-1 || to > maxID {
+			to = maxID + 1
+		}
+		ranges = append(ranges, [2]int64{from, to})
+		if to > maxID {
+			break
+		}
+	}
+	return
+}
+
 func (s *SQLStore) triggerHostSectorPruning() {
 	select {
 	case s.hostSectorPruneSigChan <- struct{}{}:
@@ -687,6 +1562,13 @@ func (s *SQLStore) triggerHostSectorPruning() {
 	}
 }
 
+func (s *SQLStore) triggerTombstonePruning() {
+	select {
+	case s.tombstonePruneSigChan <- struct{}{}:
+	default:
+	}
+}
+
 func (s *SQLStore) triggerSlabPruning() {
 	select {
 	case s.slabPruneSigChan <- struct{}{}:
@@ -710,9 +1592,9 @@ func (s *SQLStore) invalidateSlabHealthByFCID(ctx context.Context, fcids []types
 	}
 }
 
-func (s *SQLStore) Objects(ctx context.Context, bucket, prefix, substring, delim, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey) (resp api.ObjectsResponse, err error) {
+func (s *SQLStore) Objects(ctx context.Context, bucket, prefix, substring, glob, delim, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey, minHealth, maxHealth *float64) (resp api.ObjectsResponse, err error) {
 	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
-		resp, err = tx.Objects(ctx, bucket, prefix, substring, delim, sortBy, sortDir, marker, limit, slabEncryptionKey)
+		resp, err = tx.Objects(ctx, bucket, prefix, substring, glob, delim, sortBy, sortDir, marker, limit, slabEncryptionKey, minHealth, maxHealth)
 		return err
 	})
 	return
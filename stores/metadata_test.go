@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -17,6 +18,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/alerts"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/config"
 	isql "go.sia.tech/renterd/internal/sql"
@@ -47,7 +49,7 @@ func (s *testSQLStore) InsertSlab(slab object.Slab) {
 			},
 		},
 	}
-	err := s.UpdateObject(context.Background(), testBucket, "/"+hex.EncodeToString(frand.Bytes(16)), "", "", api.ObjectUserMetadata{}, obj)
+	err := s.UpdateObject(context.Background(), testBucket, "/"+hex.EncodeToString(frand.Bytes(16)), "", "", "", "", api.ObjectUserMetadata{}, nil, obj)
 	if err != nil {
 		s.t.Fatal(err)
 	}
@@ -65,16 +67,16 @@ func (s *SQLStore) RemoveObjectBlocking(ctx context.Context, bucket, key string)
 func (s *SQLStore) RemoveObjectsBlocking(ctx context.Context, bucket, prefix string) error {
 	ts := time.Now()
 	time.Sleep(time.Millisecond)
-	if err := s.RemoveObjects(ctx, bucket, prefix); err != nil {
+	if _, err := s.RemoveObjects(ctx, bucket, prefix); err != nil {
 		return err
 	}
 	return s.waitForSlabPruneLoop(ts)
 }
 
-func (s *SQLStore) RenameObjectBlocking(ctx context.Context, bucket, keyOld, keyNew string, force bool) error {
+func (s *SQLStore) RenameObjectBlocking(ctx context.Context, bucket, keyOld, keyNew string, force, allowDirCollision bool) error {
 	ts := time.Now()
 	time.Sleep(time.Millisecond)
-	if err := s.RenameObject(ctx, bucket, keyOld, keyNew, force); err != nil {
+	if err := s.RenameObject(ctx, bucket, keyOld, keyNew, force, allowDirCollision); err != nil {
 		return err
 	}
 	return s.waitForSlabPruneLoop(ts)
@@ -83,7 +85,7 @@ func (s *SQLStore) RenameObjectBlocking(ctx context.Context, bucket, keyOld, key
 func (s *SQLStore) RenameObjectsBlocking(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) error {
 	ts := time.Now()
 	time.Sleep(time.Millisecond)
-	if err := s.RenameObjects(ctx, bucket, prefixOld, prefixNew, force); err != nil {
+	if _, err := s.RenameObjects(ctx, bucket, prefixOld, prefixNew, force); err != nil {
 		return err
 	}
 	return s.waitForSlabPruneLoop(ts)
@@ -96,7 +98,7 @@ func (s *SQLStore) UpdateObjectBlocking(ctx context.Context, bucket, path, eTag,
 		ts = time.Now()
 		time.Sleep(time.Millisecond)
 	}
-	if err := s.UpdateObject(ctx, bucket, path, eTag, mimeType, metadata, o); err != nil {
+	if err := s.UpdateObject(ctx, bucket, path, eTag, "", mimeType, "", metadata, nil, o); err != nil {
 		return err
 	}
 	return s.waitForSlabPruneLoop(ts)
@@ -1062,7 +1064,7 @@ func TestObjectHealth(t *testing.T) {
 	}
 
 	// assert health is returned correctly by ObjectEntries
-	resp, err := ss.Objects(context.Background(), testBucket, "/", "", "", "", "", "", -1, object.EncryptionKey{})
+	resp, err := ss.Objects(context.Background(), testBucket, "/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil)
 	entries := resp.Objects
 	if err != nil {
 		t.Fatal(err)
@@ -1073,7 +1075,7 @@ func TestObjectHealth(t *testing.T) {
 	}
 
 	// assert health is returned correctly by SearchObject
-	resp, err = ss.Objects(context.Background(), testBucket, "/", "foo", "", "", "", "", -1, object.EncryptionKey{})
+	resp, err = ss.Objects(context.Background(), testBucket, "/", "foo", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
@@ -1221,7 +1223,7 @@ func TestObjectsWithDelimiterSlash(t *testing.T) {
 		}
 	}
 	for _, test := range tests {
-		resp, err := ss.Objects(ctx, testBucket, test.path+test.prefix, "", "/", test.sortBy, test.sortDir, "", -1, object.EncryptionKey{})
+		resp, err := ss.Objects(ctx, testBucket, test.path+test.prefix, "", "", "/", test.sortBy, test.sortDir, "", -1, object.EncryptionKey{}, nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1234,7 +1236,7 @@ func TestObjectsWithDelimiterSlash(t *testing.T) {
 
 		var marker string
 		for offset := 0; offset < len(test.want); offset++ {
-			resp, err := ss.Objects(ctx, testBucket, test.path+test.prefix, "", "/", test.sortBy, test.sortDir, marker, 1, object.EncryptionKey{})
+			resp, err := ss.Objects(ctx, testBucket, test.path+test.prefix, "", "", "/", test.sortBy, test.sortDir, marker, 1, object.EncryptionKey{}, nil, nil)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1256,7 +1258,7 @@ func TestObjectsWithDelimiterSlash(t *testing.T) {
 				continue
 			}
 
-			resp, err = ss.Objects(ctx, testBucket, test.path+test.prefix, "", "/", test.sortBy, test.sortDir, test.want[offset].Key, 1, object.EncryptionKey{})
+			resp, err = ss.Objects(ctx, testBucket, test.path+test.prefix, "", "", "/", test.sortBy, test.sortDir, test.want[offset].Key, 1, object.EncryptionKey{}, nil, nil)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1329,7 +1331,7 @@ func TestObjectsExplicitDir(t *testing.T) {
 		}
 	}
 	for _, test := range tests {
-		got, err := ss.Objects(ctx, testBucket, test.path+test.prefix, "", "/", test.sortBy, test.sortDir, "", -1, object.EncryptionKey{})
+		got, err := ss.Objects(ctx, testBucket, test.path+test.prefix, "", "", "/", test.sortBy, test.sortDir, "", -1, object.EncryptionKey{}, nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1388,7 +1390,7 @@ func TestListObjectsSlabEncryptionKey(t *testing.T) {
 	}
 
 	// Fetch the objects by slab.
-	res, err := ss.Objects(context.Background(), "", "", "", "", "", "", "", -1, slab.EncryptionKey)
+	res, err := ss.Objects(context.Background(), "", "", "", "", "", "", "", "", -1, slab.EncryptionKey, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1460,7 +1462,7 @@ func TestObjectsSubstring(t *testing.T) {
 		{"uu", []api.ObjectMetadata{{Key: "/foo/baz/quux", Size: 3, Health: 1}, {Key: "/foo/baz/quuz", Size: 4, Health: 1}, {Key: "/gab/guub", Size: 5, Health: 1}}},
 	}
 	for _, test := range tests {
-		resp, err := ss.Objects(ctx, testBucket, "", test.key, "", "", "", "", -1, object.EncryptionKey{})
+		resp, err := ss.Objects(ctx, testBucket, "", test.key, "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1468,7 +1470,7 @@ func TestObjectsSubstring(t *testing.T) {
 		assertEqual(got, test.want)
 		var marker string
 		for offset := 0; offset < len(test.want); offset++ {
-			if resp, err := ss.Objects(ctx, testBucket, "", test.key, "", "", "", marker, 1, object.EncryptionKey{}); err != nil {
+			if resp, err := ss.Objects(ctx, testBucket, "", test.key, "", "", "", "", marker, 1, object.EncryptionKey{}, nil, nil); err != nil {
 				t.Fatal(err)
 			} else if got := resp.Objects; len(got) != 1 {
 				t.Errorf("\nkey: %v unexpected number of objects, %d != 1", test.key, len(got))
@@ -1481,6 +1483,66 @@ func TestObjectsSubstring(t *testing.T) {
 	}
 }
 
+func TestObjectsGlob(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{
+		"/LOGS/2024-01/error.log",
+		"/logs/2023-12/error.log",
+		"/logs/2024-01/access.log",
+		"/logs/2024-01/error.log",
+		"/logs/2024-02/error.log",
+		"/logs/2024-02/error.log.gz",
+		"/logs/2024-1/error.log",
+		"/logs/a*b",
+		"/other/error.log",
+	} {
+		if err := ss.UpdateObject(ctx, testBucket, key, testETag, "", testMimeType, "", testMetadata, nil, newTestObject(0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		glob   string
+		want   []string
+	}{
+		{"", "/logs/2024-*/error.log", []string{"/logs/2024-01/error.log", "/logs/2024-02/error.log", "/logs/2024-1/error.log"}},
+		{"", "/logs/2024-0?/error.log", []string{"/logs/2024-01/error.log", "/logs/2024-02/error.log"}},
+		{"", "/logs/202[34]-1*/error.log", []string{"/logs/2023-12/error.log", "/logs/2024-1/error.log"}},
+		{"", "/logs/2024-0[1-2]/*.log", []string{"/logs/2024-01/access.log", "/logs/2024-01/error.log", "/logs/2024-02/error.log"}},
+		{"", "/logs/2024-[!1]*", []string{"/logs/2024-01/access.log", "/logs/2024-01/error.log", "/logs/2024-02/error.log", "/logs/2024-02/error.log.gz"}},
+		{"", "/logs/a[*]b", []string{"/logs/a*b"}},
+		{"", "/logs/*.log", []string{"/logs/2023-12/error.log", "/logs/2024-01/access.log", "/logs/2024-01/error.log", "/logs/2024-02/error.log", "/logs/2024-1/error.log"}},
+		{"", "*/error.log", []string{"/LOGS/2024-01/error.log", "/logs/2023-12/error.log", "/logs/2024-01/error.log", "/logs/2024-02/error.log", "/logs/2024-1/error.log", "/other/error.log"}},
+		{"/logs/2024-02/", "*.gz", []string{"/logs/2024-02/error.log.gz"}},
+		{"", "/logs/2025-*", nil},
+	}
+	for _, test := range tests {
+		resp, err := ss.Objects(ctx, testBucket, test.prefix, "", test.glob, "", "", "", "", -1, object.EncryptionKey{}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, obj := range resp.Objects {
+			got = append(got, obj.Key)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("glob %q: unexpected objects %v, want %v", test.glob, got, test.want)
+		}
+	}
+
+	// assert invalid patterns and patterns combined with a delimiter are
+	// rejected
+	if _, err := ss.Objects(ctx, testBucket, "", "", "/logs/[", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); !errors.Is(err, api.ErrInvalidGlob) {
+		t.Fatal("unexpected error", err)
+	} else if _, err := ss.Objects(ctx, testBucket, "/logs/", "", "*", "/", "", "", "", -1, object.EncryptionKey{}, nil, nil); !errors.Is(err, api.ErrUnsupportedDelimiter) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
 // TestSlabsForMigration tests the functionality of SlabsForMigration.
 func TestSlabsForMigration(t *testing.T) {
 	// create db
@@ -1690,6 +1752,92 @@ func TestSlabsForMigrationNegHealth(t *testing.T) {
 	}
 }
 
+func TestSlabsForMigrationPinnedHosts(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+	ctx := context.Background()
+
+	// add two hosts
+	hks, err := ss.addTestHosts(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hk1, hk2 := hks[0], hks[1]
+
+	// add a contract
+	fcids, _, err := ss.addTestContracts(hks[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// add an unhealthy object that is pinned to both hosts
+	obj := object.Object{
+		Key: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
+		Slabs: []object.SlabSlice{
+			{
+				Slab: object.Slab{
+					EncryptionKey: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
+					MinShards:     2,
+					Shards: []object.Sector{
+						newTestShard(hk1, fcids[0], types.Hash256{1}),
+						newTestShard(hk1, fcids[0], types.Hash256{2}),
+					},
+				},
+			},
+		},
+	}
+	if err := ss.UpdateObject(ctx, testBucket, "/foo", testETag, "", testMimeType, "", testMetadata, []types.PublicKey{hk1, hk2, hk1}, obj); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the slab is pinned to both hosts
+	assertPinnedHosts := func(expected ...types.PublicKey) {
+		t.Helper()
+		if err := ss.RefreshHealth(ctx); err != nil {
+			t.Fatal(err)
+		}
+		slabs, err := ss.SlabsForMigration(ctx, 0.99, -1)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(slabs) != 1 {
+			t.Fatalf("unexpected amount of slabs to migrate, %v!=1", len(slabs))
+		}
+		pinned := slabs[0].PinnedHosts
+		sort.Slice(pinned, func(i, j int) bool { return bytes.Compare(pinned[i][:], pinned[j][:]) < 0 })
+		sort.Slice(expected, func(i, j int) bool { return bytes.Compare(expected[i][:], expected[j][:]) < 0 })
+		if !reflect.DeepEqual(pinned, expected) {
+			t.Fatalf("unexpected pinned hosts, %v != %v", pinned, expected)
+		}
+	}
+	assertPinnedHosts(hk1, hk2)
+
+	// copy the object and remove the original, the copy is pinned as well
+	if _, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/foo", "/bar", testMimeType, "", testMetadata, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if err := ss.RemoveObject(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	}
+	assertPinnedHosts(hk1, hk2)
+
+	// overwrite the copy with an unpinned object using the same slab
+	if err := ss.UpdateObject(ctx, testBucket, "/bar", testETag, "", testMimeType, "", testMetadata, nil, obj); err != nil {
+		t.Fatal(err)
+	}
+	assertPinnedHosts()
+
+	// assert the pin of a multipart upload is persisted
+	resp, err := ss.CreateMultipartUpload(ctx, testBucket, "/baz", object.NoOpKey, testMimeType, testMetadata, []types.PublicKey{hk2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu, err := ss.MultipartUpload(ctx, resp.UploadID)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(mu.PinnedHosts, []types.PublicKey{hk2}) {
+		t.Fatalf("unexpected pinned hosts, %v", mu.PinnedHosts)
+	}
+}
+
 func TestUnhealthySlabsNoContracts(t *testing.T) {
 	// create db
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
@@ -1898,6 +2046,253 @@ func TestContractSectors(t *testing.T) {
 	}
 }
 
+// TestRelinkContractSectors verifies sectors of a renewed contract can be
+// linked to its renewal.
+func TestRelinkContractSectors(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add a host with two contracts
+	hk := types.PublicKey{1}
+	if err := ss.addTestHost(hk); err != nil {
+		t.Fatal(err)
+	}
+	fcids, _, err := ss.addTestContracts([]types.PublicKey{hk, hk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewed, renewal := fcids[0], fcids[1]
+
+	// add an object with all of its sectors stored on the renewed contract,
+	// the first sector is also linked to the renewal already
+	obj := newTestObject(1)
+	for i := range obj.Slabs[0].Shards {
+		obj.Slabs[0].Shards[i].Contracts = map[types.PublicKey][]types.FileContractID{hk: {renewed}}
+	}
+	obj.Slabs[0].Shards[0].Contracts[hk] = append(obj.Slabs[0].Shards[0].Contracts[hk], renewal)
+	if _, err := ss.addTestObject(t.Name(), obj); err != nil {
+		t.Fatal(err)
+	}
+	nSectors := len(obj.Slabs[0].Shards)
+
+	assertLinks := func(fcid types.FileContractID, expected int) {
+		t.Helper()
+		var n int
+		if err := ss.DB().QueryRow(context.Background(), `
+			SELECT COUNT(*)
+			FROM contract_sectors cs
+			INNER JOIN contracts c ON c.id = cs.db_contract_id
+			WHERE c.fcid = ?`, sql.FileContractID(fcid)).Scan(&n); err != nil {
+			t.Fatal(err)
+		} else if n != expected {
+			t.Fatalf("expected %v links for contract %v, got %v", expected, fcid, n)
+		}
+	}
+	assertLinks(renewed, nSectors)
+	assertLinks(renewal, 1)
+
+	// relink a single sector
+	var relinked int64
+	if err := ss.db.Transaction(context.Background(), func(tx sql.DatabaseTx) (err error) {
+		relinked, err = tx.RelinkContractSectors(context.Background(), renewed, renewal, 1)
+		return
+	}); err != nil {
+		t.Fatal(err)
+	} else if relinked != 1 {
+		t.Fatalf("expected 1 relinked sector, got %v", relinked)
+	}
+	assertLinks(renewed, nSectors-1)
+
+	// relink the remaining sectors
+	if n, err := ss.RelinkContractSectors(context.Background(), map[types.FileContractID]types.FileContractID{renewed: renewal}); err != nil {
+		t.Fatal(err)
+	} else if n != uint64(nSectors-1) {
+		t.Fatalf("expected %v relinked sectors, got %v", nSectors-1, n)
+	}
+	assertLinks(renewed, 0)
+	assertLinks(renewal, nSectors)
+
+	// relinking again is a no-op
+	if n, err := ss.RelinkContractSectors(context.Background(), map[types.FileContractID]types.FileContractID{renewed: renewal}); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("expected no relinked sectors, got %v", n)
+	}
+
+	// the object's sectors should reference the renewal
+	o, err := ss.Object(context.Background(), testBucket, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sector := range o.Slabs[0].Shards {
+		if fcids := sector.Contracts[hk]; len(fcids) != 1 || fcids[0] != renewal {
+			t.Fatal("unexpected contracts", sector.Contracts)
+		}
+	}
+
+	// unknown contracts
+	if _, err := ss.RelinkContractSectors(context.Background(), map[types.FileContractID]types.FileContractID{{9}: renewal}); !errors.Is(err, api.ErrContractNotFound) {
+		t.Fatal("unexpected error", err)
+	} else if _, err := ss.RelinkContractSectors(context.Background(), map[types.FileContractID]types.FileContractID{renewed: {9}}); !errors.Is(err, api.ErrContractNotFound) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// contracts with another host
+	hk2 := types.PublicKey{2}
+	if err := ss.addTestHost(hk2); err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := ss.addTestContracts([]types.PublicKey{hk2})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.RelinkContractSectors(context.Background(), map[types.FileContractID]types.FileContractID{renewal: other[0]}); err == nil {
+		t.Fatal("expected error")
+	}
+	assertLinks(renewal, nSectors)
+}
+
+func TestOffboardContract(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add three hosts with a contract each
+	hks, err := ss.addTestHosts(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fcids, _, err := ss.addTestContracts(hks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// add an object with two slabs stored on the first two contracts and one
+	// with a single slab only stored on the second contract
+	obj := newTestObject(2)
+	for i := range obj.Slabs {
+		for j := range obj.Slabs[i].Shards {
+			obj.Slabs[i].Shards[j].Contracts = map[types.PublicKey][]types.FileContractID{hks[j%2]: {fcids[j%2]}}
+		}
+	}
+	if _, err := ss.addTestObject("foo", obj); err != nil {
+		t.Fatal(err)
+	}
+	obj = newTestObject(1)
+	for i := range obj.Slabs[0].Shards {
+		obj.Slabs[0].Shards[i].Contracts = map[types.PublicKey][]types.FileContractID{hks[1]: {fcids[1]}}
+	}
+	if _, err := ss.addTestObject("bar", obj); err != nil {
+		t.Fatal(err)
+	}
+
+	// mark the health of all slabs as valid
+	if _, err := ss.DB().Exec(context.Background(), "UPDATE slabs SET health_valid_until = ?", time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	// helper to finish offboardings
+	finish := func() {
+		t.Helper()
+		if err := ss.db.Transaction(context.Background(), func(tx sql.DatabaseTx) error {
+			_, err := tx.FinishContractOffboardings(context.Background())
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// helper to assert the offboarding progress
+	assertOffboarding := func(fcid types.FileContractID, slabs, migrated uint64, archived bool) {
+		t.Helper()
+		if res, err := ss.ContractOffboarding(context.Background(), fcid); err != nil {
+			t.Fatal(err)
+		} else if res.ContractID != fcid || res.Slabs != slabs || res.Migrated != migrated || res.Archived != archived {
+			t.Fatalf("unexpected offboarding %+v", res)
+		}
+	}
+
+	// offboard the first contract
+	res, err := ss.OffboardContract(context.Background(), fcids[0])
+	if err != nil {
+		t.Fatal(err)
+	} else if res.ContractID != fcids[0] || res.Slabs != 2 || res.Migrated != 0 || res.Archived {
+		t.Fatalf("unexpected offboarding %+v", res)
+	}
+
+	// the contract shouldn't be archived yet but be bad, even if its
+	// usability is updated
+	if err := ss.UpdateContractUsability(context.Background(), fcids[0], api.ContractUsabilityGood); err != nil {
+		t.Fatal(err)
+	} else if c, err := ss.Contract(context.Background(), fcids[0]); err != nil {
+		t.Fatal(err)
+	} else if c.Usability != api.ContractUsabilityBad {
+		t.Fatal("unexpected usability", c.Usability)
+	}
+
+	// the affected slabs should have their health invalidated
+	var invalid int
+	if err := ss.DB().QueryRow(context.Background(), "SELECT COUNT(*) FROM slabs WHERE health_valid_until = 0").Scan(&invalid); err != nil {
+		t.Fatal(err)
+	} else if invalid != 2 {
+		t.Fatalf("expected 2 slabs with invalid health, got %v", invalid)
+	}
+
+	// offboarding it again should return its progress
+	if res, err := ss.OffboardContract(context.Background(), fcids[0]); err != nil {
+		t.Fatal(err)
+	} else if res.Slabs != 2 || res.Migrated != 0 {
+		t.Fatalf("unexpected offboarding %+v", res)
+	}
+
+	// mark one slab as repaired, the contract shouldn't be archived
+	if _, err := ss.DB().Exec(context.Background(), `
+		UPDATE slabs
+		SET health = 1, health_valid_until = ?
+		WHERE id = (SELECT MIN(db_slab_id) FROM contract_offboardings)
+	`, time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	}
+	finish()
+	assertOffboarding(fcids[0], 2, 1, false)
+
+	// delete the other one, the contract should be archived and the
+	// offboarding records removed
+	if err := ss.RemoveObject(context.Background(), testBucket, "foo"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.pruneSlabs(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertOffboarding(fcids[0], 2, 2, false)
+	finish()
+	assertOffboarding(fcids[0], 0, 0, true)
+
+	if _, err := ss.Contract(context.Background(), fcids[0]); !errors.Is(err, api.ErrContractNotFound) {
+		t.Fatal("unexpected error", err)
+	}
+	var reason string
+	if err := ss.DB().QueryRow(context.Background(), "SELECT archival_reason FROM contracts WHERE fcid = ?", sql.FileContractID(fcids[0])).Scan(&reason); err != nil {
+		t.Fatal(err)
+	} else if reason != api.ContractArchivalReasonOffboarded {
+		t.Fatal("unexpected archival reason", reason)
+	}
+
+	// a contract can't be offboarded twice
+	if _, err := ss.OffboardContract(context.Background(), fcids[0]); !errors.Is(err, api.ErrContractNotFound) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// a contract without slabs is archived right away
+	if res, err := ss.OffboardContract(context.Background(), fcids[2]); err != nil {
+		t.Fatal(err)
+	} else if res.Slabs != 0 || !res.Archived {
+		t.Fatalf("unexpected offboarding %+v", res)
+	}
+
+	// unknown contracts
+	if _, err := ss.ContractOffboarding(context.Background(), types.FileContractID{9}); !errors.Is(err, api.ErrContractNotFound) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
 // TestUpdateSlab verifies the functionality of UpdateSlab.
 func TestUpdateSlab(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
@@ -2201,7 +2596,7 @@ func TestRenameObjects(t *testing.T) {
 	}
 
 	// Try renaming objects that don't exist.
-	if err := ss.RenameObjectBlocking(ctx, testBucket, "/fileś", "/fileś2", false); !errors.Is(err, api.ErrObjectNotFound) {
+	if err := ss.RenameObjectBlocking(ctx, testBucket, "/fileś", "/fileś2", false, false); !errors.Is(err, api.ErrObjectNotFound) {
 		t.Fatal(err)
 	}
 	if err := ss.RenameObjectsBlocking(ctx, testBucket, "/fileś1", "/fileś2", false); !errors.Is(err, api.ErrObjectNotFound) {
@@ -2215,13 +2610,15 @@ func TestRenameObjects(t *testing.T) {
 	if err := ss.RenameObjectsBlocking(ctx, testBucket, "/fileś/dir/", "/fileś/", false); err != nil {
 		t.Fatal(err)
 	}
-	if err := ss.RenameObjectBlocking(ctx, testBucket, "/foo", "/fileś/foo", false); err != nil {
+	if err := ss.RenameObjectBlocking(ctx, testBucket, "/foo", "/fileś/foo", false, false); !errors.Is(err, api.ErrObjectKeyIsDirectory) {
+		t.Fatal(err)
+	} else if err := ss.RenameObjectBlocking(ctx, testBucket, "/foo", "/fileś/foo", false, true); err != nil {
 		t.Fatal(err)
 	}
-	if err := ss.RenameObjectBlocking(ctx, testBucket, "/bar", "/fileś/bar", false); err != nil {
+	if err := ss.RenameObjectBlocking(ctx, testBucket, "/bar", "/fileś/bar", false, false); err != nil {
 		t.Fatal(err)
 	}
-	if err := ss.RenameObjectBlocking(ctx, testBucket, "/baz", "/fileś/baz", false); err != nil {
+	if err := ss.RenameObjectBlocking(ctx, testBucket, "/baz", "/fileś/baz", false, false); err != nil {
 		t.Fatal(err)
 	}
 	if err := ss.RenameObjectsBlocking(ctx, testBucket, "/fileś/case", "/fileś/case1", false); err != nil {
@@ -2234,7 +2631,7 @@ func TestRenameObjects(t *testing.T) {
 		t.Fatal(err)
 	} else if err := ss.RenameObjectsBlocking(ctx, testBucket, "/baz2", "/fileś/baz", true); err != nil {
 		t.Fatal(err)
-	} else if err := ss.RenameObjectBlocking(ctx, testBucket, "/baz3", "/fileś/baz", true); err != nil {
+	} else if err := ss.RenameObjectBlocking(ctx, testBucket, "/baz3", "/fileś/baz", true, false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -2260,7 +2657,7 @@ func TestRenameObjects(t *testing.T) {
 	}
 
 	// Assert that number of objects matches.
-	resp, err := ss.Objects(ctx, testBucket, "", "/", "", "", "", "", 100, object.EncryptionKey{})
+	resp, err := ss.Objects(ctx, testBucket, "", "/", "", "", "", "", "", 100, object.EncryptionKey{}, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2276,82 +2673,242 @@ func TestRenameObjects(t *testing.T) {
 	}
 }
 
-func TestRenameObjectsRegression(t *testing.T) {
+// TestRenameObjectDirectoryCollision asserts that renaming an object onto a key
+// that is a directory of existing objects is rejected unless explicitly
+// allowed.
+func TestRenameObjectDirectoryCollision(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
 	defer ss.Close()
 
-	// define directory structure
-	objects := []string{
-		"/videos/comedy/",
-		"/videos/horror/",
-		"/audio/classical/",
-		"/pictures/",
-		"/README.md",
-
-		"/videos/trailer",
-		"/videos/comedy/ep1",
-		"/videos/comedy/ep2",
-		"/videos/horror/ep1",
-	}
-
-	// define a helper to assert the number of objects with given prefix
+	// create an object in another bucket that shouldn't interfere
 	ctx := context.Background()
-	assertNumObjects := func(path string, n int) {
-		t.Helper()
-
-		var delimiter string
-		if strings.HasSuffix(path, "/") {
-			delimiter = "/"
-		}
-
-		res, err := ss.Objects(ctx, testBucket, path, "", delimiter, "", "", "", -1, object.EncryptionKey{})
-		if err != nil {
-			t.Fatal(err)
-		} else if len(res.Objects) != n {
-			t.Fatalf("unexpected number of objects %d != %d, objects:\n%+v", len(res.Objects), n, res.Objects)
-		}
+	if err := ss.CreateBucket(ctx, "other", api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	} else if err := ss.UpdateObject(ctx, "other", "/other/foo", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
 	}
 
-	// persist the structure
-	for _, path := range objects {
-		var s int
-		if !strings.HasSuffix(path, "/") {
-			s = 1
-		}
-		if _, err := ss.addTestObject(path, newTestObject(s)); err != nil {
+	// create a few objects
+	for _, key := range []string{"/dir/foo", "/bar", "/baz", "/other"} {
+		if _, err := ss.addTestObject(key, newTestObject(1)); err != nil {
 			t.Fatal(err)
 		}
 	}
-	// assert the structure
-	assertNumObjects("/", 4)
-	assertNumObjects("/videos", 6)
-	assertNumObjects("/videos/", 3)
-	assertNumObjects("/videos/comedy/", 2)
-	assertNumObjects("/videos/horror/", 1)
-	assertNumObjects("/audio/", 1)
-	assertNumObjects("/pictures/", 0)
 
-	// assert we can't rename to an already existing directory without force
-	if err := ss.RenameObjects(ctx, testBucket, "/videos/comedy/", "/videos/horror/", false); !errors.Is(err, api.ErrObjectExists) {
-		t.Fatal("unexpected error", err)
+	// renaming onto a directory should fail by default
+	if err := ss.RenameObjectBlocking(ctx, testBucket, "/bar", "/dir", false, false); !errors.Is(err, api.ErrObjectKeyIsDirectory) {
+		t.Fatal("expected ErrObjectKeyIsDirectory", err)
+	} else if err := ss.RenameObjectBlocking(ctx, testBucket, "/bar", "/dir", true, false); !errors.Is(err, api.ErrObjectKeyIsDirectory) {
+		t.Fatal("expected ErrObjectKeyIsDirectory", err)
+	}
+
+	// a rejected rename shouldn't have touched the source
+	if _, err := ss.Object(ctx, testBucket, "/bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// directories in other buckets don't count
+	if err := ss.RenameObjectBlocking(ctx, testBucket, "/baz", "/other", true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// the source itself doesn't count towards the directory
+	if err := ss.RenameObjectBlocking(ctx, testBucket, "/dir/foo", "/dir", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// renaming onto a directory is possible if allowed
+	if _, err := ss.addTestObject("/dir/foo", newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if err := ss.RenameObjectBlocking(ctx, testBucket, "/bar", "/dir2", false, true); err != nil {
+		t.Fatal(err)
+	} else if err := ss.RenameObjectBlocking(ctx, testBucket, "/dir2", "/dir", true, true); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.Object(ctx, testBucket, "/dir"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.Object(ctx, testBucket, "/dir/foo"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteObjectWithSize(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add two objects that share the same slab
+	ctx := context.Background()
+	obj := newTestObject(1)
+	for _, key := range []string{"foo", "bar"} {
+		if err := ss.UpdateObject(ctx, testBucket, key, testETag, "", testMimeType, "", testMetadata, nil, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleteObject := func(key string) (deleted bool, size int64) {
+		t.Helper()
+		if err := ss.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+			deleted, size, err = tx.DeleteObjectWithSize(ctx, testBucket, key)
+			return
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	// assert the logical size of the object is returned even though its slab
+	// is still referenced by the other object
+	if deleted, size := deleteObject("foo"); !deleted {
+		t.Fatal("expected object to be deleted")
+	} else if size != int64(obj.TotalSize()) {
+		t.Fatalf("unexpected size, %v != %v", size, obj.TotalSize())
+	} else if n := ss.Count("objects"); n != 1 {
+		t.Fatal("unexpected number of objects", n)
+	} else if n := ss.Count("slabs"); n != 1 {
+		t.Fatal("unexpected number of slabs", n)
+	}
+
+	// assert deleting a missing object frees nothing
+	if deleted, size := deleteObject("foo"); deleted || size != 0 {
+		t.Fatal("unexpected result", deleted, size)
+	}
+}
+
+func TestDeleteObjectsCount(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add objects under a prefix and a few outside of it
+	ctx := context.Background()
+	addObjects := func(prefix string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if err := ss.UpdateObject(ctx, testBucket, fmt.Sprintf("%s%d", prefix, i), testETag, "", testMimeType, "", testMetadata, nil, newTestObject(0)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	addObjects("/prefix/", 2500)
+	addObjects("/other/", 10)
+
+	// delete the objects under the prefix in batches and assert the counts
+	var total int64
+	var counts []int64
+	for {
+		var deleted int64
+		if err := ss.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+			deleted, err = tx.DeleteObjects(ctx, testBucket, "/prefix/", 1000)
+			return
+		}); err != nil {
+			t.Fatal(err)
+		} else if deleted == 0 {
+			break
+		}
+		total += deleted
+		counts = append(counts, deleted)
+	}
+	if total != 2500 {
+		t.Fatal("unexpected number of deleted objects", total)
+	} else if !reflect.DeepEqual(counts, []int64{1000, 1000, 500}) {
+		t.Fatal("unexpected batches", counts)
+	} else if n := ss.Count("objects"); n != 10 {
+		t.Fatal("unexpected number of remaining objects", n)
+	}
+
+	// assert removing objects stops once the threshold is reached
+	addObjects("/prefix/", 25)
+	if deleted, err := ss.removeObjects(ctx, testBucket, "/prefix/", 10); err != nil {
+		t.Fatal(err)
+	} else if deleted != 10 {
+		t.Fatal("unexpected number of deleted objects", deleted)
+	} else if n := ss.Count("objects"); n != 25 {
+		t.Fatal("unexpected number of remaining objects", n)
+	} else if deleted, err := ss.RemoveObjects(ctx, testBucket, "/prefix/"); err != nil {
+		t.Fatal(err)
+	} else if deleted != 15 {
+		t.Fatal("unexpected number of deleted objects", deleted)
+	}
+}
+
+func TestRenameObjectsRegression(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// define directory structure
+	objects := []string{
+		"/videos/comedy/",
+		"/videos/horror/",
+		"/audio/classical/",
+		"/pictures/",
+		"/README.md",
+
+		"/videos/trailer",
+		"/videos/comedy/ep1",
+		"/videos/comedy/ep2",
+		"/videos/horror/ep1",
+	}
+
+	// define a helper to assert the number of objects with given prefix
+	ctx := context.Background()
+	assertNumObjects := func(path string, n int) {
+		t.Helper()
+
+		var delimiter string
+		if strings.HasSuffix(path, "/") {
+			delimiter = "/"
+		}
+
+		res, err := ss.Objects(ctx, testBucket, path, "", "", delimiter, "", "", "", -1, object.EncryptionKey{}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(res.Objects) != n {
+			t.Fatalf("unexpected number of objects %d != %d, objects:\n%+v", len(res.Objects), n, res.Objects)
+		}
+	}
+
+	// persist the structure
+	for _, path := range objects {
+		var s int
+		if !strings.HasSuffix(path, "/") {
+			s = 1
+		}
+		if _, err := ss.addTestObject(path, newTestObject(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// assert the structure
+	assertNumObjects("/", 4)
+	assertNumObjects("/videos", 6)
+	assertNumObjects("/videos/", 3)
+	assertNumObjects("/videos/comedy/", 2)
+	assertNumObjects("/videos/horror/", 1)
+	assertNumObjects("/audio/", 1)
+	assertNumObjects("/pictures/", 0)
+
+	// assert we can't rename to an already existing directory without force
+	if _, err := ss.RenameObjects(ctx, testBucket, "/videos/comedy/", "/videos/horror/", false); !errors.Is(err, api.ErrObjectExists) {
+		t.Fatal("unexpected error", err)
 	}
 
 	// assert we can forcefully rename it
-	if err := ss.RenameObjects(ctx, testBucket, "/videos/comedy/", "/videos/horror/", true); err != nil {
+	if res, err := ss.RenameObjects(ctx, testBucket, "/videos/comedy/", "/videos/horror/", true); err != nil {
 		t.Fatal(err)
+	} else if res.Renamed != 3 || res.Overwritten != 2 {
+		t.Fatalf("unexpected response %+v", res)
 	}
 	assertNumObjects("/videos/horror/", 2)
 
 	// assert we can rename it and its children still point to the right directory
-	if err := ss.RenameObjects(ctx, testBucket, "/videos/horror/", "/videos/thriller/", false); err != nil {
+	if res, err := ss.RenameObjects(ctx, testBucket, "/videos/horror/", "/videos/thriller/", false); err != nil {
 		t.Fatal(err)
+	} else if res.Renamed != 3 || res.Overwritten != 0 {
+		t.Fatalf("unexpected response %+v", res)
 	}
 	assertNumObjects("/videos/", 2)
 	assertNumObjects("/videos/horror/", 0)
 	assertNumObjects("/videos/thriller/", 2)
 
 	// assert we rename a grand parent and all children remain intact
-	if err := ss.RenameObjects(ctx, testBucket, "/videos/", "/video/", true); err != nil {
+	if _, err := ss.RenameObjects(ctx, testBucket, "/videos/", "/video/", true); err != nil {
 		t.Fatal(err)
 	}
 
@@ -2360,7 +2917,7 @@ func TestRenameObjectsRegression(t *testing.T) {
 	assertNumObjects("/", 4)
 
 	// assert we can move a folder up
-	if err := ss.RenameObjects(ctx, testBucket, "/video/thriller/", "/thriller/", false); err != nil {
+	if _, err := ss.RenameObjects(ctx, testBucket, "/video/thriller/", "/thriller/", false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -2369,7 +2926,7 @@ func TestRenameObjectsRegression(t *testing.T) {
 	assertNumObjects("/", 5)
 
 	// assert we can move a folder down
-	if err := ss.RenameObjects(ctx, testBucket, "/thriller/", "/audio/thriller/", false); err != nil {
+	if _, err := ss.RenameObjects(ctx, testBucket, "/thriller/", "/audio/thriller/", false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -2379,6 +2936,147 @@ func TestRenameObjectsRegression(t *testing.T) {
 	assertNumObjects("/", 4)
 }
 
+func TestObjectChecksumBackfill(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add a few objects, only one of them with a checksum
+	ctx := context.Background()
+	checksum := hex.EncodeToString(frand.Bytes(32))
+	if err := ss.UpdateObject(ctx, testBucket, "/a", testETag, checksum, testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"/b", "/c", "/d"} {
+		if _, err := ss.addTestObject(key, newTestObject(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// mark '/d' as pending deletion
+	if _, err := ss.DB().Exec(ctx, "UPDATE objects SET object_id = NULL WHERE object_id = ?", "/d"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the objects without a checksum are returned in batches
+	resp, err := ss.ObjectsMissingChecksum(ctx, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if !resp.HasMore || len(resp.Objects) != 1 || resp.Objects[0].Key != "/b" || resp.Objects[0].Bucket != testBucket {
+		t.Fatalf("unexpected response %+v", resp)
+	} else if resp.NextMarker != resp.Objects[0].ID {
+		t.Fatalf("unexpected marker %v", resp.NextMarker)
+	}
+	idB := resp.Objects[0].ID
+	resp, err = ss.ObjectsMissingChecksum(ctx, resp.NextMarker, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.HasMore || len(resp.Objects) != 1 || resp.Objects[0].Key != "/c" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	// backfill '/b' and assert it's no longer returned
+	backfilled := hex.EncodeToString(frand.Bytes(32))
+	if err := ss.BackfillObjectChecksum(ctx, idB, backfilled); err != nil {
+		t.Fatal(err)
+	} else if resp, err := ss.ObjectsMissingChecksum(ctx, 0, 10); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Objects) != 1 || resp.Objects[0].Key != "/c" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	// assert backfilling again doesn't overwrite the checksum
+	if err := ss.BackfillObjectChecksum(ctx, idB, hex.EncodeToString(frand.Bytes(32))); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.ObjectMetadata(ctx, testBucket, "/b"); err != nil {
+		t.Fatal(err)
+	} else if obj.Checksum != backfilled {
+		t.Fatalf("unexpected checksum %v != %v", obj.Checksum, backfilled)
+	} else if obj, err := ss.ObjectMetadata(ctx, testBucket, "/a"); err != nil {
+		t.Fatal(err)
+	} else if obj.Checksum != checksum {
+		t.Fatalf("unexpected checksum %v != %v", obj.Checksum, checksum)
+	}
+}
+
+func TestObjectsNoSlabs(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add a few objects, two of them are empty
+	ctx := context.Background()
+	for _, key := range []string{"/a", "/b", "/c", "/d", "/e"} {
+		o := newTestObject(1)
+		if key == "/a" || key == "/d" {
+			o = object.Object{Key: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted)}
+		}
+		if _, err := ss.addTestObject(key, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// add an object without slabs in another bucket
+	if err := ss.CreateBucket(ctx, "other", api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	} else if err := ss.UpdateObject(ctx, "other", "/a", testETag, "", testMimeType, "", testMetadata, nil, object.Object{Key: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// remove the slices of '/b' and '/e' to simulate objects that lost
+	// their data and mark '/e' as pending deletion
+	if _, err := ss.DB().Exec(ctx, "DELETE FROM slices WHERE db_object_id IN (SELECT id FROM objects WHERE object_id IN (?, ?))", "/b", "/e"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.DB().Exec(ctx, "UPDATE objects SET object_id = NULL WHERE object_id = ?", "/e"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert all objects without slabs are returned
+	resp, err := ss.ObjectsNoSlabs(ctx, testBucket, "", 10, false)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.HasMore || len(resp.Objects) != 3 {
+		t.Fatalf("unexpected response %+v", resp)
+	} else if o := resp.Objects[0]; o.Key != "/a" || o.Size != 0 || o.MissingData {
+		t.Fatalf("unexpected object %+v", o)
+	} else if o := resp.Objects[1]; o.Key != "/b" || o.Size == 0 || !o.MissingData {
+		t.Fatalf("unexpected object %+v", o)
+	} else if o := resp.Objects[2]; o.Key != "/d" || o.Size != 0 || o.MissingData {
+		t.Fatalf("unexpected object %+v", o)
+	}
+
+	// assert only objects missing their data are returned
+	resp, err = ss.ObjectsNoSlabs(ctx, testBucket, "", 10, true)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(resp.Objects) != 1 || resp.Objects[0].Key != "/b" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	// assert we can paginate
+	var keys []string
+	var marker string
+	for {
+		resp, err := ss.ObjectsNoSlabs(ctx, testBucket, marker, 1, false)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(resp.Objects) != 1 {
+			t.Fatalf("unexpected number of objects %d", len(resp.Objects))
+		}
+		keys = append(keys, resp.Objects[0].Key)
+		if !resp.HasMore {
+			break
+		}
+		marker = resp.NextMarker
+	}
+	if !reflect.DeepEqual(keys, []string{"/a", "/b", "/d"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	// assert unknown buckets are rejected
+	if _, err := ss.ObjectsNoSlabs(ctx, "unknown", "", 10, false); !errors.Is(err, api.ErrBucketNotFound) {
+		t.Fatal("expected ErrBucketNotFound", err)
+	}
+}
+
 // TestObjectsStats is a unit test for ObjectsStats.
 func TestObjectsStats(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
@@ -2484,77 +3182,250 @@ func TestObjectsStats(t *testing.T) {
 	}
 }
 
-func TestPartialSlab(t *testing.T) {
+func TestSlabsStats(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
 	defer ss.Close()
 
-	// create 2 hosts
-	hks, err := ss.addTestHosts(2)
+	// assert stats on clean database
+	stats, err := ss.SlabsStats(context.Background())
 	if err != nil {
 		t.Fatal(err)
+	} else if stats != (api.SlabsStatsResponse{}) {
+		t.Fatal("unexpected stats", stats)
 	}
-	hk1, hk2 := hks[0], hks[1]
 
-	// create 2 contracts
-	fcids, _, err := ss.addTestContracts(hks)
+	// add an object per health range
+	for i, health := range []float64{1, 1, 0.5, 0, -1} {
+		key := fmt.Sprintf("obj%d", i)
+		if _, err := ss.addTestObject(key, newTestObject(1)); err != nil {
+			t.Fatal(err)
+		} else if err := ss.overrideSlabHealth(key, health); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// add a partial slab that hasn't been uploaded yet
+	if _, _, err := ss.AddPartialSlab(context.Background(), frand.Bytes(1), 1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the slabs are counted by health and buffered slabs are ignored
+	stats, err = ss.SlabsStats(context.Background())
 	if err != nil {
 		t.Fatal(err)
+	} else if stats != (api.SlabsStatsResponse{NumHealthy: 2, NumDegraded: 1, NumAtRisk: 1, NumLost: 1}) {
+		t.Fatal("unexpected stats", stats)
 	}
-	fcid1, fcid2 := fcids[0], fcids[1]
+}
 
-	// helper function to assert buffer stats returned by SlabBuffers.
-	assertBuffer := func(name string, size int64, complete, locked bool) {
-		t.Helper()
-		buffers, err := ss.SlabBuffers(context.Background())
-		if err != nil {
+func TestSharedSlabs(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add an object with two slabs
+	obj := newTestObject(2)
+	if _, err := ss.addTestObject("foo", obj); err != nil {
+		t.Fatal(err)
+	}
+	shared, other := obj.Slabs[0], obj.Slabs[1]
+
+	// add two more objects referencing the first slab, one of them twice
+	for key, slabs := range map[string][]object.SlabSlice{
+		"bar": {shared},
+		"baz": {shared, shared},
+	} {
+		o := newTestObject(0)
+		o.Slabs = slabs
+		if _, err := ss.addTestObject(key, o); err != nil {
 			t.Fatal(err)
 		}
-		if len(buffers) == 0 {
-			t.Fatal("no buffers")
-		}
-		var buf api.SlabBuffer
-		for _, b := range buffers {
-			if b.Filename == name {
-				buf = b
-				break
-			}
-		}
-		if buf == (api.SlabBuffer{}) {
-			t.Fatal("buffer not found for name", name)
-		}
-		if buf.Filename != name {
-			t.Fatal("wrong filename", buf.Filename, name)
-		}
-		if buf.MaxSize != int64(bufferedSlabSize(1)) {
-			t.Fatal("wrong max size", buf.MaxSize, bufferedSlabSize(1))
-		}
-		if buf.Size != size {
-			t.Fatal("wrong size", buf.Size, size)
-		}
-		if buf.Complete != complete {
-			t.Fatal("wrong complete", buf.Complete, complete)
-		}
-		if buf.Locked != locked {
-			t.Fatal("wrong locked", buf.Locked, locked)
-		}
 	}
 
-	// prepare the data for 3 partial slabs. The first one is very small. The
-	// second one almost fills a buffer except for 1 byte. The third one spans 2
-	// buffers.
-	fullSlabSize := bufferedSlabSize(1)
-	slab1Data := []byte{1, 2, 3, 4}
-	slab2Data := frand.Bytes(int(fullSlabSize) - len(slab1Data) - 1) // leave 1 byte
-	slab3Data := []byte{5, 6}                                        // 1 byte more than fits in the slab
+	// assert objects are only counted once per slab
+	slabs, err := ss.SharedSlabs(context.Background(), 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(slabs) != 1 {
+		t.Fatal("unexpected number of shared slabs", len(slabs))
+	} else if slabs[0].EncryptionKey.String() != shared.EncryptionKey.String() || slabs[0].NumObjects != 3 {
+		t.Fatal("unexpected shared slab", slabs[0])
+	}
 
-	// Add the first slab.
-	ctx := context.Background()
-	slabs, bufferSize, err := ss.AddPartialSlab(ctx, slab1Data, 1, 2)
+	// assert slabs are sorted by the number of objects referencing them
+	slabs, err = ss.SharedSlabs(context.Background(), 0, 100)
 	if err != nil {
 		t.Fatal(err)
+	} else if len(slabs) != 2 {
+		t.Fatal("unexpected number of shared slabs", len(slabs))
+	} else if slabs[0].EncryptionKey.String() != shared.EncryptionKey.String() || slabs[1].EncryptionKey.String() != other.EncryptionKey.String() || slabs[1].NumObjects != 1 {
+		t.Fatal("unexpected shared slabs", slabs)
 	}
-	if len(slabs) != 1 {
-		t.Fatal("expected 1 slab to be created", len(slabs))
+
+	// assert the limit is applied and the threshold is exclusive
+	if slabs, err := ss.SharedSlabs(context.Background(), 0, 1); err != nil {
+		t.Fatal(err)
+	} else if len(slabs) != 1 {
+		t.Fatal("unexpected number of shared slabs", len(slabs))
+	} else if slabs, err := ss.SharedSlabs(context.Background(), 3, 100); err != nil {
+		t.Fatal(err)
+	} else if len(slabs) != 0 {
+		t.Fatal("unexpected number of shared slabs", len(slabs))
+	}
+}
+
+func TestRemoveObjectAsync(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add two objects
+	for _, key := range []string{"foo", "bar"} {
+		if _, err := ss.addTestObject(key, newTestObject(2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// removing an object that doesn't exist should fail
+	if err := ss.RemoveObjectAsync(context.Background(), testBucket, "baz"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("expected ErrObjectNotFound", err)
+	}
+
+	// remove 'foo' asynchronously
+	if err := ss.RemoveObjectAsync(context.Background(), testBucket, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the object should be gone immediately
+	if _, err := ss.Object(context.Background(), testBucket, "foo"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("expected ErrObjectNotFound", err)
+	}
+	resp, err := ss.Objects(context.Background(), testBucket, "", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(resp.Objects) != 1 || resp.Objects[0].Key != "bar" {
+		t.Fatal("unexpected objects", resp.Objects)
+	}
+
+	// the object and its slabs are pruned in the background
+	ss.Retry(100, 100*time.Millisecond, func() error {
+		if n := ss.Count("objects"); n != 1 {
+			return fmt.Errorf("expected 1 object, got %d", n)
+		} else if n := ss.Count("slabs"); n != 2 {
+			return fmt.Errorf("expected 2 slabs, got %d", n)
+		}
+		return nil
+	})
+
+	// tombstone 'bar' without triggering the pruning
+	if _, err := ss.DB().Exec(context.Background(), "UPDATE objects SET object_id = NULL WHERE object_id = ?", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert it's reported as pending deletion
+	stats, err := ss.ObjectsStats(context.Background(), api.ObjectsStatsOpts{})
+	if err != nil {
+		t.Fatal(err)
+	} else if stats.NumObjects != 0 || stats.NumPendingDeletions != 1 || stats.TotalObjectsSize != 0 {
+		t.Fatal("unexpected stats", stats)
+	}
+
+	// assert the slabs of tombstoned objects are neither shared nor migrated
+	if _, err := ss.DB().Exec(context.Background(), "UPDATE slabs SET health = 0, health_valid_until = ?", time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	} else if slabs, err := ss.SharedSlabs(context.Background(), 0, 100); err != nil {
+		t.Fatal(err)
+	} else if len(slabs) != 0 {
+		t.Fatal("unexpected shared slabs", slabs)
+	} else if slabs, err := ss.SlabsForMigration(context.Background(), 0.99, -1); err != nil {
+		t.Fatal(err)
+	} else if len(slabs) != 0 {
+		t.Fatal("unexpected slabs for migration", slabs)
+	}
+
+	// assert pending deletions are pruned once triggered
+	ss.triggerTombstonePruning()
+	ss.Retry(100, 100*time.Millisecond, func() error {
+		if stats, err := ss.ObjectsStats(context.Background(), api.ObjectsStatsOpts{}); err != nil {
+			return err
+		} else if stats.NumPendingDeletions != 0 {
+			return fmt.Errorf("expected no pending deletions, got %d", stats.NumPendingDeletions)
+		} else if n := ss.Count("slabs"); n != 0 {
+			return fmt.Errorf("expected no slabs, got %d", n)
+		}
+		return nil
+	})
+}
+
+func TestPartialSlab(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// create 2 hosts
+	hks, err := ss.addTestHosts(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hk1, hk2 := hks[0], hks[1]
+
+	// create 2 contracts
+	fcids, _, err := ss.addTestContracts(hks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fcid1, fcid2 := fcids[0], fcids[1]
+
+	// helper function to assert buffer stats returned by SlabBuffers.
+	assertBuffer := func(name string, size int64, complete, locked bool) {
+		t.Helper()
+		buffers, err := ss.SlabBuffers(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buffers) == 0 {
+			t.Fatal("no buffers")
+		}
+		var buf api.SlabBuffer
+		for _, b := range buffers {
+			if b.Filename == name {
+				buf = b
+				break
+			}
+		}
+		if buf == (api.SlabBuffer{}) {
+			t.Fatal("buffer not found for name", name)
+		}
+		if buf.Filename != name {
+			t.Fatal("wrong filename", buf.Filename, name)
+		}
+		if buf.MaxSize != int64(bufferedSlabSize(1)) {
+			t.Fatal("wrong max size", buf.MaxSize, bufferedSlabSize(1))
+		}
+		if buf.Size != size {
+			t.Fatal("wrong size", buf.Size, size)
+		}
+		if buf.Complete != complete {
+			t.Fatal("wrong complete", buf.Complete, complete)
+		}
+		if buf.Locked != locked {
+			t.Fatal("wrong locked", buf.Locked, locked)
+		}
+	}
+
+	// prepare the data for 3 partial slabs. The first one is very small. The
+	// second one almost fills a buffer except for 1 byte. The third one spans 2
+	// buffers.
+	fullSlabSize := bufferedSlabSize(1)
+	slab1Data := []byte{1, 2, 3, 4}
+	slab2Data := frand.Bytes(int(fullSlabSize) - len(slab1Data) - 1) // leave 1 byte
+	slab3Data := []byte{5, 6}                                        // 1 byte more than fits in the slab
+
+	// Add the first slab.
+	ctx := context.Background()
+	slabs, bufferSize, err := ss.AddPartialSlab(ctx, slab1Data, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slabs) != 1 {
+		t.Fatal("expected 1 slab to be created", len(slabs))
 	}
 	if slabs[0].Length != uint32(len(slab1Data)) || slabs[0].Offset != 0 {
 		t.Fatal("wrong offset/length", slabs[0].Offset, slabs[0].Length)
@@ -2623,6 +3494,8 @@ func TestPartialSlab(t *testing.T) {
 	}
 	if !reflect.DeepEqual(obj, *fetched.Object) {
 		t.Fatal("mismatch", cmp.Diff(obj, *fetched.Object, cmp.AllowUnexported(object.EncryptionKey{})))
+	} else if !fetched.Pending {
+		t.Fatal("expected object to be pending")
 	}
 
 	// Add the second slab.
@@ -2732,6 +3605,13 @@ func TestPartialSlab(t *testing.T) {
 	}
 	assertBuffer(buffer2Name, 1, false, false)
 
+	// The object's data is on hosts now.
+	if fetched, err := ss.Object(ctx, testBucket, "/key"); err != nil {
+		t.Fatal(err)
+	} else if fetched.Pending {
+		t.Fatal("expected object to no longer be pending")
+	}
+
 	_, err = ss.FetchPartialSlab(ctx, slabs[0].EncryptionKey, slabs[0].Offset, slabs[0].Length)
 	if !errors.Is(err, api.ErrObjectNotFound) {
 		t.Fatal("expected ErrObjectNotFound", err)
@@ -2976,7 +3856,7 @@ func TestObjectsBySlabKey(t *testing.T) {
 	}
 
 	// Fetch the objects by slab.
-	res, err := ss.Objects(context.Background(), testBucket, "", "", "/", "", "", "", -1, slab.EncryptionKey)
+	res, err := ss.Objects(context.Background(), testBucket, "", "", "", "/", "", "", "", -1, slab.EncryptionKey, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -3014,7 +3894,7 @@ func TestBuckets(t *testing.T) {
 		t.Fatal(err)
 	} else if err := ss.CreateBucket(context.Background(), b2, api.BucketPolicy{}); err != nil {
 		t.Fatal(err)
-	} else if err := ss.DeleteBucket(context.Background(), testBucket); err != nil {
+	} else if err := ss.DeleteBucket(context.Background(), testBucket, false); err != nil {
 		t.Fatal(err)
 	} else if buckets, err := ss.Buckets(context.Background()); err != nil {
 		t.Fatal(err)
@@ -3030,18 +3910,252 @@ func TestBuckets(t *testing.T) {
 	// one that doesn't exist.
 	if err := ss.CreateBucket(context.Background(), b1, api.BucketPolicy{}); !errors.Is(err, api.ErrBucketExists) {
 		t.Fatal("expected ErrBucketExists", err)
-	} else if err := ss.DeleteBucket(context.Background(), "foo"); !errors.Is(err, api.ErrBucketNotFound) {
+	} else if err := ss.DeleteBucket(context.Background(), "foo", false); !errors.Is(err, api.ErrBucketNotFound) {
 		t.Fatal("expected ErrBucketNotFound", err)
 	}
 }
 
+func TestBucketQuota(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+	ctx := context.Background()
+
+	// create a bucket with a quota that fits the first object
+	o1, o2 := newTestObject(1), newTestObject(1)
+	quota := uint64(o1.TotalSize())
+	if err := ss.CreateBucket(ctx, "quota", api.BucketPolicy{Quota: quota}); err != nil {
+		t.Fatal(err)
+	} else if b, err := ss.Bucket(ctx, "quota"); err != nil {
+		t.Fatal(err)
+	} else if b.Policy.Quota != quota {
+		t.Fatal("unexpected quota", b.Policy.Quota)
+	}
+
+	// assert the first object fits but the second one doesn't
+	if err := ss.UpdateObject(ctx, "quota", "/o1", testETag, "", testMimeType, "", testMetadata, nil, o1); err != nil {
+		t.Fatal(err)
+	} else if err := ss.UpdateObject(ctx, "quota", "/o2", testETag, "", testMimeType, "", testMetadata, nil, o2); !errors.Is(err, api.ErrBucketQuotaExceeded) {
+		t.Fatal("expected ErrBucketQuotaExceeded", err)
+	} else if _, err := ss.Object(ctx, "quota", "/o2"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("expected ErrObjectNotFound", err)
+	}
+
+	// assert overwriting the object doesn't count its old size
+	if err := ss.UpdateObject(ctx, "quota", "/o1", testETag, "", testMimeType, "", testMetadata, nil, o1); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert copying or moving objects into the bucket is limited too
+	if _, err := ss.addTestObject("/o2", o2); err != nil {
+		t.Fatal(err)
+	} else if _, _, err := ss.CopyObject(ctx, "quota", "quota", "/o1", "/o1-copy", "", "", nil, api.CopyPolicyOverwrite); !errors.Is(err, api.ErrBucketQuotaExceeded) {
+		t.Fatal("expected ErrBucketQuotaExceeded", err)
+	} else if err := ss.MoveObject(ctx, testBucket, "quota", "/o2"); !errors.Is(err, api.ErrBucketQuotaExceeded) {
+		t.Fatal("expected ErrBucketQuotaExceeded", err)
+	}
+
+	// lift the quota and assert the object can be moved
+	if err := ss.UpdateBucketPolicy(ctx, "quota", api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	} else if err := ss.MoveObject(ctx, testBucket, "quota", "/o2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the running bucket size matches the objects in the bucket
+	assertSize := func() {
+		t.Helper()
+		var size, expected int64
+		if err := ss.DB().QueryRow(ctx, "SELECT size FROM buckets WHERE name = ?", "quota").Scan(&size); err != nil {
+			t.Fatal(err)
+		} else if err := ss.DB().QueryRow(ctx, "SELECT COALESCE(SUM(o.size), 0) FROM objects o INNER JOIN buckets b ON o.db_bucket_id = b.id WHERE b.name = ? AND o.object_id IS NOT NULL", "quota").Scan(&expected); err != nil {
+			t.Fatal(err)
+		} else if size != expected {
+			t.Fatalf("unexpected bucket size %v, expected %v", size, expected)
+		}
+	}
+	assertSize()
+
+	if _, _, err := ss.CopyObject(ctx, "quota", "quota", "/o1", "/o1-copy", "", "", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	assertSize()
+	if err := ss.RenameObjectsBlocking(ctx, "quota", "/o1-", "/o2-", false); err != nil {
+		t.Fatal(err)
+	}
+	assertSize()
+	if _, _, err := ss.CopyObject(ctx, "quota", "quota", "/o1", "/o1-copy", "", "", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if err := ss.RenameObjectsBlocking(ctx, "quota", "/o1-", "/o2-", true); err != nil {
+		t.Fatal(err)
+	}
+	assertSize()
+	if err := ss.RemoveObjectBlocking(ctx, "quota", "/o2-copy"); err != nil {
+		t.Fatal(err)
+	}
+	assertSize()
+	if err := ss.RemoveObjectAsync(ctx, "quota", "/o2"); err != nil {
+		t.Fatal(err)
+	}
+	assertSize()
+	if err := ss.RemoveObjectsBlocking(ctx, "quota", "/"); err != nil {
+		t.Fatal(err)
+	}
+	assertSize()
+}
+
+func TestCaseInsensitiveBucket(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+	ctx := context.Background()
+
+	// create a case-insensitive bucket
+	bucket := "ci"
+	if err := ss.CreateBucket(ctx, bucket, api.BucketPolicy{CaseInsensitive: true}); err != nil {
+		t.Fatal(err)
+	} else if b, err := ss.Bucket(ctx, bucket); err != nil {
+		t.Fatal(err)
+	} else if !b.Policy.CaseInsensitive {
+		t.Fatal("expected bucket to be case-insensitive")
+	}
+
+	// add an object
+	if err := ss.UpdateObject(ctx, bucket, "/Foo/Bar.txt", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert it can be fetched using any case and keeps its original key
+	for _, key := range []string{"/Foo/Bar.txt", "/foo/bar.txt", "/FOO/BAR.TXT"} {
+		if obj, err := ss.Object(ctx, bucket, key); err != nil {
+			t.Fatal(err)
+		} else if obj.ObjectMetadata.Key != "/Foo/Bar.txt" {
+			t.Fatal("unexpected key", obj.ObjectMetadata.Key)
+		} else if obj, err := ss.ObjectMetadata(ctx, bucket, key); err != nil {
+			t.Fatal(err)
+		} else if obj.ObjectMetadata.Key != "/Foo/Bar.txt" {
+			t.Fatal("unexpected key", obj.ObjectMetadata.Key)
+		}
+	}
+
+	// assert overwriting the object with the same key works but adding one
+	// that only differs in case doesn't
+	if err := ss.UpdateObject(ctx, bucket, "/Foo/Bar.txt", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if err := ss.UpdateObject(ctx, bucket, "/foo/bar.txt", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); !errors.Is(err, api.ErrObjectKeyCaseConflict) {
+		t.Fatal("expected ErrObjectKeyCaseConflict", err)
+	}
+
+	// assert the object can be deleted using a different case
+	if err := ss.RemoveObject(ctx, bucket, "/FOO/bar.TXT"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.Object(ctx, bucket, "/Foo/Bar.txt"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("expected ErrObjectNotFound", err)
+	}
+
+	// assert non-ASCII keys are matched case-insensitively too
+	if err := ss.UpdateObject(ctx, bucket, "/Äpfel", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, bucket, "/äPFEL"); err != nil {
+		t.Fatal(err)
+	} else if obj.ObjectMetadata.Key != "/Äpfel" {
+		t.Fatal("unexpected key", obj.ObjectMetadata.Key)
+	} else if err := ss.UpdateObject(ctx, bucket, "/äpfel", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); !errors.Is(err, api.ErrObjectKeyCaseConflict) {
+		t.Fatal("expected ErrObjectKeyCaseConflict", err)
+	}
+
+	// assert copying, renaming and moving an object can't introduce keys that
+	// only differ in case either
+	if err := ss.CreateBucket(ctx, "cs", api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"/Other", "/dir/a", "/other/A"} {
+		if err := ss.UpdateObject(ctx, bucket, key, testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ss.UpdateObject(ctx, "cs", "/ÄPFEL", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ss.CopyObject(ctx, bucket, bucket, "/Äpfel", "/ÄPFEL", "", "", nil, api.CopyPolicyOverwrite); !errors.Is(err, api.ErrObjectKeyCaseConflict) {
+		t.Fatal("expected ErrObjectKeyCaseConflict", err)
+	} else if err := ss.RenameObject(ctx, bucket, "/Other", "/äpfel", false, true); !errors.Is(err, api.ErrObjectKeyCaseConflict) {
+		t.Fatal("expected ErrObjectKeyCaseConflict", err)
+	} else if _, err := ss.RenameObjects(ctx, bucket, "/other/", "/dir/", false); !errors.Is(err, api.ErrObjectKeyCaseConflict) {
+		t.Fatal("expected ErrObjectKeyCaseConflict", err)
+	} else if err := ss.MoveObject(ctx, "cs", bucket, "/ÄPFEL"); !errors.Is(err, api.ErrObjectKeyCaseConflict) {
+		t.Fatal("expected ErrObjectKeyCaseConflict", err)
+	} else if _, _, err := ss.CopyObject(ctx, bucket, bucket, "/Äpfel", "/Birnen", "", "", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if err := ss.RenameObject(ctx, bucket, "/Birnen", "/Kirschen", false, true); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, bucket, "/KIRSCHEN"); err != nil {
+		t.Fatal(err)
+	} else if obj.ObjectMetadata.Key != "/Kirschen" {
+		t.Fatal("unexpected key", obj.ObjectMetadata.Key)
+	}
+
+	// assert renaming a prefix whose lowercase form has a different byte
+	// length keeps the lowercase keys intact
+	if err := ss.UpdateObject(ctx, bucket, "/İdir/file", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.RenameObjects(ctx, bucket, "/İdir/", "/new/", false); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, bucket, "/NEW/FILE"); err != nil {
+		t.Fatal(err)
+	} else if obj.ObjectMetadata.Key != "/new/file" {
+		t.Fatal("unexpected key", obj.ObjectMetadata.Key)
+	}
+	var lower string
+	if err := ss.DB().QueryRow(ctx, "SELECT object_id_lower FROM objects WHERE object_id = ?", "/new/file").Scan(&lower); err != nil {
+		t.Fatal(err)
+	} else if lower != "/new/file" {
+		t.Fatalf("unexpected lowercase key %q", lower)
+	}
+
+	// assert lookups in the default bucket remain case-sensitive
+	if _, err := ss.addTestObject("/Foo", newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.Object(ctx, testBucket, "/foo"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("expected ErrObjectNotFound", err)
+	} else if _, err := ss.addTestObject("/foo", newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// tombstone two objects, which shouldn't be considered conflicting, and
+	// clear the lowercase key of '/Foo' to simulate an object that was added
+	// before the column existed
+	for _, key := range []string{"/tomb1", "/tomb2"} {
+		if _, err := ss.addTestObject(key, newTestObject(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ss.DB().Exec(ctx, "UPDATE objects SET object_id = NULL, object_id_lower = NULL WHERE object_id IN (?, ?)", "/tomb1", "/tomb2"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.DB().Exec(ctx, "UPDATE objects SET object_id_lower = NULL WHERE object_id = ?", "/Foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the default bucket can't be made case-insensitive while it
+	// contains keys that only differ in case
+	if err := ss.UpdateBucketPolicy(ctx, testBucket, api.BucketPolicy{CaseInsensitive: true}); !errors.Is(err, api.ErrObjectKeyCaseConflict) {
+		t.Fatal("expected ErrObjectKeyCaseConflict", err)
+	} else if err := ss.RemoveObject(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if err := ss.UpdateBucketPolicy(ctx, testBucket, api.BucketPolicy{CaseInsensitive: true}); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if obj.ObjectMetadata.Key != "/Foo" {
+		t.Fatal("unexpected key", obj.ObjectMetadata.Key)
+	}
+}
+
 func TestBucketObjects(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
 	defer ss.Close()
 
 	// Adding an object to a bucket that doesn't exist shouldn't work.
 	obj := newTestObject(1)
-	err := ss.UpdateObject(context.Background(), "unknown-bucket", "/foo", testETag, testMimeType, testMetadata, obj)
+	err := ss.UpdateObject(context.Background(), "unknown-bucket", "/foo", testETag, "", testMimeType, "", testMetadata, nil, obj)
 	if !errors.Is(err, api.ErrBucketNotFound) {
 		t.Fatal("expected ErrBucketNotFound", err)
 	}
@@ -3072,65 +4186,65 @@ func TestBucketObjects(t *testing.T) {
 		obj := newTestObject(frand.Intn(9) + 1)
 		obj.Slabs = obj.Slabs[:1]
 		obj.Slabs[0].Length = uint32(o.size)
-		err := ss.UpdateObject(ctx, o.bucket, o.path, testETag, testMimeType, testMetadata, obj)
+		err := ss.UpdateObject(ctx, o.bucket, o.path, testETag, "", testMimeType, "", testMetadata, nil, obj)
 		if err != nil {
 			t.Fatal(err)
 		}
 	}
 
 	// Deleting a bucket with objects shouldn't work.
-	if err := ss.DeleteBucket(ctx, b1); !errors.Is(err, api.ErrBucketNotEmpty) {
+	if err := ss.DeleteBucket(ctx, b1, false); !errors.Is(err, api.ErrBucketNotEmpty) {
 		t.Fatal(err)
 	}
 
 	// List the objects in the buckets.
-	if resp, err := ss.Objects(context.Background(), b1, "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	if resp, err := ss.Objects(context.Background(), b1, "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 1 entry", len(entries))
 	} else if entries[0].Size != 1 {
 		t.Fatal("unexpected size", entries[0].Size)
-	} else if resp, err := ss.Objects(context.Background(), b2, "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b2, "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 1 entry", len(entries))
 	} else if entries[0].Size != 2 {
 		t.Fatal("unexpected size", entries[0].Size)
-	} else if resp, err := ss.Objects(context.Background(), "", "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), "", "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 2 {
 		t.Fatal("expected 2 entries", len(entries))
 	}
 
 	// Search the objects in the buckets.
-	if resp, err := ss.Objects(context.Background(), b1, "", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	if resp, err := ss.Objects(context.Background(), b1, "", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if objects := resp.Objects; len(objects) != 2 {
 		t.Fatal("expected 2 objects", len(objects))
 	} else if objects[0].Size != 3 || objects[1].Size != 1 {
 		t.Fatal("unexpected size", objects[0].Size, objects[1].Size)
-	} else if resp, err := ss.Objects(context.Background(), b2, "", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b2, "", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if objects := resp.Objects; len(objects) != 2 {
 		t.Fatal("expected 2 objects", len(objects))
 	} else if objects[0].Size != 4 || objects[1].Size != 2 {
 		t.Fatal("unexpected size", objects[0].Size, objects[1].Size)
-	} else if resp, err := ss.Objects(context.Background(), "", "", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), "", "", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if objects := resp.Objects; len(objects) != 4 {
 		t.Fatal("expected 4 objects", len(objects))
 	}
 
 	// Rename object foo/bar in bucket 1 to foo/baz but not in bucket 2.
-	if err := ss.RenameObjectBlocking(context.Background(), b1, "/foo/bar", "/foo/baz", false); err != nil {
+	if err := ss.RenameObjectBlocking(context.Background(), b1, "/foo/bar", "/foo/baz", false, false); err != nil {
 		t.Fatal(err)
-	} else if resp, err := ss.Objects(context.Background(), b1, "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b1, "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 2 entries", len(entries))
 	} else if entries[0].Key != "/foo/baz" {
 		t.Fatal("unexpected name", entries[0].Key)
-	} else if resp, err := ss.Objects(context.Background(), b2, "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b2, "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 2 entries", len(entries))
@@ -3141,13 +4255,13 @@ func TestBucketObjects(t *testing.T) {
 	// Rename foo/bar in bucket 2 using the batch rename.
 	if err := ss.RenameObjectsBlocking(context.Background(), b2, "/foo/bar", "/foo/bam", false); err != nil {
 		t.Fatal(err)
-	} else if resp, err := ss.Objects(context.Background(), b1, "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b1, "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 2 entries", len(entries))
 	} else if entries[0].Key != "/foo/baz" {
 		t.Fatal("unexpected name", entries[0].Key)
-	} else if resp, err := ss.Objects(context.Background(), b2, "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b2, "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 2 entries", len(entries))
@@ -3160,28 +4274,28 @@ func TestBucketObjects(t *testing.T) {
 		t.Fatal(err)
 	} else if err := ss.RemoveObjectBlocking(context.Background(), b1, "/foo/baz"); err != nil {
 		t.Fatal(err)
-	} else if resp, err := ss.Objects(context.Background(), b1, "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b1, "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) > 0 {
 		t.Fatal("expected 0 entries", len(entries))
-	} else if resp, err := ss.Objects(context.Background(), b2, "/foo/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b2, "/foo/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 1 entry", len(entries))
 	}
 
 	// Delete all files in bucket 2.
-	if resp, err := ss.Objects(context.Background(), b2, "/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	if resp, err := ss.Objects(context.Background(), b2, "/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 2 {
 		t.Fatal("expected 2 entries", len(entries))
 	} else if err := ss.RemoveObjectsBlocking(context.Background(), b2, "/"); err != nil {
 		t.Fatal(err)
-	} else if resp, err := ss.Objects(context.Background(), b2, "/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b2, "/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 0 {
 		t.Fatal("expected 0 entries", len(entries))
-	} else if resp, err := ss.Objects(context.Background(), b1, "/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(context.Background(), b1, "/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 1 entry", len(entries))
@@ -3199,15 +4313,28 @@ func TestBucketObjects(t *testing.T) {
 	// See if we can fetch the object by slab.
 	if obj, err := ss.Object(context.Background(), b1, "/bar"); err != nil {
 		t.Fatal(err)
-	} else if res, err := ss.Objects(context.Background(), b1, "", "", "", "", "", "", -1, obj.Slabs[0].EncryptionKey); err != nil {
+	} else if res, err := ss.Objects(context.Background(), b1, "", "", "", "", "", "", "", -1, obj.Slabs[0].EncryptionKey, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if len(res.Objects) != 1 {
 		t.Fatal("expected 1 object", len(objects))
-	} else if res, err := ss.Objects(context.Background(), b2, "", "", "", "", "", "", -1, obj.Slabs[0].EncryptionKey); err != nil {
+	} else if res, err := ss.Objects(context.Background(), b2, "", "", "", "", "", "", "", -1, obj.Slabs[0].EncryptionKey, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if len(res.Objects) != 0 {
 		t.Fatal("expected 0 objects", len(objects))
 	}
+
+	// Force delete bucket 1 which still contains /bar.
+	if err := ss.DeleteBucket(context.Background(), b1, false); !errors.Is(err, api.ErrBucketNotEmpty) {
+		t.Fatal(err)
+	} else if err := ss.DeleteBucket(context.Background(), b1, true); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.Bucket(context.Background(), b1); !errors.Is(err, api.ErrBucketNotFound) {
+		t.Fatal(err)
+	} else if _, err := ss.Object(context.Background(), b1, "/bar"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal(err)
+	} else if err := ss.DeleteBucket(context.Background(), b1, true); !errors.Is(err, api.ErrBucketNotFound) {
+		t.Fatal(err)
+	}
 }
 
 func TestCopyObject(t *testing.T) {
@@ -3224,15 +4351,15 @@ func TestCopyObject(t *testing.T) {
 
 	// Create one object.
 	obj := newTestObject(1)
-	err := ss.UpdateObject(ctx, "src", "/foo", testETag, testMimeType, testMetadata, obj)
+	err := ss.UpdateObject(ctx, "src", "/foo", testETag, "", testMimeType, "", testMetadata, nil, obj)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Copy it within the same bucket.
-	if om, err := ss.CopyObject(ctx, "src", "src", "/foo", "/bar", "", nil); err != nil {
+	if om, _, err := ss.CopyObject(ctx, "src", "src", "/foo", "/bar", "", "", nil, api.CopyPolicyOverwrite); err != nil {
 		t.Fatal(err)
-	} else if resp, err := ss.Objects(ctx, "src", "/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(ctx, "src", "/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 2 {
 		t.Fatal("expected 2 entries", len(entries))
@@ -3243,9 +4370,9 @@ func TestCopyObject(t *testing.T) {
 	}
 
 	// Copy it cross buckets.
-	if om, err := ss.CopyObject(ctx, "src", "dst", "/foo", "/bar", "", nil); err != nil {
+	if om, _, err := ss.CopyObject(ctx, "src", "dst", "/foo", "/bar", "", "", nil, api.CopyPolicyOverwrite); err != nil {
 		t.Fatal(err)
-	} else if resp, err := ss.Objects(ctx, "dst", "/", "", "", "", "", "", -1, object.EncryptionKey{}); err != nil {
+	} else if resp, err := ss.Objects(ctx, "dst", "/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
 		t.Fatal(err)
 	} else if entries := resp.Objects; len(entries) != 1 {
 		t.Fatal("expected 1 entry", len(entries))
@@ -3254,36 +4381,327 @@ func TestCopyObject(t *testing.T) {
 	} else if om.ModTime.IsZero() {
 		t.Fatal("expected mod time to be set")
 	}
-}
-
-func TestMarkSlabUploadedAfterRenew(t *testing.T) {
-	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
-	defer ss.Close()
 
-	// create host.
-	hks, err := ss.addTestHosts(1)
-	if err != nil {
+	// Overwrite the destination with a different mime type.
+	if om, copied, err := ss.CopyObject(ctx, "src", "dst", "/foo", "/bar", "foo/bar", "", nil, api.CopyPolicyOverwrite); err != nil {
 		t.Fatal(err)
+	} else if !copied || om.MimeType != "foo/bar" {
+		t.Fatal("expected object to be overwritten", copied, om.MimeType)
 	}
-	hk := hks[0]
 
-	// create contracts
-	fcids, _, err := ss.addTestContracts(hks)
-	if err != nil {
+	// Skip the copy since the destination exists.
+	if om, copied, err := ss.CopyObject(ctx, "src", "dst", "/foo", "/bar", "bar/baz", "", nil, api.CopyPolicySkip); err != nil {
 		t.Fatal(err)
+	} else if copied || om.MimeType != "foo/bar" {
+		t.Fatal("expected copy to be skipped", copied, om.MimeType)
 	}
-	fcid := fcids[0]
 
-	// create a full buffered slab.
-	completeSize := bufferedSlabSize(1)
-	slabs, _, err := ss.AddPartialSlab(context.Background(), frand.Bytes(completeSize), 1, 1)
-	if err != nil {
+	// Fail the copy since the destination exists.
+	if _, _, err := ss.CopyObject(ctx, "src", "dst", "/foo", "/bar", "bar/baz", "", nil, api.CopyPolicyFail); !errors.Is(err, api.ErrObjectExists) {
+		t.Fatal("expected ErrObjectExists", err)
+	} else if obj, err := ss.Object(ctx, "dst", "/bar"); err != nil {
 		t.Fatal(err)
+	} else if obj.MimeType != "foo/bar" {
+		t.Fatal("expected destination to be untouched", obj.MimeType)
 	}
 
-	// add it to an object to prevent it from getting pruned.
-	_, err = ss.addTestObject("/"+t.Name(), object.Object{
-		Key:   object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
+	// Copy to a new destination with both policies.
+	for _, policy := range []string{api.CopyPolicySkip, api.CopyPolicyFail} {
+		if _, copied, err := ss.CopyObject(ctx, "src", "dst", "/foo", "/"+policy, "", "", nil, policy); err != nil {
+			t.Fatal(err)
+		} else if !copied {
+			t.Fatalf("expected object to be copied with policy %q", policy)
+		}
+	}
+}
+
+func TestCopyObjectSharesSlabs(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// create the destination bucket
+	ctx := context.Background()
+	if err := ss.CreateBucket(ctx, "dst", api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// add an object and copy it across buckets
+	obj := newTestObject(2)
+	if err := ss.UpdateObject(ctx, testBucket, "/foo", testETag, "", testMimeType, "", testMetadata, nil, obj); err != nil {
+		t.Fatal(err)
+	} else if _, _, err := ss.CopyObject(ctx, testBucket, "dst", "/foo", "/bar", "", "", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert no slabs were duplicated
+	if n := ss.Count("slabs"); n != 2 {
+		t.Fatalf("expected 2 slabs, got %v", n)
+	}
+
+	// delete the source and prune, the slabs are still referenced by the copy
+	if err := ss.RemoveObject(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.pruneSlabs(ctx); err != nil {
+		t.Fatal(err)
+	} else if n := ss.Count("slabs"); n != 2 {
+		t.Fatalf("expected 2 slabs, got %v", n)
+	}
+
+	// assert the copy is intact
+	if copied, err := ss.Object(ctx, "dst", "/bar"); err != nil {
+		t.Fatal(err)
+	} else if len(copied.Object.Slabs) != len(obj.Slabs) {
+		t.Fatalf("expected %v slabs, got %v", len(obj.Slabs), len(copied.Object.Slabs))
+	} else {
+		for i, slab := range copied.Object.Slabs {
+			if slab.EncryptionKey.String() != obj.Slabs[i].EncryptionKey.String() || len(slab.Shards) != len(obj.Slabs[i].Shards) {
+				t.Fatalf("slab %v doesn't match the source", i)
+			}
+		}
+	}
+
+	// delete the copy and prune, now the slabs should be gone
+	if err := ss.RemoveObject(ctx, "dst", "/bar"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.pruneSlabs(ctx); err != nil {
+		t.Fatal(err)
+	} else if n := ss.Count("slabs"); n != 0 {
+		t.Fatalf("expected 0 slabs, got %v", n)
+	}
+}
+
+func TestSlabKeyCollision(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add an object
+	ctx := context.Background()
+	obj := newTestObject(1)
+	if err := ss.UpdateObject(ctx, testBucket, "/foo", testETag, "", testMimeType, "", testMetadata, nil, obj); err != nil {
+		t.Fatal(err)
+	}
+
+	// add another object that reuses the slab's key with different params
+	collision := newTestObject(1)
+	collision.Slabs[0].EncryptionKey = obj.Slabs[0].EncryptionKey
+	collision.Slabs[0].MinShards = obj.Slabs[0].MinShards + 1
+	err := ss.UpdateObject(ctx, testBucket, "/bar", testETag, "", testMimeType, "", testMetadata, nil, collision)
+	if !errors.Is(err, api.ErrSlabKeyCollision) {
+		t.Fatal("expected ErrSlabKeyCollision, got", err)
+	}
+
+	// assert the object wasn't added and the slab is untouched
+	if _, err := ss.Object(ctx, testBucket, "/bar"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("expected ErrObjectNotFound, got", err)
+	} else if slab, err := ss.Slab(ctx, obj.Slabs[0].EncryptionKey); err != nil {
+		t.Fatal(err)
+	} else if slab.MinShards != obj.Slabs[0].MinShards || len(slab.Shards) != len(obj.Slabs[0].Shards) {
+		t.Fatal("slab was modified", slab.MinShards, len(slab.Shards))
+	}
+
+	// assert an alert was registered
+	if resp, err := ss.alerts.Alerts(ctx, alerts.AlertsOpts{}); err != nil {
+		t.Fatal(err)
+	} else if resp.Totals.Critical != 1 {
+		t.Fatal("expected a critical alert", resp.Totals)
+	}
+
+	// adding an object that reuses the slab with the same params is fine
+	dedup := newTestObject(1)
+	dedup.Slabs[0] = obj.Slabs[0]
+	if err := ss.UpdateObject(ctx, testBucket, "/baz", testETag, "", testMimeType, "", testMetadata, nil, dedup); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestObjectContentDisposition(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add an object with a content disposition
+	ctx := context.Background()
+	cd := `attachment; filename="foo.txt"`
+	if err := ss.UpdateObject(ctx, testBucket, "/foo", testETag, "", testMimeType, cd, testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert it's returned
+	if obj, err := ss.Object(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if obj.ContentDisposition != cd {
+		t.Fatalf("unexpected content disposition %q", obj.ContentDisposition)
+	} else if resp, err := ss.Objects(ctx, testBucket, "/", "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Objects) != 1 || resp.Objects[0].ContentDisposition != cd {
+		t.Fatalf("unexpected objects %+v", resp.Objects)
+	}
+
+	// copy the object, the content disposition should be kept
+	if om, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/foo", "/bar", "", "", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if om.ContentDisposition != cd {
+		t.Fatalf("unexpected content disposition %q", om.ContentDisposition)
+	}
+
+	// copy the object with an override
+	if om, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/foo", "/baz", "", "inline", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if om.ContentDisposition != "inline" {
+		t.Fatalf("unexpected content disposition %q", om.ContentDisposition)
+	}
+
+	// update the object in place
+	if om, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/foo", "/foo", "", "inline", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if om.ContentDisposition != "inline" {
+		t.Fatalf("unexpected content disposition %q", om.ContentDisposition)
+	}
+}
+
+func TestUpdateObjectMetadataInPlace(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// create an object with metadata
+	ctx := context.Background()
+	if err := ss.UpdateObject(ctx, testBucket, "/foo", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// helper to fetch the metadata rows
+	metadataIDs := func() map[string]int64 {
+		t.Helper()
+		rows, err := ss.DB().Query(ctx, "SELECT id, `key` FROM object_user_metadata")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		ids := make(map[string]int64)
+		for rows.Next() {
+			var id int64
+			var key string
+			if err := rows.Scan(&id, &key); err != nil {
+				t.Fatal(err)
+			}
+			ids[key] = id
+		}
+		return ids
+	}
+	before := metadataIDs()
+
+	// update the metadata in place, changing one key, removing one and adding
+	// one
+	md := api.ObjectUserMetadata{
+		"foo":  "baz",
+		"quux": "corge",
+	}
+	if _, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/foo", "/foo", testMimeType, "", md, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(obj.Metadata, md) {
+		t.Fatal("unexpected metadata", cmp.Diff(obj.Metadata, md))
+	}
+
+	// assert the changed key was updated rather than recreated
+	after := metadataIDs()
+	if len(after) != 2 {
+		t.Fatal("unexpected number of metadata entries", len(after))
+	} else if after["foo"] != before["foo"] {
+		t.Fatal("expected changed key to be updated in place")
+	} else if _, ok := after["baz"]; ok {
+		t.Fatal("expected removed key to be deleted")
+	}
+
+	// update the metadata with an identical map, nothing should change
+	if _, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/foo", "/foo", testMimeType, "", md, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if ids := metadataIDs(); !reflect.DeepEqual(ids, after) {
+		t.Fatal("expected metadata to remain untouched", cmp.Diff(ids, after))
+	}
+}
+
+func TestMoveObject(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// Create the buckets.
+	ctx := context.Background()
+	if err := ss.CreateBucket(ctx, "src", api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	} else if err := ss.CreateBucket(ctx, "dst", api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create two objects.
+	if err := ss.UpdateObject(ctx, "src", "/foo", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if err := ss.UpdateObject(ctx, "dst", "/bar", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+	slabs := ss.Count("slabs")
+
+	// Move the first object to the destination bucket.
+	if err := ss.MoveObject(ctx, "src", "dst", "/foo"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.Object(ctx, "src", "/foo"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("expected object to be gone from source bucket", err)
+	} else if obj, err := ss.Object(ctx, "dst", "/foo"); err != nil {
+		t.Fatal(err)
+	} else if obj.Bucket != "dst" {
+		t.Fatal("unexpected bucket", obj.Bucket)
+	} else if !reflect.DeepEqual(obj.Metadata, testMetadata) {
+		t.Fatal("unexpected metadata", obj.Metadata)
+	} else if n := ss.Count("slabs"); n != slabs {
+		t.Fatalf("expected %d slabs, got %d", slabs, n)
+	}
+
+	// Moving it again should fail since it doesn't exist in the source.
+	if err := ss.MoveObject(ctx, "src", "dst", "/foo"); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// Moving an object onto an existing key should fail.
+	if err := ss.UpdateObject(ctx, "src", "/bar", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if err := ss.MoveObject(ctx, "src", "dst", "/bar"); !errors.Is(err, api.ErrObjectExists) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// Moving to an unknown bucket should fail.
+	if err := ss.MoveObject(ctx, "src", "unknown", "/bar"); !errors.Is(err, api.ErrBucketNotFound) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestMarkSlabUploadedAfterRenew(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// create host.
+	hks, err := ss.addTestHosts(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hk := hks[0]
+
+	// create contracts
+	fcids, _, err := ss.addTestContracts(hks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fcid := fcids[0]
+
+	// create a full buffered slab.
+	completeSize := bufferedSlabSize(1)
+	slabs, _, err := ss.AddPartialSlab(context.Background(), frand.Bytes(completeSize), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// add it to an object to prevent it from getting pruned.
+	_, err = ss.addTestObject("/"+t.Name(), object.Object{
+		Key:   object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
 		Slabs: slabs,
 	})
 	if err != nil {
@@ -3397,7 +4815,7 @@ func TestObjectsNoDelimiter(t *testing.T) {
 		}
 	}
 	for _, test := range tests {
-		res, err := ss.Objects(ctx, testBucket, test.prefix, "", "", test.sortBy, test.sortDir, "", -1, object.EncryptionKey{})
+		res, err := ss.Objects(ctx, testBucket, test.prefix, "", "", "", test.sortBy, test.sortDir, "", -1, object.EncryptionKey{}, nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -3412,7 +4830,7 @@ func TestObjectsNoDelimiter(t *testing.T) {
 		if len(res.Objects) > 0 {
 			marker := ""
 			for offset := 0; offset < len(test.want); offset++ {
-				res, err := ss.Objects(ctx, testBucket, test.prefix, "", "", test.sortBy, test.sortDir, marker, 1, object.EncryptionKey{})
+				res, err := ss.Objects(ctx, testBucket, test.prefix, "", "", "", test.sortBy, test.sortDir, marker, 1, object.EncryptionKey{}, nil, nil)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -3432,6 +4850,185 @@ func TestObjectsNoDelimiter(t *testing.T) {
 	}
 }
 
+func TestObjectsAcrossBuckets(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// create a second bucket
+	ctx := context.Background()
+	const otherBucket = "other"
+	if err := ss.CreateBucket(ctx, otherBucket, api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// add objects to both buckets
+	objects := []struct {
+		bucket string
+		key    string
+		size   int64
+	}{
+		{testBucket, "/a", 3},
+		{otherBucket, "/b", 1},
+		{testBucket, "/c", 2},
+		{otherBucket, "/d", 4},
+	}
+	now := time.Now().Round(time.Second)
+	for i, o := range objects {
+		obj := newTestObject(1)
+		obj.Slabs[0].Length = uint32(o.size)
+		if err := ss.UpdateObject(ctx, o.bucket, o.key, testETag, "", testMimeType, "", testMetadata, nil, obj); err != nil {
+			t.Fatal(err)
+		} else if _, err := ss.DB().Exec(ctx, "UPDATE objects SET created_at = ? WHERE object_id = ?", now.Add(time.Duration(i)*time.Minute), o.key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{api.ObjectSortBySize, []string{otherBucket + "/d", testBucket + "/a", testBucket + "/c", otherBucket + "/b"}},
+		{api.ObjectSortByModTime, []string{otherBucket + "/d", testBucket + "/c", otherBucket + "/b", testBucket + "/a"}},
+	}
+	for _, test := range tests {
+		// list all objects at once
+		res, err := ss.Objects(ctx, "", "", "", "", "", test.sortBy, api.SortDirDesc, "", -1, object.EncryptionKey{}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, o := range res.Objects {
+			got = append(got, o.Bucket+o.Key)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("unexpected objects when sorting by %v, %v != %v", test.sortBy, got, test.want)
+		}
+
+		// paginate through the objects
+		var marker string
+		for _, want := range test.want {
+			res, err := ss.Objects(ctx, "", "", "", "", "", test.sortBy, api.SortDirDesc, marker, 1, object.EncryptionKey{}, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			} else if len(res.Objects) != 1 {
+				t.Fatalf("expected 1 object, got %v", len(res.Objects))
+			} else if got := res.Objects[0].Bucket + res.Objects[0].Key; got != want {
+				t.Fatalf("expected %v, got %v, marker %v", want, got, marker)
+			}
+			marker = res.NextMarker
+		}
+	}
+
+	// add an object with the same key, size and modtime to both buckets
+	for _, bucket := range []string{testBucket, otherBucket} {
+		obj := newTestObject(1)
+		obj.Slabs[0].Length = 5
+		if err := ss.UpdateObject(ctx, bucket, "/e", testETag, "", testMimeType, "", testMetadata, nil, obj); err != nil {
+			t.Fatal(err)
+		} else if _, err := ss.DB().Exec(ctx, "UPDATE objects SET created_at = ? WHERE object_id = ?", now, "/e"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// paginating doesn't skip or repeat objects with the same key
+	for _, sortBy := range []string{api.ObjectSortByName, api.ObjectSortBySize, api.ObjectSortByModTime} {
+		for _, sortDir := range []string{api.SortDirAsc, api.SortDirDesc} {
+			seen := make(map[string]struct{})
+			var marker string
+			for {
+				res, err := ss.Objects(ctx, "", "", "", "", "", sortBy, sortDir, marker, 1, object.EncryptionKey{}, nil, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, o := range res.Objects {
+					if _, ok := seen[o.Bucket+o.Key]; ok {
+						t.Fatalf("object %v listed twice when sorting by %v %v", o.Bucket+o.Key, sortBy, sortDir)
+					}
+					seen[o.Bucket+o.Key] = struct{}{}
+				}
+				if !res.HasMore {
+					break
+				}
+				marker = res.NextMarker
+			}
+			if len(seen) != len(objects)+2 {
+				t.Fatalf("expected %v objects when sorting by %v %v, got %v", len(objects)+2, sortBy, sortDir, len(seen))
+			}
+		}
+	}
+}
+
+func TestObjectsHealthRange(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add objects with varying health
+	ctx := context.Background()
+	objects := []struct {
+		key    string
+		health float64
+	}{
+		{"/a", 1},
+		{"/b", 0.4},
+		{"/c", -1},
+		{"/d", 0},
+		{"/e", 0.5},
+		{"/dir/f", 0.2},
+	}
+	for _, o := range objects {
+		if err := ss.UpdateObject(ctx, testBucket, o.key, testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+			t.Fatal(err)
+		} else if _, err := ss.DB().Exec(ctx, "UPDATE objects SET health = ? WHERE object_id = ?", o.health, o.key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	health := func(h float64) *float64 { return &h }
+	tests := []struct {
+		delim     string
+		minHealth *float64
+		maxHealth *float64
+		want      []string
+	}{
+		{"", nil, nil, []string{"/a", "/b", "/c", "/d", "/dir/f", "/e"}},
+		{"", health(0), health(0.5), []string{"/d", "/dir/f", "/b", "/e"}},
+		{"", nil, health(0), []string{"/c", "/d"}},
+		{"", health(0.5), nil, []string{"/e", "/a"}},
+		{"/", health(0), health(0.5), []string{"/d", "/dir/", "/b", "/e"}},
+	}
+	for _, test := range tests {
+		// list all objects at once
+		res, err := ss.Objects(ctx, testBucket, "/", "", "", test.delim, "", "", "", -1, object.EncryptionKey{}, test.minHealth, test.maxHealth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, o := range res.Objects {
+			got = append(got, o.Key)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("unexpected objects, %v != %v", got, test.want)
+		}
+
+		// paginate through the objects
+		if test.minHealth == nil && test.maxHealth == nil {
+			continue
+		}
+		var marker string
+		for _, want := range test.want {
+			res, err := ss.Objects(ctx, testBucket, "/", "", "", test.delim, api.ObjectSortByHealth, api.SortDirAsc, marker, 1, object.EncryptionKey{}, test.minHealth, test.maxHealth)
+			if err != nil {
+				t.Fatal(err)
+			} else if len(res.Objects) != 1 {
+				t.Fatalf("expected 1 object, got %v", len(res.Objects))
+			} else if got := res.Objects[0].Key; got != want {
+				t.Fatalf("expected %v, got %v, marker %v", want, got, marker)
+			}
+			marker = res.NextMarker
+		}
+	}
+}
+
 func TestDeleteHostSector(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
 	defer ss.Close()
@@ -3775,7 +5372,7 @@ func TestSlabHealthInvalidation(t *testing.T) {
 
 	// prepare a slab with pieces on h3 and h4
 	s2 := object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted)
-	err = ss.UpdateObject(context.Background(), testBucket, "/o2", testETag, testMimeType, testMetadata, object.Object{
+	err = ss.UpdateObject(context.Background(), testBucket, "/o2", testETag, "", testMimeType, "", testMetadata, nil, object.Object{
 		Key: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
 		Slabs: []object.SlabSlice{{Slab: object.Slab{
 			EncryptionKey: s2,
@@ -4254,19 +5851,184 @@ func TestSlabCleanup(t *testing.T) {
 	}
 }
 
-func TestUpdateObjectReuseSlab(t *testing.T) {
+func TestPruneSlabsParallel(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
 	defer ss.Close()
+	ss.slabPruningParallelism = 4
 
-	minShards, totalShards := 10, 30
-
-	// create 90 hosts, enough for 3 slabs with 30 each
-	hks, err := ss.addTestHosts(3 * totalShards)
-	if err != nil {
+	// create a buffered slab
+	if _, err := ss.DB().Exec(context.Background(), "INSERT INTO buffered_slabs (filename) VALUES ('foo');"); err != nil {
 		t.Fatal(err)
 	}
 
-	// create one contract each
+	// create unreferenced slabs, one of them referencing the buffered slab
+	const numSlabs = 2*slabPruningBatchSize + 50
+	for i := 0; i < numSlabs; i++ {
+		var bufferedSlabID any
+		if i == numSlabs/2 {
+			bufferedSlabID = 1
+		}
+		if _, err := ss.DB().Exec(context.Background(), "INSERT INTO slabs (db_buffered_slab_id, `key`, health_valid_until) VALUES (?, ?, ?);", bufferedSlabID, sql.EncryptionKey(object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted)), 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// prune the slabs, the buffered one should remain
+	if pruned, err := ss.pruneSlabs(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if pruned != numSlabs-1 {
+		t.Fatalf("expected %v slabs to be pruned, got %v", numSlabs-1, pruned)
+	} else if n := ss.Count("slabs"); n != 1 {
+		t.Fatalf("expected 1 slab, got %v", n)
+	}
+}
+
+func TestPartitionIDRange(t *testing.T) {
+	tests := []struct {
+		minID, maxID int64
+		n            int
+		want         [][2]int64
+	}{
+		{0, 0, 4, [][2]int64{{0, 1}}},
+		{1, 10, 1, [][2]int64{{1, 11}}},
+		{1, 10, 3, [][2]int64{{1, 4}, {4, 7}, {7, 11}}},
+		{1, 2, 4, [][2]int64{{1, 2}, {2, 3}}},
+		{5, 8, 0, [][2]int64{{5, 9}}},
+	}
+	for _, test := range tests {
+		if got := partitionIDRange(test.minID, test.maxID, test.n); !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("unexpected ranges for [%d, %d] and n=%d, %v != %v", test.minID, test.maxID, test.n, got, test.want)
+		}
+	}
+}
+
+func TestInsertObjectDedupSlabs(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// create 6 hosts with a contract each
+	hks, err := ss.addTestHosts(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fcids, _, err := ss.addTestContracts(hks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// helper to create an object with a single 1-of-3 slab stored on the
+	// contracts starting at the given offset
+	slab := object.Slab{
+		EncryptionKey: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
+		MinShards:     1,
+	}
+	for i := 0; i < 3; i++ {
+		slab.Shards = append(slab.Shards, object.Sector{Root: frand.Entropy256()})
+	}
+	newObject := func(offset int) object.Object {
+		s := slab
+		s.Shards = make([]object.Sector, len(slab.Shards))
+		for i := range s.Shards {
+			s.Shards[i] = object.Sector{
+				Root:      slab.Shards[i].Root,
+				Contracts: map[types.PublicKey][]types.FileContractID{hks[offset+i]: {fcids[offset+i]}},
+			}
+		}
+		return object.Object{
+			Key:   object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
+			Slabs: []object.SlabSlice{{Slab: s, Length: rhpv2.SectorSize}},
+		}
+	}
+
+	assertCounts := func(slabs, sectors, contractSectors int64) {
+		t.Helper()
+		if n := ss.Count("slabs"); n != slabs {
+			t.Fatal("unexpected number of slabs", n)
+		} else if n := ss.Count("sectors"); n != sectors {
+			t.Fatal("unexpected number of sectors", n)
+		} else if n := ss.Count("contract_sectors"); n != contractSectors {
+			t.Fatal("unexpected number of contract sectors", n)
+		}
+	}
+
+	// add an object
+	if _, err := ss.addTestObject("/1", newObject(0)); err != nil {
+		t.Fatal(err)
+	}
+	assertCounts(1, 3, 3)
+
+	// add an object with the same slab, since the slab is complete its
+	// sectors aren't inserted again but its new contract links are
+	if _, err := ss.addTestObject("/2", newObject(3)); err != nil {
+		t.Fatal(err)
+	}
+	assertCounts(1, 3, 6)
+
+	// prune the slab's sectors and add the slab again, the sectors have to
+	// be inserted again
+	if _, err := ss.DB().Exec(context.Background(), "DELETE FROM sectors"); err != nil {
+		t.Fatal(err)
+	}
+	assertCounts(1, 0, 0)
+	if _, err := ss.addTestObject("/3", newObject(3)); err != nil {
+		t.Fatal(err)
+	}
+	assertCounts(1, 3, 3)
+
+	// archive the contracts and add the slab again, the sectors are complete
+	// but their contract links have to be inserted again
+	if err := ss.ArchiveContracts(context.Background(), map[types.FileContractID]string{
+		fcids[3]: "foo",
+		fcids[4]: "foo",
+		fcids[5]: "foo",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	assertCounts(1, 3, 0)
+	if _, err := ss.addTestObject("/4", newObject(0)); err != nil {
+		t.Fatal(err)
+	}
+	assertCounts(1, 3, 3)
+
+	// assert all objects reference the slab with its sectors
+	for _, key := range []string{"/1", "/2", "/3", "/4"} {
+		obj, err := ss.Object(context.Background(), testBucket, key)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(obj.Object.Slabs) != 1 || len(obj.Object.Slabs[0].Shards) != 3 {
+			t.Fatal("unexpected slabs", obj.Object.Slabs)
+		}
+		for i, shard := range obj.Object.Slabs[0].Shards {
+			if shard.Root != slab.Shards[i].Root {
+				t.Fatal("unexpected root", shard.Root)
+			} else if ids := shard.Contracts[hks[i]]; len(ids) != 1 || ids[0] != fcids[i] {
+				t.Fatal("unexpected contracts", shard.Contracts)
+			}
+		}
+	}
+
+	// adding the slab with roots that don't match the stored ones fails
+	mismatch := newObject(0)
+	mismatch.Slabs[0].Shards[1].Root = frand.Entropy256()
+	if _, err := ss.addTestObject("/5", mismatch); err == nil {
+		t.Fatal("expected error")
+	}
+	assertCounts(1, 3, 3)
+}
+
+func TestUpdateObjectReuseSlab(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	minShards, totalShards := 10, 30
+
+	// create 90 hosts, enough for 3 slabs with 30 each
+	hks, err := ss.addTestHosts(3 * totalShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create one contract each
 	fcids, _, err := ss.addTestContracts(hks)
 	if err != nil {
 		t.Fatal(err)
@@ -4648,7 +6410,7 @@ func TestUpdateObjectParallel(t *testing.T) {
 			}
 
 			// update the object
-			if err := ss.UpdateObject(context.Background(), testBucket, name, testETag, testMimeType, testMetadata, obj); err != nil {
+			if err := ss.UpdateObject(context.Background(), testBucket, name, testETag, "", testMimeType, "", testMetadata, nil, obj); err != nil {
 				t.Error(err)
 				return
 			}
@@ -4892,3 +6654,587 @@ func TestHostSectors(t *testing.T) {
 		t.Fatal("expected updated at to change")
 	}
 }
+
+func TestSavepoint(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	insertBucket := func(tx isql.Tx, name string) error {
+		_, err := tx.Exec(context.Background(), "INSERT INTO buckets (created_at, name) VALUES (?, ?)", time.Now(), name)
+		return err
+	}
+
+	// insert a bucket and try to insert another one within a savepoint that
+	// fails, the outer transaction should still be committed
+	errSavepoint := errors.New("savepoint failed")
+	if err := ss.DB().Transaction(context.Background(), func(tx isql.Tx) error {
+		if err := insertBucket(tx, "foo"); err != nil {
+			return err
+		}
+		err := tx.Savepoint(context.Background(), "test", func() error {
+			if err := insertBucket(tx, "bar"); err != nil {
+				return err
+			}
+			return errSavepoint
+		})
+		if !errors.Is(err, errSavepoint) {
+			t.Fatal("unexpected error", err)
+		}
+
+		// assert the savepoint can be reused after a rollback
+		return tx.Savepoint(context.Background(), "test", func() error {
+			return insertBucket(tx, "baz")
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the buckets
+	for name, exists := range map[string]bool{
+		"foo": true,
+		"bar": false,
+		"baz": true,
+	} {
+		if _, err := ss.Bucket(context.Background(), name); exists && err != nil {
+			t.Fatal(err)
+		} else if !exists && !errors.Is(err, api.ErrBucketNotFound) {
+			t.Fatal("unexpected error", err)
+		}
+	}
+}
+
+func TestTransactionRetries(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// fail the first two attempts with an error that is considered a lock
+	// error by both backends
+	var attempts int
+	ctx := isql.WithOperation(context.Background(), opInsertObject)
+	if err := ss.DB().Transaction(ctx, func(tx isql.Tx) error {
+		if attempts++; attempts <= 2 {
+			return errors.New("database is locked; Deadlock found when trying to get lock")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// transactions without an operation are not tracked
+	attempts = 0
+	if err := ss.DB().Transaction(context.Background(), func(tx isql.Tx) error {
+		if attempts++; attempts <= 1 {
+			return errors.New("database is locked; Deadlock found when trying to get lock")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the retries were tracked
+	if retries := ss.TransactionRetries(); len(retries) != 1 {
+		t.Fatal("unexpected retries", retries)
+	} else if retries[opInsertObject] != 2 {
+		t.Fatal("unexpected retries", retries)
+	}
+}
+
+func TestObjectChecksum(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add an object with a checksum
+	ctx := context.Background()
+	checksum := hex.EncodeToString(frand.Bytes(32))
+	if err := ss.UpdateObject(ctx, testBucket, "/foo", testETag, checksum, testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert it's returned when fetching the object
+	if obj, err := ss.Object(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if obj.Checksum != checksum {
+		t.Fatal("unexpected checksum", obj.Checksum)
+	}
+
+	// assert it's returned when listing objects
+	if resp, err := ss.Objects(ctx, testBucket, "/", "", "", "/", "", "", "", -1, object.EncryptionKey{}, nil, nil); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Objects) != 1 || resp.Objects[0].Checksum != checksum {
+		t.Fatal("unexpected objects", resp.Objects)
+	}
+
+	// assert it's copied
+	if om, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/foo", "/bar", "", "", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	} else if om.Checksum != checksum {
+		t.Fatal("unexpected checksum", om.Checksum)
+	}
+}
+
+func TestSampleSectors(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// assert sampling an empty database returns no samples
+	if samples, err := ss.SampleSectors(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	} else if len(samples) != 0 {
+		t.Fatal("expected no samples", len(samples))
+	}
+
+	// create 3 hosts with a contract each
+	hks, err := ss.addTestHosts(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fcids, _, err := ss.addTestContracts(hks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create a slab with one sector per host
+	expected := make(map[api.SectorSample]struct{})
+	var shards []object.Sector
+	for i, hk := range hks {
+		root := types.Hash256{byte(i + 1)}
+		shards = append(shards, object.Sector{
+			Contracts: map[types.PublicKey][]types.FileContractID{hk: {fcids[i]}},
+			Root:      root,
+		})
+		expected[api.SectorSample{Root: root, HostKey: hk}] = struct{}{}
+	}
+	ss.InsertSlab(object.Slab{
+		EncryptionKey: object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
+		MinShards:     1,
+		Shards:        shards,
+	})
+
+	// sample more sectors than there are and assert we only get unique,
+	// valid samples
+	samples, err := ss.SampleSectors(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(samples) == 0 || len(samples) > len(expected) {
+		t.Fatal("unexpected number of samples", len(samples))
+	}
+	seen := make(map[api.SectorSample]struct{})
+	for _, sample := range samples {
+		if _, ok := expected[sample]; !ok {
+			t.Fatal("unexpected sample", sample)
+		} else if _, ok := seen[sample]; ok {
+			t.Fatal("duplicate sample", sample)
+		}
+		seen[sample] = struct{}{}
+	}
+}
+
+func TestPinObject(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add two objects with a single slab each
+	ctx := context.Background()
+	objs := make(map[string]object.Object)
+	for _, key := range []string{"/a", "/b"} {
+		objs[key] = newTestObject(1)
+		if err := ss.UpdateObject(ctx, testBucket, key, testETag, "", testMimeType, "", testMetadata, nil, objs[key]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// give both slabs the same health
+	if _, err := ss.DB().Exec(ctx, "UPDATE slabs SET health = 0.5, health_valid_until = ?", time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	// pinning an object that doesn't exist should fail
+	if err := ss.PinObject(ctx, testBucket, "/c", true); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	// pin the second object
+	if err := ss.PinObject(ctx, testBucket, "/b", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the flag is returned
+	if obj, err := ss.Object(ctx, testBucket, "/b"); err != nil {
+		t.Fatal(err)
+	} else if !obj.Pinned {
+		t.Fatal("expected object to be pinned")
+	} else if obj, err := ss.ObjectMetadata(ctx, testBucket, "/b"); err != nil {
+		t.Fatal(err)
+	} else if !obj.Pinned {
+		t.Fatal("expected object to be pinned")
+	} else if obj, err := ss.Object(ctx, testBucket, "/a"); err != nil {
+		t.Fatal(err)
+	} else if obj.Pinned {
+		t.Fatal("expected object not to be pinned")
+	}
+
+	// assert the pinned slab is returned first
+	slabs, err := ss.SlabsForMigration(ctx, 0.99, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []api.UnhealthySlab{
+		{EncryptionKey: objs["/b"].Slabs[0].EncryptionKey, Health: 0.5, Pinned: true},
+		{EncryptionKey: objs["/a"].Slabs[0].EncryptionKey, Health: 0.5},
+	}
+	if !reflect.DeepEqual(slabs, expected) {
+		t.Fatalf("unexpected slabs, %+v != %+v", slabs, expected)
+	}
+
+	// assert pruning leaves the pinned object's slab alone
+	if _, err := ss.pruneSlabs(ctx); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, testBucket, "/b"); err != nil {
+		t.Fatal(err)
+	} else if len(obj.Slabs) != 1 || obj.Slabs[0].EncryptionKey.String() != objs["/b"].Slabs[0].EncryptionKey.String() {
+		t.Fatal("expected pinned object's slab to remain")
+	}
+
+	// helper to assert whether an object is pinned
+	assertPinned := func(key string, pinned bool) {
+		t.Helper()
+		if obj, err := ss.Object(ctx, testBucket, key); err != nil {
+			t.Fatal(err)
+		} else if obj.Pinned != pinned {
+			t.Fatalf("expected pinned to be %v for %v", pinned, key)
+		}
+	}
+
+	// assert overwriting the object keeps it pinned
+	if err := ss.UpdateObject(ctx, testBucket, "/b", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+	assertPinned("/b", true)
+
+	// assert copying the object carries over the flag
+	if _, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/b", "/c", "", "", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	assertPinned("/c", true)
+
+	// assert overwriting a pinned object with a copy keeps it pinned
+	if _, _, err := ss.CopyObject(ctx, testBucket, testBucket, "/a", "/c", "", "", nil, api.CopyPolicyOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	assertPinned("/c", true)
+	assertPinned("/a", false)
+
+	// unpin the object
+	if err := ss.PinObject(ctx, testBucket, "/b", false); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, testBucket, "/b"); err != nil {
+		t.Fatal(err)
+	} else if obj.Pinned {
+		t.Fatal("expected object not to be pinned")
+	}
+}
+
+func TestObjectManifest(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add objects to the default bucket and another bucket
+	ctx := context.Background()
+	if err := ss.CreateBucket(ctx, "other", api.BucketPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	objs := make(map[string]object.Object)
+	for i, key := range []string{"/c", "/a", "/b"} {
+		objs[key] = newTestObject(i + 1)
+		if err := ss.UpdateObject(ctx, testBucket, key, testETag, "", testMimeType, "", testMetadata, nil, objs[key]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ss.UpdateObject(ctx, "other", "/d", testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the manifest is paginated by key
+	var entries []api.ObjectManifestEntry
+	var marker string
+	for {
+		batch, err := ss.ObjectManifest(ctx, testBucket, marker, 2)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(batch) == 0 {
+			break
+		}
+		entries = append(entries, batch...)
+		marker = batch[len(batch)-1].Key
+	}
+	if len(entries) != 3 {
+		t.Fatalf("unexpected number of entries, %v != 3", len(entries))
+	}
+	for i, key := range []string{"/a", "/b", "/c"} {
+		entry := entries[i]
+		if entry.Key != key {
+			t.Fatalf("unexpected key, %v != %v", entry.Key, key)
+		} else if entry.Size != objs[key].TotalSize() || entry.ETag != testETag {
+			t.Fatalf("unexpected entry %+v", entry)
+		} else if len(entry.Slabs) != len(objs[key].Slabs) {
+			t.Fatalf("unexpected number of slabs, %v != %v", len(entry.Slabs), len(objs[key].Slabs))
+		}
+		for j, slab := range objs[key].Slabs {
+			if entry.Slabs[j].String() != slab.EncryptionKey.String() {
+				t.Fatalf("unexpected slab key at index %d", j)
+			}
+		}
+	}
+
+	// assert unknown buckets are reported
+	if _, err := ss.ObjectManifest(ctx, "unknown", "", 2); !errors.Is(err, api.ErrBucketNotFound) {
+		t.Fatalf("expected ErrBucketNotFound, got %v", err)
+	}
+}
+
+func TestBucketLifecycleRules(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// assert the bucket has to exist
+	ctx := context.Background()
+	if _, err := ss.BucketLifecycleRules(ctx, "unknown"); !errors.Is(err, api.ErrBucketNotFound) {
+		t.Fatalf("expected ErrBucketNotFound, got %v", err)
+	} else if err := ss.UpdateBucketLifecycleRules(ctx, "unknown", nil); !errors.Is(err, api.ErrBucketNotFound) {
+		t.Fatalf("expected ErrBucketNotFound, got %v", err)
+	}
+
+	// assert a new bucket has no rules
+	if rules, err := ss.BucketLifecycleRules(ctx, testBucket); err != nil {
+		t.Fatal(err)
+	} else if len(rules) != 0 {
+		t.Fatalf("expected no rules, got %v", rules)
+	}
+
+	// add rules
+	expected := []api.BucketLifecycleRule{
+		{Prefix: "/tmp/", ExpirationDays: 1},
+		{ExpirationDays: 30},
+	}
+	if err := ss.UpdateBucketLifecycleRules(ctx, testBucket, expected); err != nil {
+		t.Fatal(err)
+	} else if rules, err := ss.BucketLifecycleRules(ctx, testBucket); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("unexpected rules, %+v != %+v", rules, expected)
+	}
+
+	// add objects of varying age
+	ages := map[string]time.Duration{
+		"/tmp/a": 48 * time.Hour,
+		"/tmp/b": 0,
+		"/keep":  48 * time.Hour,
+		"/old":   31 * 24 * time.Hour,
+	}
+	for key, age := range ages {
+		if err := ss.UpdateObject(ctx, testBucket, key, testETag, "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+			t.Fatal(err)
+		} else if _, err := ss.DB().Exec(ctx, "UPDATE objects SET created_at = ? WHERE object_id = ?", time.Now().Add(-age), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// expire objects
+	if expired, err := ss.expireObjects(ctx); err != nil {
+		t.Fatal(err)
+	} else if expired != 2 {
+		t.Fatalf("expected 2 expired objects, got %v", expired)
+	}
+
+	// assert only the expired objects are gone
+	for key := range ages {
+		_, err := ss.Object(ctx, testBucket, key)
+		if key == "/tmp/a" || key == "/old" {
+			if !errors.Is(err, api.ErrObjectNotFound) {
+				t.Fatalf("expected object %v to be expired, got %v", key, err)
+			}
+		} else if err != nil {
+			t.Fatalf("expected object %v to remain, got %v", key, err)
+		}
+	}
+
+	// remove the rules
+	if err := ss.UpdateBucketLifecycleRules(ctx, testBucket, nil); err != nil {
+		t.Fatal(err)
+	} else if rules, err := ss.BucketLifecycleRules(ctx, testBucket); err != nil {
+		t.Fatal(err)
+	} else if len(rules) != 0 {
+		t.Fatalf("expected no rules, got %v", rules)
+	}
+}
+
+func TestUpdateObjectIdempotent(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add an object using an idempotency key
+	ctx := context.Background()
+	hash := frand.Entropy256()
+	if _, err := ss.UpdateObjectIdempotent(ctx, "key", hash, time.Time{}, testBucket, "/foo", "etag1", "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// overwrite the object without a key
+	if err := ss.UpdateObject(ctx, testBucket, "/foo", "etag2", "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert retrying the first request is a no-op that returns the original
+	// ETag
+	if eTag, err := ss.UpdateObjectIdempotent(ctx, "key", hash, time.Time{}, testBucket, "/foo", "etag1", "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if eTag != "etag1" {
+		t.Fatalf("expected original etag, got %v", eTag)
+	} else if obj, err := ss.Object(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if obj.ETag != "etag2" {
+		t.Fatalf("expected object not to be overwritten, got etag %v", obj.ETag)
+	}
+
+	// assert reusing the key for a different request fails
+	if _, err := ss.UpdateObjectIdempotent(ctx, "key", frand.Entropy256(), time.Time{}, testBucket, "/foo", "etag3", "", testMimeType, "", testMetadata, nil, newTestObject(1)); !errors.Is(err, api.ErrIdempotencyKeyReused) {
+		t.Fatalf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+
+	// assert the key can be reused once it expired, even if it wasn't pruned
+	// yet
+	if _, err := ss.DB().Exec(ctx, "UPDATE object_idempotency_keys SET created_at = ?", time.Now().Add(-idempotencyKeyTTL-time.Minute)); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.UpdateObjectIdempotent(ctx, "key", frand.Entropy256(), time.Time{}, testBucket, "/foo", "etag3", "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if obj.ETag != "etag3" {
+		t.Fatalf("expected object to be overwritten, got etag %v", obj.ETag)
+	}
+
+	// assert a conditional write fails if the object was stored after the
+	// given time but succeeds if it wasn't
+	since := time.Now().Add(-time.Hour)
+	if _, err := ss.UpdateObjectIdempotent(ctx, "key2", frand.Entropy256(), since, testBucket, "/foo", "etag4", "", testMimeType, "", testMetadata, nil, newTestObject(1)); !errors.Is(err, api.ErrObjectModified) {
+		t.Fatalf("expected ErrObjectModified, got %v", err)
+	} else if _, err := ss.UpdateObjectIdempotent(ctx, "key2", frand.Entropy256(), time.Now().Add(time.Hour), testBucket, "/foo", "etag4", "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.UpdateObjectIdempotent(ctx, "key3", frand.Entropy256(), since, testBucket, "/bar", "etag1", "", testMimeType, "", testMetadata, nil, newTestObject(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert expired keys are pruned
+	if _, err := ss.DB().Exec(ctx, "UPDATE object_idempotency_keys SET created_at = ? WHERE idempotency_key = ?", time.Now().Add(-idempotencyKeyTTL-time.Minute), "key"); err != nil {
+		t.Fatal(err)
+	} else if pruned, err := ss.pruneIdempotencyKeys(ctx, time.Now().Add(-idempotencyKeyTTL)); err != nil {
+		t.Fatal(err)
+	} else if pruned != 1 {
+		t.Fatalf("expected 1 pruned key, got %v", pruned)
+	}
+}
+
+func TestObjectEvents(t *testing.T) {
+	cfg := defaultTestSQLStoreConfig
+	cfg.objectEventLog = true
+	ss := newTestSQLStore(t, cfg)
+	defer ss.Close()
+
+	// perform a series of mutations
+	ctx := context.Background()
+	ss.objectEventDelay = 0
+	for _, key := range []string{"a", "a", "dir/1", "dir/2", "new/2"} {
+		if _, err := ss.addTestObject(key, newTestObject(0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ss.RenameObject(ctx, testBucket, "a", "b", false, false); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.RenameObjects(ctx, testBucket, "dir/", "new/", true); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.RemoveObjects(ctx, testBucket, "new/"); err != nil {
+		t.Fatal(err)
+	} else if err := ss.RemoveObject(ctx, testBucket, "b"); err != nil {
+		t.Fatal(err)
+	} else if err := ss.RenameObject(ctx, testBucket, "b", "c", false, false); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("unexpected error", err) // failed mutations are not recorded
+	}
+
+	type event struct{ op, key, renamedTo string }
+	expected := []event{
+		{api.ObjectEventCreate, "a", ""},
+		{api.ObjectEventUpdate, "a", ""},
+		{api.ObjectEventCreate, "dir/1", ""},
+		{api.ObjectEventCreate, "dir/2", ""},
+		{api.ObjectEventCreate, "new/2", ""},
+		{api.ObjectEventRename, "a", "b"},
+		{api.ObjectEventDelete, "new/2", ""},
+		{api.ObjectEventRename, "dir/1", "new/1"},
+		{api.ObjectEventRename, "dir/2", "new/2"},
+		{api.ObjectEventDelete, "new/1", ""},
+		{api.ObjectEventDelete, "new/2", ""},
+		{api.ObjectEventDelete, "b", ""},
+	}
+
+	// page through the log
+	var events []api.ObjectEvent
+	var marker uint64
+	for {
+		res, err := ss.ObjectEvents(ctx, marker, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, res.Events...)
+		marker = res.NextMarker
+		if !res.HasMore {
+			break
+		}
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i, e := range events {
+		if got := (event{e.Operation, e.Key, e.RenamedTo}); got != expected[i] {
+			t.Fatalf("event %d: expected %+v, got %+v", i, expected[i], got)
+		} else if e.Bucket != testBucket || e.ETag != testETag {
+			t.Fatalf("event %d: unexpected bucket or etag, %+v", i, e)
+		} else if e.Timestamp.IsZero() {
+			t.Fatalf("event %d: missing timestamp", i)
+		}
+	}
+
+	// fetching after the last event returns nothing
+	if res, err := ss.ObjectEvents(ctx, marker, 5); err != nil {
+		t.Fatal(err)
+	} else if len(res.Events) != 0 || res.HasMore || res.NextMarker != marker {
+		t.Fatalf("unexpected response %+v", res)
+	}
+
+	// recent events are held back
+	ss.objectEventDelay = time.Hour
+	if res, err := ss.ObjectEvents(ctx, 0, 5); err != nil {
+		t.Fatal(err)
+	} else if len(res.Events) != 0 || res.NextMarker != 0 {
+		t.Fatalf("unexpected response %+v", res)
+	}
+
+	// prune all events but the last one
+	if _, err := ss.DB().Exec(ctx, "UPDATE object_events SET created_at = ? WHERE id < ?", time.Now().Add(-time.Hour), marker); err != nil {
+		t.Fatal(err)
+	} else if pruned, err := ss.pruneObjectEvents(ctx, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	} else if pruned != int64(len(expected)-1) {
+		t.Fatalf("expected %d pruned events, got %d", len(expected)-1, pruned)
+	} else if n := ss.Count("object_events"); n != 1 {
+		t.Fatalf("expected 1 event, got %d", n)
+	}
+
+	// the log is disabled by default
+	ss2 := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss2.Close()
+	if _, err := ss2.addTestObject("a", newTestObject(0)); err != nil {
+		t.Fatal(err)
+	} else if n := ss2.Count("object_events"); n != 0 {
+		t.Fatalf("expected no events, got %d", n)
+	}
+}
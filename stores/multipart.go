@@ -5,15 +5,16 @@ import (
 	"fmt"
 	"sort"
 
+	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/object"
 	sql "go.sia.tech/renterd/stores/sql"
 )
 
-func (s *SQLStore) CreateMultipartUpload(ctx context.Context, bucket, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata) (api.MultipartCreateResponse, error) {
+func (s *SQLStore) CreateMultipartUpload(ctx context.Context, bucket, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey) (api.MultipartCreateResponse, error) {
 	var uploadID string
 	err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
-		uploadID, err = tx.InsertMultipartUpload(ctx, bucket, key, ec, mimeType, metadata)
+		uploadID, err = tx.InsertMultipartUpload(ctx, bucket, key, ec, mimeType, metadata, pinnedHosts)
 		return
 	})
 	if err != nil {
@@ -25,9 +26,27 @@ func (s *SQLStore) CreateMultipartUpload(ctx context.Context, bucket, key string
 }
 
 func (s *SQLStore) AddMultipartPart(ctx context.Context, bucket, key, eTag, uploadID string, partNumber int, slices []object.SlabSlice) (err error) {
-	return s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
 		return tx.AddMultipartPart(ctx, bucket, key, eTag, uploadID, partNumber, slices)
 	})
+	s.alertOnSlabKeyCollision(err)
+	return
+}
+
+func (s *SQLStore) AddMultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber, index int, checksum types.Hash256, slices []object.SlabSlice) (err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		return tx.AddMultipartPartProgress(ctx, bucket, key, uploadID, partNumber, index, checksum, slices)
+	})
+	s.alertOnSlabKeyCollision(err)
+	return
+}
+
+func (s *SQLStore) MultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber int) (slices []api.MultipartPartProgressSlice, err error) {
+	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+		slices, err = tx.MultipartPartProgress(ctx, bucket, key, uploadID, partNumber)
+		return
+	})
+	return
 }
 
 func (s *SQLStore) MultipartUpload(ctx context.Context, uploadID string) (resp api.MultipartUpload, err error) {
@@ -81,7 +100,12 @@ func (s *SQLStore) CompleteMultipartUpload(ctx context.Context, bucket, key stri
 	var eTag string
 	var prune bool
 	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
-		// Delete potentially existing object.
+		// Delete potentially existing object, remember whether it was
+		// pinned.
+		pinned, err := tx.ObjectPinned(ctx, bucket, key)
+		if err != nil {
+			return fmt.Errorf("failed to fetch pinned flag: %w", err)
+		}
 		prune, err = tx.DeleteObject(ctx, bucket, key)
 		if err != nil {
 			return fmt.Errorf("failed to delete object: %w", err)
@@ -91,8 +115,14 @@ func (s *SQLStore) CompleteMultipartUpload(ctx context.Context, bucket, key stri
 		eTag, err = tx.CompleteMultipartUpload(ctx, bucket, key, uploadID, parts, opts)
 		if err != nil {
 			return fmt.Errorf("failed to complete multipart upload: %w", err)
+		} else if err := checkBucketQuota(ctx, tx, bucket); err != nil {
+			return err
+		} else if pinned {
+			if err := tx.PinObject(ctx, bucket, key, true); err != nil {
+				return fmt.Errorf("failed to pin object: %w", err)
+			}
 		}
-		return nil
+		return s.recordObjectEvent(ctx, tx, objectEventOp(prune), bucket, key, "")
 	})
 	if err != nil {
 		return api.MultipartCompleteResponse{}, err
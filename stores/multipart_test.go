@@ -3,6 +3,7 @@ package stores
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"reflect"
 	"sort"
 	"strings"
@@ -47,7 +48,7 @@ func TestMultipartUploadWithUploadPackingRegression(t *testing.T) {
 	totalSize := int64(nParts * partSize)
 
 	// Upload parts until we have enough data for 2 buffers.
-	resp, err := ss.CreateMultipartUpload(ctx, testBucket, objName, object.NoOpKey, testMimeType, testMetadata)
+	resp, err := ss.CreateMultipartUpload(ctx, testBucket, objName, object.NoOpKey, testMimeType, testMetadata, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -184,15 +185,15 @@ func TestMultipartUploads(t *testing.T) {
 	defer ss.Close()
 
 	// create 3 multipart uploads, the first 2 have the same path
-	resp1, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo", object.NoOpKey, testMimeType, testMetadata)
+	resp1, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo", object.NoOpKey, testMimeType, testMetadata, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	resp2, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo", object.NoOpKey, testMimeType, testMetadata)
+	resp2, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo", object.NoOpKey, testMimeType, testMetadata, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	resp3, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo2", object.NoOpKey, testMimeType, testMetadata)
+	resp3, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo2", object.NoOpKey, testMimeType, testMetadata, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -272,16 +273,180 @@ func TestMultipartUploads(t *testing.T) {
 	}
 }
 
+func TestMultipartUploadParts(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// create an upload
+	ctx := context.Background()
+	resp, err := ss.CreateMultipartUpload(ctx, testBucket, "/foo", object.NoOpKey, testMimeType, testMetadata, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an upload without parts has no parts
+	lpr, err := ss.MultipartUploadParts(ctx, testBucket, "/foo", resp.UploadID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(lpr.Parts) != 0 || lpr.HasMore {
+		t.Fatal("unexpected response", lpr)
+	}
+
+	// add parts out of order
+	etags := make(map[int]string)
+	for _, pn := range []int{3, 1, 2} {
+		slices, _, err := ss.AddPartialSlab(ctx, frand.Bytes(pn*10), 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		etags[pn] = hex.EncodeToString(frand.Bytes(16))
+		if err := ss.AddMultipartPart(ctx, testBucket, "/foo", etags[pn], resp.UploadID, pn, slices); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert parts are returned ordered by part number
+	lpr, err = ss.MultipartUploadParts(ctx, testBucket, "/foo", resp.UploadID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(lpr.Parts) != 3 || lpr.HasMore {
+		t.Fatal("unexpected response", lpr)
+	}
+	for i, part := range lpr.Parts {
+		if part.PartNumber != i+1 {
+			t.Fatal("unexpected part number", part.PartNumber)
+		} else if part.ETag != etags[part.PartNumber] {
+			t.Fatal("unexpected etag", part.ETag)
+		} else if part.Size != int64(part.PartNumber*10) {
+			t.Fatal("unexpected size", part.Size)
+		}
+	}
+
+	// paginate
+	lpr, err = ss.MultipartUploadParts(ctx, testBucket, "/foo", resp.UploadID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(lpr.Parts) != 1 || lpr.Parts[0].PartNumber != 2 || !lpr.HasMore || lpr.NextMarker != 2 {
+		t.Fatal("unexpected response", lpr)
+	}
+
+	// unknown uploads should return an error
+	if _, err := ss.MultipartUploadParts(ctx, testBucket, "/foo", hex.EncodeToString(frand.Bytes(32)), 0, 0); !errors.Is(err, api.ErrMultipartUploadNotFound) {
+		t.Fatal("expected ErrMultipartUploadNotFound", err)
+	} else if _, err := ss.MultipartUploadParts(ctx, testBucket, "/bar", resp.UploadID, 0, 0); !errors.Is(err, api.ErrMultipartUploadNotFound) {
+		t.Fatal("expected ErrMultipartUploadNotFound", err)
+	}
+}
+
+func TestMultipartPartProgress(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// create an upload
+	ctx := context.Background()
+	resp, err := ss.CreateMultipartUpload(ctx, testBucket, "/foo", object.NoOpKey, testMimeType, testMetadata, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// persist the progress of part 1 out of order
+	addProgress := func(index int, size int) ([]object.SlabSlice, types.Hash256) {
+		t.Helper()
+		slices, _, err := ss.AddPartialSlab(ctx, frand.Bytes(size), 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checksum := frand.Entropy256()
+		if err := ss.AddMultipartPartProgress(ctx, testBucket, "/foo", resp.UploadID, 1, index, checksum, slices); err != nil {
+			t.Fatal(err)
+		}
+		return slices, checksum
+	}
+	_, c1 := addProgress(1, 20)
+	_, c0 := addProgress(0, 10)
+
+	// assert the part isn't listed
+	lpr, err := ss.MultipartUploadParts(ctx, testBucket, "/foo", resp.UploadID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(lpr.Parts) != 0 {
+		t.Fatal("expected no parts", lpr.Parts)
+	}
+
+	// assert the progress is returned ordered by index
+	assertProgress := func(checksums []types.Hash256, lengths []uint32) {
+		t.Helper()
+		progress, err := ss.MultipartPartProgress(ctx, testBucket, "/foo", resp.UploadID, 1)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(progress) != len(checksums) {
+			t.Fatal("unexpected progress", len(progress))
+		}
+		for i, ps := range progress {
+			if ps.Index != i {
+				t.Fatal("unexpected index", ps.Index)
+			} else if ps.Checksum != checksums[i] {
+				t.Fatal("unexpected checksum", ps.Checksum)
+			} else if ps.Slice.Length != lengths[i] {
+				t.Fatal("unexpected length", ps.Slice.Length)
+			}
+		}
+	}
+	assertProgress([]types.Hash256{c0, c1}, []uint32{10, 20})
+
+	// persisting the same index again replaces the slice
+	_, c0 = addProgress(0, 30)
+	assertProgress([]types.Hash256{c0, c1}, []uint32{30, 20})
+
+	// other parts and uploads have no progress
+	if progress, err := ss.MultipartPartProgress(ctx, testBucket, "/foo", resp.UploadID, 2); err != nil {
+		t.Fatal(err)
+	} else if len(progress) != 0 {
+		t.Fatal("unexpected progress", progress)
+	} else if progress, err := ss.MultipartPartProgress(ctx, testBucket, "/bar", resp.UploadID, 1); err != nil {
+		t.Fatal(err)
+	} else if len(progress) != 0 {
+		t.Fatal("unexpected progress", progress)
+	}
+
+	// adding the part removes its progress
+	slices, _, err := ss.AddPartialSlab(ctx, frand.Bytes(50), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := ss.AddMultipartPart(ctx, testBucket, "/foo", "etag", resp.UploadID, 1, slices); err != nil {
+		t.Fatal(err)
+	}
+	assertProgress(nil, nil)
+	lpr, err = ss.MultipartUploadParts(ctx, testBucket, "/foo", resp.UploadID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(lpr.Parts) != 1 || lpr.Parts[0].Size != 50 {
+		t.Fatal("unexpected parts", lpr.Parts)
+	}
+
+	// progress of a part that's uploaded again doesn't affect the finished
+	// part until it's replaced
+	_, c0 = addProgress(0, 10)
+	assertProgress([]types.Hash256{c0}, []uint32{10})
+	if _, err := ss.CompleteMultipartUpload(ctx, testBucket, "/foo", resp.UploadID, []api.MultipartCompletedPart{{PartNumber: 1, ETag: "etag"}}, api.CompleteMultipartOptions{}); err != nil {
+		t.Fatal(err)
+	} else if obj, err := ss.Object(ctx, testBucket, "/foo"); err != nil {
+		t.Fatal(err)
+	} else if obj.Size != 50 || len(obj.Object.Slabs) != 1 {
+		t.Fatal("unexpected object", obj.Size, len(obj.Object.Slabs))
+	}
+}
+
 func TestMultipartUploadEmptyObjects(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
 	defer ss.Close()
 
 	// create 2 multipart parts
-	resp1, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo1", object.NoOpKey, testMimeType, testMetadata)
+	resp1, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo1", object.NoOpKey, testMimeType, testMetadata, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	resp2, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo2", object.NoOpKey, testMimeType, testMetadata)
+	resp2, err := ss.CreateMultipartUpload(context.Background(), testBucket, "/foo2", object.NoOpKey, testMimeType, testMetadata, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
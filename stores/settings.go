@@ -43,6 +43,22 @@ func (s *SQLStore) UpdateUploadSettings(ctx context.Context, us api.UploadSettin
 	return s.updateSetting(ctx, SettingUpload, us)
 }
 
+// UpdateSettings updates the given settings in a single transaction, settings
+// that are nil remain unchanged.
+func (s *SQLStore) UpdateSettings(ctx context.Context, gs *api.GougingSettings, ps *api.PinnedSettings, us *api.UploadSettings) error {
+	settings := make(map[string]any)
+	if gs != nil {
+		settings[SettingGouging] = *gs
+	}
+	if ps != nil {
+		settings[SettingPinned] = *ps
+	}
+	if us != nil {
+		settings[SettingUpload] = *us
+	}
+	return s.updateSettings(ctx, settings)
+}
+
 func (s *SQLStore) S3Settings(ctx context.Context) (ss api.S3Settings, err error) {
 	err = s.fetchSetting(ctx, SettingS3, &ss)
 	return
@@ -84,24 +100,39 @@ func (s *SQLStore) fetchSetting(ctx context.Context, key string, out interface{}
 }
 
 func (s *SQLStore) updateSetting(ctx context.Context, key string, value any) error {
+	return s.updateSettings(ctx, map[string]any{key: value})
+}
+
+func (s *SQLStore) updateSettings(ctx context.Context, settings map[string]any) error {
 	s.settingsMu.Lock()
 	defer s.settingsMu.Unlock()
 
-	// marshal the value
-	b, err := json.Marshal(value)
-	if err != nil {
-		return fmt.Errorf("couldn't marshal the given value, error: %v", err)
+	// marshal the values
+	values := make(map[string]string, len(settings))
+	for key, value := range settings {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("couldn't marshal the given value, error: %v", err)
+		}
+		values[key] = string(b)
 	}
 
 	// update db first
-	err = s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
-		return tx.UpdateSetting(ctx, key, string(b))
+	err := s.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		for key, value := range values {
+			if err := tx.UpdateSetting(ctx, key, value); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return err
 	}
 
 	// update cache second
-	s.settings[key] = string(b)
+	for key, value := range values {
+		s.settings[key] = value
+	}
 	return nil
 }
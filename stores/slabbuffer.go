@@ -2,14 +2,17 @@ package stores
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/alerts"
@@ -20,31 +23,70 @@ import (
 	"lukechampine.com/frand"
 )
 
+const (
+	// SlabBufferCompressionNone indicates that the data of a slab buffer is
+	// stored on disk as is.
+	SlabBufferCompressionNone = ""
+
+	// SlabBufferCompressionZstd indicates that the data of a slab buffer is
+	// stored on disk as a sequence of zstd compressed frames.
+	SlabBufferCompressionZstd = "zstd"
+)
+
+// frameHeaderSize is the size of the header preceding every compressed frame
+// in a buffer file, it contains the uncompressed and compressed length of the
+// frame.
+const frameHeaderSize = 8
+
 var (
 	errBufferNotFound = errors.New("buffer not found")
 )
 
 type SlabBuffer struct {
-	dbID     uint
-	filename string
-	slabKey  object.EncryptionKey
-	maxSize  int64
+	dbID        uint
+	compression string
+	filename    string
+	slabKey     object.EncryptionKey
+	maxSize     int64
+	codec       *bufferCodec
 
 	mu          sync.Mutex
+	complete    bool // set when the buffer is forcefully marked as complete
 	file        *os.File
+	fileSize    int64
+	frames      []bufferFrame
 	lockedUntil time.Time
 	size        int64
 	syncErr     error
 }
 
+// bufferCodec compresses and decompresses the data of compressed buffers, it
+// is safe for concurrent use.
+type bufferCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+// bufferFrame describes a compressed frame within a buffer file. Every append
+// to a compressed buffer results in a new frame.
+type bufferFrame struct {
+	offset           int64 // offset of the uncompressed data
+	length           int64 // length of the uncompressed data
+	fileOffset       int64 // offset of the compressed data in the file
+	compressedLength int64 // length of the compressed data
+}
+
 type bufferGroupID [2]byte
 
 type SlabBufferManager struct {
 	alerts                          alerts.Alerter
 	bufferedSlabCompletionThreshold int64
+	codec                           *bufferCodec
+	compression                     string
 	db                              sql.Database
 	dir                             string
 	logger                          *zap.SugaredLogger
+	maxDiskUsage                    int64
 
 	mu                sync.Mutex
 	completeBuffers   map[bufferGroupID][]*SlabBuffer
@@ -52,12 +94,28 @@ type SlabBufferManager struct {
 	buffersByKey      map[string]*SlabBuffer
 }
 
-func newSlabBufferManager(ctx context.Context, a alerts.Alerter, db sql.Database, logger *zap.Logger, slabBufferCompletionThreshold int64, partialSlabDir string) (*SlabBufferManager, error) {
+func newSlabBufferManager(ctx context.Context, a alerts.Alerter, db sql.Database, logger *zap.Logger, slabBufferCompletionThreshold, maxDiskUsage int64, compression, partialSlabDir string) (*SlabBufferManager, error) {
 	logger = logger.Named("slabbuffers")
 	if slabBufferCompletionThreshold < 0 || slabBufferCompletionThreshold > 1<<22 {
 		return nil, fmt.Errorf("invalid slabBufferCompletionThreshold %v", slabBufferCompletionThreshold)
+	} else if maxDiskUsage < 0 {
+		return nil, fmt.Errorf("invalid slabBufferMaxDiskUsage %v", maxDiskUsage)
+	} else if !isValidBufferCompression(compression) {
+		return nil, fmt.Errorf("invalid slab buffer compression '%v'", compression)
 	}
 
+	// create the codec, it's always created since buffers that were created
+	// with compression enabled might still be around
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	codec := &bufferCodec{enc: enc, dec: dec}
+
 	var buffers []sql.LoadedSlabBuffer
 	var orphans []string
 	if err := db.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
@@ -70,9 +128,12 @@ func newSlabBufferManager(ctx context.Context, a alerts.Alerter, db sql.Database
 	mgr := &SlabBufferManager{
 		alerts:                          a,
 		bufferedSlabCompletionThreshold: slabBufferCompletionThreshold,
+		codec:                           codec,
+		compression:                     compression,
 		db:                              db,
 		dir:                             partialSlabDir,
 		logger:                          logger.Sugar(),
+		maxDiskUsage:                    maxDiskUsage,
 
 		completeBuffers:   make(map[bufferGroupID][]*SlabBuffer),
 		incompleteBuffers: make(map[bufferGroupID][]*SlabBuffer),
@@ -88,9 +149,7 @@ func newSlabBufferManager(ctx context.Context, a alerts.Alerter, db sql.Database
 	}
 
 	for _, buffer := range buffers {
-		// Open the file.
-		file, err := os.OpenFile(filepath.Join(partialSlabDir, buffer.Filename), os.O_RDWR, 0600)
-		if err != nil {
+		registerLoadAlert := func(err error) {
 			_ = a.RegisterAlert(ctx, alerts.Alert{
 				ID:       types.HashBytes([]byte(buffer.Filename)),
 				Severity: alerts.SeverityCritical,
@@ -101,18 +160,32 @@ func newSlabBufferManager(ctx context.Context, a alerts.Alerter, db sql.Database
 				},
 				Timestamp: time.Now(),
 			})
-			logger.Sugar().Errorf("failed to open buffer file %v for slab %v: %v", buffer.Filename, buffer.Key, err)
+			logger.Sugar().Errorf("failed to load buffer file %v for slab %v: %v", buffer.Filename, buffer.Key, err)
+		}
+
+		// Open the file.
+		file, err := os.OpenFile(filepath.Join(partialSlabDir, buffer.Filename), os.O_RDWR, 0600)
+		if err != nil {
+			registerLoadAlert(err)
 			continue
 		}
 
 		// Create the slab buffer.
 		sb := &SlabBuffer{
-			dbID:     uint(buffer.ID),
-			filename: buffer.Filename,
-			slabKey:  buffer.Key,
-			maxSize:  int64(bufferedSlabSize(buffer.MinShards)),
-			file:     file,
-			size:     buffer.Size,
+			dbID:        uint(buffer.ID),
+			compression: buffer.Compression,
+			filename:    buffer.Filename,
+			slabKey:     buffer.Key,
+			maxSize:     int64(bufferedSlabSize(buffer.MinShards)),
+			codec:       codec,
+			file:        file,
+			fileSize:    buffer.Size,
+			size:        buffer.Size,
+		}
+		if err := sb.loadFrames(); err != nil {
+			_ = file.Close()
+			registerLoadAlert(err)
+			continue
 		}
 		// Add the buffer to the manager.
 		gid := bufferGID(buffer.MinShards, buffer.TotalShards)
@@ -142,6 +215,10 @@ func (mgr *SlabBufferManager) Close() error {
 			errs = append(errs, err)
 		}
 	}
+	if err := mgr.codec.enc.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	mgr.codec.dec.Close()
 	mgr.buffersByKey = nil
 	mgr.incompleteBuffers = nil
 	mgr.completeBuffers = nil
@@ -159,6 +236,12 @@ func (mgr *SlabBufferManager) AddPartialSlab(ctx context.Context, data []byte, m
 		return nil, 0, fmt.Errorf("data size %v exceeds size of a slab %v", len(data), slabSize)
 	}
 
+	// Refuse to buffer more data if the buffers take up too much disk space,
+	// they need to be uploaded first.
+	if mgr.maxDiskUsage > 0 && mgr.Stats().DiskUsage >= mgr.maxDiskUsage {
+		return nil, 0, api.ErrSlabBufferFull
+	}
+
 	// Deep copy available buffers. We don't want to block the manager while we
 	// perform disk I/O.
 	mgr.mu.Lock()
@@ -190,7 +273,7 @@ func (mgr *SlabBufferManager) AddPartialSlab(ctx context.Context, data []byte, m
 	if len(data) > 0 {
 		var sb *SlabBuffer
 		err := mgr.db.Transaction(ctx, func(tx sql.DatabaseTx) error {
-			sb, err = createSlabBuffer(ctx, tx, mgr.dir, minShards, totalShards)
+			sb, err = createSlabBuffer(ctx, tx, mgr.dir, mgr.compression, mgr.codec, minShards, totalShards)
 			return err
 		})
 		if err != nil {
@@ -240,6 +323,29 @@ func (mgr *SlabBufferManager) BufferSize(gid bufferGroupID) (total int64) {
 	return
 }
 
+// Stats returns stats about the slab buffers and their disk usage.
+func (mgr *SlabBufferManager) Stats() (stats api.SlabBuffersStatsResponse) {
+	mgr.mu.Lock()
+	buffers := make([]*SlabBuffer, 0, len(mgr.buffersByKey))
+	for _, buffer := range mgr.buffersByKey {
+		buffers = append(buffers, buffer)
+	}
+	for _, complete := range mgr.completeBuffers {
+		stats.NumCompleteBuffers += uint64(len(complete))
+	}
+	mgr.mu.Unlock()
+
+	stats.NumBuffers = uint64(len(buffers))
+	stats.MaxDiskUsage = mgr.maxDiskUsage
+	for _, buffer := range buffers {
+		buffer.mu.Lock()
+		stats.TotalSize += buffer.size
+		stats.DiskUsage += buffer.fileSize
+		buffer.mu.Unlock()
+	}
+	return
+}
+
 func (mgr *SlabBufferManager) FetchPartialSlab(ctx context.Context, ec object.EncryptionKey, offset, length uint32) ([]byte, error) {
 	mgr.mu.Lock()
 	buffer, exists := mgr.buffersByKey[ec.String()]
@@ -249,7 +355,7 @@ func (mgr *SlabBufferManager) FetchPartialSlab(ctx context.Context, ec object.En
 	}
 
 	data := make([]byte, length)
-	_, err := buffer.file.ReadAt(data, int64(offset))
+	err := buffer.readAt(data, int64(offset))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read data from buffer (offset: %v, length: %v): %w", offset, length, err)
 	}
@@ -259,36 +365,62 @@ func (mgr *SlabBufferManager) FetchPartialSlab(ctx context.Context, ec object.En
 func (mgr *SlabBufferManager) SlabBuffers() (sbs []api.SlabBuffer) {
 	// Fetch buffers.
 	mgr.mu.Lock()
-	var completeBuffers, incompleteBuffers []*SlabBuffer
-	for _, buffers := range mgr.completeBuffers {
-		completeBuffers = append(completeBuffers, buffers...)
+	completeBuffers := make(map[bufferGroupID][]*SlabBuffer)
+	incompleteBuffers := make(map[bufferGroupID][]*SlabBuffer)
+	for gid, buffers := range mgr.completeBuffers {
+		completeBuffers[gid] = append([]*SlabBuffer{}, buffers...)
 	}
-	for _, buffers := range mgr.incompleteBuffers {
-		incompleteBuffers = append(incompleteBuffers, buffers...)
+	for gid, buffers := range mgr.incompleteBuffers {
+		incompleteBuffers[gid] = append([]*SlabBuffer{}, buffers...)
 	}
 	mgr.mu.Unlock()
 
 	// Convert them.
-	convertBuffer := func(buffer *SlabBuffer, complete bool) api.SlabBuffer {
+	convertBuffer := func(buffer *SlabBuffer, gid bufferGroupID, complete bool) api.SlabBuffer {
 		buffer.mu.Lock()
 		defer buffer.mu.Unlock()
 		return api.SlabBuffer{
-			Complete: complete,
-			Filename: buffer.filename,
-			Size:     buffer.size,
-			MaxSize:  buffer.maxSize,
-			Locked:   time.Now().Before(buffer.lockedUntil),
+			Complete:    complete,
+			Compression: buffer.compression,
+			Filename:    buffer.filename,
+			Size:        buffer.size,
+			SizeOnDisk:  buffer.fileSize,
+			MaxSize:     buffer.maxSize,
+			MinShards:   gid[0],
+			TotalShards: gid[1],
+			Locked:      time.Now().Before(buffer.lockedUntil),
 		}
 	}
-	for _, buffer := range completeBuffers {
-		sbs = append(sbs, convertBuffer(buffer, true))
+	for gid, buffers := range completeBuffers {
+		for _, buffer := range buffers {
+			sbs = append(sbs, convertBuffer(buffer, gid, true))
+		}
 	}
-	for _, buffer := range incompleteBuffers {
-		sbs = append(sbs, convertBuffer(buffer, false))
+	for gid, buffers := range incompleteBuffers {
+		for _, buffer := range buffers {
+			sbs = append(sbs, convertBuffer(buffer, gid, false))
+		}
 	}
 	return sbs
 }
 
+// MarkAllComplete marks all incomplete buffers as complete, regardless of the
+// completion threshold, which makes them available for upload. Data is no
+// longer appended to buffers that were marked complete.
+func (mgr *SlabBufferManager) MarkAllComplete() {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	for gid, buffers := range mgr.incompleteBuffers {
+		for _, buffer := range buffers {
+			buffer.mu.Lock()
+			buffer.complete = true
+			buffer.mu.Unlock()
+		}
+		mgr.completeBuffers[gid] = append(mgr.completeBuffers[gid], buffers...)
+		delete(mgr.incompleteBuffers, gid)
+	}
+}
+
 func (mgr *SlabBufferManager) SlabsForUpload(ctx context.Context, lockingDuration time.Duration, minShards, totalShards uint8, limit int) (slabs []api.PackedSlab, _ error) {
 	// Deep copy complete buffers. We don't want to block the manager while we
 	// perform disk I/O.
@@ -300,8 +432,10 @@ func (mgr *SlabBufferManager) SlabsForUpload(ctx context.Context, lockingDuratio
 		if !buffer.acquireForUpload(lockingDuration) {
 			continue
 		}
+		buffer.mu.Lock()
 		data := make([]byte, buffer.size)
-		_, err := buffer.file.ReadAt(data, 0)
+		buffer.mu.Unlock()
+		err := buffer.readAt(data, 0)
 		if err != nil {
 			mgr.alerts.RegisterAlert(ctx, alerts.Alert{
 				ID:       types.HashBytes([]byte(buffer.filename)),
@@ -376,10 +510,10 @@ func (buf *SlabBuffer) recordAppend(data []byte, mustFit bool, minShards uint8,
 	buf.mu.Lock()
 	defer buf.mu.Unlock()
 	remainingSpace := buf.maxSize - buf.size
-	if isCompleteBuffer(buf.size, buf.maxSize, completionThreshold) {
+	if buf.complete || isCompleteBuffer(buf.size, buf.maxSize, completionThreshold) {
 		return object.SlabSlice{}, data, false, nil
 	} else if int64(len(data)) <= remainingSpace {
-		_, err := buf.file.WriteAt(data, buf.size)
+		err := buf.write(data)
 		if err != nil {
 			return object.SlabSlice{}, nil, true, err
 		}
@@ -391,7 +525,7 @@ func (buf *SlabBuffer) recordAppend(data []byte, mustFit bool, minShards uint8,
 		buf.size += int64(len(data))
 		return slab, nil, true, nil
 	} else if !mustFit {
-		_, err := buf.file.WriteAt(data[:remainingSpace], buf.size)
+		err := buf.write(data[:remainingSpace])
 		if err != nil {
 			return object.SlabSlice{}, nil, true, err
 		}
@@ -407,6 +541,104 @@ func (buf *SlabBuffer) recordAppend(data []byte, mustFit bool, minShards uint8,
 	}
 }
 
+// loadFrames rebuilds the frames of a compressed buffer from its file, the
+// buffer's size needs to be set before calling it.
+func (buf *SlabBuffer) loadFrames() error {
+	switch buf.compression {
+	case SlabBufferCompressionNone:
+		return nil
+	case SlabBufferCompressionZstd:
+	default:
+		return fmt.Errorf("unknown compression '%v'", buf.compression)
+	}
+
+	var offset, fileOffset int64
+	header := make([]byte, frameHeaderSize)
+	for offset < buf.size {
+		if _, err := buf.file.ReadAt(header, fileOffset); err != nil {
+			return fmt.Errorf("failed to read frame header at offset %v: %w", fileOffset, err)
+		}
+		frame := bufferFrame{
+			offset:           offset,
+			length:           int64(binary.LittleEndian.Uint32(header[:4])),
+			fileOffset:       fileOffset + frameHeaderSize,
+			compressedLength: int64(binary.LittleEndian.Uint32(header[4:])),
+		}
+		buf.frames = append(buf.frames, frame)
+		offset += frame.length
+		fileOffset = frame.fileOffset + frame.compressedLength
+	}
+	if offset != buf.size {
+		return fmt.Errorf("buffer size %v doesn't match size of frames %v", buf.size, offset)
+	}
+	buf.fileSize = fileOffset
+	return nil
+}
+
+// readAt reads len(p) bytes of uncompressed data starting at offset off into
+// p, decompressing the data if necessary.
+func (buf *SlabBuffer) readAt(p []byte, off int64) error {
+	if buf.compression == SlabBufferCompressionNone {
+		_, err := buf.file.ReadAt(p, off)
+		return err
+	}
+
+	buf.mu.Lock()
+	frames := buf.frames
+	buf.mu.Unlock()
+
+	var n int64
+	end := off + int64(len(p))
+	for _, frame := range frames {
+		if frame.offset+frame.length <= off || frame.offset >= end {
+			continue
+		}
+		compressed := make([]byte, frame.compressedLength)
+		if _, err := buf.file.ReadAt(compressed, frame.fileOffset); err != nil {
+			return fmt.Errorf("failed to read frame at offset %v: %w", frame.fileOffset, err)
+		}
+		data, err := buf.codec.dec.DecodeAll(compressed, make([]byte, 0, frame.length))
+		if err != nil {
+			return fmt.Errorf("failed to decompress frame at offset %v: %w", frame.fileOffset, err)
+		} else if int64(len(data)) != frame.length {
+			return fmt.Errorf("decompressed frame has length %v, expected %v", len(data), frame.length)
+		}
+		start, stop := max(off, frame.offset), min(end, frame.offset+frame.length)
+		n += int64(copy(p[start-off:stop-off], data[start-frame.offset:stop-frame.offset]))
+	}
+	if n != int64(len(p)) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// write appends data to the buffer's file, compressing it if necessary. The
+// caller is expected to hold the buffer's lock and to update its size.
+func (buf *SlabBuffer) write(data []byte) error {
+	if buf.compression == SlabBufferCompressionNone {
+		_, err := buf.file.WriteAt(data, buf.size)
+		if err == nil {
+			buf.fileSize = buf.size + int64(len(data))
+		}
+		return err
+	}
+
+	frame := buf.codec.enc.EncodeAll(data, make([]byte, frameHeaderSize, frameHeaderSize+len(data)))
+	binary.LittleEndian.PutUint32(frame[:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(frame[4:], uint32(len(frame)-frameHeaderSize))
+	if _, err := buf.file.WriteAt(frame, buf.fileSize); err != nil {
+		return err
+	}
+	buf.frames = append(buf.frames, bufferFrame{
+		offset:           buf.size,
+		length:           int64(len(data)),
+		fileOffset:       buf.fileSize + frameHeaderSize,
+		compressedLength: int64(len(frame) - frameHeaderSize),
+	})
+	buf.fileSize += int64(len(frame))
+	return nil
+}
+
 func (buf *SlabBuffer) commitAppend(completionThreshold int64) (bool, error) {
 	// Fetch the current size first. We know that we have at least synced the
 	// buffer up to this point upon success.
@@ -456,7 +688,11 @@ func bufferedSlabSize(minShards uint8) int {
 	return int(rhpv2.SectorSize) * int(minShards)
 }
 
-func createSlabBuffer(ctx context.Context, tx sql.DatabaseTx, dir string, minShards, totalShards uint8) (*SlabBuffer, error) {
+func isValidBufferCompression(compression string) bool {
+	return compression == SlabBufferCompressionNone || compression == SlabBufferCompressionZstd
+}
+
+func createSlabBuffer(ctx context.Context, tx sql.DatabaseTx, dir, compression string, codec *bufferCodec, minShards, totalShards uint8) (*SlabBuffer, error) {
 	// Create a new buffer and slab.
 	fileName := bufferFilename(minShards, totalShards)
 	file, err := os.Create(filepath.Join(dir, fileName))
@@ -465,15 +701,17 @@ func createSlabBuffer(ctx context.Context, tx sql.DatabaseTx, dir string, minSha
 	}
 
 	ec := object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted)
-	bufferedSlabID, err := tx.InsertBufferedSlab(ctx, fileName, ec, minShards, totalShards)
+	bufferedSlabID, err := tx.InsertBufferedSlab(ctx, fileName, compression, ec, minShards, totalShards)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert buffered slab: %w", err)
 	}
 	return &SlabBuffer{
-		dbID:     uint(bufferedSlabID),
-		filename: fileName,
-		slabKey:  ec,
-		maxSize:  int64(bufferedSlabSize(minShards)),
-		file:     file,
+		dbID:        uint(bufferedSlabID),
+		compression: compression,
+		filename:    fileName,
+		slabKey:     ec,
+		maxSize:     int64(bufferedSlabSize(minShards)),
+		codec:       codec,
+		file:        file,
 	}, err
 }
@@ -1,10 +1,15 @@
 package stores
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
 	"lukechampine.com/frand"
 )
 
@@ -13,7 +18,7 @@ func TestRecordAppendToCompletedBuffer(t *testing.T) {
 	defer ss.Close()
 
 	completionThreshold := int64(1000)
-	mgr, err := newSlabBufferManager(context.Background(), ss.alerts, ss.db, ss.logger.Desugar(), completionThreshold, t.TempDir())
+	mgr, err := newSlabBufferManager(context.Background(), ss.alerts, ss.db, ss.logger.Desugar(), completionThreshold, 0, SlabBufferCompressionNone, t.TempDir())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,7 +65,7 @@ func TestMarkBufferCompleteTwice(t *testing.T) {
 	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
 	defer ss.Close()
 
-	mgr, err := newSlabBufferManager(context.Background(), ss.alerts, ss.db, ss.logger.Desugar(), 0, t.TempDir())
+	mgr, err := newSlabBufferManager(context.Background(), ss.alerts, ss.db, ss.logger.Desugar(), 0, 0, SlabBufferCompressionNone, t.TempDir())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -101,3 +106,188 @@ func TestMarkBufferCompleteTwice(t *testing.T) {
 		t.Fatal("expected error marking buffer complete twice", err)
 	}
 }
+
+func TestSlabBufferMaxDiskUsage(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	mgr, err := newSlabBufferManager(context.Background(), ss.alerts, ss.db, ss.logger.Desugar(), 0, 100, SlabBufferCompressionNone, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Close()
+
+	// add two partial slabs, the second one exceeds the max disk usage
+	for i := 0; i < 2; i++ {
+		if _, _, err := mgr.AddPartialSlab(context.Background(), frand.Bytes(60), 1, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert the stats
+	stats := mgr.Stats()
+	if stats.NumBuffers != 1 || stats.NumCompleteBuffers != 0 || stats.TotalSize != 120 || stats.DiskUsage != 120 || stats.MaxDiskUsage != 100 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// adding another partial slab should fail
+	if _, _, err := mgr.AddPartialSlab(context.Background(), frand.Bytes(1), 1, 2); !errors.Is(err, api.ErrSlabBufferFull) {
+		t.Fatal("expected ErrSlabBufferFull, got", err)
+	}
+
+	// upload the buffer, which frees up the disk space
+	mgr.MarkAllComplete()
+	sbs := mgr.SlabBuffers()
+	if len(sbs) != 1 {
+		t.Fatalf("expected 1 buffer, got %v", len(sbs))
+	}
+	mgr.RemoveBuffers(sbs[0].Filename)
+	if stats := mgr.Stats(); stats.NumBuffers != 0 || stats.DiskUsage != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// adding a partial slab should work again
+	if _, _, err := mgr.AddPartialSlab(context.Background(), frand.Bytes(1), 1, 2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompressedSlabBuffer(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// replace the manager with one that compresses buffers
+	dir := t.TempDir()
+	if err := ss.slabBufferMgr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := newSlabBufferManager(context.Background(), ss.alerts, ss.db, ss.logger.Desugar(), 0, 0, SlabBufferCompressionZstd, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.slabBufferMgr = mgr
+
+	// add two partial slabs of text-heavy data
+	data1 := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 2000))
+	data2 := []byte(strings.Repeat("lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 3000))
+	slices1, _, err := ss.AddPartialSlab(context.Background(), data1, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices2, _, err := ss.AddPartialSlab(context.Background(), data2, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(slices1) != 1 || len(slices2) != 1 {
+		t.Fatal("expected 1 slice per partial slab")
+	}
+
+	// assert the buffer is compressed
+	buffers, err := ss.SlabBuffers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(buffers) != 1 {
+		t.Fatal("expected 1 buffer", len(buffers))
+	} else if buffers[0].Compression != SlabBufferCompressionZstd {
+		t.Fatal("unexpected compression", buffers[0].Compression)
+	} else if buffers[0].Size != int64(len(data1)+len(data2)) {
+		t.Fatal("unexpected size", buffers[0].Size)
+	} else if buffers[0].SizeOnDisk >= buffers[0].Size/10 {
+		t.Fatal("expected buffer to be compressed", buffers[0].SizeOnDisk, buffers[0].Size)
+	}
+	t.Logf("compressed %d bytes to %d bytes on disk", buffers[0].Size, buffers[0].SizeOnDisk)
+
+	// assert we can fetch the data, including a range that spans both frames
+	assertFetch := func(offset, length int) {
+		t.Helper()
+		data := append(append([]byte{}, data1...), data2...)
+		fetched, err := ss.FetchPartialSlab(context.Background(), slices1[0].EncryptionKey, uint32(offset), uint32(length))
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(fetched, data[offset:offset+length]) {
+			t.Fatal("data mismatch")
+		}
+	}
+	assertFetch(0, len(data1))
+	assertFetch(len(data1), len(data2))
+	assertFetch(len(data1)-10, 20)
+
+	// add an object that references both slices so the buffer size is
+	// persisted and reload the manager
+	obj := object.Object{
+		Key:   object.GenerateEncryptionKey(object.EncryptionKeyTypeSalted),
+		Slabs: append(slices1, slices2...),
+	}
+	if err := ss.UpdateObjectBlocking(context.Background(), testBucket, "obj", testETag, testMimeType, testMetadata, obj); err != nil {
+		t.Fatal(err)
+	} else if err := mgr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err = newSlabBufferManager(context.Background(), ss.alerts, ss.db, ss.logger.Desugar(), 0, 0, SlabBufferCompressionNone, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.slabBufferMgr = mgr
+
+	// the buffer should still be readable even though compression is disabled
+	assertFetch(0, len(data1))
+	assertFetch(len(data1)-10, 20)
+}
+
+func TestMarkAllComplete(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	mgr, err := newSlabBufferManager(context.Background(), ss.alerts, ss.db, ss.logger.Desugar(), 1000, 0, SlabBufferCompressionNone, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Close()
+
+	// add a slab that doesn't fill the buffer
+	minShards, totalShards := uint8(1), uint8(2)
+	gid := bufferGID(minShards, totalShards)
+	_, _, err = mgr.AddPartialSlab(context.Background(), frand.Bytes(100), minShards, totalShards)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(mgr.incompleteBuffers[gid]) != 1 {
+		t.Fatalf("expected 1 incomplete buffer, got %v", len(mgr.incompleteBuffers[gid]))
+	}
+
+	// no slabs should be ready for upload
+	slabs, err := mgr.SlabsForUpload(context.Background(), time.Minute, minShards, totalShards, -1)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(slabs) != 0 {
+		t.Fatalf("expected no slabs, got %v", len(slabs))
+	}
+
+	// mark all buffers as complete
+	mgr.MarkAllComplete()
+	if len(mgr.completeBuffers[gid]) != 1 {
+		t.Fatalf("expected 1 complete buffer, got %v", len(mgr.completeBuffers[gid]))
+	} else if len(mgr.incompleteBuffers[gid]) != 0 {
+		t.Fatalf("expected 0 incomplete buffers, got %v", len(mgr.incompleteBuffers[gid]))
+	} else if sbs := mgr.SlabBuffers(); len(sbs) != 1 || !sbs[0].Complete || sbs[0].MinShards != minShards || sbs[0].TotalShards != totalShards {
+		t.Fatalf("unexpected slab buffers %+v", sbs)
+	}
+
+	// new data should go into a new buffer
+	_, _, err = mgr.AddPartialSlab(context.Background(), frand.Bytes(100), minShards, totalShards)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(mgr.completeBuffers[gid]) != 1 {
+		t.Fatalf("expected 1 complete buffer, got %v", len(mgr.completeBuffers[gid]))
+	} else if len(mgr.incompleteBuffers[gid]) != 1 {
+		t.Fatalf("expected 1 incomplete buffer, got %v", len(mgr.incompleteBuffers[gid]))
+	}
+
+	// the completed buffer should be ready for upload
+	slabs, err = mgr.SlabsForUpload(context.Background(), time.Minute, minShards, totalShards, -1)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(slabs) != 1 {
+		t.Fatalf("expected 1 slab, got %v", len(slabs))
+	} else if len(slabs[0].Data) != 100 {
+		t.Fatalf("expected 100 bytes, got %v", len(slabs[0].Data))
+	}
+}
@@ -9,10 +9,17 @@ import (
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/alerts"
+	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/stores/sql"
 	"go.uber.org/zap"
 )
 
+const (
+	// dbHealthDegradedLatency is the round-trip latency above which the
+	// database is considered degraded.
+	dbHealthDegradedLatency = 250 * time.Millisecond
+)
+
 type (
 	// Config contains all params for creating a SQLStore
 	Config struct {
@@ -24,6 +31,11 @@ type (
 		AnnouncementMaxAge            time.Duration
 		WalletAddress                 types.Address
 		SlabBufferCompletionThreshold int64
+		SlabBufferCompression         string
+		SlabBufferMaxDiskUsage        int64
+		SlabPruningParallelism        int
+		ObjectEventLog                bool
+		ObjectEventRetention          time.Duration
 		Logger                        *zap.Logger
 		LongQueryDuration             time.Duration
 		LongTxDuration                time.Duration
@@ -43,7 +55,11 @@ type (
 		walletAddress types.Address
 
 		// ObjectDB related fields
-		slabBufferMgr *SlabBufferManager
+		slabBufferMgr          *SlabBufferManager
+		slabPruningParallelism int
+		objectEventLog         bool
+		objectEventRetention   time.Duration
+		objectEventDelay       time.Duration
 
 		// SettingsDB related fields
 		settingsMu sync.Mutex
@@ -54,6 +70,7 @@ type (
 
 		hostSectorPruneSigChan chan struct{}
 		slabPruneSigChan       chan struct{}
+		tombstonePruneSigChan  chan struct{}
 		wg                     sync.WaitGroup
 
 		mu                      sync.Mutex
@@ -100,8 +117,14 @@ func NewSQLStore(cfg Config) (*SQLStore, error) {
 		settings:      make(map[string]string),
 		walletAddress: cfg.WalletAddress,
 
+		slabPruningParallelism: cfg.SlabPruningParallelism,
+		objectEventLog:         cfg.ObjectEventLog,
+		objectEventRetention:   cfg.ObjectEventRetention,
+		objectEventDelay:       objectEventVisibilityDelay,
+
 		hostSectorPruneSigChan: make(chan struct{}, 1),
 		slabPruneSigChan:       make(chan struct{}, 1),
+		tombstonePruneSigChan:  make(chan struct{}, 1),
 
 		lastPrunedHostSectorsAt: time.Now(),
 		lastPrunedSlabsAt:       time.Now(),
@@ -110,7 +133,7 @@ func NewSQLStore(cfg Config) (*SQLStore, error) {
 		shutdownCtxCancel: shutdownCtxCancel,
 	}
 
-	ss.slabBufferMgr, err = newSlabBufferManager(shutdownCtx, cfg.Alerts, dbMain, l, cfg.SlabBufferCompletionThreshold, cfg.PartialSlabDir)
+	ss.slabBufferMgr, err = newSlabBufferManager(shutdownCtx, cfg.Alerts, dbMain, l, cfg.SlabBufferCompletionThreshold, cfg.SlabBufferMaxDiskUsage, cfg.SlabBufferCompression, cfg.PartialSlabDir)
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +142,50 @@ func NewSQLStore(cfg Config) (*SQLStore, error) {
 	return ss, nil
 }
 
+// DatabaseHealth runs a trivial query against the database to measure its
+// round-trip latency and checks whether all migrations were applied and the
+// connection pool has connections available. It's cheap enough to be polled
+// frequently.
+func (s *SQLStore) DatabaseHealth(ctx context.Context) (dh api.DatabaseHealth) {
+	dh.Status = api.HealthStatusOK
+
+	// check connection pool
+	stats := s.db.Stats()
+	dh.MaxOpenConnections = stats.MaxOpenConnections
+	dh.OpenConnections = stats.OpenConnections
+	dh.InUse = stats.InUse
+	dh.Idle = stats.Idle
+	dh.ConnectionsAvailable = stats.MaxOpenConnections == 0 || stats.InUse < stats.MaxOpenConnections
+	if !dh.ConnectionsAvailable {
+		dh.Status = api.HealthStatusDegraded
+	}
+
+	// measure latency
+	latency, err := s.db.Ping(ctx)
+	if err != nil {
+		dh.Status = api.HealthStatusUnavailable
+		dh.Error = err.Error()
+		return
+	}
+	dh.Latency = api.DurationMS(latency)
+	if latency > dbHealthDegradedLatency {
+		dh.Status = api.HealthStatusDegraded
+	}
+
+	// check migrations
+	dh.PendingMigrations, err = s.db.PendingMigrations(ctx)
+	if err != nil {
+		dh.Status = api.HealthStatusUnavailable
+		dh.Error = err.Error()
+		return
+	}
+	dh.Migrated = dh.PendingMigrations == 0
+	if !dh.Migrated {
+		dh.Status = api.HealthStatusUnavailable
+	}
+	return
+}
+
 func (s *SQLStore) initPruneLoops() {
 	s.wg.Add(1)
 	go func() {
@@ -130,6 +197,43 @@ func (s *SQLStore) initPruneLoops() {
 		s.pruneSlabsLoop()
 		s.wg.Done()
 	}()
+	s.wg.Add(1)
+	go func() {
+		s.pruneTombstonesLoop()
+		s.wg.Done()
+	}()
+	s.wg.Add(1)
+	go func() {
+		s.expireObjectsLoop()
+		s.wg.Done()
+	}()
+	s.wg.Add(1)
+	go func() {
+		s.pruneIdempotencyKeysLoop()
+		s.wg.Done()
+	}()
+	if s.objectEventLog && s.objectEventRetention > 0 {
+		s.wg.Add(1)
+		go func() {
+			s.pruneObjectEventsLoop()
+			s.wg.Done()
+		}()
+	}
+
+	// objects might have been marked as deleted before a restart, only
+	// trigger pruning if that's the case to avoid a write transaction racing
+	// with the first queries against the store
+	var exists bool
+	if err := s.db.Transaction(s.shutdownCtx, func(tx sql.DatabaseTx) (err error) {
+		exists, err = tx.ObjectTombstonesExist(s.shutdownCtx)
+		return
+	}); err != nil {
+		s.logger.Warnw("failed to check for object tombstones", zap.Error(err))
+		exists = true
+	}
+	if exists {
+		s.triggerTombstonePruning()
+	}
 }
 
 // Close closes the underlying database connection of the store.
@@ -0,0 +1,261 @@
+package sql
+
+import (
+	"context"
+	dsql "database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.sia.tech/renterd/internal/sql"
+	"go.uber.org/zap"
+)
+
+// ErrCopyConflict is returned when a copied row conflicts with a row that
+// already exists in the destination database.
+var ErrCopyConflict = errors.New("row conflicts with an existing row in the destination")
+
+type copyTable struct {
+	name string
+	keys []string // unique columns used to paginate the table
+}
+
+// mainTables contains all tables of the main database in the order they need
+// to be copied in to satisfy foreign key constraints.
+var mainTables = []copyTable{
+	{"hosts", []string{"id"}},
+	{"host_addresses", []string{"id"}},
+	{"host_checks", []string{"id"}},
+	{"host_sector_verifications", []string{"id"}},
+	{"host_allowlist_entries", []string{"id"}},
+	{"host_allowlist_entry_hosts", []string{"db_allowlist_entry_id", "db_host_id"}},
+	{"host_blocklist_entries", []string{"id"}},
+	{"host_blocklist_entry_hosts", []string{"db_blocklist_entry_id", "db_host_id"}},
+	{"contracts", []string{"id"}},
+	{"contract_elements", []string{"id"}},
+	{"buckets", []string{"id"}},
+	{"bucket_lifecycle_rules", []string{"id"}},
+	{"buffered_slabs", []string{"id"}},
+	{"slabs", []string{"id"}},
+	{"sectors", []string{"id"}},
+	{"contract_sectors", []string{"db_sector_id", "db_contract_id"}},
+	{"host_sectors", []string{"db_sector_id", "db_host_id"}},
+	{"contract_offboardings", []string{"id"}},
+	{"objects", []string{"id"}},
+	{"multipart_uploads", []string{"id"}},
+	{"multipart_parts", []string{"id"}},
+	{"multipart_part_progress", []string{"id"}},
+	{"slices", []string{"id"}},
+	{"object_user_metadata", []string{"id"}},
+	{"object_pinned_hosts", []string{"id"}},
+	{"object_idempotency_keys", []string{"id"}},
+	{"object_events", []string{"id"}},
+	{"consensus_infos", []string{"id"}},
+	{"settings", []string{"id"}},
+	{"autopilot_config", []string{"id"}},
+	{"ephemeral_accounts", []string{"id"}},
+	{"webhooks", []string{"id"}},
+	{"syncer_peers", []string{"id"}},
+	{"syncer_bans", []string{"id"}},
+	{"wallet_events", []string{"id"}},
+	{"wallet_outputs", []string{"id"}},
+}
+
+// CopyMainDatabase copies all rows of the main database in src to dst,
+// preserving their ids. Both databases are expected to be fully migrated.
+// Rows are copied in batches, each batch being inserted in its own
+// transaction, and every table is resumed from the last row found in dst so
+// an interrupted copy can be picked up by calling CopyMainDatabase again.
+// After all tables were copied, the row counts of both databases are compared.
+//
+// Rows are never overwritten or skipped, a row that conflicts with an existing
+// row in dst causes the copy to fail with ErrCopyConflict.
+//
+// NOTE: conflictMsg is the dialect specific error message of a unique
+// constraint violation, e.g. 'Duplicate entry' for MySQL.
+func CopyMainDatabase(ctx context.Context, src *dsql.DB, dst *sql.DB, conflictMsg string, batchSize int, l *zap.SugaredLogger) error {
+	if batchSize <= 0 {
+		return errors.New("batch size must be greater than zero")
+	}
+
+	// make sure both databases are on the same schema
+	srcMigrations, err := migrationIDs(ctx, src)
+	if err != nil {
+		return fmt.Errorf("failed to fetch source migrations: %w", err)
+	}
+	dstMigrations, err := migrationIDs(ctx, dst.DB())
+	if err != nil {
+		return fmt.Errorf("failed to fetch destination migrations: %w", err)
+	}
+	if !slices.Equal(srcMigrations, dstMigrations) {
+		return errors.New("source and destination database have different migrations applied, make sure both are fully migrated")
+	}
+
+	// copy tables
+	for _, t := range mainTables {
+		l.Infof("copying table '%s'", t.name)
+		n, err := copyTableRows(ctx, src, dst, t, conflictMsg, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to copy table '%s': %w", t.name, err)
+		}
+		l.Infof("copied %d rows from table '%s'", n, t.name)
+	}
+
+	// validate row counts
+	for _, t := range mainTables {
+		var srcCount, dstCount int64
+		if err := src.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM `%s`", t.name)).Scan(&srcCount); err != nil {
+			return fmt.Errorf("failed to count rows in source table '%s': %w", t.name, err)
+		} else if err := dst.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM `%s`", t.name)).Scan(&dstCount); err != nil {
+			return fmt.Errorf("failed to count rows in destination table '%s': %w", t.name, err)
+		} else if srcCount != dstCount {
+			return fmt.Errorf("row count mismatch for table '%s': %d in source, %d in destination", t.name, srcCount, dstCount)
+		}
+	}
+	return nil
+}
+
+func copyTableRows(ctx context.Context, src *dsql.DB, dst *sql.DB, t copyTable, conflictMsg string, batchSize int) (copied int64, _ error) {
+	keys := quoteColumns(t.keys)
+
+	// resume from the last row in the destination table
+	last := make([]any, len(t.keys))
+	lastPtrs := make([]any, len(t.keys))
+	for i := range last {
+		lastPtrs[i] = &last[i]
+	}
+	err := dst.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM `%s` ORDER BY %s LIMIT 1", strings.Join(keys, ", "), t.name, strings.Join(orderDesc(keys), ", "))).Scan(lastPtrs...)
+	if errors.Is(err, dsql.ErrNoRows) {
+		last = nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to fetch last copied row: %w", err)
+	}
+
+	for {
+		// fetch the next batch
+		query := fmt.Sprintf("SELECT * FROM `%s`", t.name)
+		var args []any
+		if last != nil {
+			where, whereArgs := keysetCondition(keys, last)
+			query += " WHERE " + where
+			args = append(args, whereArgs...)
+		}
+		query += fmt.Sprintf(" ORDER BY %s LIMIT %d", strings.Join(keys, ", "), batchSize)
+		cols, batch, err := queryRows(ctx, src, query, args...)
+		if err != nil {
+			return copied, fmt.Errorf("failed to fetch rows: %w", err)
+		} else if len(batch) == 0 {
+			return copied, nil
+		}
+
+		// insert it
+		placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ") + ")"
+		values := make([]string, len(batch))
+		insertArgs := make([]any, 0, len(batch)*len(cols))
+		for i, row := range batch {
+			values[i] = placeholders
+			insertArgs = append(insertArgs, row...)
+		}
+		err = dst.Transaction(ctx, func(tx sql.Tx) error {
+			_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO `%s` (%s) VALUES %s", t.name, strings.Join(quoteColumns(cols), ", "), strings.Join(values, ", ")), insertArgs...)
+			return err
+		})
+		if err != nil && strings.Contains(err.Error(), conflictMsg) {
+			return copied, fmt.Errorf("%w: %v", ErrCopyConflict, err)
+		} else if err != nil {
+			return copied, fmt.Errorf("failed to insert rows: %w", err)
+		}
+		copied += int64(len(batch))
+
+		// update the last key
+		lastRow := batch[len(batch)-1]
+		last = make([]any, len(t.keys))
+		for i, key := range t.keys {
+			idx := slices.Index(cols, key)
+			if idx == -1 {
+				return copied, fmt.Errorf("key column '%s' not found", key)
+			}
+			last[i] = lastRow[idx]
+		}
+	}
+}
+
+// keysetCondition returns a condition that matches all rows that come after
+// the row identified by the given key values when ordering by the given keys.
+func keysetCondition(keys []string, values []any) (string, []any) {
+	var conds []string
+	var args []any
+	for i := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, keys[j]+" = ?")
+			args = append(args, values[j])
+		}
+		parts = append(parts, keys[i]+" > ?")
+		args = append(args, values[i])
+		conds = append(conds, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+func migrationIDs(ctx context.Context, db *dsql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id FROM migrations ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func orderDesc(cols []string) []string {
+	out := make([]string, len(cols))
+	for i, col := range cols {
+		out[i] = col + " DESC"
+	}
+	return out
+}
+
+func queryRows(ctx context.Context, db *dsql.DB, query string, args ...any) ([]string, [][]any, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var batch [][]any
+	for rows.Next() {
+		row := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		batch = append(batch, row)
+	}
+	return cols, batch, rows.Err()
+}
+
+func quoteColumns(cols []string) []string {
+	out := make([]string, len(cols))
+	for i, col := range cols {
+		out[i] = "`" + col + "`"
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package sql
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// TestMainTablesComplete asserts every table of the main database's schema is
+// copied by CopyMainDatabase.
+func TestMainTablesComplete(t *testing.T) {
+	schema, err := os.ReadFile("sqlite/migrations/main/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for _, match := range regexp.MustCompile("CREATE TABLE `?([a-z_]+)`?").FindAllSubmatch(schema, -1) {
+		want = append(want, string(match[1]))
+	}
+	var got []string
+	for _, table := range mainTables {
+		got = append(got, table.name)
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("tables copied don't match the schema\ngot:  %v\nwant: %v", got, want)
+	}
+}
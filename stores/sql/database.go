@@ -2,6 +2,7 @@ package sql
 
 import (
 	"context"
+	dsql "database/sql"
 	"io"
 	"time"
 
@@ -45,9 +46,24 @@ type (
 		// PartialSlabDir returns the directory where partial slabs are stored.
 		PartialSlabDir() string
 
+		// PendingMigrations returns the number of migrations that weren't
+		// applied to the database yet.
+		PendingMigrations(ctx context.Context) (int, error)
+
+		// Ping runs a trivial query against the database and returns the
+		// round-trip latency.
+		Ping(ctx context.Context) (time.Duration, error)
+
+		// Stats returns the connection pool stats of the database.
+		Stats() dsql.DBStats
+
 		// Transaction starts a new transaction.
 		Transaction(ctx context.Context, fn func(DatabaseTx) error) error
 
+		// TransactionRetries returns the number of times transactions had to
+		// be retried due to contention, grouped by operation.
+		TransactionRetries() map[string]uint64
+
 		// Version returns the database version and name.
 		Version(ctx context.Context) (string, string, error)
 	}
@@ -63,6 +79,11 @@ type (
 		// AddMultipartPart adds a part to an unfinished multipart upload.
 		AddMultipartPart(ctx context.Context, bucket, key, eTag, uploadID string, partNumber int, slices object.SlabSlices) error
 
+		// AddMultipartPartProgress persists slices of a part that is still
+		// being uploaded, starting at the given index. Slices previously
+		// persisted at the same indices are replaced.
+		AddMultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber, index int, checksum types.Hash256, slices object.SlabSlices) error
+
 		// AddPeer adds a peer to the store.
 		AddPeer(ctx context.Context, addr string) error
 
@@ -81,6 +102,10 @@ type (
 		// AutopilotConfig returns the autopilot configuration.
 		AutopilotConfig(ctx context.Context) (api.AutopilotConfig, error)
 
+		// BackfillObjectChecksum sets the checksum of the object with the
+		// given ID unless it already has one.
+		BackfillObjectChecksum(ctx context.Context, id uint64, checksum string) error
+
 		// BanPeer temporarily bans one or more IPs. The addr should either be a
 		// single IP with port (e.g. 1.2.3.4:5678) or a CIDR subnet (e.g.
 		// 1.2.3.4/16).
@@ -90,6 +115,15 @@ type (
 		// exist, it returns api.ErrBucketNotFound.
 		Bucket(ctx context.Context, bucket string) (api.Bucket, error)
 
+		// BucketLifecycleRules returns the lifecycle rules of the bucket with
+		// the given name. If the bucket doesn't exist, it returns
+		// api.ErrBucketNotFound.
+		BucketLifecycleRules(ctx context.Context, bucket string) ([]api.BucketLifecycleRule, error)
+
+		// BucketSize returns the total size of the objects in the bucket with
+		// the given name.
+		BucketSize(ctx context.Context, bucket string) (uint64, error)
+
 		// Buckets returns a list of all buckets in the database.
 		Buckets(ctx context.Context) ([]api.Bucket, error)
 
@@ -115,6 +149,10 @@ type (
 		// well as the estimated number of bytes that can be pruned from it.
 		ContractSize(ctx context.Context, id types.FileContractID) (api.ContractSize, error)
 
+		// ContractOffboarding returns the migration progress of the slabs
+		// that were stored on an offboarded contract.
+		ContractOffboarding(ctx context.Context, fcid types.FileContractID) (api.ContractOffboarding, error)
+
 		// ContractSizes returns the sizes of all contracts in the database as
 		// well as the estimated number of bytes that can be pruned from them.
 		ContractSizes(ctx context.Context) (map[types.FileContractID]api.ContractSize, error)
@@ -122,7 +160,7 @@ type (
 		// CopyObject copies an object from one bucket and key to another. If
 		// source and destination are the same, only the metadata and mimeType
 		// are overwritten with the provided ones.
-		CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey, mimeType string, metadata api.ObjectUserMetadata) (api.ObjectMetadata, error)
+		CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey, mimeType, contentDisposition string, metadata api.ObjectUserMetadata) (api.ObjectMetadata, error)
 
 		// CreateBucket creates a new bucket with the given name and policy. If
 		// the bucket already exists, api.ErrBucketExists is returned.
@@ -142,9 +180,14 @@ type (
 		// the requested object was actually deleted.
 		DeleteObject(ctx context.Context, bucket, key string) (bool, error)
 
-		// DeleteObjects deletes a batch of objects starting with the given
-		// prefix and returns 'true' if any object was deleted.
-		DeleteObjects(ctx context.Context, bucket, prefix string, limit int64) (bool, error)
+		// DeleteObjectWithSize deletes an object like DeleteObject but also
+		// returns the logical size of the deleted object, so callers can
+		// account for the freed storage within the same transaction.
+		DeleteObjectWithSize(ctx context.Context, bucket, key string) (bool, int64, error)
+
+		// DeleteObjects deletes a batch of at most 'limit' objects starting
+		// with the given prefix and returns the number of deleted objects.
+		DeleteObjects(ctx context.Context, bucket, prefix string, limit int64) (int64, error)
 
 		// DeleteSetting deletes the setting with the given key.
 		DeleteSetting(ctx context.Context, key string) error
@@ -154,6 +197,11 @@ type (
 		// webhooks.ErrWebhookNotFound is returned.
 		DeleteWebhook(ctx context.Context, wh webhooks.Webhook) error
 
+		// FinishContractOffboardings archives offboarded contracts once all
+		// of their slabs were migrated and returns the number of archived
+		// contracts.
+		FinishContractOffboardings(ctx context.Context) (int64, error)
+
 		// FileContractElement returns the up-to-date file contract element for
 		// a given contract id.
 		FileContractElement(ctx context.Context, fcid types.FileContractID) (types.V2FileContractElement, error)
@@ -168,21 +216,40 @@ type (
 		// HostBlocklist returns the list of host addresses on the blocklist.
 		HostBlocklist(ctx context.Context) ([]string, error)
 
+		// HostVerificationStats returns the accumulated results of the sector
+		// verifications per host.
+		HostVerificationStats(ctx context.Context) ([]api.HostVerificationStats, error)
+
 		// InitAutopilotConfig initializes the autopilot config in the database.
 		InitAutopilotConfig(ctx context.Context) error
 
+		// IdempotencyKey returns the hash of the request and the ETag of the
+		// object the given idempotency key was used for. Keys used before the
+		// given time are considered expired. If the key wasn't used yet, it
+		// returns false.
+		IdempotencyKey(ctx context.Context, key string, after time.Time) (types.Hash256, string, bool, error)
+
 		// InsertBufferedSlab inserts a buffered slab into the database. This
 		// includes the creation of a buffered slab as well as the corresponding
 		// regular slab it is linked to. It returns the ID of the buffered slab
-		// that was created.
-		InsertBufferedSlab(ctx context.Context, fileName string, ec object.EncryptionKey, minShards, totalShards uint8) (int64, error)
+		// that was created. The compression is the algorithm used to compress
+		// the buffer's data on disk, an empty string means no compression.
+		InsertBufferedSlab(ctx context.Context, fileName, compression string, ec object.EncryptionKey, minShards, totalShards uint8) (int64, error)
+
+		// InsertIdempotencyKey records that the given idempotency key was used
+		// for the request with the given hash, which resulted in an object
+		// with the given ETag.
+		InsertIdempotencyKey(ctx context.Context, key string, requestHash types.Hash256, eTag string) error
 
 		// InsertMultipartUpload creates a new multipart upload and returns a
-		// unique upload ID.
-		InsertMultipartUpload(ctx context.Context, bucket, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata) (string, error)
+		// unique upload ID. The pinned hosts are carried over to the object
+		// when the upload is completed.
+		InsertMultipartUpload(ctx context.Context, bucket, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey) (string, error)
 
-		// InsertObject inserts a new object into the database.
-		InsertObject(ctx context.Context, bucket, key string, o object.Object, mimeType, eTag string, md api.ObjectUserMetadata) error
+		// InsertObject inserts a new object into the database. The checksum is
+		// optional and stored as is. If pinned hosts are given, the object's
+		// slabs are only migrated to those hosts.
+		InsertObject(ctx context.Context, bucket, key string, o object.Object, mimeType, eTag, checksum, contentDisposition string, md api.ObjectUserMetadata, pinnedHosts []types.PublicKey) error
 
 		// InvalidateSlabHealthByFCID invalidates the health of all slabs that
 		// are associated with any of the provided contracts.
@@ -202,12 +269,22 @@ type (
 		// The returned string contains the filename of the slab buffer on disk.
 		MarkPackedSlabUploaded(ctx context.Context, slab api.UploadedPackedSlab) (string, error)
 
+		// MoveObject moves an object from one bucket to another without
+		// touching its slabs. Returns api.ErrObjectExists if the destination
+		// bucket already contains an object with the same key.
+		MoveObject(ctx context.Context, srcBucket, dstBucket, key string) error
+
+		// MultipartPartProgress returns the slices that were persisted for a
+		// part that is still being uploaded.
+		MultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber int) ([]api.MultipartPartProgressSlice, error)
+
 		// MultipartUpload returns the multipart upload with the given ID or
 		// api.ErrMultipartUploadNotFound if the upload doesn't exist.
 		MultipartUpload(ctx context.Context, uploadID string) (api.MultipartUpload, error)
 
 		// MultipartUploadParts returns a list of all parts for a given
-		// multipart upload
+		// multipart upload ordered by part number or
+		// api.ErrMultipartUploadNotFound if the upload doesn't exist.
 		MultipartUploadParts(ctx context.Context, bucket, key, uploadID string, marker int, limit int64) (api.MultipartListPartsResponse, error)
 
 		// MultipartUploads returns a list of all multipart uploads.
@@ -216,15 +293,47 @@ type (
 		// Object returns an object from the database.
 		Object(ctx context.Context, bucket, key string) (api.Object, error)
 
-		// Objects returns a list of objects from the given bucket.
-		Objects(ctx context.Context, bucket, prefix, substring, delim, sortBy, sortDir, marker string, limit int, encryptionKey object.EncryptionKey) (resp api.ObjectsResponse, err error)
+		// Objects returns a list of objects from the given bucket, optionally
+		// filtered by a range of health.
+		Objects(ctx context.Context, bucket, prefix, substring, glob, delim, sortBy, sortDir, marker string, limit int, encryptionKey object.EncryptionKey, minHealth, maxHealth *float64) (resp api.ObjectsResponse, err error)
+
+		// ObjectManifest returns up to 'limit' manifest entries for the
+		// objects in a bucket, sorted by key and starting after 'marker'.
+		ObjectManifest(ctx context.Context, bucket, marker string, limit int) ([]api.ObjectManifestEntry, error)
 
 		// ObjectMetadata returns an object's metadata.
 		ObjectMetadata(ctx context.Context, bucket, key string) (api.Object, error)
 
+		// ObjectPinned returns whether an object is pinned, false is returned
+		// if the object doesn't exist.
+		ObjectPinned(ctx context.Context, bucket, key string) (bool, error)
+
+		// ObjectEvents returns up to 'limit' events from the object event
+		// log with an ID greater than 'marker' that were recorded before
+		// 'before'.
+		ObjectEvents(ctx context.Context, marker uint64, before time.Time, limit int) ([]api.ObjectEvent, error)
+
+		// ObjectsMissingChecksum returns up to 'limit' objects without a
+		// checksum with an ID greater than 'marker'.
+		ObjectsMissingChecksum(ctx context.Context, marker uint64, limit int) ([]api.ObjectMissingChecksum, error)
+
+		// ObjectsNoSlabs returns a batch of objects in the given bucket that
+		// don't reference any slabs, optionally only the ones with a
+		// non-zero size.
+		ObjectsNoSlabs(ctx context.Context, bucket, marker string, limit int, missingDataOnly bool) (api.ObjectsNoSlabsResponse, error)
+
 		// ObjectsStats returns overall stats about stored objects
 		ObjectsStats(ctx context.Context, opts api.ObjectsStatsOpts) (api.ObjectsStatsResponse, error)
 
+		// ObjectTombstonesExist returns true if there are objects that were
+		// deleted asynchronously but not pruned yet.
+		ObjectTombstonesExist(ctx context.Context) (bool, error)
+
+		// OffboardContract marks a contract as bad and remembers the slabs
+		// that had sectors stored on it. It returns the number of affected
+		// slabs.
+		OffboardContract(ctx context.Context, fcid types.FileContractID) (int64, error)
+
 		// PeerBanned returns true if the peer is banned.
 		PeerBanned(ctx context.Context, addr string) (bool, error)
 
@@ -238,6 +347,10 @@ type (
 		// ProcessChainUpdate applies the given chain update to the database.
 		ProcessChainUpdate(ctx context.Context, applyFn func(ChainUpdateTx) error) error
 
+		// PinObject sets the pinned flag of an object. Returns
+		// api.ErrObjectNotFound if the object doesn't exist.
+		PinObject(ctx context.Context, bucket, key string, pinned bool) error
+
 		// PrunableContractRoots returns the indices of roots that are not in
 		// the contract.
 		PrunableContractRoots(ctx context.Context, fcid types.FileContractID, roots []types.Hash256) (indices []uint64, err error)
@@ -246,9 +359,25 @@ type (
 		// longer linked to an active contract.
 		PruneHostSectors(ctx context.Context, limit int64) (int64, error)
 
-		// PruneSlabs deletes slabs that are no longer referenced by any slice
-		// or slab buffer.
-		PruneSlabs(ctx context.Context, limit int64) (int64, error)
+		// PruneObjectEvents deletes up to 'limit' events from the object
+		// event log that were recorded before the given time and returns the
+		// number of deleted events.
+		PruneObjectEvents(ctx context.Context, before time.Time, limit int64) (int64, error)
+
+		// PruneIdempotencyKeys deletes up to 'limit' idempotency keys that
+		// were used before the given time and returns the number of deleted
+		// keys.
+		PruneIdempotencyKeys(ctx context.Context, before time.Time, limit int64) (int64, error)
+
+		// PruneObjectTombstones deletes up to 'limit' slices of objects that
+		// were marked as deleted by TombstoneObject, followed by the objects
+		// that have no slices left. It returns the number of deleted rows.
+		PruneObjectTombstones(ctx context.Context, limit int64) (int64, error)
+
+		// PruneSlabs deletes up to 'limit' slabs with an id in the range
+		// [minID, maxID) that are no longer referenced by any slice or slab
+		// buffer.
+		PruneSlabs(ctx context.Context, minID, maxID, limit int64) (int64, error)
 
 		// PutContract inserts the contract if it does not exist, otherwise it
 		// will overwrite all fields.
@@ -265,6 +394,26 @@ type (
 		// therefore only useful for gouging checks.
 		RecordHostScans(ctx context.Context, scans []api.HostScan) error
 
+		// RecordHostVerifications adds the outcome of the given sector
+		// verifications to the verification stats of the hosts. Unknown hosts
+		// are ignored.
+		RecordHostVerifications(ctx context.Context, verifications []api.HostVerification) error
+
+		// RecordObjectEvents appends the given events to the object event
+		// log.
+		RecordObjectEvents(ctx context.Context, events []api.ObjectEvent) error
+
+		// RecordRenameObjectsEvents appends the events of renaming all
+		// objects with the given prefix to the object event log, objects
+		// that are overwritten when 'force' is set are recorded as deleted.
+		// It has to be called before the objects are renamed.
+		RecordRenameObjectsEvents(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) error
+
+		// RelinkContractSectors moves up to 'limit' sector links from the
+		// contract 'from' to the contract 'to' and returns the number of links
+		// that were moved.
+		RelinkContractSectors(ctx context.Context, from, to types.FileContractID, limit int64) (int64, error)
+
 		// RemoveOfflineHosts removes all hosts that have been offline for
 		// longer than maxDownTime and been scanned at least minRecentFailures
 		// times. The contracts of those hosts are also removed.
@@ -275,16 +424,19 @@ type (
 		// object already exists at the target location or api.ErrObjectNotFound
 		// if the object at keyOld doesn't exist. If force is true, the instead
 		// of returning api.ErrObjectExists, the existing object will be
-		// deleted.
-		RenameObject(ctx context.Context, bucket, keyOld, keyNew string, force bool) error
+		// deleted. If keyNew is a directory of other objects,
+		// api.ErrObjectKeyIsDirectory is returned unless allowDirCollision is
+		// true.
+		RenameObject(ctx context.Context, bucket, keyOld, keyNew string, force, allowDirCollision bool) error
 
 		// RenameObjects renames all objects in the database with the given
 		// prefix to the new prefix. If 'force' is true, it will overwrite any
 		// existing objects with the new prefix. If no object can be renamed,
 		// `api.ErrOBjectNotFound` is returned. If 'force' is false and an
 		// object already exists with the new prefix, `api.ErrObjectExists` is
-		// returned.
-		RenameObjects(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) error
+		// returned. The response contains the number of renamed objects and
+		// the number of objects that were overwritten.
+		RenameObjects(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) (api.ObjectsRenameResponse, error)
 
 		// RenewedContract returns the metadata of the contract that was renewed
 		// from the specified contract or ErrContractNotFound otherwise.
@@ -296,29 +448,64 @@ type (
 		// ResetLostSectors resets the lost sector count for the given host.
 		ResetLostSectors(ctx context.Context, hk types.PublicKey) error
 
+		// ResolveObjectKey returns the key of the object the given key refers
+		// to, which only differs from the given key in case-insensitive
+		// buckets.
+		ResolveObjectKey(ctx context.Context, bucket, key string) (string, error)
+
 		// SaveAccounts saves the given accounts in the db, overwriting any
 		// existing ones.
 		SaveAccounts(ctx context.Context, accounts []api.Account) error
 
+		// SampleSectors returns up to n randomly picked sectors together with
+		// the host that is expected to store them.
+		SampleSectors(ctx context.Context, n int) ([]api.SectorSample, error)
+
 		// Setting returns the setting with the given key from the database.
 		Setting(ctx context.Context, key string) (string, error)
 
+		// SharedSlabs returns up to 'limit' slabs that are referenced by more
+		// than 'minObjects' objects, sorted by the number of objects.
+		SharedSlabs(ctx context.Context, minObjects uint64, limit int) ([]api.SharedSlab, error)
+
 		// Slab returns the slab with the given ID or api.ErrSlabNotFound.
 		Slab(ctx context.Context, key object.EncryptionKey) (object.Slab, error)
 
+		// SlabIDRange returns the smallest and largest slab id in the
+		// database or zero for both if there are no slabs.
+		SlabIDRange(ctx context.Context) (minID, maxID int64, err error)
+
 		// SlabsForMigration returns up to 'limit' slabs with a health smaller
 		// than or equal to 'healthCutoff'
 		SlabsForMigration(ctx context.Context, healthCutoff float64, limit int) ([]api.UnhealthySlab, error)
 
+		// SlabsStats returns the number of uploaded slabs per health range.
+		SlabsStats(ctx context.Context) (api.SlabsStatsResponse, error)
+
 		// Tip returns the sync height.
 		Tip(ctx context.Context) (types.ChainIndex, error)
 
+		// TombstoneExpiredObjects marks up to 'limit' objects that expired
+		// according to the lifecycle rules of their bucket as deleted and
+		// returns the marked objects.
+		TombstoneExpiredObjects(ctx context.Context, now time.Time, limit int64) ([]api.ObjectMetadata, error)
+
+		// TombstoneObject marks an object as deleted without deleting its
+		// slices, the object is no longer visible but is deleted
+		// asynchronously by PruneObjectTombstones. It returns true if the
+		// requested object was marked as deleted.
+		TombstoneObject(ctx context.Context, bucket, key string) (bool, error)
+
 		// UnspentSiacoinElements returns all wallet outputs in the database.
 		UnspentSiacoinElements(ctx context.Context) ([]types.SiacoinElement, error)
 
 		// UpdateAutopilotConfig updates the autopilot config in the database.
 		UpdateAutopilotConfig(ctx context.Context, ap api.AutopilotConfig) error
 
+		// UpdateBucketLifecycleRules replaces the lifecycle rules of the
+		// bucket with the given name.
+		UpdateBucketLifecycleRules(ctx context.Context, bucket string, rules []api.BucketLifecycleRule) error
+
 		// UpdateBucketPolicy updates the policy of the bucket with the provided
 		// one, fully overwriting the existing policy.
 		UpdateBucketPolicy(ctx context.Context, bucket string, policy api.BucketPolicy) error
@@ -417,6 +604,7 @@ type (
 
 	LoadedSlabBuffer struct {
 		ID          int64
+		Compression string
 		Filename    string
 		Key         object.EncryptionKey
 		MinShards   uint8
@@ -31,6 +31,22 @@ import (
 	"lukechampine.com/frand"
 )
 
+const (
+	// objectMetadataInsertAttempts is the number of times we try to insert
+	// an object's user metadata before giving up on the whole transaction.
+	// Only lock wait timeouts are retried within the transaction.
+	objectMetadataInsertAttempts = 3
+
+	// lockWaitTimeoutMsg is the error MySQL returns when a statement timed
+	// out waiting for a row lock. Unlike a deadlock, only the statement is
+	// rolled back and the surrounding transaction remains usable.
+	lockWaitTimeoutMsg = "Lock wait timeout exceeded"
+
+	// sampleSectorsMaxRounds is the number of times we pick random sector
+	// ids when sampling sectors before settling for fewer samples.
+	sampleSectorsMaxRounds = 3
+)
+
 var (
 	ErrNegativeOffset  = errors.New("offset can not be negative")
 	ErrSettingNotFound = errors.New("setting not found")
@@ -68,6 +84,10 @@ type (
 
 		CharLengthExpr() string
 
+		// GlobExpr returns a WHERE expression and its argument that match all
+		// rows where the given column matches the parsed glob pattern.
+		GlobExpr(col string, pattern []api.GlobPart) (string, any)
+
 		// ScanObjectMetadata scans the object metadata from the given scanner.
 		// The columns required to scan the metadata are returned by the
 		// SelectObjectMetadataExpr helper method. Additional fields can be
@@ -195,6 +215,209 @@ func ArchiveContract(ctx context.Context, tx sql.Tx, fcid types.FileContractID,
 	return nil
 }
 
+// OffboardContract marks the contract with the given id as unusable and
+// remembers the slabs that had sectors stored on it. The health of those slabs
+// is invalidated so they are picked up by the next migration run, the contract
+// is only archived by FinishContractOffboardings once they were migrated. A
+// contract without any slabs is archived right away. It returns the number of
+// affected slabs.
+func OffboardContract(ctx context.Context, tx sql.Tx, fcid types.FileContractID) (int64, error) {
+	var contractID int64
+	if err := tx.QueryRow(ctx, "SELECT id FROM contracts WHERE fcid = ? AND archival_reason IS NULL", FileContractID(fcid)).
+		Scan(&contractID); errors.Is(err, dsql.ErrNoRows) {
+		return 0, api.ErrContractNotFound
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to fetch contract: %w", err)
+	}
+
+	// check whether the contract is already being offboarded
+	var n int64
+	if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM contract_offboardings WHERE db_contract_id = ?", contractID).
+		Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to fetch offboarded slabs: %w", err)
+	} else if n > 0 {
+		return n, nil
+	}
+
+	// remember the affected slabs
+	res, err := tx.Exec(ctx, `
+		INSERT INTO contract_offboardings (created_at, db_contract_id, db_slab_id)
+		SELECT DISTINCT ?, ?, s.db_slab_id
+		FROM contract_sectors cs
+		INNER JOIN sectors s ON s.id = cs.db_sector_id
+		WHERE cs.db_contract_id = ?
+	`, time.Now(), contractID, contractID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert offboarded slabs: %w", err)
+	}
+	n, err = res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch number of offboarded slabs: %w", err)
+	} else if n == 0 {
+		return 0, ArchiveContract(ctx, tx, fcid, api.ContractArchivalReasonOffboarded)
+	}
+
+	// mark the contract as bad, its sectors no longer count towards the
+	// health of the slabs but it can still be downloaded from while they are
+	// being migrated
+	_, err = tx.Exec(ctx, "UPDATE contracts SET usability = ? WHERE id = ?", contractUsabilityBad, contractID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update contract usability: %w", err)
+	}
+
+	// invalidate their health
+	_, err = tx.Exec(ctx, `
+		UPDATE slabs
+		SET health_valid_until = 0
+		WHERE id IN (
+			SELECT db_slab_id
+			FROM contract_offboardings
+			WHERE db_contract_id = ?
+		)
+	`, contractID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate slab health: %w", err)
+	}
+	return n, nil
+}
+
+// FinishContractOffboardings archives the offboarded contracts whose slabs
+// were all migrated or deleted and removes their offboarding records. It
+// returns the number of contracts that were archived.
+func FinishContractOffboardings(ctx context.Context, tx sql.Tx) (int64, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT c.id, c.fcid, c.archival_reason IS NULL
+		FROM contracts c
+		WHERE EXISTS (
+			SELECT 1
+			FROM contract_offboardings co
+			WHERE co.db_contract_id = c.id
+		) AND NOT EXISTS (
+			SELECT 1
+			FROM contract_offboardings co
+			INNER JOIN slabs sla ON sla.id = co.db_slab_id
+			WHERE co.db_contract_id = c.id AND (sla.health < 1 OR sla.health_valid_until <= ?)
+		)
+	`, time.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch finished offboardings: %w", err)
+	}
+	defer rows.Close()
+
+	type offboarding struct {
+		id     int64
+		fcid   types.FileContractID
+		active bool
+	}
+	var finished []offboarding
+	for rows.Next() {
+		var o offboarding
+		if err := rows.Scan(&o.id, (*FileContractID)(&o.fcid), &o.active); err != nil {
+			return 0, fmt.Errorf("failed to scan finished offboarding: %w", err)
+		}
+		finished = append(finished, o)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to fetch finished offboardings: %w", err)
+	}
+	rows.Close()
+
+	var archived int64
+	for _, o := range finished {
+		// the contract might have been archived for another reason in the
+		// meantime, e.g. because it expired
+		if o.active {
+			if err := ArchiveContract(ctx, tx, o.fcid, api.ContractArchivalReasonOffboarded); err != nil {
+				return 0, err
+			}
+			archived++
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM contract_offboardings WHERE db_contract_id = ?", o.id); err != nil {
+			return 0, fmt.Errorf("failed to delete offboarded slabs: %w", err)
+		}
+	}
+	return archived, nil
+}
+
+// RelinkContractSectors moves up to 'limit' contract_sectors links from the
+// contract with id 'from' to the contract with id 'to'. Sectors that are
+// already linked to 'to' only have their link to 'from' removed. It returns
+// the number of links that were moved, which is smaller than the limit once
+// all sectors are relinked.
+func RelinkContractSectors(ctx context.Context, tx sql.Tx, from, to types.FileContractID, limit int64) (int64, error) {
+	if from == to {
+		return 0, fmt.Errorf("can't relink sectors of contract %v to itself", from)
+	} else if limit <= 0 {
+		return 0, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	// fetch contract ids and hosts
+	contractID := func(fcid types.FileContractID) (id int64, hk PublicKey, err error) {
+		err = tx.QueryRow(ctx, "SELECT id, host_key FROM contracts WHERE fcid = ?", FileContractID(fcid)).Scan(&id, &hk)
+		if errors.Is(err, dsql.ErrNoRows) {
+			err = fmt.Errorf("%w: %v", api.ErrContractNotFound, fcid)
+		}
+		return
+	}
+	fromID, fromHK, err := contractID(from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch renewed contract: %w", err)
+	}
+	toID, toHK, err := contractID(to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch renewal: %w", err)
+	}
+
+	// sectors can only be relinked between contracts with the same host,
+	// otherwise the links would point to a host that doesn't store them
+	if fromHK != toHK {
+		return 0, fmt.Errorf("can't relink sectors of contract %v with host %v to contract %v with host %v", from, types.PublicKey(fromHK), to, types.PublicKey(toHK))
+	}
+
+	// fetch a batch of sectors linked to the renewed contract
+	rows, err := tx.Query(ctx, "SELECT db_sector_id FROM contract_sectors WHERE db_contract_id = ? ORDER BY db_sector_id LIMIT ?", fromID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch linked sectors: %w", err)
+	}
+	defer rows.Close()
+
+	var sectorIDs []any
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("failed to scan sector id: %w", err)
+		}
+		sectorIDs = append(sectorIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to fetch linked sectors: %w", err)
+	} else if len(sectorIDs) == 0 {
+		return 0, nil
+	}
+	placeholders := strings.Repeat("?, ", len(sectorIDs)-1) + "?"
+
+	// link the sectors to the renewal unless they already are
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO contract_sectors (db_contract_id, db_sector_id)
+		SELECT ?, s.id
+		FROM sectors s
+		WHERE s.id IN (%s) AND NOT EXISTS (
+			SELECT 1
+			FROM contract_sectors cs
+			WHERE cs.db_contract_id = ? AND cs.db_sector_id = s.id
+		)`, placeholders), append(append([]any{toID}, sectorIDs...), toID)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to link sectors to renewal: %w", err)
+	}
+
+	// unlink them from the renewed contract
+	res, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM contract_sectors WHERE db_contract_id = ? AND db_sector_id IN (%s)", placeholders), append([]any{fromID}, sectorIDs...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unlink sectors from renewed contract: %w", err)
+	}
+	return res.RowsAffected()
+}
+
 func AutopilotConfig(ctx context.Context, tx sql.Tx) (cfg api.AutopilotConfig, err error) {
 	err = tx.QueryRow(ctx, `
 SELECT
@@ -208,7 +431,9 @@ SELECT
 	contracts_prune,
 	hosts_max_downtime_hours,
 	hosts_min_protocol_version,
-	hosts_max_consecutive_scan_failures
+	hosts_max_consecutive_scan_failures,
+	hosts_max_announcement_age_hours,
+	hosts_recent_failure_cooldown_hours
 FROM autopilot_config
 WHERE id = ?`, sql.AutopilotID).Scan(
 		&cfg.Enabled,
@@ -222,6 +447,8 @@ WHERE id = ?`, sql.AutopilotID).Scan(
 		&cfg.Hosts.MaxDowntimeHours,
 		&cfg.Hosts.MinProtocolVersion,
 		&cfg.Hosts.MaxConsecutiveScanFailures,
+		&cfg.Hosts.MaxAnnouncementAgeHours,
+		&cfg.Hosts.RecentFailureCooldownHours,
 	)
 	return
 }
@@ -234,6 +461,78 @@ func Bucket(ctx context.Context, tx sql.Tx, bucket string) (api.Bucket, error) {
 	return b, nil
 }
 
+// BucketSize returns the total size of the objects in the given bucket,
+// objects pending deletion aren't counted. The size is kept up to date in the
+// same transaction that adds or removes objects, so it doesn't have to be
+// computed from the bucket's objects.
+func BucketSize(ctx context.Context, tx sql.Tx, bucket string) (size uint64, err error) {
+	err = tx.QueryRow(ctx, "SELECT size FROM buckets WHERE name = ?", bucket).Scan(&size)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return 0, api.ErrBucketNotFound
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to fetch bucket size: %w", err)
+	}
+	return size, nil
+}
+
+// UpdateBucketSize adds 'delta' to the size of the bucket with the given id.
+func UpdateBucketSize(ctx context.Context, tx sql.Tx, bucketID, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "UPDATE buckets SET size = size + ? WHERE id = ?", delta, bucketID)
+	if err != nil {
+		return fmt.Errorf("failed to update bucket size: %w", err)
+	}
+	return nil
+}
+
+// SubtractObjectsFromBucketSize subtracts the size of the objects in the
+// bucket with the given id that match 'objectsExpr' from the bucket's size.
+// The objects are referred to as 'o' and the function has to be called before
+// they are deleted or tombstoned.
+func SubtractObjectsFromBucketSize(ctx context.Context, tx sql.Tx, bucketID int64, objectsExpr string, args ...any) error {
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE buckets
+		SET size = size - (
+			SELECT COALESCE(SUM(o.size), 0)
+			FROM objects o
+			WHERE o.db_bucket_id = ? AND o.object_id IS NOT NULL AND %s
+		)
+		WHERE id = ?`, objectsExpr), append(append([]any{bucketID}, args...), bucketID)...)
+	if err != nil {
+		return fmt.Errorf("failed to update bucket size: %w", err)
+	}
+	return nil
+}
+
+// BucketLifecycleRules returns the lifecycle rules of the given bucket.
+func BucketLifecycleRules(ctx context.Context, tx sql.Tx, bucket string) ([]api.BucketLifecycleRule, error) {
+	var bucketID int64
+	err := tx.QueryRow(ctx, "SELECT id FROM buckets WHERE name = ?", bucket).Scan(&bucketID)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return nil, api.ErrBucketNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch bucket id: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, "SELECT prefix, expiration_days FROM bucket_lifecycle_rules WHERE db_bucket_id = ? ORDER BY id ASC", bucketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lifecycle rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []api.BucketLifecycleRule{}
+	for rows.Next() {
+		var rule api.BucketLifecycleRule
+		if err := rows.Scan(&rule.Prefix, &rule.ExpirationDays); err != nil {
+			return nil, fmt.Errorf("failed to scan lifecycle rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
 func Buckets(ctx context.Context, tx sql.Tx) ([]api.Bucket, error) {
 	rows, err := tx.Query(ctx, "SELECT created_at, name, COALESCE(policy, '{}') FROM buckets")
 	if err != nil {
@@ -378,7 +677,35 @@ func ContractSizes(ctx context.Context, tx sql.Tx) (map[types.FileContractID]api
 	return sizes, nil
 }
 
-func CopyObject(ctx context.Context, tx sql.Tx, srcBucket, dstBucket, srcKey, dstKey, mimeType string, metadata api.ObjectUserMetadata) (api.ObjectMetadata, error) {
+// ContractOffboarding returns the migration progress of the slabs that were
+// stored on the given contract at the time it was offboarded. A slab counts as
+// migrated once it was deleted or its cached health is back to full.
+func ContractOffboarding(ctx context.Context, tx sql.Tx, fcid types.FileContractID) (api.ContractOffboarding, error) {
+	var contractID int64
+	var archivalReason dsql.NullString
+	if err := tx.QueryRow(ctx, "SELECT id, archival_reason FROM contracts WHERE fcid = ?", FileContractID(fcid)).
+		Scan(&contractID, &archivalReason); errors.Is(err, dsql.ErrNoRows) {
+		return api.ContractOffboarding{}, api.ErrContractNotFound
+	} else if err != nil {
+		return api.ContractOffboarding{}, fmt.Errorf("failed to fetch contract: %w", err)
+	}
+
+	res := api.ContractOffboarding{
+		ContractID: fcid,
+		Archived:   archivalReason.Valid,
+	}
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(co.id), COALESCE(SUM(CASE WHEN sla.id IS NULL OR (sla.health >= 1 AND sla.health_valid_until > ?) THEN 1 ELSE 0 END), 0)
+		FROM contract_offboardings co
+		LEFT JOIN slabs sla ON sla.id = co.db_slab_id
+		WHERE co.db_contract_id = ?
+	`, time.Now().Unix(), contractID).Scan(&res.Slabs, &res.Migrated); err != nil {
+		return api.ContractOffboarding{}, fmt.Errorf("failed to fetch offboarding progress: %w", err)
+	}
+	return res, nil
+}
+
+func CopyObject(ctx context.Context, tx sql.Tx, srcBucket, dstBucket, srcKey, dstKey, mimeType, contentDisposition string, metadata api.ObjectUserMetadata) (api.ObjectMetadata, error) {
 	// stmt to fetch bucket id
 	bucketIDStmt, err := tx.Prepare(ctx, "SELECT id FROM buckets WHERE name = ?")
 	if err != nil {
@@ -407,8 +734,8 @@ func CopyObject(ctx context.Context, tx sql.Tx, srcBucket, dstBucket, srcKey, ds
 
 	// helper to fetch metadata
 	fetchMetadata := func(objID int64) (om api.ObjectMetadata, err error) {
-		err = tx.QueryRow(ctx, "SELECT etag, health, created_at, object_id, size, mime_type FROM objects WHERE id = ?", objID).
-			Scan(&om.ETag, &om.Health, (*time.Time)(&om.ModTime), &om.Key, &om.Size, &om.MimeType)
+		err = tx.QueryRow(ctx, "SELECT etag, checksum, content_disposition, health, created_at, object_id, size, mime_type FROM objects WHERE id = ?", objID).
+			Scan(&om.ETag, &om.Checksum, &om.ContentDisposition, &om.Health, (*time.Time)(&om.ModTime), &om.Key, &om.Size, &om.MimeType)
 		if err != nil {
 			return api.ObjectMetadata{}, fmt.Errorf("failed to fetch new object: %w", err)
 		}
@@ -420,7 +747,12 @@ func CopyObject(ctx context.Context, tx sql.Tx, srcBucket, dstBucket, srcKey, ds
 		// object.
 		if _, err := tx.Exec(ctx, "UPDATE objects SET mime_type = ? WHERE id = ?", mimeType, srcObjID); err != nil {
 			return api.ObjectMetadata{}, fmt.Errorf("failed to update mime type: %w", err)
-		} else if err := UpdateMetadata(ctx, tx, srcObjID, metadata); err != nil {
+		} else if contentDisposition != "" {
+			if _, err := tx.Exec(ctx, "UPDATE objects SET content_disposition = ? WHERE id = ?", contentDisposition, srcObjID); err != nil {
+				return api.ObjectMetadata{}, fmt.Errorf("failed to update content disposition: %w", err)
+			}
+		}
+		if err := UpdateMetadata(ctx, tx, srcObjID, metadata); err != nil {
 			return api.ObjectMetadata{}, fmt.Errorf("failed to update metadata: %w", err)
 		}
 		return fetchMetadata(srcObjID)
@@ -435,20 +767,24 @@ func CopyObject(ctx context.Context, tx sql.Tx, srcBucket, dstBucket, srcKey, ds
 		return api.ObjectMetadata{}, fmt.Errorf("failed to fetch dest bucket id: %w", err)
 	}
 
-	// copy object
-	res, err := tx.Exec(ctx, `INSERT INTO objects (created_at, object_id, db_bucket_id,`+"`key`"+`, size, mime_type, etag)
-						SELECT ?, ?, ?, `+"`key`"+`, size, ?, etag
+	// copy object, the source's content disposition is kept unless overridden
+	// and the copy is pinned if the source is
+	res, err := tx.Exec(ctx, `INSERT INTO objects (created_at, object_id, object_id_lower, db_bucket_id,`+"`key`"+`, size, mime_type, etag, checksum, content_disposition, pinned)
+						SELECT ?, ?, ?, ?, `+"`key`"+`, size, ?, etag, checksum, CASE WHEN ? = '' THEN content_disposition ELSE ? END, pinned
 						FROM objects
-						WHERE id = ?`, time.Now(), dstKey, dstBID, mimeType, srcObjID)
+						WHERE id = ?`, time.Now(), dstKey, strings.ToLower(dstKey), dstBID, mimeType, contentDisposition, contentDisposition, srcObjID)
 	if err != nil {
 		return api.ObjectMetadata{}, fmt.Errorf("failed to insert object: %w", err)
 	}
 	dstObjID, err := res.LastInsertId()
 	if err != nil {
 		return api.ObjectMetadata{}, fmt.Errorf("failed to fetch object id: %w", err)
+	} else if err := CheckObjectKeyCaseConflict(ctx, tx, "o.id = ?", dstObjID); err != nil {
+		return api.ObjectMetadata{}, err
 	}
 
-	// copy slices
+	// copy slices, the copy references the same slabs as the source which
+	// are only pruned once no slices reference them anymore
 	_, err = tx.Exec(ctx, `INSERT INTO slices (created_at, db_object_id, object_index, db_slab_id, offset, length)
 				SELECT ?, ?, object_index, db_slab_id, offset, length
 				FROM slices
@@ -462,8 +798,24 @@ func CopyObject(ctx context.Context, tx sql.Tx, srcBucket, dstBucket, srcKey, ds
 		return api.ObjectMetadata{}, fmt.Errorf("failed to insert metadata: %w", err)
 	}
 
+	// copy pinned hosts, the copy's slabs are the source's slabs so they are
+	// bound by the same pin
+	_, err = tx.Exec(ctx, `INSERT INTO object_pinned_hosts (created_at, db_object_id, public_key)
+				SELECT ?, ?, public_key
+				FROM object_pinned_hosts
+				WHERE db_object_id = ?`, time.Now(), dstObjID, srcObjID)
+	if err != nil {
+		return api.ObjectMetadata{}, fmt.Errorf("failed to copy pinned hosts: %w", err)
+	}
+
 	// fetch copied object
-	return fetchMetadata(dstObjID)
+	om, err := fetchMetadata(dstObjID)
+	if err != nil {
+		return api.ObjectMetadata{}, err
+	} else if err := UpdateBucketSize(ctx, tx, dstBID, om.Size); err != nil {
+		return api.ObjectMetadata{}, err
+	}
+	return om, nil
 }
 
 func DeleteBucket(ctx context.Context, tx sql.Tx, bucket string) error {
@@ -475,12 +827,16 @@ func DeleteBucket(ctx context.Context, tx sql.Tx, bucket string) error {
 		return fmt.Errorf("failed to fetch bucket id: %w", err)
 	}
 	var empty bool
-	err = tx.QueryRow(ctx, "SELECT NOT EXISTS(SELECT 1 FROM objects WHERE db_bucket_id = ?)", id).Scan(&empty)
+	err = tx.QueryRow(ctx, "SELECT NOT EXISTS(SELECT 1 FROM objects WHERE db_bucket_id = ? AND object_id IS NOT NULL)", id).Scan(&empty)
 	if err != nil {
 		return fmt.Errorf("failed to check if bucket is empty: %w", err)
 	} else if !empty {
 		return api.ErrBucketNotEmpty
 	}
+	_, err = tx.Exec(ctx, "DELETE FROM objects WHERE db_bucket_id = ? AND object_id IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tombstoned objects: %w", err)
+	}
 	_, err = tx.Exec(ctx, "DELETE FROM buckets WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete bucket: %w", err)
@@ -488,6 +844,33 @@ func DeleteBucket(ctx context.Context, tx sql.Tx, bucket string) error {
 	return nil
 }
 
+// DeleteObjectWithSize deletes an object and returns its size alongside
+// whether it was deleted. The size is the object's logical size, slabs shared
+// with other objects aren't freed by the deletion so counting the bytes of
+// its slabs would over-report the freed storage. The size is subtracted from
+// the size of the object's bucket.
+func DeleteObjectWithSize(ctx context.Context, tx sql.Tx, bucket, key string) (bool, int64, error) {
+	var objID, bucketID, size int64
+	err := tx.QueryRow(ctx, "SELECT id, db_bucket_id, COALESCE(size, 0) FROM objects WHERE object_id = ? AND db_bucket_id = (SELECT id FROM buckets WHERE buckets.name = ?)", key, bucket).Scan(&objID, &bucketID, &size)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return false, 0, nil
+	} else if err != nil {
+		return false, 0, fmt.Errorf("failed to fetch object: %w", err)
+	}
+
+	res, err := tx.Exec(ctx, "DELETE FROM objects WHERE id = ?", objID)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to delete object: %w", err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, 0, err
+	} else if n == 0 {
+		return false, 0, nil
+	} else if err := UpdateBucketSize(ctx, tx, bucketID, -size); err != nil {
+		return false, 0, err
+	}
+	return true, size, nil
+}
+
 func DeleteHostSector(ctx context.Context, tx sql.Tx, hk types.PublicKey, root types.Hash256) (int, error) {
 	// fetch sector id
 	var sectorID int64
@@ -557,11 +940,6 @@ func DeleteHostSector(ctx context.Context, tx sql.Tx, hk types.PublicKey, root t
 	return int(deletedSectors), nil
 }
 
-func DeleteMetadata(ctx context.Context, tx sql.Tx, objID int64) error {
-	_, err := tx.Exec(ctx, "DELETE FROM object_user_metadata WHERE db_object_id = ?", objID)
-	return err
-}
-
 func DeleteSetting(ctx context.Context, tx sql.Tx, key string) error {
 	if _, err := tx.Exec(ctx, "DELETE FROM settings WHERE `key` = ?", key); err != nil {
 		return fmt.Errorf("failed to delete setting '%s': %w", key, err)
@@ -581,6 +959,57 @@ func DeleteWebhook(ctx context.Context, tx sql.Tx, wh webhooks.Webhook) error {
 	return nil
 }
 
+// CheckSlabCollision returns api.ErrSlabKeyCollision if a slab that was
+// deduplicated on its key was stored with redundancy parameters that don't
+// match the given slab. Slabs with the same key are expected to be identical,
+// merging them despite a mismatch would silently corrupt the existing slab.
+// Slabs without shards reference a partial slab, their total shards are not
+// known and therefore not checked.
+func CheckSlabCollision(s object.Slab, minShards, totalShards uint8) error {
+	if s.MinShards != minShards || (len(s.Shards) > 0 && len(s.Shards) != int(totalShards)) {
+		return fmt.Errorf("%w: slab %v is stored as %d-of-%d, got %d-of-%d", api.ErrSlabKeyCollision, s.EncryptionKey, minShards, totalShards, s.MinShards, len(s.Shards))
+	}
+	return nil
+}
+
+// SlabSectorsComplete returns whether a slab that already exists has all of its
+// sectors. Inserting a slab that's complete doesn't need to insert its sectors
+// again, a slab whose sectors were pruned has to.
+func SlabSectorsComplete(numSectors int, totalShards uint8) bool {
+	return numSectors > 0 && numSectors == int(totalShards)
+}
+
+// SlabSectorIDs returns the ids of a slab's sectors ordered by their index
+// within the slab. The sectors are expected to match the given shards, since
+// the ids are used to link the shards' contracts to them.
+func SlabSectorIDs(ctx context.Context, tx sql.Tx, slabID int64, shards []object.Sector) ([]int64, error) {
+	rows, err := tx.Query(ctx, "SELECT id, root FROM sectors WHERE db_slab_id = ? ORDER BY slab_index", slabID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sectors: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var root Hash256
+		if err := rows.Scan(&id, &root); err != nil {
+			return nil, fmt.Errorf("failed to scan sector id: %w", err)
+		} else if len(ids) >= len(shards) {
+			return nil, fmt.Errorf("slab %v has more than the expected %d sectors", slabID, len(shards))
+		} else if types.Hash256(root) != shards[len(ids)].Root {
+			return nil, fmt.Errorf("sector %d of slab %v has root %v, expected %v", len(ids), slabID, types.Hash256(root), shards[len(ids)].Root)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch sectors: %w", err)
+	} else if len(ids) != len(shards) {
+		return nil, fmt.Errorf("slab %v has %d sectors, expected %d", slabID, len(ids), len(shards))
+	}
+	return ids, nil
+}
+
 func FetchUsedContracts(ctx context.Context, tx sql.Tx, fcids []types.FileContractID) (map[types.FileContractID]UsedContract, error) {
 	if len(fcids) == 0 {
 		return make(map[types.FileContractID]UsedContract), nil
@@ -667,6 +1096,35 @@ func HostBlocklist(ctx context.Context, tx sql.Tx) ([]string, error) {
 	return blocklist, nil
 }
 
+func HostVerificationStats(ctx context.Context, tx sql.Tx) ([]api.HostVerificationStats, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT h.public_key, v.successes, v.failures, v.last_error, v.last_verified
+		FROM host_sector_verifications v
+		INNER JOIN hosts h ON h.id = v.db_host_id
+		ORDER BY h.public_key ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch host verification stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []api.HostVerificationStats
+	for rows.Next() {
+		var s api.HostVerificationStats
+		if err := rows.Scan((*PublicKey)(&s.HostKey), &s.Successes, &s.Failures, &s.LastError, (*UnixTimeMS)(&s.LastVerified)); err != nil {
+			return nil, fmt.Errorf("failed to scan host verification stats: %w", err)
+		}
+		if total := s.Successes + s.Failures; total > 0 {
+			s.SuccessRate = float64(s.Successes) / float64(total)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate host verification stats: %w", err)
+	}
+	return stats, nil
+}
+
 func Hosts(ctx context.Context, tx sql.Tx, opts api.HostOptions) ([]api.Host, error) {
 	if opts.Offset < 0 {
 		return nil, ErrNegativeOffset
@@ -735,9 +1193,9 @@ func Hosts(ctx context.Context, tx sql.Tx, opts api.HostOptions) ([]api.Host, er
 	if opts.UsabilityMode != api.UsabilityFilterModeAll {
 		switch opts.UsabilityMode {
 		case api.UsabilityFilterModeUsable:
-			whereExprs = append(whereExprs, "EXISTS (SELECT 1 FROM hosts h2 INNER JOIN host_checks hc ON hc.db_host_id = h2.id AND h2.id = h.id WHERE (hc.usability_blocked = 0 AND hc.usability_offline = 0 AND hc.usability_low_score = 0 AND hc.usability_redundant_ip = 0 AND hc.usability_gouging = 0 AND hc.usability_low_max_duration = 0 AND hc.usability_not_accepting_contracts = 0 AND hc.usability_not_announced = 0 AND hc.usability_not_completing_scan = 0))")
+			whereExprs = append(whereExprs, "EXISTS (SELECT 1 FROM hosts h2 INNER JOIN host_checks hc ON hc.db_host_id = h2.id AND h2.id = h.id WHERE (hc.usability_blocked = 0 AND hc.usability_offline = 0 AND hc.usability_low_score = 0 AND hc.usability_redundant_ip = 0 AND hc.usability_gouging = 0 AND hc.usability_low_max_duration = 0 AND hc.usability_not_accepting_contracts = 0 AND hc.usability_not_announced = 0 AND hc.usability_not_completing_scan = 0 AND hc.usability_stale_announcement = 0 AND hc.usability_recently_failed = 0))")
 		case api.UsabilityFilterModeUnusable:
-			whereExprs = append(whereExprs, "EXISTS (SELECT 1 FROM hosts h2 INNER JOIN host_checks hc ON hc.db_host_id = h2.id AND h2.id = h.id WHERE (hc.usability_blocked = 1 OR hc.usability_offline = 1 OR hc.usability_low_score = 1 OR hc.usability_redundant_ip = 1 OR hc.usability_gouging = 1 OR hc.usability_low_max_duration = 1 OR hc.usability_not_accepting_contracts = 1 OR hc.usability_not_announced = 1 OR hc.usability_not_completing_scan = 1))")
+			whereExprs = append(whereExprs, "EXISTS (SELECT 1 FROM hosts h2 INNER JOIN host_checks hc ON hc.db_host_id = h2.id AND h2.id = h.id WHERE (hc.usability_blocked = 1 OR hc.usability_offline = 1 OR hc.usability_low_score = 1 OR hc.usability_redundant_ip = 1 OR hc.usability_gouging = 1 OR hc.usability_low_max_duration = 1 OR hc.usability_not_accepting_contracts = 1 OR hc.usability_not_announced = 1 OR hc.usability_not_completing_scan = 1 OR hc.usability_stale_announcement = 1 OR hc.usability_recently_failed = 1))")
 		}
 	}
 
@@ -812,6 +1270,7 @@ SELECT
 	h.downtime,
 	h.successful_interactions,
 	h.failed_interactions,
+	h.last_failed_interaction,
 	COALESCE(h.lost_sectors, 0),
 	h.scanned,
 
@@ -826,6 +1285,8 @@ SELECT
 	COALESCE(hc.usability_not_accepting_contracts, 0),
 	COALESCE(hc.usability_not_announced, 0),
 	COALESCE(hc.usability_not_completing_scan, 0),
+	COALESCE(hc.usability_stale_announcement, 0),
+	COALESCE(hc.usability_recently_failed, 0),
 
 	COALESCE(hc.score_age,0),
 	COALESCE(hc.score_collateral,0),
@@ -859,9 +1320,9 @@ LEFT JOIN host_checks hc ON hc.db_host_id = h.id
 			&h.NetAddress, (*PriceTable)(&h.PriceTable.HostPriceTable), &pte,
 			(*HostSettings)(&h.Settings), (*V2HostSettings)(&h.V2Settings), &h.Interactions.TotalScans, (*UnixTimeMS)(&h.Interactions.LastScan), &h.Interactions.LastScanSuccess,
 			&h.Interactions.SecondToLastScanSuccess, (*DurationMS)(&h.Interactions.Uptime), (*DurationMS)(&h.Interactions.Downtime),
-			&h.Interactions.SuccessfulInteractions, &h.Interactions.FailedInteractions, &h.Interactions.LostSectors,
+			&h.Interactions.SuccessfulInteractions, &h.Interactions.FailedInteractions, (*UnixTimeMS)(&h.Interactions.LastFailedInteraction), &h.Interactions.LostSectors,
 			&h.Scanned, &h.Blocked, &h.Checks.UsabilityBreakdown.Blocked, &h.Checks.UsabilityBreakdown.Offline, &h.Checks.UsabilityBreakdown.LowScore, &h.Checks.UsabilityBreakdown.RedundantIP,
-			&h.Checks.UsabilityBreakdown.Gouging, &h.Checks.UsabilityBreakdown.LowMaxDuration, &h.Checks.UsabilityBreakdown.NotAcceptingContracts, &h.Checks.UsabilityBreakdown.NotAnnounced, &h.Checks.UsabilityBreakdown.NotCompletingScan,
+			&h.Checks.UsabilityBreakdown.Gouging, &h.Checks.UsabilityBreakdown.LowMaxDuration, &h.Checks.UsabilityBreakdown.NotAcceptingContracts, &h.Checks.UsabilityBreakdown.NotAnnounced, &h.Checks.UsabilityBreakdown.NotCompletingScan, &h.Checks.UsabilityBreakdown.StaleAnnouncement, &h.Checks.UsabilityBreakdown.RecentlyFailed,
 			&h.Checks.ScoreBreakdown.Age, &h.Checks.ScoreBreakdown.Collateral, &h.Checks.ScoreBreakdown.Interactions, &h.Checks.ScoreBreakdown.StorageRemaining, &h.Checks.ScoreBreakdown.Uptime,
 			&h.Checks.ScoreBreakdown.Version, &h.Checks.ScoreBreakdown.Prices, &h.Checks.GougingBreakdown.DownloadErr, &h.Checks.GougingBreakdown.GougingErr,
 			&h.Checks.GougingBreakdown.PruneErr, &h.Checks.GougingBreakdown.UploadErr)
@@ -894,10 +1355,10 @@ LEFT JOIN host_checks hc ON hc.db_host_id = h.id
 	return hosts, nil
 }
 
-func InsertBufferedSlab(ctx context.Context, tx sql.Tx, fileName string, ec object.EncryptionKey, minShards, totalShards uint8) (int64, error) {
+func InsertBufferedSlab(ctx context.Context, tx sql.Tx, fileName, compression string, ec object.EncryptionKey, minShards, totalShards uint8) (int64, error) {
 	// insert buffered slab
-	res, err := tx.Exec(ctx, `INSERT INTO buffered_slabs (created_at, filename) VALUES (?, ?)`,
-		time.Now(), fileName)
+	res, err := tx.Exec(ctx, `INSERT INTO buffered_slabs (created_at, filename, compression) VALUES (?, ?, ?)`,
+		time.Now(), fileName, compression)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert buffered slab: %w", err)
 	}
@@ -936,7 +1397,87 @@ func InsertMetadata(ctx context.Context, tx sql.Tx, objID, muID *int64, md api.O
 	return nil
 }
 
-func InsertMultipartUpload(ctx context.Context, tx sql.Tx, bucket, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata) (string, error) {
+// InsertPinnedHosts pins the object or multipart upload with the given id to
+// the given hosts.
+func InsertPinnedHosts(ctx context.Context, tx sql.Tx, objID, muID *int64, hks []types.PublicKey) error {
+	if len(hks) == 0 {
+		return nil
+	} else if (objID == nil) == (muID == nil) {
+		return errors.New("either objID or muID must be set")
+	}
+	insertStmt, err := tx.Prepare(ctx, "INSERT INTO object_pinned_hosts (created_at, db_object_id, db_multipart_upload_id, public_key) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement to insert pinned hosts: %w", err)
+	}
+	defer insertStmt.Close()
+
+	seen := make(map[types.PublicKey]struct{})
+	for _, hk := range hks {
+		if _, ok := seen[hk]; ok {
+			continue
+		}
+		seen[hk] = struct{}{}
+		if _, err := insertStmt.Exec(ctx, time.Now(), objID, muID, PublicKey(hk)); err != nil {
+			return fmt.Errorf("failed to insert pinned host: %w", err)
+		}
+	}
+	return nil
+}
+
+// InsertObjectMetadata inserts the user metadata of an object within a
+// savepoint. If the insert times out waiting for a lock, the savepoint is
+// rolled back and the insert is retried without discarding the slabs and
+// sectors that were inserted earlier in the same transaction. Any other error,
+// including deadlocks, leaves the transaction to be retried as a whole.
+func InsertObjectMetadata(ctx context.Context, tx sql.Tx, objID int64, md api.ObjectUserMetadata) (err error) {
+	if len(md) == 0 {
+		return nil
+	}
+	for attempt := 1; attempt <= objectMetadataInsertAttempts; attempt++ {
+		err = tx.Savepoint(ctx, "insert_object_metadata", func() error {
+			return InsertMetadata(ctx, tx, &objID, nil, md)
+		})
+		if errors.Is(err, sql.ErrSavepointsUnsupported) {
+			return fmt.Errorf("failed to insert object metadata without savepoint: %w", err)
+		} else if err == nil || ctx.Err() != nil || !strings.Contains(err.Error(), lockWaitTimeoutMsg) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to insert object metadata after %d attempts: %w", objectMetadataInsertAttempts, err)
+}
+
+// IdempotencyKey returns the hash of the request and the ETag of the object the
+// given idempotency key was used for. Keys used before the given time are
+// considered expired. If the key wasn't used yet, it returns false.
+func IdempotencyKey(ctx context.Context, tx sql.Tx, key string, after time.Time) (types.Hash256, string, bool, error) {
+	var requestHash types.Hash256
+	var eTag string
+	err := tx.QueryRow(ctx, "SELECT request_hash, etag FROM object_idempotency_keys WHERE idempotency_key = ? AND created_at >= ?", key, after).
+		Scan((*Hash256)(&requestHash), &eTag)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return types.Hash256{}, "", false, nil
+	} else if err != nil {
+		return types.Hash256{}, "", false, fmt.Errorf("failed to fetch idempotency key: %w", err)
+	}
+	return requestHash, eTag, true, nil
+}
+
+// InsertIdempotencyKey records that the given idempotency key was used for the
+// request with the given hash, which resulted in an object with the given
+// ETag. An expired record of the key that wasn't pruned yet is replaced.
+func InsertIdempotencyKey(ctx context.Context, tx sql.Tx, key string, requestHash types.Hash256, eTag string) error {
+	_, err := tx.Exec(ctx, "DELETE FROM object_idempotency_keys WHERE idempotency_key = ?", key)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired idempotency key: %w", err)
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO object_idempotency_keys (created_at, idempotency_key, request_hash, etag) VALUES (?, ?, ?, ?)", time.Now(), key, Hash256(requestHash), eTag)
+	if err != nil {
+		return fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+	return nil
+}
+
+func InsertMultipartUpload(ctx context.Context, tx sql.Tx, bucket, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey) (string, error) {
 	// fetch bucket id
 	var bucketID int64
 	err := tx.QueryRow(ctx, "SELECT id FROM buckets WHERE buckets.name = ?", bucket).
@@ -965,29 +1506,88 @@ func InsertMultipartUpload(ctx context.Context, tx sql.Tx, bucket, key string, e
 	if err := InsertMetadata(ctx, tx, nil, &muID, metadata); err != nil {
 		return "", fmt.Errorf("failed to insert multipart metadata: %w", err)
 	}
+
+	// insert pinned hosts
+	if err := InsertPinnedHosts(ctx, tx, nil, &muID, pinnedHosts); err != nil {
+		return "", fmt.Errorf("failed to insert multipart pinned hosts: %w", err)
+	}
 	return uploadID, nil
 }
 
-func InsertObject(ctx context.Context, tx sql.Tx, key string, bucketID, size int64, ec object.EncryptionKey, mimeType, eTag string) (int64, error) {
-	res, err := tx.Exec(ctx, `INSERT INTO objects (created_at, object_id, db_bucket_id, `+"`key`"+`, size, mime_type, etag)
-						VALUES (?, ?, ?, ?, ?, ?, ?)`,
+func InsertObject(ctx context.Context, tx sql.Tx, key string, bucketID, size int64, ec object.EncryptionKey, mimeType, eTag, checksum, contentDisposition string) (int64, error) {
+	res, err := tx.Exec(ctx, `INSERT INTO objects (created_at, object_id, object_id_lower, db_bucket_id, `+"`key`"+`, size, mime_type, etag, checksum, content_disposition)
+						VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		time.Now(),
 		key,
+		strings.ToLower(key),
 		bucketID,
 		EncryptionKey(ec),
 		size,
 		mimeType,
-		eTag)
+		eTag,
+		checksum,
+		contentDisposition)
+	if err != nil {
+		return 0, err
+	}
+	objID, err := res.LastInsertId()
 	if err != nil {
 		return 0, err
+	} else if err := CheckObjectKeyCaseConflict(ctx, tx, "o.id = ?", objID); err != nil {
+		return 0, err
+	} else if err := UpdateBucketSize(ctx, tx, bucketID, size); err != nil {
+		return 0, err
 	}
-	return res.LastInsertId()
+	return objID, nil
+}
+
+// CheckObjectKeyCaseConflict returns api.ErrObjectKeyCaseConflict if one of the
+// objects matching the given filter is stored in a case-insensitive bucket that
+// contains another object whose key only differs in case. It is called after
+// objects were written since the transaction is rolled back on error.
+func CheckObjectKeyCaseConflict(ctx context.Context, tx sql.Tx, filterExpr string, args ...any) error {
+	var key string
+	err := tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT o.object_id
+		FROM objects o
+		INNER JOIN buckets b ON b.id = o.db_bucket_id
+		INNER JOIN objects o2 ON o2.db_bucket_id = o.db_bucket_id AND o2.object_id_lower = o.object_id_lower AND o2.id <> o.id
+		WHERE b.case_insensitive = 1 AND %s
+		LIMIT 1
+	`, filterExpr), args...).Scan(&key)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to check for conflicting keys: %w", err)
+	}
+	return fmt.Errorf("%w: key: %s", api.ErrObjectKeyCaseConflict, key)
+}
+
+// ResolveObjectKey returns the key of the object in the given bucket that the
+// given key refers to. In case-insensitive buckets that's the key the object
+// was uploaded with, in all other buckets it's the given key.
+func ResolveObjectKey(ctx context.Context, tx sql.Tx, bucket, key string) (string, error) {
+	var resolved string
+	err := tx.QueryRow(ctx, `
+		SELECT o.object_id
+		FROM objects o
+		INNER JOIN buckets b ON b.id = o.db_bucket_id
+		WHERE b.name = ? AND b.case_insensitive = 1 AND o.object_id_lower = ?
+		ORDER BY o.object_id ASC
+		LIMIT 1
+	`, bucket, strings.ToLower(key)).Scan(&resolved)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return key, nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to resolve object key: %w", err)
+	}
+	return resolved, nil
 }
 
 func LoadSlabBuffers(ctx context.Context, tx sql.Tx) (bufferedSlabs []LoadedSlabBuffer, orphanedBuffers []string, err error) {
 	// collect all buffers
 	rows, err := tx.Query(ctx, `
-			SELECT bs.id, bs.filename, sla.key, sla.min_shards, sla.total_shards
+			SELECT bs.id, bs.filename, bs.compression, sla.key, sla.min_shards, sla.total_shards
 			FROM buffered_slabs bs
 			INNER JOIN slabs sla ON sla.db_buffered_slab_id = bs.id
 		`)
@@ -998,7 +1598,7 @@ func LoadSlabBuffers(ctx context.Context, tx sql.Tx) (bufferedSlabs []LoadedSlab
 
 	for rows.Next() {
 		var bs LoadedSlabBuffer
-		if err := rows.Scan(&bs.ID, &bs.Filename, (*EncryptionKey)(&bs.Key), &bs.MinShards, &bs.TotalShards); err != nil {
+		if err := rows.Scan(&bs.ID, &bs.Filename, &bs.Compression, (*EncryptionKey)(&bs.Key), &bs.MinShards, &bs.TotalShards); err != nil {
 			return nil, nil, fmt.Errorf("failed to scan buffered slab: %w", err)
 		}
 		bufferedSlabs = append(bufferedSlabs, bs)
@@ -1045,13 +1645,77 @@ func LoadSlabBuffers(ctx context.Context, tx sql.Tx) (bufferedSlabs []LoadedSlab
 	return bufferedSlabs, orphanedBuffers, nil
 }
 
+// UpdateMetadata updates the user metadata of an object. Rather than
+// replacing all of the object's metadata, it only inserts, updates and deletes
+// the keys that changed.
 func UpdateMetadata(ctx context.Context, tx sql.Tx, objID int64, md api.ObjectUserMetadata) error {
-	if err := DeleteMetadata(ctx, tx, objID); err != nil {
-		return err
-	} else if err := InsertMetadata(ctx, tx, &objID, nil, md); err != nil {
-		return err
+	// fetch existing metadata
+	rows, err := tx.Query(ctx, "SELECT `key`, value FROM object_user_metadata WHERE db_object_id = ?", objID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch object metadata: %w", err)
 	}
-	return nil
+	existing := make(api.ObjectUserMetadata)
+	for rows.Next() {
+		var key string
+		var value dsql.NullString
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan object metadata: %w", err)
+		}
+		existing[key] = value.String
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("failed to fetch object metadata: %w", err)
+	}
+	rows.Close()
+
+	// diff metadata
+	var deleted []string
+	updated := make(api.ObjectUserMetadata)
+	inserted := make(api.ObjectUserMetadata)
+	for k := range existing {
+		if _, ok := md[k]; !ok {
+			deleted = append(deleted, k)
+		}
+	}
+	for k, v := range md {
+		if old, ok := existing[k]; !ok {
+			inserted[k] = v
+		} else if old != v {
+			updated[k] = v
+		}
+	}
+
+	// delete removed keys
+	if len(deleted) > 0 {
+		args := []any{objID}
+		for _, k := range deleted {
+			args = append(args, k)
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM object_user_metadata WHERE db_object_id = ? AND `key` IN (%s)", strings.Repeat("?, ", len(deleted)-1)+"?"), args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete object metadata: %w", err)
+		}
+	}
+
+	// update changed keys
+	if len(updated) > 0 {
+		updateMetadataStmt, err := tx.Prepare(ctx, "UPDATE object_user_metadata SET value = ? WHERE db_object_id = ? AND `key` = ?")
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement to update object metadata: %w", err)
+		}
+		defer updateMetadataStmt.Close()
+
+		for k, v := range updated {
+			if _, err := updateMetadataStmt.Exec(ctx, v, objID, k); err != nil {
+				return fmt.Errorf("failed to update object metadata: %w", err)
+			}
+		}
+	}
+
+	// insert new keys
+	return InsertMetadata(ctx, tx, &objID, nil, inserted)
 }
 
 func PrepareSlabHealth(ctx context.Context, tx sql.Tx, limit int64, now time.Time) error {
@@ -1089,43 +1753,173 @@ CREATE TEMPORARY TABLE slabs_health AS
 	return err
 }
 
-func whereObjectMarker(marker, sortBy, sortDir string, queryMarker func(dst any, marker, col string) error) (whereExprs []string, whereArgs []any, _ error) {
+// ObjectKeyIsDirectory returns whether any object other than the one with key
+// 'exclude' lives in the directory the given key would imply, i.e. whether an
+// object's key has the given key followed by a slash as prefix.
+func ObjectKeyIsDirectory(ctx context.Context, tx sql.Tx, bucket, key, exclude string) (bool, error) {
+	prefixExpr, prefixArgs := ObjectIDPrefixExpr("object_id", key+"/")
+	args := append([]any{bucket, exclude}, prefixArgs...)
+
+	var exists bool
+	err := tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM objects
+			WHERE db_bucket_id = (SELECT id FROM buckets WHERE buckets.name = ?) AND object_id != ? AND %s
+		)`, prefixExpr), args...).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether key is a directory: %w", err)
+	}
+	return exists, nil
+}
+
+// ObjectIDPrefixExpr returns a WHERE expression and its arguments that match
+// all rows where the given column starts with the given prefix. Next to the
+// case-sensitive LIKE, the column is bounded by a range which allows the
+// database to use an index on the column rather than scanning all rows.
+func ObjectIDPrefixExpr(col, prefix string) (string, []any) {
+	var exprs []string
+	var args []any
+	if lower := prefixLowerBound(prefix); lower != "" {
+		exprs = append(exprs, col+" >= ?")
+		args = append(args, lower)
+	}
+	if upper, ok := prefixUpperBound(prefix); ok {
+		exprs = append(exprs, col+" < ?")
+		args = append(args, upper)
+	}
+	exprs = append(exprs, fmt.Sprintf("%[1]s LIKE ? AND SUBSTR(%[1]s, 1, ?) = ?", col))
+	args = append(args, prefix+"%", utf8.RuneCountInString(prefix), prefix)
+	return strings.Join(exprs, " AND "), args
+}
+
+// prefixLowerBound returns a string that is smaller than or equal to all
+// strings with the given prefix. Since MySQL pads strings with spaces when
+// comparing them, "foo" sorts after "foo\t", which is why the prefix is cut
+// right before its last character that sorts after a space.
+func prefixLowerBound(prefix string) string {
+	if !utf8.ValidString(prefix) {
+		return ""
+	}
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] > ' ' {
+			_, size := utf8.DecodeLastRuneInString(prefix[:i+1])
+			return prefix[:i+1-size]
+		}
+	}
+	return ""
+}
+
+// prefixUpperBound returns the smallest string that is greater than all
+// strings with the given prefix. If no such string exists, e.g. because the
+// prefix is empty, false is returned.
+func prefixUpperBound(prefix string) (string, bool) {
+	if !utf8.ValidString(prefix) {
+		return "", false
+	}
+	runes := []rune(prefix)
+	for len(runes) > 0 {
+		last := runes[len(runes)-1]
+		if last == utf8.MaxRune {
+			runes = runes[:len(runes)-1]
+			continue
+		}
+		last++
+		if last >= 0xD800 && last <= 0xDFFF {
+			last = 0xE000 // skip surrogates
+		}
+		runes[len(runes)-1] = last
+		return string(runes), true
+	}
+	return "", false
+}
+
+// parseObjectMarker splits the marker of a listing into the bucket and key of
+// the object it refers to. Listings across all buckets prefix the marker with
+// the object's bucket since a key can exist in multiple buckets.
+func parseObjectMarker(bucket, marker string) (markerBucket, markerKey string, _ error) {
+	if marker == "" || bucket != "" {
+		return bucket, marker, nil
+	}
+	markerBucket, markerKey, ok := strings.Cut(marker, "/")
+	if !ok || markerBucket == "" {
+		return "", "", fmt.Errorf("%w: marker of a listing across buckets must be prefixed with the bucket", api.ErrMarkerNotFound)
+	}
+	return markerBucket, markerKey, nil
+}
+
+// nextObjectMarker returns the marker to continue a listing after the given
+// object.
+func nextObjectMarker(bucket string, om api.ObjectMetadata) string {
+	if bucket == "" {
+		return om.Bucket + "/" + om.Key
+	}
+	return om.Key
+}
+
+func whereObjectMarker(marker, markerBucket, sortBy, sortDir string, crossBucket bool, queryMarker func(dst any, marker, col string) error) (whereExprs []string, whereArgs []any, _ error) {
 	if marker == "" {
 		return nil, nil, nil
 	} else if sortBy == "" || sortDir == "" {
 		return nil, nil, fmt.Errorf("sortBy and sortDir must be set")
 	}
 
+	// objects with the same value for the sorted column are ordered by their
+	// key, across buckets they are ordered by their bucket first since the
+	// same key can exist in multiple buckets
+	tiebreakExpr := "o.object_id > ?"
+	tiebreakArgs := []any{marker}
+	if crossBucket {
+		tiebreakExpr = "(b.name > ? OR (b.name = ? AND o.object_id > ?))"
+		tiebreakArgs = []any{markerBucket, markerBucket, marker}
+	}
+
 	desc := strings.ToLower(sortDir) == api.SortDirDesc
 	switch strings.ToLower(sortBy) {
 	case api.ObjectSortByName:
+		op := ">"
 		if desc {
-			whereExprs = append(whereExprs, "o.object_id < ?")
+			op = "<"
+		}
+		if crossBucket {
+			whereExprs = append(whereExprs, fmt.Sprintf("(o.object_id %s ? OR (o.object_id = ? AND b.name > ?))", op))
+			whereArgs = append(whereArgs, marker, marker, markerBucket)
 		} else {
-			whereExprs = append(whereExprs, "o.object_id > ?")
+			whereExprs = append(whereExprs, fmt.Sprintf("o.object_id %s ?", op))
+			whereArgs = append(whereArgs, marker)
 		}
-		whereArgs = append(whereArgs, marker)
 	case api.ObjectSortByHealth:
 		var markerHealth float64
 		if err := queryMarker(&markerHealth, marker, "health"); err != nil {
 			return nil, nil, fmt.Errorf("failed to fetch health marker: %w", err)
 		} else if desc {
-			whereExprs = append(whereExprs, "((o.health <= ? AND o.object_id >?) OR o.health < ?)")
-			whereArgs = append(whereArgs, markerHealth, marker, markerHealth)
+			whereExprs = append(whereExprs, fmt.Sprintf("((o.health <= ? AND %s) OR o.health < ?)", tiebreakExpr))
+			whereArgs = append(append(append(whereArgs, markerHealth), tiebreakArgs...), markerHealth)
 		} else {
-			whereExprs = append(whereExprs, "(o.health > ? OR (o.health >= ? AND object_id > ?))")
-			whereArgs = append(whereArgs, markerHealth, markerHealth, marker)
+			whereExprs = append(whereExprs, fmt.Sprintf("(o.health > ? OR (o.health >= ? AND %s))", tiebreakExpr))
+			whereArgs = append(append(whereArgs, markerHealth, markerHealth), tiebreakArgs...)
+		}
+	case api.ObjectSortByModTime:
+		var markerModTime any
+		if err := queryMarker(&markerModTime, marker, "created_at"); err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch modtime marker: %w", err)
+		} else if desc {
+			whereExprs = append(whereExprs, fmt.Sprintf("((o.created_at <= ? AND %s) OR o.created_at < ?)", tiebreakExpr))
+			whereArgs = append(append(append(whereArgs, markerModTime), tiebreakArgs...), markerModTime)
+		} else {
+			whereExprs = append(whereExprs, fmt.Sprintf("(o.created_at > ? OR (o.created_at >= ? AND %s))", tiebreakExpr))
+			whereArgs = append(append(whereArgs, markerModTime, markerModTime), tiebreakArgs...)
 		}
 	case api.ObjectSortBySize:
 		var markerSize int64
 		if err := queryMarker(&markerSize, marker, "size"); err != nil {
 			return nil, nil, fmt.Errorf("failed to fetch health marker: %w", err)
 		} else if desc {
-			whereExprs = append(whereExprs, "((o.size <= ? AND o.object_id >?) OR o.size < ?)")
-			whereArgs = append(whereArgs, markerSize, marker, markerSize)
+			whereExprs = append(whereExprs, fmt.Sprintf("((o.size <= ? AND %s) OR o.size < ?)", tiebreakExpr))
+			whereArgs = append(append(append(whereArgs, markerSize), tiebreakArgs...), markerSize)
 		} else {
-			whereExprs = append(whereExprs, "(o.size > ? OR (o.size >= ? AND object_id > ?))")
-			whereArgs = append(whereArgs, markerSize, markerSize, marker)
+			whereExprs = append(whereExprs, fmt.Sprintf("(o.size > ? OR (o.size >= ? AND %s))", tiebreakExpr))
+			whereArgs = append(append(whereArgs, markerSize, markerSize), tiebreakArgs...)
 		}
 	default:
 		return nil, nil, fmt.Errorf("invalid marker: %v", marker)
@@ -1133,7 +1927,23 @@ func whereObjectMarker(marker, sortBy, sortDir string, queryMarker func(dst any,
 	return whereExprs, whereArgs, nil
 }
 
-func orderByObject(sortBy, sortDir string) (orderByExprs []string, _ error) {
+// whereObjectHealth returns the expressions to filter objects by health. The
+// health of an object is the health of its worst slab, which is kept up to date
+// when refreshing slab health so we can filter using the index on the objects
+// table rather than joining the slabs.
+func whereObjectHealth(minHealth, maxHealth *float64) (whereExprs []string, whereArgs []any) {
+	if minHealth != nil {
+		whereExprs = append(whereExprs, "o.health >= ?")
+		whereArgs = append(whereArgs, *minHealth)
+	}
+	if maxHealth != nil {
+		whereExprs = append(whereExprs, "o.health <= ?")
+		whereArgs = append(whereArgs, *maxHealth)
+	}
+	return
+}
+
+func orderByObject(sortBy, sortDir string, crossBucket bool) (orderByExprs []string, _ error) {
 	if sortBy == "" || sortDir == "" {
 		return nil, fmt.Errorf("sortBy and sortDir must be set")
 	}
@@ -1150,27 +1960,157 @@ func orderByObject(sortBy, sortDir string) (orderByExprs []string, _ error) {
 		orderByExprs = append(orderByExprs, "o.object_id "+dir2SQL[strings.ToLower(sortDir)])
 	case api.ObjectSortByHealth:
 		orderByExprs = append(orderByExprs, "o.health "+dir2SQL[strings.ToLower(sortDir)])
+	case api.ObjectSortByModTime:
+		orderByExprs = append(orderByExprs, "o.created_at "+dir2SQL[strings.ToLower(sortDir)])
 	case api.ObjectSortBySize:
 		orderByExprs = append(orderByExprs, "o.size "+dir2SQL[strings.ToLower(sortDir)])
 	default:
 		return nil, fmt.Errorf("invalid sortBy: %v", sortBy)
 	}
 
-	// always sort by object_id as well if we aren't explicitly
+	// always sort by object_id as well if we aren't explicitly, across
+	// buckets the bucket breaks ties between objects with the same key
 	if sortBy != api.ObjectSortByName {
+		if crossBucket {
+			orderByExprs = append(orderByExprs, "b.name ASC")
+		}
 		orderByExprs = append(orderByExprs, "o.object_id ASC")
+	} else if crossBucket {
+		orderByExprs = append(orderByExprs, "b.name ASC")
 	}
 	return orderByExprs, nil
 }
 
+func MoveObject(ctx context.Context, tx sql.Tx, srcBucket, dstBucket, key string) error {
+	// stmt to fetch bucket id
+	bucketIDStmt, err := tx.Prepare(ctx, "SELECT id FROM buckets WHERE name = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement to fetch bucket id: %w", err)
+	}
+	defer bucketIDStmt.Close()
+
+	// fetch source bucket
+	var srcBID int64
+	err = bucketIDStmt.QueryRow(ctx, srcBucket).Scan(&srcBID)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return fmt.Errorf("%w: source bucket", api.ErrBucketNotFound)
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch src bucket id: %w", err)
+	}
+
+	// fetch destination bucket
+	var dstBID int64
+	err = bucketIDStmt.QueryRow(ctx, dstBucket).Scan(&dstBID)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return fmt.Errorf("%w: destination bucket", api.ErrBucketNotFound)
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch dest bucket id: %w", err)
+	}
+
+	// fetch src object id
+	var objID, size int64
+	err = tx.QueryRow(ctx, "SELECT id, COALESCE(size, 0) FROM objects WHERE db_bucket_id = ? AND object_id = ?", srcBID, key).
+		Scan(&objID, &size)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return fmt.Errorf("%w: key %v", api.ErrObjectNotFound, key)
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch object id: %w", err)
+	} else if srcBID == dstBID {
+		return nil // nothing to do
+	}
+
+	// check whether the destination already has an object with that key
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM objects WHERE db_bucket_id = ? AND object_id = ?)", dstBID, key).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check if object exists: %w", err)
+	} else if exists {
+		return api.ErrObjectExists
+	}
+
+	// reassign the object, the slices reference the object by id so the slabs
+	// remain untouched
+	if _, err := tx.Exec(ctx, "UPDATE objects SET db_bucket_id = ?, object_id_lower = ? WHERE id = ?", dstBID, strings.ToLower(key), objID); err != nil {
+		return fmt.Errorf("failed to move object: %w", err)
+	} else if err := UpdateBucketSize(ctx, tx, srcBID, -size); err != nil {
+		return err
+	} else if err := UpdateBucketSize(ctx, tx, dstBID, size); err != nil {
+		return err
+	}
+	return CheckObjectKeyCaseConflict(ctx, tx, "o.id = ?", objID)
+}
+
 func MultipartUpload(ctx context.Context, tx sql.Tx, uploadID string) (api.MultipartUpload, error) {
 	resp, err := scanMultipartUpload(tx.QueryRow(ctx, "SELECT b.name, mu.key, mu.object_id, mu.upload_id, mu.created_at FROM multipart_uploads mu INNER JOIN buckets b ON b.id = mu.db_bucket_id WHERE mu.upload_id = ?", uploadID))
 	if err != nil {
 		return api.MultipartUpload{}, fmt.Errorf("failed to fetch multipart upload: %w", err)
 	}
+
+	// fetch pinned hosts
+	rows, err := tx.Query(ctx, `
+		SELECT oph.public_key
+		FROM object_pinned_hosts oph
+		INNER JOIN multipart_uploads mu ON mu.id = oph.db_multipart_upload_id
+		WHERE mu.upload_id = ?
+	`, uploadID)
+	if err != nil {
+		return api.MultipartUpload{}, fmt.Errorf("failed to fetch pinned hosts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hk types.PublicKey
+		if err := rows.Scan((*PublicKey)(&hk)); err != nil {
+			return api.MultipartUpload{}, fmt.Errorf("failed to scan pinned host: %w", err)
+		}
+		resp.PinnedHosts = append(resp.PinnedHosts, hk)
+	}
+	if err := rows.Err(); err != nil {
+		return api.MultipartUpload{}, fmt.Errorf("failed to fetch pinned hosts: %w", err)
+	}
 	return resp, nil
 }
 
+// MultipartPartProgress returns the slices that were persisted for a part of a
+// multipart upload that is still being uploaded, ordered by their index.
+func MultipartPartProgress(ctx context.Context, tx sql.Tx, bucket, key, uploadID string, partNumber int) ([]api.MultipartPartProgressSlice, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT mpp.object_index, mpp.checksum, sla.key, sla.min_shards, sli.offset, sli.length
+		FROM multipart_part_progress mpp
+		INNER JOIN multipart_parts mp ON mp.id = mpp.db_multipart_part_id
+		INNER JOIN multipart_uploads mu ON mu.id = mp.db_multipart_upload_id
+		INNER JOIN buckets b ON b.id = mu.db_bucket_id
+		INNER JOIN slices sli ON sli.db_multipart_part_id = mp.id AND sli.object_index = mpp.object_index
+		INNER JOIN slabs sla ON sla.id = sli.db_slab_id
+		WHERE b.name = ? AND mu.object_id = ? AND mu.upload_id = ? AND mp.part_number = ? AND mp.in_progress = 1
+		ORDER BY mpp.object_index ASC
+	`, bucket, key, uploadID, partNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch part progress: %w", err)
+	}
+	defer rows.Close()
+
+	var slices []api.MultipartPartProgressSlice
+	for rows.Next() {
+		var ps api.MultipartPartProgressSlice
+		if err := rows.Scan(&ps.Index, (*Hash256)(&ps.Checksum), (*EncryptionKey)(&ps.Slice.EncryptionKey), &ps.Slice.MinShards, &ps.Slice.Offset, &ps.Slice.Length); err != nil {
+			return nil, fmt.Errorf("failed to scan part progress: %w", err)
+		}
+		ps.Index-- // object indices start at 1
+		slices = append(slices, ps)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate part progress: %w", err)
+	}
+
+	// fill in the shards
+	for i := range slices {
+		slices[i].Slice.Slab, err = Slab(ctx, tx, slices[i].Slice.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch slab: %w", err)
+		}
+	}
+	return slices, nil
+}
+
 func MultipartUploadParts(ctx context.Context, tx sql.Tx, bucket, key, uploadID string, marker int, limit int64) (api.MultipartListPartsResponse, error) {
 	limitExpr := ""
 	limitUsed := limit > 0
@@ -1183,7 +2123,7 @@ func MultipartUploadParts(ctx context.Context, tx sql.Tx, bucket, key, uploadID
 		FROM multipart_parts mp
 		INNER JOIN multipart_uploads mus ON mus.id = mp.db_multipart_upload_id
 		INNER JOIN buckets b ON b.id = mus.db_bucket_id
-		WHERE mus.object_id = ? AND b.name = ? AND mus.upload_id = ? AND part_number > ?
+		WHERE mus.object_id = ? AND b.name = ? AND mus.upload_id = ? AND part_number > ? AND mp.in_progress = 0
 		ORDER BY part_number ASC
 		%s
 	`, limitExpr), key, bucket, uploadID, marker)
@@ -1200,6 +2140,26 @@ func MultipartUploadParts(ctx context.Context, tx sql.Tx, bucket, key, uploadID
 		}
 		parts = append(parts, part)
 	}
+	if err := rows.Err(); err != nil {
+		return api.MultipartListPartsResponse{}, fmt.Errorf("failed to iterate parts: %w", err)
+	}
+
+	// an empty page is either an upload without parts or an unknown upload
+	if len(parts) == 0 {
+		var exists bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1
+				FROM multipart_uploads mus
+				INNER JOIN buckets b ON b.id = mus.db_bucket_id
+				WHERE mus.object_id = ? AND b.name = ? AND mus.upload_id = ?
+			)`, key, bucket, uploadID).Scan(&exists)
+		if err != nil {
+			return api.MultipartListPartsResponse{}, fmt.Errorf("failed to check if multipart upload exists: %w", err)
+		} else if !exists {
+			return api.MultipartListPartsResponse{}, api.ErrMultipartUploadNotFound
+		}
+	}
 
 	// check if there are more parts beyond 'limit'.
 	var hasMore bool
@@ -1304,7 +2264,7 @@ func MultipartUploadForCompletion(ctx context.Context, tx sql.Tx, bucket, key, u
 	}
 
 	// find relevant parts
-	rows, err := tx.Query(ctx, "SELECT id, part_number, etag, size FROM multipart_parts WHERE db_multipart_upload_id = ? ORDER BY part_number ASC", mpu.ID)
+	rows, err := tx.Query(ctx, "SELECT id, part_number, etag, size FROM multipart_parts WHERE db_multipart_upload_id = ? AND in_progress = 0 ORDER BY part_number ASC", mpu.ID)
 	if err != nil {
 		return multipartUpload{}, nil, 0, "", fmt.Errorf("failed to fetch parts: %w", err)
 	}
@@ -1387,12 +2347,15 @@ func NormalizePeer(peer string) (string, error) {
 	return normalized.String(), nil
 }
 
-func Objects(ctx context.Context, tx Tx, bucket, prefix, substring, delim, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey) (resp api.ObjectsResponse, err error) {
+func Objects(ctx context.Context, tx Tx, bucket, prefix, substring, glob, delim, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey, minHealth, maxHealth *float64) (resp api.ObjectsResponse, err error) {
+	if glob != "" && delim != "" {
+		return api.ObjectsResponse{}, fmt.Errorf("%w: glob patterns are only supported without a delimiter", api.ErrUnsupportedDelimiter)
+	}
 	switch delim {
 	case "":
-		resp, err = listObjectsNoDelim(ctx, tx, bucket, prefix, substring, sortBy, sortDir, marker, limit, slabEncryptionKey)
+		resp, err = listObjectsNoDelim(ctx, tx, bucket, prefix, substring, glob, sortBy, sortDir, marker, limit, slabEncryptionKey, minHealth, maxHealth)
 	case "/":
-		resp, err = listObjectsSlashDelim(ctx, tx, bucket, prefix, sortBy, sortDir, marker, limit, slabEncryptionKey)
+		resp, err = listObjectsSlashDelim(ctx, tx, bucket, prefix, sortBy, sortDir, marker, limit, slabEncryptionKey, minHealth, maxHealth)
 	default:
 		err = fmt.Errorf("unsupported delimiter: '%s'", delim)
 	}
@@ -1402,12 +2365,13 @@ func Objects(ctx context.Context, tx Tx, bucket, prefix, substring, delim, sortB
 func ObjectMetadata(ctx context.Context, tx Tx, bucket, key string) (api.Object, error) {
 	// fetch object id
 	var objID int64
+	var pinned bool
 	if err := tx.QueryRow(ctx, `
-		SELECT o.id
+		SELECT o.id, o.pinned
 		FROM objects o
 		INNER JOIN buckets b ON b.id = o.db_bucket_id
 		WHERE o.object_id = ? AND b.name = ?
-	`, key, bucket).Scan(&objID); errors.Is(err, dsql.ErrNoRows) {
+	`, key, bucket).Scan(&objID, &pinned); errors.Is(err, dsql.ErrNoRows) {
 		return api.Object{}, api.ErrObjectNotFound
 	} else if err != nil {
 		return api.Object{}, fmt.Errorf("failed to fetch object id: %w", err)
@@ -1443,14 +2407,148 @@ func ObjectMetadata(ctx context.Context, tx Tx, bucket, key string) (api.Object,
 		if err := rows.Scan(&key, &value); err != nil {
 			return api.Object{}, fmt.Errorf("failed to scan user metadata: %w", err)
 		}
-		metadata[key] = value
+		metadata[key] = value
+	}
+
+	return api.Object{
+		Metadata:       metadata,
+		Pinned:         pinned,
+		ObjectMetadata: om,
+		Object:         nil, // only return metadata
+	}, nil
+}
+
+// ObjectsNoSlabs returns up to 'limit' objects in the given bucket that don't
+// reference any slabs, ordered by key and starting after 'marker'. Objects that
+// are pending deletion are ignored.
+func ObjectsNoSlabs(ctx context.Context, tx sql.Tx, bucket, marker string, limit int, missingDataOnly bool) (api.ObjectsNoSlabsResponse, error) {
+	if limit <= 0 {
+		return api.ObjectsNoSlabsResponse{}, errors.New("limit must be positive")
+	}
+
+	var bucketID int64
+	err := tx.QueryRow(ctx, "SELECT id FROM buckets WHERE name = ?", bucket).Scan(&bucketID)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return api.ObjectsNoSlabsResponse{}, api.ErrBucketNotFound
+	} else if err != nil {
+		return api.ObjectsNoSlabsResponse{}, fmt.Errorf("failed to fetch bucket id: %w", err)
+	}
+
+	var sizeExpr string
+	if missingDataOnly {
+		sizeExpr = "AND o.size > 0"
+	}
+
+	// NOTE: the anti-join uses the index on slices.db_object_id, on SQLite the
+	// objects are scanned using the covering index on (db_bucket_id,
+	// object_id, size, created_at), on MySQL that index would exceed the max
+	// key length so the unique index on (db_bucket_id, object_id) is used
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT o.object_id, o.size, o.created_at
+		FROM objects o
+		WHERE o.db_bucket_id = ? AND o.object_id IS NOT NULL AND o.object_id > ? %s
+		AND NOT EXISTS (SELECT 1 FROM slices sli WHERE sli.db_object_id = o.id)
+		ORDER BY o.object_id ASC
+		LIMIT ?
+	`, sizeExpr), bucketID, marker, limit+1)
+	if err != nil {
+		return api.ObjectsNoSlabsResponse{}, fmt.Errorf("failed to fetch objects without slabs: %w", err)
+	}
+	defer rows.Close()
+
+	objects := make([]api.ObjectNoSlabs, 0, limit)
+	for rows.Next() {
+		var o api.ObjectNoSlabs
+		if err := rows.Scan(&o.Key, &o.Size, (*time.Time)(&o.ModTime)); err != nil {
+			return api.ObjectsNoSlabsResponse{}, fmt.Errorf("failed to scan object: %w", err)
+		}
+		o.MissingData = o.Size > 0
+		objects = append(objects, o)
+	}
+	if err := rows.Err(); err != nil {
+		return api.ObjectsNoSlabsResponse{}, fmt.Errorf("failed to iterate objects: %w", err)
+	}
+
+	var resp api.ObjectsNoSlabsResponse
+	if len(objects) > limit {
+		resp.HasMore = true
+		objects = objects[:limit]
+		resp.NextMarker = objects[len(objects)-1].Key
+	}
+	resp.Objects = objects
+	return resp, nil
+}
+
+// ObjectsMissingChecksum returns up to 'limit' objects without a checksum with
+// an ID greater than 'marker', ordered by ID.
+func ObjectsMissingChecksum(ctx context.Context, tx sql.Tx, marker uint64, limit int) ([]api.ObjectMissingChecksum, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT o.id, b.name, o.object_id, o.etag, o.size
+		FROM objects o
+		INNER JOIN buckets b ON b.id = o.db_bucket_id
+		WHERE o.checksum = '' AND o.object_id IS NOT NULL AND o.id > ?
+		ORDER BY o.id ASC
+		LIMIT ?`, marker, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch objects missing a checksum: %w", err)
+	}
+	defer rows.Close()
+
+	objects := make([]api.ObjectMissingChecksum, 0, limit)
+	for rows.Next() {
+		var o api.ObjectMissingChecksum
+		var eTag dsql.NullString
+		if err := rows.Scan(&o.ID, &o.Bucket, &o.Key, &eTag, &o.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan object: %w", err)
+		}
+		o.ETag = eTag.String
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+// BackfillObjectChecksum sets the checksum of the object with the given ID if
+// it doesn't have one yet. Objects that were deleted or already have a
+// checksum are left untouched.
+func BackfillObjectChecksum(ctx context.Context, tx sql.Tx, id uint64, checksum string) error {
+	_, err := tx.Exec(ctx, "UPDATE objects SET checksum = ? WHERE id = ? AND checksum = ''", checksum, id)
+	if err != nil {
+		return fmt.Errorf("failed to backfill object checksum: %w", err)
+	}
+	return nil
+}
+
+// ObjectEvents returns up to 'limit' events from the object event log with an
+// ID greater than 'marker' that were recorded before 'before', in the order
+// they were recorded.
+func ObjectEvents(ctx context.Context, tx sql.Tx, marker uint64, before time.Time, limit int) ([]api.ObjectEvent, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, created_at, operation, bucket, object_id, renamed_to, etag, size
+		FROM object_events
+		WHERE id > ? AND created_at < ?
+		ORDER BY id ASC
+		LIMIT ?`, marker, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch object events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]api.ObjectEvent, 0, limit)
+	for rows.Next() {
+		var e api.ObjectEvent
+		var ts time.Time
+		if err := rows.Scan(&e.ID, &ts, &e.Operation, &e.Bucket, &e.Key, &e.RenamedTo, &e.ETag, &e.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan object event: %w", err)
+		}
+		e.Timestamp = api.TimeRFC3339(ts)
+		events = append(events, e)
 	}
+	return events, rows.Err()
+}
 
-	return api.Object{
-		Metadata:       metadata,
-		ObjectMetadata: om,
-		Object:         nil, // only return metadata
-	}, nil
+func ObjectTombstonesExist(ctx context.Context, tx sql.Tx) (exists bool, err error) {
+	err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM objects WHERE object_id IS NULL)").Scan(&exists)
+	return
 }
 
 func ObjectsStats(ctx context.Context, tx sql.Tx, opts api.ObjectsStatsOpts) (api.ObjectsStatsResponse, error) {
@@ -1469,11 +2567,17 @@ func ObjectsStats(ctx context.Context, tx sql.Tx, opts api.ObjectsStatsOpts) (ap
 		args = append(args, bucketID)
 	}
 
-	// objects stats
-	var numObjects, totalObjectsSize uint64
+	// objects stats, objects without an object id are pending deletion
+	var numObjects, numPendingDeletions, totalObjectsSize uint64
 	var minHealth float64
-	err := tx.QueryRow(ctx, "SELECT COUNT(*), COALESCE(MIN(health), 1), COALESCE(SUM(size), 0) FROM objects "+bucketExpr, args...).
-		Scan(&numObjects, &minHealth, &totalObjectsSize)
+	err := tx.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN object_id IS NOT NULL THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN object_id IS NULL THEN 1 ELSE 0 END), 0),
+			COALESCE(MIN(CASE WHEN object_id IS NOT NULL THEN health END), 1),
+			COALESCE(SUM(CASE WHEN object_id IS NOT NULL THEN size ELSE 0 END), 0)
+		FROM objects `+bucketExpr, args...).
+		Scan(&numObjects, &numPendingDeletions, &minHealth, &totalObjectsSize)
 	if err != nil {
 		return api.ObjectsStatsResponse{}, fmt.Errorf("failed to fetch objects stats: %w", err)
 	}
@@ -1524,6 +2628,7 @@ func ObjectsStats(ctx context.Context, tx sql.Tx, opts api.ObjectsStatsOpts) (ap
 	return api.ObjectsStatsResponse{
 		MinHealth:                  minHealth,
 		NumObjects:                 numObjects,
+		NumPendingDeletions:        numPendingDeletions,
 		NumUnfinishedObjects:       unfinishedObjects,
 		TotalUnfinishedObjectsSize: totalUnfinishedObjectsSize,
 		TotalObjectsSize:           totalObjectsSize,
@@ -1532,6 +2637,22 @@ func ObjectsStats(ctx context.Context, tx sql.Tx, opts api.ObjectsStatsOpts) (ap
 	}, nil
 }
 
+func SlabsStats(ctx context.Context, tx sql.Tx) (resp api.SlabsStatsResponse, _ error) {
+	err := tx.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN health >= 1 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN health > 0 AND health < 1 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN health = 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN health < 0 THEN 1 ELSE 0 END), 0)
+		FROM slabs
+		WHERE db_buffered_slab_id IS NULL
+	`).Scan(&resp.NumHealthy, &resp.NumDegraded, &resp.NumAtRisk, &resp.NumLost)
+	if err != nil {
+		return api.SlabsStatsResponse{}, fmt.Errorf("failed to fetch slabs stats: %w", err)
+	}
+	return resp, nil
+}
+
 func PeerBanned(ctx context.Context, tx sql.Tx, addr string) (bool, error) {
 	// normalize the address to a CIDR
 	netCIDR, err := NormalizePeer(addr)
@@ -1604,7 +2725,121 @@ func Peers(ctx context.Context, tx sql.Tx) ([]syncer.PeerInfo, error) {
 	return peers, nil
 }
 
-func PruneSlabs(ctx context.Context, tx sql.Tx, limit int64) (int64, error) {
+// ObjectManifest returns up to 'limit' manifest entries for the objects in the
+// given bucket, sorted by key and starting after 'marker'.
+func ObjectManifest(ctx context.Context, tx sql.Tx, bucket, marker string, limit int) ([]api.ObjectManifestEntry, error) {
+	var bucketID int64
+	err := tx.QueryRow(ctx, "SELECT id FROM buckets WHERE name = ?", bucket).Scan(&bucketID)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return nil, api.ErrBucketNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch bucket id: %w", err)
+	}
+
+	// fetch the objects
+	rows, err := tx.Query(ctx, `
+		SELECT o.id, o.object_id, o.size, o.etag, o.checksum
+		FROM objects o
+		WHERE o.db_bucket_id = ? AND o.object_id > ?
+		ORDER BY o.object_id ASC
+		LIMIT ?
+	`, bucketID, marker, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch objects: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []api.ObjectManifestEntry
+	var args []any
+	indices := make(map[int64]int)
+	for rows.Next() {
+		var objID int64
+		var entry api.ObjectManifestEntry
+		if err := rows.Scan(&objID, &entry.Key, &entry.Size, &entry.ETag, &entry.Checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan object: %w", err)
+		}
+		entry.Slabs = []object.EncryptionKey{}
+		indices[objID] = len(entries)
+		entries = append(entries, entry)
+		args = append(args, objID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	} else if len(entries) == 0 {
+		return nil, nil
+	}
+
+	// fetch the slab keys of the objects
+	slabRows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT sli.db_object_id, sla.key
+		FROM slices sli
+		INNER JOIN slabs sla ON sla.id = sli.db_slab_id
+		WHERE sli.db_object_id IN (%s)
+		ORDER BY sli.db_object_id ASC, sli.object_index ASC
+	`, strings.Repeat("?, ", len(args)-1)+"?"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch slab keys: %w", err)
+	}
+	defer slabRows.Close()
+
+	for slabRows.Next() {
+		var objID int64
+		var key object.EncryptionKey
+		if err := slabRows.Scan(&objID, (*EncryptionKey)(&key)); err != nil {
+			return nil, fmt.Errorf("failed to scan slab key: %w", err)
+		}
+		i := indices[objID]
+		entries[i].Slabs = append(entries[i].Slabs, key)
+	}
+	return entries, slabRows.Err()
+}
+
+// ObjectPinned returns whether an object is pinned, false is returned if the
+// object doesn't exist.
+func ObjectPinned(ctx context.Context, tx sql.Tx, bucket, key string) (bool, error) {
+	var pinned bool
+	err := tx.QueryRow(ctx, `
+		SELECT o.pinned
+		FROM objects o
+		INNER JOIN buckets b ON b.id = o.db_bucket_id
+		WHERE o.object_id = ? AND b.name = ?
+	`, key, bucket).Scan(&pinned)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to fetch pinned flag: %w", err)
+	}
+	return pinned, nil
+}
+
+// PinObject sets the pinned flag of an object. Slabs of pinned objects are
+// prioritized over slabs of unpinned objects with the same health when
+// fetching slabs for migration.
+func PinObject(ctx context.Context, tx sql.Tx, bucket, key string, pinned bool) error {
+	var objID int64
+	err := tx.QueryRow(ctx, `
+		SELECT o.id
+		FROM objects o
+		INNER JOIN buckets b ON b.id = o.db_bucket_id
+		WHERE o.object_id = ? AND b.name = ?
+	`, key, bucket).Scan(&objID)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return api.ErrObjectNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch object id: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE objects SET pinned = ? WHERE id = ?", pinned, objID)
+	if err != nil {
+		return fmt.Errorf("failed to update pinned flag: %w", err)
+	}
+	return nil
+}
+
+// PruneSlabs deletes slabs that are neither buffered nor referenced by any
+// object. Since the slabs of pinned objects are always referenced by their
+// slices, they are never pruned.
+func PruneSlabs(ctx context.Context, tx sql.Tx, minID, maxID, limit int64) (int64, error) {
 	res, err := tx.Exec(ctx, `
 	DELETE FROM slabs
 	WHERE id IN (
@@ -1612,16 +2847,21 @@ func PruneSlabs(ctx context.Context, tx sql.Tx, limit int64) (int64, error) {
 			SELECT s.id
 			FROM slabs s
 			LEFT JOIN slices sl ON sl.db_slab_id = s.id
-			WHERE s.db_buffered_slab_id IS NULL AND sl.db_slab_id IS NULL
+			WHERE s.db_buffered_slab_id IS NULL AND sl.db_slab_id IS NULL AND s.id >= ? AND s.id < ?
 			LIMIT ?
 		) AS limited
-	)`, limit)
+	)`, minID, maxID, limit)
 	if err != nil {
 		return 0, err
 	}
 	return res.RowsAffected()
 }
 
+func SlabIDRange(ctx context.Context, tx sql.Tx) (minID, maxID int64, err error) {
+	err = tx.QueryRow(ctx, "SELECT COALESCE(MIN(id), 0), COALESCE(MAX(id), 0) FROM slabs").Scan(&minID, &maxID)
+	return
+}
+
 func RecordHostScans(ctx context.Context, tx sql.Tx, scans []api.HostScan) error {
 	if len(scans) == 0 {
 		return nil
@@ -1645,7 +2885,8 @@ func RecordHostScans(ctx context.Context, tx sql.Tx, scans []api.HostScan) error
 		price_table = CASE WHEN ? THEN ? ELSE price_table END,
 		price_table_expiry = CASE WHEN ? THEN ? ELSE price_table_expiry END,
 		successful_interactions = CASE WHEN ? THEN successful_interactions + 1 ELSE successful_interactions END,
-		failed_interactions = CASE WHEN ? THEN failed_interactions + 1 ELSE failed_interactions END
+		failed_interactions = CASE WHEN ? THEN failed_interactions + 1 ELSE failed_interactions END,
+		last_failed_interaction = CASE WHEN ? THEN ? ELSE last_failed_interaction END
 		WHERE public_key = ?
 	`)
 	if err != nil {
@@ -1668,8 +2909,9 @@ func RecordHostScans(ctx context.Context, tx sql.Tx, scans []api.HostScan) error
 			scan.Success, V2HostSettings(scan.V2Settings), // settings
 			scan.Success, PriceTable(scan.PriceTable), // price_table
 			scan.Success, now, // price_table_expiry
-			scan.Success,  // successful_interactions
-			!scan.Success, // failed_interactions
+			scan.Success,            // successful_interactions
+			!scan.Success,           // failed_interactions
+			!scan.Success, scanTime, // last_failed_interaction
 			PublicKey(scan.HostKey),
 		)
 		if err != nil {
@@ -1679,6 +2921,26 @@ func RecordHostScans(ctx context.Context, tx sql.Tx, scans []api.HostScan) error
 	return nil
 }
 
+// RecordObjectEvents appends the given events to the object event log, the
+// events' IDs are assigned by the database.
+func RecordObjectEvents(ctx context.Context, tx sql.Tx, events []api.ObjectEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(ctx, "INSERT INTO object_events (created_at, operation, bucket, object_id, renamed_to, etag, size) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement to insert object event: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range events {
+		if _, err := stmt.Exec(ctx, e.Timestamp.Std(), e.Operation, e.Bucket, e.Key, e.RenamedTo, e.ETag, e.Size); err != nil {
+			return fmt.Errorf("failed to insert object event: %w", err)
+		}
+	}
+	return nil
+}
+
 func RemoveOfflineHosts(ctx context.Context, tx sql.Tx, minRecentFailures uint64, maxDownTime time.Duration) (int64, error) {
 	// fetch contracts belonging to offline hosts
 	rows, err := tx.Query(ctx, `
@@ -1794,6 +3056,56 @@ func ResetLostSectors(ctx context.Context, tx sql.Tx, hk types.PublicKey) error
 	return nil
 }
 
+func SampleSectors(ctx context.Context, tx sql.Tx, n int) ([]api.SectorSample, error) {
+	// fetch the largest sector id that is linked to a contract
+	var maxID dsql.NullInt64
+	if err := tx.QueryRow(ctx, "SELECT MAX(db_sector_id) FROM contract_sectors").Scan(&maxID); err != nil {
+		return nil, fmt.Errorf("failed to fetch max sector id: %w", err)
+	} else if !maxID.Valid || maxID.Int64 == 0 {
+		return nil, nil
+	}
+
+	// picking random ids is a lot cheaper than ordering the whole table
+	// randomly, ids that fall into gaps of the id space are skipped so we
+	// pick a few rounds of ids until we have enough samples
+	var samples []api.SectorSample
+	seen := make(map[api.SectorSample]struct{})
+	for round := 0; round < sampleSectorsMaxRounds && len(samples) < n; round++ {
+		ids := make([]any, n-len(samples))
+		for i := range ids {
+			ids[i] = int64(frand.Intn(int(maxID.Int64))) + 1
+		}
+
+		rows, err := tx.Query(ctx, fmt.Sprintf(`
+			SELECT s.root, c.host_key
+			FROM contract_sectors cs
+			INNER JOIN sectors s ON s.id = cs.db_sector_id
+			INNER JOIN contracts c ON c.id = cs.db_contract_id
+			WHERE cs.db_sector_id IN (%s)
+		`, strings.Repeat("?, ", len(ids)-1)+"?"), ids...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample sectors: %w", err)
+		}
+		for rows.Next() && len(samples) < n {
+			var sample api.SectorSample
+			if err := rows.Scan((*Hash256)(&sample.Root), (*PublicKey)(&sample.HostKey)); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan sector sample: %w", err)
+			} else if _, exists := seen[sample]; exists {
+				continue
+			}
+			seen[sample] = struct{}{}
+			samples = append(samples, sample)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate sector samples: %w", err)
+		}
+	}
+	return samples, nil
+}
+
 func Setting(ctx context.Context, tx sql.Tx, key string) (string, error) {
 	var value string
 	err := tx.QueryRow(ctx, "SELECT value FROM settings WHERE `key` = ?", key).Scan((*BusSetting)(&value))
@@ -1940,12 +3252,166 @@ func Tip(ctx context.Context, tx sql.Tx) (types.ChainIndex, error) {
 	}, nil
 }
 
+func TombstoneObject(ctx context.Context, tx sql.Tx, bucket, key string) (bool, error) {
+	var objID, bucketID, size int64
+	err := tx.QueryRow(ctx, "SELECT id, db_bucket_id, COALESCE(size, 0) FROM objects WHERE object_id = ? AND db_bucket_id = (SELECT id FROM buckets WHERE buckets.name = ?)", key, bucket).Scan(&objID, &bucketID, &size)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to fetch object: %w", err)
+	}
+
+	// an object without an object id can't be looked up or listed anymore but
+	// its slices remain until the object is pruned
+	res, err := tx.Exec(ctx, "UPDATE objects SET object_id = NULL, object_id_lower = NULL WHERE id = ?", objID)
+	if err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if n == 0 {
+		return false, nil
+	} else if err := UpdateBucketSize(ctx, tx, bucketID, -size); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TombstoneExpiredObjects marks up to 'limit' objects that expired according to
+// the lifecycle rules of their bucket as deleted and returns the objects that
+// were marked.
+func TombstoneExpiredObjects(ctx context.Context, tx sql.Tx, now time.Time, limit int64) ([]api.ObjectMetadata, error) {
+	type rule struct {
+		bucketID int64
+		bucket   string
+		prefix   string
+		days     uint64
+	}
+
+	// fetch all rules
+	rows, err := tx.Query(ctx, "SELECT r.db_bucket_id, b.name, r.prefix, r.expiration_days FROM bucket_lifecycle_rules r INNER JOIN buckets b ON b.id = r.db_bucket_id ORDER BY r.id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lifecycle rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []rule
+	for rows.Next() {
+		var r rule
+		if err := rows.Scan(&r.bucketID, &r.bucket, &r.prefix, &r.days); err != nil {
+			return nil, fmt.Errorf("failed to scan lifecycle rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// tombstone the expired objects of every rule until we reach the limit
+	var expired []api.ObjectMetadata
+	for _, r := range rules {
+		if int64(len(expired)) >= limit {
+			break
+		}
+
+		whereExprs := []string{"db_bucket_id = ?", "object_id IS NOT NULL", "created_at < ?"}
+		args := []any{r.bucketID, now.Add(-time.Duration(r.days) * 24 * time.Hour)}
+		if r.prefix != "" {
+			expr, prefixArgs := ObjectIDPrefixExpr("object_id", r.prefix)
+			whereExprs = append(whereExprs, expr)
+			args = append(args, prefixArgs...)
+		}
+		args = append(args, limit-int64(len(expired)))
+
+		objRows, err := tx.Query(ctx, fmt.Sprintf("SELECT id, object_id, COALESCE(etag, ''), COALESCE(size, 0) FROM objects WHERE %s LIMIT ?", strings.Join(whereExprs, " AND ")), args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch expired objects: %w", err)
+		}
+		var ids []any
+		var objs []api.ObjectMetadata
+		var size int64
+		for objRows.Next() {
+			var id int64
+			om := api.ObjectMetadata{Bucket: r.bucket}
+			if err := objRows.Scan(&id, &om.Key, &om.ETag, &om.Size); err != nil {
+				objRows.Close()
+				return nil, fmt.Errorf("failed to scan expired object: %w", err)
+			}
+			ids = append(ids, id)
+			objs = append(objs, om)
+			size += int64(om.Size)
+		}
+		objRows.Close()
+		if err := objRows.Err(); err != nil {
+			return nil, err
+		} else if len(ids) == 0 {
+			continue
+		}
+
+		// an object without an object id can't be looked up or listed anymore,
+		// it gets pruned together with the other tombstones
+		_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE objects SET object_id = NULL, object_id_lower = NULL WHERE id IN (%s)", strings.Repeat("?, ", len(ids)-1)+"?"), ids...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to tombstone expired objects: %w", err)
+		} else if err := UpdateBucketSize(ctx, tx, r.bucketID, -size); err != nil {
+			return nil, err
+		}
+		expired = append(expired, objs...)
+	}
+	return expired, nil
+}
+
+func SharedSlabs(ctx context.Context, tx sql.Tx, minObjects uint64, limit int) ([]api.SharedSlab, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid limit %d, must be positive", limit)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT sla.key, COUNT(DISTINCT sli.db_object_id) AS num_objects
+		FROM slices sli
+		INNER JOIN slabs sla ON sla.id = sli.db_slab_id
+		INNER JOIN objects o ON o.id = sli.db_object_id
+		WHERE o.object_id IS NOT NULL
+		GROUP BY sla.id, sla.key
+		HAVING COUNT(DISTINCT sli.db_object_id) > ?
+		ORDER BY num_objects DESC, sla.id ASC
+		LIMIT ?
+	`, minObjects, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shared slabs: %w", err)
+	}
+	defer rows.Close()
+
+	var slabs []api.SharedSlab
+	for rows.Next() {
+		var slab api.SharedSlab
+		if err := rows.Scan((*EncryptionKey)(&slab.EncryptionKey), &slab.NumObjects); err != nil {
+			return nil, fmt.Errorf("failed to scan shared slab: %w", err)
+		}
+		slabs = append(slabs, slab)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate shared slabs: %w", err)
+	}
+	return slabs, nil
+}
+
 func SlabsForMigration(ctx context.Context, tx sql.Tx, healthCutoff float64, limit int) ([]api.UnhealthySlab, error) {
 	rows, err := tx.Query(ctx, `
-		SELECT sla.key, sla.health
+		SELECT sla.id, sla.key, sla.health, ps.db_slab_id IS NOT NULL AS pinned
 		FROM slabs sla
-		WHERE sla.health <= ? AND sla.health_valid_until > ? AND sla.db_buffered_slab_id IS NULL
-		ORDER BY sla.health ASC
+		LEFT JOIN (
+			SELECT DISTINCT sli.db_slab_id
+			FROM slices sli
+			INNER JOIN objects o ON o.id = sli.db_object_id
+			WHERE o.object_id IS NOT NULL AND o.pinned
+		) ps ON ps.db_slab_id = sla.id
+		WHERE sla.health <= ? AND sla.health_valid_until > ? AND sla.db_buffered_slab_id IS NULL AND EXISTS (
+			SELECT 1
+			FROM slices sli
+			LEFT JOIN objects o ON o.id = sli.db_object_id
+			WHERE sli.db_slab_id = sla.id AND (sli.db_object_id IS NULL OR o.object_id IS NOT NULL)
+		)
+		ORDER BY sla.health ASC, pinned DESC
 		LIMIT ?
 	`, healthCutoff, time.Now().Unix(), limit)
 	if err != nil {
@@ -1954,12 +3420,56 @@ func SlabsForMigration(ctx context.Context, tx sql.Tx, healthCutoff float64, lim
 	defer rows.Close()
 
 	var slabs []api.UnhealthySlab
+	var slabIDs []any
+	indices := make(map[int64]int)
 	for rows.Next() {
 		var slab api.UnhealthySlab
-		if err := rows.Scan((*EncryptionKey)(&slab.EncryptionKey), &slab.Health); err != nil {
+		var slabID int64
+		if err := rows.Scan(&slabID, (*EncryptionKey)(&slab.EncryptionKey), &slab.Health, &slab.Pinned); err != nil {
 			return nil, fmt.Errorf("failed to scan unhealthy slab: %w", err)
 		}
+		indices[slabID] = len(slabs)
 		slabs = append(slabs, slab)
+		slabIDs = append(slabIDs, slabID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch unhealthy slabs: %w", err)
+	} else if len(slabs) == 0 {
+		return nil, nil
+	}
+
+	// fetch the hosts the slabs are pinned to, a slab is pinned to a host if
+	// any object or multipart upload that references it is pinned to it, the
+	// two are looked up separately so each join can use its index
+	inExpr := strings.Repeat("?, ", len(slabIDs)-1) + "?"
+	pinned, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT sli.db_slab_id, oph.public_key
+		FROM slices sli
+		INNER JOIN object_pinned_hosts oph ON oph.db_object_id = sli.db_object_id
+		WHERE sli.db_slab_id IN (%s)
+		UNION
+		SELECT sli.db_slab_id, oph.public_key
+		FROM slices sli
+		INNER JOIN multipart_parts mpp ON mpp.id = sli.db_multipart_part_id
+		INNER JOIN object_pinned_hosts oph ON oph.db_multipart_upload_id = mpp.db_multipart_upload_id
+		WHERE sli.db_slab_id IN (%s)
+	`, inExpr, inExpr), append(slabIDs, slabIDs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pinned hosts: %w", err)
+	}
+	defer pinned.Close()
+
+	for pinned.Next() {
+		var slabID int64
+		var hk types.PublicKey
+		if err := pinned.Scan(&slabID, (*PublicKey)(&hk)); err != nil {
+			return nil, fmt.Errorf("failed to scan pinned host: %w", err)
+		}
+		i := indices[slabID]
+		slabs[i].PinnedHosts = append(slabs[i].PinnedHosts, hk)
+	}
+	if err := pinned.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch pinned hosts: %w", err)
 	}
 	return slabs, nil
 }
@@ -1969,7 +3479,32 @@ func UpdateBucketPolicy(ctx context.Context, tx sql.Tx, bucket string, bp api.Bu
 	if err != nil {
 		return err
 	}
-	res, err := tx.Exec(ctx, "UPDATE buckets SET policy = ? WHERE name = ?", policy, bucket)
+
+	// a bucket can only be made case-insensitive if none of its objects'
+	// keys differ only in case
+	if bp.CaseInsensitive {
+		if err := backfillObjectIDLower(ctx, tx, bucket); err != nil {
+			return err
+		}
+
+		var conflict bool
+		err := tx.QueryRow(ctx, `
+			SELECT 1
+			FROM objects o
+			INNER JOIN buckets b ON b.id = o.db_bucket_id
+			WHERE b.name = ? AND b.case_insensitive = 0 AND o.object_id IS NOT NULL
+			GROUP BY o.object_id_lower
+			HAVING COUNT(*) > 1
+			LIMIT 1
+		`, bucket).Scan(&conflict)
+		if err == nil {
+			return api.ErrObjectKeyCaseConflict
+		} else if !errors.Is(err, dsql.ErrNoRows) {
+			return fmt.Errorf("failed to check for conflicting keys: %w", err)
+		}
+	}
+
+	res, err := tx.Exec(ctx, "UPDATE buckets SET policy = ?, case_insensitive = ? WHERE name = ?", policy, bp.CaseInsensitive, bucket)
 	if err != nil {
 		return fmt.Errorf("failed to update bucket policy: %w", err)
 	} else if n, err := res.RowsAffected(); err != nil {
@@ -1980,6 +3515,80 @@ func UpdateBucketPolicy(ctx context.Context, tx sql.Tx, bucket string, bp api.Bu
 	return nil
 }
 
+// backfillObjectIDLower sets the lowercase key of the objects in the given
+// bucket that were added before the column was introduced. Keys are lowercased
+// in Go rather than by the database since SQLite's lower() only folds ASCII
+// characters.
+func backfillObjectIDLower(ctx context.Context, tx sql.Tx, bucket string) error {
+	const batchSize = 1000
+
+	updateStmt, err := tx.Prepare(ctx, "UPDATE objects SET object_id_lower = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement to update lowercase key: %w", err)
+	}
+	defer updateStmt.Close()
+
+	for {
+		rows, err := tx.Query(ctx, `
+			SELECT o.id, o.object_id
+			FROM objects o
+			INNER JOIN buckets b ON b.id = o.db_bucket_id
+			WHERE b.name = ? AND o.object_id IS NOT NULL AND o.object_id_lower IS NULL
+			LIMIT ?
+		`, bucket, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to fetch objects without lowercase key: %w", err)
+		}
+
+		type row struct {
+			id  int64
+			key string
+		}
+		var objects []row
+		for rows.Next() {
+			var o row
+			if err := rows.Scan(&o.id, &o.key); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan object: %w", err)
+			}
+			objects = append(objects, o)
+		}
+		if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+			return fmt.Errorf("failed to fetch objects without lowercase key: %w", err)
+		}
+
+		for _, o := range objects {
+			if _, err := updateStmt.Exec(ctx, strings.ToLower(o.key), o.id); err != nil {
+				return fmt.Errorf("failed to update lowercase key: %w", err)
+			}
+		}
+		if len(objects) < batchSize {
+			return nil
+		}
+	}
+}
+
+// UpdateBucketLifecycleRules replaces the lifecycle rules of the given bucket.
+func UpdateBucketLifecycleRules(ctx context.Context, tx sql.Tx, bucket string, rules []api.BucketLifecycleRule) error {
+	var bucketID int64
+	err := tx.QueryRow(ctx, "SELECT id FROM buckets WHERE name = ?", bucket).Scan(&bucketID)
+	if errors.Is(err, dsql.ErrNoRows) {
+		return api.ErrBucketNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch bucket id: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM bucket_lifecycle_rules WHERE db_bucket_id = ?", bucketID); err != nil {
+		return fmt.Errorf("failed to delete lifecycle rules: %w", err)
+	}
+	for _, rule := range rules {
+		if _, err := tx.Exec(ctx, "INSERT INTO bucket_lifecycle_rules (created_at, db_bucket_id, prefix, expiration_days) VALUES (?, ?, ?, ?)", time.Now(), bucketID, rule.Prefix, rule.ExpirationDays); err != nil {
+			return fmt.Errorf("failed to insert lifecycle rule: %w", err)
+		}
+	}
+	return nil
+}
+
 func UpdateContract(ctx context.Context, tx sql.Tx, fcid types.FileContractID, c api.ContractMetadata) error {
 	// validate metadata
 	var state ContractState
@@ -2022,7 +3631,16 @@ func UpdateContractUsability(ctx context.Context, tx sql.Tx, fcid types.FileCont
 		return err
 	}
 
-	_, err := tx.Exec(ctx, `UPDATE contracts SET usability = ? WHERE fcid = ?`, u, FileContractID(fcid))
+	// contracts that are being offboarded can't become good again
+	_, err := tx.Exec(ctx, `
+		UPDATE contracts
+		SET usability = ?
+		WHERE fcid = ? AND (? = ? OR NOT EXISTS (
+			SELECT 1
+			FROM contract_offboardings co
+			WHERE co.db_contract_id = contracts.id
+		))
+	`, u, FileContractID(fcid), u, contractUsabilityBad)
 	if errors.Is(err, dsql.ErrNoRows) {
 		return api.ErrContractNotFound
 	}
@@ -2042,7 +3660,9 @@ SET enabled = ?,
 	contracts_prune = ?,
 	hosts_max_downtime_hours = ?,
 	hosts_min_protocol_version = ?,
-	hosts_max_consecutive_scan_failures = ?
+	hosts_max_consecutive_scan_failures = ?,
+	hosts_max_announcement_age_hours = ?,
+	hosts_recent_failure_cooldown_hours = ?
 WHERE id = ?`,
 		cfg.Enabled,
 		cfg.Contracts.Amount,
@@ -2055,6 +3675,8 @@ WHERE id = ?`,
 		cfg.Hosts.MaxDowntimeHours,
 		cfg.Hosts.MinProtocolVersion,
 		cfg.Hosts.MaxConsecutiveScanFailures,
+		cfg.Hosts.MaxAnnouncementAgeHours,
+		cfg.Hosts.RecentFailureCooldownHours,
 		sql.AutopilotID)
 	return err
 }
@@ -2221,7 +3843,8 @@ EXISTS (
 		hc.usability_low_max_duration = 0 AND
 		hc.usability_not_accepting_contracts = 0 AND
 		hc.usability_not_announced = 0 AND
-		hc.usability_not_completing_scan = 0
+		hc.usability_not_completing_scan = 0 AND
+		hc.usability_stale_announcement = 0
 )`)
 
 	// query hosts
@@ -2233,7 +3856,8 @@ EXISTS (
 	COALESCE(h.settings->>'$.siamuxport', "") AS siamux_port,
 	h.price_table,
 	h.settings,
-	h.v2_settings
+	h.v2_settings,
+	MAX(hc.usability_recently_failed)
 	FROM hosts h
 	INNER JOIN contracts c on c.host_id = h.id and c.archival_reason IS NULL AND c.usability = ?
 	INNER JOIN host_checks hc on hc.db_host_id = h.id
@@ -2253,7 +3877,8 @@ EXISTS (
 		var pt PriceTable
 		var hs HostSettings
 		var v2Hs V2HostSettings
-		err := rows.Scan(&hostID, &hk, &addr, &port, &pt, &hs, &v2Hs)
+		var recentlyFailed bool
+		err := rows.Scan(&hostID, &hk, &addr, &port, &pt, &hs, &v2Hs, &recentlyFailed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan host: %w", err)
 		}
@@ -2267,8 +3892,9 @@ EXISTS (
 
 		hosts = append(hosts, HostInfo{
 			api.HostInfo{
-				PublicKey:  types.PublicKey(hk),
-				SiamuxAddr: siamuxAddr,
+				PublicKey:      types.PublicKey(hk),
+				SiamuxAddr:     siamuxAddr,
+				RecentlyFailed: recentlyFailed,
 			},
 			rhpv2.HostSettings(hs),
 			rhpv3.HostPriceTable(pt),
@@ -2544,7 +4170,7 @@ func RecordContractSpending(ctx context.Context, tx Tx, fcid types.FileContractI
 func Object(ctx context.Context, tx Tx, bucket, key string) (api.Object, error) {
 	/// fetch object metadata
 	row := tx.QueryRow(ctx, fmt.Sprintf(`
-		SELECT %s, o.id, o.key
+		SELECT %s, o.id, o.key, o.pinned
 		FROM objects o
 		INNER JOIN buckets b ON o.db_bucket_id = b.id
 		WHERE o.object_id = ? AND b.name = ?
@@ -2552,7 +4178,8 @@ func Object(ctx context.Context, tx Tx, bucket, key string) (api.Object, error)
 		tx.SelectObjectMetadataExpr()), key, bucket)
 	var objID int64
 	var ec object.EncryptionKey
-	om, err := tx.ScanObjectMetadata(row, &objID, (*EncryptionKey)(&ec))
+	var pinned bool
+	om, err := tx.ScanObjectMetadata(row, &objID, (*EncryptionKey)(&ec), &pinned)
 	if errors.Is(err, dsql.ErrNoRows) {
 		return api.Object{}, api.ErrObjectNotFound
 	} else if err != nil {
@@ -2601,16 +4228,20 @@ func Object(ctx context.Context, tx Tx, bucket, key string) (api.Object, error)
 		slabSlices = append(slabSlices, ss)
 	}
 
-	// fill in the shards
+	// fill in the shards, buffered slabs don't have any yet
+	var pending bool
 	for i := range slabSlices {
 		slabSlices[i].Slab, err = Slab(ctx, tx, slabSlices[i].EncryptionKey)
 		if err != nil {
 			return api.Object{}, fmt.Errorf("failed to fetch slab: %w", err)
 		}
+		pending = pending || slabSlices[i].IsPartial()
 	}
 
 	return api.Object{
 		Metadata:       oum,
+		Pinned:         pinned,
+		Pending:        pending,
 		ObjectMetadata: om,
 		Object: &object.Object{
 			Key:   ec,
@@ -2660,7 +4291,7 @@ func fillInV2Addresses(ctx context.Context, tx sql.Tx, hostIDs []int64, assignFn
 	return nil
 }
 
-func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey) (api.ObjectsResponse, error) {
+func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, glob, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey, minHealth, maxHealth *float64) (api.ObjectsResponse, error) {
 	// fetch one more to see if there are more entries
 	if limit <= -1 {
 		limit = math.MaxInt
@@ -2668,8 +4299,11 @@ func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, s
 		limit++
 	}
 
-	// establish sane defaults for sorting
-	if sortBy == "" {
+	// establish sane defaults for sorting, when filtering by health the most
+	// at-risk objects are listed first
+	if sortBy == "" && (minHealth != nil || maxHealth != nil) {
+		sortBy = api.ObjectSortByHealth
+	} else if sortBy == "" {
 		sortBy = api.ObjectSortByName
 	}
 	if sortDir == "" {
@@ -2685,10 +4319,13 @@ func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, s
 		whereArgs = append(whereArgs, bucket)
 	}
 
-	// apply prefix
+	// apply prefix, without a prefix we still need to skip tombstoned objects
 	if prefix != "" {
-		whereExprs = append(whereExprs, "o.object_id LIKE ? AND SUBSTR(o.object_id, 1, ?) = ?")
-		whereArgs = append(whereArgs, prefix+"%", utf8.RuneCountInString(prefix), prefix)
+		prefixExpr, prefixArgs := ObjectIDPrefixExpr("o.object_id", prefix)
+		whereExprs = append(whereExprs, prefixExpr)
+		whereArgs = append(whereArgs, prefixArgs...)
+	} else {
+		whereExprs = append(whereExprs, "o.object_id IS NOT NULL")
 	}
 
 	// apply substring
@@ -2697,28 +4334,42 @@ func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, s
 		whereArgs = append(whereArgs, substring)
 	}
 
+	// apply glob, the pattern's static prefix bounds the object ids so the
+	// index on the object id is used to narrow down the rows to match
+	if glob != "" {
+		pattern, err := api.ParseGlob(glob)
+		if err != nil {
+			return api.ObjectsResponse{}, err
+		}
+		globPrefix, _ := api.GlobPrefix(glob)
+		if globPrefix != "" {
+			prefixExpr, prefixArgs := ObjectIDPrefixExpr("o.object_id", globPrefix)
+			whereExprs = append(whereExprs, prefixExpr)
+			whereArgs = append(whereArgs, prefixArgs...)
+		}
+		globExpr, globArg := tx.GlobExpr("o.object_id", pattern)
+		whereExprs = append(whereExprs, globExpr)
+		whereArgs = append(whereArgs, globArg)
+	}
+
 	// apply sorting
-	orderByExprs, err := orderByObject(sortBy, sortDir)
+	orderByExprs, err := orderByObject(sortBy, sortDir, bucket == "")
 	if err != nil {
 		return api.ObjectsResponse{}, fmt.Errorf("failed to apply sorting: %w", err)
 	}
 
 	// apply marker
-	markerExprs, markerArgs, err := whereObjectMarker(marker, sortBy, sortDir, func(dst any, marker, col string) error {
-		markerExprs := []string{"o.object_id = ?"}
-		markerArgs := []any{marker}
-
-		if bucket != "" {
-			markerExprs = append(markerExprs, "b.name = ?")
-			markerArgs = append(markerArgs, bucket)
-		}
-
+	markerBucket, marker, err := parseObjectMarker(bucket, marker)
+	if err != nil {
+		return api.ObjectsResponse{}, err
+	}
+	markerExprs, markerArgs, err := whereObjectMarker(marker, markerBucket, sortBy, sortDir, bucket == "", func(dst any, marker, col string) error {
 		err := tx.QueryRow(ctx, fmt.Sprintf(`
 			SELECT o.%s
 			FROM objects o
 			INNER JOIN buckets b ON o.db_bucket_id = b.id
-			WHERE %s
-		`, col, strings.Join(markerExprs, " AND ")), markerArgs...).Scan(dst)
+			WHERE o.object_id = ? AND b.name = ?
+		`, col), marker, markerBucket).Scan(dst)
 		if errors.Is(err, dsql.ErrNoRows) {
 			return api.ErrMarkerNotFound
 		} else {
@@ -2737,6 +4388,11 @@ func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, s
 		whereArgs = append(whereArgs, EncryptionKey(slabEncryptionKey))
 	}
 
+	// apply health range
+	healthExprs, healthArgs := whereObjectHealth(minHealth, maxHealth)
+	whereExprs = append(whereExprs, healthExprs...)
+	whereArgs = append(whereArgs, healthArgs...)
+
 	// apply limit
 	whereArgs = append(whereArgs, limit)
 
@@ -2772,6 +4428,9 @@ func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, s
 		}
 		objects = append(objects, om)
 	}
+	if err := rows.Err(); err != nil {
+		return api.ObjectsResponse{}, fmt.Errorf("failed to fetch objects: %w", err)
+	}
 
 	var hasMore bool
 	var nextMarker string
@@ -2779,7 +4438,7 @@ func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, s
 		objects = objects[:len(objects)-1]
 		if len(objects) > 0 {
 			hasMore = true
-			nextMarker = objects[len(objects)-1].Key
+			nextMarker = nextObjectMarker(bucket, objects[len(objects)-1])
 		}
 	}
 
@@ -2790,7 +4449,7 @@ func listObjectsNoDelim(ctx context.Context, tx Tx, bucket, prefix, substring, s
 	}, nil
 }
 
-func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey) (api.ObjectsResponse, error) {
+func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey, minHealth, maxHealth *float64) (api.ObjectsResponse, error) {
 	// split prefix into path and object prefix
 	path := "/" // root of bucket
 	if idx := strings.LastIndex(prefix, "/"); idx != -1 {
@@ -2808,8 +4467,11 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 		limit++
 	}
 
-	// establish sane defaults for sorting
-	if sortBy == "" {
+	// establish sane defaults for sorting, when filtering by health the most
+	// at-risk objects are listed first
+	if sortBy == "" && (minHealth != nil || maxHealth != nil) {
+		sortBy = api.ObjectSortByHealth
+	} else if sortBy == "" {
 		sortBy = api.ObjectSortByName
 	}
 	if sortDir == "" {
@@ -2817,11 +4479,11 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 	}
 
 	// add object query args
-	args := []any{
-		path + "%", utf8.RuneCountInString(path), path, // case-sensitive object_id LIKE
-		path,                             // exclude exact path
-		utf8.RuneCountInString(path) + 1, // exclude dirs
-	}
+	pathExpr, pathArgs := ObjectIDPrefixExpr("o.object_id", path)
+	args := append(pathArgs,
+		path,                           // exclude exact path
+		utf8.RuneCountInString(path)+1, // exclude dirs
+	)
 
 	var slabKeyObjExpr string
 	if slabEncryptionKey != (object.EncryptionKey{}) {
@@ -2830,9 +4492,9 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 	}
 
 	// add directory query args
+	args = append(args, utf8.RuneCountInString(path), utf8.RuneCountInString(path)+1)
+	args = append(args, pathArgs...)
 	args = append(args,
-		utf8.RuneCountInString(path), utf8.RuneCountInString(path)+1,
-		path+"%", utf8.RuneCountInString(path), path, // case-sensitive object_id LIKE
 		utf8.RuneCountInString(path), utf8.RuneCountInString(path)+1, path,
 		utf8.RuneCountInString(path), utf8.RuneCountInString(path)+1,
 	)
@@ -2843,43 +4505,35 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 
 	// apply marker
 	var whereExprs []string
-	markerExprs, markerArgs, err := whereObjectMarker(marker, sortBy, sortDir, func(dst any, marker, col string) error {
+	markerBucket, marker, err := parseObjectMarker(bucket, marker)
+	if err != nil {
+		return api.ObjectsResponse{}, err
+	}
+	markerExprs, markerArgs, err := whereObjectMarker(marker, markerBucket, sortBy, sortDir, bucket == "", func(dst any, marker, col string) error {
 		var groupFn string
 		switch col {
 		case "size":
 			groupFn = "SUM"
 		case "health":
 			groupFn = "MIN"
+		case "created_at":
+			groupFn = "MAX"
 		default:
 			return fmt.Errorf("unknown column: %v", col)
 		}
 
-		markerExprsObj := []string{"o.object_id = ?"}
-		markerArgsObj := []any{marker}
-		if bucket != "" {
-			markerExprsObj = append(markerExprsObj, "b.name = ?")
-			markerArgsObj = append(markerArgsObj, bucket)
-		}
-
-		markerExprsDir := []string{"SUBSTR(o.object_id, 1, ?) = ?"}
-		markerArgsDir := []any{utf8.RuneCountInString(marker), marker}
-		if bucket != "" {
-			markerExprsDir = append(markerExprsDir, "b.name = ?")
-			markerArgsDir = append(markerArgsDir, bucket)
-		}
-
 		err := tx.QueryRow(ctx, fmt.Sprintf(`
 			SELECT o.%s
 			FROM objects o
 			INNER JOIN buckets b ON o.db_bucket_id = b.id
-			WHERE %s
+			WHERE o.object_id = ? AND b.name = ?
 			UNION ALL
 			SELECT %s(o.%s)
 			FROM objects o
 			INNER JOIN buckets b ON o.db_bucket_id = b.id
-			WHERE %s
+			WHERE SUBSTR(o.object_id, 1, ?) = ? AND b.name = ?
 			GROUP BY o.db_bucket_id
-		`, col, strings.Join(markerExprsObj, " AND "), groupFn, col, strings.Join(markerExprsDir, " AND ")), append(markerArgsObj, markerArgsDir...)...).Scan(dst)
+		`, col, groupFn, col), marker, markerBucket, utf8.RuneCountInString(marker), marker, markerBucket).Scan(dst)
 		if errors.Is(err, dsql.ErrNoRows) {
 			return api.ErrMarkerNotFound
 		} else {
@@ -2907,8 +4561,13 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 		)
 	}
 
+	// apply health range, directories are filtered by their worst health
+	healthExprs, healthArgs := whereObjectHealth(minHealth, maxHealth)
+	whereExprs = append(whereExprs, healthExprs...)
+	args = append(args, healthArgs...)
+
 	// apply sorting
-	orderByExprs, err := orderByObject(sortBy, sortDir)
+	orderByExprs, err := orderByObject(sortBy, sortDir, bucket == "")
 	if err != nil {
 		return api.ObjectsResponse{}, fmt.Errorf("failed to apply sorting: %w", err)
 	}
@@ -2928,10 +4587,10 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 	query := fmt.Sprintf(`
 	SELECT %s
 	FROM (
-		SELECT o.db_bucket_id, o.object_id, o.size, o.health, o.mime_type, o.created_at, o.etag
+		SELECT o.db_bucket_id, o.object_id, o.size, o.health, o.mime_type, o.created_at, o.etag, o.checksum, o.content_disposition
 		FROM objects o
 		WHERE
-			o.object_id LIKE ? AND SUBSTR(o.object_id, 1, ?) = ? AND
+			%s AND
 			o.object_id != ? AND
 			INSTR(SUBSTR(o.object_id, ?), "/") = 0
 			AND SUBSTR(o.object_id, -1, 1) != "/"
@@ -2939,13 +4598,13 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 
 		UNION ALL
 
-		SELECT MIN(o.db_bucket_id), MIN(SUBSTR(o.object_id, 1, ?+INSTR(SUBSTR(o.object_id, ?), "/"))) as object_id, SUM(o.size) as size, MIN(o.health), '' as mime_type, MAX(o.created_at), '' as etag
+		SELECT o.db_bucket_id, MIN(SUBSTR(o.object_id, 1, ?+INSTR(SUBSTR(o.object_id, ?), "/"))) as object_id, SUM(o.size) as size, MIN(o.health), '' as mime_type, MAX(o.created_at), '' as etag, '' as checksum, '' as content_disposition
 		FROM objects o
 		WHERE
-			o.object_id LIKE ? AND SUBSTR(o.object_id, 1, ?) = ? AND
+			%s AND
 			SUBSTR(o.object_id, 1, ?+INSTR(SUBSTR(o.object_id, ?), "/")) != ?
 			%s
-		GROUP BY SUBSTR(o.object_id, 1, ?+INSTR(SUBSTR(o.object_id, ?), "/"))
+		GROUP BY o.db_bucket_id, SUBSTR(o.object_id, 1, ?+INSTR(SUBSTR(o.object_id, ?), "/"))
 	) AS o
 	INNER JOIN buckets b ON b.id = o.db_bucket_id
 	%s
@@ -2953,7 +4612,9 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 	LIMIT ?
 `,
 		tx.SelectObjectMetadataExpr(),
+		pathExpr,
 		slabKeyObjExpr,
+		pathExpr,
 		slabKeyDirExpr,
 		whereExpr,
 		strings.Join(orderByExprs, ", "),
@@ -2972,6 +4633,9 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 		}
 		objects = append(objects, om)
 	}
+	if err := rows.Err(); err != nil {
+		return api.ObjectsResponse{}, fmt.Errorf("failed to fetch objects: %w", err)
+	}
 
 	// trim last element if we have more
 	var hasMore bool
@@ -2980,7 +4644,7 @@ func listObjectsSlashDelim(ctx context.Context, tx Tx, bucket, prefix, sortBy, s
 		objects = objects[:len(objects)-1]
 		if len(objects) > 0 {
 			hasMore = true
-			nextMarker = objects[len(objects)-1].Key
+			nextMarker = nextObjectMarker(bucket, objects[len(objects)-1])
 		}
 	}
 
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -63,6 +64,12 @@ func (b *MainDatabase) Close() error {
 	return b.db.Close()
 }
 
+// CopyFrom copies all rows of the main database in src into the database. The
+// copy can be resumed if it was interrupted by calling CopyFrom again.
+func (b *MainDatabase) CopyFrom(ctx context.Context, src *dsql.DB, batchSize int) error {
+	return ssql.CopyMainDatabase(ctx, src, b.db, "Duplicate entry", batchSize, b.log)
+}
+
 func (b *MainDatabase) CreateMigrationTable(ctx context.Context) error {
 	return createMigrationTable(ctx, b.db)
 }
@@ -102,16 +109,32 @@ func (b *MainDatabase) Migrate(ctx context.Context) error {
 	return sql.PerformMigrations(ctx, b, migrationsFs, "main", sql.MainMigrations(ctx, b, migrationsFs, b.log))
 }
 
+func (b *MainDatabase) PendingMigrations(ctx context.Context) (int, error) {
+	return sql.PendingMigrations(ctx, b.db, sql.MainMigrations(ctx, b, migrationsFs, b.log))
+}
+
 func (b *MainDatabase) PartialSlabDir() string {
 	return b.PartialSlabDir()
 }
 
+func (b *MainDatabase) Ping(ctx context.Context) (time.Duration, error) {
+	return b.db.Ping(ctx)
+}
+
+func (b *MainDatabase) Stats() dsql.DBStats {
+	return b.db.DB().Stats()
+}
+
 func (b *MainDatabase) Transaction(ctx context.Context, fn func(tx ssql.DatabaseTx) error) error {
 	return b.db.Transaction(ctx, func(tx sql.Tx) error {
 		return fn(b.wrapTxn(tx))
 	})
 }
 
+func (b *MainDatabase) TransactionRetries() map[string]uint64 {
+	return b.db.TransactionRetries()
+}
+
 func (b *MainDatabase) UpdateSetting(ctx context.Context, tx sql.Tx, key, value string) error {
 	mtx := b.wrapTxn(tx)
 	return mtx.UpdateSetting(ctx, key, value)
@@ -142,7 +165,7 @@ func (tx *MainDatabaseTx) AddMultipartPart(ctx context.Context, bucket, path, eT
 		return fmt.Errorf("failed to fetch multipart upload: %w", err)
 	}
 
-	// delete a potentially existing part
+	// delete a potentially existing part, including a part in progress
 	_, err = tx.Exec(ctx, "DELETE FROM multipart_parts WHERE db_multipart_upload_id = ? AND part_number = ?",
 		muID, partNumber)
 	if err != nil {
@@ -164,7 +187,65 @@ func (tx *MainDatabaseTx) AddMultipartPart(ctx context.Context, bucket, path, eT
 	}
 
 	// create slices
-	return tx.insertSlabs(ctx, nil, &partID, slices)
+	return tx.insertSlabs(ctx, nil, &partID, 0, slices)
+}
+
+func (tx *MainDatabaseTx) AddMultipartPartProgress(ctx context.Context, bucket, path, uploadID string, partNumber, index int, checksum types.Hash256, slices object.SlabSlices) error {
+	if len(slices) == 0 {
+		return nil // nothing to do
+	}
+
+	// find multipart upload
+	var muID int64
+	err := tx.QueryRow(ctx, "SELECT id FROM multipart_uploads WHERE upload_id = ?", uploadID).
+		Scan(&muID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch multipart upload: %w", err)
+	}
+
+	// find the part in progress or create it, it's neither listed nor used to
+	// complete the upload until it's replaced by the finished part
+	var partID int64
+	err = tx.QueryRow(ctx, "SELECT id FROM multipart_parts WHERE db_multipart_upload_id = ? AND part_number = ? AND in_progress = ?", muID, partNumber, true).
+		Scan(&partID)
+	if errors.Is(err, dsql.ErrNoRows) {
+		res, err := tx.Exec(ctx, "INSERT INTO multipart_parts (created_at, etag, part_number, size, db_multipart_upload_id, in_progress) VALUES (?, ?, ?, ?, ?, ?)",
+			time.Now(), "", partNumber, 0, muID, true)
+		if err != nil {
+			return fmt.Errorf("failed to insert part: %w", err)
+		} else if partID, err = res.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to fetch part id: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch part: %w", err)
+	}
+
+	// delete the slices previously persisted at the same indices, this makes
+	// persisting progress idempotent
+	if _, err := tx.Exec(ctx, "DELETE FROM slices WHERE db_multipart_part_id = ? AND object_index > ? AND object_index <= ?", partID, index, index+len(slices)); err != nil {
+		return fmt.Errorf("failed to delete existing slices: %w", err)
+	} else if _, err := tx.Exec(ctx, "DELETE FROM multipart_part_progress WHERE db_multipart_part_id = ? AND object_index > ? AND object_index <= ?", partID, index, index+len(slices)); err != nil {
+		return fmt.Errorf("failed to delete existing progress: %w", err)
+	}
+
+	// create slices
+	if err := tx.insertSlabs(ctx, nil, &partID, index, slices); err != nil {
+		return err
+	}
+
+	// store the checksum of the data the slices were created from
+	insertProgressStmt, err := tx.Prepare(ctx, "INSERT INTO multipart_part_progress (created_at, db_multipart_part_id, object_index, checksum) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement to insert progress: %w", err)
+	}
+	defer insertProgressStmt.Close()
+
+	for i := range slices {
+		if _, err := insertProgressStmt.Exec(ctx, time.Now(), partID, index+i+1, ssql.Hash256(checksum)); err != nil {
+			return fmt.Errorf("failed to insert progress: %w", err)
+		}
+	}
+	return nil
 }
 
 func (tx *MainDatabaseTx) AddPeer(ctx context.Context, addr string) error {
@@ -209,6 +290,10 @@ func (tx *MainDatabaseTx) AutopilotConfig(ctx context.Context) (api.AutopilotCon
 	return ssql.AutopilotConfig(ctx, tx)
 }
 
+func (tx *MainDatabaseTx) BackfillObjectChecksum(ctx context.Context, id uint64, checksum string) error {
+	return ssql.BackfillObjectChecksum(ctx, tx, id, checksum)
+}
+
 func (tx *MainDatabaseTx) BanPeer(ctx context.Context, addr string, duration time.Duration, reason string) error {
 	cidr, err := ssql.NormalizePeer(addr)
 	if err != nil {
@@ -229,6 +314,14 @@ func (tx *MainDatabaseTx) Bucket(ctx context.Context, bucket string) (api.Bucket
 	return ssql.Bucket(ctx, tx, bucket)
 }
 
+func (tx *MainDatabaseTx) BucketLifecycleRules(ctx context.Context, bucket string) ([]api.BucketLifecycleRule, error) {
+	return ssql.BucketLifecycleRules(ctx, tx, bucket)
+}
+
+func (tx *MainDatabaseTx) BucketSize(ctx context.Context, bucket string) (uint64, error) {
+	return ssql.BucketSize(ctx, tx, bucket)
+}
+
 func (tx *MainDatabaseTx) Buckets(ctx context.Context) ([]api.Bucket, error) {
 	return ssql.Buckets(ctx, tx)
 }
@@ -237,6 +330,38 @@ func (tx *MainDatabaseTx) CharLengthExpr() string {
 	return "CHAR_LENGTH"
 }
 
+// GlobExpr translates the pattern into a regular expression since MySQL has no
+// GLOB operator. The match is case-sensitive and '.' matches line terminators
+// to match the semantics of a glob pattern.
+func (tx *MainDatabaseTx) GlobExpr(col string, pattern []api.GlobPart) (string, any) {
+	var sb strings.Builder
+	sb.WriteByte('^')
+	for _, part := range pattern {
+		switch {
+		case part.Literal != 0:
+			sb.WriteString(regexp.QuoteMeta(string(part.Literal)))
+		case part.Wildcard == '*':
+			sb.WriteString(".*")
+		case part.Wildcard == '?':
+			sb.WriteByte('.')
+		default:
+			sb.WriteByte('[')
+			if part.Negate {
+				sb.WriteByte('^')
+			}
+			for _, r := range part.Class {
+				if strings.ContainsRune(`\[]^&`, r) {
+					sb.WriteByte('\\')
+				}
+				sb.WriteRune(r)
+			}
+			sb.WriteByte(']')
+		}
+	}
+	sb.WriteByte('$')
+	return fmt.Sprintf("REGEXP_LIKE(%s, ?, 'cn')", col), sb.String()
+}
+
 func (tx *MainDatabaseTx) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []api.MultipartCompletedPart, opts api.CompleteMultipartOptions) (string, error) {
 	mpu, neededParts, size, eTag, err := ssql.MultipartUploadForCompletion(ctx, tx, bucket, key, uploadID, parts)
 	if err != nil {
@@ -244,7 +369,7 @@ func (tx *MainDatabaseTx) CompleteMultipartUpload(ctx context.Context, bucket, k
 	}
 
 	// create the object
-	objID, err := ssql.InsertObject(ctx, tx, key, mpu.BucketID, size, mpu.EC, mpu.MimeType, eTag)
+	objID, err := ssql.InsertObject(ctx, tx, key, mpu.BucketID, size, mpu.EC, mpu.MimeType, eTag, "", "")
 	if err != nil {
 		return "", fmt.Errorf("failed to insert object: %w", err)
 	}
@@ -285,6 +410,11 @@ func (tx *MainDatabaseTx) CompleteMultipartUpload(ctx context.Context, bucket, k
 	if err != nil {
 		return "", fmt.Errorf("failed to update object metadata: %w", err)
 	}
+	_, err = tx.Exec(ctx, "UPDATE object_pinned_hosts SET db_multipart_upload_id = NULL, db_object_id = ? WHERE db_multipart_upload_id = ?",
+		objID, mpu.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to update pinned hosts: %w", err)
+	}
 
 	// delete the multipart upload
 	if _, err := tx.Exec(ctx, "DELETE FROM multipart_uploads WHERE id = ?", mpu.ID); err != nil {
@@ -310,12 +440,16 @@ func (tx *MainDatabaseTx) ContractSize(ctx context.Context, id types.FileContrac
 	return ssql.ContractSize(ctx, tx, id)
 }
 
+func (tx *MainDatabaseTx) ContractOffboarding(ctx context.Context, fcid types.FileContractID) (api.ContractOffboarding, error) {
+	return ssql.ContractOffboarding(ctx, tx, fcid)
+}
+
 func (tx *MainDatabaseTx) ContractSizes(ctx context.Context) (map[types.FileContractID]api.ContractSize, error) {
 	return ssql.ContractSizes(ctx, tx)
 }
 
-func (tx *MainDatabaseTx) CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey, mimeType string, metadata api.ObjectUserMetadata) (api.ObjectMetadata, error) {
-	return ssql.CopyObject(ctx, tx, srcBucket, dstBucket, srcKey, dstKey, mimeType, metadata)
+func (tx *MainDatabaseTx) CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey, mimeType, contentDisposition string, metadata api.ObjectUserMetadata) (api.ObjectMetadata, error) {
+	return ssql.CopyObject(ctx, tx, srcBucket, dstBucket, srcKey, dstKey, mimeType, contentDisposition, metadata)
 }
 
 func (tx *MainDatabaseTx) CreateBucket(ctx context.Context, bucket string, bp api.BucketPolicy) error {
@@ -323,8 +457,8 @@ func (tx *MainDatabaseTx) CreateBucket(ctx context.Context, bucket string, bp ap
 	if err != nil {
 		return err
 	}
-	res, err := tx.Exec(ctx, "INSERT INTO buckets (created_at, name, policy) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE id = id",
-		time.Now(), bucket, policy)
+	res, err := tx.Exec(ctx, "INSERT INTO buckets (created_at, name, policy, case_insensitive) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = id",
+		time.Now(), bucket, policy, bp.CaseInsensitive)
 	if err != nil {
 		return fmt.Errorf("failed to create bucket: %w", err)
 	} else if n, err := res.RowsAffected(); err != nil {
@@ -347,6 +481,10 @@ func (tx *MainDatabaseTx) DeleteWebhook(ctx context.Context, wh webhooks.Webhook
 	return ssql.DeleteWebhook(ctx, tx, wh)
 }
 
+func (tx *MainDatabaseTx) FinishContractOffboardings(ctx context.Context) (int64, error) {
+	return ssql.FinishContractOffboardings(ctx, tx)
+}
+
 func (tx *MainDatabaseTx) FileContractElement(ctx context.Context, fcid types.FileContractID) (types.V2FileContractElement, error) {
 	return ssql.FileContractElement(ctx, tx, fcid)
 }
@@ -356,46 +494,49 @@ func (tx *MainDatabaseTx) DeleteBucket(ctx context.Context, bucket string) error
 }
 
 func (tx *MainDatabaseTx) DeleteObject(ctx context.Context, bucket string, key string) (bool, error) {
-	// check if the object exists first to avoid unnecessary locking for the
-	// common case
-	var objID uint
-	err := tx.QueryRow(ctx, "SELECT id FROM objects WHERE object_id = ? AND db_bucket_id = (SELECT id FROM buckets WHERE buckets.name = ?)", key, bucket).Scan(&objID)
+	// the shared implementation checks if the object exists first which
+	// avoids unnecessary locking for the common case
+	deleted, _, err := ssql.DeleteObjectWithSize(ctx, tx, bucket, key)
+	return deleted, err
+}
+
+func (tx *MainDatabaseTx) DeleteObjectWithSize(ctx context.Context, bucket string, key string) (bool, int64, error) {
+	return ssql.DeleteObjectWithSize(ctx, tx, bucket, key)
+}
+
+func (tx *MainDatabaseTx) DeleteObjects(ctx context.Context, bucket string, key string, limit int64) (int64, error) {
+	var bucketID int64
+	err := tx.QueryRow(ctx, "SELECT id FROM buckets WHERE buckets.name = ?", bucket).Scan(&bucketID)
 	if errors.Is(err, dsql.ErrNoRows) {
-		return false, nil
+		return 0, nil
 	} else if err != nil {
-		return false, err
+		return 0, fmt.Errorf("failed to fetch bucket id: %w", err)
 	}
 
-	resp, err := tx.Exec(ctx, "DELETE FROM objects WHERE id = ?", objID)
-	if err != nil {
-		return false, err
-	} else if n, err := resp.RowsAffected(); err != nil {
-		return false, err
-	} else {
-		return n != 0, nil
+	prefixExpr, prefixArgs := ssql.ObjectIDPrefixExpr("object_id", key)
+	limitedIDs := fmt.Sprintf(`
+		SELECT id
+		FROM objects
+		WHERE %s AND db_bucket_id = ?
+		ORDER BY id
+		LIMIT ?`, prefixExpr)
+	args := append(prefixArgs, bucketID, limit)
+
+	// the objects are subtracted from the bucket's size before they are
+	// deleted, both statements select the same objects since they are ordered
+	if err := ssql.SubtractObjectsFromBucketSize(ctx, tx, bucketID, fmt.Sprintf("o.id IN (SELECT id FROM (%s) AS limited)", limitedIDs), args...); err != nil {
+		return 0, err
 	}
-}
-
-func (tx *MainDatabaseTx) DeleteObjects(ctx context.Context, bucket string, key string, limit int64) (bool, error) {
-	resp, err := tx.Exec(ctx, `
+	resp, err := tx.Exec(ctx, fmt.Sprintf(`
 	DELETE o
 	FROM objects o
-	JOIN (
-		SELECT id
-		FROM objects
-		WHERE object_id LIKE ? AND db_bucket_id = (
-		    SELECT id FROM buckets WHERE buckets.name = ?
-		)
-		LIMIT ?
-	) AS limited ON o.id = limited.id`,
-		key+"%", bucket, limit)
+	JOIN (%s) AS limited ON o.id = limited.id`, limitedIDs), args...)
 	if err != nil {
-		return false, err
-	} else if n, err := resp.RowsAffected(); err != nil {
-		return false, err
-	} else {
-		return n != 0, nil
+		return 0, err
 	}
+	// NOTE: rows deleted through cascading foreign keys aren't counted, so
+	// this is the number of deleted objects
+	return resp.RowsAffected()
 }
 
 func (tx *MainDatabaseTx) HostAllowlist(ctx context.Context) ([]types.PublicKey, error) {
@@ -406,6 +547,10 @@ func (tx *MainDatabaseTx) HostBlocklist(ctx context.Context) ([]string, error) {
 	return ssql.HostBlocklist(ctx, tx)
 }
 
+func (tx *MainDatabaseTx) HostVerificationStats(ctx context.Context) ([]api.HostVerificationStats, error) {
+	return ssql.HostVerificationStats(ctx, tx)
+}
+
 func (tx *MainDatabaseTx) Hosts(ctx context.Context, opts api.HostOptions) ([]api.Host, error) {
 	return ssql.Hosts(ctx, tx, opts)
 }
@@ -424,8 +569,10 @@ INSERT IGNORE INTO autopilot_config (
 	contracts_prune,
 	hosts_max_consecutive_scan_failures,
 	hosts_max_downtime_hours,
-	hosts_min_protocol_version
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+	hosts_min_protocol_version,
+	hosts_max_announcement_age_hours,
+	hosts_recent_failure_cooldown_hours
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
 		sql.AutopilotID,
 		time.Now(),
 		api.DefaultAutopilotConfig.Contracts.Amount,
@@ -438,19 +585,29 @@ INSERT IGNORE INTO autopilot_config (
 		api.DefaultAutopilotConfig.Hosts.MaxConsecutiveScanFailures,
 		api.DefaultAutopilotConfig.Hosts.MaxDowntimeHours,
 		api.DefaultAutopilotConfig.Hosts.MinProtocolVersion,
+		api.DefaultAutopilotConfig.Hosts.MaxAnnouncementAgeHours,
+		api.DefaultAutopilotConfig.Hosts.RecentFailureCooldownHours,
 	)
 	return err
 }
 
-func (tx *MainDatabaseTx) InsertBufferedSlab(ctx context.Context, fileName string, ec object.EncryptionKey, minShards, totalShards uint8) (int64, error) {
-	return ssql.InsertBufferedSlab(ctx, tx, fileName, ec, minShards, totalShards)
+func (tx *MainDatabaseTx) IdempotencyKey(ctx context.Context, key string, after time.Time) (types.Hash256, string, bool, error) {
+	return ssql.IdempotencyKey(ctx, tx, key, after)
+}
+
+func (tx *MainDatabaseTx) InsertBufferedSlab(ctx context.Context, fileName, compression string, ec object.EncryptionKey, minShards, totalShards uint8) (int64, error) {
+	return ssql.InsertBufferedSlab(ctx, tx, fileName, compression, ec, minShards, totalShards)
 }
 
-func (tx *MainDatabaseTx) InsertMultipartUpload(ctx context.Context, bucket, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata) (string, error) {
-	return ssql.InsertMultipartUpload(ctx, tx, bucket, key, ec, mimeType, metadata)
+func (tx *MainDatabaseTx) InsertIdempotencyKey(ctx context.Context, key string, requestHash types.Hash256, eTag string) error {
+	return ssql.InsertIdempotencyKey(ctx, tx, key, requestHash, eTag)
 }
 
-func (tx *MainDatabaseTx) InsertObject(ctx context.Context, bucket, key string, o object.Object, mimeType, eTag string, md api.ObjectUserMetadata) error {
+func (tx *MainDatabaseTx) InsertMultipartUpload(ctx context.Context, bucket, key string, ec object.EncryptionKey, mimeType string, metadata api.ObjectUserMetadata, pinnedHosts []types.PublicKey) (string, error) {
+	return ssql.InsertMultipartUpload(ctx, tx, bucket, key, ec, mimeType, metadata, pinnedHosts)
+}
+
+func (tx *MainDatabaseTx) InsertObject(ctx context.Context, bucket, key string, o object.Object, mimeType, eTag, checksum, contentDisposition string, md api.ObjectUserMetadata, pinnedHosts []types.PublicKey) error {
 	// get bucket id
 	var bucketID int64
 	err := tx.QueryRow(ctx, "SELECT id FROM buckets WHERE buckets.name = ?", bucket).Scan(&bucketID)
@@ -461,20 +618,25 @@ func (tx *MainDatabaseTx) InsertObject(ctx context.Context, bucket, key string,
 	}
 
 	// insert object
-	objID, err := ssql.InsertObject(ctx, tx, key, bucketID, o.TotalSize(), o.Key, mimeType, eTag)
+	objID, err := ssql.InsertObject(ctx, tx, key, bucketID, o.TotalSize(), o.Key, mimeType, eTag, checksum, contentDisposition)
 	if err != nil {
 		return fmt.Errorf("failed to insert object: %w", err)
 	}
 
 	// insert slabs
-	if err := tx.insertSlabs(ctx, &objID, nil, o.Slabs); err != nil {
+	if err := tx.insertSlabs(ctx, &objID, nil, 0, o.Slabs); err != nil {
 		return fmt.Errorf("failed to insert slabs: %w", err)
 	}
 
 	// insert metadata
-	if err := ssql.InsertMetadata(ctx, tx, &objID, nil, md); err != nil {
+	if err := ssql.InsertObjectMetadata(ctx, tx, objID, md); err != nil {
 		return fmt.Errorf("failed to insert object metadata: %w", err)
 	}
+
+	// insert pinned hosts
+	if err := ssql.InsertPinnedHosts(ctx, tx, &objID, nil, pinnedHosts); err != nil {
+		return fmt.Errorf("failed to insert pinned hosts: %w", err)
+	}
 	return nil
 }
 
@@ -609,10 +771,18 @@ func (tx *MainDatabaseTx) MarkPackedSlabUploaded(ctx context.Context, slab api.U
 	return ssql.MarkPackedSlabUploaded(ctx, tx, slab)
 }
 
+func (tx *MainDatabaseTx) MoveObject(ctx context.Context, srcBucket, dstBucket, key string) error {
+	return ssql.MoveObject(ctx, tx, srcBucket, dstBucket, key)
+}
+
 func (tx *MainDatabaseTx) MultipartUpload(ctx context.Context, uploadID string) (api.MultipartUpload, error) {
 	return ssql.MultipartUpload(ctx, tx, uploadID)
 }
 
+func (tx *MainDatabaseTx) MultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber int) ([]api.MultipartPartProgressSlice, error) {
+	return ssql.MultipartPartProgress(ctx, tx, bucket, key, uploadID, partNumber)
+}
+
 func (tx *MainDatabaseTx) MultipartUploadParts(ctx context.Context, bucket, key, uploadID string, marker int, limit int64) (api.MultipartListPartsResponse, error) {
 	return ssql.MultipartUploadParts(ctx, tx, bucket, key, uploadID, marker, limit)
 }
@@ -625,18 +795,42 @@ func (tx *MainDatabaseTx) Object(ctx context.Context, bucket, key string) (api.O
 	return ssql.Object(ctx, tx, bucket, key)
 }
 
-func (tx *MainDatabaseTx) Objects(ctx context.Context, bucket, prefix, substring, delim, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey) (api.ObjectsResponse, error) {
-	return ssql.Objects(ctx, tx, bucket, prefix, substring, delim, sortBy, sortDir, marker, limit, slabEncryptionKey)
+func (tx *MainDatabaseTx) Objects(ctx context.Context, bucket, prefix, substring, glob, delim, sortBy, sortDir, marker string, limit int, slabEncryptionKey object.EncryptionKey, minHealth, maxHealth *float64) (api.ObjectsResponse, error) {
+	return ssql.Objects(ctx, tx, bucket, prefix, substring, glob, delim, sortBy, sortDir, marker, limit, slabEncryptionKey, minHealth, maxHealth)
+}
+
+func (tx *MainDatabaseTx) ObjectManifest(ctx context.Context, bucket, marker string, limit int) ([]api.ObjectManifestEntry, error) {
+	return ssql.ObjectManifest(ctx, tx, bucket, marker, limit)
 }
 
 func (tx *MainDatabaseTx) ObjectMetadata(ctx context.Context, bucket, key string) (api.Object, error) {
 	return ssql.ObjectMetadata(ctx, tx, bucket, key)
 }
 
+func (tx *MainDatabaseTx) ObjectEvents(ctx context.Context, marker uint64, before time.Time, limit int) ([]api.ObjectEvent, error) {
+	return ssql.ObjectEvents(ctx, tx, marker, before, limit)
+}
+
+func (tx *MainDatabaseTx) ObjectsMissingChecksum(ctx context.Context, marker uint64, limit int) ([]api.ObjectMissingChecksum, error) {
+	return ssql.ObjectsMissingChecksum(ctx, tx, marker, limit)
+}
+
+func (tx *MainDatabaseTx) ObjectsNoSlabs(ctx context.Context, bucket, marker string, limit int, missingDataOnly bool) (api.ObjectsNoSlabsResponse, error) {
+	return ssql.ObjectsNoSlabs(ctx, tx, bucket, marker, limit, missingDataOnly)
+}
+
 func (tx *MainDatabaseTx) ObjectsStats(ctx context.Context, opts api.ObjectsStatsOpts) (api.ObjectsStatsResponse, error) {
 	return ssql.ObjectsStats(ctx, tx, opts)
 }
 
+func (tx *MainDatabaseTx) ObjectTombstonesExist(ctx context.Context) (bool, error) {
+	return ssql.ObjectTombstonesExist(ctx, tx)
+}
+
+func (tx *MainDatabaseTx) OffboardContract(ctx context.Context, fcid types.FileContractID) (int64, error) {
+	return ssql.OffboardContract(ctx, tx, fcid)
+}
+
 func (tx *MainDatabaseTx) PeerBanned(ctx context.Context, addr string) (bool, error) {
 	return ssql.PeerBanned(ctx, tx, addr)
 }
@@ -658,6 +852,14 @@ func (tx *MainDatabaseTx) ProcessChainUpdate(ctx context.Context, fn func(ssql.C
 	})
 }
 
+func (tx *MainDatabaseTx) ObjectPinned(ctx context.Context, bucket, key string) (bool, error) {
+	return ssql.ObjectPinned(ctx, tx, bucket, key)
+}
+
+func (tx *MainDatabaseTx) PinObject(ctx context.Context, bucket, key string, pinned bool) error {
+	return ssql.PinObject(ctx, tx, bucket, key, pinned)
+}
+
 func (tx *MainDatabaseTx) PrunableContractRoots(ctx context.Context, fcid types.FileContractID, roots []types.Hash256) (indices []uint64, err error) {
 	// build tmp table name
 	tmpTable := strings.ReplaceAll(fmt.Sprintf("tmp_host_roots_%s", fcid.String()[:8]), ":", "_")
@@ -726,8 +928,55 @@ WHERE db_host_id NOT IN (
 	return res.RowsAffected()
 }
 
-func (tx *MainDatabaseTx) PruneSlabs(ctx context.Context, limit int64) (int64, error) {
-	return ssql.PruneSlabs(ctx, tx, limit)
+func (tx *MainDatabaseTx) PruneObjectEvents(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	res, err := tx.Exec(ctx, "DELETE FROM object_events WHERE created_at < ? ORDER BY id LIMIT ?", before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune object events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (tx *MainDatabaseTx) PruneIdempotencyKeys(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	res, err := tx.Exec(ctx, "DELETE FROM object_idempotency_keys WHERE created_at < ? ORDER BY id LIMIT ?", before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (tx *MainDatabaseTx) PruneObjectTombstones(ctx context.Context, limit int64) (int64, error) {
+	// delete the slices of tombstoned objects first
+	res, err := tx.Exec(ctx, `
+	DELETE FROM slices
+	WHERE db_object_id IN (SELECT id FROM objects WHERE object_id IS NULL)
+	LIMIT ?`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete slices: %w", err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	} else if deleted >= limit {
+		return deleted, nil
+	}
+
+	// then delete the tombstones that have no slices left
+	res, err = tx.Exec(ctx, `
+	DELETE FROM objects
+	WHERE object_id IS NULL AND NOT EXISTS (SELECT 1 FROM slices sli WHERE sli.db_object_id = objects.id)
+	LIMIT ?`, limit-deleted)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete objects: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return deleted + n, nil
+}
+
+func (tx *MainDatabaseTx) PruneSlabs(ctx context.Context, minID, maxID, limit int64) (int64, error) {
+	return ssql.PruneSlabs(ctx, tx, minID, maxID, limit)
 }
 
 func (tx *MainDatabaseTx) PutContract(ctx context.Context, c api.ContractMetadata) error {
@@ -785,11 +1034,98 @@ func (tx *MainDatabaseTx) RecordHostScans(ctx context.Context, scans []api.HostS
 	return ssql.RecordHostScans(ctx, tx, scans)
 }
 
+func (tx *MainDatabaseTx) RecordHostVerifications(ctx context.Context, verifications []api.HostVerification) error {
+	if len(verifications) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(ctx, `
+		INSERT INTO host_sector_verifications (created_at, db_host_id, successes, failures, last_error, last_verified)
+		SELECT ?, h.id, ?, ?, ?, ?
+		FROM hosts h
+		WHERE h.public_key = ?
+		ON DUPLICATE KEY UPDATE
+			successes = successes + VALUES(successes),
+			failures = failures + VALUES(failures),
+			last_error = CASE WHEN VALUES(failures) > 0 THEN VALUES(last_error) ELSE last_error END,
+			last_verified = VALUES(last_verified)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement to record host verification: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, v := range verifications {
+		var successes, failures uint64
+		if v.Error == "" {
+			successes++
+		} else {
+			failures++
+		}
+		if _, err := stmt.Exec(ctx, time.Now(), successes, failures, v.Error, ssql.UnixTimeMS(v.Timestamp), ssql.PublicKey(v.HostKey)); err != nil {
+			return fmt.Errorf("failed to record host verification: %w", err)
+		}
+	}
+	return nil
+}
+
+func (tx *MainDatabaseTx) RecordObjectEvents(ctx context.Context, events []api.ObjectEvent) error {
+	return ssql.RecordObjectEvents(ctx, tx, events)
+}
+
+func (tx *MainDatabaseTx) RecordRenameObjectsEvents(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) error {
+	// the keys the objects with the old prefix are renamed to
+	srcExpr, srcArgs := ssql.ObjectIDPrefixExpr("src.object_id", prefixOld)
+	targetsQuery := fmt.Sprintf(`
+		SELECT CONCAT(?, SUBSTR(src.object_id, ?))
+		FROM objects src
+		WHERE src.db_bucket_id = b.id AND %s`, srcExpr)
+	targetsArgs := append([]any{prefixNew, utf8.RuneCountInString(prefixOld) + 1}, srcArgs...)
+
+	now := time.Now()
+	if force {
+		// objects at the target keys are deleted by the rename
+		query := fmt.Sprintf(`
+		INSERT INTO object_events (created_at, operation, bucket, object_id, renamed_to, etag, size)
+		SELECT ?, ?, b.name, o.object_id, '', COALESCE(o.etag, ''), o.size
+		FROM objects o
+		INNER JOIN buckets b ON b.id = o.db_bucket_id
+		WHERE b.name = ? AND o.object_id IN (%s)
+		ORDER BY o.object_id ASC`, targetsQuery)
+		args := append([]any{now, api.ObjectEventDelete, bucket}, targetsArgs...)
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to record delete events: %w", err)
+		}
+	}
+
+	// objects that are overwritten themselves aren't renamed
+	prefixExpr, prefixArgs := ssql.ObjectIDPrefixExpr("o.object_id", prefixOld)
+	query := fmt.Sprintf(`
+		INSERT INTO object_events (created_at, operation, bucket, object_id, renamed_to, etag, size)
+		SELECT ?, ?, b.name, o.object_id, CONCAT(?, SUBSTR(o.object_id, ?)), COALESCE(o.etag, ''), o.size
+		FROM objects o
+		INNER JOIN buckets b ON b.id = o.db_bucket_id
+		WHERE b.name = ? AND %s`, prefixExpr)
+	args := append([]any{now, api.ObjectEventRename, prefixNew, utf8.RuneCountInString(prefixOld) + 1, bucket}, prefixArgs...)
+	if force {
+		query += fmt.Sprintf(" AND o.object_id NOT IN (%s)", targetsQuery)
+		args = append(args, targetsArgs...)
+	}
+	query += " ORDER BY o.object_id ASC"
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record rename events: %w", err)
+	}
+	return nil
+}
+
+func (tx *MainDatabaseTx) RelinkContractSectors(ctx context.Context, from, to types.FileContractID, limit int64) (int64, error) {
+	return ssql.RelinkContractSectors(ctx, tx, from, to, limit)
+}
+
 func (tx *MainDatabaseTx) RemoveOfflineHosts(ctx context.Context, minRecentFailures uint64, maxDownTime time.Duration) (int64, error) {
 	return ssql.RemoveOfflineHosts(ctx, tx, minRecentFailures, maxDownTime)
 }
 
-func (tx *MainDatabaseTx) RenameObject(ctx context.Context, bucket, keyOld, keyNew string, force bool) error {
+func (tx *MainDatabaseTx) RenameObject(ctx context.Context, bucket, keyOld, keyNew string, force, allowDirCollision bool) error {
 	if force {
 		// delete potentially existing object at destination
 		if _, err := tx.DeleteObject(ctx, bucket, keyNew); err != nil {
@@ -803,7 +1139,14 @@ func (tx *MainDatabaseTx) RenameObject(ctx context.Context, bucket, keyOld, keyN
 			return api.ErrObjectExists
 		}
 	}
-	resp, err := tx.Exec(ctx, `UPDATE objects SET object_id = ? WHERE object_id = ? AND db_bucket_id = (SELECT id FROM buckets WHERE buckets.name = ?)`, keyNew, keyOld, bucket)
+	if !allowDirCollision {
+		if isDir, err := ssql.ObjectKeyIsDirectory(ctx, tx, bucket, keyNew, keyOld); err != nil {
+			return err
+		} else if isDir {
+			return fmt.Errorf("%w: key %v", api.ErrObjectKeyIsDirectory, keyNew)
+		}
+	}
+	resp, err := tx.Exec(ctx, `UPDATE objects SET object_id = ?, object_id_lower = ? WHERE object_id = ? AND db_bucket_id = (SELECT id FROM buckets WHERE buckets.name = ?)`, keyNew, strings.ToLower(keyNew), keyOld, bucket)
 	if err != nil {
 		return err
 	} else if n, err := resp.RowsAffected(); err != nil {
@@ -811,34 +1154,40 @@ func (tx *MainDatabaseTx) RenameObject(ctx context.Context, bucket, keyOld, keyN
 	} else if n == 0 {
 		return fmt.Errorf("%w: key %v", api.ErrObjectNotFound, keyOld)
 	}
-	return nil
+	return ssql.CheckObjectKeyCaseConflict(ctx, tx, "b.name = ? AND o.object_id = ?", bucket, keyNew)
 }
 
-func (tx *MainDatabaseTx) RenameObjects(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) error {
+func (tx *MainDatabaseTx) RenameObjects(ctx context.Context, bucket, prefixOld, prefixNew string, force bool) (res api.ObjectsRenameResponse, _ error) {
+	prefixExpr, prefixArgs := ssql.ObjectIDPrefixExpr("object_id", prefixOld)
 	if force {
+		var bucketID int64
+		err := tx.QueryRow(ctx, "SELECT id FROM buckets WHERE buckets.name = ?", bucket).Scan(&bucketID)
+		if errors.Is(err, dsql.ErrNoRows) {
+			return api.ObjectsRenameResponse{}, fmt.Errorf("%w: prefix %v", api.ErrObjectNotFound, prefixOld)
+		} else if err != nil {
+			return api.ObjectsRenameResponse{}, fmt.Errorf("failed to fetch bucket id: %w", err)
+		}
+
 		// to avoid a conflict on update, we delete objects that would conflict
 		// with objects being renamed, within the scope of the bucket of course
-		query := `
-		DELETE
-		FROM objects
-		WHERE
-			db_bucket_id = (SELECT id FROM buckets WHERE buckets.name = ?) AND
-			object_id IN (
-				SELECT *
-				FROM (
-					SELECT CONCAT(?, SUBSTR(object_id, ?))
-					FROM objects
-					WHERE object_id LIKE ? AND SUBSTR(object_id, 1, ?) = ?
-				) as i
-			)`
-		args := []any{
-			bucket,
-			prefixNew, utf8.RuneCountInString(prefixOld) + 1,
-			prefixOld + "%", utf8.RuneCountInString(prefixOld), prefixOld,
+		targets := fmt.Sprintf(`
+			SELECT *
+			FROM (
+				SELECT CONCAT(?, SUBSTR(object_id, ?))
+				FROM objects
+				WHERE %s
+			) AS i`, prefixExpr)
+		args := append([]any{prefixNew, utf8.RuneCountInString(prefixOld) + 1}, prefixArgs...)
+		if err := ssql.SubtractObjectsFromBucketSize(ctx, tx, bucketID, fmt.Sprintf("o.object_id IN (%s)", targets), args...); err != nil {
+			return api.ObjectsRenameResponse{}, err
 		}
-		_, err := tx.Exec(ctx, query, args...)
+		resp, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM objects WHERE db_bucket_id = ? AND object_id IN (%s)", targets), append([]any{bucketID}, args...)...)
 		if err != nil {
-			return err
+			return api.ObjectsRenameResponse{}, err
+		} else if n, err := resp.RowsAffected(); err != nil {
+			return api.ObjectsRenameResponse{}, err
+		} else {
+			res.Overwritten = uint64(n)
 		}
 	}
 
@@ -846,29 +1195,38 @@ func (tx *MainDatabaseTx) RenameObjects(ctx context.Context, bucket, prefixOld,
 	// the old prefix (case sensitive) and it doesn't exactly match the new
 	// prefix, we update the object_id at all times but only update directory_id
 	// only when the object is an immediate child (no slash in suffix)
-	query := `
+	query := fmt.Sprintf(`
 		UPDATE objects
-		SET object_id = CONCAT(?, SUBSTR(object_id, ?))
+		SET object_id = CONCAT(?, SUBSTR(object_id, ?)), object_id_lower = CONCAT(?, SUBSTR(object_id_lower, ?))
 		WHERE
 			db_bucket_id = (SELECT id FROM buckets WHERE buckets.name = ?) AND
-			object_id LIKE ? AND SUBSTR(object_id, 1, ?) = ?`
+			%s`, prefixExpr)
 
-	args := []any{
+	args := append([]any{
 		prefixNew, utf8.RuneCountInString(prefixOld) + 1,
+		strings.ToLower(prefixNew), utf8.RuneCountInString(strings.ToLower(prefixOld)) + 1,
 		bucket,
-		prefixOld + "%", utf8.RuneCountInString(prefixOld), prefixOld,
-	}
+	}, prefixArgs...)
 	resp, err := tx.Exec(ctx, query, args...)
 	if err != nil && strings.Contains(err.Error(), "Duplicate entry") {
-		return api.ErrObjectExists
+		return api.ObjectsRenameResponse{}, api.ErrObjectExists
 	} else if err != nil {
-		return err
+		return api.ObjectsRenameResponse{}, err
 	} else if n, err := resp.RowsAffected(); err != nil {
-		return err
+		return api.ObjectsRenameResponse{}, err
 	} else if n == 0 {
-		return fmt.Errorf("%w: prefix %v", api.ErrObjectNotFound, prefixOld)
+		return api.ObjectsRenameResponse{}, fmt.Errorf("%w: prefix %v", api.ErrObjectNotFound, prefixOld)
+	} else {
+		res.Renamed = uint64(n)
 	}
-	return nil
+
+	// renamed objects in case-insensitive buckets can't differ only in case
+	// from the bucket's other objects
+	newPrefixExpr, newPrefixArgs := ssql.ObjectIDPrefixExpr("o.object_id", prefixNew)
+	if err := ssql.CheckObjectKeyCaseConflict(ctx, tx, "b.name = ? AND "+newPrefixExpr, append([]any{bucket}, newPrefixArgs...)...); err != nil {
+		return api.ObjectsRenameResponse{}, err
+	}
+	return res, nil
 }
 
 func (tx *MainDatabaseTx) RenewedContract(ctx context.Context, renewedFrom types.FileContractID) (api.ContractMetadata, error) {
@@ -883,6 +1241,10 @@ func (tx *MainDatabaseTx) ResetLostSectors(ctx context.Context, hk types.PublicK
 	return ssql.ResetLostSectors(ctx, tx, hk)
 }
 
+func (tx *MainDatabaseTx) ResolveObjectKey(ctx context.Context, bucket, key string) (string, error) {
+	return ssql.ResolveObjectKey(ctx, tx, bucket, key)
+}
+
 func (tx MainDatabaseTx) SaveAccounts(ctx context.Context, accounts []api.Account) error {
 	// clean_shutdown = 1 after save
 	stmt, err := tx.Prepare(ctx, `
@@ -912,8 +1274,12 @@ func (tx MainDatabaseTx) SaveAccounts(ctx context.Context, accounts []api.Accoun
 	return nil
 }
 
+func (tx *MainDatabaseTx) SampleSectors(ctx context.Context, n int) ([]api.SectorSample, error) {
+	return ssql.SampleSectors(ctx, tx, n)
+}
+
 func (tx *MainDatabaseTx) ScanObjectMetadata(s ssql.Scanner, others ...any) (md api.ObjectMetadata, err error) {
-	dst := []any{&md.Key, &md.Size, &md.Health, &md.MimeType, &md.ModTime, &md.ETag, &md.Bucket}
+	dst := []any{&md.Key, &md.Size, &md.Health, &md.MimeType, &md.ModTime, &md.ETag, &md.Checksum, &md.ContentDisposition, &md.Bucket}
 	dst = append(dst, others...)
 	if err := s.Scan(dst...); err != nil {
 		return api.ObjectMetadata{}, fmt.Errorf("failed to scan object metadata: %w", err)
@@ -922,25 +1288,45 @@ func (tx *MainDatabaseTx) ScanObjectMetadata(s ssql.Scanner, others ...any) (md
 }
 
 func (tx *MainDatabaseTx) SelectObjectMetadataExpr() string {
-	return "o.object_id, o.size, o.health, o.mime_type, o.created_at, o.etag, b.name"
+	return "o.object_id, o.size, o.health, o.mime_type, o.created_at, o.etag, o.checksum, o.content_disposition, b.name"
 }
 
 func (tx *MainDatabaseTx) Setting(ctx context.Context, key string) (string, error) {
 	return ssql.Setting(ctx, tx, key)
 }
 
+func (tx *MainDatabaseTx) SharedSlabs(ctx context.Context, minObjects uint64, limit int) ([]api.SharedSlab, error) {
+	return ssql.SharedSlabs(ctx, tx, minObjects, limit)
+}
+
 func (tx *MainDatabaseTx) Slab(ctx context.Context, key object.EncryptionKey) (object.Slab, error) {
 	return ssql.Slab(ctx, tx, key)
 }
 
+func (tx *MainDatabaseTx) SlabIDRange(ctx context.Context) (minID, maxID int64, err error) {
+	return ssql.SlabIDRange(ctx, tx)
+}
+
 func (tx *MainDatabaseTx) SlabsForMigration(ctx context.Context, healthCutoff float64, limit int) ([]api.UnhealthySlab, error) {
 	return ssql.SlabsForMigration(ctx, tx, healthCutoff, limit)
 }
 
+func (tx *MainDatabaseTx) SlabsStats(ctx context.Context) (api.SlabsStatsResponse, error) {
+	return ssql.SlabsStats(ctx, tx)
+}
+
 func (tx *MainDatabaseTx) Tip(ctx context.Context) (types.ChainIndex, error) {
 	return ssql.Tip(ctx, tx.Tx)
 }
 
+func (tx *MainDatabaseTx) TombstoneExpiredObjects(ctx context.Context, now time.Time, limit int64) ([]api.ObjectMetadata, error) {
+	return ssql.TombstoneExpiredObjects(ctx, tx, now, limit)
+}
+
+func (tx *MainDatabaseTx) TombstoneObject(ctx context.Context, bucket, key string) (bool, error) {
+	return ssql.TombstoneObject(ctx, tx, bucket, key)
+}
+
 func (tx *MainDatabaseTx) UnspentSiacoinElements(ctx context.Context) (elements []types.SiacoinElement, err error) {
 	return ssql.UnspentSiacoinElements(ctx, tx.Tx)
 }
@@ -949,6 +1335,10 @@ func (tx *MainDatabaseTx) UpdateAutopilotConfig(ctx context.Context, cfg api.Aut
 	return ssql.UpdateAutopilotConfig(ctx, tx, cfg)
 }
 
+func (tx *MainDatabaseTx) UpdateBucketLifecycleRules(ctx context.Context, bucket string, rules []api.BucketLifecycleRule) error {
+	return ssql.UpdateBucketLifecycleRules(ctx, tx, bucket, rules)
+}
+
 func (tx *MainDatabaseTx) UpdateBucketPolicy(ctx context.Context, bucket string, bp api.BucketPolicy) error {
 	return ssql.UpdateBucketPolicy(ctx, tx, bucket, bp)
 }
@@ -1078,23 +1468,23 @@ func (tx *MainDatabaseTx) UpdateHostBlocklistEntries(ctx context.Context, add, r
 func (tx *MainDatabaseTx) UpdateHostCheck(ctx context.Context, hk types.PublicKey, hc api.HostChecks) error {
 	_, err := tx.Exec(ctx, `
 		INSERT INTO host_checks (created_at, db_host_id, usability_blocked, usability_offline, usability_low_score,
-			usability_redundant_ip, usability_gouging, usability_low_max_duration, usability_not_accepting_contracts, usability_not_announced, usability_not_completing_scan,
+			usability_redundant_ip, usability_gouging, usability_low_max_duration, usability_not_accepting_contracts, usability_not_announced, usability_not_completing_scan, usability_stale_announcement, usability_recently_failed,
 			score_age, score_collateral, score_interactions, score_storage_remaining, score_uptime, score_version, score_prices,
 			gouging_download_err, gouging_gouging_err, gouging_prune_err, gouging_upload_err)
 	    VALUES (?,
 			(SELECT id FROM hosts WHERE public_key = ?),
-			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
 			created_at = VALUES(created_at), db_host_id = VALUES(db_host_id),
 			usability_blocked = VALUES(usability_blocked), usability_offline = VALUES(usability_offline), usability_low_score = VALUES(usability_low_score),
 			usability_redundant_ip = VALUES(usability_redundant_ip), usability_gouging = VALUES(usability_gouging), usability_low_max_duration = VALUES(usability_low_max_duration), usability_not_accepting_contracts = VALUES(usability_not_accepting_contracts),
-			usability_not_announced = VALUES(usability_not_announced), usability_not_completing_scan = VALUES(usability_not_completing_scan),
+			usability_not_announced = VALUES(usability_not_announced), usability_not_completing_scan = VALUES(usability_not_completing_scan), usability_stale_announcement = VALUES(usability_stale_announcement), usability_recently_failed = VALUES(usability_recently_failed),
 			score_age = VALUES(score_age), score_collateral = VALUES(score_collateral), score_interactions = VALUES(score_interactions),
 			score_storage_remaining = VALUES(score_storage_remaining), score_uptime = VALUES(score_uptime), score_version = VALUES(score_version),
 			score_prices = VALUES(score_prices), gouging_download_err = VALUES(gouging_download_err),
 			gouging_gouging_err = VALUES(gouging_gouging_err), gouging_prune_err = VALUES(gouging_prune_err), gouging_upload_err = VALUES(gouging_upload_err)
 	`, time.Now(), ssql.PublicKey(hk), hc.UsabilityBreakdown.Blocked, hc.UsabilityBreakdown.Offline, hc.UsabilityBreakdown.LowScore,
-		hc.UsabilityBreakdown.RedundantIP, hc.UsabilityBreakdown.Gouging, hc.UsabilityBreakdown.LowMaxDuration, hc.UsabilityBreakdown.NotAcceptingContracts, hc.UsabilityBreakdown.NotAnnounced, hc.UsabilityBreakdown.NotCompletingScan,
+		hc.UsabilityBreakdown.RedundantIP, hc.UsabilityBreakdown.Gouging, hc.UsabilityBreakdown.LowMaxDuration, hc.UsabilityBreakdown.NotAcceptingContracts, hc.UsabilityBreakdown.NotAnnounced, hc.UsabilityBreakdown.NotCompletingScan, hc.UsabilityBreakdown.StaleAnnouncement, hc.UsabilityBreakdown.RecentlyFailed,
 		hc.ScoreBreakdown.Age, hc.ScoreBreakdown.Collateral, hc.ScoreBreakdown.Interactions, hc.ScoreBreakdown.StorageRemaining, hc.ScoreBreakdown.Uptime, hc.ScoreBreakdown.Version, hc.ScoreBreakdown.Prices,
 		hc.GougingBreakdown.DownloadErr, hc.GougingBreakdown.GougingErr, hc.GougingBreakdown.PruneErr, hc.GougingBreakdown.UploadErr,
 	)
@@ -1203,7 +1593,7 @@ func (tx *MainDatabaseTx) Webhooks(ctx context.Context) ([]webhooks.Webhook, err
 	return ssql.Webhooks(ctx, tx)
 }
 
-func (tx *MainDatabaseTx) insertSlabs(ctx context.Context, objID, partID *int64, slices object.SlabSlices) error {
+func (tx *MainDatabaseTx) insertSlabs(ctx context.Context, objID, partID *int64, offset int, slices object.SlabSlices) error {
 	if (objID == nil) == (partID == nil) {
 		return errors.New("exactly one of objID and partID must be set")
 	} else if len(slices) == 0 {
@@ -1225,7 +1615,14 @@ func (tx *MainDatabaseTx) insertSlabs(ctx context.Context, objID, partID *int64,
 	}
 	defer insertSlabStmt.Close()
 
+	querySlabStmt, err := tx.Prepare(ctx, "SELECT min_shards, total_shards, (SELECT COUNT(*) FROM sectors WHERE db_slab_id = slabs.id) FROM slabs WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement to query slab: %w", err)
+	}
+	defer querySlabStmt.Close()
+
 	slabIDs := make([]int64, len(slices))
+	complete := make([]bool, len(slices))
 	for i := range slices {
 		res, err := insertSlabStmt.Exec(ctx,
 			time.Now(),
@@ -1240,6 +1637,16 @@ func (tx *MainDatabaseTx) insertSlabs(ctx context.Context, objID, partID *int64,
 		if err != nil {
 			return fmt.Errorf("failed to fetch slab id: %w", err)
 		}
+
+		// the slab might already exist, make sure it matches
+		var minShards, totalShards uint8
+		var numSectors int
+		if err := querySlabStmt.QueryRow(ctx, slabIDs[i]).Scan(&minShards, &totalShards, &numSectors); err != nil {
+			return fmt.Errorf("failed to fetch slab: %w", err)
+		} else if err := ssql.CheckSlabCollision(slices[i].Slab, minShards, totalShards); err != nil {
+			return err
+		}
+		complete[i] = ssql.SlabSectorsComplete(numSectors, totalShards)
 	}
 
 	// insert slices
@@ -1254,7 +1661,7 @@ func (tx *MainDatabaseTx) insertSlabs(ctx context.Context, objID, partID *int64,
 		res, err := insertSliceStmt.Exec(ctx,
 			time.Now(),
 			objID,
-			uint(i+1),
+			uint(offset+i+1),
 			partID,
 			slabIDs[i],
 			slices[i].Offset,
@@ -1269,9 +1676,13 @@ func (tx *MainDatabaseTx) insertSlabs(ctx context.Context, objID, partID *int64,
 		}
 	}
 
-	// insert sectors
+	// insert sectors, slabs that already exist with all of their sectors are
+	// shared with the objects that reference them and are skipped
 	var upsertSectors []upsertSector
 	for i, ss := range slices {
+		if complete[i] {
+			continue
+		}
 		for j := range ss.Shards {
 			upsertSectors = append(upsertSectors, upsertSector{
 				slabIDs[i],
@@ -1280,11 +1691,27 @@ func (tx *MainDatabaseTx) insertSlabs(ctx context.Context, objID, partID *int64,
 			})
 		}
 	}
-	sectorIDs, err := tx.upsertSectors(ctx, upsertSectors)
+	upsertedIDs, err := tx.upsertSectors(ctx, upsertSectors)
 	if err != nil {
 		return fmt.Errorf("failed to insert sectors: %w", err)
 	}
 
+	// collect the sector ids of all slabs, the ones of complete slabs are
+	// fetched since their contract links still need to be inserted
+	var sectorIDs []int64
+	for i, ss := range slices {
+		if !complete[i] {
+			sectorIDs = append(sectorIDs, upsertedIDs[:len(ss.Shards)]...)
+			upsertedIDs = upsertedIDs[len(ss.Shards):]
+			continue
+		}
+		ids, err := ssql.SlabSectorIDs(ctx, tx.Tx, slabIDs[i], ss.Shards)
+		if err != nil {
+			return fmt.Errorf("failed to fetch sectors of slab %v: %w", ss.EncryptionKey, err)
+		}
+		sectorIDs = append(sectorIDs, ids...)
+	}
+
 	// insert contract <-> sector links
 	sectorIdx := 0
 	var upsertContractSectors []ssql.ContractSector
@@ -0,0 +1 @@
+ALTER TABLE `objects` ADD COLUMN `checksum` varchar(64) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `buffered_slabs` ADD COLUMN `compression` varchar(32) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE `autopilot_config` ADD COLUMN `hosts_max_announcement_age_hours` bigint unsigned NOT NULL DEFAULT 0;
+ALTER TABLE `host_checks` ADD COLUMN `usability_stale_announcement` boolean NOT NULL DEFAULT false;
+CREATE INDEX `idx_host_checks_usability_stale_announcement` ON `host_checks` (`usability_stale_announcement`);
@@ -0,0 +1,4 @@
+ALTER TABLE `hosts` ADD COLUMN `last_failed_interaction` bigint NOT NULL DEFAULT 0;
+ALTER TABLE `autopilot_config` ADD COLUMN `hosts_recent_failure_cooldown_hours` bigint unsigned NOT NULL DEFAULT 0;
+ALTER TABLE `host_checks` ADD COLUMN `usability_recently_failed` boolean NOT NULL DEFAULT false;
+CREATE INDEX `idx_host_checks_usability_recently_failed` ON `host_checks` (`usability_recently_failed`);
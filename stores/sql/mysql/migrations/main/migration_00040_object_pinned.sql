@@ -0,0 +1 @@
+ALTER TABLE `objects` ADD COLUMN `pinned` boolean NOT NULL DEFAULT false;
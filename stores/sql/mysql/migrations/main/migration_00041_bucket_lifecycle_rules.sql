@@ -0,0 +1,10 @@
+CREATE TABLE `bucket_lifecycle_rules` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `db_bucket_id` bigint unsigned NOT NULL,
+  `prefix` varchar(766) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '',
+  `expiration_days` bigint unsigned NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_bucket_lifecycle_rules_db_bucket_id` (`db_bucket_id`),
+  CONSTRAINT `fk_bucket_lifecycle_rules_db_bucket` FOREIGN KEY (`db_bucket_id`) REFERENCES `buckets` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,10 @@
+CREATE TABLE `object_idempotency_keys` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NOT NULL,
+  `idempotency_key` varchar(255) NOT NULL,
+  `request_hash` binary(32) NOT NULL,
+  `etag` varchar(191) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_object_idempotency_keys_idempotency_key` (`idempotency_key`),
+  KEY `idx_object_idempotency_keys_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
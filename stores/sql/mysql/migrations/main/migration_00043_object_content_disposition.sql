@@ -0,0 +1 @@
+ALTER TABLE `objects` ADD COLUMN `content_disposition` varchar(255) NOT NULL DEFAULT '';
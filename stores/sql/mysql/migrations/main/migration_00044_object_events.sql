@@ -0,0 +1,12 @@
+CREATE TABLE `object_events` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NOT NULL,
+  `operation` varchar(16) NOT NULL,
+  `bucket` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+  `object_id` varchar(766) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+  `renamed_to` varchar(766) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '',
+  `etag` varchar(191) NOT NULL DEFAULT '',
+  `size` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  KEY `idx_object_events_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
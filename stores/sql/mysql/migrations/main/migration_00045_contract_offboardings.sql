@@ -0,0 +1,11 @@
+CREATE TABLE `contract_offboardings` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NOT NULL,
+  `db_contract_id` bigint unsigned NOT NULL,
+  `db_slab_id` bigint unsigned DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_contract_offboardings_db_contract_id` (`db_contract_id`),
+  KEY `idx_contract_offboardings_db_slab_id` (`db_slab_id`),
+  CONSTRAINT `fk_contract_offboardings_contract` FOREIGN KEY (`db_contract_id`) REFERENCES `contracts` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_contract_offboardings_slab` FOREIGN KEY (`db_slab_id`) REFERENCES `slabs` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE `buckets` ADD COLUMN `case_insensitive` boolean NOT NULL DEFAULT false;
+ALTER TABLE `objects` ADD COLUMN `object_id_lower` varchar(766) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin DEFAULT NULL;
+ALTER TABLE `objects` ADD INDEX `idx_objects_bucket_object_id_lower` (`db_bucket_id`,`object_id_lower`);
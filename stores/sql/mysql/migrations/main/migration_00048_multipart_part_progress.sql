@@ -0,0 +1,13 @@
+ALTER TABLE `multipart_parts` ADD COLUMN `in_progress` boolean NOT NULL DEFAULT false;
+ALTER TABLE `multipart_parts` ADD INDEX `idx_multipart_parts_in_progress` (`in_progress`);
+
+CREATE TABLE IF NOT EXISTS `multipart_part_progress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `db_multipart_part_id` bigint unsigned NOT NULL,
+  `object_index` bigint unsigned NOT NULL,
+  `checksum` binary(32) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_multipart_part_progress_part_index` (`db_multipart_part_id`,`object_index`),
+  CONSTRAINT `fk_multipart_part_progress_part` FOREIGN KEY (`db_multipart_part_id`) REFERENCES `multipart_parts` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `object_pinned_hosts` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `db_object_id` bigint unsigned DEFAULT NULL,
+  `db_multipart_upload_id` bigint unsigned DEFAULT NULL,
+  `public_key` varbinary(32) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_object_pinned_hosts_db_object_id` (`db_object_id`),
+  KEY `idx_object_pinned_hosts_db_multipart_upload_id` (`db_multipart_upload_id`),
+  CONSTRAINT `fk_object_pinned_hosts_object` FOREIGN KEY (`db_object_id`) REFERENCES `objects` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_object_pinned_hosts_multipart_upload` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `host_sector_verifications` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `db_host_id` bigint unsigned NOT NULL,
+  `successes` bigint unsigned NOT NULL DEFAULT 0,
+  `failures` bigint unsigned NOT NULL DEFAULT 0,
+  `last_error` text NOT NULL,
+  `last_verified` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_host_sector_verifications_db_host_id` (`db_host_id`),
+  CONSTRAINT `fk_host_sector_verifications_host` FOREIGN KEY (`db_host_id`) REFERENCES `hosts` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
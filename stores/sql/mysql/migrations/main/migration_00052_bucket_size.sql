@@ -0,0 +1,2 @@
+ALTER TABLE `buckets` ADD COLUMN `size` bigint NOT NULL DEFAULT 0;
+UPDATE `buckets` b SET b.`size` = (SELECT COALESCE(SUM(o.`size`), 0) FROM `objects` o WHERE o.`db_bucket_id` = b.`id` AND o.`object_id` IS NOT NULL);
@@ -4,6 +4,8 @@ CREATE TABLE `buckets` (
   `created_at` datetime(3) DEFAULT NULL,
   `policy` JSON,
   `name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin DEFAULT NULL,
+  `case_insensitive` boolean NOT NULL DEFAULT false,
+  `size` bigint NOT NULL DEFAULT 0,
   PRIMARY KEY (`id`),
   UNIQUE KEY `name` (`name`),
   KEY `idx_buckets_name` (`name`)
@@ -14,6 +16,7 @@ CREATE TABLE `buffered_slabs` (
   `id` bigint unsigned NOT NULL AUTO_INCREMENT,
   `created_at` datetime(3) DEFAULT NULL,
   `filename` longtext,
+  `compression` varchar(32) NOT NULL DEFAULT '',
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
@@ -49,6 +52,7 @@ CREATE TABLE `hosts` (
   `lost_sectors` bigint unsigned DEFAULT NULL,
   `last_announcement` datetime(3) DEFAULT NULL,
   `net_address` varchar(191) DEFAULT NULL,
+  `last_failed_interaction` bigint NOT NULL DEFAULT 0,
   PRIMARY KEY (`id`),
   UNIQUE KEY `public_key` (`public_key`),
   KEY `idx_hosts_public_key` (`public_key`),
@@ -260,13 +264,27 @@ CREATE TABLE `multipart_parts` (
   `part_number` bigint DEFAULT NULL,
   `size` bigint unsigned DEFAULT NULL,
   `db_multipart_upload_id` bigint unsigned NOT NULL,
+  `in_progress` boolean NOT NULL DEFAULT false,
   PRIMARY KEY (`id`),
   KEY `idx_multipart_parts_etag` (`etag`),
   KEY `idx_multipart_parts_part_number` (`part_number`),
   KEY `idx_multipart_parts_db_multipart_upload_id` (`db_multipart_upload_id`),
+  KEY `idx_multipart_parts_in_progress` (`in_progress`),
   CONSTRAINT `fk_multipart_uploads_parts` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads` (`id`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+-- dbMultipartPartProgress
+CREATE TABLE `multipart_part_progress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `db_multipart_part_id` bigint unsigned NOT NULL,
+  `object_index` bigint unsigned NOT NULL,
+  `checksum` binary(32) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_multipart_part_progress_part_index` (`db_multipart_part_id`,`object_index`),
+  CONSTRAINT `fk_multipart_part_progress_part` FOREIGN KEY (`db_multipart_part_id`) REFERENCES `multipart_parts` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
 -- dbObject
 CREATE TABLE `objects` (
   `id` bigint unsigned NOT NULL AUTO_INCREMENT,
@@ -278,6 +296,10 @@ CREATE TABLE `objects` (
   `size` bigint DEFAULT NULL,
   `mime_type` longtext,
   `etag` varchar(191) DEFAULT NULL,
+  `checksum` varchar(64) NOT NULL DEFAULT '',
+  `pinned` boolean NOT NULL DEFAULT false,
+  `content_disposition` varchar(255) NOT NULL DEFAULT '',
+  `object_id_lower` varchar(766) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin DEFAULT NULL,
   PRIMARY KEY (`id`),
   UNIQUE KEY `idx_object_bucket` (`db_bucket_id`,`object_id`),
   KEY `idx_objects_db_bucket_id` (`db_bucket_id`),
@@ -286,6 +308,8 @@ CREATE TABLE `objects` (
   KEY `idx_objects_etag` (`etag`),
   KEY `idx_objects_size` (`size`),
   KEY `idx_objects_created_at` (`created_at`),
+  KEY `idx_objects_bucket_object_id_lower` (`db_bucket_id`,`object_id_lower`),
+  KEY `idx_objects_checksum` (`checksum`),
   CONSTRAINT `fk_objects_db_bucket` FOREIGN KEY (`db_bucket_id`) REFERENCES `buckets` (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
@@ -346,6 +370,20 @@ CREATE TABLE `object_user_metadata` (
   CONSTRAINT `fk_multipart_upload_user_metadata` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads` (`id`) ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+-- dbObjectPinnedHost
+CREATE TABLE `object_pinned_hosts` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `db_object_id` bigint unsigned DEFAULT NULL,
+  `db_multipart_upload_id` bigint unsigned DEFAULT NULL,
+  `public_key` varbinary(32) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_object_pinned_hosts_db_object_id` (`db_object_id`),
+  KEY `idx_object_pinned_hosts_db_multipart_upload_id` (`db_multipart_upload_id`),
+  CONSTRAINT `fk_object_pinned_hosts_object` FOREIGN KEY (`db_object_id`) REFERENCES `objects` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_object_pinned_hosts_multipart_upload` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
 -- dbHostCheck
 CREATE TABLE `host_checks` (
   `id` bigint unsigned NOT NULL AUTO_INCREMENT,
@@ -362,6 +400,8 @@ CREATE TABLE `host_checks` (
   `usability_not_accepting_contracts` boolean NOT NULL DEFAULT false,
   `usability_not_announced` boolean NOT NULL DEFAULT false,
   `usability_not_completing_scan` boolean NOT NULL DEFAULT false,
+  `usability_stale_announcement` boolean NOT NULL DEFAULT false,
+  `usability_recently_failed` boolean NOT NULL DEFAULT false,
 
   `score_age` double NOT NULL,
   `score_collateral` double NOT NULL,
@@ -387,6 +427,8 @@ CREATE TABLE `host_checks` (
   INDEX `idx_host_checks_usability_not_accepting_contracts` (`usability_not_accepting_contracts`),
   INDEX `idx_host_checks_usability_not_announced` (`usability_not_announced`),
   INDEX `idx_host_checks_usability_not_completing_scan` (`usability_not_completing_scan`),
+  INDEX `idx_host_checks_usability_stale_announcement` (`usability_stale_announcement`),
+  INDEX `idx_host_checks_usability_recently_failed` (`usability_recently_failed`),
   INDEX `idx_host_checks_score_age` (`score_age`),
   INDEX `idx_host_checks_score_collateral` (`score_collateral`),
   INDEX `idx_host_checks_score_interactions` (`score_interactions`),
@@ -489,7 +531,74 @@ CREATE TABLE `autopilot_config` (
   `hosts_max_downtime_hours` bigint unsigned DEFAULT NULL,
   `hosts_min_protocol_version` varchar(191) DEFAULT NULL,
   `hosts_max_consecutive_scan_failures` bigint unsigned DEFAULT NULL,
+  `hosts_max_announcement_age_hours` bigint unsigned NOT NULL DEFAULT 0,
+  `hosts_recent_failure_cooldown_hours` bigint unsigned NOT NULL DEFAULT 0,
 
   PRIMARY KEY (`id`),
   CHECK (`id` = 1)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- bucket lifecycle rules
+CREATE TABLE `bucket_lifecycle_rules` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `db_bucket_id` bigint unsigned NOT NULL,
+  `prefix` varchar(766) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '',
+  `expiration_days` bigint unsigned NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_bucket_lifecycle_rules_db_bucket_id` (`db_bucket_id`),
+  CONSTRAINT `fk_bucket_lifecycle_rules_db_bucket` FOREIGN KEY (`db_bucket_id`) REFERENCES `buckets` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- object idempotency keys
+CREATE TABLE `object_idempotency_keys` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NOT NULL,
+  `idempotency_key` varchar(255) NOT NULL,
+  `request_hash` binary(32) NOT NULL,
+  `etag` varchar(191) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_object_idempotency_keys_idempotency_key` (`idempotency_key`),
+  KEY `idx_object_idempotency_keys_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- object events
+CREATE TABLE `object_events` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NOT NULL,
+  `operation` varchar(16) NOT NULL,
+  `bucket` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+  `object_id` varchar(766) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+  `renamed_to` varchar(766) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '',
+  `etag` varchar(191) NOT NULL DEFAULT '',
+  `size` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  KEY `idx_object_events_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- contract offboardings
+CREATE TABLE `contract_offboardings` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NOT NULL,
+  `db_contract_id` bigint unsigned NOT NULL,
+  `db_slab_id` bigint unsigned DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_contract_offboardings_db_contract_id` (`db_contract_id`),
+  KEY `idx_contract_offboardings_db_slab_id` (`db_slab_id`),
+  CONSTRAINT `fk_contract_offboardings_contract` FOREIGN KEY (`db_contract_id`) REFERENCES `contracts` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_contract_offboardings_slab` FOREIGN KEY (`db_slab_id`) REFERENCES `slabs` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- host sector verifications
+CREATE TABLE `host_sector_verifications` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `db_host_id` bigint unsigned NOT NULL,
+  `successes` bigint unsigned NOT NULL DEFAULT 0,
+  `failures` bigint unsigned NOT NULL DEFAULT 0,
+  `last_error` text NOT NULL,
+  `last_verified` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_host_sector_verifications_db_host_id` (`db_host_id`),
+  CONSTRAINT `fk_host_sector_verifications_host` FOREIGN KEY (`db_host_id`) REFERENCES `hosts` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -6,6 +6,7 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -21,8 +22,67 @@ var deadlockMsgs = []string{
 //go:embed all:migrations/*
 var migrationsFs embed.FS
 
+// DefaultCacheSize is the number of pages SQLite keeps in its page cache
+// unless configured otherwise.
+const DefaultCacheSize = 65536
+
 func Open(path string) (*dsql.DB, error) {
-	return dsql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=30000&_foreign_keys=1&_journal_mode=WAL&_secure_delete=false&_auto_vacuum=INCREMENTAL&_cache_size=65536", path))
+	return OpenCustom(path, 0, 0)
+}
+
+// OpenCustom opens the database at the given path with a custom page size and
+// cache size, zero values fall back to the defaults. The page size is fixed
+// once a database is created, so it's only applied to new databases. Like the
+// cache_size pragma, a positive cache size is a number of pages while a
+// negative cache size is an amount of KiB.
+func OpenCustom(path string, pageSize, cacheSize int64) (*dsql.DB, error) {
+	if pageSize != 0 {
+		if pageSize < 512 || pageSize > 65536 || pageSize&(pageSize-1) != 0 {
+			return nil, fmt.Errorf("invalid page size %d, must be a power of two between 512 and 65536", pageSize)
+		} else if err := initPageSize(path, pageSize); err != nil {
+			return nil, fmt.Errorf("failed to set page size: %w", err)
+		}
+	}
+	if cacheSize == 0 {
+		cacheSize = DefaultCacheSize
+	}
+	return dsql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=30000&_foreign_keys=1&_journal_mode=WAL&_secure_delete=false&_auto_vacuum=INCREMENTAL&_cache_size=%d", path, cacheSize))
+}
+
+// initPageSize sets the page size of the database at the given path if it
+// doesn't exist yet. The page size can't be changed once the database is in WAL
+// mode, so this has to happen before the database is opened regularly.
+func initPageSize(path string, pageSize int64) error {
+	if fi, err := os.Stat(path); err == nil && fi.Size() > 0 {
+		return nil // existing database
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	db, err := dsql.Open("sqlite3", fmt.Sprintf("file:%s", path))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// vacuuming the empty database writes its header, which persists the
+	// page size
+	for _, stmt := range []string{
+		fmt.Sprintf("PRAGMA page_size = %d", pageSize),
+		"PRAGMA auto_vacuum = INCREMENTAL",
+		"VACUUM",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PageSize returns the page size of the database.
+func PageSize(db *dsql.DB) (pageSize int64, err error) {
+	err = db.QueryRow("PRAGMA page_size").Scan(&pageSize)
+	return
 }
 
 func OpenEphemeral(name string) (*dsql.DB, error) {
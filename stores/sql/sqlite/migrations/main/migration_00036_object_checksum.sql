@@ -0,0 +1 @@
+ALTER TABLE `objects` ADD COLUMN `checksum` text NOT NULL DEFAULT '';
\ No newline at end of file
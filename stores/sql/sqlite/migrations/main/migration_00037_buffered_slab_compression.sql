@@ -0,0 +1 @@
+ALTER TABLE `buffered_slabs` ADD COLUMN `compression` text NOT NULL DEFAULT '';
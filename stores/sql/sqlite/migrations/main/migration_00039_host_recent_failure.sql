@@ -0,0 +1,4 @@
+ALTER TABLE `hosts` ADD COLUMN `last_failed_interaction` integer NOT NULL DEFAULT 0;
+ALTER TABLE `autopilot_config` ADD COLUMN `hosts_recent_failure_cooldown_hours` integer NOT NULL DEFAULT 0;
+ALTER TABLE `host_checks` ADD COLUMN `usability_recently_failed` INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX `idx_host_checks_usability_recently_failed` ON `host_checks` (`usability_recently_failed`);
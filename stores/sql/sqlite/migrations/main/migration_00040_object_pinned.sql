@@ -0,0 +1 @@
+ALTER TABLE `objects` ADD COLUMN `pinned` INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+CREATE TABLE `bucket_lifecycle_rules` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `db_bucket_id` integer NOT NULL,
+    `prefix` text NOT NULL DEFAULT '',
+    `expiration_days` integer NOT NULL,
+    CONSTRAINT `fk_bucket_lifecycle_rules_db_bucket` FOREIGN KEY (`db_bucket_id`) REFERENCES `buckets`(`id`) ON DELETE CASCADE);
+CREATE INDEX `idx_bucket_lifecycle_rules_db_bucket_id` ON `bucket_lifecycle_rules`(`db_bucket_id`);
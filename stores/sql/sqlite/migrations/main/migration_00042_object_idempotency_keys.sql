@@ -0,0 +1,8 @@
+CREATE TABLE `object_idempotency_keys` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime NOT NULL,
+    `idempotency_key` text NOT NULL,
+    `request_hash` blob NOT NULL,
+    `etag` text NOT NULL DEFAULT '');
+CREATE UNIQUE INDEX `idx_object_idempotency_keys_idempotency_key` ON `object_idempotency_keys`(`idempotency_key`);
+CREATE INDEX `idx_object_idempotency_keys_created_at` ON `object_idempotency_keys`(`created_at`);
@@ -0,0 +1,10 @@
+CREATE TABLE `object_events` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime NOT NULL,
+    `operation` text NOT NULL,
+    `bucket` text NOT NULL,
+    `object_id` text NOT NULL,
+    `renamed_to` text NOT NULL DEFAULT '',
+    `etag` text NOT NULL DEFAULT '',
+    `size` integer NOT NULL DEFAULT 0);
+CREATE INDEX `idx_object_events_created_at` ON `object_events`(`created_at`);
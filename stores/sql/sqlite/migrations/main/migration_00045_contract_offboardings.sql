@@ -0,0 +1,9 @@
+CREATE TABLE `contract_offboardings` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime NOT NULL,
+    `db_contract_id` integer NOT NULL,
+    `db_slab_id` integer DEFAULT NULL,
+    CONSTRAINT `fk_contract_offboardings_contract` FOREIGN KEY (`db_contract_id`) REFERENCES `contracts`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_contract_offboardings_slab` FOREIGN KEY (`db_slab_id`) REFERENCES `slabs`(`id`) ON DELETE SET NULL);
+CREATE INDEX `idx_contract_offboardings_db_contract_id` ON `contract_offboardings`(`db_contract_id`);
+CREATE INDEX `idx_contract_offboardings_db_slab_id` ON `contract_offboardings`(`db_slab_id`);
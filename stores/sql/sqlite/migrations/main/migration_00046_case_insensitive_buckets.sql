@@ -0,0 +1,3 @@
+ALTER TABLE `buckets` ADD COLUMN `case_insensitive` INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE `objects` ADD COLUMN `object_id_lower` text;
+CREATE INDEX `idx_objects_bucket_object_id_lower` ON `objects`(`db_bucket_id`,`object_id_lower`);
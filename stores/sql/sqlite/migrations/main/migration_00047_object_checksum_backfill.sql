@@ -0,0 +1,2 @@
+CREATE INDEX `idx_objects_checksum` ON `objects`(`checksum`);
+UPDATE `objects` SET `checksum` = 'e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855' WHERE `checksum` = '' AND `size` = 0;
@@ -0,0 +1,5 @@
+ALTER TABLE `multipart_parts` ADD COLUMN `in_progress` INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX `idx_multipart_parts_in_progress` ON `multipart_parts`(`in_progress`);
+
+CREATE TABLE IF NOT EXISTS `multipart_part_progress` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_multipart_part_id` integer NOT NULL,`object_index` integer NOT NULL,`checksum` blob NOT NULL,CONSTRAINT `fk_multipart_part_progress_part` FOREIGN KEY (`db_multipart_part_id`) REFERENCES `multipart_parts`(`id`) ON DELETE CASCADE);
+CREATE UNIQUE INDEX `idx_multipart_part_progress_part_index` ON `multipart_part_progress`(`db_multipart_part_id`,`object_index`);
@@ -0,0 +1,3 @@
+CREATE TABLE IF NOT EXISTS `object_pinned_hosts` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_object_id` integer DEFAULT NULL,`db_multipart_upload_id` integer DEFAULT NULL,`public_key` blob NOT NULL,CONSTRAINT `fk_object_pinned_hosts_object` FOREIGN KEY (`db_object_id`) REFERENCES `objects`(`id`) ON DELETE CASCADE,CONSTRAINT `fk_object_pinned_hosts_multipart_upload` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads`(`id`) ON DELETE CASCADE);
+CREATE INDEX `idx_object_pinned_hosts_db_object_id` ON `object_pinned_hosts`(`db_object_id`);
+CREATE INDEX `idx_object_pinned_hosts_db_multipart_upload_id` ON `object_pinned_hosts`(`db_multipart_upload_id`);
@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS `host_sector_verifications` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_host_id` integer NOT NULL,`successes` integer NOT NULL DEFAULT 0,`failures` integer NOT NULL DEFAULT 0,`last_error` text NOT NULL DEFAULT '',`last_verified` integer NOT NULL,CONSTRAINT `fk_host_sector_verifications_host` FOREIGN KEY (`db_host_id`) REFERENCES `hosts`(`id`) ON DELETE CASCADE);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_host_sector_verifications_db_host_id` ON `host_sector_verifications`(`db_host_id`);
@@ -0,0 +1 @@
+CREATE INDEX `idx_objects_bucket_object_id_size` ON `objects`(`db_bucket_id`,`object_id`,`size`,`created_at`);
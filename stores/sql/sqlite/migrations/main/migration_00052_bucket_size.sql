@@ -0,0 +1,2 @@
+ALTER TABLE `buckets` ADD COLUMN `size` integer NOT NULL DEFAULT 0;
+UPDATE `buckets` SET `size` = (SELECT COALESCE(SUM(`objects`.`size`), 0) FROM `objects` WHERE `objects`.`db_bucket_id` = `buckets`.`id` AND `objects`.`object_id` IS NOT NULL);
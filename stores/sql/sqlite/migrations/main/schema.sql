@@ -20,7 +20,8 @@ CREATE TABLE `hosts` (
 `failed_interactions` real,
 `lost_sectors` integer,
 `last_announcement` datetime,
-`net_address` text);
+`net_address` text,
+`last_failed_interaction` integer NOT NULL DEFAULT 0);
 CREATE INDEX `idx_hosts_recent_scan_failures` ON `hosts`(`recent_scan_failures`);
 CREATE INDEX `idx_hosts_recent_downtime` ON `hosts`(`recent_downtime`);
 CREATE INDEX `idx_hosts_scanned` ON `hosts`(`scanned`);
@@ -45,11 +46,11 @@ CREATE INDEX `idx_contracts_window_end` ON `contracts`(`window_end`);
 CREATE INDEX `idx_contracts_window_start` ON `contracts`(`window_start`);
 
 -- dbBucket
-CREATE TABLE `buckets` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`policy` text,`name` text NOT NULL UNIQUE);
+CREATE TABLE `buckets` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`policy` text,`name` text NOT NULL UNIQUE,`case_insensitive` INTEGER NOT NULL DEFAULT 0,`size` integer NOT NULL DEFAULT 0);
 CREATE INDEX `idx_buckets_name` ON `buckets`(`name`);
 
 -- dbObject
-CREATE TABLE `objects` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_bucket_id` integer NOT NULL, `object_id` text,`key` blob,`health` real NOT NULL DEFAULT 1,`size` integer,`mime_type` text,`etag` text,CONSTRAINT `fk_objects_db_bucket` FOREIGN KEY (`db_bucket_id`) REFERENCES `buckets`(`id`));
+CREATE TABLE `objects` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_bucket_id` integer NOT NULL, `object_id` text,`key` blob,`health` real NOT NULL DEFAULT 1,`size` integer,`mime_type` text,`etag` text,`checksum` text NOT NULL DEFAULT '',`pinned` INTEGER NOT NULL DEFAULT 0,`content_disposition` text NOT NULL DEFAULT '',`object_id_lower` text,CONSTRAINT `fk_objects_db_bucket` FOREIGN KEY (`db_bucket_id`) REFERENCES `buckets`(`id`));
 CREATE INDEX `idx_objects_db_bucket_id` ON `objects`(`db_bucket_id`);
 CREATE INDEX `idx_objects_etag` ON `objects`(`etag`);
 CREATE INDEX `idx_objects_health` ON `objects`(`health`);
@@ -57,6 +58,9 @@ CREATE INDEX `idx_objects_object_id` ON `objects`(`object_id`);
 CREATE INDEX `idx_objects_size` ON `objects`(`size`);
 CREATE UNIQUE INDEX `idx_object_bucket` ON `objects`(`db_bucket_id`,`object_id`);
 CREATE INDEX `idx_objects_created_at` ON `objects`(`created_at`);
+CREATE INDEX `idx_objects_bucket_object_id_lower` ON `objects`(`db_bucket_id`,`object_id_lower`);
+CREATE INDEX `idx_objects_checksum` ON `objects`(`checksum`);
+CREATE INDEX `idx_objects_bucket_object_id_size` ON `objects`(`db_bucket_id`,`object_id`,`size`,`created_at`);
 
 -- dbMultipartUpload
 CREATE TABLE `multipart_uploads` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`key` blob,`upload_id` text NOT NULL,`object_id` text NOT NULL,`db_bucket_id` integer NOT NULL,`mime_type` text,CONSTRAINT `fk_multipart_uploads_db_bucket` FOREIGN KEY (`db_bucket_id`) REFERENCES `buckets`(`id`) ON DELETE CASCADE);
@@ -66,7 +70,7 @@ CREATE INDEX `idx_multipart_uploads_object_id` ON `multipart_uploads`(`object_id
 CREATE UNIQUE INDEX `idx_multipart_uploads_upload_id` ON `multipart_uploads`(`upload_id`);
 
 -- dbBufferedSlab
-CREATE TABLE `buffered_slabs` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`filename` text);
+CREATE TABLE `buffered_slabs` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`filename` text,`compression` text NOT NULL DEFAULT '');
 
 -- dbSlab
 CREATE TABLE `slabs` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_buffered_slab_id` integer DEFAULT NULL,`health` real NOT NULL DEFAULT 1,`health_valid_until` integer NOT NULL DEFAULT 0,`key` blob NOT NULL UNIQUE,`min_shards` integer,`total_shards` integer,CONSTRAINT `fk_buffered_slabs_db_slab` FOREIGN KEY (`db_buffered_slab_id`) REFERENCES `buffered_slabs`(`id`));
@@ -95,10 +99,15 @@ CREATE INDEX `idx_host_sectors_db_host_id` ON `host_sectors`(`db_host_id`);
 CREATE INDEX `idx_host_sectors_db_sector_id` ON `host_sectors`(`db_sector_id`);
 
 -- dbMultipartPart
-CREATE TABLE `multipart_parts` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`etag` text,`part_number` integer,`size` integer,`db_multipart_upload_id` integer NOT NULL,CONSTRAINT `fk_multipart_uploads_parts` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads`(`id`) ON DELETE CASCADE);
+CREATE TABLE `multipart_parts` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`etag` text,`part_number` integer,`size` integer,`db_multipart_upload_id` integer NOT NULL,`in_progress` INTEGER NOT NULL DEFAULT 0,CONSTRAINT `fk_multipart_uploads_parts` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads`(`id`) ON DELETE CASCADE);
 CREATE INDEX `idx_multipart_parts_db_multipart_upload_id` ON `multipart_parts`(`db_multipart_upload_id`);
 CREATE INDEX `idx_multipart_parts_part_number` ON `multipart_parts`(`part_number`);
 CREATE INDEX `idx_multipart_parts_etag` ON `multipart_parts`(`etag`);
+CREATE INDEX `idx_multipart_parts_in_progress` ON `multipart_parts`(`in_progress`);
+
+-- dbMultipartPartProgress
+CREATE TABLE `multipart_part_progress` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_multipart_part_id` integer NOT NULL,`object_index` integer NOT NULL,`checksum` blob NOT NULL,CONSTRAINT `fk_multipart_part_progress_part` FOREIGN KEY (`db_multipart_part_id`) REFERENCES `multipart_parts`(`id`) ON DELETE CASCADE);
+CREATE UNIQUE INDEX `idx_multipart_part_progress_part_index` ON `multipart_part_progress`(`db_multipart_part_id`,`object_index`);
 
 -- dbSlice
 CREATE TABLE `slices` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_object_id` integer,`object_index` integer,`db_multipart_part_id` integer,`db_slab_id` integer,`offset` integer,`length` integer,CONSTRAINT `fk_objects_slabs` FOREIGN KEY (`db_object_id`) REFERENCES `objects`(`id`) ON DELETE CASCADE,CONSTRAINT `fk_multipart_parts_slabs` FOREIGN KEY (`db_multipart_part_id`) REFERENCES `multipart_parts`(`id`) ON DELETE CASCADE,CONSTRAINT `fk_slabs_slices` FOREIGN KEY (`db_slab_id`) REFERENCES `slabs`(`id`));
@@ -154,6 +163,11 @@ CREATE UNIQUE INDEX `idx_module_event_url` ON `webhooks`(`module`,`event`,`url`)
 CREATE TABLE `object_user_metadata` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_object_id` integer DEFAULT NULL,`db_multipart_upload_id` integer DEFAULT NULL,`key` text NOT NULL,`value` text, CONSTRAINT `fk_object_user_metadata` FOREIGN KEY (`db_object_id`) REFERENCES `objects` (`id`) ON DELETE CASCADE, CONSTRAINT `fk_multipart_upload_user_metadata` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads` (`id`) ON DELETE SET NULL);
 CREATE UNIQUE INDEX `idx_object_user_metadata_key` ON `object_user_metadata`(`db_object_id`,`db_multipart_upload_id`,`key`);
 
+-- dbObjectPinnedHost
+CREATE TABLE `object_pinned_hosts` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_object_id` integer DEFAULT NULL,`db_multipart_upload_id` integer DEFAULT NULL,`public_key` blob NOT NULL,CONSTRAINT `fk_object_pinned_hosts_object` FOREIGN KEY (`db_object_id`) REFERENCES `objects`(`id`) ON DELETE CASCADE,CONSTRAINT `fk_object_pinned_hosts_multipart_upload` FOREIGN KEY (`db_multipart_upload_id`) REFERENCES `multipart_uploads`(`id`) ON DELETE CASCADE);
+CREATE INDEX `idx_object_pinned_hosts_db_object_id` ON `object_pinned_hosts`(`db_object_id`);
+CREATE INDEX `idx_object_pinned_hosts_db_multipart_upload_id` ON `object_pinned_hosts`(`db_multipart_upload_id`);
+
 -- dbHostCheck
 CREATE TABLE `host_checks` (
 `id` INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -168,6 +182,8 @@ CREATE TABLE `host_checks` (
 `usability_not_accepting_contracts` INTEGER NOT NULL DEFAULT 0,
 `usability_not_announced` INTEGER NOT NULL DEFAULT 0,
 `usability_not_completing_scan` INTEGER NOT NULL DEFAULT 0,
+`usability_stale_announcement` INTEGER NOT NULL DEFAULT 0,
+`usability_recently_failed` INTEGER NOT NULL DEFAULT 0,
 `score_age` REAL NOT NULL,
 `score_collateral` REAL NOT NULL,
 `score_interactions` REAL NOT NULL,
@@ -190,6 +206,8 @@ CREATE INDEX `idx_host_checks_usability_low_max_duration` ON `host_checks` (`usa
 CREATE INDEX `idx_host_checks_usability_not_accepting_contracts` ON `host_checks` (`usability_not_accepting_contracts`);
 CREATE INDEX `idx_host_checks_usability_not_announced` ON `host_checks` (`usability_not_announced`);
 CREATE INDEX `idx_host_checks_usability_not_completing_scan` ON `host_checks` (`usability_not_completing_scan`);
+CREATE INDEX `idx_host_checks_usability_stale_announcement` ON `host_checks` (`usability_stale_announcement`);
+CREATE INDEX `idx_host_checks_usability_recently_failed` ON `host_checks` (`usability_recently_failed`);
 CREATE INDEX `idx_host_checks_score_age` ON `host_checks` (`score_age`);
 CREATE INDEX `idx_host_checks_score_collateral` ON `host_checks` (`score_collateral`);
 CREATE INDEX `idx_host_checks_score_interactions` ON `host_checks` (`score_interactions`);
@@ -232,4 +250,51 @@ CREATE TABLE `contract_elements` (
 CREATE UNIQUE INDEX `idx_contract_elements_db_contract_id` ON `contract_elements`(`db_contract_id`);
 
 -- autopilot config
-CREATE TABLE autopilot_config (id INTEGER PRIMARY KEY CHECK (id = 1), created_at datetime, enabled integer NOT NULL DEFAULT 0, contracts_amount integer, contracts_period integer, contracts_renew_window integer, contracts_download integer, contracts_upload integer, contracts_storage integer, contracts_prune integer NOT NULL DEFAULT 0, hosts_max_downtime_hours integer, hosts_min_protocol_version text, hosts_max_consecutive_scan_failures integer);
+CREATE TABLE autopilot_config (id INTEGER PRIMARY KEY CHECK (id = 1), created_at datetime, enabled integer NOT NULL DEFAULT 0, contracts_amount integer, contracts_period integer, contracts_renew_window integer, contracts_download integer, contracts_upload integer, contracts_storage integer, contracts_prune integer NOT NULL DEFAULT 0, hosts_max_downtime_hours integer, hosts_min_protocol_version text, hosts_max_consecutive_scan_failures integer, hosts_max_announcement_age_hours integer NOT NULL DEFAULT 0, hosts_recent_failure_cooldown_hours integer NOT NULL DEFAULT 0);
+
+-- bucket lifecycle rules
+CREATE TABLE `bucket_lifecycle_rules` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `db_bucket_id` integer NOT NULL,
+    `prefix` text NOT NULL DEFAULT '',
+    `expiration_days` integer NOT NULL,
+    CONSTRAINT `fk_bucket_lifecycle_rules_db_bucket` FOREIGN KEY (`db_bucket_id`) REFERENCES `buckets`(`id`) ON DELETE CASCADE);
+CREATE INDEX `idx_bucket_lifecycle_rules_db_bucket_id` ON `bucket_lifecycle_rules`(`db_bucket_id`);
+
+-- object idempotency keys
+CREATE TABLE `object_idempotency_keys` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime NOT NULL,
+    `idempotency_key` text NOT NULL,
+    `request_hash` blob NOT NULL,
+    `etag` text NOT NULL DEFAULT '');
+CREATE UNIQUE INDEX `idx_object_idempotency_keys_idempotency_key` ON `object_idempotency_keys`(`idempotency_key`);
+CREATE INDEX `idx_object_idempotency_keys_created_at` ON `object_idempotency_keys`(`created_at`);
+
+-- object events
+CREATE TABLE `object_events` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime NOT NULL,
+    `operation` text NOT NULL,
+    `bucket` text NOT NULL,
+    `object_id` text NOT NULL,
+    `renamed_to` text NOT NULL DEFAULT '',
+    `etag` text NOT NULL DEFAULT '',
+    `size` integer NOT NULL DEFAULT 0);
+CREATE INDEX `idx_object_events_created_at` ON `object_events`(`created_at`);
+
+-- contract offboardings
+CREATE TABLE `contract_offboardings` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime NOT NULL,
+    `db_contract_id` integer NOT NULL,
+    `db_slab_id` integer DEFAULT NULL,
+    CONSTRAINT `fk_contract_offboardings_contract` FOREIGN KEY (`db_contract_id`) REFERENCES `contracts`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_contract_offboardings_slab` FOREIGN KEY (`db_slab_id`) REFERENCES `slabs`(`id`) ON DELETE SET NULL);
+CREATE INDEX `idx_contract_offboardings_db_contract_id` ON `contract_offboardings`(`db_contract_id`);
+CREATE INDEX `idx_contract_offboardings_db_slab_id` ON `contract_offboardings`(`db_slab_id`);
+
+-- host sector verifications
+CREATE TABLE `host_sector_verifications` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`db_host_id` integer NOT NULL,`successes` integer NOT NULL DEFAULT 0,`failures` integer NOT NULL DEFAULT 0,`last_error` text NOT NULL DEFAULT '',`last_verified` integer NOT NULL,CONSTRAINT `fk_host_sector_verifications_host` FOREIGN KEY (`db_host_id`) REFERENCES `hosts`(`id`) ON DELETE CASCADE);
+CREATE UNIQUE INDEX `idx_host_sector_verifications_db_host_id` ON `host_sector_verifications`(`db_host_id`);
@@ -7,9 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/alerts"
 	"go.sia.tech/renterd/api"
@@ -42,11 +45,12 @@ type testSQLStore struct {
 }
 
 type testSQLStoreConfig struct {
-	dbName        string
-	dbMetricsName string
-	dir           string
-	persistent    bool
-	skipMigrate   bool
+	dbName         string
+	dbMetricsName  string
+	dir            string
+	objectEventLog bool
+	persistent     bool
+	skipMigrate    bool
 }
 
 var defaultTestSQLStoreConfig = testSQLStoreConfig{}
@@ -173,6 +177,7 @@ func newTestSQLStore(t *testing.T, cfg testSQLStoreConfig) *testSQLStore {
 		DBMetrics:                     dbMetrics,
 		PartialSlabDir:                partialSlabDir,
 		Migrate:                       !cfg.skipMigrate,
+		ObjectEventLog:                cfg.objectEventLog,
 		SlabBufferCompletionThreshold: 0,
 		Logger:                        zap.NewNop(),
 		LongQueryDuration:             100 * time.Millisecond,
@@ -336,3 +341,309 @@ func (s *testSQLStore) renewTestContract(hk types.PublicKey, renewedFrom, renewe
 	renewal.RenewedFrom = renewedFrom
 	return s.AddRenewal(context.Background(), renewal)
 }
+
+func TestCopyMainDatabase(t *testing.T) {
+	src := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer src.Close()
+	if _, ok := src.db.(*sqlite.MainDatabase); !ok {
+		t.Skip("test requires SQLite")
+	}
+
+	// add some hosts, contracts and objects
+	hks, err := src.addTestHosts(3)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, _, err := src.addTestContracts(hks); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := src.addTestObject(fmt.Sprintf("obj_%d", i), newTestObject(2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// prepare destination, the test bucket is copied from the source
+	dst := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer dst.Close()
+	if _, err := dst.DB().Exec(context.Background(), "DELETE FROM buckets"); err != nil {
+		t.Fatal(err)
+	}
+	dstDB := dst.db.(*sqlite.MainDatabase)
+
+	// copy the database
+	if err := dstDB.CopyFrom(context.Background(), src.DB().DB(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the objects were copied
+	assertObjects := func() {
+		t.Helper()
+		for i := 0; i < 5; i++ {
+			key := fmt.Sprintf("obj_%d", i)
+			srcObj, err := src.Object(context.Background(), testBucket, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dstObj, err := dst.Object(context.Background(), testBucket, key)
+			if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(srcObj, dstObj) {
+				t.Fatal("object mismatch", cmp.Diff(srcObj, dstObj, cmp.AllowUnexported(object.EncryptionKey{})))
+			}
+		}
+	}
+	assertObjects()
+
+	// simulate an interrupted copy by removing the last objects
+	if _, err := dst.DB().Exec(context.Background(), "DELETE FROM objects WHERE id > 2"); err != nil {
+		t.Fatal(err)
+	} else if n := dst.Count("objects"); n != 2 {
+		t.Fatal("unexpected number of objects", n)
+	}
+
+	// resume the copy
+	if err := dstDB.CopyFrom(context.Background(), src.DB().DB(), 2); err != nil {
+		t.Fatal(err)
+	}
+	assertObjects()
+	if n := dst.Count("contracts"); n != 3 {
+		t.Fatal("unexpected number of contracts", n)
+	}
+
+	// copying into a database with a row count mismatch fails
+	if _, err := dst.DB().Exec(context.Background(), "DELETE FROM contracts WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	} else if err := dstDB.CopyFrom(context.Background(), src.DB().DB(), 2); err == nil || !strings.Contains(err.Error(), "row count mismatch") {
+		t.Fatal("expected row count mismatch", err)
+	}
+
+	// copying a row that conflicts with an existing one fails, the host is
+	// added with id 1 so the copy resumes with the host that has the same key
+	dst2 := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer dst2.Close()
+	if _, err := dst2.DB().Exec(context.Background(), "DELETE FROM buckets"); err != nil {
+		t.Fatal(err)
+	} else if err := dst2.addTestHost(hks[1]); err != nil {
+		t.Fatal(err)
+	} else if err := dst2.db.(*sqlite.MainDatabase).CopyFrom(context.Background(), src.DB().DB(), 2); !errors.Is(err, sql.ErrCopyConflict) {
+		t.Fatal("expected conflict", err)
+	}
+}
+
+func TestSQLitePageSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+
+	// invalid page sizes are rejected
+	if _, err := sqlite.OpenCustom(path, 1000, 0); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// open a new database with a custom page size and cache size
+	conn, err := sqlite.OpenCustom(path, 8192, -2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sqlite.NewMainDatabase(conn, zap.NewNop(), 100*time.Millisecond, 100*time.Millisecond, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := db.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the pragmas were applied
+	var cacheSize int64
+	var journalMode string
+	if pageSize, err := sqlite.PageSize(conn); err != nil {
+		t.Fatal(err)
+	} else if pageSize != 8192 {
+		t.Fatalf("unexpected page size %d", pageSize)
+	} else if err := conn.QueryRow("PRAGMA cache_size").Scan(&cacheSize); err != nil {
+		t.Fatal(err)
+	} else if cacheSize != -2000 {
+		t.Fatalf("unexpected cache size %d", cacheSize)
+	} else if err := conn.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatal(err)
+	} else if journalMode != "wal" {
+		t.Fatalf("unexpected journal mode %q", journalMode)
+	} else if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopen the database with a different page size, it's left untouched
+	conn, err = sqlite.OpenCustom(path, 4096, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if pageSize, err := sqlite.PageSize(conn); err != nil {
+		t.Fatal(err)
+	} else if pageSize != 8192 {
+		t.Fatalf("unexpected page size %d", pageSize)
+	}
+}
+
+func TestObjectIDPrefixQueryPlan(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add objects that are close to the boundaries of the range scan
+	keys := []string{
+		"/foo",
+		"/foo\t",
+		"/foo/",
+		"/foo/bar",
+		"/foo\U0010FFFF",
+		"/fop",
+		"/fo",
+	}
+	for _, key := range keys {
+		if _, err := ss.addTestObject(key, newTestObject(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert the prefix matches exactly the objects starting with it
+	for _, prefix := range []string{"", "/", "/fo", "/foo", "/foo/", "/foo\t", "/foo\U0010FFFF"} {
+		var expected []string
+		for _, key := range keys {
+			if strings.HasPrefix(key, prefix) {
+				expected = append(expected, key)
+			}
+		}
+		resp, err := ss.Objects(context.Background(), testBucket, prefix, "", "", "", "", "", "", -1, object.EncryptionKey{}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(resp.Objects) != len(expected) {
+			t.Fatalf("prefix %q: expected %d objects, got %d", prefix, len(expected), len(resp.Objects))
+		}
+		for _, obj := range resp.Objects {
+			if !strings.HasPrefix(obj.Key, prefix) {
+				t.Fatalf("prefix %q: unexpected object %q", prefix, obj.Key)
+			}
+		}
+	}
+
+	// assert the query planner uses an index on the object id
+	if _, ok := ss.db.(*sqlite.MainDatabase); !ok {
+		t.Skip("query plan is only checked on SQLite")
+	}
+	expr, args := sql.ObjectIDPrefixExpr("object_id", "/foo/")
+	rows, err := ss.DB().Query(context.Background(), fmt.Sprintf("EXPLAIN QUERY PLAN SELECT id FROM objects WHERE %s", expr), args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		plan = append(plan, detail)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	} else if details := strings.Join(plan, "\n"); !strings.Contains(details, "INDEX idx_objects_object_id (object_id>? AND object_id<?)") {
+		t.Fatal("expected range scan on object id index, got", details)
+	}
+}
+
+func TestObjectsGlobQueryPlan(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// build the expression used to filter objects by the glob
+	glob := "/logs/2024-*/error.log"
+	pattern, err := api.ParseGlob(glob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globPrefix, err := api.GlobPrefix(glob)
+	if err != nil {
+		t.Fatal(err)
+	} else if globPrefix != "/logs/2024-" {
+		t.Fatalf("unexpected prefix %q", globPrefix)
+	}
+	expr, args := sql.ObjectIDPrefixExpr("object_id", globPrefix)
+	if err := ss.db.Transaction(context.Background(), func(tx sql.DatabaseTx) error {
+		globExpr, globArg := tx.(sql.Tx).GlobExpr("object_id", pattern)
+		expr = fmt.Sprintf("%s AND %s", expr, globExpr)
+		args = append(args, globArg)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the query planner uses the index on the object id for the static
+	// prefix of the glob
+	var explain string
+	if _, ok := ss.db.(*sqlite.MainDatabase); ok {
+		explain = "EXPLAIN QUERY PLAN SELECT id FROM objects WHERE %s"
+	} else {
+		explain = "EXPLAIN SELECT id FROM objects WHERE %s"
+	}
+	rows, err := ss.DB().Query(context.Background(), fmt.Sprintf(explain, expr), args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan []string
+	for rows.Next() {
+		values := make([]dsql.NullString, len(cols))
+		dsts := make([]any, len(cols))
+		for i := range values {
+			dsts[i] = &values[i]
+		}
+		if err := rows.Scan(dsts...); err != nil {
+			t.Fatal(err)
+		}
+		for i, col := range cols {
+			if col == "detail" || col == "possible_keys" {
+				plan = append(plan, values[i].String)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	} else if details := strings.Join(plan, "\n"); !strings.Contains(details, "idx_objects_object_id") {
+		t.Fatal("expected the object id index to be used, got", details)
+	}
+}
+
+func TestDatabaseHealth(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// assert the database is healthy
+	dh := ss.DatabaseHealth(context.Background())
+	if dh.Status == api.HealthStatusUnavailable {
+		t.Fatal("unexpected status", dh.Status, dh.Error)
+	} else if !dh.Migrated || dh.PendingMigrations != 0 {
+		t.Fatal("expected all migrations to be applied", dh.PendingMigrations)
+	} else if !dh.ConnectionsAvailable {
+		t.Fatal("expected connections to be available")
+	}
+
+	// remove a migration and assert the database is unavailable
+	if _, err := ss.DB().Exec(context.Background(), "DELETE FROM migrations WHERE id = ?", "00001_init"); err != nil {
+		t.Fatal(err)
+	} else if dh := ss.DatabaseHealth(context.Background()); dh.Status != api.HealthStatusUnavailable {
+		t.Fatal("unexpected status", dh.Status)
+	} else if dh.Migrated || dh.PendingMigrations != 1 {
+		t.Fatal("expected one pending migration", dh.PendingMigrations)
+	}
+
+	// close the store and assert the database is unavailable
+	if err := ss.Close(); err != nil {
+		t.Fatal(err)
+	} else if dh := ss.DatabaseHealth(context.Background()); dh.Status != api.HealthStatusUnavailable || dh.Error == "" {
+		t.Fatal("unexpected status", dh.Status, dh.Error)
+	}
+}
@@ -80,6 +80,28 @@ func (c *Client) DownloadStats() (resp api.DownloadStatsResponse, err error) {
 	return
 }
 
+// ObjectRecoverability checks whether every slab of the object at the given
+// key can be recovered from hosts the worker has good contracts with, without
+// downloading any data.
+func (c *Client) ObjectRecoverability(ctx context.Context, bucket, key string) (resp api.ObjectRecoverabilityResponse, err error) {
+	values := url.Values{}
+	values.Set("bucket", bucket)
+
+	key = api.ObjectKeyEscape(key)
+	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/objects/recoverability/%s?"+values.Encode(), key), &resp)
+	return
+}
+
+// EstimateUploadCost estimates the cost of uploading an object of given size
+// to the worker's current set of candidate hosts.
+func (c *Client) EstimateUploadCost(ctx context.Context, size uint64, opts api.EstimateUploadCostOptions) (resp api.UploadCostEstimateResponse, err error) {
+	values := url.Values{}
+	values.Set("size", fmt.Sprint(size))
+	opts.Apply(values)
+	err = c.c.WithContext(ctx).GET("/upload/estimate?"+values.Encode(), &resp)
+	return
+}
+
 // HeadObject returns the metadata of the object at the given key.
 func (c *Client) HeadObject(ctx context.Context, bucket, key string, opts api.HeadObjectOptions) (*api.HeadObjectResponse, error) {
 	c.c.Custom("HEAD", fmt.Sprintf("/object/%s", key), nil, nil)
@@ -156,6 +178,13 @@ func (c *Client) RemoveObjects(ctx context.Context, bucket, prefix string) (err
 	return
 }
 
+// FlushSlabBuffers uploads all slab buffers, regardless of how full they are,
+// and blocks until they are uploaded or the context is done.
+func (c *Client) FlushSlabBuffers(ctx context.Context) (resp api.SlabBuffersFlushResponse, err error) {
+	err = c.c.WithContext(ctx).POST("/slabbuffers/flush", nil, &resp)
+	return
+}
+
 // State returns the current state of the worker.
 func (c *Client) State() (state api.WorkerStateResponse, err error) {
 	err = c.c.GET("/state", &state)
@@ -223,7 +252,36 @@ func (c *Client) UploadObject(ctx context.Context, r io.Reader, bucket, key stri
 	if err != nil {
 		return nil, err
 	}
-	return &api.UploadObjectResponse{ETag: header.Get("ETag")}, nil
+	resp := &api.UploadObjectResponse{ETag: header.Get("ETag")}
+	if v := header.Get(api.ObjectUploadIDHeader); v != "" {
+		var uID api.UploadID
+		if err := uID.UnmarshalText([]byte(v)); err != nil {
+			return nil, fmt.Errorf("failed to parse upload id: %w", err)
+		}
+		resp.UploadID = &uID
+	}
+	resp.Pending = header.Get(api.ObjectUploadPendingHeader) == "true"
+	return resp, nil
+}
+
+// UploadPersistenceStatus returns the persistence status of an upload that
+// was performed with api.UploadDurabilityAsync.
+func (c *Client) UploadPersistenceStatus(ctx context.Context, id api.UploadID) (status api.UploadPersistenceStatus, err error) {
+	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/uploads/%s/persistence", id), &status)
+	return
+}
+
+// ActiveUploads returns the object uploads that are currently in progress
+// together with the effective settings they use.
+func (c *Client) ActiveUploads(ctx context.Context) (resp []api.ActiveUpload, err error) {
+	err = c.c.WithContext(ctx).GET("/uploads", &resp)
+	return
+}
+
+// UploaderHistory returns the recent sector upload stats of the given host.
+func (c *Client) UploaderHistory(ctx context.Context, hk types.PublicKey) (resp api.UploaderHistoryResponse, err error) {
+	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/stats/uploads/%s", hk), &resp)
+	return
 }
 
 // UploadStats returns the upload stats.
@@ -235,6 +293,7 @@ func (c *Client) UploadStats() (resp api.UploadStatsResponse, err error) {
 func (c *Client) object(ctx context.Context, bucket, key string, opts api.DownloadObjectOptions) (_ io.ReadCloser, _ http.Header, err error) {
 	values := url.Values{}
 	values.Set("bucket", bucket)
+	opts.Apply(values)
 	key += "?" + values.Encode()
 
 	c.c.Custom("GET", fmt.Sprintf("/object/%s", key), nil, (*[]api.ObjectMetadata)(nil))
@@ -293,12 +352,13 @@ func parseObjectResponseHeaders(header http.Header) (api.HeadObjectResponse, err
 		return api.HeadObjectResponse{}, fmt.Errorf("failed to parse Last-Modified header: %w", err)
 	}
 	return api.HeadObjectResponse{
-		ContentType:  header.Get("Content-Type"),
-		Etag:         trimEtag(header.Get("ETag")),
-		LastModified: api.TimeRFC3339(modTime),
-		Range:        r,
-		Size:         size,
-		Metadata:     api.ExtractObjectUserMetadataFrom(headers),
+		ContentDisposition: header.Get("Content-Disposition"),
+		ContentType:        header.Get("Content-Type"),
+		Etag:               trimEtag(header.Get("ETag")),
+		LastModified:       api.TimeRFC3339(modTime),
+		Range:              r,
+		Size:               size,
+		Metadata:           api.ExtractObjectUserMetadataFrom(headers),
 	}, nil
 }
 
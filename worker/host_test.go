@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,6 +22,8 @@ import (
 	"lukechampine.com/frand"
 )
 
+var errTestHostUploadFailed = errors.New("test host failed to upload sector")
+
 type (
 	testHost struct {
 		*mocks.Host
@@ -28,6 +31,10 @@ type (
 		hptFn       func() api.HostPriceTable
 		pFn         func() rhpv4.HostPrices
 		uploadDelay time.Duration
+
+		// uploadFailures is the number of sector uploads that fail before
+		// the host starts accepting sectors
+		uploadFailures atomic.Int64
 	}
 
 	testHostManager struct {
@@ -129,6 +136,9 @@ func (h *testHost) DownloadSector(ctx context.Context, w io.Writer, root types.H
 }
 
 func (h *testHost) UploadSector(ctx context.Context, sectorRoot types.Hash256, sector *[rhpv2.SectorSize]byte) error {
+	if h.uploadFailures.Add(-1) >= 0 {
+		return errTestHostUploadFailed
+	}
 	h.Contract.AddSector(sectorRoot, sector)
 	if h.uploadDelay > 0 {
 		select {
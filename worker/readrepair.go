@@ -0,0 +1,170 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/gouging"
+	"go.sia.tech/renterd/internal/memory"
+	"go.sia.tech/renterd/internal/upload"
+	"go.sia.tech/renterd/object"
+	"go.uber.org/zap"
+)
+
+const (
+	// readRepairTimeout is the max amount of time a single read-repair is
+	// allowed to take.
+	readRepairTimeout = 5 * time.Minute
+)
+
+type (
+	// readRepairer repairs the unhealthy shards of slabs that were recovered
+	// during a download, using the shards that were downloaded. It limits
+	// the number of bytes that are repaired within a given interval so that
+	// reads don't turn into writes, slabs that exceed the budget are skipped
+	// and left to the migrator.
+	readRepairer struct {
+		w *Worker
+
+		maxBytes uint64 // 0 means unlimited
+		interval time.Duration
+
+		mu          sync.Mutex
+		windowStart time.Time
+		used        uint64
+		inflight    map[object.EncryptionKey]struct{}
+		stats       api.ReadRepairStats
+	}
+)
+
+func newReadRepairer(w *Worker, maxBytes uint64, interval time.Duration) *readRepairer {
+	return &readRepairer{
+		w:        w,
+		maxBytes: maxBytes,
+		interval: interval,
+		inflight: make(map[object.EncryptionKey]struct{}),
+	}
+}
+
+// ReadRepair implements download.ReadRepairer. The download memory of the
+// shards is held until the repair is done since the shards are used to
+// repair the slab.
+func (rr *readRepairer) ReadRepair(s object.Slab, shards [][]byte, unhealthy []int, mem memory.Memory) bool {
+	size := uint64(len(unhealthy)) * rhpv2.SectorSize
+	if !rr.acquire(s.EncryptionKey, size) {
+		return false
+	}
+
+	go func() {
+		if mem != nil {
+			defer mem.Release()
+		}
+		err := rr.repair(s, shards, unhealthy, size)
+
+		rr.mu.Lock()
+		delete(rr.inflight, s.EncryptionKey)
+		if err != nil {
+			rr.stats.Failed++
+		} else {
+			rr.stats.Repaired++
+			rr.stats.RepairedShards += uint64(len(unhealthy))
+		}
+		rr.mu.Unlock()
+
+		if err != nil {
+			rr.w.logger.Debugw("read-repair failed",
+				zap.Error(err),
+				zap.Stringer("slab", s.EncryptionKey),
+				zap.Int("numShards", len(unhealthy)),
+			)
+		}
+	}()
+	return true
+}
+
+// Stats returns the read-repair stats.
+func (rr *readRepairer) Stats() api.ReadRepairStats {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.stats
+}
+
+// acquire reserves n bytes of the budget for repairing the slab with given key,
+// it returns false if the slab is already being repaired or the budget of the
+// current interval is exhausted.
+func (rr *readRepairer) acquire(key object.EncryptionKey, n uint64) bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if _, ok := rr.inflight[key]; ok {
+		rr.stats.Skipped++
+		return false
+	}
+
+	if rr.maxBytes > 0 {
+		if now := time.Now(); now.Sub(rr.windowStart) >= rr.interval {
+			rr.windowStart = now
+			rr.used = 0
+		}
+		if rr.used+n > rr.maxBytes {
+			rr.stats.Skipped++
+			return false
+		}
+		rr.used += n
+	}
+
+	rr.inflight[key] = struct{}{}
+	return true
+}
+
+func (rr *readRepairer) repair(s object.Slab, shards [][]byte, unhealthy []int, size uint64) error {
+	ctx, cancel := context.WithTimeout(rr.w.shutdownCtx, readRepairTimeout)
+	defer cancel()
+
+	// fetch host & contract info
+	contracts, err := rr.w.hostContracts(ctx)
+	if err != nil {
+		return err
+	}
+
+	// fetch upload params
+	up, err := rr.w.bus.UploadParams(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't fetch upload params from bus: %w", err)
+	}
+
+	// attach gouging checker to the context
+	ctx = gouging.WithChecker(ctx, rr.w.bus, up.GougingParams)
+
+	// filter out the hosts that already store a shard of the slab, including
+	// the ones that store the unhealthy shards
+	used := make(map[types.PublicKey]struct{})
+	for _, shard := range s.Shards {
+		for hk := range shard.Contracts {
+			used[hk] = struct{}{}
+		}
+	}
+	var allowed []upload.HostInfo
+	for _, h := range contracts {
+		if _, ok := used[h.PublicKey]; !ok {
+			allowed = append(allowed, h)
+			used[h.PublicKey] = struct{}{}
+		}
+	}
+
+	// acquire memory for the repair
+	mem := rr.w.uploadManager.AcquireMemory(ctx, size)
+	if mem == nil {
+		return fmt.Errorf("failed to acquire memory for read-repair")
+	}
+	defer mem.Release()
+
+	// upload the unhealthy shards, this updates the slab in the bus
+	_, err = rr.w.uploadManager.RepairShards(ctx, s, unhealthy, shards, allowed, up.CurrentHeight, mem)
+	return err
+}
@@ -173,7 +173,7 @@ func (s *s3) BucketExists(ctx context.Context, name string) (bool, error) {
 // TODO: This check is not atomic. The backend needs to be updated to support
 // atomically checking whether a bucket is empty.
 func (s *s3) DeleteBucket(ctx context.Context, name string) error {
-	err := s.b.DeleteBucket(ctx, name)
+	err := s.b.DeleteBucket(ctx, name, api.DeleteBucketOptions{})
 	if utils.IsErr(err, api.ErrBucketNotEmpty) {
 		return gofakes3.ErrBucketNotEmpty
 	} else if utils.IsErr(err, api.ErrBucketNotFound) {
@@ -315,7 +315,7 @@ func (s *s3) HeadObject(ctx context.Context, bucketName, key string) (*gofakes3.
 //	delete marker, which becomes the latest version of the object. If there
 //	isn't a null version, Amazon S3 does not remove any objects.
 func (s *s3) DeleteObject(ctx context.Context, bucketName, key string) (gofakes3.ObjectDeleteResult, error) {
-	err := s.b.DeleteObject(ctx, bucketName, key)
+	err := s.b.DeleteObject(ctx, bucketName, key, api.DeleteObjectOptions{})
 	if utils.IsErr(err, api.ErrBucketNotFound) {
 		return gofakes3.ObjectDeleteResult{}, gofakes3.BucketNotFound(bucketName)
 	} else if utils.IsErr(err, api.ErrObjectNotFound) {
@@ -356,7 +356,7 @@ func (s *s3) PutObject(ctx context.Context, bucketName, key string, meta map[str
 func (s *s3) DeleteMulti(ctx context.Context, bucketName string, objects ...string) (gofakes3.MultiDeleteResult, error) {
 	var res gofakes3.MultiDeleteResult
 	for _, key := range objects {
-		err := s.b.DeleteObject(ctx, bucketName, key)
+		err := s.b.DeleteObject(ctx, bucketName, key, api.DeleteObjectOptions{})
 		if err != nil && !utils.IsErr(err, api.ErrObjectNotFound) {
 			res.Error = append(res.Error, gofakes3.ErrorResult{
 				Key:     key,
@@ -455,7 +455,9 @@ func (s *s3) ListMultipartUploads(ctx context.Context, bucket string, marker *go
 
 func (s *s3) ListParts(ctx context.Context, bucket, object string, uploadID gofakes3.UploadID, marker int, limit int64) (*gofakes3.ListMultipartUploadPartsResult, error) {
 	resp, err := s.b.MultipartUploadParts(ctx, bucket, "/"+object, string(uploadID), marker, limit)
-	if err != nil {
+	if utils.IsErr(err, api.ErrMultipartUploadNotFound) {
+		return nil, gofakes3.ErrNoSuchUpload
+	} else if err != nil {
 		return nil, gofakes3.ErrorMessage(gofakes3.ErrInternal, err.Error())
 	}
 	var parts []gofakes3.ListMultipartUploadPartItem
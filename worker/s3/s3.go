@@ -27,12 +27,12 @@ type Opts struct {
 type Bus interface {
 	Bucket(ctx context.Context, bucketName string) (api.Bucket, error)
 	CreateBucket(ctx context.Context, bucketName string, opts api.CreateBucketOptions) error
-	DeleteBucket(ctx context.Context, bucketName string) error
+	DeleteBucket(ctx context.Context, bucketName string, opts api.DeleteBucketOptions) error
 	ListBuckets(ctx context.Context) (buckets []api.Bucket, err error)
 
 	AddObject(ctx context.Context, bucket, key string, o object.Object, opts api.AddObjectOptions) (err error)
-	CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey string, opts api.CopyObjectOptions) (om api.ObjectMetadata, err error)
-	DeleteObject(ctx context.Context, bucket, key string) (err error)
+	CopyObject(ctx context.Context, srcBucket, dstBucket, srcKey, dstKey string, opts api.CopyObjectOptions) (resp api.CopyObjectResponse, err error)
+	DeleteObject(ctx context.Context, bucket, key string, opts api.DeleteObjectOptions) (err error)
 	Objects(ctx context.Context, prefix string, opts api.ListObjectOptions) (resp api.ObjectsResponse, err error)
 
 	AbortMultipartUpload(ctx context.Context, bucket, key string, uploadID string) (err error)
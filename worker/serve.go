@@ -59,6 +59,9 @@ func serveContent(rw http.ResponseWriter, req *http.Request, name string, conten
 	// set content type and etag
 	rw.Header().Set("Content-Type", hor.ContentType)
 	rw.Header().Set("ETag", api.FormatETag(hor.Etag))
+	if hor.ContentDisposition != "" {
+		rw.Header().Set("Content-Disposition", hor.ContentDisposition)
+	}
 
 	// set the user metadata headers
 	for k, v := range hor.Metadata {
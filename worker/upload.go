@@ -1,19 +1,27 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
+	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/download"
 	"go.sia.tech/renterd/internal/gouging"
 	"go.sia.tech/renterd/internal/memory"
 	"go.sia.tech/renterd/internal/upload"
+	"go.sia.tech/renterd/object"
 	"go.uber.org/zap"
 )
 
@@ -22,36 +30,29 @@ const (
 	defaultPackedSlabsUploadTimeout = 10 * time.Minute
 )
 
-func (w *Worker) upload(ctx context.Context, bucket, key string, rs api.RedundancySettings, r io.Reader, hosts []upload.HostInfo, opts ...upload.Option) (_ string, err error) {
+func (w *Worker) upload(ctx context.Context, bucket, key string, rs api.RedundancySettings, r io.Reader, hosts []upload.HostInfo, opts ...upload.Option) (_ string, _ api.UploadID, err error) {
 	// apply the options
 	up := upload.DefaultParameters(bucket, key, rs)
 	for _, opt := range opts {
 		opt(&up)
 	}
 
-	// if not given, try decide on a mime type using the file extension
-	if !up.Multipart && up.MimeType == "" {
-		up.MimeType = mime.TypeByExtension(filepath.Ext(up.Key))
-
-		// if mime type is still not known, wrap the reader with a mime reader
-		if up.MimeType == "" {
-			up.MimeType, r, err = upload.NewMimeReader(r)
-			if err != nil {
-				return
-			}
-		}
+	// if not given, try decide on a mime type
+	r, err = detectMimeType(&up, r)
+	if err != nil {
+		return "", api.UploadID{}, err
 	}
 
 	// perform the upload
-	bufferSizeLimitReached, eTag, err := w.uploadManager.Upload(ctx, r, hosts, up)
+	bufferSizeLimitReached, eTag, uID, err := w.uploadManager.Upload(ctx, r, hosts, up)
 	if err != nil {
-		return "", err
+		return "", api.UploadID{}, err
 	}
 
 	// return early if worker was shut down or if we don't have to consider
 	// packed uploads
 	if w.isStopped() || !up.Packing {
-		return eTag, nil
+		return eTag, uID, nil
 	}
 
 	// try and upload one slab synchronously
@@ -76,10 +77,83 @@ func (w *Worker) upload(ctx context.Context, bucket, key string, rs api.Redundan
 	// make sure there's a goroutine uploading any packed slabs
 	go w.threadedUploadPackedSlabs(up.RS)
 
-	return eTag, nil
+	return eTag, uID, nil
+}
+
+// bufferObject adds the object to the partial slab buffers without uploading
+// it to hosts, it's used when there aren't enough hosts to upload to. The
+// buffered slabs are uploaded once there are.
+func (w *Worker) bufferObject(ctx context.Context, bucket, key string, rs api.RedundancySettings, r io.Reader, opts ...upload.Option) (_ string, _ api.UploadID, err error) {
+	// apply the options
+	up := upload.DefaultParameters(bucket, key, rs)
+	for _, opt := range opts {
+		opt(&up)
+	}
+
+	// if not given, try decide on a mime type
+	r, err = detectMimeType(&up, r)
+	if err != nil {
+		return "", api.UploadID{}, err
+	}
+
+	// buffer the object
+	eTag, uID, err := w.uploadManager.BufferObject(ctx, r, up)
+	if err != nil {
+		return "", api.UploadID{}, err
+	}
+	w.logger.Warnw("object was buffered because there aren't enough hosts to upload it to", "bucket", bucket, "key", key)
+	return eTag, uID, nil
+}
+
+// detectMimeType sets the mime type of the upload using the key's file
+// extension if it wasn't given, if that fails the returned reader sniffs the
+// mime type from the data.
+func detectMimeType(up *upload.Parameters, r io.Reader) (_ io.Reader, err error) {
+	if up.Multipart || up.MimeType != "" {
+		return r, nil
+	}
+
+	up.MimeType = mime.TypeByExtension(filepath.Ext(up.Key))
+	if up.MimeType == "" {
+		up.MimeType, r, err = upload.NewMimeReader(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// verifyUpload downloads the given object from the hosts and compares the hash
+// of its data to the ETag that was computed while uploading it. Objects
+// encrypted with a customer key require the same key to be downloaded.
+func (w *Worker) verifyUpload(ctx context.Context, o object.Object, eTag string, customerKey *[32]byte) error {
+	hosts, err := w.cache.UsableHosts(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't fetch usable hosts: %w", err)
+	}
+
+	var opts []download.Option
+	if customerKey != nil {
+		opts = append(opts, download.WithCustomerKey(*customerKey))
+	}
+
+	// NOTE: the ETag is the md5 hash of the object's data
+	h := md5.New()
+	if err := w.downloadManager.DownloadObject(ctx, h, o, 0, uint64(o.TotalSize()), hosts, opts...); err != nil {
+		return fmt.Errorf("%w: couldn't download object: %v", api.ErrUploadVerificationFailed, err)
+	} else if downloaded := hex.EncodeToString(h.Sum(nil)); downloaded != eTag {
+		return fmt.Errorf("%w: downloaded data has ETag %q, expected %q", api.ErrUploadVerificationFailed, downloaded, eTag)
+	}
+	return nil
 }
 
 func (w *Worker) threadedUploadPackedSlabs(rs api.RedundancySettings) {
+	// acquiring memory would block forever if a slab doesn't fit
+	if err := w.uploadManager.CheckSlabMemory(rs); err != nil {
+		w.logger.Errorf("couldn't upload packed slabs: %v", err)
+		return
+	}
+
 	key := fmt.Sprintf("%d-%d", rs.MinShards, rs.TotalShards)
 	w.uploadsMu.Lock()
 	if _, ok := w.uploadingPackedSlabs[key]; ok {
@@ -145,6 +219,92 @@ func (w *Worker) threadedUploadPackedSlabs(rs api.RedundancySettings) {
 	wg.Wait()
 }
 
+// flushSlabBuffers forces all slab buffers to be uploaded, regardless of how
+// full they are. It blocks until all buffers that existed when it was called
+// are uploaded, either by this call or by a background upload, or until the
+// context is done. Slabs that fail to upload are reported in the response.
+// The buffers are only marked as complete once, waiting for buffers that are
+// uploaded in the background doesn't complete buffers that were created
+// afterwards.
+func (w *Worker) flushSlabBuffers(ctx context.Context) (resp api.SlabBuffersFlushResponse, _ error) {
+	// mark all buffers as complete
+	buffers, err := w.bus.CompleteSlabBuffers(ctx)
+	if err != nil {
+		return api.SlabBuffersFlushResponse{}, fmt.Errorf("couldn't mark slab buffers as complete: %w", err)
+	}
+
+	// keep track of the buffers we need to flush
+	pending := make(map[string]struct{})
+	groups := make(map[api.RedundancySettings]struct{})
+	for _, buffer := range buffers {
+		pending[buffer.Filename] = struct{}{}
+		groups[api.RedundancySettings{MinShards: int(buffer.MinShards), TotalShards: int(buffer.TotalShards)}] = struct{}{}
+	}
+
+	for len(pending) > 0 {
+		// upload packed slabs until there are none left, slabs that failed
+		// to upload remain locked so they are not handed out again
+		for rs := range groups {
+			if err := w.uploadManager.CheckSlabMemory(rs); err != nil {
+				return resp, err
+			}
+			for {
+				mem := w.uploadManager.AcquireMemory(ctx, rs.SlabSize())
+				if mem == nil {
+					return resp, errors.New("couldn't acquire memory to upload packed slab")
+				}
+
+				packedSlabs, err := w.bus.PackedSlabsForUpload(ctx, defaultPackedSlabsLockDuration, uint8(rs.MinShards), uint8(rs.TotalShards), 1)
+				if err != nil {
+					mem.Release()
+					return resp, fmt.Errorf("couldn't fetch packed slabs from bus: %w", err)
+				} else if len(packedSlabs) == 0 {
+					mem.Release()
+					break
+				}
+
+				err = w.uploadPackedSlab(ctx, mem, packedSlabs[0], rs)
+				mem.Release()
+				if err != nil {
+					if resp.Errors == nil {
+						resp.Errors = make(map[string]string)
+					}
+					resp.Errors[packedSlabs[0].EncryptionKey.String()] = err.Error()
+				} else {
+					resp.Flushed++
+				}
+			}
+		}
+		if len(resp.Errors) > 0 {
+			return resp, nil
+		}
+
+		// check which buffers remain, those are being uploaded in the
+		// background
+		buffers, err := w.bus.SlabBuffers(ctx)
+		if err != nil {
+			return resp, fmt.Errorf("couldn't fetch slab buffers: %w", err)
+		}
+		remaining := make(map[string]struct{})
+		for _, buffer := range buffers {
+			if _, ok := pending[buffer.Filename]; ok {
+				remaining[buffer.Filename] = struct{}{}
+			}
+		}
+		pending = remaining
+		if len(pending) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return resp, context.Cause(ctx)
+		case <-time.After(time.Second):
+		}
+	}
+	return resp, nil
+}
+
 func (w *Worker) hostContracts(ctx context.Context) (hosts []upload.HostInfo, _ error) {
 	usableHosts, err := w.bus.UsableHosts(ctx)
 	if err != nil {
@@ -174,6 +334,170 @@ func (w *Worker) hostContracts(ctx context.Context) (hosts []upload.HostInfo, _
 	return
 }
 
+// hostUploadPrices contains the prices a host charges for storing and
+// uploading data, used to estimate the cost of an upload.
+type hostUploadPrices struct {
+	hostKey types.PublicKey
+	period  uint64
+
+	storagePrice types.Currency // per byte per block
+	uploadPrice  types.Currency // per byte
+}
+
+// estimateUploadCost estimates the cost of uploading an object of given size
+// with given redundancy settings to the hosts the worker has usable contracts
+// with.
+func (w *Worker) estimateUploadCost(ctx context.Context, size uint64, rs api.RedundancySettings, bh uint64) (api.UploadCostEstimateResponse, error) {
+	contracts, err := w.hostContracts(ctx)
+	if err != nil {
+		return api.UploadCostEstimateResponse{}, err
+	}
+
+	// storage is paid for until the end of the contract, if a host has
+	// multiple contracts we assume the one that lasts the longest is used
+	endHeights := make(map[types.PublicKey]uint64)
+	for _, c := range contracts {
+		if c.ContractEndHeight > endHeights[c.PublicKey] {
+			endHeights[c.PublicKey] = c.ContractEndHeight
+		}
+	}
+
+	var prices []hostUploadPrices
+	for hk, endHeight := range endHeights {
+		if endHeight <= bh {
+			continue // contract expired
+		}
+
+		h, err := w.bus.Host(ctx, hk)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return api.UploadCostEstimateResponse{}, err
+		} else if err != nil {
+			w.logger.Debugw("failed to fetch host for upload cost estimate", "hk", hk, zap.Error(err))
+			continue
+		}
+
+		hp := hostUploadPrices{hostKey: hk, period: endHeight - bh}
+		if h.IsV2() {
+			hp.storagePrice = h.V2Settings.Prices.StoragePrice
+			hp.uploadPrice = h.V2Settings.Prices.IngressPrice
+		} else {
+			var overflow bool
+			hp.storagePrice = h.PriceTable.WriteStoreCost
+			hp.uploadPrice, overflow = gouging.UploadPricePerByte(h.PriceTable.HostPriceTable)
+			if overflow {
+				w.logger.Debugw("upload price overflows", "hk", hk)
+				continue
+			}
+		}
+		prices = append(prices, hp)
+	}
+	return estimateUploadCost(size, rs, prices)
+}
+
+// estimateUploadCost distributes the sectors required to upload an object of
+// given size evenly over the given hosts and returns the cost of storing and
+// uploading them.
+func estimateUploadCost(size uint64, rs api.RedundancySettings, hosts []hostUploadPrices) (api.UploadCostEstimateResponse, error) {
+	if len(hosts) < rs.TotalShards {
+		return api.UploadCostEstimateResponse{}, fmt.Errorf("%w: %d hosts < %d total shards", upload.ErrUploadNotEnoughHosts, len(hosts), rs.TotalShards)
+	}
+
+	// sort the hosts to make the estimate deterministic
+	hosts = append([]hostUploadPrices(nil), hosts...)
+	sort.Slice(hosts, func(i, j int) bool {
+		return bytes.Compare(hosts[i].hostKey[:], hosts[j].hostKey[:]) < 0
+	})
+
+	// every slab holds minShards sectors worth of data and is uploaded as
+	// totalShards sectors
+	slabSize := uint64(rs.MinShards) * rhpv2.SectorSize
+	slabs := (size + slabSize - 1) / slabSize
+	sectors := slabs * uint64(rs.TotalShards)
+
+	estimate := api.UploadCostEstimateResponse{
+		Size:        size,
+		MinShards:   rs.MinShards,
+		TotalShards: rs.TotalShards,
+		Sectors:     sectors,
+	}
+	for i, h := range hosts {
+		n := sectors / uint64(len(hosts))
+		if uint64(i) < sectors%uint64(len(hosts)) {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+
+		dataSize := n * rhpv2.SectorSize
+		storage, overflow := h.storagePrice.Mul64WithOverflow(dataSize)
+		if !overflow {
+			storage, overflow = storage.Mul64WithOverflow(h.period)
+		}
+		if overflow {
+			return api.UploadCostEstimateResponse{}, fmt.Errorf("storage cost of host %v overflows", h.hostKey)
+		}
+		ingress, overflow := h.uploadPrice.Mul64WithOverflow(dataSize)
+		if overflow {
+			return api.UploadCostEstimateResponse{}, fmt.Errorf("upload cost of host %v overflows", h.hostKey)
+		}
+
+		estimate.Hosts = append(estimate.Hosts, api.HostUploadCost{
+			HostKey: h.hostKey,
+			Sectors: n,
+			Period:  h.period,
+			Storage: storage,
+			Upload:  ingress,
+			Total:   storage.Add(ingress),
+		})
+		estimate.Storage = estimate.Storage.Add(storage)
+		estimate.Upload = estimate.Upload.Add(ingress)
+	}
+	estimate.Total = estimate.Storage.Add(estimate.Upload)
+	return estimate, nil
+}
+
+// pinnedHostContracts filters the given contracts down to the ones with the
+// pinned hosts, it returns an error if there aren't enough pinned hosts to
+// upload 'totalShards' shards.
+func pinnedHostContracts(contracts []upload.HostInfo, pinned []types.PublicKey, totalShards int) (filtered []upload.HostInfo, _ error) {
+	pinnedMap := make(map[types.PublicKey]struct{})
+	for _, hk := range pinned {
+		pinnedMap[hk] = struct{}{}
+	}
+
+	hosts := make(map[types.PublicKey]struct{})
+	for _, c := range contracts {
+		if _, ok := pinnedMap[c.PublicKey]; ok {
+			filtered = append(filtered, c)
+			hosts[c.PublicKey] = struct{}{}
+		}
+	}
+	if len(hosts) < totalShards {
+		return nil, fmt.Errorf("%w: %d usable pinned hosts < %d total shards", api.ErrInsufficientPinnedHosts, len(hosts), totalShards)
+	}
+	return filtered, nil
+}
+
+// checkDistinctHosts returns an error if the given contracts don't cover
+// enough distinct hosts to upload 'totalShards' shards to different hosts, or
+// if they cover fewer hosts than the configured minimum.
+func checkDistinctHosts(contracts []upload.HostInfo, totalShards int, minDistinct uint64) error {
+	hosts := make(map[types.PublicKey]struct{})
+	for _, c := range contracts {
+		hosts[c.PublicKey] = struct{}{}
+	}
+
+	required := uint64(totalShards)
+	if minDistinct > required {
+		required = minDistinct
+	}
+	if uint64(len(hosts)) < required {
+		return fmt.Errorf("%w: %d distinct hosts < %d required, short by %d", api.ErrInsufficientDistinctHosts, len(hosts), required, required-uint64(len(hosts)))
+	}
+	return nil
+}
+
 func (w *Worker) uploadPackedSlab(ctx context.Context, mem memory.Memory, ps api.PackedSlab, rs api.RedundancySettings) error {
 	// fetch host & contract info
 	contracts, err := w.hostContracts(ctx)
@@ -3,14 +3,20 @@ package worker
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/breaker"
 	"go.sia.tech/renterd/internal/download"
 	"go.sia.tech/renterd/internal/test"
 	"go.sia.tech/renterd/internal/upload"
@@ -42,7 +48,7 @@ func TestUpload(t *testing.T) {
 	params := testParameters(t.Name())
 
 	// upload data
-	_, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	_, _, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -123,7 +129,7 @@ func TestUpload(t *testing.T) {
 
 	// try and upload into a bucket that does not exist
 	params.Bucket = "doesnotexist"
-	_, _, err = ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	_, _, _, err = ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
 	if !errors.Is(err, api.ErrBucketNotFound) {
 		t.Fatal("expected bucket not found error", err)
 	}
@@ -131,12 +137,248 @@ func TestUpload(t *testing.T) {
 	// upload data using a cancelled context - assert we don't hang
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	_, _, err = ul.Upload(ctx, bytes.NewReader(data), w.UploadHosts(), params)
+	_, _, _, err = ul.Upload(ctx, bytes.NewReader(data), w.UploadHosts(), params)
 	if err == nil || !errors.Is(err, upload.ErrUploadCancelled) {
 		t.Fatal(err)
 	}
 }
 
+func TestObjectRecoverability(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// upload an object
+	params := testParameters(t.Name())
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the object is recoverable
+	res, err := w.ObjectRecoverability(context.Background(), testBucket, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if !res.Recoverable || len(res.Slabs) != 1 {
+		t.Fatalf("unexpected response %+v", res)
+	} else if sr := res.Slabs[0]; !sr.Recoverable || sr.AvailableShards != uint8(testRedundancySettings.TotalShards) || sr.MinShards != uint8(testRedundancySettings.MinShards) {
+		t.Fatalf("unexpected slab %+v", sr)
+	}
+
+	// delete the contracts until we're one shard short of the minimum
+	o, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := o.Object.Slabs[0].Shards
+	for _, shard := range shards[:len(shards)-testRedundancySettings.MinShards+1] {
+		for _, fcids := range shard.Contracts {
+			for _, fcid := range fcids {
+				w.cs.DeleteContracdt(fcid)
+			}
+		}
+	}
+
+	// assert the object is no longer recoverable
+	res, err = w.ObjectRecoverability(context.Background(), testBucket, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Recoverable || res.Slabs[0].Recoverable {
+		t.Fatalf("unexpected response %+v", res)
+	} else if res.Slabs[0].AvailableShards != uint8(testRedundancySettings.MinShards-1) {
+		t.Fatalf("unexpected number of available shards %d", res.Slabs[0].AvailableShards)
+	}
+}
+
+func TestUploadVerification(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	verify := func(ctx context.Context, o object.Object, eTag string) error {
+		return w.verifyUpload(ctx, o, eTag, nil)
+	}
+
+	// upload an object with verification
+	data := frand.Bytes(128)
+	eTag, _, err := w.upload(context.Background(), testBucket, t.Name(), testRedundancySettings, bytes.NewReader(data), w.UploadHosts(), upload.WithVerification(verify))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the object verifies against its ETag but not against another one
+	res, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if err := w.verifyUpload(context.Background(), *res.Object, eTag, nil); err != nil {
+		t.Fatal(err)
+	} else if err := w.verifyUpload(context.Background(), *res.Object, hex.EncodeToString(make([]byte, 16)), nil); !errors.Is(err, api.ErrUploadVerificationFailed) {
+		t.Fatalf("expected ErrUploadVerificationFailed, got %v", err)
+	}
+
+	// assert an object that fails verification isn't persisted
+	failing := func(ctx context.Context, o object.Object, eTag string) error {
+		return verify(ctx, o, hex.EncodeToString(make([]byte, 16)))
+	}
+	_, _, err = w.upload(context.Background(), testBucket, t.Name()+"failed", testRedundancySettings, bytes.NewReader(data), w.UploadHosts(), upload.WithVerification(failing))
+	if !errors.Is(err, api.ErrUploadVerificationFailed) {
+		t.Fatalf("expected ErrUploadVerificationFailed, got %v", err)
+	} else if _, err := w.os.Object(context.Background(), testBucket, t.Name()+"failed", api.GetObjectOptions{}); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	// assert verification can't be combined with async uploads
+	_, err = w.UploadObject(context.Background(), bytes.NewReader(data), testBucket, t.Name(), api.UploadObjectOptions{Durability: api.UploadDurabilityAsync, Verify: true})
+	if !errors.Is(err, api.ErrUploadVerificationAsync) {
+		t.Fatalf("expected ErrUploadVerificationAsync, got %v", err)
+	}
+}
+
+func TestUploadAsyncDurability(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// upload data asynchronously
+	params := testParameters(t.Name())
+	params.Durability = api.UploadDurabilityAsync
+	_, _, uID, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the object is eventually persisted
+	tt := test.NewTT(t)
+	tt.Retry(100, 10*time.Millisecond, func() error {
+		status, ok := w.uploadManager.PersistenceStatus(uID)
+		if !ok {
+			return errors.New("no persistence status")
+		} else if status.Status != api.UploadPersistenceSucceeded {
+			return fmt.Errorf("unexpected status %v", status.Status)
+		} else if status.Attempts != 1 {
+			return fmt.Errorf("unexpected attempts %v", status.Attempts)
+		}
+		return nil
+	})
+	if _, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// upload to a bucket that doesn't exist and assert persisting the object
+	// fails without retrying
+	params = testParameters(t.Name())
+	params.Bucket = "nonexistent"
+	params.Durability = api.UploadDurabilityAsync
+	_, _, uID, err = w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.Retry(100, 10*time.Millisecond, func() error {
+		status, ok := w.uploadManager.PersistenceStatus(uID)
+		if !ok {
+			return errors.New("no persistence status")
+		} else if status.Status != api.UploadPersistenceFailed {
+			return fmt.Errorf("unexpected status %v", status.Status)
+		} else if status.Attempts != 1 || status.Error == "" {
+			return fmt.Errorf("unexpected status %+v", status)
+		}
+		return nil
+	})
+
+	// assert synchronous uploads don't track a persistence status
+	_, _, uID, err = w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), testParameters(t.Name()+"sync"))
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := w.uploadManager.PersistenceStatus(uID); ok {
+		t.Fatal("expected no persistence status")
+	}
+}
+
+func TestDownloadMaxHostsPerSlab(t *testing.T) {
+	// create test worker that tries at most 3 hosts per slab
+	cfg := newTestWorkerCfg()
+	cfg.DownloadMaxHostsPerSlab = 3
+	w := newTestWorker(t, cfg)
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// upload data
+	params := testParameters(t.Name())
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// grab the object
+	o, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt all but one shard
+	for i := 1; i < len(o.Object.Slabs[0].Shards); i++ {
+		o.Object.Slabs[0].Shards[i].Root = frand.Entropy256()
+	}
+
+	// assert the download fails after trying 3 hosts
+	var buf bytes.Buffer
+	err = w.downloadManager.DownloadObject(context.Background(), &buf, *o.Object, 0, uint64(o.Size), w.UsableHosts())
+	if !errors.Is(err, download.ErrInsufficientShardsRecovered) {
+		t.Fatal("expected insufficient shards error", err)
+	} else if !errors.Is(err, download.ErrMaxHostsPerSlabReached) || !strings.Contains(err.Error(), "launched=3") {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestUploadCustomerKey(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// create test data and customer key
+	data := frand.Bytes(128)
+	var customerKey [32]byte
+	frand.Read(customerKey[:])
+
+	// upload data with the customer key
+	params := testParameters(t.Name())
+	upload.WithCustomerKey(customerKey)(&params)
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// grab the object and assert the customer key wasn't persisted
+	o, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if o.Object.Key.Type() != object.EncryptionKeyTypeCustomer {
+		t.Fatal("unexpected key type", o.Object.Key.Type())
+	} else if bytes.Contains([]byte(o.Object.Key.String()), []byte(fmt.Sprintf("%x", customerKey[:]))) {
+		t.Fatal("customer key was persisted")
+	}
+
+	// downloading without the customer key fails
+	var buf bytes.Buffer
+	err = w.downloadManager.DownloadObject(context.Background(), &buf, *o.Object, 0, uint64(o.Size), w.UsableHosts())
+	if !errors.Is(err, object.ErrMissingEncryptionKey) {
+		t.Fatal("expected ErrMissingEncryptionKey, got", err)
+	}
+
+	// download the data with the customer key and assert it matches
+	err = w.downloadManager.DownloadObject(context.Background(), &buf, *o.Object, 0, uint64(o.Size), w.UsableHosts(), download.WithCustomerKey(customerKey))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("data mismatch")
+	}
+}
+
 func TestUploadPackedSlab(t *testing.T) {
 	// create test worker
 	w := newTestWorker(t, newTestWorkerCfg())
@@ -158,7 +400,7 @@ func TestUploadPackedSlab(t *testing.T) {
 	data := frand.Bytes(128)
 
 	// upload data
-	_, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	_, _, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -226,51 +468,456 @@ func TestUploadPackedSlab(t *testing.T) {
 		return cnt
 	}
 
-	// define a helper that uploads data using the worker
-	var c int
-	uploadBytes := func(n int) {
-		t.Helper()
-		params.Key = fmt.Sprintf("%s_%d", t.Name(), c)
-		_, err := w.upload(context.Background(), params.Bucket, params.Key, testRedundancySettings, bytes.NewReader(frand.Bytes(n)), w.UploadHosts(), upload.WithPacking(true))
-		if err != nil {
-			t.Fatal(err)
-		}
-		c++
+	// define a helper that uploads data using the worker
+	var c int
+	uploadBytes := func(n int) {
+		t.Helper()
+		params.Key = fmt.Sprintf("%s_%d", t.Name(), c)
+		_, _, err := w.upload(context.Background(), params.Bucket, params.Key, testRedundancySettings, bytes.NewReader(frand.Bytes(n)), w.UploadHosts(), upload.WithPacking(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c++
+	}
+
+	// block aysnc packed slab uploads
+	w.BlockAsyncPackedSlabUploads(params)
+
+	// configure max buffer size
+	os.SetSlabBufferMaxSizeSoft(128)
+
+	// upload 2x64 bytes using the worker and assert we still have two packed
+	// slabs (buffer limit not reached)
+	uploadBytes(64)
+	uploadBytes(64)
+	if packedSlabsCount() != 2 {
+		t.Fatal("expected 2 packed slabs")
+	}
+
+	// upload one more byte and assert we still have two packed slabs (one got
+	// uploaded synchronously because buffer limit was reached)
+	uploadBytes(1)
+	if packedSlabsCount() != 2 {
+		t.Fatal("expected 2 packed slabs")
+	}
+
+	// unblock asynchronous uploads
+	w.UnblockAsyncPackedSlabUploads(params)
+	uploadBytes(129) // ensure background thread is running
+
+	// assert packed slabs get uploaded asynchronously
+	if err := test.Retry(100, 100*time.Millisecond, func() error {
+		if packedSlabsCount() != 0 {
+			return errors.New("expected 0 packed slabs")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBufferObject(t *testing.T) {
+	// create test worker without any hosts
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// buffer an object that spans more than one slab
+	data := frand.Bytes(int(testRedundancySettings.SlabSizeNoRedundancy()) + 1)
+	eTag, _, err := w.bufferObject(context.Background(), testBucket, t.Name(), testRedundancySettings, bytes.NewReader(data), upload.WithPacking(true))
+	if err != nil {
+		t.Fatal(err)
+	} else if eTag == "" {
+		t.Fatal("expected etag to be set")
+	}
+
+	// assert all of its data was buffered
+	if n := w.os.NumPartials(); n != 2 {
+		t.Fatal("expected 2 packed slabs", n)
+	}
+
+	// assert the object can be downloaded from the buffers
+	o, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = w.downloadManager.DownloadObject(context.Background(), &buf, *o.Object, 0, uint64(len(data)), w.UsableHosts())
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("data mismatch")
+	}
+
+	// assert buffering stops once the buffers exceed their soft limit
+	w.os.SetSlabBufferMaxSizeSoft(1)
+	_, _, err = w.bufferObject(context.Background(), testBucket, t.Name()+"2", testRedundancySettings, bytes.NewReader(data), upload.WithPacking(true))
+	if !errors.Is(err, api.ErrSlabBufferFull) {
+		t.Fatal("expected ErrSlabBufferFull", err)
+	} else if n := w.os.NumPartials(); n != 3 {
+		t.Fatal("expected 3 packed slabs", n)
+	}
+
+	// assert objects that fit are still buffered
+	eTag, _, err = w.bufferObject(context.Background(), testBucket, t.Name()+"3", testRedundancySettings, bytes.NewReader(data[:1]), upload.WithPacking(true))
+	if err != nil {
+		t.Fatal(err)
+	} else if eTag == "" {
+		t.Fatal("expected etag to be set")
+	}
+}
+
+func TestUploadPartialSlabBufferFull(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// make the slab buffers reject partial slabs
+	w.os.SetSlabBufferFull(true)
+
+	// upload data with packing enabled
+	params := testParameters(t.Name())
+	params.Packing = true
+	data := frand.Bytes(128)
+	bufferSizeLimitReached, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bufferSizeLimitReached {
+		t.Fatal("expected buffer size limit to be reached")
+	}
+
+	// assert the partial slab was uploaded instead of buffered
+	if w.os.NumPartials() != 0 {
+		t.Fatal("expected no partial slabs")
+	}
+	o, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(o.Object.Slabs) != 1 || len(o.Object.Slabs[0].Shards) != testRedundancySettings.TotalShards {
+		t.Fatal("expected the slab to be uploaded")
+	}
+
+	// download the data and assert it matches
+	var buf bytes.Buffer
+	err = w.downloadManager.DownloadObject(context.Background(), &buf, *o.Object, 0, uint64(o.Size), w.UsableHosts())
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("data mismatch")
+	}
+}
+
+func TestUploadPersistRetry(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// prepare upload params
+	params := testParameters(t.Name())
+	params.PersistMaxAttempts = 2
+
+	// make the first attempt at persisting the object fail and assert the
+	// upload succeeds
+	w.os.SetAddObjectFailures(1)
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := w.os.Object(context.Background(), testBucket, params.Key, api.GetObjectOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// make all attempts fail and assert the upload fails with the right error
+	params.Key = t.Name() + "_failed"
+	w.os.SetAddObjectFailures(2)
+	_, _, _, err = w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if !errors.Is(err, upload.ErrPersistFailed) {
+		t.Fatal("expected ErrPersistFailed, got", err)
+	} else if _, err := w.os.Object(context.Background(), testBucket, params.Key, api.GetObjectOptions{}); !errors.Is(err, api.ErrObjectNotFound) {
+		t.Fatal("expected object not to exist, got", err)
+	}
+}
+
+func TestUploadResumeMultipartPart(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// prepare upload params for a part that spans a full and a partial slab
+	params := testParameters(t.Name())
+	params.Multipart = true
+	params.Packing = true
+	params.UploadID = hex.EncodeToString(frand.Bytes(32))
+	params.PartNumber = 1
+	params.PersistMaxAttempts = 1
+	data := frand.Bytes(int(testRedundancySettings.SlabSizeNoRedundancy()) + 128)
+
+	// make adding the part fail, as if the worker was restarted
+	w.os.SetAddMultipartPartFailures(1)
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	if !errors.Is(err, upload.ErrPersistFailed) {
+		t.Fatal("expected ErrPersistFailed, got", err)
+	} else if w.os.NumPartials() != 1 {
+		t.Fatal("expected one partial slab", w.os.NumPartials())
+	}
+	progress, err := w.os.MultipartPartProgress(context.Background(), testBucket, params.Key, params.UploadID, params.PartNumber)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(progress) != 2 {
+		t.Fatal("expected both slabs to be persisted", len(progress))
+	}
+
+	// upload the part again and assert the persisted slabs are reused
+	_, eTag, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	} else if eTag != fmt.Sprintf("%x", md5.Sum(data)) {
+		t.Fatal("unexpected etag", eTag)
+	} else if w.os.NumPartials() != 1 {
+		t.Fatal("expected the partial slab not to be buffered again", w.os.NumPartials())
+	}
+	slices, ok := w.os.MultipartPart(params.UploadID, params.PartNumber)
+	if !ok {
+		t.Fatal("expected part to be added")
+	} else if len(slices) != len(progress) {
+		t.Fatal("unexpected number of slices", len(slices))
+	}
+	for i := range slices {
+		if slices[i].EncryptionKey.String() != progress[i].Slice.EncryptionKey.String() {
+			t.Fatalf("slab %d was uploaded again", i)
+		}
+	}
+
+	// upload different data and assert it's not mistaken for the finished
+	// part, only the first attempt buffers the partial slab
+	data[0]++
+	w.os.SetAddMultipartPartFailures(1)
+	if _, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params); !errors.Is(err, upload.ErrPersistFailed) {
+		t.Fatal("expected ErrPersistFailed, got", err)
+	}
+	_, _, _, err = w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := w.os.MultipartPart(params.UploadID, params.PartNumber)
+	if len(updated) != 2 {
+		t.Fatal("unexpected number of slices", len(updated))
+	} else if updated[0].EncryptionKey.String() == slices[0].EncryptionKey.String() {
+		t.Fatal("expected modified slab to be uploaded again")
+	} else if w.os.NumPartials() != 2 {
+		t.Fatal("expected the partial slab to be buffered once", w.os.NumPartials())
+	}
+
+	// download the part and assert it matches
+	var buf bytes.Buffer
+	o := object.Object{Key: params.EC, Slabs: updated}
+	if err := w.downloadManager.DownloadObject(context.Background(), &buf, o, 0, uint64(len(data)), w.UsableHosts()); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("data mismatch")
+	}
+}
+
+func TestUploadPackedSlabReadAfterWrite(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// convenience variables
+	os := w.os
+	dl := w.downloadManager
+	ul := w.uploadManager
+
+	// create upload params
+	params := testParameters(t.Name())
+	params.Packing = true
+
+	// create test data that spans a full slab and a partial one
+	slabSize := params.RS.SlabSizeNoRedundancy()
+	data := frand.Bytes(int(slabSize) + 128)
+
+	// upload data
+	_, _, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the tail of the object is buffered
+	if os.NumPartials() != 1 {
+		t.Fatal("expected 1 partial slab")
+	}
+
+	// grab the object
+	o, err := os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(o.Object.Slabs) != 2 || o.Object.Slabs[0].IsPartial() || !o.Object.Slabs[1].IsPartial() {
+		t.Fatal("expected one uploaded and one buffered slab")
+	}
+
+	// download the object, as well as ranges within the buffered slab and
+	// ranges spanning both slabs, and assert the data matches
+	for _, r := range []struct{ offset, length uint64 }{
+		{0, uint64(len(data))},
+		{slabSize, 128},
+		{slabSize + 64, 32},
+		{slabSize - 64, 128},
+	} {
+		var buf bytes.Buffer
+		err = dl.DownloadObject(context.Background(), &buf, *o.Object, r.offset, r.length, w.UsableHosts())
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(data[r.offset:r.offset+r.length], buf.Bytes()) {
+			t.Fatalf("data mismatch for range %d-%d", r.offset, r.offset+r.length)
+		}
+	}
+}
+
+func TestFlushSlabBuffers(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// create upload params
+	params := testParameters(t.Name())
+	params.Packing = true
+
+	// upload two objects that end up in partial slabs
+	for i := 0; i < 2; i++ {
+		params.Key = fmt.Sprintf("%s_%d", t.Name(), i)
+		_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.os.NumPartials() != 2 {
+		t.Fatal("expected 2 partial slabs")
+	}
+
+	// flush the slab buffers
+	resp, err := w.flushSlabBuffers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Flushed != 2 {
+		t.Fatalf("expected 2 flushed slabs, got %v", resp.Flushed)
+	} else if len(resp.Errors) != 0 {
+		t.Fatalf("unexpected errors %v", resp.Errors)
+	} else if w.os.NumPartials() != 0 {
+		t.Fatal("expected no partial slabs")
+	}
+}
+
+func TestPinnedHostContracts(t *testing.T) {
+	hk1, hk2, hk3 := types.PublicKey{1}, types.PublicKey{2}, types.PublicKey{3}
+	contracts := []upload.HostInfo{
+		{HostInfo: api.HostInfo{PublicKey: hk1}, ContractID: types.FileContractID{1}},
+		{HostInfo: api.HostInfo{PublicKey: hk2}, ContractID: types.FileContractID{2}},
+		{HostInfo: api.HostInfo{PublicKey: hk3}, ContractID: types.FileContractID{3}},
+	}
+
+	// pin two hosts, one of which we don't have a contract with
+	pinned := []types.PublicKey{hk1, hk3, {4}}
+	filtered, err := pinnedHostContracts(contracts, pinned, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(filtered) != 2 || filtered[0].PublicKey != hk1 || filtered[1].PublicKey != hk3 {
+		t.Fatalf("unexpected contracts %+v", filtered)
+	}
+
+	// assert we can't satisfy 3 total shards
+	_, err = pinnedHostContracts(contracts, pinned, 3)
+	if !errors.Is(err, api.ErrInsufficientPinnedHosts) {
+		t.Fatalf("expected ErrInsufficientPinnedHosts, got %v", err)
+	}
+}
+
+func TestCheckDistinctHosts(t *testing.T) {
+	hk1, hk2 := types.PublicKey{1}, types.PublicKey{2}
+	contracts := []upload.HostInfo{
+		{HostInfo: api.HostInfo{PublicKey: hk1}, ContractID: types.FileContractID{1}},
+		{HostInfo: api.HostInfo{PublicKey: hk1}, ContractID: types.FileContractID{2}},
+		{HostInfo: api.HostInfo{PublicKey: hk2}, ContractID: types.FileContractID{3}},
+	}
+
+	// two distinct hosts are enough for two shards
+	if err := checkDistinctHosts(contracts, 2, 0); err != nil {
+		t.Fatal(err)
 	}
 
-	// block aysnc packed slab uploads
-	w.BlockAsyncPackedSlabUploads(params)
+	// three contracts but only two distinct hosts aren't enough for three shards
+	if err := checkDistinctHosts(contracts, 3, 0); !errors.Is(err, api.ErrInsufficientDistinctHosts) {
+		t.Fatalf("expected ErrInsufficientDistinctHosts, got %v", err)
+	} else if !strings.Contains(err.Error(), "2 distinct hosts < 3 required, short by 1") {
+		t.Fatalf("unexpected error %v", err)
+	}
 
-	// configure max buffer size
-	os.SetSlabBufferMaxSizeSoft(128)
+	// the configured minimum takes precedence over the number of shards
+	if err := checkDistinctHosts(contracts, 1, 4); !errors.Is(err, api.ErrInsufficientDistinctHosts) {
+		t.Fatalf("expected ErrInsufficientDistinctHosts, got %v", err)
+	} else if !strings.Contains(err.Error(), "short by 2") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
 
-	// upload 2x64 bytes using the worker and assert we still have two packed
-	// slabs (buffer limit not reached)
-	uploadBytes(64)
-	uploadBytes(64)
-	if packedSlabsCount() != 2 {
-		t.Fatal("expected 2 packed slabs")
+func TestEstimateUploadCost(t *testing.T) {
+	hosts := []hostUploadPrices{
+		{hostKey: types.PublicKey{3}, period: 10, storagePrice: types.NewCurrency64(1), uploadPrice: types.NewCurrency64(2)},
+		{hostKey: types.PublicKey{1}, period: 20, storagePrice: types.NewCurrency64(3), uploadPrice: types.NewCurrency64(4)},
+		{hostKey: types.PublicKey{2}, period: 30, storagePrice: types.NewCurrency64(5), uploadPrice: types.NewCurrency64(6)},
 	}
 
-	// upload one more byte and assert we still have two packed slabs (one got
-	// uploaded synchronously because buffer limit was reached)
-	uploadBytes(1)
-	if packedSlabsCount() != 2 {
-		t.Fatal("expected 2 packed slabs")
+	// assert we need at least as many hosts as total shards
+	_, err := estimateUploadCost(1, api.RedundancySettings{MinShards: 1, TotalShards: 4}, hosts)
+	if !errors.Is(err, upload.ErrUploadNotEnoughHosts) {
+		t.Fatalf("expected ErrUploadNotEnoughHosts, got %v", err)
 	}
 
-	// unblock asynchronous uploads
-	w.UnblockAsyncPackedSlabUploads(params)
-	uploadBytes(129) // ensure background thread is running
+	// 3 slabs of 2 sectors worth of data, uploaded as 9 sectors
+	rs := api.RedundancySettings{MinShards: 2, TotalShards: 3}
+	size := uint64(5 * rhpv2.SectorSize)
+	estimate, err := estimateUploadCost(size, rs, hosts)
+	if err != nil {
+		t.Fatal(err)
+	} else if estimate.Sectors != 9 {
+		t.Fatalf("expected 9 sectors, got %v", estimate.Sectors)
+	} else if len(estimate.Hosts) != 3 {
+		t.Fatalf("expected 3 hosts, got %v", len(estimate.Hosts))
+	}
 
-	// assert packed slabs get uploaded asynchronously
-	if err := test.Retry(100, 100*time.Millisecond, func() error {
-		if packedSlabsCount() != 0 {
-			return errors.New("expected 0 packed slabs")
+	// assert the breakdown is sorted by host key and every host stores 3
+	// sectors for the remainder of its contract
+	ss := uint64(rhpv2.SectorSize)
+	expected := []api.HostUploadCost{
+		{HostKey: types.PublicKey{1}, Sectors: 3, Period: 20, Storage: types.NewCurrency64(3 * ss * 3 * 20), Upload: types.NewCurrency64(3 * ss * 4)},
+		{HostKey: types.PublicKey{2}, Sectors: 3, Period: 30, Storage: types.NewCurrency64(3 * ss * 5 * 30), Upload: types.NewCurrency64(3 * ss * 6)},
+		{HostKey: types.PublicKey{3}, Sectors: 3, Period: 10, Storage: types.NewCurrency64(3 * ss * 1 * 10), Upload: types.NewCurrency64(3 * ss * 2)},
+	}
+	var storage, ingress types.Currency
+	for i, hc := range estimate.Hosts {
+		expected[i].Total = expected[i].Storage.Add(expected[i].Upload)
+		if hc != expected[i] {
+			t.Fatalf("unexpected host cost %d: %+v != %+v", i, hc, expected[i])
 		}
-		return nil
-	}); err != nil {
+		storage = storage.Add(hc.Storage)
+		ingress = ingress.Add(hc.Upload)
+	}
+	if !estimate.Storage.Equals(storage) || !estimate.Upload.Equals(ingress) || !estimate.Total.Equals(storage.Add(ingress)) {
+		t.Fatalf("unexpected totals %+v", estimate)
+	}
+
+	// assert the remainder is distributed over the first hosts
+	estimate, err = estimateUploadCost(1, api.RedundancySettings{MinShards: 1, TotalShards: 2}, hosts)
+	if err != nil {
 		t.Fatal(err)
+	} else if len(estimate.Hosts) != 2 || estimate.Hosts[0].Sectors != 1 || estimate.Hosts[1].Sectors != 1 {
+		t.Fatalf("unexpected hosts %+v", estimate.Hosts)
 	}
 }
 
@@ -294,7 +941,7 @@ func TestMigrateLostSector(t *testing.T) {
 	params := testParameters(t.Name())
 
 	// upload data
-	_, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	_, _, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -390,6 +1037,166 @@ func TestMigrateLostSector(t *testing.T) {
 	}
 }
 
+func TestRepairShards(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards * 2)
+
+	// convenience variables
+	os := w.os
+	mm := w.ulmm
+	dl := w.downloadManager
+	ul := w.uploadManager
+
+	// upload data
+	params := testParameters(t.Name())
+	_, _, _, err := ul.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// grab the slab
+	o, err := os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(o.Object.Slabs) != 1 {
+		t.Fatal("expected 1 slab")
+	}
+	slab := o.Object.Slabs[0].Slab
+
+	// build used hosts
+	usedHosts := make(map[types.PublicKey]struct{})
+	for _, shard := range slab.Shards {
+		for hk := range shard.Contracts {
+			usedHosts[hk] = struct{}{}
+		}
+	}
+
+	// download the healthy shards
+	shards, err := dl.DownloadShards(context.Background(), slab, w.UsableHosts())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing []int
+	for i := range shards {
+		if len(shards[i]) == 0 {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) != len(slab.Shards)-int(slab.MinShards) {
+		t.Fatal("expected only the minimum number of shards to be downloaded", len(missing))
+	}
+
+	// recreate upload hosts
+	var hosts []upload.HostInfo
+	for _, h := range w.UploadHosts() {
+		if _, used := usedHosts[h.PublicKey]; !used {
+			hosts = append(hosts, h)
+		}
+	}
+
+	// move all shards to new hosts
+	shardIndices := make([]int, len(slab.Shards))
+	for i := range shardIndices {
+		shardIndices[i] = i
+	}
+	mem := mm.AcquireMemory(context.Background(), uint64(len(shardIndices))*rhpv2.SectorSize)
+	var progress int
+	ctx := upload.WithShardProgress(context.Background(), func(uploaded int) { progress = uploaded })
+	regenerated, err := ul.RepairShards(ctx, slab, shardIndices, shards, hosts, 0, mem)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(regenerated, missing) {
+		t.Fatal("unexpected regenerated shards", regenerated, missing)
+	} else if progress != len(shardIndices) {
+		t.Fatal("unexpected progress", progress, len(shardIndices))
+	}
+
+	// assert every shard was added to a new host, which implies the
+	// regenerated shards match the original ones
+	o, err = os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, shard := range o.Object.Slabs[0].Shards {
+		var onNewHost bool
+		for hk := range shard.Contracts {
+			if _, used := usedHosts[hk]; !used {
+				onNewHost = true
+			}
+		}
+		if !onNewHost {
+			t.Fatalf("shard %d wasn't moved to a new host", i)
+		}
+	}
+}
+
+func TestReadRepair(t *testing.T) {
+	// create test worker with read-repair enabled
+	cfg := newTestWorkerCfg()
+	cfg.DownloadReadRepair = true
+	w := newTestWorker(t, cfg)
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards * 2)
+
+	// upload a full slab
+	params := testParameters(t.Name())
+	data := frand.Bytes(rhpv2.SectorSize * testRedundancySettings.MinShards)
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slab := o.Object.Slabs[0].Slab
+
+	// download the object without the host of the last shard
+	var unhealthy types.PublicKey
+	for hk := range slab.Shards[len(slab.Shards)-1].Contracts {
+		unhealthy = hk
+	}
+	var hosts []api.HostInfo
+	for _, h := range w.UsableHosts() {
+		if h.PublicKey != unhealthy {
+			hosts = append(hosts, h)
+		}
+	}
+	var buf bytes.Buffer
+	err = w.downloadManager.DownloadObject(context.Background(), &buf, *o.Object, 0, uint64(o.Size), hosts, download.WithReadRepair(w.readRepairer))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("data mismatch")
+	}
+
+	// assert the last shard was repaired
+	w.tt.Retry(100, 10*time.Millisecond, func() error {
+		if stats := w.readRepairer.Stats(); stats.Repaired != 1 || stats.RepairedShards != 1 {
+			return fmt.Errorf("unexpected stats %+v", stats)
+		}
+		return nil
+	})
+	o, err = w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if shard := o.Object.Slabs[0].Shards[len(slab.Shards)-1]; len(shard.Contracts) != 2 {
+		t.Fatal("expected shard to be stored on a new host", shard.Contracts)
+	}
+
+	// assert the slab is skipped if the budget is exhausted
+	w.readRepairer.maxBytes = 1
+	if w.readRepairer.ReadRepair(slab, nil, []int{0}, nil) {
+		t.Fatal("expected slab to be skipped")
+	} else if stats := w.readRepairer.Stats(); stats.Skipped != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
 func TestUploadShards(t *testing.T) {
 	// create test worker
 	w := newTestWorker(t, newTestWorkerCfg())
@@ -410,7 +1217,7 @@ func TestUploadShards(t *testing.T) {
 	params := testParameters(t.Name())
 
 	// upload data
-	_, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	_, _, _, err := ul.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -558,10 +1365,135 @@ func TestUploadSingleSectorSlowHosts(t *testing.T) {
 	params.RS.TotalShards = totalShards
 
 	// upload data
-	_, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(data), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadSlabDeadline(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker, all of them are slow
+	hosts := w.AddHosts(testRedundancySettings.TotalShards)
+	for _, h := range hosts {
+		h.uploadDelay = time.Hour
+	}
+
+	// create upload params
+	params := testParameters(t.Name())
+	params.SlabDeadline = 100 * time.Millisecond
+
+	// assert the upload fails fast
+	start := time.Now()
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if !errors.Is(err, api.ErrSlabUploadDeadlineExceeded) {
+		t.Fatal("unexpected error", err)
+	} else if time.Since(start) > 10*time.Second {
+		t.Fatal("upload took too long", time.Since(start))
+	}
+}
+
+func TestUploadNoCandidateWait(t *testing.T) {
+	upload := func(noCandidateWait time.Duration) error {
+		t.Helper()
+
+		// create test worker
+		cfg := newTestWorkerCfg()
+		cfg.UploadNoCandidateWait = noCandidateWait
+		w := newTestWorker(t, cfg)
+
+		// add exactly as many hosts as there are shards and have one of them
+		// fail its first upload
+		hosts := w.AddHosts(testRedundancySettings.TotalShards)
+		hosts[0].uploadFailures.Store(1)
+
+		// upload data
+		params := testParameters(t.Name())
+		_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+		return err
+	}
+
+	// assert the upload fails if it's not allowed to wait for a candidate
+	if err := upload(0); err == nil || !strings.Contains(err.Error(), errTestHostUploadFailed.Error()) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// assert the upload succeeds if it is since the host is retried
+	if err := upload(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadShutdownGracePeriod(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker, all of them are slightly slow
+	hosts := w.AddHosts(testRedundancySettings.TotalShards)
+	for _, h := range hosts {
+		h.uploadDelay = 200 * time.Millisecond
+	}
+
+	// start an upload
+	params := testParameters(t.Name())
+	errChan := make(chan error, 1)
+	go func() {
+		_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+		errChan <- err
+	}()
+
+	// wait until the upload is in progress
+	w.tt.Retry(100, 10*time.Millisecond, func() error {
+		if len(w.uploadManager.ActiveUploads()) != 1 {
+			return errors.New("upload not started")
+		}
+		return nil
+	})
+
+	// drain the upload manager, the upload should finish within the grace period
+	w.uploadManager.Drain(context.Background(), 10*time.Second)
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatal("expected upload to be finished")
+	}
+
+	// new uploads should be rejected
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if !errors.Is(err, upload.ErrShuttingDown) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestUploadShutdownGracePeriodAsync(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// upload data asynchronously, the first attempt at persisting the
+	// object fails so it's retried after a second
+	w.os.SetAddObjectFailures(1)
+	params := testParameters(t.Name())
+	params.Durability = api.UploadDurabilityAsync
+	_, _, uID, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// drain the upload manager, it should wait for the object to be persisted
+	w.uploadManager.Drain(context.Background(), 10*time.Second)
+	if status, ok := w.uploadManager.PersistenceStatus(uID); !ok {
+		t.Fatal("no persistence status")
+	} else if status.Status != api.UploadPersistenceSucceeded || status.Attempts != 2 {
+		t.Fatalf("unexpected status %+v", status)
+	}
 }
 
 func TestUploadRegression(t *testing.T) {
@@ -587,7 +1519,7 @@ func TestUploadRegression(t *testing.T) {
 	// upload data
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
-	_, err := w.upload(ctx, params.Bucket, params.Key, testRedundancySettings, bytes.NewReader(data), w.UploadHosts())
+	_, _, err := w.upload(ctx, params.Bucket, params.Key, testRedundancySettings, bytes.NewReader(data), w.UploadHosts())
 	if !errors.Is(err, upload.ErrUploadCancelled) {
 		t.Fatal(err)
 	}
@@ -596,7 +1528,7 @@ func TestUploadRegression(t *testing.T) {
 	unblock()
 
 	// upload data
-	_, err = w.upload(context.Background(), params.Bucket, params.Key, testRedundancySettings, bytes.NewReader(data), w.UploadHosts())
+	_, _, err = w.upload(context.Background(), params.Bucket, params.Key, testRedundancySettings, bytes.NewReader(data), w.UploadHosts())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -617,6 +1549,135 @@ func TestUploadRegression(t *testing.T) {
 	}
 }
 
+func TestActiveUploads(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// assert there are no active uploads
+	if uploads := w.uploadManager.ActiveUploads(); len(uploads) != 0 {
+		t.Fatal("expected no active uploads", len(uploads))
+	}
+
+	// start an upload that blocks until we close the pipe
+	params := testParameters(t.Name())
+	upload.WithMimeType("text/plain")(&params)
+	pr, pw := io.Pipe()
+	errChan := make(chan error, 1)
+	go func() {
+		_, _, _, err := w.uploadManager.Upload(context.Background(), pr, w.UploadHosts(), params)
+		errChan <- err
+	}()
+
+	// assert the upload is listed with its effective parameters
+	var uploads []upload.ActiveUpload
+	for i := 0; i < 100 && len(uploads) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		uploads = w.uploadManager.ActiveUploads()
+	}
+	if len(uploads) != 1 {
+		t.Fatal("expected one active upload", len(uploads))
+	} else if u := uploads[0]; u.Params.Key != t.Name() || u.Params.MimeType != "text/plain" || u.Params.RS != testRedundancySettings {
+		t.Fatal("unexpected parameters", u.Params)
+	} else if u.OverdriveTimeout != newTestWorkerCfg().UploadOverdriveTimeout {
+		t.Fatal("unexpected overdrive timeout", u.OverdriveTimeout)
+	} else if u.StartedAt.IsZero() {
+		t.Fatal("expected start time to be set")
+	}
+
+	// finish the upload and assert it's no longer listed
+	if _, err := pw.Write(frand.Bytes(128)); err != nil {
+		t.Fatal(err)
+	} else if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := <-errChan; err != nil {
+		t.Fatal(err)
+	} else if uploads := w.uploadManager.ActiveUploads(); len(uploads) != 0 {
+		t.Fatal("expected no active uploads", len(uploads))
+	}
+}
+
+func TestUploadPhaseLatencies(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add hosts to worker
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// upload data
+	params := testParameters(t.Name())
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert every phase was tracked, overdrive might cause more sector
+	// uploads than there are shards
+	stats := w.uploadManager.Stats()
+	for phase, count := range map[string]uint64{
+		upload.PhaseAcquireMemory: 2, // the second one runs into EOF
+		upload.PhaseEncode:        1,
+		upload.PhaseContractLock:  uint64(testRedundancySettings.TotalShards),
+		upload.PhaseSectorUpload:  uint64(testRedundancySettings.TotalShards),
+		upload.PhasePersist:       1,
+	} {
+		h, ok := stats.PhaseLatencies[phase]
+		if !ok {
+			t.Fatalf("phase %v wasn't tracked", phase)
+		} else if h.Count < count {
+			t.Fatalf("phase %v: expected at least %v observations, got %v", phase, count, h.Count)
+		}
+
+		// buckets are cumulative
+		for i := 1; i < len(h.Buckets); i++ {
+			if h.Buckets[i].Count < h.Buckets[i-1].Count {
+				t.Fatalf("phase %v: buckets aren't cumulative", phase)
+			} else if h.Buckets[i].Count > h.Count {
+				t.Fatalf("phase %v: bucket exceeds the total count", phase)
+			}
+		}
+	}
+}
+
+func TestUploadHostBreaker(t *testing.T) {
+	// create test worker
+	w := newTestWorker(t, newTestWorkerCfg())
+
+	// add one more host than necessary
+	hosts := w.AddHosts(testRedundancySettings.TotalShards + 1)
+
+	// trip the breaker of the first host, e.g. due to failed downloads
+	tripped := hosts[0].PublicKey()
+	for i := 0; i < breaker.DefaultThreshold; i++ {
+		w.hostBreakers.RecordFailure(tripped)
+	}
+
+	// upload data
+	params := testParameters(t.Name())
+	_, _, _, err := w.uploadManager.Upload(context.Background(), bytes.NewReader(frand.Bytes(128)), w.UploadHosts(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the tripped host wasn't used
+	o, err := w.os.Object(context.Background(), testBucket, t.Name(), api.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, shard := range o.Object.Slabs[0].Shards {
+		if _, used := shard.Contracts[tripped]; used {
+			t.Fatal("expected host with open breaker to be avoided")
+		}
+	}
+
+	// assert the breaker state is reported
+	if state := w.hostBreakers.State(tripped); state != breaker.StateOpen {
+		t.Fatal("unexpected breaker state", state)
+	}
+}
+
 func testParameters(key string) upload.Parameters {
 	return upload.Parameters{
 		Bucket: testBucket,
@@ -25,6 +25,7 @@ import (
 	"go.sia.tech/renterd/build"
 	"go.sia.tech/renterd/config"
 	"go.sia.tech/renterd/internal/accounts"
+	"go.sia.tech/renterd/internal/breaker"
 	"go.sia.tech/renterd/internal/contracts"
 	"go.sia.tech/renterd/internal/download"
 	"go.sia.tech/renterd/internal/gouging"
@@ -35,6 +36,7 @@ import (
 	rhp3 "go.sia.tech/renterd/internal/rhp/v3"
 	rhp4 "go.sia.tech/renterd/internal/rhp/v4"
 	"go.sia.tech/renterd/internal/upload"
+	"go.sia.tech/renterd/internal/upload/uploader"
 	"go.sia.tech/renterd/internal/utils"
 	iworker "go.sia.tech/renterd/internal/worker"
 	"go.sia.tech/renterd/object"
@@ -100,6 +102,7 @@ type (
 		RecordContractSpending(ctx context.Context, records []api.ContractSpendingRecord) error
 
 		Host(ctx context.Context, hostKey types.PublicKey) (api.Host, error)
+		Hosts(ctx context.Context, opts api.HostOptions) ([]api.Host, error)
 		UsableHosts(ctx context.Context) ([]api.HostInfo, error)
 	}
 
@@ -112,21 +115,25 @@ type (
 		// NOTE: used for upload
 		AddObject(ctx context.Context, bucket, key string, o object.Object, opts api.AddObjectOptions) error
 		AddMultipartPart(ctx context.Context, bucket, key, ETag, uploadID string, partNumber int, slices []object.SlabSlice) (err error)
+		AddMultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber, index int, checksum types.Hash256, slices []object.SlabSlice) (err error)
 		AddPartialSlab(ctx context.Context, data []byte, minShards, totalShards uint8) (slabs []object.SlabSlice, slabBufferMaxSizeSoftReached bool, err error)
 		AddUploadingSectors(ctx context.Context, uID api.UploadID, root []types.Hash256) error
 		FinishUpload(ctx context.Context, uID api.UploadID) error
 		Objects(ctx context.Context, prefix string, opts api.ListObjectOptions) (resp api.ObjectsResponse, err error)
 		MarkPackedSlabsUploaded(ctx context.Context, slabs []api.UploadedPackedSlab) error
+		MultipartPartProgress(ctx context.Context, bucket, key, uploadID string, partNumber int) ([]api.MultipartPartProgressSlice, error)
 		TrackUpload(ctx context.Context, uID api.UploadID) error
 		UpdateSlab(ctx context.Context, key object.EncryptionKey, sectors []api.UploadedSector) error
 
 		// NOTE: used by worker
 		Bucket(_ context.Context, bucket string) (api.Bucket, error)
+		CompleteSlabBuffers(ctx context.Context) ([]api.SlabBuffer, error)
 		Object(ctx context.Context, bucket, key string, opts api.GetObjectOptions) (api.Object, error)
-		DeleteObject(ctx context.Context, bucket, key string) error
+		DeleteObject(ctx context.Context, bucket, key string, opts api.DeleteObjectOptions) error
 		MultipartUpload(ctx context.Context, uploadID string) (resp api.MultipartUpload, err error)
 		PackedSlabsForUpload(ctx context.Context, lockingDuration time.Duration, minShards, totalShards uint8, limit int) ([]api.PackedSlab, error)
 		RemoveObjects(ctx context.Context, bucket, prefix string) error
+		SlabBuffers(ctx context.Context) ([]api.SlabBuffer, error)
 	}
 
 	SettingStore interface {
@@ -159,9 +166,17 @@ type Worker struct {
 	masterKey utils.MasterKey
 	startTime time.Time
 
+	uploadMinDistinctHosts uint64
+	uploadSlabDeadline     time.Duration
+	uploadShutdownGrace    time.Duration
+	uploadPersistAttempts  int
+	uploadETagBufferSize   uint64
+
 	downloadManager *download.Manager
 	uploadManager   *upload.Manager
+	hostBreakers    *breaker.Breakers
 	hostManager     hosts.Manager
+	readRepairer    *readRepairer // nil if read-repair is disabled
 
 	accounts *accounts.Manager
 	cache    iworker.WorkerCache
@@ -219,12 +234,19 @@ func (w *Worker) downloadsStatsHandlerGET(jc jape.Context) {
 		dss = append(dss, api.DownloaderStats{
 			HostKey:                    hk,
 			AvgSectorDownloadSpeedMBPS: mbps,
+			Breaker:                    string(w.hostBreakers.State(hk)),
 		})
 	}
 	sort.SliceStable(dss, func(i, j int) bool {
 		return dss[i].AvgSectorDownloadSpeedMBPS > dss[j].AvgSectorDownloadSpeedMBPS
 	})
 
+	// prepare read-repair stats
+	var rrs api.ReadRepairStats
+	if w.readRepairer != nil {
+		rrs = w.readRepairer.Stats()
+	}
+
 	// encode response
 	api.WriteResponse(jc, api.DownloadStatsResponse{
 		AvgDownloadSpeedMBPS: math.Ceil(stats.AvgDownloadSpeedMBPS*100) / 100,
@@ -232,6 +254,7 @@ func (w *Worker) downloadsStatsHandlerGET(jc jape.Context) {
 		HealthyDownloaders:   stats.HealthyDownloaders,
 		NumDownloaders:       stats.NumDownloaders,
 		DownloadersStats:     dss,
+		ReadRepairs:          rrs,
 	})
 }
 
@@ -241,25 +264,137 @@ func (w *Worker) uploadsStatsHandlerGET(jc jape.Context) {
 	// prepare upload stats
 	var uss []api.UploaderStats
 	for hk, mbps := range stats.UploadSpeedsMBPS {
+		timeouts := stats.SectorUploadTimeouts[hk]
 		uss = append(uss, api.UploaderStats{
 			HostKey:                  hk,
 			AvgSectorUploadSpeedMBPS: mbps,
+			SectorUploadTimeout:      api.DurationMS(timeouts.Timeout),
+			SectorUploadTimeouts:     timeouts.TimedOut,
+			Breaker:                  string(w.hostBreakers.State(hk)),
 		})
 	}
 	sort.SliceStable(uss, func(i, j int) bool {
 		return uss[i].AvgSectorUploadSpeedMBPS > uss[j].AvgSectorUploadSpeedMBPS
 	})
 
+	// prepare latency histograms
+	latencies := make(map[string]api.UploadLatencyHistogram, len(stats.PhaseLatencies))
+	for phase, h := range stats.PhaseLatencies {
+		hist := api.UploadLatencyHistogram{Count: h.Count}
+		if h.Count > 0 {
+			hist.Avg = api.DurationMS(h.Total / time.Duration(h.Count))
+		}
+		for _, b := range h.Buckets {
+			hist.Buckets = append(hist.Buckets, api.UploadLatencyBucket{
+				LE:    api.DurationMS(b.UpperBound),
+				Count: b.Count,
+			})
+		}
+		latencies[phase] = hist
+	}
+
 	// encode response
 	api.WriteResponse(jc, api.UploadStatsResponse{
 		AvgSlabUploadSpeedMBPS: math.Ceil(stats.AvgSlabUploadSpeedMBPS*100) / 100,
 		AvgOverdrivePct:        math.Floor(stats.AvgOverdrivePct*100*100) / 100,
 		HealthyUploaders:       stats.HealthyUploaders,
 		NumUploaders:           stats.NumUploaders,
+		ActiveUploaders:        stats.ActiveUploaders,
+		IdleUploaders:          stats.IdleUploaders,
+		MemoryPressure:         math.Round(stats.MemoryPressure*100) / 100,
+		RejectedUploads:        stats.RejectedUploads,
 		UploadersStats:         uss,
+
+		PhaseLatencies: latencies,
+	})
+}
+
+func (w *Worker) uploadsStatsHostHandlerGET(jc jape.Context) {
+	var hk types.PublicKey
+	if jc.DecodeParam("hostkey", &hk) != nil {
+		return
+	}
+	history, err := w.uploadManager.HostStats(hk)
+	if errors.Is(err, upload.ErrUploaderNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch uploader stats", err) != nil {
+		return
+	}
+
+	// convert bytes per ms to mbps
+	speeds := history.SectorUploadSpeedBytesPerMS
+	for i := range speeds.Values {
+		speeds.Values[i] *= 0.008
+	}
+
+	jc.Encode(api.UploaderHistoryResponse{
+		HostKey:             hk,
+		ContractID:          history.ContractID,
+		ConsecutiveFailures: history.ConsecutiveFailures,
+		Breaker:             string(w.hostBreakers.State(hk)),
+
+		SectorUploadEstimateMS: api.UploaderDataPoints(history.SectorUploadEstimateMS),
+		SectorUploadSpeedMBPS: api.UploaderDataPoints{
+			P50:    speeds.P50 * 0.008,
+			P90:    speeds.P90 * 0.008,
+			P99:    speeds.P99 * 0.008,
+			Values: speeds.Values,
+		},
 	})
 }
 
+func (w *Worker) uploadsHandlerGET(jc jape.Context) {
+	active := w.uploadManager.ActiveUploads()
+	uploads := make([]api.ActiveUpload, 0, len(active))
+	for _, u := range active {
+		latency := make(map[string]api.UploadPhaseLatency, len(u.Latency))
+		for phase, l := range u.Latency {
+			latency[phase] = api.UploadPhaseLatency{
+				Count: l.Count,
+				Total: api.DurationMS(l.Total),
+			}
+		}
+		uploads = append(uploads, api.ActiveUpload{
+			ID:        u.ID,
+			Bucket:    u.Params.Bucket,
+			Key:       u.Params.Key,
+			StartedAt: api.TimeRFC3339(u.StartedAt),
+
+			Multipart:  u.Params.Multipart,
+			UploadID:   u.Params.UploadID,
+			PartNumber: u.Params.PartNumber,
+
+			BlockHeight:        u.Params.BH,
+			CustomerEncryption: u.Params.CustomerKey != nil,
+			EncryptionOffset:   u.Params.EncryptionOffset,
+			MimeType:           u.Params.MimeType,
+			Packing:            u.Params.Packing,
+			Durability:         u.Params.Durability,
+			Redundancy:         u.Params.RS,
+
+			MaxOverdrive:     u.MaxOverdrive,
+			OverdriveTimeout: api.DurationMS(u.OverdriveTimeout),
+
+			Latency: latency,
+		})
+	}
+	jc.Encode(uploads)
+}
+
+func (w *Worker) uploadPersistenceHandlerGET(jc jape.Context) {
+	var id api.UploadID
+	if jc.DecodeParam("id", &id) != nil {
+		return
+	}
+	status, ok := w.uploadManager.PersistenceStatus(id)
+	if !ok {
+		jc.Error(fmt.Errorf("no persistence status found for upload %v", id), http.StatusNotFound)
+		return
+	}
+	jc.Encode(status)
+}
+
 func (w *Worker) objectHandlerHEAD(jc jape.Context) {
 	// parse bucket
 	var bucket string
@@ -324,6 +459,14 @@ func (w *Worker) objectHandlerGET(jc jape.Context) {
 		return
 	}
 
+	mode := api.DownloadModeSpeed
+	if jc.DecodeForm("mode", &mode) != nil {
+		return
+	} else if mode != api.DownloadModeSpeed && mode != api.DownloadModeCost {
+		jc.Error(fmt.Errorf("invalid download mode '%s', options are '%s' and '%s'", mode, api.DownloadModeSpeed, api.DownloadModeCost), http.StatusBadRequest)
+		return
+	}
+
 	dr, err := api.ParseDownloadRange(jc.Request)
 	if errors.Is(err, http_range.ErrInvalid) || errors.Is(err, api.ErrMultiRangeNotSupported) {
 		jc.Error(err, http.StatusBadRequest)
@@ -336,13 +479,21 @@ func (w *Worker) objectHandlerGET(jc jape.Context) {
 		return
 	}
 
+	encryptionKey, err := api.ParseEncryptionKeyHeader(jc.Request.Header)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
 	gor, err := w.GetObject(ctx, bucket, key, api.DownloadObjectOptions{
-		Range: &dr,
+		EncryptionKey: encryptionKey,
+		Mode:          mode,
+		Range:         &dr,
 	})
 	if utils.IsErr(err, api.ErrObjectNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
-	} else if errors.Is(err, http_range.ErrInvalid) {
+	} else if errors.Is(err, http_range.ErrInvalid) || errors.Is(err, object.ErrMissingEncryptionKey) {
 		jc.Error(err, http.StatusBadRequest)
 		return
 	} else if jc.Check("couldn't get object", err) != nil {
@@ -385,6 +536,49 @@ func (w *Worker) objectHandlerPUT(jc jape.Context) {
 		return
 	}
 
+	// decode the durability mode
+	var durability string
+	if jc.DecodeForm("durability", &durability) != nil {
+		return
+	}
+
+	// decode the slab deadline
+	var slabDeadline api.DurationMS
+	if jc.DecodeForm("slabdeadline", &slabDeadline) != nil {
+		return
+	}
+
+	// decode whether the upload should be verified
+	var verify bool
+	if jc.DecodeForm("verify", &verify) != nil {
+		return
+	}
+
+	// decode whether the object should be buffered if there aren't enough
+	// hosts to upload it to
+	var bufferIfNoHosts bool
+	if jc.DecodeForm("bufferifnohosts", &bufferIfNoHosts) != nil {
+		return
+	}
+
+	// decode the hosts the upload is pinned to
+	var hostKeys []types.PublicKey
+	for _, v := range jc.Request.Form["hostkey"] {
+		var hk types.PublicKey
+		if err := hk.UnmarshalText([]byte(v)); err != nil {
+			jc.Error(fmt.Errorf("invalid form value \"hostkey\": %w", err), http.StatusBadRequest)
+			return
+		}
+		hostKeys = append(hostKeys, hk)
+	}
+
+	// decode the customer key the object is encrypted with
+	encryptionKey, err := api.ParseEncryptionKeyHeader(jc.Request.Header)
+	if err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
 	// parse headers and extract object meta
 	metadata := make(api.ObjectUserMetadata)
 	for k, v := range jc.Request.Header {
@@ -393,29 +587,60 @@ func (w *Worker) objectHandlerPUT(jc jape.Context) {
 		}
 	}
 
+	// validate the content disposition
+	contentDisposition := jc.Request.Header.Get("Content-Disposition")
+	if err := api.ValidateContentDisposition(contentDisposition); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
 	// upload the object
 	resp, err := w.UploadObject(ctx, jc.Request.Body, bucket, path, api.UploadObjectOptions{
-		MinShards:     minShards,
-		TotalShards:   totalShards,
-		ContentLength: jc.Request.ContentLength,
-		MimeType:      mimeType,
-		Metadata:      metadata,
+		MinShards:          minShards,
+		TotalShards:        totalShards,
+		ContentLength:      jc.Request.ContentLength,
+		MimeType:           mimeType,
+		Metadata:           metadata,
+		HostKeys:           hostKeys,
+		ContentDisposition: contentDisposition,
+		EncryptionKey:      encryptionKey,
+		Durability:         durability,
+		SlabDeadline:       time.Duration(slabDeadline),
+		Verify:             verify,
+		BufferIfNoHosts:    bufferIfNoHosts,
 	})
-	if utils.IsErr(err, api.ErrInvalidRedundancySettings) {
+	if utils.IsErr(err, api.ErrInvalidRedundancySettings) || utils.IsErr(err, api.ErrInsufficientPinnedHosts) || utils.IsErr(err, api.ErrInvalidUploadDurability) || utils.IsErr(err, api.ErrUploadVerificationAsync) {
 		jc.Error(err, http.StatusBadRequest)
 		return
 	} else if utils.IsErr(err, api.ErrBucketNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
-	} else if utils.IsErr(err, api.ErrConsensusNotSynced) {
+	} else if utils.IsErr(err, api.ErrConsensusNotSynced) || utils.IsErr(err, api.ErrInsufficientDistinctHosts) || utils.IsErr(err, api.ErrSlabBufferFull) {
+		jc.Error(err, http.StatusServiceUnavailable)
+		return
+	} else if utils.IsErr(err, api.ErrServerBusy) {
+		jc.ResponseWriter.Header().Set("Retry-After", "1")
 		jc.Error(err, http.StatusServiceUnavailable)
 		return
+	} else if utils.IsErr(err, api.ErrSlabUploadDeadlineExceeded) {
+		jc.Error(err, http.StatusGatewayTimeout)
+		return
 	} else if jc.Check("couldn't upload object", err) != nil {
 		return
 	}
 
 	// set etag header
 	jc.ResponseWriter.Header().Set("ETag", api.FormatETag(resp.ETag))
+
+	// set upload id header for asynchronously persisted uploads
+	if resp.UploadID != nil {
+		jc.ResponseWriter.Header().Set(api.ObjectUploadIDHeader, resp.UploadID.String())
+	}
+
+	// signal that the object's data isn't on hosts yet
+	if resp.Pending {
+		jc.ResponseWriter.Header().Set(api.ObjectUploadPendingHeader, "true")
+	}
 }
 
 func (w *Worker) multipartUploadHandlerPUT(jc jape.Context) {
@@ -482,9 +707,16 @@ func (w *Worker) multipartUploadHandlerPUT(jc jape.Context) {
 	} else if utils.IsErr(err, api.ErrBucketNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
-	} else if utils.IsErr(err, api.ErrConsensusNotSynced) {
+	} else if utils.IsErr(err, api.ErrConsensusNotSynced) || utils.IsErr(err, api.ErrInsufficientDistinctHosts) {
+		jc.Error(err, http.StatusServiceUnavailable)
+		return
+	} else if utils.IsErr(err, api.ErrServerBusy) {
+		jc.ResponseWriter.Header().Set("Retry-After", "1")
 		jc.Error(err, http.StatusServiceUnavailable)
 		return
+	} else if utils.IsErr(err, api.ErrSlabUploadDeadlineExceeded) {
+		jc.Error(err, http.StatusGatewayTimeout)
+		return
 	} else if utils.IsErr(err, api.ErrMultipartUploadNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
@@ -499,12 +731,31 @@ func (w *Worker) multipartUploadHandlerPUT(jc jape.Context) {
 	jc.ResponseWriter.Header().Set("ETag", api.FormatETag(resp.ETag))
 }
 
+func (w *Worker) objectsRecoverabilityHandlerGET(jc jape.Context) {
+	var bucket string
+	if jc.DecodeForm("bucket", &bucket) != nil {
+		return
+	} else if bucket == "" {
+		jc.Error(api.ErrBucketMissing, http.StatusBadRequest)
+		return
+	}
+
+	res, err := w.ObjectRecoverability(jc.Request.Context(), bucket, jc.PathParam("key"))
+	if utils.IsErr(err, api.ErrObjectNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("couldn't check object recoverability", err) != nil {
+		return
+	}
+	jc.Encode(res)
+}
+
 func (w *Worker) objectHandlerDELETE(jc jape.Context) {
 	var bucket string
 	if jc.DecodeForm("bucket", &bucket) != nil {
 		return
 	}
-	err := w.bus.DeleteObject(jc.Request.Context(), bucket, jc.PathParam("key"))
+	err := w.bus.DeleteObject(jc.Request.Context(), bucket, jc.PathParam("key"), api.DeleteObjectOptions{})
 	if utils.IsErr(err, api.ErrObjectNotFound) {
 		jc.Error(err, http.StatusNotFound)
 		return
@@ -529,6 +780,50 @@ func (w *Worker) objectsRemoveHandlerPOST(jc jape.Context) {
 	jc.Check("couldn't remove objects", w.bus.RemoveObjects(jc.Request.Context(), orr.Bucket, orr.Prefix))
 }
 
+func (w *Worker) uploadEstimateHandlerGET(jc jape.Context) {
+	ctx := jc.Request.Context()
+
+	// decode the size of the object to estimate the cost for
+	var size uint64
+	if jc.DecodeForm("size", &size) != nil {
+		return
+	}
+
+	// allow overriding the redundancy settings
+	var minShards, totalShards int
+	if jc.DecodeForm("minshards", &minShards) != nil {
+		return
+	}
+	if jc.DecodeForm("totalshards", &totalShards) != nil {
+		return
+	}
+
+	// fetch the upload parameters
+	up, err := w.bus.UploadParams(ctx)
+	if jc.Check("couldn't fetch upload parameters from bus", err) != nil {
+		return
+	}
+	if minShards != 0 {
+		up.RedundancySettings.MinShards = minShards
+	}
+	if totalShards != 0 {
+		up.RedundancySettings.TotalShards = totalShards
+	}
+	if err := up.RedundancySettings.Validate(); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	estimate, err := w.estimateUploadCost(ctx, size, up.RedundancySettings, up.CurrentHeight)
+	if errors.Is(err, upload.ErrUploadNotEnoughHosts) {
+		jc.Error(err, http.StatusServiceUnavailable)
+		return
+	} else if jc.Check("couldn't estimate upload cost", err) != nil {
+		return
+	}
+	jc.Encode(estimate)
+}
+
 func (w *Worker) memoryGET(jc jape.Context) {
 	api.WriteResponse(jc, api.MemoryResponse{
 		Download: w.downloadManager.MemoryStatus(),
@@ -563,6 +858,14 @@ func (w *Worker) accountsResetDriftHandlerPOST(jc jape.Context) {
 	}
 }
 
+func (w *Worker) slabBuffersFlushHandlerPOST(jc jape.Context) {
+	resp, err := w.flushSlabBuffers(jc.Request.Context())
+	if jc.Check("couldn't flush slab buffers", err) != nil {
+		return
+	}
+	jc.Encode(resp)
+}
+
 func (w *Worker) stateHandlerGET(jc jape.Context) {
 	jc.Encode(api.WorkerStateResponse{
 		ID:        w.id,
@@ -593,23 +896,63 @@ func New(cfg config.Worker, masterKey [32]byte, b Bus, l *zap.Logger) (*Worker,
 	if cfg.UploadOverdriveTimeout == 0 {
 		return nil, errors.New("upload overdrive timeout must be positive")
 	}
+	if cfg.UploadStatsRecomputeInterval == 0 {
+		cfg.UploadStatsRecomputeInterval = uploader.DefaultStatsRecomputeInterval
+	}
+	if cfg.UploadContractDurationWeight < 0 {
+		return nil, errors.New("upload contract duration weight must not be negative")
+	}
+	if cfg.UploadCandidateTolerance < 0 {
+		return nil, errors.New("upload candidate tolerance must not be negative")
+	}
+	if cfg.UploadNoCandidateWait < 0 {
+		return nil, errors.New("upload no candidate wait must not be negative")
+	}
+	if cfg.UploadSectorTimeoutMin == 0 {
+		cfg.UploadSectorTimeoutMin = uploader.DefaultSectorUploadTimeoutMin
+	}
+	if cfg.UploadSectorTimeoutMax == 0 {
+		cfg.UploadSectorTimeoutMax = max(uploader.DefaultSectorUploadTimeoutMax, cfg.UploadSectorTimeoutMin)
+	}
+	if cfg.UploadSectorTimeoutMax < cfg.UploadSectorTimeoutMin {
+		return nil, errors.New("upload sector timeout max must not be lower than its min")
+	}
+	if cfg.HostBreakerThreshold == 0 {
+		cfg.HostBreakerThreshold = breaker.DefaultThreshold
+	}
+	if cfg.HostBreakerCooldown == 0 {
+		cfg.HostBreakerCooldown = breaker.DefaultCooldown
+	}
 	if cfg.DownloadMaxMemory == 0 {
 		return nil, errors.New("downloadMaxMemory cannot be 0")
 	}
 	if cfg.UploadMaxMemory == 0 {
 		return nil, errors.New("uploadMaxMemory cannot be 0")
 	}
+	if cfg.UploadMinFreeMemory > cfg.UploadMaxMemory {
+		return nil, errors.New("uploadMinFreeMemory must not exceed uploadMaxMemory")
+	}
 	if cfg.CacheExpiry == 0 {
 		return nil, errors.New("cache expiry cannot be 0")
 	}
+	if cfg.DownloadReadRepair && cfg.DownloadReadRepairBudget > 0 && cfg.DownloadReadRepairBudgetInterval == 0 {
+		return nil, errors.New("download read-repair budget interval must be positive")
+	}
+	eb, err := object.NewErasureBackend(cfg.ErasureBackend)
+	if err != nil {
+		return nil, err
+	}
 
 	a := alerts.WithOrigin(b, fmt.Sprintf("worker.%s", cfg.ID))
 	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
 
 	dialer := rhp.NewFallbackDialer(b, net.Dialer{}, l)
 	w := &Worker{
-		alerts:               a,
-		cache:                iworker.NewCache(b, cfg.CacheExpiry, l),
+		alerts: a,
+		cache: iworker.NewCache(b, cfg.CacheExpiry, map[string]time.Duration{
+			iworker.CacheKeyDownloadPricePrefix: cfg.CacheExpiryDownloadPrices,
+			iworker.CacheKeyUsableHosts:         cfg.CacheExpiryUsableHosts,
+		}, l),
 		id:                   cfg.ID,
 		bus:                  b,
 		masterKey:            masterKey,
@@ -619,8 +962,15 @@ func New(cfg config.Worker, masterKey [32]byte, b Bus, l *zap.Logger) (*Worker,
 		rhp4Client:           rhp4.New(dialer),
 		startTime:            time.Now(),
 		uploadingPackedSlabs: make(map[string]struct{}),
-		shutdownCtx:          shutdownCtx,
-		shutdownCtxCancel:    shutdownCancel,
+
+		uploadMinDistinctHosts: cfg.UploadMinDistinctHosts,
+		uploadSlabDeadline:     cfg.UploadSlabDeadline,
+		uploadShutdownGrace:    cfg.UploadShutdownGracePeriod,
+		uploadPersistAttempts:  cfg.UploadPersistMaxAttempts,
+		uploadETagBufferSize:   cfg.UploadETagBufferSize,
+
+		shutdownCtx:       shutdownCtx,
+		shutdownCtxCancel: shutdownCancel,
 	}
 
 	if err := w.initAccounts(cfg.AccountsRefillInterval); err != nil {
@@ -632,12 +982,30 @@ func New(cfg config.Worker, masterKey [32]byte, b Bus, l *zap.Logger) (*Worker,
 	w.contractSpendingRecorder = contracts.NewSpendingRecorder(w.shutdownCtx, w.bus, cfg.BusFlushInterval, l)
 	hm := hosts.NewManager(w.masterKey, w.accounts, w.contractSpendingRecorder, dialer, l)
 	w.hostManager = hm
+	w.hostBreakers = breaker.New(cfg.HostBreakerThreshold, cfg.HostBreakerCooldown)
 
 	dlmm := memory.NewManager(cfg.DownloadMaxMemory, l.Named("downloadmanager"))
-	w.downloadManager = download.NewManager(w.shutdownCtx, &uploadKey, hm, dlmm, w.bus, cfg.DownloadMaxOverdrive, cfg.DownloadOverdriveTimeout, l)
+	w.downloadManager = download.NewManager(w.shutdownCtx, &uploadKey, w.hostBreakers, hm, dlmm, w.bus, eb, cfg.DownloadMaxHostsPerSlab, cfg.DownloadMaxOverdrive, cfg.DownloadOverdriveTimeout, l)
 
 	ulmm := memory.NewManager(cfg.UploadMaxMemory, l.Named("uploadmanager"))
-	w.uploadManager = upload.NewManager(w.shutdownCtx, &uploadKey, hm, ulmm, w.bus, w.bus, w.bus, cfg.UploadMaxOverdrive, cfg.UploadOverdriveTimeout, l)
+	w.uploadManager = upload.NewManager(w.shutdownCtx, &uploadKey, w.hostBreakers, hm, ulmm, w.bus, w.bus, w.bus, eb, upload.ManagerConfig{
+		MaxOverdrive:              cfg.UploadMaxOverdrive,
+		MaxOverdrivePerSlab:       cfg.UploadMaxOverdrivePerSlab,
+		MinFreeMemory:             cfg.UploadMinFreeMemory,
+		ContractDurationWeight:    cfg.UploadContractDurationWeight,
+		CandidateTolerance:        cfg.UploadCandidateTolerance,
+		CandidateFailureThreshold: cfg.UploadCandidateFailureThreshold,
+		MaxActiveUploaders:        cfg.UploadMaxActiveUploaders,
+		OverdriveTimeout:          cfg.UploadOverdriveTimeout,
+		NoCandidateWait:           cfg.UploadNoCandidateWait,
+		StatsRecomputeInterval:    cfg.UploadStatsRecomputeInterval,
+		SectorUploadTimeoutMin:    cfg.UploadSectorTimeoutMin,
+		SectorUploadTimeoutMax:    cfg.UploadSectorTimeoutMax,
+	}, l)
+
+	if cfg.DownloadReadRepair {
+		w.readRepairer = newReadRepairer(w, cfg.DownloadReadRepairBudget, cfg.DownloadReadRepairBudgetInterval)
+	}
 
 	return w, nil
 }
@@ -653,21 +1021,32 @@ func (w *Worker) Handler() http.Handler {
 
 		"PUT    /multipart/*key": w.multipartUploadHandlerPUT,
 
-		"HEAD   /object/*key":    w.objectHandlerHEAD,
-		"GET    /object/*key":    w.objectHandlerGET,
-		"PUT    /object/*key":    w.objectHandlerPUT,
-		"DELETE /object/*key":    w.objectHandlerDELETE,
-		"POST   /objects/remove": w.objectsRemoveHandlerPOST,
+		"HEAD   /object/*key":                 w.objectHandlerHEAD,
+		"GET    /object/*key":                 w.objectHandlerGET,
+		"PUT    /object/*key":                 w.objectHandlerPUT,
+		"DELETE /object/*key":                 w.objectHandlerDELETE,
+		"POST   /objects/remove":              w.objectsRemoveHandlerPOST,
+		"GET    /objects/recoverability/*key": w.objectsRecoverabilityHandlerGET,
+
+		"POST   /slabbuffers/flush": w.slabBuffersFlushHandlerPOST,
 
 		"GET    /state": w.stateHandlerGET,
 
-		"GET    /stats/downloads": w.downloadsStatsHandlerGET,
-		"GET    /stats/uploads":   w.uploadsStatsHandlerGET,
+		"GET    /stats/downloads":        w.downloadsStatsHandlerGET,
+		"GET    /stats/uploads":          w.uploadsStatsHandlerGET,
+		"GET    /stats/uploads/:hostkey": w.uploadsStatsHostHandlerGET,
+
+		"GET    /upload/estimate":         w.uploadEstimateHandlerGET,
+		"GET    /uploads/:id/persistence": w.uploadPersistenceHandlerGET,
+		"GET    /uploads":                 w.uploadsHandlerGET,
 	})
 }
 
 // Shutdown shuts down the worker.
 func (w *Worker) Shutdown(ctx context.Context) error {
+	// give in-flight uploads a chance to finish
+	w.uploadManager.Drain(ctx, w.uploadShutdownGrace)
+
 	// cancel shutdown context
 	w.shutdownCtxCancel()
 
@@ -707,12 +1086,13 @@ func (w *Worker) headObject(ctx context.Context, bucket, key string, onlyMetadat
 	}
 
 	return &api.HeadObjectResponse{
-		ContentType:  res.MimeType,
-		Etag:         res.ETag,
-		LastModified: res.ModTime,
-		Range:        opts.Range.ContentRange(res.Size),
-		Size:         res.Size,
-		Metadata:     res.Metadata,
+		ContentDisposition: res.ContentDisposition,
+		ContentType:        res.MimeType,
+		Etag:               res.ETag,
+		LastModified:       res.ModTime,
+		Range:              opts.Range.ContentRange(res.Size),
+		Size:               res.Size,
+		Metadata:           res.Metadata,
 	}, res, nil
 }
 
@@ -753,6 +1133,11 @@ func (w *Worker) GetObject(ctx context.Context, bucket, key string, opts api.Dow
 	}
 	obj := *res.Object
 
+	// objects uploaded with a customer key require the same key to download
+	if err := obj.Key.VerifyCustomerKey(opts.EncryptionKey); err != nil {
+		return nil, err
+	}
+
 	// adjust range
 	if opts.Range == nil {
 		opts.Range = &api.DownloadRange{}
@@ -772,6 +1157,26 @@ func (w *Worker) GetObject(ctx context.Context, bucket, key string, opts api.Dow
 		return nil, fmt.Errorf("couldn't fetch contracts from bus: %w", err)
 	}
 
+	// prefer cheap hosts if the download is optimized for cost
+	var dlOpts []download.Option
+	if opts.EncryptionKey != nil {
+		dlOpts = append(dlOpts, download.WithCustomerKey(*opts.EncryptionKey))
+	}
+	if w.readRepairer != nil {
+		dlOpts = append(dlOpts, download.WithReadRepair(w.readRepairer))
+	}
+	if opts.Mode == api.DownloadModeCost {
+		hks := make([]types.PublicKey, 0, len(hosts))
+		for _, h := range hosts {
+			hks = append(hks, h.PublicKey)
+		}
+		prices, err := w.cache.DownloadPrices(ctx, hks)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't fetch download prices: %w", err)
+		}
+		dlOpts = append(dlOpts, download.WithCheapestHosts(prices))
+	}
+
 	// prepare the content
 	var content io.ReadCloser
 	if opts.Range.Length == 0 || obj.TotalSize() == 0 {
@@ -782,7 +1187,7 @@ func (w *Worker) GetObject(ctx context.Context, bucket, key string, opts api.Dow
 		// otherwise return a pipe reader
 		downloadFn := func(wr io.Writer, offset, length int64) error {
 			ctx = gouging.WithChecker(ctx, w.bus, gp)
-			err = w.downloadManager.DownloadObject(ctx, wr, obj, uint64(offset), uint64(length), hosts)
+			err = w.downloadManager.DownloadObject(ctx, wr, obj, uint64(offset), uint64(length), hosts, dlOpts...)
 			if err != nil {
 				w.logger.Error(err)
 				if !errors.Is(err, download.ErrShuttingDown) &&
@@ -813,6 +1218,64 @@ func (w *Worker) HeadObject(ctx context.Context, bucket, key string, opts api.He
 	return res, err
 }
 
+// ObjectRecoverability checks whether every slab of the object has at least
+// its minimum number of shards stored with good contracts on usable hosts. It
+// only looks at the object's metadata and doesn't download any data.
+func (w *Worker) ObjectRecoverability(ctx context.Context, bucket, key string) (api.ObjectRecoverabilityResponse, error) {
+	res, err := w.bus.Object(ctx, bucket, key, api.GetObjectOptions{})
+	if err != nil {
+		return api.ObjectRecoverabilityResponse{}, fmt.Errorf("couldn't fetch object: %w", err)
+	}
+
+	hosts, err := w.hostContracts(ctx)
+	if err != nil {
+		return api.ObjectRecoverabilityResponse{}, err
+	}
+	good := make(map[types.FileContractID]struct{}, len(hosts))
+	for _, h := range hosts {
+		good[h.ContractID] = struct{}{}
+	}
+
+	resp := api.ObjectRecoverabilityResponse{Recoverable: true}
+	if res.Object == nil {
+		return resp, nil
+	}
+	for _, ss := range res.Object.Slabs {
+		sr := slabRecoverability(ss.Slab, good)
+		resp.Recoverable = resp.Recoverable && sr.Recoverable
+		resp.Slabs = append(resp.Slabs, sr)
+	}
+	return resp, nil
+}
+
+// slabRecoverability counts the shards of the slab that are stored with one of
+// the given contracts.
+func slabRecoverability(s object.Slab, good map[types.FileContractID]struct{}) api.SlabRecoverability {
+	sr := api.SlabRecoverability{
+		EncryptionKey: s.EncryptionKey,
+		MinShards:     s.MinShards,
+		TotalShards:   uint8(len(s.Shards)),
+	}
+	if s.IsPartial() {
+		sr.Recoverable = true // the data is still buffered on the bus
+		return sr
+	}
+
+	for _, shard := range s.Shards {
+	outer:
+		for _, fcids := range shard.Contracts {
+			for _, fcid := range fcids {
+				if _, ok := good[fcid]; ok {
+					sr.AvailableShards++
+					break outer
+				}
+			}
+		}
+	}
+	sr.Recoverable = sr.AvailableShards >= sr.MinShards
+	return sr
+}
+
 func (w *Worker) SyncAccount(ctx context.Context, fcid types.FileContractID, host api.HostInfo) error {
 	// handle v2 host
 	if host.IsV2() {
@@ -841,6 +1304,16 @@ func (w *Worker) SyncAccount(ctx context.Context, fcid types.FileContractID, hos
 }
 
 func (w *Worker) UploadObject(ctx context.Context, r io.Reader, bucket, key string, opts api.UploadObjectOptions) (*api.UploadObjectResponse, error) {
+	// validate the durability mode
+	switch opts.Durability {
+	case "", api.UploadDurabilitySync, api.UploadDurabilityAsync:
+	default:
+		return nil, fmt.Errorf("%w: %q", api.ErrInvalidUploadDurability, opts.Durability)
+	}
+	if opts.Verify && opts.Durability == api.UploadDurabilityAsync {
+		return nil, api.ErrUploadVerificationAsync
+	}
+
 	// prepare upload params
 	up, err := w.prepareUploadParams(ctx, bucket, opts.MinShards, opts.TotalShards)
 	if err != nil {
@@ -856,23 +1329,84 @@ func (w *Worker) UploadObject(ctx context.Context, r io.Reader, bucket, key stri
 		return nil, fmt.Errorf("couldn't fetch contracts from bus: %w", err)
 	}
 
-	// upload
-	eTag, err := w.upload(ctx, bucket, key, up.RedundancySettings, r, contracts,
+	// restrict the upload to the pinned hosts, packing is disabled since
+	// packed slabs are uploaded to any host
+	packing := up.UploadPacking
+	if len(opts.HostKeys) > 0 {
+		contracts, err = pinnedHostContracts(contracts, opts.HostKeys, up.RedundancySettings.TotalShards)
+		if err != nil {
+			return nil, err
+		}
+		packing = false
+	}
+
+	// verification downloads the object from the hosts, packing is disabled
+	// since buffered data would be read back from the bus instead
+	if opts.Verify {
+		packing = false
+	}
+
+	// make sure the upload can achieve its redundancy, if requested the
+	// object is buffered instead
+	hostsErr := checkDistinctHosts(contracts, up.RedundancySettings.TotalShards, w.uploadMinDistinctHosts)
+	if hostsErr != nil && !(opts.BufferIfNoHosts && packing) {
+		return nil, hostsErr
+	}
+
+	// prepare upload options
+	uploadOpts := []upload.Option{
 		upload.WithBlockHeight(up.CurrentHeight),
 		upload.WithMimeType(opts.MimeType),
-		upload.WithPacking(up.UploadPacking),
+		upload.WithContentDisposition(opts.ContentDisposition),
+		upload.WithPacking(packing),
 		upload.WithObjectUserMetadata(opts.Metadata),
-	)
+		upload.WithPinnedHosts(opts.HostKeys),
+		upload.WithDurability(opts.Durability),
+		upload.WithSlabDeadline(w.uploadSlabDeadline),
+		upload.WithPersistMaxAttempts(w.uploadPersistAttempts),
+		upload.WithETagBufferSize(w.uploadETagBufferSize),
+	}
+	if opts.SlabDeadline > 0 {
+		uploadOpts = append(uploadOpts, upload.WithSlabDeadline(opts.SlabDeadline))
+	}
+	if opts.EncryptionKey != nil {
+		uploadOpts = append(uploadOpts, upload.WithCustomerKey(*opts.EncryptionKey))
+	}
+	if opts.Verify {
+		uploadOpts = append(uploadOpts, upload.WithVerification(func(ctx context.Context, o object.Object, eTag string) error {
+			return w.verifyUpload(ctx, o, eTag, opts.EncryptionKey)
+		}))
+	}
+
+	// buffer the object if there aren't enough hosts
+	if hostsErr != nil {
+		eTag, uID, err := w.bufferObject(ctx, bucket, key, up.RedundancySettings, r, uploadOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't buffer object: %w", err)
+		}
+		resp := &api.UploadObjectResponse{ETag: eTag, Pending: true}
+		if opts.Durability == api.UploadDurabilityAsync {
+			resp.UploadID = &uID
+		}
+		return resp, nil
+	}
+
+	// upload
+	eTag, uID, err := w.upload(ctx, bucket, key, up.RedundancySettings, r, contracts, uploadOpts...)
 	if err != nil {
 		w.logger.With(zap.Error(err)).With("key", key).With("bucket", bucket).Error("failed to upload object")
 		if !errors.Is(err, ErrShuttingDown) && !errors.Is(err, upload.ErrUploadCancelled) && !errors.Is(err, context.Canceled) {
-			w.registerAlert(newUploadFailedAlert(bucket, key, opts.MimeType, up.RedundancySettings.MinShards, up.RedundancySettings.TotalShards, len(contracts), up.UploadPacking, false, err))
+			w.registerAlert(newUploadFailedAlert(bucket, key, opts.MimeType, up.RedundancySettings.MinShards, up.RedundancySettings.TotalShards, len(contracts), packing, false, err))
 		}
 		return nil, fmt.Errorf("couldn't upload object: %w", err)
 	}
-	return &api.UploadObjectResponse{
+	resp := &api.UploadObjectResponse{
 		ETag: eTag,
-	}, nil
+	}
+	if opts.Durability == api.UploadDurabilityAsync {
+		resp.UploadID = &uID
+	}
+	return resp, nil
 }
 
 func (w *Worker) UploadMultipartUploadPart(ctx context.Context, r io.Reader, bucket, path, uploadID string, partNumber int, opts api.UploadMultipartUploadPartOptions) (*api.UploadMultipartUploadPartResponse, error) {
@@ -894,10 +1428,12 @@ func (w *Worker) UploadMultipartUploadPart(ctx context.Context, r io.Reader, buc
 	// prepare opts
 	uploadOpts := []upload.Option{
 		upload.WithBlockHeight(up.CurrentHeight),
-		upload.WithPacking(up.UploadPacking),
 		upload.WithCustomKey(mu.EncryptionKey),
 		upload.WithPartNumber(partNumber),
 		upload.WithUploadID(uploadID),
+		upload.WithSlabDeadline(w.uploadSlabDeadline),
+		upload.WithPersistMaxAttempts(w.uploadPersistAttempts),
+		upload.WithETagBufferSize(w.uploadETagBufferSize),
 	}
 
 	// make sure only one of the following is set
@@ -906,6 +1442,9 @@ func (w *Worker) UploadMultipartUploadPart(ctx context.Context, r io.Reader, buc
 	} else if opts.EncryptionOffset != nil && *opts.EncryptionOffset < 0 {
 		return nil, fmt.Errorf("%w: encryption offset must be positive", api.ErrInvalidMultipartEncryptionSettings)
 	} else if encryptionEnabled {
+		if err := object.ValidateEncryptionOffset(uint64(*opts.EncryptionOffset)); err != nil {
+			return nil, fmt.Errorf("%w: %v", api.ErrInvalidMultipartEncryptionSettings, err)
+		}
 		uploadOpts = append(uploadOpts, upload.WithCustomEncryptionOffset(uint64(*opts.EncryptionOffset)))
 	}
 
@@ -915,12 +1454,28 @@ func (w *Worker) UploadMultipartUploadPart(ctx context.Context, r io.Reader, buc
 		return nil, fmt.Errorf("couldn't fetch contracts from bus: %w", err)
 	}
 
+	// restrict the part to the hosts the upload is pinned to
+	packing := up.UploadPacking
+	if len(mu.PinnedHosts) > 0 {
+		contracts, err = pinnedHostContracts(contracts, mu.PinnedHosts, up.RedundancySettings.TotalShards)
+		if err != nil {
+			return nil, err
+		}
+		packing = false
+	}
+	uploadOpts = append(uploadOpts, upload.WithPacking(packing))
+
+	// make sure the upload can achieve its redundancy
+	if err := checkDistinctHosts(contracts, up.RedundancySettings.TotalShards, w.uploadMinDistinctHosts); err != nil {
+		return nil, err
+	}
+
 	// upload
-	eTag, err := w.upload(ctx, bucket, path, up.RedundancySettings, r, contracts, uploadOpts...)
+	eTag, _, err := w.upload(ctx, bucket, path, up.RedundancySettings, r, contracts, uploadOpts...)
 	if err != nil {
 		w.logger.With(zap.Error(err)).With("path", path).With("bucket", bucket).Error("failed to upload object")
 		if !errors.Is(err, ErrShuttingDown) && !errors.Is(err, upload.ErrUploadCancelled) && !errors.Is(err, context.Canceled) {
-			w.registerAlert(newUploadFailedAlert(bucket, path, "", up.RedundancySettings.MinShards, up.RedundancySettings.TotalShards, len(contracts), up.UploadPacking, false, err))
+			w.registerAlert(newUploadFailedAlert(bucket, path, "", up.RedundancySettings.MinShards, up.RedundancySettings.TotalShards, len(contracts), packing, false, err))
 		}
 		return nil, fmt.Errorf("couldn't upload object: %w", err)
 	}
@@ -939,7 +1494,7 @@ func (w *Worker) initAccounts(refillInterval time.Duration) (err error) {
 
 func (w *Worker) prepareUploadParams(ctx context.Context, bucket string, minShards, totalShards int) (api.UploadParams, error) {
 	// return early if the bucket does not exist
-	_, err := w.bus.Bucket(ctx, bucket)
+	b, err := w.bus.Bucket(ctx, bucket)
 	if err != nil {
 		return api.UploadParams{}, fmt.Errorf("bucket '%s' not found; %w", bucket, err)
 	}
@@ -955,7 +1510,12 @@ func (w *Worker) prepareUploadParams(ctx context.Context, bucket string, minShar
 		return api.UploadParams{}, api.ErrConsensusNotSynced
 	}
 
-	// allow overriding the redundancy settings
+	// the bucket's policy overrides the global redundancy settings
+	if b.Policy.Redundancy != nil {
+		up.RedundancySettings = *b.Policy.Redundancy
+	}
+
+	// allow overriding the redundancy settings per upload
 	if minShards != 0 {
 		up.RedundancySettings.MinShards = minShards
 	}
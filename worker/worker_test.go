@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"fmt"
+	"testing"
 	"time"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
@@ -13,7 +14,9 @@ import (
 	"go.sia.tech/renterd/internal/test"
 	"go.sia.tech/renterd/internal/test/mocks"
 	"go.sia.tech/renterd/internal/upload"
+	"go.sia.tech/renterd/internal/upload/uploader"
 	"go.sia.tech/renterd/internal/utils"
+	"go.sia.tech/renterd/object"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/blake2b"
 	"lukechampine.com/frand"
@@ -56,8 +59,21 @@ func newTestWorker(t test.TestingCommon, cfg config.Worker) *testWorker {
 	// override managers
 	hm := newTestHostManager(t)
 	uploadKey := mk.DeriveUploadKey()
-	w.downloadManager = download.NewManager(context.Background(), &uploadKey, hm, dlmm, b, cfg.DownloadMaxOverdrive, cfg.DownloadOverdriveTimeout, zap.NewNop())
-	w.uploadManager = upload.NewManager(context.Background(), &uploadKey, hm, ulmm, b, b, b, cfg.UploadMaxMemory, cfg.UploadOverdriveTimeout, zap.NewNop())
+	w.downloadManager = download.NewManager(context.Background(), &uploadKey, w.hostBreakers, hm, dlmm, b, object.DefaultErasureBackend, cfg.DownloadMaxHostsPerSlab, cfg.DownloadMaxOverdrive, cfg.DownloadOverdriveTimeout, zap.NewNop())
+	w.uploadManager = upload.NewManager(context.Background(), &uploadKey, w.hostBreakers, hm, ulmm, b, b, b, object.DefaultErasureBackend, upload.ManagerConfig{
+		MaxOverdrive:              cfg.UploadMaxMemory,
+		MaxOverdrivePerSlab:       cfg.UploadMaxOverdrivePerSlab,
+		MinFreeMemory:             cfg.UploadMinFreeMemory,
+		ContractDurationWeight:    cfg.UploadContractDurationWeight,
+		CandidateTolerance:        cfg.UploadCandidateTolerance,
+		CandidateFailureThreshold: cfg.UploadCandidateFailureThreshold,
+		MaxActiveUploaders:        cfg.UploadMaxActiveUploaders,
+		OverdriveTimeout:          cfg.UploadOverdriveTimeout,
+		NoCandidateWait:           cfg.UploadNoCandidateWait,
+		StatsRecomputeInterval:    cfg.UploadStatsRecomputeInterval,
+		SectorUploadTimeoutMin:    cfg.UploadSectorTimeoutMin,
+		SectorUploadTimeoutMax:    cfg.UploadSectorTimeoutMax,
+	}, zap.NewNop())
 
 	return &testWorker{
 		test.NewTT(t),
@@ -151,14 +167,18 @@ func (w *testWorker) UsableHosts() []api.HostInfo {
 
 func newTestWorkerCfg() config.Worker {
 	return config.Worker{
-		AccountsRefillInterval:   time.Second,
-		CacheExpiry:              100 * time.Millisecond,
-		ID:                       "test",
-		BusFlushInterval:         time.Second,
-		DownloadOverdriveTimeout: time.Second,
-		UploadOverdriveTimeout:   time.Second,
-		DownloadMaxMemory:        1 << 12, // 4 KiB
-		UploadMaxMemory:          1 << 12, // 4 KiB
+		AccountsRefillInterval:          time.Second,
+		CacheExpiry:                     100 * time.Millisecond,
+		ID:                              "test",
+		BusFlushInterval:                time.Second,
+		DownloadOverdriveTimeout:        time.Second,
+		UploadOverdriveTimeout:          time.Second,
+		UploadStatsRecomputeInterval:    uploader.DefaultStatsRecomputeInterval,
+		UploadCandidateFailureThreshold: uploader.DefaultCandidateFailureThreshold,
+		UploadSectorTimeoutMin:          uploader.DefaultSectorUploadTimeoutMin,
+		UploadSectorTimeoutMax:          uploader.DefaultSectorUploadTimeoutMax,
+		DownloadMaxMemory:               1 << 12, // 4 KiB
+		UploadMaxMemory:                 1 << 12, // 4 KiB
 	}
 }
 
@@ -167,3 +187,25 @@ func newTestSector() (*[rhpv2.SectorSize]byte, types.Hash256) {
 	frand.Read(sector[:])
 	return &sector, rhpv2.SectorRoot(&sector)
 }
+
+func TestNewUploadDefaults(t *testing.T) {
+	// settings that were added later fall back to their defaults
+	cfg := newTestWorkerCfg()
+	cfg.UploadStatsRecomputeInterval = 0
+	cfg.UploadSectorTimeoutMin = 0
+	cfg.UploadSectorTimeoutMax = 0
+	cs := mocks.NewContractStore()
+	b := mocks.NewBus(cs, mocks.NewHostStore(), mocks.NewObjectStore(testBucket, cs))
+	w, err := New(cfg, [32]byte{}, b, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Shutdown(context.Background())
+
+	// an inconsistent config is still rejected
+	cfg.UploadSectorTimeoutMin = time.Minute
+	cfg.UploadSectorTimeoutMax = time.Second
+	if _, err := New(cfg, [32]byte{}, b, zap.NewNop()); err == nil {
+		t.Fatal("expected error")
+	}
+}